@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"sync"
+)
+
+// connTracker counts open client connections via http.Server.ConnState so
+// shutdown can report how many were drained.
+type connTracker struct {
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+}
+
+func (t *connTracker) track(c net.Conn, state http.ConnState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.conns == nil {
+		t.conns = make(map[net.Conn]struct{})
+	}
+	switch state {
+	case http.StateNew:
+		t.conns[c] = struct{}{}
+	case http.StateHijacked, http.StateClosed:
+		delete(t.conns, c)
+	}
+}
+
+func (t *connTracker) active() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.conns)
+}
@@ -0,0 +1,268 @@
+// Package admin implements the operator endpoints under /admin and
+// /debug.
+package admin
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/audit"
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/jsonbody"
+	"github.com/content-factory/go-gateway/internal/maintenance"
+	"github.com/content-factory/go-gateway/internal/openapi"
+	"github.com/content-factory/go-gateway/internal/ratelimit"
+	"github.com/content-factory/go-gateway/internal/realtime"
+	"github.com/content-factory/go-gateway/internal/respond"
+	"github.com/content-factory/go-gateway/internal/router"
+)
+
+// Scope is the token scope the admin endpoints require.
+const Scope = "admin"
+
+// DefaultCloseReason is sent to clients whose connection is closed
+// without a reason.
+const DefaultCloseReason = "closed by administrator"
+
+// maxCloseReason is the longest reason that fits a WebSocket close frame.
+const maxCloseReason = 123
+
+// maxBodyBytes caps the admin request bodies, which are a few small
+// fields each.
+const maxBodyBytes = 16 << 10
+
+// Server holds the dependencies of the admin handlers.
+type Server struct {
+	// Connections is the registry of the open real-time streams, shared
+	// with the handlers serving them.
+	Connections *realtime.Registry
+	// Pool, if set, is the backend connection pool GET /debug/stats
+	// reports on.
+	Pool Pool
+	// Profiling enables the pprof handlers, see RegisterProfiling.
+	Profiling bool
+	// Maintenance, if set, is the maintenance mode /admin/maintenance
+	// reads and switches.
+	Maintenance *maintenance.Mode
+	// DrainOnMaintenance closes the open streams when maintenance mode
+	// is switched on, unless the request says otherwise. Otherwise they
+	// stay open until they end by themselves.
+	DrainOnMaintenance bool
+	// RateLimits, if set, is the limiter whose per-client state
+	// /admin/ratelimits shows and adjusts.
+	RateLimits *ratelimit.Limiter
+}
+
+// Register mounts the admin routes on rt, wrapping each handler in
+// protect, which must authenticate the caller and require Scope.
+func (s *Server) Register(rt *router.Router, protect func(http.Handler) http.Handler) {
+	for _, rte := range s.routes() {
+		rt.Handle(rte.method, rte.pattern, protect(rte.handler))
+	}
+}
+
+// Describe adds the admin routes to spec.
+func (s *Server) Describe(spec *openapi.Spec) {
+	for _, rte := range s.routes() {
+		spec.Add(rte.method, rte.pattern, rte.doc)
+	}
+}
+
+// route is one admin endpoint and its documentation.
+type route struct {
+	method, pattern string
+	handler         http.Handler
+	doc             openapi.Operation
+}
+
+func (s *Server) routes() []route {
+	errorBody := openapi.JSON(apierror.Envelope{})
+	denied := []openapi.Response{
+		{Status: http.StatusUnauthorized, Description: "Missing or invalid credentials.", Body: errorBody},
+		{Status: http.StatusForbidden, Description: "The caller lacks the " + Scope + " scope.", Body: errorBody},
+	}
+	routes := []route{
+		{
+			method: http.MethodGet, pattern: "/admin/connections",
+			handler: http.HandlerFunc(s.listConnections),
+			doc: openapi.Operation{
+				Summary: "List open WebSocket and SSE streams, oldest first",
+				Tags:    []string{"admin"},
+				Responses: append([]openapi.Response{{
+					Status: http.StatusOK, Description: "The open streams.", Body: openapi.JSON(connectionList{}),
+				}}, denied...),
+			},
+		},
+		{
+			method: http.MethodPost, pattern: "/admin/connections/{id:uuid}/close",
+			handler: http.HandlerFunc(s.closeConnection),
+			doc: openapi.Operation{
+				Summary: "Close one stream, telling the client why",
+				Tags:    []string{"admin"},
+				Params:  []openapi.Param{{Name: "id", In: "path"}},
+				Request: &openapi.Body{ContentType: "application/json", Type: closeRequest{}, Optional: true},
+				Responses: append([]openapi.Response{
+					{Status: http.StatusNoContent, Description: "The stream is closing."},
+					{Status: http.StatusNotFound, Description: "No open stream has the ID.", Body: errorBody},
+					{Status: http.StatusUnprocessableEntity, Description: "The reason is too long.", Body: errorBody},
+				}, denied...),
+			},
+		},
+		{
+			method: http.MethodGet, pattern: "/debug/stats",
+			handler: http.HandlerFunc(s.stats),
+			doc: openapi.Operation{
+				Summary: "Goroutine, memory, GC, stream and backend pool figures, for chasing leaks",
+				Tags:    []string{"admin"},
+				Responses: append([]openapi.Response{{
+					Status: http.StatusOK, Description: "The current figures.", Body: openapi.JSON(runtimeStats{}),
+				}}, denied...),
+			},
+		},
+	}
+	if s.RateLimits != nil {
+		routes = append(routes, s.rateLimitRoutes(errorBody, denied)...)
+	}
+	if s.Maintenance == nil {
+		return routes
+	}
+	return append(routes,
+		route{
+			method: http.MethodGet, pattern: "/admin/maintenance",
+			handler: http.HandlerFunc(s.maintenanceStatus),
+			doc: openapi.Operation{
+				Summary: "Report whether the gateway is in maintenance mode",
+				Tags:    []string{"admin"},
+				Responses: append([]openapi.Response{{
+					Status: http.StatusOK, Description: "The maintenance mode in force.", Body: openapi.JSON(maintenanceResponse{}),
+				}}, denied...),
+			},
+		},
+		route{
+			method: http.MethodPost, pattern: "/admin/maintenance",
+			handler: http.HandlerFunc(s.setMaintenance),
+			doc: openapi.Operation{
+				Summary: "Switch maintenance mode on or off, optionally closing the open streams",
+				Tags:    []string{"admin"},
+				Request: &openapi.Body{ContentType: "application/json", Type: maintenanceRequest{}},
+				Responses: append([]openapi.Response{
+					{Status: http.StatusOK, Description: "The maintenance mode now in force.", Body: openapi.JSON(maintenanceResponse{})},
+					{Status: http.StatusBadRequest, Description: "The body is not valid JSON.", Body: errorBody},
+					{Status: http.StatusUnprocessableEntity, Description: "enabled is missing or the message is too long.", Body: errorBody},
+				}, denied...),
+			},
+		},
+	)
+}
+
+// connectionList is the response to GET /admin/connections. The counts
+// are of the streams holding a connection slot, which includes any that
+// are still closing.
+type connectionList struct {
+	Connections  []realtime.Connection `json:"connections"`
+	PerPrincipal map[string]int        `json:"per_principal"`
+	PerClientIP  map[string]int        `json:"per_client_ip"`
+}
+
+func (s *Server) listConnections(w http.ResponseWriter, r *http.Request) {
+	byPrincipal, byIP := s.Connections.Counts()
+	respond.Write(w, r, http.StatusOK, connectionList{
+		Connections:  s.Connections.List(),
+		PerPrincipal: byPrincipal,
+		PerClientIP:  byIP,
+	})
+}
+
+// closeRequest is the optional body of POST
+// /admin/connections/{id}/close.
+type closeRequest struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// closeConnection ends one stream. The close is asynchronous: the stream
+// sends its close frame and goes away shortly after the 204.
+func (s *Server) closeConnection(w http.ResponseWriter, r *http.Request) {
+	var req closeRequest
+	if e := jsonbody.Decode(r, &req, jsonbody.Options{Optional: true, MaxBytes: maxBodyBytes}); e != nil {
+		apierror.Write(w, r, e)
+		return
+	}
+	if len(req.Reason) > maxCloseReason {
+		apierror.Write(w, r, apierror.Validation(apierror.FieldError{
+			Field: "reason", Message: "must be at most " + strconv.Itoa(maxCloseReason) + " bytes",
+		}))
+		return
+	}
+	if req.Reason == "" {
+		req.Reason = DefaultCloseReason
+	}
+	id := router.Param(r, "id")
+	if !s.Connections.Close(id, req.Reason) {
+		audit.Record(r.Context(), audit.ActionCloseConnection, audit.OutcomeFailure,
+			slog.String("connection_id", id), slog.String("reason", "no open connection has that ID"))
+		apierror.Write(w, r, apierror.NotFound("no open connection has that ID"))
+		return
+	}
+	audit.Record(r.Context(), audit.ActionCloseConnection, audit.OutcomeSuccess,
+		slog.String("connection_id", id), slog.String("close_reason", req.Reason))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// maintenanceRequest is the body of POST /admin/maintenance. DrainStreams
+// overrides Server.DrainOnMaintenance when switching the mode on.
+type maintenanceRequest struct {
+	Enabled      *bool  `json:"enabled"`
+	Message      string `json:"message,omitempty"`
+	DrainStreams *bool  `json:"drain_streams,omitempty"`
+}
+
+// maintenanceResponse is the maintenance mode in force and, after it was
+// switched on, how many streams were asked to close.
+type maintenanceResponse struct {
+	maintenance.Status
+	ClosedStreams int `json:"closed_streams,omitempty"`
+}
+
+func (s *Server) maintenanceStatus(w http.ResponseWriter, r *http.Request) {
+	respond.Write(w, r, http.StatusOK, maintenanceResponse{Status: s.Maintenance.Status()})
+}
+
+// setMaintenance switches maintenance mode. Requests already being served
+// finish either way; the open streams are closed only if asked to, with
+// the message as their close reason.
+func (s *Server) setMaintenance(w http.ResponseWriter, r *http.Request) {
+	var req maintenanceRequest
+	if e := jsonbody.Decode(r, &req, jsonbody.Options{MaxBytes: maxBodyBytes}); e != nil {
+		apierror.Write(w, r, e)
+		return
+	}
+	var fields []apierror.FieldError
+	if req.Enabled == nil {
+		fields = append(fields, apierror.FieldError{Field: "enabled", Message: "is required"})
+	}
+	if len(req.Message) > maxCloseReason {
+		fields = append(fields, apierror.FieldError{
+			Field: "message", Message: "must be at most " + strconv.Itoa(maxCloseReason) + " bytes",
+		})
+	}
+	if len(fields) > 0 {
+		apierror.Write(w, r, apierror.Validation(fields...))
+		return
+	}
+
+	resp := maintenanceResponse{Status: s.Maintenance.Set(*req.Enabled, req.Message)}
+	drain := s.DrainOnMaintenance
+	if req.DrainStreams != nil {
+		drain = *req.DrainStreams
+	}
+	if resp.Enabled && drain && s.Connections != nil {
+		resp.ClosedStreams = s.Connections.CloseAll(resp.Message)
+	}
+	gateway.Logger(r.Context()).Warn("maintenance mode switched", "enabled", resp.Enabled, "message", resp.Message,
+		"closed_streams", resp.ClosedStreams)
+	audit.Record(r.Context(), audit.ActionMaintenance, audit.OutcomeSuccess, slog.Bool("enabled", resp.Enabled),
+		slog.String("message", resp.Message), slog.Int("closed_streams", resp.ClosedStreams))
+	respond.Write(w, r, http.StatusOK, resp)
+}
@@ -0,0 +1,346 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/grpcpool"
+	"github.com/content-factory/go-gateway/internal/jobs"
+	"github.com/content-factory/go-gateway/internal/maintenance"
+	"github.com/content-factory/go-gateway/internal/ratelimit"
+	"github.com/content-factory/go-gateway/internal/realtime"
+	"github.com/content-factory/go-gateway/internal/router"
+	"github.com/content-factory/go-gateway/internal/websocket"
+)
+
+// idleSource opens subscriptions that never send an event.
+type idleSource struct{}
+
+func (idleSource) Subscribe(ctx context.Context, jobID string, after int64) (<-chan jobs.Event, error) {
+	ch := make(chan jobs.Event)
+	context.AfterFunc(ctx, func() { close(ch) })
+	return ch, nil
+}
+
+// asUser authenticates every request as subject.
+func asUser(subject string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(gateway.WithClaims(r.Context(), &gateway.Claims{Subject: subject})))
+		})
+	}
+}
+
+// fakePool reports two connections, one of them down.
+type fakePool struct{}
+
+func (fakePool) Health() []grpcpool.ConnHealth {
+	return []grpcpool.ConnHealth{{Index: 0, State: "ready"}, {Index: 1, State: "transient_failure"}}
+}
+
+// setup serves a WebSocket stream backed by a Hub and the admin routes
+// over its registry.
+func setup(t *testing.T) (wsURL string, admin http.Handler) {
+	t.Helper()
+	hub := realtime.NewHub(jobs.NewFeed(context.Background(), idleSource{}, jobs.NewBus()))
+	rt := router.New()
+	rt.Handle(http.MethodGet, "/ws/jobs/{id}", asUser("user-1")(realtime.NewWebSocketHandler(hub)))
+	srv := httptest.NewServer(rt)
+	t.Cleanup(srv.Close)
+
+	adminRT := router.New()
+	(&Server{
+		Connections: hub.Connections(),
+		Pool:        fakePool{},
+		Maintenance: maintenance.New(false, "", time.Minute),
+	}).Register(adminRT, func(h http.Handler) http.Handler { return h })
+	return "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws/jobs/job-1", adminRT
+}
+
+func listConnections(t *testing.T, h http.Handler) connectionList {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/connections", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("list: status = %d", rec.Code)
+	}
+	var body connectionList
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestListAndCloseConnections(t *testing.T) {
+	wsURL, admin := setup(t)
+	conn, _, err := websocket.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	var list connectionList
+	waitFor(t, "the connection to be listed", func() bool {
+		list = listConnections(t, admin)
+		return len(list.Connections) == 1
+	})
+	c := list.Connections[0]
+	if c.JobID != "job-1" || c.Principal != "user-1" || c.ClientIP != "127.0.0.1" || c.Transport != realtime.TransportWebSocket || c.ConnectedAt.IsZero() {
+		t.Errorf("connection = %+v", c)
+	}
+	if list.PerPrincipal["user-1"] != 1 || list.PerClientIP["127.0.0.1"] != 1 {
+		t.Errorf("counts = %v %v", list.PerPrincipal, list.PerClientIP)
+	}
+
+	rec := httptest.NewRecorder()
+	admin.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/connections/"+c.ID+"/close",
+		strings.NewReader(`{"reason": "deploying v2"}`)))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("close: status = %d, body %s", rec.Code, rec.Body)
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) || !strings.Contains(err.Error(), "deploying v2") {
+		t.Errorf("read after close = %v, want going away with the reason", err)
+	}
+	waitFor(t, "the connection to be forgotten", func() bool {
+		list := listConnections(t, admin)
+		return len(list.Connections) == 0 && len(list.PerPrincipal) == 0
+	})
+}
+
+func TestCloseConnectionErrors(t *testing.T) {
+	_, admin := setup(t)
+	tests := []struct {
+		name, id, body string
+		want           int
+	}{
+		{"malformed id", "nope", "", http.StatusBadRequest},
+		{"unknown id", "5b0c7f2e-8d1a-4e3b-9f6c-0a2d4e6f8b10", "", http.StatusNotFound},
+		{"bad json", "5b0c7f2e-8d1a-4e3b-9f6c-0a2d4e6f8b10", "{", http.StatusBadRequest},
+		{"unknown field", "5b0c7f2e-8d1a-4e3b-9f6c-0a2d4e6f8b10", `{"reasn": "x"}`, http.StatusBadRequest},
+		{"reason too long", "5b0c7f2e-8d1a-4e3b-9f6c-0a2d4e6f8b10", `{"reason": "` + strings.Repeat("x", 124) + `"}`, http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			admin.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/connections/"+tt.id+"/close", strings.NewReader(tt.body)))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestStats(t *testing.T) {
+	wsURL, admin := setup(t)
+	conn, _, err := websocket.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	waitFor(t, "the connection to be listed", func() bool {
+		return len(listConnections(t, admin).Connections) == 1
+	})
+
+	rec := httptest.NewRecorder()
+	admin.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/stats", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	var got runtimeStats
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Goroutines < 1 || got.Memory.HeapAlloc == 0 || got.Memory.Sys == 0 || got.GC.RecentPauses == nil {
+		t.Errorf("runtime figures = %+v", got)
+	}
+	if got.Streams != (streamStats{Open: 1, WebSocket: 1}) {
+		t.Errorf("streams = %+v", got.Streams)
+	}
+	if got.Backend == nil || got.Backend.Connections != 2 || got.Backend.States["ready"] != 1 || got.Backend.States["transient_failure"] != 1 {
+		t.Errorf("backend pool = %+v", got.Backend)
+	}
+}
+
+func TestProfiling(t *testing.T) {
+	get := func(s *Server, path string) int {
+		rt := router.New()
+		s.RegisterProfiling(rt, func(h http.Handler) http.Handler { return h })
+		rec := httptest.NewRecorder()
+		rt.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code
+	}
+	for _, path := range []string{"/debug/pprof/", "/debug/pprof/heap", "/debug/pprof/cmdline", "/debug/pprof/profile"} {
+		if code := get(&Server{}, path); code != http.StatusNotFound {
+			t.Errorf("disabled: GET %s = %d, want 404", path, code)
+		}
+	}
+	for _, path := range []string{"/debug/pprof/", "/debug/pprof/heap?debug=1", "/debug/pprof/cmdline"} {
+		if code := get(&Server{Profiling: true}, path); code != http.StatusOK {
+			t.Errorf("enabled: GET %s = %d, want 200", path, code)
+		}
+	}
+}
+
+func setMaintenance(t *testing.T, h http.Handler, body string) (int, maintenanceResponse) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/maintenance", strings.NewReader(body)))
+	var resp maintenanceResponse
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rec.Code, resp
+}
+
+func TestMaintenance(t *testing.T) {
+	wsURL, admin := setup(t)
+	conn, _, err := websocket.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	waitFor(t, "the connection to be listed", func() bool {
+		return len(listConnections(t, admin).Connections) == 1
+	})
+
+	// Without drain_streams the stream stays open.
+	code, resp := setMaintenance(t, admin, `{"enabled": true, "message": "deploying v2"}`)
+	if code != http.StatusOK || !resp.Enabled || resp.Message != "deploying v2" || resp.ClosedStreams != 0 {
+		t.Fatalf("switch on: %d %+v", code, resp)
+	}
+	if n := len(listConnections(t, admin).Connections); n != 1 {
+		t.Fatalf("%d streams open after switching on without draining, want 1", n)
+	}
+
+	rec := httptest.NewRecorder()
+	admin.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/maintenance", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"enabled":true`) {
+		t.Fatalf("status: %d %s", rec.Code, rec.Body)
+	}
+
+	code, resp = setMaintenance(t, admin, `{"enabled": true, "drain_streams": true}`)
+	if code != http.StatusOK || resp.ClosedStreams != 1 || resp.Message != "deploying v2" {
+		t.Fatalf("drain: %d %+v", code, resp)
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("read after draining = %v, want going away", err)
+	}
+
+	if code, resp = setMaintenance(t, admin, `{"enabled": false}`); code != http.StatusOK || resp.Enabled {
+		t.Errorf("switch off: %d %+v", code, resp)
+	}
+	for body, want := range map[string]int{
+		"{":                                   http.StatusBadRequest,
+		`{}`:                                  http.StatusUnprocessableEntity,
+		`{"enabled": "yes"}`:                  http.StatusBadRequest,
+		`{"enabled": true}{"enabled": false}`: http.StatusBadRequest,
+		`{"enabled": true, "message": "` + strings.Repeat("x", 124) + `"}`: http.StatusUnprocessableEntity,
+	} {
+		if code, _ := setMaintenance(t, admin, body); code != want {
+			t.Errorf("POST %s = %d, want %d", body, code, want)
+		}
+	}
+}
+
+func TestRateLimits(t *testing.T) {
+	limiter := ratelimit.New(ratelimit.Config{RPS: 0.001, Burst: 1})
+	api := asUser("user-1")(limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	call := func() int {
+		rec := httptest.NewRecorder()
+		api.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/jobs", nil))
+		return rec.Code
+	}
+	rt := router.New()
+	(&Server{RateLimits: limiter}).Register(rt, asUser("operator"))
+	admin := func(method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		rt.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := admin(http.MethodGet, "/admin/ratelimits/user:user-1", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("unseen client: status = %d", rec.Code)
+	}
+	if call() != http.StatusOK || call() != http.StatusTooManyRequests {
+		t.Fatal("burst of 1 not enforced")
+	}
+	rec := admin(http.MethodGet, "/admin/ratelimits/user:user-1", "")
+	var st ratelimit.ClientState
+	if err := json.Unmarshal(rec.Body.Bytes(), &st); rec.Code != http.StatusOK || err != nil {
+		t.Fatalf("state: status = %d, body %s", rec.Code, rec.Body)
+	}
+	if len(st.Buckets) != 1 || st.Buckets[0].Burst != 1 || st.Buckets[0].Tokens >= 1 || st.Override != nil {
+		t.Errorf("state = %+v", st)
+	}
+
+	// A reset lets the next request through.
+	if rec := admin(http.MethodPost, "/admin/ratelimits/user:user-1/reset", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("reset: status = %d", rec.Code)
+	}
+	if call() != http.StatusOK || call() != http.StatusTooManyRequests {
+		t.Error("reset did not refill the bucket")
+	}
+
+	// So does an override, with its own burst.
+	rec = admin(http.MethodPut, "/admin/ratelimits/user:user-1/override", `{"rps": 1, "burst": 3, "ttl_seconds": 600}`)
+	st = ratelimit.ClientState{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &st); rec.Code != http.StatusOK || err != nil || st.Override == nil || st.Override.Burst != 3 {
+		t.Fatalf("override: status = %d, body %s", rec.Code, rec.Body)
+	}
+	if time.Until(st.Override.Expires) < 9*time.Minute {
+		t.Errorf("override expires at %v, want in 10m", st.Override.Expires)
+	}
+	for i := range 3 {
+		if code := call(); code != http.StatusOK {
+			t.Fatalf("request %d under override: status = %d", i, code)
+		}
+	}
+	if rec := admin(http.MethodDelete, "/admin/ratelimits/user:user-1/override", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("clear: status = %d", rec.Code)
+	}
+	if call() != http.StatusTooManyRequests {
+		t.Error("usual limit not back after clearing the override")
+	}
+
+	for _, tt := range []struct {
+		method, target, body string
+		want                 int
+	}{
+		{http.MethodGet, "/admin/ratelimits/alice", "", http.StatusBadRequest},
+		{http.MethodGet, "/admin/ratelimits/user:", "", http.StatusBadRequest},
+		{http.MethodGet, "/admin/ratelimits/tenant:acme", "", http.StatusBadRequest},
+		{http.MethodGet, "/admin/ratelimits/tenant:acme:alice", "", http.StatusBadRequest},
+		{http.MethodGet, "/admin/ratelimits/tenant:acme:user:user-1", "", http.StatusNotFound},
+		{http.MethodPost, "/admin/ratelimits/ip:203.0.113.9/reset", "", http.StatusNotFound},
+		{http.MethodDelete, "/admin/ratelimits/user:user-1/override", "", http.StatusNotFound},
+		{http.MethodPut, "/admin/ratelimits/user:user-1/override", "{", http.StatusBadRequest},
+		{http.MethodPut, "/admin/ratelimits/user:user-1/override", `{"rps": 0, "burst": 0, "ttl_seconds": 86401}`, http.StatusUnprocessableEntity},
+	} {
+		if rec := admin(tt.method, tt.target, tt.body); rec.Code != tt.want {
+			t.Errorf("%s %s %s: status = %d, want %d", tt.method, tt.target, tt.body, rec.Code, tt.want)
+		}
+	}
+}
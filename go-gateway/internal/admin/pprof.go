@@ -0,0 +1,28 @@
+package admin
+
+import (
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/content-factory/go-gateway/internal/router"
+)
+
+// RegisterProfiling mounts the net/http/pprof handlers under
+// /debug/pprof/ if s.Profiling is set, wrapping each in protect, which
+// must authenticate the caller and require Scope. protect should not
+// apply a request timeout or rate limit: a CPU profile or trace streams
+// for as long as its seconds parameter asks.
+func (s *Server) RegisterProfiling(rt *router.Router, protect func(http.Handler) http.Handler) {
+	if !s.Profiling {
+		return
+	}
+	rt.Handle(http.MethodGet, "/debug/pprof/", protect(http.HandlerFunc(pprof.Index)))
+	rt.Handle(http.MethodGet, "/debug/pprof/cmdline", protect(http.HandlerFunc(pprof.Cmdline)))
+	rt.Handle(http.MethodGet, "/debug/pprof/profile", protect(http.HandlerFunc(pprof.Profile)))
+	rt.Handle(http.MethodGet, "/debug/pprof/symbol", protect(http.HandlerFunc(pprof.Symbol)))
+	rt.Handle(http.MethodPost, "/debug/pprof/symbol", protect(http.HandlerFunc(pprof.Symbol)))
+	rt.Handle(http.MethodGet, "/debug/pprof/trace", protect(http.HandlerFunc(pprof.Trace)))
+	// Index serves the named profiles, heap, goroutine, allocs and the
+	// rest, from the path.
+	rt.Handle(http.MethodGet, "/debug/pprof/{profile}", protect(http.HandlerFunc(pprof.Index)))
+}
@@ -0,0 +1,189 @@
+package admin
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/audit"
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/jsonbody"
+	"github.com/content-factory/go-gateway/internal/openapi"
+	"github.com/content-factory/go-gateway/internal/ratelimit"
+	"github.com/content-factory/go-gateway/internal/respond"
+	"github.com/content-factory/go-gateway/internal/router"
+)
+
+// keyPrefixes are the kinds of client key ratelimit.Key gives.
+var keyPrefixes = []string{"user:", "apikey:", "ip:"}
+
+func (s *Server) rateLimitRoutes(errorBody *openapi.Body, denied []openapi.Response) []route {
+	keyParam := openapi.Param{Name: "key", In: "path",
+		Description: "The client's rate limit key: user:<subject>, apikey:<id> or ip:<address>, after tenant:<id>: for a tenant's client."}
+	badKey := openapi.Response{Status: http.StatusBadRequest, Description: "The key is not a client key.", Body: errorBody}
+	missing := openapi.Response{Status: http.StatusNotFound, Description: "The gateway holds no rate limit state for the client.", Body: errorBody}
+	return []route{
+		{
+			method: http.MethodGet, pattern: "/admin/ratelimits/{key}",
+			handler: http.HandlerFunc(s.rateLimitState),
+			doc: openapi.Operation{
+				Summary: "Show a client's token buckets and limit override",
+				Tags:    []string{"admin"},
+				Params:  []openapi.Param{keyParam},
+				Responses: append([]openapi.Response{
+					{Status: http.StatusOK, Description: "The client's rate limit state.", Body: openapi.JSON(ratelimit.ClientState{})},
+					badKey, missing,
+				}, denied...),
+			},
+		},
+		{
+			method: http.MethodPost, pattern: "/admin/ratelimits/{key}/reset",
+			handler: http.HandlerFunc(s.resetRateLimit),
+			doc: openapi.Operation{
+				Summary: "Refill a client's token buckets",
+				Tags:    []string{"admin"},
+				Params:  []openapi.Param{keyParam},
+				Responses: append([]openapi.Response{
+					{Status: http.StatusNoContent, Description: "The client's next requests start with a full burst."},
+					badKey, missing,
+				}, denied...),
+			},
+		},
+		{
+			method: http.MethodPut, pattern: "/admin/ratelimits/{key}/override",
+			handler: http.HandlerFunc(s.setRateLimitOverride),
+			doc: openapi.Operation{
+				Summary:     "Give a client its own rate limit for a while",
+				Description: "The override replaces every limit the client gets, route overrides included, until it expires, at most " + ratelimit.MaxOverrideTTL.String() + " later. The client starts again with a full burst.",
+				Tags:        []string{"admin"},
+				Params:      []openapi.Param{keyParam},
+				Request:     openapi.JSON(overrideRequest{}),
+				Responses: append([]openapi.Response{
+					{Status: http.StatusOK, Description: "The client's rate limit state with the override.", Body: openapi.JSON(ratelimit.ClientState{})},
+					badKey,
+					{Status: http.StatusUnprocessableEntity, Description: "rps, burst or ttl_seconds is out of range.", Body: errorBody},
+				}, denied...),
+			},
+		},
+		{
+			method: http.MethodDelete, pattern: "/admin/ratelimits/{key}/override",
+			handler: http.HandlerFunc(s.clearRateLimitOverride),
+			doc: openapi.Operation{
+				Summary: "End a client's rate limit override early",
+				Tags:    []string{"admin"},
+				Params:  []openapi.Param{keyParam},
+				Responses: append([]openapi.Response{
+					{Status: http.StatusNoContent, Description: "The client's usual limits apply again."},
+					badKey,
+					{Status: http.StatusNotFound, Description: "The client has no override in force.", Body: errorBody},
+				}, denied...),
+			},
+		},
+	}
+}
+
+// overrideRequest is the body of PUT /admin/ratelimits/{key}/override.
+type overrideRequest struct {
+	RPS        float64 `json:"rps"`
+	Burst      int     `json:"burst"`
+	TTLSeconds int     `json:"ttl_seconds"`
+}
+
+// clientKey returns the {key} parameter, answering 400 unless it is a key
+// ratelimit.Key could give.
+func clientKey(w http.ResponseWriter, r *http.Request) (string, bool) {
+	key := router.Param(r, "key")
+	client := key
+	if rest, ok := strings.CutPrefix(key, ratelimit.TenantPrefix); ok {
+		// Tenant IDs hold no colons.
+		if id, c, ok := strings.Cut(rest, ":"); ok && id != "" {
+			client = c
+		}
+	}
+	for _, p := range keyPrefixes {
+		if len(client) > len(p) && strings.HasPrefix(client, p) {
+			return key, true
+		}
+	}
+	apierror.Write(w, r, apierror.InvalidRequest("the key must be user:<subject>, apikey:<id> or ip:<address>",
+		apierror.FieldError{Field: "key", Message: "is not a client key"}))
+	return "", false
+}
+
+func (s *Server) rateLimitState(w http.ResponseWriter, r *http.Request) {
+	key, ok := clientKey(w, r)
+	if !ok {
+		return
+	}
+	st, ok := s.RateLimits.State(key)
+	if !ok {
+		apierror.Write(w, r, apierror.NotFound("no rate limit state for that client"))
+		return
+	}
+	respond.Write(w, r, http.StatusOK, st)
+}
+
+func (s *Server) resetRateLimit(w http.ResponseWriter, r *http.Request) {
+	key, ok := clientKey(w, r)
+	if !ok {
+		return
+	}
+	if !s.RateLimits.Reset(key) {
+		apierror.Write(w, r, apierror.NotFound("no rate limit state for that client"))
+		return
+	}
+	gateway.Logger(r.Context()).Warn("rate limit reset", "key", key)
+	audit.Record(r.Context(), audit.ActionRateLimitReset, audit.OutcomeSuccess, slog.String("key", key))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) setRateLimitOverride(w http.ResponseWriter, r *http.Request) {
+	key, ok := clientKey(w, r)
+	if !ok {
+		return
+	}
+	var req overrideRequest
+	if e := jsonbody.Decode(r, &req, jsonbody.Options{MaxBytes: maxBodyBytes}); e != nil {
+		apierror.Write(w, r, e)
+		return
+	}
+	var fields []apierror.FieldError
+	if req.RPS <= 0 {
+		fields = append(fields, apierror.FieldError{Field: "rps", Message: "must be positive"})
+	}
+	if req.Burst < 1 {
+		fields = append(fields, apierror.FieldError{Field: "burst", Message: "must be at least 1"})
+	}
+	if maxTTL := int(ratelimit.MaxOverrideTTL / time.Second); req.TTLSeconds < 1 || req.TTLSeconds > maxTTL {
+		fields = append(fields, apierror.FieldError{Field: "ttl_seconds", Message: "must be between 1 and " + strconv.Itoa(maxTTL)})
+	}
+	if len(fields) > 0 {
+		apierror.Write(w, r, apierror.Validation(fields...))
+		return
+	}
+
+	o := s.RateLimits.SetOverride(key, req.RPS, req.Burst, time.Duration(req.TTLSeconds)*time.Second)
+	gateway.Logger(r.Context()).Warn("rate limit override set", "key", key, "rps", o.RPS, "burst", o.Burst,
+		"expires_at", o.Expires)
+	audit.Record(r.Context(), audit.ActionRateLimitOverride, audit.OutcomeSuccess, slog.String("key", key),
+		slog.Float64("rps", o.RPS), slog.Int("burst", o.Burst), slog.Time("expires_at", o.Expires))
+	st, _ := s.RateLimits.State(key)
+	respond.Write(w, r, http.StatusOK, st)
+}
+
+func (s *Server) clearRateLimitOverride(w http.ResponseWriter, r *http.Request) {
+	key, ok := clientKey(w, r)
+	if !ok {
+		return
+	}
+	if !s.RateLimits.ClearOverride(key) {
+		apierror.Write(w, r, apierror.NotFound("the client has no rate limit override"))
+		return
+	}
+	gateway.Logger(r.Context()).Warn("rate limit override cleared", "key", key)
+	audit.Record(r.Context(), audit.ActionRateLimitClear, audit.OutcomeSuccess, slog.String("key", key))
+	w.WriteHeader(http.StatusNoContent)
+}
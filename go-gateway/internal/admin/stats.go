@@ -0,0 +1,118 @@
+package admin
+
+import (
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/grpcpool"
+	"github.com/content-factory/go-gateway/internal/realtime"
+	"github.com/content-factory/go-gateway/internal/respond"
+)
+
+// Pool is the view of the backend connection pool GET /debug/stats
+// reports; *grpcpool.Pool implements it.
+type Pool interface {
+	Health() []grpcpool.ConnHealth
+}
+
+// recentPauses is how many of the latest GC pauses the stats list.
+const recentPauses = 8
+
+// runtimeStats is the response to GET /debug/stats.
+type runtimeStats struct {
+	Time       time.Time   `json:"time"`
+	Uptime     float64     `json:"uptime_seconds"`
+	Goroutines int         `json:"goroutines"`
+	Memory     memoryStats `json:"memory"`
+	GC         gcStats     `json:"gc"`
+	Streams    streamStats `json:"streams"`
+	Backend    *poolStats  `json:"backend_pool,omitempty"`
+}
+
+type memoryStats struct {
+	HeapAlloc   uint64 `json:"heap_alloc_bytes"`
+	HeapInuse   uint64 `json:"heap_inuse_bytes"`
+	HeapObjects uint64 `json:"heap_objects"`
+	Sys         uint64 `json:"sys_bytes"`
+	TotalAlloc  uint64 `json:"total_alloc_bytes"`
+}
+
+type gcStats struct {
+	Cycles       uint32    `json:"cycles"`
+	LastAt       time.Time `json:"last_at,omitzero"`
+	PauseTotal   float64   `json:"pause_total_seconds"`
+	RecentPauses []float64 `json:"recent_pauses_seconds"`
+	CPUFraction  float64   `json:"cpu_fraction"`
+}
+
+// streamStats counts the open real-time streams by transport.
+type streamStats struct {
+	Open      int `json:"open"`
+	WebSocket int `json:"websocket"`
+	SSE       int `json:"sse"`
+	LongPoll  int `json:"long_poll"`
+}
+
+// poolStats counts the backend connections by state.
+type poolStats struct {
+	Connections int            `json:"connections"`
+	States      map[string]int `json:"states"`
+}
+
+// started is when the process started, near enough, for uptime.
+var started = time.Now()
+
+// stats reports runtime figures for chasing leaks. It reads the memory
+// statistics once per call, a pause of well under a millisecond, so it
+// can be polled every few seconds.
+func (s *Server) stats(w http.ResponseWriter, r *http.Request) {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	out := runtimeStats{
+		Time:       time.Now().UTC(),
+		Uptime:     time.Since(started).Seconds(),
+		Goroutines: runtime.NumGoroutine(),
+		Memory: memoryStats{
+			HeapAlloc:   ms.HeapAlloc,
+			HeapInuse:   ms.HeapInuse,
+			HeapObjects: ms.HeapObjects,
+			Sys:         ms.Sys,
+			TotalAlloc:  ms.TotalAlloc,
+		},
+		GC: gcStats{
+			Cycles:       ms.NumGC,
+			PauseTotal:   time.Duration(ms.PauseTotalNs).Seconds(),
+			RecentPauses: []float64{},
+			CPUFraction:  ms.GCCPUFraction,
+		},
+	}
+	if ms.NumGC > 0 {
+		out.GC.LastAt = time.Unix(0, int64(ms.LastGC)).UTC()
+	}
+	// PauseNs is a ring buffer whose latest entry is at (NumGC+255)%256.
+	for i := range min(uint32(recentPauses), ms.NumGC) {
+		pause := ms.PauseNs[(ms.NumGC-1-i)%uint32(len(ms.PauseNs))]
+		out.GC.RecentPauses = append(out.GC.RecentPauses, time.Duration(pause).Seconds())
+	}
+
+	for _, c := range s.Connections.List() {
+		out.Streams.Open++
+		switch c.Transport {
+		case realtime.TransportWebSocket:
+			out.Streams.WebSocket++
+		case realtime.TransportSSE:
+			out.Streams.SSE++
+		case realtime.TransportLongPoll:
+			out.Streams.LongPoll++
+		}
+	}
+	if s.Pool != nil {
+		conns := s.Pool.Health()
+		out.Backend = &poolStats{Connections: len(conns), States: map[string]int{}}
+		for _, c := range conns {
+			out.Backend.States[c.State]++
+		}
+	}
+	respond.Write(w, r, http.StatusOK, out)
+}
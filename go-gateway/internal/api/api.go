@@ -0,0 +1,264 @@
+// Package api implements the gateway's versioned REST endpoints under
+// /api/v1.
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/content-factory/go-gateway/internal/backend"
+	"github.com/content-factory/go-gateway/internal/cache"
+	"github.com/content-factory/go-gateway/internal/flags"
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/idempotency"
+	"github.com/content-factory/go-gateway/internal/jobs"
+	"github.com/content-factory/go-gateway/internal/middleware"
+	"github.com/content-factory/go-gateway/internal/queue"
+	"github.com/content-factory/go-gateway/internal/ratelimit"
+	"github.com/content-factory/go-gateway/internal/routeconf"
+	"github.com/content-factory/go-gateway/internal/router"
+	"github.com/content-factory/go-gateway/internal/signedurl"
+	"github.com/content-factory/go-gateway/internal/webhook"
+)
+
+const (
+	// DefaultMaxBodyBytes caps request bodies when Server.MaxBodyBytes is
+	// zero.
+	DefaultMaxBodyBytes = 1 << 20
+	// DefaultMaxUploadBytes caps upload bodies when Server.MaxUploadBytes
+	// is zero.
+	DefaultMaxUploadBytes = 256 << 20
+)
+
+// Backend is the subset of the ContentOrchestrator client the handlers use.
+// *backend.Client implements it; tests substitute fakes.
+type Backend interface {
+	CreateContent(ctx context.Context, req *backend.CreateContentRequest) (*backend.CreateContentResponse, error)
+	EstimateContent(ctx context.Context, req *backend.CreateContentRequest) (*backend.ContentEstimate, error)
+	GetJobStatus(ctx context.Context, jobID string) (*backend.JobStatusResponse, error)
+	GetJobRequest(ctx context.Context, jobID string) (*backend.CreateContentRequest, error)
+	ListJobs(ctx context.Context, req *backend.ListJobsRequest) (*backend.ListJobsResponse, error)
+	CancelJob(ctx context.Context, req *backend.CancelJobRequest) (*backend.JobStatusResponse, error)
+	SetJobVisibility(ctx context.Context, req *backend.SetJobVisibilityRequest) (*backend.JobStatusResponse, error)
+	GetContentInfo(ctx context.Context, contentID string) (*backend.ContentInfo, error)
+	DownloadContent(ctx context.Context, contentID string, offset, length int64) (*backend.ContentStream, error)
+	GenerateStream(ctx context.Context, req *backend.CreateContentRequest) (*backend.TokenStream, error)
+	UploadAsset(ctx context.Context, req *backend.UploadAssetRequest, body io.Reader) (*backend.Asset, error)
+}
+
+// Server holds the dependencies of the REST handlers.
+type Server struct {
+	Backend Backend
+	// RequestTimeout is the deadline of each API request, see
+	// middleware.Timeout. Zero means middleware.DefaultTimeout.
+	// RequestTimeoutVar, if set, is used instead, so the deadline can be
+	// changed while serving.
+	RequestTimeout    time.Duration
+	RequestTimeoutVar *middleware.TimeoutVar
+	// MaxBodyBytes caps JSON request bodies, MaxUploadBytes those of
+	// upload routes. Both override the gateway-wide middleware.MaxBytes.
+	MaxBodyBytes   int64
+	MaxUploadBytes int64
+	// Routes overrides MaxBodyBytes and MaxUploadBytes for some routes,
+	// and sets the window in which their duplicate submissions are
+	// coalesced.
+	Routes routeconf.Table
+	// MaxAssetBytes caps each uploaded asset file; zero means
+	// DefaultMaxAssetBytes. AssetTypes lists the media types allowed as
+	// assets; empty means DefaultAssetTypes.
+	MaxAssetBytes int64
+	AssetTypes    []string
+	// StrictJSON rejects request bodies with fields the API does not
+	// define, instead of ignoring them.
+	StrictJSON bool
+	// Idempotency, if set, records Idempotency-Key headers on content
+	// submissions for IdempotencyTTL (zero means idempotency.DefaultTTL).
+	Idempotency    idempotency.Store
+	IdempotencyTTL time.Duration
+	// DefaultPageSize is the limit of list endpoints that clients leave
+	// out, and MaxPageSize the largest they may ask for. Zero means
+	// DefaultPageSize and DefaultMaxPageSize.
+	DefaultPageSize int
+	MaxPageSize     int
+	// MaxBatchItems caps the requests of a batch submission, and
+	// BatchConcurrency the backend calls it or a bulk status request
+	// makes at once. Zero means DefaultMaxBatchItems and
+	// DefaultBatchConcurrency.
+	MaxBatchItems    int
+	BatchConcurrency int
+	// MaxStatusIDs caps the job IDs of a bulk status request. Zero means
+	// DefaultMaxStatusIDs.
+	MaxStatusIDs int
+	// Cache, if set, holds job reads for CacheTTL (zero means
+	// cache.DefaultTTL).
+	Cache    cache.Store
+	CacheTTL time.Duration
+	// Queue, if set, buffers content submissions for queue.Workers to
+	// hand to the backend, instead of submitting them while the client
+	// waits.
+	Queue queue.Queue
+	// QueueThroughput, if set, is how fast jobs leave Queue, which the
+	// queue positions of waiting jobs estimate their wait from.
+	QueueThroughput *queue.Throughput
+	// Capabilities, if set, says whether content submission is available;
+	// while it is not, submissions are refused and reads still served.
+	Capabilities Capabilities
+	// Webhooks, if set, delivers the webhooks of submissions that ask for
+	// one; without it webhook_url is refused.
+	Webhooks *webhook.Dispatcher
+	// Jobs, if set, follows the jobs submitted directly to the backend
+	// onto the job event bus, for the consumers of every job. Jobs on
+	// Queue are followed by whatever submits them.
+	Jobs *jobs.Feed
+	// Flags gates the routes behind feature flags; without it they are
+	// not registered.
+	Flags *flags.Set
+	// SignedURLs, if set, issues signed download URLs of content files,
+	// and RegisterPublic serves them; without it neither route exists.
+	SignedURLs *signedurl.Signer
+	// PublicContent, if set, lets owners make their jobs public, and
+	// RegisterPublic serves those behind it, in place of the chain it is
+	// given, so they can have a rate limit of their own. Without it
+	// neither route exists.
+	PublicContent func(http.Handler) http.Handler
+
+	// flights merges identical backend reads in flight, see shared. It is
+	// made by Register.
+	flights *singleflight.Group
+	// coalescer answers duplicate submissions, see idempotent.
+	coalescer *idempotency.Coalescer
+}
+
+// Register mounts the API routes on rt, wrapping each handler in protect
+// (authentication and rate limiting) and the request timeout. Routes
+// weighing more than one request have their rate-limit weight set before
+// protect runs, see ratelimit.Weigh.
+func (s *Server) Register(rt *router.Router, protect func(http.Handler) http.Handler) {
+	mounts := s.mounts()
+	for _, rte := range s.routes() {
+		if rte.doc.Public {
+			continue
+		}
+		h := mounts[rte.mount](rte.handler)
+		weigh := ratelimit.Weigh(rte.weight)
+		if rte.flag == "" {
+			rt.Handle(rte.method, rte.pattern, weigh(protect(h)))
+			continue
+		}
+		if s.Flags != nil {
+			rt.HandleWhen(rte.method, rte.pattern, weigh(protect(flags.Require(rte.flag)(h))), s.Flags.Visible(rte.flag))
+		}
+	}
+}
+
+// RegisterPublic mounts the routes that need no credentials, those of
+// signed URLs and public content, on rt, wrapping each handler in chain
+// or the route's own.
+func (s *Server) RegisterPublic(rt *router.Router, chain func(http.Handler) http.Handler) {
+	mounts := s.mounts()
+	for _, rte := range s.routes() {
+		if !rte.doc.Public {
+			continue
+		}
+		wrap := chain
+		if rte.chain != nil {
+			wrap = rte.chain
+		}
+		rt.Handle(rte.method, rte.pattern, wrap(mounts[rte.mount](rte.handler)))
+	}
+}
+
+// mounts returns the middleware of each mountKind.
+func (s *Server) mounts() map[mountKind]func(http.Handler) http.Handler {
+	if s.flights == nil {
+		s.flights = new(singleflight.Group)
+	}
+	timeout := s.timeout()
+	bodyLimit := middleware.RouteBodyLimit(s.Routes, s.maxBodyBytes())
+	uploadLimit := middleware.RouteBodyLimit(s.Routes, s.maxUploadBytes())
+	return map[mountKind]func(http.Handler) http.Handler{
+		mountPlain: func(h http.Handler) http.Handler { return acceptable(bodyLimit(timeout(h))) },
+		// Cached reads are answered before the timeout starts.
+		mountCached: func(h http.Handler) http.Handler { return acceptable(s.cached(s.cacheTTL())(timeout(h))) },
+		// Streaming handlers run as long as the transfer takes, and so do
+		// uploads.
+		mountStream: func(h http.Handler) http.Handler { return middleware.LongLived(bodyLimit(h)) },
+		mountUpload: func(h http.Handler) http.Handler { return middleware.LongLived(acceptable(uploadLimit(h))) },
+	}
+}
+
+func (s *Server) timeout() func(http.Handler) http.Handler {
+	if s.RequestTimeoutVar != nil {
+		return s.RequestTimeoutVar.Middleware
+	}
+	d := s.RequestTimeout
+	if d <= 0 {
+		d = middleware.DefaultTimeout
+	}
+	return middleware.Timeout(d)
+}
+
+// idempotent honours Idempotency-Key on h when a store is configured,
+// and coalesces duplicate submissions without one on routes with a
+// coalesce window.
+func (s *Server) idempotent(h http.Handler) http.Handler {
+	if s.coalescer == nil {
+		s.coalescer = idempotency.NewCoalescer()
+	}
+	h = s.coalescer.Middleware(s.Routes, s.maxBodyBytes())(h)
+	if s.Idempotency == nil {
+		return h
+	}
+	ttl := s.IdempotencyTTL
+	if ttl <= 0 {
+		ttl = idempotency.DefaultTTL
+	}
+	return idempotency.Middleware(s.Idempotency, ttl, s.maxBodyBytes())(h)
+}
+
+// cached serves repeated reads from s.Cache for ttl, and stale ones in
+// place of errors on routes with a stale_if_error override.
+func (s *Server) cached(ttl time.Duration) func(http.Handler) http.Handler {
+	if s.Cache == nil {
+		return func(h http.Handler) http.Handler { return h }
+	}
+	return cache.Middleware(s.Cache, ttl, s.Routes)
+}
+
+func (s *Server) cacheTTL() time.Duration {
+	if s.CacheTTL <= 0 {
+		return cache.DefaultTTL
+	}
+	return s.CacheTTL
+}
+
+// invalidate drops the cached responses of the resources at paths. Write
+// handlers call it for every resource they change.
+func (s *Server) invalidate(ctx context.Context, paths ...string) {
+	if s.Cache == nil {
+		return
+	}
+	for _, p := range paths {
+		if err := s.Cache.Invalidate(ctx, p); err != nil {
+			gateway.Logger(ctx).WarnContext(ctx, "failed to invalidate cached responses", "path", p, "error", err)
+		}
+	}
+}
+
+func (s *Server) maxBodyBytes() int64 {
+	if s.MaxBodyBytes <= 0 {
+		return DefaultMaxBodyBytes
+	}
+	return s.MaxBodyBytes
+}
+
+func (s *Server) maxUploadBytes() int64 {
+	if s.MaxUploadBytes <= 0 {
+		return DefaultMaxUploadBytes
+	}
+	return s.MaxUploadBytes
+}
@@ -0,0 +1,206 @@
+package api
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/backend"
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/respond"
+	"github.com/content-factory/go-gateway/internal/router"
+)
+
+// DefaultMaxAssetBytes caps each uploaded file when Server.MaxAssetBytes
+// is zero.
+const DefaultMaxAssetBytes = 32 << 20
+
+// DefaultAssetTypes are the media types accepted as assets when
+// Server.AssetTypes is empty.
+var DefaultAssetTypes = []string{
+	"image/png", "image/jpeg", "image/webp", "image/gif",
+	"application/pdf", "text/plain", "text/markdown",
+}
+
+// maxAssetsPerRequest bounds the files of one upload request.
+const maxAssetsPerRequest = 20
+
+// sniffLen is how much of a file http.DetectContentType looks at.
+const sniffLen = 512
+
+var errAssetTooLarge = errors.New("asset too large")
+
+// Asset is one stored file in the response to POST
+// /api/v1/content/{id}/assets.
+type Asset struct {
+	AssetID     string `json:"asset_id"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+}
+
+// uploadAssets attaches the files of a multipart/form-data request to one
+// of the caller's jobs. Each file part is streamed to the backend as it
+// is read; form fields without a filename are ignored. A file's declared
+// type must be allowed and agree with its sniffed contents. Files stored
+// before a failing one stay attached to the job.
+func (s *Server) uploadAssets(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := router.Param(r, "id")
+	job, err := s.Backend.GetJobStatus(ctx, id)
+	if err != nil {
+		apierror.Write(w, r, apierror.FromRPC(err))
+		return
+	}
+	claims := gateway.ClaimsFromContext(ctx)
+	if claims == nil || job.OwnerID != claims.Subject {
+		forbid(w, r, "the job belongs to another user")
+		return
+	}
+	mr, err := r.MultipartReader()
+	if err != nil {
+		apierror.Write(w, r, apierror.New(http.StatusUnsupportedMediaType, apierror.CodeUnsupportedMediaType, "the request body must be multipart/form-data"))
+		return
+	}
+
+	assets := []Asset{}
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			apierror.Write(w, r, s.uploadReadError(err))
+			return
+		}
+		if part.FileName() == "" {
+			part.Close()
+			continue
+		}
+		if len(assets) == maxAssetsPerRequest {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.CodeTooManyFiles,
+				"at most "+strconv.Itoa(maxAssetsPerRequest)+" files may be uploaded at once"))
+			return
+		}
+
+		body := bufio.NewReaderSize(part, sniffLen)
+		head, err := body.Peek(sniffLen)
+		if err != nil && !errors.Is(err, io.EOF) {
+			apierror.Write(w, r, s.uploadReadError(err))
+			return
+		}
+		ctype, ok := s.assetType(part.Header.Get("Content-Type"), head)
+		if !ok {
+			apierror.Write(w, r, apierror.New(http.StatusUnsupportedMediaType, apierror.CodeUnsupportedAssetType,
+				"file "+strconv.Quote(part.FileName())+" is not one of the allowed types: "+strings.Join(s.assetTypes(), ", ")))
+			return
+		}
+		src := &assetReader{r: body, left: s.maxAssetBytes()}
+		stored, err := s.Backend.UploadAsset(ctx, &backend.UploadAssetRequest{
+			JobID:       id,
+			OwnerID:     claims.Subject,
+			Filename:    part.FileName(),
+			ContentType: ctype,
+		}, src)
+		if src.err != nil {
+			apierror.Write(w, r, s.uploadReadError(src.err))
+			return
+		}
+		if err != nil {
+			apierror.Write(w, r, apierror.FromRPC(err))
+			return
+		}
+		assets = append(assets, Asset{
+			AssetID:     stored.AssetID,
+			Filename:    stored.Filename,
+			ContentType: stored.ContentType,
+			Size:        stored.Size,
+		})
+	}
+	if len(assets) == 0 {
+		apierror.Write(w, r, apierror.Validation(apierror.FieldError{Field: "files", Message: "must include at least one file"}))
+		return
+	}
+	respond.Write(w, r, http.StatusCreated, assets)
+}
+
+// assetType returns the media type of a file declared as declared whose
+// contents start with head, provided it is allowed. A declared type must
+// agree with the sniffed one, so a renamed executable cannot pass as an
+// image; text types all sniff as text/plain. Without a declared type the
+// sniffed one is used.
+func (s *Server) assetType(declared string, head []byte) (string, bool) {
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	ctype, _, err := mime.ParseMediaType(declared)
+	if err != nil || ctype == "application/octet-stream" {
+		ctype = sniffed
+	}
+	if ctype != sniffed && !(strings.HasPrefix(ctype, "text/") && sniffed == "text/plain") {
+		return "", false
+	}
+	for _, allowed := range s.assetTypes() {
+		if strings.EqualFold(ctype, allowed) {
+			return ctype, true
+		}
+	}
+	return "", false
+}
+
+// uploadReadError reports a failure reading the upload itself, as opposed
+// to storing it.
+func (s *Server) uploadReadError(err error) *apierror.Error {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		return apierror.BodyTooLarge(tooLarge.Limit)
+	case errors.Is(err, errAssetTooLarge):
+		return apierror.New(http.StatusRequestEntityTooLarge, apierror.CodeAssetTooLarge,
+			"each file must be at most "+strconv.FormatInt(s.maxAssetBytes(), 10)+" bytes")
+	default:
+		return apierror.New(http.StatusBadRequest, apierror.CodeInvalidMultipart, "could not read the multipart request body")
+	}
+}
+
+// assetReader reads one file, failing once it passes the per-file limit.
+// It remembers the error so the handler can tell a bad upload from a
+// backend failure.
+type assetReader struct {
+	r    io.Reader
+	left int64
+	err  error
+}
+
+func (a *assetReader) Read(p []byte) (int, error) {
+	if int64(len(p)) > a.left+1 {
+		p = p[:a.left+1]
+	}
+	n, err := a.r.Read(p)
+	if int64(n) > a.left {
+		a.err = errAssetTooLarge
+		return 0, a.err
+	}
+	a.left -= int64(n)
+	if err != nil && !errors.Is(err, io.EOF) {
+		a.err = err
+	}
+	return n, err
+}
+
+func (s *Server) maxAssetBytes() int64 {
+	if s.MaxAssetBytes <= 0 {
+		return DefaultMaxAssetBytes
+	}
+	return s.MaxAssetBytes
+}
+
+func (s *Server) assetTypes() []string {
+	if len(s.AssetTypes) == 0 {
+		return DefaultAssetTypes
+	}
+	return s.AssetTypes
+}
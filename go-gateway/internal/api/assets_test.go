@@ -0,0 +1,230 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/backend"
+	"github.com/content-factory/go-gateway/internal/rpc"
+	pb "github.com/content-factory/go-gateway/protos/content_factory"
+)
+
+func (f *fakeBackend) UploadAsset(ctx context.Context, req *backend.UploadAssetRequest, body io.Reader) (*backend.Asset, error) {
+	return backend.New(f.uploads).UploadAsset(ctx, req, body)
+}
+
+// uploadConn stores the asset uploads of backend.Client in memory.
+type uploadConn struct {
+	started []backend.UploadAssetRequest
+	data    [][]byte
+	chunks  int
+}
+
+func (c *uploadConn) Invoke(ctx context.Context, method string, req, resp any) error {
+	switch method {
+	case backend.MethodStartAssetUpload:
+		r := req.(*pb.StartAssetUploadRequest)
+		c.started = append(c.started, backend.UploadAssetRequest{
+			JobID: r.GetJobId(), OwnerID: r.GetOwnerId(), Filename: r.GetFilename(), ContentType: r.GetContentType(),
+		})
+		c.data = append(c.data, nil)
+		resp.(*pb.StartAssetUploadResponse).UploadId = strconv.Itoa(len(c.started) - 1)
+	case backend.MethodUploadAssetChunk:
+		chunk := req.(*pb.AssetChunk)
+		i, _ := strconv.Atoi(chunk.GetUploadId())
+		if chunk.GetOffset() != int64(len(c.data[i])) {
+			return rpc.Errorf(rpc.InvalidArgument, "chunk at %d, have %d bytes", chunk.GetOffset(), len(c.data[i]))
+		}
+		c.data[i] = append(c.data[i], chunk.GetData()...)
+		c.chunks++
+	case backend.MethodFinishAssetUpload:
+		finish := req.(*pb.FinishAssetUploadRequest)
+		i, _ := strconv.Atoi(finish.GetUploadId())
+		if finish.GetSize() != int64(len(c.data[i])) {
+			return rpc.Errorf(rpc.InvalidArgument, "size %d, have %d bytes", finish.GetSize(), len(c.data[i]))
+		}
+		r, asset := c.started[i], resp.(*pb.Asset)
+		asset.AssetId, asset.JobId, asset.Filename, asset.ContentType, asset.Size = "asset-"+finish.GetUploadId(), r.JobID, r.Filename, r.ContentType, finish.GetSize()
+	default:
+		return rpc.Errorf(rpc.Unimplemented, "unused")
+	}
+	return nil
+}
+
+func (c *uploadConn) NewStream(ctx context.Context, method string, req any) (rpc.ClientStream, error) {
+	return nil, rpc.Errorf(rpc.Unimplemented, "unused")
+}
+
+var pngHeader = "\x89PNG\r\n\x1a\n"
+
+type uploadFile struct {
+	name, contentType, data string
+}
+
+func assetRequest(t *testing.T, jobID string, files ...uploadFile) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("note", "ignored")
+	for _, f := range files {
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", `form-data; name="file"; filename="`+f.name+`"`)
+		h.Set("Content-Type", f.contentType)
+		part, err := mw.CreatePart(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write([]byte(f.data))
+	}
+	mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/content/"+jobID+"/assets", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func assetBackend() *fakeBackend {
+	be := jobBackend()
+	be.uploads = &uploadConn{}
+	return be
+}
+
+func TestUploadAssets(t *testing.T) {
+	be := assetBackend()
+	image := pngHeader + strings.Repeat("x", 3*backend.UploadChunkSize)
+	rec := serveRequest(t, &Server{Backend: be}, assetRequest(t, jobRunning,
+		uploadFile{"ref.png", "image/png", image},
+		uploadFile{"brief.md", "text/markdown", "# Brief\nMake it short."},
+	))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	var got []Asset
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := []Asset{
+		{AssetID: "asset-0", Filename: "ref.png", ContentType: "image/png", Size: int64(len(image))},
+		{AssetID: "asset-1", Filename: "brief.md", ContentType: "text/markdown", Size: 22},
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("assets = %+v, want %+v", got, want)
+	}
+	if string(be.uploads.data[0]) != image {
+		t.Error("stored image differs from the upload")
+	}
+	if be.uploads.chunks < 4 {
+		t.Errorf("image sent in %d chunks, want it split", be.uploads.chunks)
+	}
+	if r := be.uploads.started[0]; r.JobID != jobRunning || r.OwnerID != "user-1" {
+		t.Errorf("upload request = %+v", r)
+	}
+}
+
+func TestUploadAssetsRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		server Server
+		req    func(t *testing.T) *http.Request
+		status int
+		code   apierror.Code
+	}{
+		{
+			name: "type not allowed",
+			req: func(t *testing.T) *http.Request {
+				return assetRequest(t, jobRunning, uploadFile{"clip.mp4", "video/mp4", "\x00\x00\x00\x18ftypmp42"})
+			},
+			status: http.StatusUnsupportedMediaType,
+			code:   "unsupported_asset_type",
+		},
+		{
+			name: "type disagrees with contents",
+			req: func(t *testing.T) *http.Request {
+				return assetRequest(t, jobRunning, uploadFile{"ref.png", "image/png", "MZ\x90\x00not an image"})
+			},
+			status: http.StatusUnsupportedMediaType,
+			code:   "unsupported_asset_type",
+		},
+		{
+			name:   "file too large",
+			server: Server{MaxAssetBytes: 16},
+			req: func(t *testing.T) *http.Request {
+				return assetRequest(t, jobRunning, uploadFile{"notes.txt", "text/plain", strings.Repeat("a", 17)})
+			},
+			status: http.StatusRequestEntityTooLarge,
+			code:   "asset_too_large",
+		},
+		{
+			name:   "request too large",
+			server: Server{MaxUploadBytes: 64},
+			req: func(t *testing.T) *http.Request {
+				req := assetRequest(t, jobRunning, uploadFile{"notes.txt", "text/plain", strings.Repeat("a", 100)})
+				req.ContentLength = -1 // found only while reading
+				return req
+			},
+			status: http.StatusRequestEntityTooLarge,
+			code:   "body_too_large",
+		},
+		{
+			name: "not multipart",
+			req: func(t *testing.T) *http.Request {
+				req := httptest.NewRequest(http.MethodPost, "/api/v1/content/"+jobRunning+"/assets", strings.NewReader(`{}`))
+				req.Header.Set("Content-Type", "application/json")
+				return req
+			},
+			status: http.StatusUnsupportedMediaType,
+			code:   "unsupported_media_type",
+		},
+		{
+			name:   "no files",
+			req:    func(t *testing.T) *http.Request { return assetRequest(t, jobRunning) },
+			status: http.StatusUnprocessableEntity,
+			code:   "invalid_request",
+		},
+		{
+			name: "another user's job",
+			req: func(t *testing.T) *http.Request {
+				return assetRequest(t, jobTheirs, uploadFile{"notes.txt", "text/plain", "hello"})
+			},
+			status: http.StatusForbidden,
+			code:   "forbidden",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			be := assetBackend()
+			s := tt.server
+			s.Backend = be
+			rec := serveRequest(t, &s, tt.req(t))
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d; body %s", rec.Code, tt.status, rec.Body)
+			}
+			var body apierror.Envelope
+			json.Unmarshal(rec.Body.Bytes(), &body)
+			if body.Error.Code != tt.code {
+				t.Errorf("error = %q, want %q", body.Error.Code, tt.code)
+			}
+		})
+	}
+}
+
+func TestUploadAssetsChecksContentLengthFirst(t *testing.T) {
+	be := assetBackend()
+	req := assetRequest(t, jobRunning, uploadFile{"notes.txt", "text/plain", strings.Repeat("a", 100)})
+	rec := serveRequest(t, &Server{Backend: be, MaxUploadBytes: 64}, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	if len(be.uploads.started) != 0 {
+		t.Error("upload started despite an oversized Content-Length")
+	}
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/jsonbody"
+	"github.com/content-factory/go-gateway/internal/respond"
+)
+
+const (
+	// DefaultMaxBatchItems caps batch submissions when
+	// Server.MaxBatchItems is zero.
+	DefaultMaxBatchItems = 100
+	// DefaultBatchConcurrency bounds the backend calls a batch makes at
+	// once when Server.BatchConcurrency is zero.
+	DefaultBatchConcurrency = 8
+)
+
+// BatchResult is the outcome of one request of a batch submission: the
+// accepted job, or the error a single submission would have got.
+type BatchResult struct {
+	Index  int             `json:"index"`
+	Status int             `json:"status"`
+	JobID  string          `json:"job_id,omitempty"`
+	State  string          `json:"job_status,omitempty"`
+	Error  *apierror.Error `json:"error,omitempty"`
+}
+
+func (r *BatchResult) fail(e *apierror.Error) {
+	r.Status, r.Error = e.Status, e
+}
+
+// createContentBatch serves POST /api/v1/content/batch. Every request in
+// the array is validated and submitted on its own, so one bad request does
+// not fail the others; the 207 response lists the results in input order.
+func (s *Server) createContentBatch(w http.ResponseWriter, r *http.Request) {
+	var items []json.RawMessage
+	if e := jsonbody.Decode(r, &items, jsonbody.Options{What: "a JSON array of content requests"}); e != nil {
+		apierror.Write(w, r, e)
+		return
+	}
+	if n, limit := len(items), s.maxBatchItems(); n == 0 || n > limit {
+		apierror.Write(w, r, apierror.New(http.StatusUnprocessableEntity, apierror.CodeInvalidBatch,
+			fmt.Sprintf("a batch must hold between 1 and %d requests, got %d", limit, n)))
+		return
+	}
+
+	results := make([]BatchResult, len(items))
+	reqs := make([]*ContentRequest, len(items))
+	for i, item := range items {
+		results[i].Index = i
+		req, err := s.decodeBatchItem(r.Context(), item)
+		if err != nil {
+			results[i].fail(err)
+			continue
+		}
+		reqs[i] = req
+	}
+
+	ctx := r.Context()
+	todo := make(chan int)
+	var wg sync.WaitGroup
+	for range min(s.batchConcurrency(), len(items)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range todo {
+				accepted, err := s.submit(ctx, reqs[i], "")
+				if err != nil {
+					results[i].fail(submitError(err))
+					continue
+				}
+				results[i].Status = http.StatusAccepted
+				results[i].JobID, results[i].State = accepted.JobID, accepted.Status
+			}
+		}()
+	}
+	for i, req := range reqs {
+		if req != nil {
+			todo <- i
+		}
+	}
+	close(todo)
+	wg.Wait()
+
+	for _, res := range results {
+		if res.Status == http.StatusAccepted {
+			s.invalidate(ctx, "/api/v1/jobs")
+			break
+		}
+	}
+	respond.Write(w, r, http.StatusMultiStatus, results)
+}
+
+// decodeBatchItem decodes and validates one request of a batch. When the
+// request is unusable it returns the error a single submission would have
+// been rejected with.
+func (s *Server) decodeBatchItem(ctx context.Context, item json.RawMessage) (*ContentRequest, *apierror.Error) {
+	var req ContentRequest
+	if e := jsonbody.DecodeBytes(item, &req, s.bodyOptions()); e != nil {
+		return nil, e
+	}
+	if errs := s.validate(ctx, &req); errs != nil {
+		return nil, apierror.Validation(errs...)
+	}
+	return &req, nil
+}
+
+func (s *Server) maxBatchItems() int {
+	if s.MaxBatchItems <= 0 {
+		return DefaultMaxBatchItems
+	}
+	return s.MaxBatchItems
+}
+
+func (s *Server) batchConcurrency() int {
+	if s.BatchConcurrency <= 0 {
+		return DefaultBatchConcurrency
+	}
+	return s.BatchConcurrency
+}
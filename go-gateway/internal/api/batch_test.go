@@ -0,0 +1,121 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/backend"
+	"github.com/content-factory/go-gateway/internal/rpc"
+)
+
+// batchBackend accepts jobs concurrently, failing those whose topic starts
+// with "unavailable", and records the most calls it saw at once.
+type batchBackend struct {
+	fakeBackend
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+	topics   []string
+}
+
+func (b *batchBackend) CreateContent(ctx context.Context, req *backend.CreateContentRequest) (*backend.CreateContentResponse, error) {
+	b.mu.Lock()
+	b.inFlight++
+	b.peak = max(b.peak, b.inFlight)
+	b.topics = append(b.topics, req.Topic)
+	b.mu.Unlock()
+	time.Sleep(5 * time.Millisecond)
+	b.mu.Lock()
+	b.inFlight--
+	b.mu.Unlock()
+	if strings.HasPrefix(req.Topic, "unavailable") {
+		return nil, rpc.Errorf(rpc.Unavailable, "backend down")
+	}
+	return &backend.CreateContentResponse{ContentID: req.JobID}, nil
+}
+
+func serveBatch(t *testing.T, s *Server, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/content/batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return serveRequest(t, s, req)
+}
+
+func TestBatchReportsEachItem(t *testing.T) {
+	be := &batchBackend{}
+	items := []string{
+		`{"prompt":"otters","format":"video"}`,
+		`{"prompt":"","format":"video"}`,
+		`{"prompt":"unavailable otters","format":"audio"}`,
+		`"not an object"`,
+	}
+	for range 6 {
+		items = append(items, `{"prompt":"more otters","format":"article"}`)
+	}
+	rec := serveBatch(t, &Server{Backend: be, BatchConcurrency: 3}, "["+strings.Join(items, ",")+"]")
+	if rec.Code != http.StatusMultiStatus {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	var results []BatchResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(items) {
+		t.Fatalf("got %d results for %d items", len(results), len(items))
+	}
+	for i, res := range results {
+		if res.Index != i {
+			t.Errorf("results[%d].index = %d", i, res.Index)
+		}
+	}
+	if r := results[0]; r.Status != http.StatusAccepted || r.JobID == "" || r.State != "queued" || r.Error != nil {
+		t.Errorf("valid item = %+v", r)
+	}
+	if r := results[1]; r.Status != http.StatusUnprocessableEntity || r.Error == nil || len(r.Error.Fields) != 1 || r.Error.Fields[0].Field != "prompt" {
+		t.Errorf("invalid item = %+v", r)
+	}
+	if r := results[2]; r.Status != http.StatusServiceUnavailable || r.Error == nil || r.Error.Code != "backend_unavailable" || r.JobID != "" {
+		t.Errorf("failed item = %+v", r)
+	}
+	if r := results[3]; r.Status != http.StatusBadRequest || r.Error == nil || r.Error.Code != "invalid_json" {
+		t.Errorf("malformed item = %+v", r)
+	}
+	if len(be.topics) != 8 {
+		t.Errorf("backend got %d submissions, want 8", len(be.topics))
+	}
+	if be.peak > 3 {
+		t.Errorf("%d submissions in flight, want at most 3", be.peak)
+	}
+}
+
+func TestBatchRejectsBadShape(t *testing.T) {
+	one := `{"prompt":"otters","format":"video"}`
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		code   string
+	}{
+		{"not an array", one, http.StatusBadRequest, "invalid_json"},
+		{"empty", `[]`, http.StatusUnprocessableEntity, "invalid_batch"},
+		{"too long", "[" + one + "," + one + "," + one + "]", http.StatusUnprocessableEntity, "invalid_batch"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			be := &batchBackend{}
+			rec := serveBatch(t, &Server{Backend: be, MaxBatchItems: 2}, tt.body)
+			if rec.Code != tt.status || !strings.Contains(rec.Body.String(), `"code":"`+tt.code+`"`) {
+				t.Errorf("response = %d %s, want %d %s", rec.Code, rec.Body, tt.status, tt.code)
+			}
+			if len(be.topics) != 0 {
+				t.Error("backend called for a rejected batch")
+			}
+		})
+	}
+}
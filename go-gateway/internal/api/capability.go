@@ -0,0 +1,56 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/health"
+)
+
+// Capabilities the gateway reports in /readyz, see health.Checker.
+const (
+	// CapabilitySubmission is submitting content jobs, which the handlers
+	// refuse while it is unavailable.
+	CapabilitySubmission = "content_submission"
+	// CapabilityReads is reading jobs and their content.
+	CapabilityReads = "job_reads"
+)
+
+// capabilityRetryAfter is the Retry-After, in seconds, of a request
+// refused because its capability is unavailable.
+const capabilityRetryAfter = 10
+
+// Capabilities says whether a capability of the gateway is available.
+// *health.Checker implements it.
+type Capabilities interface {
+	// Capability returns nil if capability name is available, or else an
+	// error saying why not.
+	Capability(ctx context.Context, name string) error
+}
+
+// available refuses requests to h with 503 while the capability name is
+// unavailable.
+func (s *Server) available(name string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.Capabilities == nil {
+			h.ServeHTTP(w, r)
+			return
+		}
+		err := s.Capabilities.Capability(r.Context(), name)
+		if err == nil {
+			h.ServeHTTP(w, r)
+			return
+		}
+		reason := err.Error()
+		var unavailable *health.UnavailableError
+		if errors.As(err, &unavailable) {
+			reason = unavailable.Reason
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(capabilityRetryAfter))
+		apierror.Write(w, r, apierror.New(http.StatusServiceUnavailable, apierror.CodeCapabilityUnavailable,
+			name+" is temporarily unavailable: "+reason))
+	})
+}
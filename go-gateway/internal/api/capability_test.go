@@ -0,0 +1,63 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/content-factory/go-gateway/internal/health"
+)
+
+// fixedCapabilities reports each capability it holds unavailable, for
+// the reason it maps it to.
+type fixedCapabilities map[string]string
+
+func (c fixedCapabilities) Capability(ctx context.Context, name string) error {
+	if reason, ok := c[name]; ok {
+		return &health.UnavailableError{Capability: name, Reason: reason}
+	}
+	return nil
+}
+
+func TestSubmissionUnavailable(t *testing.T) {
+	be := jobBackend()
+	s := &Server{Backend: be, Capabilities: fixedCapabilities{CapabilitySubmission: "queue: queue is saturated (900 of 1000 jobs)"}}
+	body := `{"prompt":"a video about otters","format":"video"}`
+
+	submissions := map[string]*http.Request{
+		"content": httptest.NewRequest(http.MethodPost, "/api/v1/content", strings.NewReader(body)),
+		"batch":   httptest.NewRequest(http.MethodPost, "/api/v1/content/batch", strings.NewReader("["+body+"]")),
+		"retry":   httptest.NewRequest(http.MethodPost, "/api/v1/jobs/"+jobBroken+"/retry", nil),
+	}
+	for name, req := range submissions {
+		req.Header.Set("Content-Type", "application/json")
+		rec := serveRequest(t, s, req)
+		var got struct {
+			Error struct{ Code, Message string }
+		}
+		json.Unmarshal(rec.Body.Bytes(), &got)
+		if rec.Code != http.StatusServiceUnavailable || got.Error.Code != "capability_unavailable" {
+			t.Errorf("%s: status = %d, body %s", name, rec.Code, rec.Body)
+			continue
+		}
+		if !strings.Contains(got.Error.Message, "queue is saturated") || rec.Header().Get("Retry-After") == "" {
+			t.Errorf("%s: message %q, Retry-After %q", name, got.Error.Message, rec.Header().Get("Retry-After"))
+		}
+	}
+	if be.got != nil {
+		t.Errorf("backend got submission %+v", be.got)
+	}
+
+	// Dry runs create nothing, and reads are another capability.
+	dry := httptest.NewRequest(http.MethodPost, "/api/v1/content?dry_run=true", strings.NewReader(body))
+	dry.Header.Set("Content-Type", "application/json")
+	if rec := serveRequest(t, s, dry); rec.Code != http.StatusOK {
+		t.Errorf("dry run: status = %d, body %s", rec.Code, rec.Body)
+	}
+	if rec := serveRequest(t, s, httptest.NewRequest(http.MethodGet, "/api/v1/jobs/"+jobDone, nil)); rec.Code != http.StatusOK {
+		t.Errorf("job read: status = %d, body %s", rec.Code, rec.Body)
+	}
+}
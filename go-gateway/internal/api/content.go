@@ -0,0 +1,291 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/audit"
+	"github.com/content-factory/go-gateway/internal/backend"
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/jsonbody"
+	"github.com/content-factory/go-gateway/internal/metrics"
+	"github.com/content-factory/go-gateway/internal/middleware"
+	"github.com/content-factory/go-gateway/internal/openapi"
+	"github.com/content-factory/go-gateway/internal/queue"
+	"github.com/content-factory/go-gateway/internal/respond"
+	"github.com/content-factory/go-gateway/internal/tenant"
+	"github.com/content-factory/go-gateway/internal/uuid"
+	"github.com/content-factory/go-gateway/internal/webhook"
+)
+
+// maxPromptLength caps prompts in characters. ContentRequest's tags
+// repeat it.
+const maxPromptLength = 4000
+
+// Formats lists the content formats the pipeline can produce.
+// ContentRequest's tags repeat them.
+var Formats = []string{"video", "audio", "avatar", "article", "social_post"}
+
+// ContentRequest is the body of POST /api/v1/content.
+type ContentRequest struct {
+	Prompt  string         `json:"prompt" validate:"required,max=4000"`
+	Format  string         `json:"format" validate:"required,oneof=video audio avatar article social_post"`
+	Options map[string]any `json:"options,omitempty"`
+	// WebhookURL, if set, is POSTed the job's outcome when it finishes,
+	// see package webhook.
+	WebhookURL string `json:"webhook_url,omitempty"`
+}
+
+// Validate returns every problem with the request, or nil: those with
+// its fields' tags, then those with its options. It trims string options
+// as a side effect.
+func (c *ContentRequest) Validate() []apierror.FieldError {
+	return append(bodyErrors(c), validateOptions(c.Options)...)
+}
+
+// DescribeSchema implements openapi.Describer with the constraints
+// Validate enforces.
+func (ContentRequest) DescribeSchema(s *openapi.Schema) {
+	maxLen := maxPromptLength
+	s.Properties["prompt"].MaxLength = &maxLen
+	for _, f := range Formats {
+		s.Properties["format"].Enum = append(s.Properties["format"].Enum, f)
+	}
+	opts := &openapi.Schema{Type: "object", Properties: make(map[string]*openapi.Schema), AdditionalProperties: false}
+	for name, spec := range optionSchema {
+		opts.Properties[name] = spec.schema()
+	}
+	s.Properties["options"] = opts
+	webhookMax := webhook.MaxURLLength
+	s.Properties["webhook_url"].Format = "uri"
+	s.Properties["webhook_url"].MaxLength = &webhookMax
+}
+
+// validate returns every problem with req, submitted for a job by the
+// caller ctx belongs to.
+func (s *Server) validate(ctx context.Context, req *ContentRequest) []apierror.FieldError {
+	errs := append(req.Validate(), tenantErrors(ctx, req)...)
+	return append(errs, s.validateWebhook(ctx, req)...)
+}
+
+// tenantErrors returns the problems with req that come from the settings
+// of the tenant the request acts for.
+func tenantErrors(ctx context.Context, req *ContentRequest) []apierror.FieldError {
+	t := gateway.TenantFromContext(ctx)
+	if !validFormat(req.Format) || tenant.AllowsFormat(t, req.Format) {
+		return nil
+	}
+	return []apierror.FieldError{{Field: "format", Message: "is not available to this tenant; it may request " + strings.Join(t.Formats, ", ")}}
+}
+
+func validFormat(f string) bool {
+	for _, allowed := range Formats {
+		if f == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// JobAccepted is the 202 response to a content submission.
+type JobAccepted struct {
+	JobID  string `json:"job_id"`
+	Status string `json:"status"`
+	// RetryOf is the failed job a retry resubmits.
+	RetryOf string `json:"retry_of,omitempty"`
+}
+
+// DryRunHeader, like the dry_run query parameter, asks POST
+// /api/v1/content to validate and price a request without submitting it.
+const DryRunHeader = "X-Dry-Run"
+
+var submissions = metrics.NewCounterVec("gateway_content_submissions_total",
+	"Content submissions that passed validation, by mode: live, dry_run, stream or retry.",
+	"mode")
+
+// ContentEstimate is the 200 response to a dry run: the request as it
+// would be submitted, and what it is expected to cost.
+type ContentEstimate struct {
+	DryRun          bool           `json:"dry_run"`
+	Request         ContentRequest `json:"request"`
+	CostUSD         float64        `json:"estimated_cost_usd"`
+	Tokens          int64          `json:"estimated_tokens"`
+	DurationSeconds int32          `json:"estimated_duration_seconds"`
+}
+
+// dryRun reports whether r asks for a dry run, with ?dry_run=true or
+// X-Dry-Run: true. ok is false if either is not a boolean.
+func dryRun(r *http.Request) (dry, ok bool) {
+	for _, v := range []string{r.URL.Query().Get("dry_run"), r.Header.Get(DryRunHeader)} {
+		if v == "" {
+			continue
+		}
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return false, false
+		}
+		dry = dry || b
+	}
+	return dry, true
+}
+
+// contentHandler serves POST /api/v1/content. Dry runs, and requests
+// whose dry-run flag is malformed, skip the idempotency and availability
+// checks: they create nothing, and a dry run must not be replayed for the
+// real submission that follows it.
+func (s *Server) contentHandler() http.Handler {
+	submit := s.available(CapabilitySubmission, s.idempotent(http.HandlerFunc(s.createContent)))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if dry, ok := dryRun(r); dry || !ok {
+			s.createContent(w, r)
+			return
+		}
+		submit.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) createContent(w http.ResponseWriter, r *http.Request) {
+	dry, ok := dryRun(r)
+	if !ok {
+		apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.CodeInvalidDryRun, "dry_run and "+DryRunHeader+" must be true or false"))
+		return
+	}
+	var req ContentRequest
+	if e := jsonbody.Decode(r, &req, s.bodyOptions()); e != nil {
+		apierror.Write(w, r, e)
+		return
+	}
+	ctx := r.Context()
+	if errs := s.validate(ctx, &req); errs != nil {
+		apierror.Write(w, r, apierror.Validation(errs...))
+		return
+	}
+
+	if dry {
+		s.estimate(w, r, &req)
+		return
+	}
+	submissions.With("live").Inc()
+	accepted, err := s.submit(ctx, &req, "")
+	if err != nil {
+		e := submitError(err)
+		if e.Code == apierror.CodeQueueFull {
+			w.Header().Set("Retry-After", strconv.Itoa(queueRetryAfter))
+		}
+		apierror.Write(w, r, e)
+		return
+	}
+
+	// The new job belongs at the top of the submitter's job list.
+	s.invalidate(ctx, "/api/v1/jobs")
+
+	w.Header().Set("Location", "/api/v1/jobs/"+accepted.JobID)
+	respond.Write(w, r, http.StatusAccepted, accepted)
+}
+
+// estimate answers a dry run with the backend's estimate for req.
+func (s *Server) estimate(w http.ResponseWriter, r *http.Request, req *ContentRequest) {
+	ctx := r.Context()
+	middleware.AddLogAttrs(ctx, slog.Bool("dry_run", true))
+	submissions.With("dry_run").Inc()
+	est, err := s.Backend.EstimateContent(ctx, backendRequest(ctx, req, ""))
+	if err != nil {
+		apierror.Write(w, r, apierror.FromRPC(err))
+		return
+	}
+	respond.Write(w, r, http.StatusOK, ContentEstimate{
+		DryRun:          true,
+		Request:         *req,
+		CostUSD:         est.CostUSD,
+		Tokens:          est.Tokens,
+		DurationSeconds: est.DurationSeconds,
+	})
+}
+
+// backendRequest is the backend's form of req, submitted by the caller as
+// jobID.
+func backendRequest(ctx context.Context, req *ContentRequest, jobID string) *backend.CreateContentRequest {
+	return &backend.CreateContentRequest{
+		JobID:   jobID,
+		Topic:   req.Prompt,
+		Format:  req.Format,
+		Options: req.Options,
+		OwnerID: principal(ctx),
+	}
+}
+
+// queueRetryAfter is the Retry-After, in seconds, of a submission refused
+// because the queue is full.
+const queueRetryAfter = 5
+
+// submit queues the job req describes for the caller: on s.Queue if there
+// is one, for its workers to hand to the backend, or else directly with
+// the backend. retryOf is the failed job it retries, if any. The outcome
+// is recorded in the audit log.
+func (s *Server) submit(ctx context.Context, req *ContentRequest, retryOf string) (JobAccepted, error) {
+	accepted, err := s.enqueueOrCreate(ctx, req, retryOf)
+	attrs := []slog.Attr{slog.String("format", req.Format)}
+	if retryOf != "" {
+		attrs = append(attrs, slog.String("retry_of", retryOf))
+	}
+	if err != nil {
+		audit.Record(ctx, audit.ActionJobCreate, audit.OutcomeFailure, append(attrs, slog.String("error", err.Error()))...)
+		return accepted, err
+	}
+	audit.Record(ctx, audit.ActionJobCreate, audit.OutcomeSuccess, append(attrs, slog.String("job_id", accepted.JobID))...)
+	return accepted, nil
+}
+
+// enqueueOrCreate does the work of submit.
+func (s *Server) enqueueOrCreate(ctx context.Context, req *ContentRequest, retryOf string) (JobAccepted, error) {
+	jobID := uuid.New()
+	breq := backendRequest(ctx, req, jobID)
+	breq.RetryOf = retryOf
+	if s.Queue != nil {
+		job := &queue.Job{Request: *breq, Tenant: gateway.TenantID(ctx), EnqueuedAt: time.Now().UTC()}
+		if err := queue.Enqueue(ctx, s.Queue, job); err != nil {
+			if !errors.Is(err, queue.ErrFull) {
+				err = fmt.Errorf("%w: %w", errQueueUnavailable, err)
+			}
+			return JobAccepted{}, err
+		}
+		s.watchWebhook(ctx, jobID, req)
+		return JobAccepted{JobID: jobID, Status: "queued", RetryOf: retryOf}, nil
+	}
+	resp, err := s.Backend.CreateContent(ctx, breq)
+	if err != nil {
+		return JobAccepted{}, err
+	}
+	if s.Jobs != nil {
+		s.Jobs.Start(ctx, jobID)
+	}
+	s.watchWebhook(ctx, jobID, req)
+	status := resp.Status
+	if status == "" {
+		status = "queued"
+	}
+	return JobAccepted{JobID: jobID, Status: status, RetryOf: retryOf}, nil
+}
+
+// errQueueUnavailable marks a submission the queue failed to take for a
+// reason other than being full, such as Redis being down.
+var errQueueUnavailable = errors.New("submission queue unavailable")
+
+// submitError is the response to a submission that failed with err.
+func submitError(err error) *apierror.Error {
+	switch {
+	case errors.Is(err, queue.ErrFull):
+		return apierror.New(http.StatusTooManyRequests, apierror.CodeQueueFull, "the submission queue is full; retry later")
+	case errors.Is(err, errQueueUnavailable):
+		slog.Error("failed to queue content job", "error", err)
+		return apierror.New(http.StatusServiceUnavailable, apierror.CodeQueueUnavailable, "the submission queue is unavailable")
+	}
+	return apierror.FromRPC(err)
+}
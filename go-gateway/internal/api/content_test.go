@@ -0,0 +1,591 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/backend"
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/idempotency"
+	"github.com/content-factory/go-gateway/internal/jobs"
+	"github.com/content-factory/go-gateway/internal/msgpack"
+	"github.com/content-factory/go-gateway/internal/queue"
+	"github.com/content-factory/go-gateway/internal/routeconf"
+	"github.com/content-factory/go-gateway/internal/router"
+	"github.com/content-factory/go-gateway/internal/rpc"
+	"github.com/content-factory/go-gateway/internal/webhook"
+)
+
+type fakeBackend struct {
+	got       *backend.CreateContentRequest
+	estimated *backend.CreateContentRequest
+	deadline  time.Time
+	resp      *backend.CreateContentResponse
+	err       error
+	jobs      map[string]*backend.JobStatusResponse
+	requests  map[string]*backend.CreateContentRequest
+	gotList   *backend.ListJobsRequest
+	cancelled *backend.CancelJobRequest
+	files     map[string]*fakeFile
+	uploads   *uploadConn
+	generated *fakeGeneration
+}
+
+func (f *fakeBackend) CreateContent(ctx context.Context, req *backend.CreateContentRequest) (*backend.CreateContentResponse, error) {
+	f.got = req
+	f.deadline, _ = ctx.Deadline()
+	if f.err != nil {
+		return nil, f.err
+	}
+	if f.resp != nil {
+		return f.resp, nil
+	}
+	return &backend.CreateContentResponse{ContentID: req.JobID}, nil
+}
+
+func (f *fakeBackend) EstimateContent(ctx context.Context, req *backend.CreateContentRequest) (*backend.ContentEstimate, error) {
+	f.estimated = req
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &backend.ContentEstimate{CostUSD: 0.42, Tokens: 1200, DurationSeconds: 90}, nil
+}
+
+func (f *fakeBackend) GetJobStatus(ctx context.Context, jobID string) (*backend.JobStatusResponse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	job, ok := f.jobs[jobID]
+	if !ok {
+		return nil, rpc.Errorf(rpc.NotFound, "no job %s", jobID)
+	}
+	return job, nil
+}
+
+func (f *fakeBackend) GetJobRequest(ctx context.Context, jobID string) (*backend.CreateContentRequest, error) {
+	req, ok := f.requests[jobID]
+	if !ok {
+		return nil, rpc.Errorf(rpc.NotFound, "no job %s", jobID)
+	}
+	return req, nil
+}
+
+// CancelJob cancels unfinished jobs that are unchanged since
+// req.IfUpdatedAt, as the service does.
+func (f *fakeBackend) CancelJob(ctx context.Context, req *backend.CancelJobRequest) (*backend.JobStatusResponse, error) {
+	f.cancelled = req
+	job, ok := f.jobs[req.JobID]
+	switch {
+	case !ok:
+		return nil, rpc.Errorf(rpc.NotFound, "no job %s", req.JobID)
+	case req.IfUpdatedAt != nil && !req.IfUpdatedAt.Equal(job.UpdatedAt):
+		return nil, rpc.Errorf(rpc.Aborted, "job %s changed", req.JobID)
+	case job.Status != StatusQueued && job.Status != StatusProcessing:
+		return nil, rpc.Errorf(rpc.FailedPrecondition, "job %s is %s", req.JobID, job.Status)
+	}
+	job.Status = StatusCancelled
+	job.UpdatedAt = job.UpdatedAt.Add(time.Second)
+	return job, nil
+}
+
+func (f *fakeBackend) SetJobVisibility(ctx context.Context, req *backend.SetJobVisibilityRequest) (*backend.JobStatusResponse, error) {
+	job, ok := f.jobs[req.JobID]
+	if !ok {
+		return nil, rpc.Errorf(rpc.NotFound, "no job %s", req.JobID)
+	}
+	job.Public = req.Public
+	return job, nil
+}
+
+// ListJobs pages through the owner's jobs in ID order; the page token is
+// the ID of the previous page's last job, as stable as the service's.
+func (f *fakeBackend) ListJobs(ctx context.Context, req *backend.ListJobsRequest) (*backend.ListJobsResponse, error) {
+	f.gotList = req
+	if f.err != nil {
+		return nil, f.err
+	}
+	var owned []backend.JobStatusResponse
+	for _, job := range f.jobs {
+		if job.OwnerID == req.OwnerID {
+			owned = append(owned, *job)
+		}
+	}
+	sort.Slice(owned, func(i, j int) bool { return owned[i].JobID < owned[j].JobID })
+	start := 0
+	if req.PageToken != "" {
+		start = sort.Search(len(owned), func(i int) bool { return owned[i].JobID > req.PageToken })
+	}
+	end := min(start+req.PageSize, len(owned))
+	resp := &backend.ListJobsResponse{Jobs: owned[start:end]}
+	if end < len(owned) {
+		resp.NextPageToken = owned[end-1].JobID
+	}
+	if req.Total != "" {
+		resp.TotalCount, resp.TotalApproximate = int64(len(owned)), req.Total == backend.TotalApproximate
+	}
+	return resp, nil
+}
+
+func serve(t *testing.T, s *Server, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/content", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return serveRequest(t, s, req)
+}
+
+// serveRequest sends req through s's routes as user-1.
+func serveRequest(t *testing.T, s *Server, req *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	newRouter(s).ServeHTTP(rec, req)
+	return rec
+}
+
+// newRouter serves s's routes as user-1.
+func newRouter(s *Server) *router.Router {
+	rt := router.New()
+	s.Register(rt, func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := gateway.WithClaims(r.Context(), &gateway.Claims{Subject: "user-1"})
+			h.ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
+	return rt
+}
+
+func TestCreateContentAccepted(t *testing.T) {
+	be := &fakeBackend{}
+	rec := serve(t, &Server{Backend: be, RequestTimeout: 5 * time.Second},
+		`{"prompt":"a video about otters","format":"video","options":{"length":30}}`)
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	var got JobAccepted
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.JobID == "" || got.Status != "queued" {
+		t.Errorf("body = %+v", got)
+	}
+	if loc := rec.Header().Get("Location"); loc != "/api/v1/jobs/"+got.JobID {
+		t.Errorf("Location = %q", loc)
+	}
+	if be.got.JobID != got.JobID || be.got.Topic != "a video about otters" || be.got.Format != "video" || be.got.OwnerID != "user-1" {
+		t.Errorf("backend request = %+v", be.got)
+	}
+	if be.got.Options["length"] != float64(30) {
+		t.Errorf("options = %v", be.got.Options)
+	}
+	if be.deadline.IsZero() || time.Until(be.deadline) > 5*time.Second {
+		t.Errorf("backend deadline = %v, want within 5s", be.deadline)
+	}
+}
+
+func TestCreateContentMsgPack(t *testing.T) {
+	be := &fakeBackend{}
+	s := &Server{Backend: be}
+	body, _ := msgpack.FromJSON([]byte(`{"prompt":"a video about otters","format":"video","options":{"length":30}}`))
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/content", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/msgpack")
+	req.Header.Set("Accept", "application/msgpack")
+	rec := serveRequest(t, s, req)
+
+	if rec.Code != http.StatusAccepted || rec.Header().Get("Content-Type") != "application/msgpack" {
+		t.Fatalf("status = %d, Content-Type %q, body %q", rec.Code, rec.Header().Get("Content-Type"), rec.Body)
+	}
+	doc, err := msgpack.ToJSON(rec.Body.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got JobAccepted
+	if err := json.Unmarshal(doc, &got); err != nil || got.JobID != be.got.JobID {
+		t.Errorf("body = %s, %v", doc, err)
+	}
+	if be.got.Topic != "a video about otters" || be.got.Options["length"] != float64(30) {
+		t.Errorf("backend request = %+v", be.got)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/content", strings.NewReader("\xc1"))
+	req.Header.Set("Content-Type", "application/msgpack")
+	if rec := serveRequest(t, s, req); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "not valid MessagePack") {
+		t.Errorf("invalid body: %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestNotAcceptable(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/content", strings.NewReader(`{"prompt":"otters","format":"video"}`))
+	req.Header.Set("Accept", "text/html")
+	be := &fakeBackend{}
+	rec := serveRequest(t, &Server{Backend: be}, req)
+	if rec.Code != http.StatusNotAcceptable || be.got != nil {
+		t.Fatalf("status = %d, backend called with %+v", rec.Code, be.got)
+	}
+	var env apierror.Envelope
+	if err := json.Unmarshal(rec.Body.Bytes(), &env); err != nil || env.Error.Code != apierror.CodeNotAcceptable {
+		t.Errorf("body = %s", rec.Body)
+	}
+}
+
+func TestCreateContentValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		fields []string
+	}{
+		{"malformed", `{"prompt":`, http.StatusBadRequest, nil},
+		{"missing both", `{}`, http.StatusUnprocessableEntity, []string{"prompt", "format"}},
+		{"bad format", `{"prompt":"x","format":"hologram"}`, http.StatusUnprocessableEntity, []string{"format"}},
+		{"long prompt", `{"prompt":"` + strings.Repeat("a", maxPromptLength+1) + `","format":"audio"}`, http.StatusUnprocessableEntity, []string{"prompt"}},
+		{"bad options", `{"prompt":"x","format":"article","options":{"temperature":2.5,"max_tokens":0.5,"style":7,"colour":"red","resolution":"8k"}}`,
+			http.StatusUnprocessableEntity, []string{"options.colour", "options.max_tokens", "options.resolution", "options.style", "options.temperature"}},
+		{"unknown field ignored", `{"prompt":"x","format":"article","priority":1}`, http.StatusAccepted, nil},
+		{"unknown field strict", `{"prompt":"x","format":"article","priority":1}`, http.StatusBadRequest, []string{"priority"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			be := &fakeBackend{}
+			rec := serve(t, &Server{Backend: be, StrictJSON: strings.HasSuffix(tt.name, "strict")}, tt.body)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if tt.status == http.StatusAccepted {
+				return
+			}
+			if be.got != nil {
+				t.Error("backend called for invalid request")
+			}
+			var body apierror.Envelope
+			json.Unmarshal(rec.Body.Bytes(), &body)
+			if len(body.Error.Fields) != len(tt.fields) {
+				t.Fatalf("fields = %+v, want %v", body.Error.Fields, tt.fields)
+			}
+			for i, f := range tt.fields {
+				if body.Error.Fields[i].Field != f {
+					t.Errorf("fields[%d] = %q, want %q", i, body.Error.Fields[i].Field, f)
+				}
+			}
+		})
+	}
+}
+
+func TestValidationMessages(t *testing.T) {
+	req := &ContentRequest{
+		Prompt: strings.Repeat("é", maxPromptLength+1),
+		Format: "hologram",
+		Options: map[string]any{
+			"temperature": 2.5, "max_tokens": 0.0, "style": "  ", "resolution": "8k", "tone": 3.0,
+		},
+	}
+	want := []apierror.FieldError{
+		{Field: "prompt", Message: "must be at most 4000 characters"},
+		{Field: "format", Message: "must be one of video, audio, avatar, article, social_post"},
+		{Field: "options.max_tokens", Message: "must be at least 1"},
+		{Field: "options.resolution", Message: "must be one of 720p, 1080p, 4k"},
+		{Field: "options.style", Message: "is required"},
+		{Field: "options.temperature", Message: "must be at most 2"},
+		{Field: "options.tone", Message: "must be a string"},
+	}
+	got := req.Validate()
+	if len(got) != len(want) {
+		t.Fatalf("errors = %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("errors[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if errs := (&ContentRequest{Prompt: "otters", Format: "video"}).Validate(); errs != nil {
+		t.Errorf("valid request: %+v", errs)
+	}
+}
+
+func TestCreateContentSanitizesOptions(t *testing.T) {
+	be := &fakeBackend{}
+	rec := serve(t, &Server{Backend: be},
+		`{"prompt":"x","format":"video","options":{"style":"  cinematic ","temperature":0,"aspect_ratio":"9:16","captions":true}}`)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	if be.got.Options["style"] != "cinematic" || be.got.Options["aspect_ratio"] != "9:16" {
+		t.Errorf("options = %v", be.got.Options)
+	}
+}
+
+func TestCreateContentBodyLimit(t *testing.T) {
+	rec := serve(t, &Server{Backend: &fakeBackend{}, MaxBodyBytes: 64},
+		`{"prompt":"`+strings.Repeat("a", 100)+`","format":"video"}`)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want 413", rec.Code)
+	}
+}
+
+func TestBackendErrorEnvelope(t *testing.T) {
+	detail := json.RawMessage(`{"@type":"type.googleapis.com/google.rpc.BadRequest","fieldViolations":[{"field":"topic"}]}`)
+	be := &fakeBackend{err: &rpc.Error{Code: rpc.InvalidArgument, Message: "topic is banned", Details: []json.RawMessage{detail}}}
+	rec := serve(t, &Server{Backend: be}, `{"prompt":"x","format":"article"}`)
+	var body apierror.Envelope
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusBadRequest || body.Error.Code != "invalid_request" || body.Error.Message != "topic is banned" {
+		t.Errorf("got %d %+v", rec.Code, body)
+	}
+	if len(body.Error.Details) != 1 || !strings.Contains(string(body.Error.Details[0]), "fieldViolations") {
+		t.Errorf("details = %s", body.Error.Details)
+	}
+
+	// Server-side failures do not leak the backend's message.
+	be.err = rpc.Errorf(rpc.Internal, "db password rejected")
+	rec = serve(t, &Server{Backend: be}, `{"prompt":"x","format":"article"}`)
+	if strings.Contains(rec.Body.String(), "password") {
+		t.Errorf("body = %s", rec.Body)
+	}
+}
+
+func TestCreateContentBackendErrors(t *testing.T) {
+	tests := []struct {
+		code   rpc.Code
+		status int
+	}{
+		{rpc.Unavailable, http.StatusServiceUnavailable},
+		{rpc.DeadlineExceeded, http.StatusGatewayTimeout},
+		{rpc.InvalidArgument, http.StatusBadRequest},
+		{rpc.ResourceExhausted, http.StatusTooManyRequests},
+		{rpc.Internal, http.StatusInternalServerError},
+		{rpc.Code(99), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code.String(), func(t *testing.T) {
+			be := &fakeBackend{err: rpc.Errorf(tt.code, "boom")}
+			rec := serve(t, &Server{Backend: be}, `{"prompt":"x","format":"article"}`)
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestCreateContentDryRun(t *testing.T) {
+	s := &Server{Backend: &fakeBackend{}, Idempotency: idempotency.NewMemoryStore()}
+	body := `{"prompt":"a video about otters","format":"video","options":{"style":" calm "}}`
+	post := func(target string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", "k1")
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		return serveRequest(t, s, req)
+	}
+
+	for name, rec := range map[string]*httptest.ResponseRecorder{
+		"query":  post("/api/v1/content?dry_run=true", nil),
+		"header": post("/api/v1/content", http.Header{DryRunHeader: {"1"}}),
+	} {
+		be := s.Backend.(*fakeBackend)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, body %s", name, rec.Code, rec.Body)
+		}
+		var got ContentEstimate
+		json.Unmarshal(rec.Body.Bytes(), &got)
+		if !got.DryRun || got.CostUSD != 0.42 || got.Tokens != 1200 || got.Request.Prompt != "a video about otters" || got.Request.Options["style"] != "calm" {
+			t.Errorf("%s: body = %+v", name, got)
+		}
+		if be.got != nil || be.estimated == nil || be.estimated.JobID != "" || be.estimated.OwnerID != "user-1" {
+			t.Errorf("%s: created %+v, estimated %+v", name, be.got, be.estimated)
+		}
+	}
+
+	// The dry runs were not recorded under the key, so the real
+	// submission goes through.
+	if rec := post("/api/v1/content", nil); rec.Code != http.StatusAccepted {
+		t.Errorf("submission after dry runs: status = %d, body %s", rec.Code, rec.Body)
+	}
+
+	if rec := post("/api/v1/content?dry_run=maybe", nil); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "invalid_dry_run") {
+		t.Errorf("bad dry_run: %d %s", rec.Code, rec.Body)
+	}
+	s.Backend = &fakeBackend{}
+	body = `{"prompt":"","format":"video"}`
+	if rec := post("/api/v1/content?dry_run=true", nil); rec.Code != http.StatusUnprocessableEntity || s.Backend.(*fakeBackend).estimated != nil {
+		t.Errorf("invalid dry run: %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestCreateContentCoalesced(t *testing.T) {
+	q := queue.NewMemory(10)
+	s := &Server{Backend: &fakeBackend{}, Queue: q,
+		Routes: routeconf.Table{"POST /api/v1/content": {CoalesceWindow: time.Minute}}}
+	body := `{"prompt":"a video about otters","format":"video"}`
+
+	var ids []string
+	for range 2 {
+		rec := serve(t, s, body)
+		var accepted JobAccepted
+		json.Unmarshal(rec.Body.Bytes(), &accepted)
+		if rec.Code != http.StatusAccepted || rec.Header().Get("Location") != "/api/v1/jobs/"+accepted.JobID {
+			t.Fatalf("status = %d, Location %q, body %s", rec.Code, rec.Header().Get("Location"), rec.Body)
+		}
+		ids = append(ids, accepted.JobID)
+	}
+	if ids[0] != ids[1] {
+		t.Errorf("double submission created jobs %v, want one", ids)
+	}
+	if n, _ := q.Len(context.Background()); n != 1 {
+		t.Errorf("queued %d jobs, want 1", n)
+	}
+}
+
+func TestCreateContentQueued(t *testing.T) {
+	be := &fakeBackend{}
+	q := queue.NewMemory(1)
+	s := &Server{Backend: be, Queue: q}
+	body := `{"prompt":"a video about otters","format":"video"}`
+
+	rec := serve(t, s, body)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	var accepted JobAccepted
+	json.Unmarshal(rec.Body.Bytes(), &accepted)
+	if accepted.Status != "queued" || be.got != nil {
+		t.Errorf("accepted %+v, backend called with %+v", accepted, be.got)
+	}
+	job, err := q.Dequeue(context.Background())
+	if err != nil || job.Request.JobID != accepted.JobID || job.Request.OwnerID != "user-1" || job.EnqueuedAt.IsZero() {
+		t.Fatalf("queued %+v, %v", job, err)
+	}
+
+	q.Enqueue(context.Background(), job)
+	rec = serve(t, s, body)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" || !strings.Contains(rec.Body.String(), `"code":"queue_full"`) {
+		t.Errorf("full queue: status = %d, Retry-After %q, body %s", rec.Code, rec.Header().Get("Retry-After"), rec.Body)
+	}
+
+	s.Queue = brokenQueue{}
+	rec = serve(t, s, body)
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), `"code":"queue_unavailable"`) {
+		t.Errorf("broken queue: status = %d, body %s", rec.Code, rec.Body)
+	}
+}
+
+func TestCreateContentTenant(t *testing.T) {
+	be := &fakeBackend{}
+	q := queue.NewMemory(1)
+	s := &Server{Backend: be, Queue: q}
+	acme := &gateway.Tenant{ID: "acme", Formats: []string{"article", "social_post"}}
+	submit := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/content", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		return serveRequest(t, s, req.WithContext(gateway.WithTenant(req.Context(), acme)))
+	}
+
+	rec := submit(`{"prompt":"a video about otters","format":"video"}`)
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "not available to this tenant; it may request article, social_post") {
+		t.Errorf("disallowed format: status = %d, body %s", rec.Code, rec.Body)
+	}
+	if rec := submit(`{"prompt":"an article about otters","format":"article"}`); rec.Code != http.StatusAccepted {
+		t.Fatalf("allowed format: status = %d, body %s", rec.Code, rec.Body)
+	}
+	if job, err := q.Dequeue(context.Background()); err != nil || job.Tenant != "acme" {
+		t.Errorf("queued %+v, %v; want acme's job", job, err)
+	}
+}
+
+func TestCreateContentWebhook(t *testing.T) {
+	be := jobBackend()
+	hooks := &webhook.Dispatcher{Jobs: jobs.NewFeed(context.Background(), idleSource{}, jobs.NewBus()), Secrets: map[string]string{"user-1": "0123456789abcdef"}}
+	defer hooks.Shutdown(context.Background())
+	body := func(url string) string {
+		return `{"prompt":"a video about otters","format":"video","webhook_url":"` + url + `"}`
+	}
+
+	for name, tt := range map[string]struct {
+		s   *Server
+		url string
+	}{
+		"disabled":  {&Server{Backend: be}, "https://hooks.example.com/done"},
+		"no secret": {&Server{Backend: be, Webhooks: &webhook.Dispatcher{Jobs: jobs.NewFeed(context.Background(), idleSource{}, jobs.NewBus())}}, "https://hooks.example.com/done"},
+		"loopback":  {&Server{Backend: be, Webhooks: hooks}, "http://127.0.0.1:8080/done"},
+		"not a URL": {&Server{Backend: be, Webhooks: hooks}, "hooks.example.com"},
+	} {
+		rec := serve(t, tt.s, body(tt.url))
+		if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), `"field":"webhook_url"`) {
+			t.Errorf("%s: status = %d, body %s", name, rec.Code, rec.Body)
+		}
+	}
+
+	s := &Server{Backend: be, Webhooks: hooks}
+	rec := serve(t, s, body("https://hooks.example.com/done"))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	var accepted JobAccepted
+	json.Unmarshal(rec.Body.Bytes(), &accepted)
+	be.jobs[accepted.JobID] = &backend.JobStatusResponse{JobID: accepted.JobID, Status: StatusQueued, OwnerID: "user-1"}
+
+	rec = serveRequest(t, s, httptest.NewRequest(http.MethodGet, "/api/v1/jobs/"+accepted.JobID, nil))
+	var job JobStatus
+	json.Unmarshal(rec.Body.Bytes(), &job)
+	if job.Webhook == nil || job.Webhook.URL != "https://hooks.example.com/done" || job.Webhook.State != webhook.StatePending {
+		t.Errorf("job = %s", rec.Body)
+	}
+}
+
+func TestCreateContentFollowsJob(t *testing.T) {
+	feed := jobs.NewFeed(context.Background(), idleSource{}, jobs.NewBus())
+	s := &Server{Backend: &fakeBackend{}, Jobs: feed}
+	if rec := serve(t, s, `{"prompt":"a video about otters","format":"video"}`); rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for feed.Following() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("submitted job not followed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// A queued job is followed once its worker submits it.
+	s.Queue = queue.NewMemory(1)
+	serve(t, s, `{"prompt":"a video about otters","format":"video"}`)
+	time.Sleep(10 * time.Millisecond)
+	if n := feed.Following(); n != 1 {
+		t.Errorf("following %d jobs after queueing one, want 1", n)
+	}
+}
+
+// idleSource streams no events.
+type idleSource struct{}
+
+func (idleSource) Subscribe(ctx context.Context, jobID string, after int64) (<-chan jobs.Event, error) {
+	ch := make(chan jobs.Event)
+	context.AfterFunc(ctx, func() { close(ch) })
+	return ch, nil
+}
+
+// brokenQueue fails like a queue whose store is unreachable.
+type brokenQueue struct{}
+
+func (brokenQueue) Enqueue(context.Context, *queue.Job) error {
+	return errors.New("connection refused")
+}
+func (brokenQueue) Dequeue(context.Context) (*queue.Job, error) {
+	return nil, errors.New("connection refused")
+}
+func (brokenQueue) Len(context.Context) (int, error) { return 0, errors.New("connection refused") }
+func (brokenQueue) Position(context.Context, string) (*queue.Place, error) {
+	return nil, errors.New("connection refused")
+}
+func (brokenQueue) Taken(context.Context) (int64, error) { return 0, errors.New("connection refused") }
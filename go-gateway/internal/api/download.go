@@ -0,0 +1,206 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/backend"
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/router"
+)
+
+// downloadContent streams a finished content file to its owner without
+// buffering it: each chunk from the backend is written and flushed as it
+// arrives. Single byte ranges are supported so interrupted downloads can
+// resume; If-Range guards a resumed download against the file having
+// changed. The request context is the stream's context, so a client that
+// disconnects cancels the backend transfer.
+func (s *Server) downloadContent(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := router.Param(r, "id")
+	info, err := s.Backend.GetContentInfo(ctx, id)
+	if err != nil {
+		apierror.Write(w, r, apierror.FromRPC(err))
+		return
+	}
+	claims := gateway.ClaimsFromContext(ctx)
+	if claims == nil || info.OwnerID != claims.Subject {
+		forbid(w, r, "the content belongs to another user")
+		return
+	}
+	s.serveContent(w, r, id, info)
+}
+
+// serveContent streams the file described by info, with content ID id,
+// once the caller is known to be allowed it.
+func (s *Server) serveContent(w http.ResponseWriter, r *http.Request, id string, info *backend.ContentInfo) {
+	ctx := r.Context()
+	h := w.Header()
+	status, offset, length := http.StatusOK, int64(0), info.Size
+	if info.Size > 0 {
+		h.Set("Accept-Ranges", "bytes")
+		if rangeApplies(r, info.ETag) {
+			br, ok, satisfiable := parseRange(r.Header.Get("Range"), info.Size)
+			switch {
+			case ok && !satisfiable:
+				h.Set("Content-Range", fmt.Sprintf("bytes */%d", info.Size))
+				apierror.Write(w, r, apierror.New(http.StatusRequestedRangeNotSatisfiable, apierror.CodeRangeNotSatisfiable, "the requested range is outside the file"))
+				return
+			case ok:
+				status, offset, length = http.StatusPartialContent, br.start, br.length
+				h.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", br.start, br.start+br.length-1, info.Size))
+			}
+		}
+	} else {
+		h.Set("Accept-Ranges", "none")
+	}
+
+	stream, err := s.Backend.DownloadContent(ctx, id, offset, lengthArg(status, length))
+	if err != nil {
+		h.Del("Accept-Ranges")
+		h.Del("Content-Range")
+		apierror.Write(w, r, apierror.FromRPC(err))
+		return
+	}
+	defer stream.Close()
+	first, err := stream.Recv()
+	if err != nil && err != io.EOF {
+		h.Del("Accept-Ranges")
+		h.Del("Content-Range")
+		apierror.Write(w, r, apierror.FromRPC(err))
+		return
+	}
+
+	contentType := info.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	h.Set("Content-Type", contentType)
+	h.Set("Content-Disposition", contentDisposition(info.Filename, id))
+	if info.ETag != "" {
+		h.Set("ETag", info.ETag)
+	}
+	if length > 0 {
+		h.Set("Content-Length", strconv.FormatInt(length, 10))
+	}
+	w.WriteHeader(status)
+	if r.Method == http.MethodHead {
+		return // the deferred Close stops the backend transfer
+	}
+
+	rc := http.NewResponseController(w)
+	remaining := length
+	chunk := first
+	for err == nil {
+		if length > 0 {
+			// Never send more than was promised in Content-Length.
+			if int64(len(chunk)) > remaining {
+				chunk = chunk[:remaining]
+			}
+			remaining -= int64(len(chunk))
+		}
+		if _, werr := w.Write(chunk); werr != nil {
+			return // the client went away; the deferred Close stops the backend
+		}
+		rc.Flush()
+		if length > 0 && remaining == 0 {
+			return
+		}
+		chunk, err = stream.Recv()
+	}
+	if errors.Is(err, io.EOF) && remaining == 0 {
+		return
+	}
+	// The status is already sent. Abort the connection so the client sees
+	// a failed transfer instead of a complete-looking truncated file.
+	level := slog.LevelWarn
+	if gateway.ClientGone(ctx) {
+		level = slog.LevelDebug
+	}
+	gateway.Logger(ctx).Log(ctx, level, "content download interrupted", "content_id", id, "error", err, "missing_bytes", remaining)
+	panic(http.ErrAbortHandler)
+}
+
+// lengthArg is the Length to request from the backend: 0 (to the end) for
+// a full download.
+func lengthArg(status int, length int64) int64 {
+	if status == http.StatusPartialContent {
+		return length
+	}
+	return 0
+}
+
+// rangeApplies reports whether to honour the Range header: only if
+// If-Range is absent or names the current ETag. If-Range holding a date
+// is never satisfied, since no Last-Modified is sent.
+func rangeApplies(r *http.Request, etag string) bool {
+	ifRange := r.Header.Get("If-Range")
+	if ifRange == "" {
+		return true
+	}
+	return etag != "" && !strings.HasPrefix(ifRange, "W/") && ifRange == etag
+}
+
+type byteRange struct {
+	start, length int64
+}
+
+// parseRange parses a Range header against a file of size bytes. ok is
+// false when the header is absent, malformed or asks for several ranges;
+// the whole file is sent then. satisfiable is false when the range starts
+// past the end of the file.
+func parseRange(header string, size int64) (br byteRange, ok, satisfiable bool) {
+	spec, found := strings.CutPrefix(header, "bytes=")
+	if !found || strings.Contains(spec, ",") {
+		return byteRange{}, false, false
+	}
+	first, last, found := strings.Cut(strings.TrimSpace(spec), "-")
+	if !found {
+		return byteRange{}, false, false
+	}
+	if first == "" {
+		// Suffix range: the final n bytes.
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n < 0 {
+			return byteRange{}, false, false
+		}
+		if n == 0 {
+			return byteRange{}, true, false
+		}
+		n = min(n, size)
+		return byteRange{start: size - n, length: n}, true, true
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return byteRange{}, false, false
+	}
+	end := size - 1
+	if last != "" {
+		if end, err = strconv.ParseInt(last, 10, 64); err != nil || end < start {
+			return byteRange{}, false, false
+		}
+		end = min(end, size-1)
+	}
+	if start >= size {
+		return byteRange{}, true, false
+	}
+	return byteRange{start: start, length: end - start + 1}, true, true
+}
+
+// contentDisposition names the download, falling back to the content ID.
+func contentDisposition(filename, id string) string {
+	if filename == "" {
+		filename = id
+	}
+	if v := mime.FormatMediaType("attachment", map[string]string{"filename": filename}); v != "" {
+		return v
+	}
+	return "attachment"
+}
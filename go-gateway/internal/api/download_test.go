@@ -0,0 +1,232 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/backend"
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/router"
+	"github.com/content-factory/go-gateway/internal/rpc"
+	pb "github.com/content-factory/go-gateway/protos/content_factory"
+)
+
+type fakeFile struct {
+	info backend.ContentInfo
+	data []byte
+	// failAfter ends the stream with an error after that many chunks.
+	failAfter int
+	// block makes the stream wait for cancellation after the first chunk.
+	block   bool
+	got     backend.DownloadRequest
+	aborted chan struct{}
+}
+
+func (f *fakeBackend) GetContentInfo(ctx context.Context, contentID string) (*backend.ContentInfo, error) {
+	file, ok := f.files[contentID]
+	if !ok {
+		return nil, rpc.Errorf(rpc.NotFound, "no content %s", contentID)
+	}
+	info := file.info
+	return &info, nil
+}
+
+func (f *fakeBackend) DownloadContent(ctx context.Context, contentID string, offset, length int64) (*backend.ContentStream, error) {
+	return backend.New(&fileConn{file: f.files[contentID]}).DownloadContent(ctx, contentID, offset, length)
+}
+
+// fileConn serves DownloadContent from a fakeFile in 4-byte chunks.
+type fileConn struct{ file *fakeFile }
+
+func (c *fileConn) Invoke(ctx context.Context, method string, req, resp any) error {
+	return rpc.Errorf(rpc.Unimplemented, "unused")
+}
+
+func (c *fileConn) NewStream(ctx context.Context, method string, req any) (rpc.ClientStream, error) {
+	r := req.(*pb.DownloadRequest)
+	c.file.got = backend.DownloadRequest{ContentID: r.GetContentId(), Offset: r.GetOffset(), Length: r.GetLength()}
+	data := c.file.data[c.file.got.Offset:]
+	if c.file.got.Length > 0 {
+		data = data[:c.file.got.Length]
+	}
+	return &chunkStream{ctx: ctx, file: c.file, data: data}, nil
+}
+
+type chunkStream struct {
+	ctx  context.Context
+	file *fakeFile
+	data []byte
+	sent int
+}
+
+func (s *chunkStream) Recv(msg any) error {
+	if s.file.failAfter > 0 && s.sent == s.file.failAfter {
+		return rpc.Errorf(rpc.Internal, "disk on fire")
+	}
+	if s.file.block && s.sent == 1 {
+		<-s.ctx.Done()
+		close(s.file.aborted)
+		return rpc.Errorf(rpc.Canceled, "canceled")
+	}
+	if len(s.data) == 0 {
+		return io.EOF
+	}
+	n := min(4, len(s.data))
+	msg.(*pb.ContentChunk).Data = s.data[:n]
+	s.data = s.data[n:]
+	s.sent++
+	return nil
+}
+
+func (s *chunkStream) Close() error { return nil }
+
+const fileBody = "0123456789abcdefghij"
+
+// fileID is the content ID of fileBackend's file.
+const fileID = "a3d0e4c2-5f6b-4e7a-8c91-2d4f6b8e0a11"
+
+func fileBackend() (*fakeBackend, *fakeFile) {
+	file := &fakeFile{
+		info: backend.ContentInfo{ContentID: fileID, ContentType: "video/mp4", Filename: "otters.mp4", Size: int64(len(fileBody)), OwnerID: "user-1", ETag: `"v1"`},
+		data: []byte(fileBody),
+	}
+	return &fakeBackend{files: map[string]*fakeFile{
+		fileID:    file,
+		jobTheirs: {info: backend.ContentInfo{OwnerID: "user-2", Size: 1}, data: []byte("x")},
+	}}, file
+}
+
+func download(t *testing.T, be *fakeBackend, id string, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/content/"+id+"/download", nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return serveRequest(t, &Server{Backend: be}, req)
+}
+
+func TestDownloadFull(t *testing.T) {
+	be, file := fileBackend()
+	rec := download(t, be, fileID, nil)
+	if rec.Code != http.StatusOK || rec.Body.String() != fileBody {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body)
+	}
+	h := rec.Header()
+	for key, want := range map[string]string{
+		"Content-Type":        "video/mp4",
+		"Content-Disposition": `attachment; filename=otters.mp4`,
+		"Content-Length":      "20",
+		"Accept-Ranges":       "bytes",
+		"ETag":                `"v1"`,
+	} {
+		if got := h.Get(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+	if file.got.Offset != 0 || file.got.Length != 0 {
+		t.Errorf("backend request = %+v, want whole file", file.got)
+	}
+}
+
+func TestDownloadHead(t *testing.T) {
+	be, _ := fileBackend()
+	req := httptest.NewRequest(http.MethodHead, "/api/v1/content/"+fileID+"/download", nil)
+	rec := serveRequest(t, &Server{Backend: be}, req)
+	if rec.Code != http.StatusOK || rec.Body.Len() != 0 || rec.Header().Get("Content-Length") != "20" || rec.Header().Get("ETag") != `"v1"` {
+		t.Errorf("status = %d, body %q, headers %v", rec.Code, rec.Body, rec.Header())
+	}
+}
+
+func TestDownloadRange(t *testing.T) {
+	tests := []struct {
+		name, rangeHdr, ifRange string
+		status                  int
+		body, contentRange      string
+	}{
+		{"bounded", "bytes=5-9", "", http.StatusPartialContent, "56789", "bytes 5-9/20"},
+		{"open ended", "bytes=15-", "", http.StatusPartialContent, "fghij", "bytes 15-19/20"},
+		{"suffix", "bytes=-3", "", http.StatusPartialContent, "hij", "bytes 17-19/20"},
+		{"clamped end", "bytes=18-100", "", http.StatusPartialContent, "ij", "bytes 18-19/20"},
+		{"matching If-Range", "bytes=0-1", `"v1"`, http.StatusPartialContent, "01", "bytes 0-1/20"},
+		{"stale If-Range", "bytes=0-1", `"v0"`, http.StatusOK, fileBody, ""},
+		{"multiple ranges", "bytes=0-1,5-6", "", http.StatusOK, fileBody, ""},
+		{"malformed", "bytes=x-y", "", http.StatusOK, fileBody, ""},
+		{"past the end", "bytes=20-", "", http.StatusRequestedRangeNotSatisfiable, "", "bytes */20"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			be, _ := fileBackend()
+			headers := map[string]string{"Range": tt.rangeHdr}
+			if tt.ifRange != "" {
+				headers["If-Range"] = tt.ifRange
+			}
+			rec := download(t, be, fileID, headers)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if tt.body != "" && rec.Body.String() != tt.body {
+				t.Errorf("body = %q, want %q", rec.Body, tt.body)
+			}
+			if got := rec.Header().Get("Content-Range"); got != tt.contentRange {
+				t.Errorf("Content-Range = %q, want %q", got, tt.contentRange)
+			}
+		})
+	}
+}
+
+func TestDownloadErrors(t *testing.T) {
+	be, _ := fileBackend()
+	if rec := download(t, be, jobTheirs, nil); rec.Code != http.StatusForbidden {
+		t.Errorf("other user's content: status = %d", rec.Code)
+	}
+	if rec := download(t, be, jobMissing, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("missing content: status = %d", rec.Code)
+	}
+}
+
+func TestDownloadFailureMidStreamAborts(t *testing.T) {
+	be, file := fileBackend()
+	file.failAfter = 2
+	defer func() {
+		if p := recover(); p != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want ErrAbortHandler", p)
+		}
+	}()
+	download(t, be, fileID, nil)
+}
+
+func TestDownloadClientDisconnectCancelsBackend(t *testing.T) {
+	be, file := fileBackend()
+	file.block = true
+	file.aborted = make(chan struct{})
+
+	rt := router.New()
+	(&Server{Backend: be}).Register(rt, func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h.ServeHTTP(w, r.WithContext(gateway.WithClaims(r.Context(), &gateway.Claims{Subject: "user-1"})))
+		})
+	})
+	srv := httptest.NewServer(rt)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/v1/content/" + fileID + "/download")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(resp.Body, buf); err != nil || !strings.HasPrefix(fileBody, string(buf)) {
+		t.Fatalf("first chunk = %q, %v", buf, err)
+	}
+	resp.Body.Close()
+
+	select {
+	case <-file.aborted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("backend stream not cancelled after client disconnect")
+	}
+}
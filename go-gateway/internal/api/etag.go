@@ -0,0 +1,65 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/content-factory/go-gateway/internal/cache"
+	"github.com/content-factory/go-gateway/internal/respond"
+)
+
+// etagOf returns a strong entity tag for a response body.
+func etagOf(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// writeWithETag writes v like respond.Write with status 200, tagged with
+// an ETag of its encoding. A request whose If-None-Match already holds
+// that tag gets an empty 304 instead.
+func writeWithETag(w http.ResponseWriter, r *http.Request, v any) {
+	// Clients may keep the document but must revalidate it every time.
+	writeTagged(w, r, v, "private, no-cache")
+}
+
+// writeTagged is writeWithETag with the given Cache-Control.
+func writeTagged(w http.ResponseWriter, r *http.Request, v any, cacheControl string) {
+	media, body, tag := encodeWithETag(r, v)
+	h := w.Header()
+	h.Set("ETag", tag)
+	respond.Vary(w, "Accept")
+	h.Set("Cache-Control", cacheControl)
+	if inm := r.Header.Get("If-None-Match"); inm != "" && cache.ETagMatches(inm, tag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	h.Set("Content-Type", media)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// encodeWithETag returns the media type and body writeWithETag sends for
+// v in answer to r, and its entity tag. The tag differs between media
+// types, as the bodies do.
+func encodeWithETag(r *http.Request, v any) (string, []byte, string) {
+	media, _ := respond.Negotiate(r)
+	body, err := respond.Encode(media, v)
+	if err != nil {
+		panic(err)
+	}
+	return media, body, etagOf(body)
+}
+
+// ifMatch reports whether an If-Match header value lists etag. Unlike
+// If-None-Match it compares strongly, so weak tags never match.
+func ifMatch(header, etag string) bool {
+	for candidate := range strings.SplitSeq(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
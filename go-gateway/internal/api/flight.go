@@ -0,0 +1,59 @@
+package api
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/metrics"
+)
+
+var deduplicated = metrics.NewCounterVec("gateway_backend_deduplicated_total",
+	"Backend reads answered by an identical call already in flight instead of a call of their own, by call.",
+	"call")
+
+// shared runs fetch once for all concurrent callers passing the same call
+// and key, handing every one of them its result or error. The key must
+// hold everything the backend's answer depends on, the caller's principal
+// included, since the callers check access to the result themselves; the
+// tenant, which picks the backend, is added here.
+//
+// fetch runs with the context of the caller that started it, minus its
+// cancellation: a caller that gives up returns ctx.Err() without failing
+// the others, who still wait for the backend within its deadline. Results
+// are shared, so callers must not modify them.
+func shared[T any](s *Server, ctx context.Context, call string, key []string, fetch func(context.Context) (T, error)) (T, error) {
+	var b strings.Builder
+	b.WriteString(call)
+	for _, k := range append([]string{gateway.TenantID(ctx)}, key...) {
+		b.WriteByte(0)
+		// Length-prefixing keeps the parts from running into each other.
+		b.WriteString(strconv.Itoa(len(k)))
+		b.WriteByte(':')
+		b.WriteString(k)
+	}
+
+	led := false
+	ch := s.flights.DoChan(b.String(), func() (any, error) {
+		led = true
+		fctx := context.WithoutCancel(ctx)
+		if deadline, ok := ctx.Deadline(); ok {
+			var cancel context.CancelFunc
+			fctx, cancel = context.WithDeadline(fctx, deadline)
+			defer cancel()
+		}
+		return fetch(fctx)
+	})
+	select {
+	case res := <-ch:
+		if !led {
+			deduplicated.With(call).Inc()
+		}
+		v, _ := res.Val.(T)
+		return v, res.Err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
@@ -0,0 +1,301 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/audit"
+	"github.com/content-factory/go-gateway/internal/backend"
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/openapi"
+	"github.com/content-factory/go-gateway/internal/respond"
+	"github.com/content-factory/go-gateway/internal/router"
+	"github.com/content-factory/go-gateway/internal/rpc"
+	"github.com/content-factory/go-gateway/internal/webhook"
+)
+
+// Job statuses reported by the content service.
+const (
+	StatusQueued     = "queued"
+	StatusProcessing = "processing"
+	StatusCompleted  = "completed"
+	StatusFailed     = "failed"
+	StatusCancelled  = "cancelled"
+)
+
+// JobStatus is the body of GET /api/v1/jobs/{id}.
+type JobStatus struct {
+	JobID     string    `json:"job_id"`
+	Status    string    `json:"status"`
+	Progress  float64   `json:"progress"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Result is set once the job has completed, Error once it has failed.
+	Result *JobResult `json:"result,omitempty"`
+	Error  *JobError  `json:"error,omitempty"`
+	// Webhook records the deliveries of the job's webhook, if it was
+	// submitted with one.
+	Webhook *webhook.Status `json:"webhook,omitempty"`
+	// RetryOf is the failed job this one retries, if it is a retry.
+	RetryOf string `json:"retry_of,omitempty"`
+	// Public is whether the owner made the job public, see
+	// PUT /api/v1/jobs/{id}/visibility. PublicURL is where anyone can
+	// then read it.
+	Public    bool   `json:"public"`
+	PublicURL string `json:"public_url,omitempty"`
+}
+
+// DescribeSchema implements openapi.Describer.
+func (JobStatus) DescribeSchema(s *openapi.Schema) {
+	s.Properties["status"].Enum = []any{StatusQueued, StatusProcessing, StatusCompleted, StatusFailed, StatusCancelled}
+}
+
+// JobResult locates a finished job's output.
+type JobResult struct {
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
+}
+
+// JobError explains why a job failed.
+type JobError struct {
+	Message string `json:"message"`
+}
+
+func newJobStatus(s *backend.JobStatusResponse) JobStatus {
+	out := JobStatus{
+		JobID:     s.JobID,
+		Status:    s.Status,
+		Progress:  s.ProgressPercent,
+		CreatedAt: s.CreatedAt,
+		UpdatedAt: s.UpdatedAt,
+		RetryOf:   s.RetryOf,
+		Public:    s.Public,
+	}
+	switch s.Status {
+	case StatusCompleted:
+		out.Result = &JobResult{URL: s.ResultURL, ThumbnailURL: s.ThumbnailURL}
+	case StatusFailed:
+		out.Error = &JobError{Message: s.ErrorMessage}
+	}
+	return out
+}
+
+// jobStatus is the response body for st, with its webhook deliveries
+// and, if it is public, its public URL.
+func (s *Server) jobStatus(ctx context.Context, st *backend.JobStatusResponse) JobStatus {
+	out := newJobStatus(st)
+	if s.Webhooks != nil {
+		out.Webhook, _ = s.Webhooks.Status(st.JobID)
+	}
+	if st.Public && s.PublicContent != nil {
+		out.PublicURL = s.publicURL(ctx, st.JobID)
+	}
+	return out
+}
+
+// getJob reports a job's current state to its owner. The response carries
+// an ETag so polling clients can revalidate with If-None-Match and get a
+// 304 while nothing has changed.
+func (s *Server) getJob(w http.ResponseWriter, r *http.Request) {
+	id := router.Param(r, "id")
+	ctx := r.Context()
+	// Clients polling a popular job share one backend call.
+	status, err := shared(s, ctx, "GetJobStatus", []string{principal(ctx), id}, func(ctx context.Context) (*backend.JobStatusResponse, error) {
+		return s.Backend.GetJobStatus(ctx, id)
+	})
+	if err != nil {
+		apierror.Write(w, r, apierror.FromRPC(err))
+		return
+	}
+	claims := gateway.ClaimsFromContext(ctx)
+	if claims == nil || status.OwnerID != claims.Subject {
+		forbid(w, r, "the job belongs to another user")
+		return
+	}
+	writeWithETag(w, r, s.jobStatus(ctx, status))
+}
+
+// cancelJob cancels a job for its owner. The caller must name the state it
+// is cancelling with If-Match: if the job has changed since that ETag the
+// request fails with 412, so the client can fetch the job and decide
+// again. The 200 response is the cancelled job with its new ETag.
+func (s *Server) cancelJob(w http.ResponseWriter, r *http.Request) {
+	match := r.Header.Get("If-Match")
+	if match == "" {
+		apierror.Write(w, r, apierror.PreconditionRequired("cancelling a job requires an If-Match header with its current ETag"))
+		return
+	}
+	ctx := r.Context()
+	id := router.Param(r, "id")
+	current, err := s.Backend.GetJobStatus(ctx, id)
+	if err != nil {
+		apierror.Write(w, r, apierror.FromRPC(err))
+		return
+	}
+	claims := gateway.ClaimsFromContext(ctx)
+	if claims == nil || current.OwnerID != claims.Subject {
+		forbid(w, r, "the job belongs to another user")
+		return
+	}
+	if _, _, tag := encodeWithETag(r, s.jobStatus(ctx, current)); !ifMatch(match, tag) {
+		apierror.Write(w, r, jobChanged())
+		return
+	}
+	if finished(current.Status) {
+		apierror.Write(w, r, jobFinished(current.Status))
+		return
+	}
+
+	// The backend refuses too if the job moves on between the two calls.
+	updated := current.UpdatedAt
+	cancelled, err := s.Backend.CancelJob(ctx, &backend.CancelJobRequest{JobID: id, IfUpdatedAt: &updated})
+	if err != nil {
+		audit.Record(ctx, audit.ActionJobCancel, audit.OutcomeFailure, slog.String("job_id", id), slog.String("error", err.Error()))
+	}
+	switch rpc.CodeOf(err) {
+	case rpc.OK:
+	case rpc.Aborted:
+		apierror.Write(w, r, jobChanged())
+		return
+	case rpc.FailedPrecondition:
+		apierror.Write(w, r, jobFinished(""))
+		return
+	default:
+		apierror.Write(w, r, apierror.FromRPC(err))
+		return
+	}
+	audit.Record(ctx, audit.ActionJobCancel, audit.OutcomeSuccess, slog.String("job_id", id))
+	s.invalidate(ctx, "/api/v1/jobs", "/api/v1/jobs/"+id, "/api/v1/jobs/"+id+"/position")
+	writeWithETag(w, r, s.jobStatus(ctx, cancelled))
+}
+
+// forbid refuses r with 403 and message, recording the denial in the
+// audit log.
+func forbid(w http.ResponseWriter, r *http.Request, message string) {
+	audit.Record(r.Context(), audit.ActionAuthorize, audit.OutcomeDenied,
+		slog.String("reason", message), slog.String("path", r.URL.Path))
+	apierror.Write(w, r, apierror.Forbidden(message))
+}
+
+// finished reports whether a job in status can no longer change.
+func finished(status string) bool {
+	return status == StatusCompleted || status == StatusFailed || status == StatusCancelled
+}
+
+func jobChanged() *apierror.Error {
+	return apierror.PreconditionFailed("the job has changed since the given ETag; fetch it again")
+}
+
+// jobFinished rejects cancelling a job that has finished, in status if it
+// is known.
+func jobFinished(status string) *apierror.Error {
+	msg := "the job has finished and can no longer be cancelled"
+	if status != "" {
+		msg = "the job has already " + status + " and can no longer be cancelled"
+	}
+	return apierror.New(http.StatusConflict, apierror.CodeJobFinished, msg)
+}
+
+// retryJob submits one of the caller's failed jobs again, as a new job
+// with the parameters the backend recorded for it and the webhook it had.
+// The parameters are validated afresh, since the caller's tenant may have
+// lost a format since. The 202 response links the new job to the failed
+// one with retry_of.
+func (s *Server) retryJob(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := router.Param(r, "id")
+	current, err := s.Backend.GetJobStatus(ctx, id)
+	if err != nil {
+		apierror.Write(w, r, apierror.FromRPC(err))
+		return
+	}
+	claims := gateway.ClaimsFromContext(ctx)
+	if claims == nil || current.OwnerID != claims.Subject {
+		forbid(w, r, "the job belongs to another user")
+		return
+	}
+	if current.Status != StatusFailed {
+		apierror.Write(w, r, apierror.New(http.StatusConflict, apierror.CodeJobNotFailed,
+			"only failed jobs can be retried; the job is "+current.Status))
+		return
+	}
+	orig, err := s.Backend.GetJobRequest(ctx, id)
+	if err != nil {
+		apierror.Write(w, r, apierror.FromRPC(err))
+		return
+	}
+	req := ContentRequest{Prompt: orig.Topic, Format: orig.Format, Options: orig.Options}
+	if s.Webhooks != nil {
+		if hook, ok := s.Webhooks.Status(id); ok {
+			req.WebhookURL = hook.URL
+		}
+	}
+	if errs := s.validate(ctx, &req); errs != nil {
+		apierror.Write(w, r, apierror.Validation(errs...))
+		return
+	}
+
+	submissions.With("retry").Inc()
+	accepted, err := s.submit(ctx, &req, id)
+	if err != nil {
+		e := submitError(err)
+		if e.Code == apierror.CodeQueueFull {
+			w.Header().Set("Retry-After", strconv.Itoa(queueRetryAfter))
+		}
+		apierror.Write(w, r, e)
+		return
+	}
+	s.invalidate(ctx, "/api/v1/jobs")
+	w.Header().Set("Location", "/api/v1/jobs/"+accepted.JobID)
+	respond.Write(w, r, http.StatusAccepted, accepted)
+}
+
+// listJobs returns one page of the caller's jobs, newest first, and their
+// total when asked for it. The backend's page token travels inside an
+// opaque cursor; a Link header repeats next_cursor for clients that
+// paginate by header.
+func (s *Server) listJobs(w http.ResponseWriter, r *http.Request) {
+	limit, cur, ok := pageParams(w, r, s.defaultPageSize(), s.maxPageSize())
+	if !ok {
+		return
+	}
+	total, ok := totalParam(w, r)
+	if !ok {
+		return
+	}
+	claims := gateway.ClaimsFromContext(r.Context())
+	if claims == nil {
+		forbid(w, r, "listing jobs requires an authenticated user")
+		return
+	}
+	req := &backend.ListJobsRequest{
+		OwnerID:   claims.Subject,
+		PageSize:  limit,
+		PageToken: cur.PageToken,
+		Total:     total,
+	}
+	key := []string{req.OwnerID, strconv.Itoa(req.PageSize), req.PageToken, req.Total}
+	resp, err := shared(s, r.Context(), "ListJobs", key, func(ctx context.Context) (*backend.ListJobsResponse, error) {
+		return s.Backend.ListJobs(ctx, req)
+	})
+	if err != nil {
+		apierror.Write(w, r, apierror.FromRPC(err))
+		return
+	}
+	page := Page[JobStatus]{Items: make([]JobStatus, 0, len(resp.Jobs))}
+	for i := range resp.Jobs {
+		page.Items = append(page.Items, s.jobStatus(r.Context(), &resp.Jobs[i]))
+	}
+	if resp.NextPageToken != "" {
+		page.NextCursor = encodeCursor(cursor{PageToken: resp.NextPageToken})
+	}
+	if total != "" {
+		page.Total, page.TotalApproximate = &resp.TotalCount, resp.TotalApproximate
+	}
+	setNextLink(w, r, page.NextCursor, limit)
+	respond.Write(w, r, http.StatusOK, page)
+}
@@ -0,0 +1,586 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/audit"
+	"github.com/content-factory/go-gateway/internal/backend"
+	"github.com/content-factory/go-gateway/internal/cache"
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/idempotency"
+	"github.com/content-factory/go-gateway/internal/rpc"
+)
+
+// The IDs of jobBackend's jobs, in list order.
+const (
+	jobBroken  = "6f1c2a9e-0b4d-4c1e-9a52-1b7d3e000001"
+	jobDone    = "6f1c2a9e-0b4d-4c1e-9a52-1b7d3e000002"
+	jobMissing = "6f1c2a9e-0b4d-4c1e-9a52-1b7d3e000003"
+	jobRunning = "6f1c2a9e-0b4d-4c1e-9a52-1b7d3e000004"
+	jobTheirs  = "6f1c2a9e-0b4d-4c1e-9a52-1b7d3e000005"
+)
+
+func jobBackend() *fakeBackend {
+	created := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	return &fakeBackend{jobs: map[string]*backend.JobStatusResponse{
+		jobRunning: {JobID: jobRunning, Status: StatusProcessing, ProgressPercent: 40, CreatedAt: created, UpdatedAt: created.Add(time.Minute), OwnerID: "user-1"},
+		jobDone:    {JobID: jobDone, Status: StatusCompleted, ProgressPercent: 100, CreatedAt: created, UpdatedAt: created, OwnerID: "user-1", ResultURL: "https://cdn.example.com/done.mp4"},
+		jobBroken:  {JobID: jobBroken, Status: StatusFailed, CreatedAt: created, UpdatedAt: created, OwnerID: "user-1", ErrorMessage: "render failed"},
+		jobTheirs:  {JobID: jobTheirs, Status: StatusQueued, OwnerID: "user-2"},
+	}}
+}
+
+func getJob(t *testing.T, be *fakeBackend, id, ifNoneMatch string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/jobs/"+id, nil)
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	return serveRequest(t, &Server{Backend: be}, req)
+}
+
+func TestGetJob(t *testing.T) {
+	be := jobBackend()
+	tests := []struct {
+		id     string
+		check  func(JobStatus) bool
+		status int
+	}{
+		{jobRunning, func(j JobStatus) bool { return j.Progress == 40 && j.Result == nil && j.Error == nil }, http.StatusOK},
+		{jobDone, func(j JobStatus) bool { return j.Result != nil && j.Result.URL == "https://cdn.example.com/done.mp4" }, http.StatusOK},
+		{jobBroken, func(j JobStatus) bool { return j.Error != nil && j.Error.Message == "render failed" }, http.StatusOK},
+		{jobTheirs, nil, http.StatusForbidden},
+		{jobMissing, nil, http.StatusNotFound},
+		{strings.ToUpper(jobRunning), func(j JobStatus) bool { return j.Progress == 40 }, http.StatusOK},
+		{"job-1", nil, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			rec := getJob(t, be, tt.id, "")
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d; body %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.check == nil {
+				return
+			}
+			var got JobStatus
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.JobID != strings.ToLower(tt.id) || !tt.check(got) {
+				t.Errorf("body = %s", rec.Body)
+			}
+		})
+	}
+}
+
+func TestGetJobConditional(t *testing.T) {
+	be := jobBackend()
+	first := getJob(t, be, jobRunning, "")
+	tag := first.Header().Get("ETag")
+	if tag == "" {
+		t.Fatal("no ETag")
+	}
+
+	rec := getJob(t, be, jobRunning, `"stale", W/`+tag)
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Fatalf("unchanged job: status = %d, body %q", rec.Code, rec.Body)
+	}
+	if rec.Header().Get("ETag") != tag {
+		t.Errorf("304 ETag = %q, want %q", rec.Header().Get("ETag"), tag)
+	}
+
+	be.jobs[jobRunning].ProgressPercent = 55
+	rec = getJob(t, be, jobRunning, tag)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == tag {
+		t.Errorf("changed job: status = %d, ETag %q", rec.Code, rec.Header().Get("ETag"))
+	}
+}
+
+func listJobs(t *testing.T, s *Server, query string) (*httptest.ResponseRecorder, Page[JobStatus]) {
+	t.Helper()
+	rec := serveRequest(t, s, httptest.NewRequest(http.MethodGet, "/api/v1/jobs"+query, nil))
+	var page Page[JobStatus]
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rec, page
+}
+
+func TestListJobsPaginates(t *testing.T) {
+	be := jobBackend()
+	s := &Server{Backend: be}
+
+	rec, first := listJobs(t, s, "?limit=2")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	if len(first.Items) != 2 || first.Items[0].JobID != jobBroken || first.Items[1].JobID != jobDone {
+		t.Fatalf("first page = %s", rec.Body)
+	}
+	if be.gotList.OwnerID != "user-1" || be.gotList.PageToken != "" {
+		t.Errorf("backend request = %+v", be.gotList)
+	}
+	if first.NextCursor == "" || first.NextCursor == "2" {
+		t.Fatalf("next_cursor = %q, want an opaque token", first.NextCursor)
+	}
+	want := `</api/v1/jobs?cursor=` + first.NextCursor + `&limit=2>; rel="next"`
+	if got := rec.Header().Get("Link"); got != want {
+		t.Errorf("Link = %q, want %q", got, want)
+	}
+
+	rec, second := listJobs(t, s, "?limit=2&cursor="+first.NextCursor)
+	if rec.Code != http.StatusOK || len(second.Items) != 1 || second.Items[0].JobID != jobRunning {
+		t.Fatalf("second page: status = %d, body %s", rec.Code, rec.Body)
+	}
+	if second.NextCursor != "" || rec.Header().Get("Link") != "" {
+		t.Errorf("last page: next_cursor = %q, Link = %q", second.NextCursor, rec.Header().Get("Link"))
+	}
+}
+
+func TestListJobsPageSize(t *testing.T) {
+	be := jobBackend()
+	if rec, _ := listJobs(t, &Server{Backend: be}, ""); rec.Code != http.StatusOK || be.gotList.PageSize != DefaultPageSize {
+		t.Errorf("default page size = %d", be.gotList.PageSize)
+	}
+	s := &Server{Backend: be, DefaultPageSize: 2, MaxPageSize: 3}
+	if rec, _ := listJobs(t, s, ""); rec.Code != http.StatusOK || be.gotList.PageSize != 2 {
+		t.Errorf("configured default page size = %d", be.gotList.PageSize)
+	}
+	if rec, _ := listJobs(t, s, "?limit=3"); rec.Code != http.StatusOK || be.gotList.PageSize != 3 {
+		t.Errorf("page size at the maximum: status %d, %d", rec.Code, be.gotList.PageSize)
+	}
+	// A limit above the maximum is refused rather than clamped.
+	be.gotList = nil
+	rec, _ := listJobs(t, s, "?limit=4")
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "must be at most 3") {
+		t.Errorf("page size above the maximum: status %d, body %s", rec.Code, rec.Body)
+	}
+	if be.gotList != nil {
+		t.Error("the backend was called with a page size above the maximum")
+	}
+	// Without a configured default, the default stays within the maximum.
+	if rec, _ := listJobs(t, &Server{Backend: be, MaxPageSize: 2}, ""); rec.Code != http.StatusOK || be.gotList.PageSize != 2 {
+		t.Errorf("default page size above the maximum: status %d, %d", rec.Code, be.gotList.PageSize)
+	}
+}
+
+func TestListJobsTotal(t *testing.T) {
+	be := jobBackend()
+	s := &Server{Backend: be}
+	rec, page := listJobs(t, s, "?limit=1")
+	if page.Total != nil || strings.Contains(rec.Body.String(), "total") || be.gotList.Total != "" {
+		t.Errorf("total counted without include_total: %s", rec.Body)
+	}
+	for query, want := range map[string]string{
+		"?limit=1&include_total=true":        backend.TotalExact,
+		"?limit=1&include_total=exact":       backend.TotalExact,
+		"?limit=1&include_total=approximate": backend.TotalApproximate,
+		"?limit=1&include_total=false":       "",
+	} {
+		rec, page := listJobs(t, s, query)
+		if rec.Code != http.StatusOK || be.gotList.Total != want {
+			t.Errorf("%s: status %d, backend asked for total %q, want %q", query, rec.Code, be.gotList.Total, want)
+			continue
+		}
+		if want == "" {
+			continue
+		}
+		if page.Total == nil || *page.Total != 3 || page.TotalApproximate != (want == backend.TotalApproximate) {
+			t.Errorf("%s: body %s, want a total of 3", query, rec.Body)
+		}
+		// The next page asks for the total again.
+		if link := rec.Header().Get("Link"); !strings.Contains(link, "include_total=") {
+			t.Errorf("%s: Link = %q", query, link)
+		}
+	}
+	if rec, _ := listJobs(t, s, "?include_total=yes"); rec.Code != http.StatusBadRequest {
+		t.Errorf("include_total=yes: status %d, want 400", rec.Code)
+	}
+}
+
+// TestListJobsCursorIsStable pages through jobs that are submitted and
+// deleted between pages: the cursor marks a position in the list rather
+// than an offset, so the next page neither repeats a job nor skips one.
+func TestListJobsCursorIsStable(t *testing.T) {
+	be := jobBackend()
+	s := &Server{Backend: be}
+	_, first := listJobs(t, s, "?limit=2")
+	if len(first.Items) != 2 || first.Items[1].JobID != jobDone {
+		t.Fatalf("first page = %+v", first.Items)
+	}
+
+	// A job before the cursor is added and one on the first page deleted;
+	// neither shifts the second page. A job after the cursor is on it.
+	const before, after = "6f1c2a9e-0b4d-4c1e-9a52-1b7d3e000000", "6f1c2a9e-0b4d-4c1e-9a52-1b7d3e000006"
+	be.jobs[before] = &backend.JobStatusResponse{JobID: before, Status: StatusQueued, OwnerID: "user-1"}
+	be.jobs[after] = &backend.JobStatusResponse{JobID: after, Status: StatusQueued, OwnerID: "user-1"}
+	delete(be.jobs, jobBroken)
+
+	rec, second := listJobs(t, s, "?limit=2&cursor="+first.NextCursor)
+	if rec.Code != http.StatusOK || len(second.Items) != 2 || second.Items[0].JobID != jobRunning || second.Items[1].JobID != after {
+		t.Errorf("second page: status %d, body %s", rec.Code, rec.Body)
+	}
+
+	// Deleting the job the cursor points after does not break it either.
+	_, first = listJobs(t, s, "?limit=2")
+	delete(be.jobs, first.Items[1].JobID)
+	if rec, second = listJobs(t, s, "?limit=2&cursor="+first.NextCursor); rec.Code != http.StatusOK || len(second.Items) == 0 || second.Items[0].JobID != jobRunning {
+		t.Errorf("after deleting the cursor's job: status %d, body %s", rec.Code, rec.Body)
+	}
+}
+
+func TestListJobsEmpty(t *testing.T) {
+	rec, _ := listJobs(t, &Server{Backend: &fakeBackend{}}, "")
+	if rec.Code != http.StatusOK || rec.Body.String() != `{"items":[],"next_cursor":""}`+"\n" {
+		t.Errorf("status = %d, body %s", rec.Code, rec.Body)
+	}
+}
+
+func TestListJobsRejectsBadParams(t *testing.T) {
+	for _, query := range []string{
+		"?limit=0",
+		"?limit=ten",
+		"?cursor=2",         // a raw backend token, not a cursor
+		"?cursor=e30",       // base64 of {}
+		"?cursor=%21%21%21", // not base64
+	} {
+		if rec, _ := listJobs(t, &Server{Backend: jobBackend()}, query); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, rec.Code)
+		}
+	}
+}
+
+func TestJobReadsAreCached(t *testing.T) {
+	be := jobBackend()
+	s := &Server{Backend: be, Cache: cache.NewLRU(100)}
+
+	if rec := getJob(t, be, jobRunning, ""); rec.Header().Get(cache.StatusHeader) != "" {
+		t.Fatal("cache used without a store")
+	}
+	read := func(path string) string {
+		rec := serveRequest(t, s, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Header().Get(cache.StatusHeader)
+	}
+	if a, b := read("/api/v1/jobs/"+jobRunning), read("/api/v1/jobs/"+jobRunning); a != "MISS" || b != "HIT" {
+		t.Errorf("job reads: %s then %s", a, b)
+	}
+	if a, b := read("/api/v1/jobs?limit=2"), read("/api/v1/jobs?limit=2"); a != "MISS" || b != "HIT" {
+		t.Errorf("list reads: %s then %s", a, b)
+	}
+
+	if rec := serve(t, s, `{"prompt":"otters","format":"video"}`); rec.Code != http.StatusAccepted {
+		t.Fatalf("submit: %d %s", rec.Code, rec.Body)
+	}
+	if got := read("/api/v1/jobs?limit=2"); got != "MISS" {
+		t.Errorf("list after submission: %s, want invalidated", got)
+	}
+	if got := read("/api/v1/jobs/" + jobRunning); got != "HIT" {
+		t.Errorf("unrelated job after submission: %s", got)
+	}
+}
+
+func cancelJob(t *testing.T, be Backend, id, ifMatch string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/jobs/"+id+"/cancel", nil)
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	return serveRequest(t, &Server{Backend: be}, req)
+}
+
+func TestCancelJob(t *testing.T) {
+	var trail bytes.Buffer
+	audit.SetDefault(audit.New(&trail))
+	t.Cleanup(func() { audit.SetDefault(nil) })
+	be := jobBackend()
+	tag := getJob(t, be, jobRunning, "").Header().Get("ETag")
+
+	rec := cancelJob(t, be, jobRunning, tag)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	var got JobStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != StatusCancelled {
+		t.Errorf("status = %q, want cancelled", got.Status)
+	}
+	newTag := rec.Header().Get("ETag")
+	if newTag == "" || newTag == tag {
+		t.Errorf("ETag = %q after cancelling, was %q", newTag, tag)
+	}
+	if be.cancelled == nil || be.cancelled.IfUpdatedAt == nil || !be.cancelled.IfUpdatedAt.Equal(time.Date(2026, 1, 1, 12, 1, 0, 0, time.UTC)) {
+		t.Errorf("backend request = %+v", be.cancelled)
+	}
+	var record map[string]any
+	if err := json.Unmarshal(trail.Bytes(), &record); err != nil || record["action"] != audit.ActionJobCancel ||
+		record["outcome"] != audit.OutcomeSuccess || record["principal"] != "user-1" || record["job_id"] != jobRunning {
+		t.Errorf("audit record %s", &trail)
+	}
+
+	// Cancelling again with the new tag finds the job finished.
+	if rec := cancelJob(t, be, jobRunning, newTag); rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "job_finished") {
+		t.Errorf("cancelled twice: %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestCancelJobPreconditions(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      string
+		ifMatch func(tag string) string
+		status  int
+		code    string
+	}{
+		{"no If-Match", jobRunning, func(string) string { return "" }, http.StatusPreconditionRequired, "precondition_required"},
+		{"stale", jobRunning, func(string) string { return `"stale"` }, http.StatusPreconditionFailed, "precondition_failed"},
+		{"weak", jobRunning, func(tag string) string { return "W/" + tag }, http.StatusPreconditionFailed, "precondition_failed"},
+		{"wildcard", jobRunning, func(string) string { return "*" }, http.StatusOK, ""},
+		{"one of several", jobRunning, func(tag string) string { return `"stale", ` + tag }, http.StatusOK, ""},
+		{"completed", jobDone, func(tag string) string { return tag }, http.StatusConflict, "job_finished"},
+		{"failed", jobBroken, func(tag string) string { return tag }, http.StatusConflict, "job_finished"},
+		{"other owner", jobTheirs, func(string) string { return "*" }, http.StatusForbidden, "forbidden"},
+		{jobMissing, jobMissing, func(string) string { return "*" }, http.StatusNotFound, "not_found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			be := jobBackend()
+			tag := getJob(t, be, tt.id, "").Header().Get("ETag")
+			rec := cancelJob(t, be, tt.id, tt.ifMatch(tag))
+			if rec.Code != tt.status || !strings.Contains(rec.Body.String(), tt.code) {
+				t.Errorf("response = %d %s, want %d %s", rec.Code, rec.Body, tt.status, tt.code)
+			}
+			if tt.status != http.StatusOK && be.cancelled != nil {
+				t.Error("backend asked to cancel")
+			}
+		})
+	}
+}
+
+func retryJob(t *testing.T, s *Server, id, key string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/jobs/"+id+"/retry", nil)
+	if key != "" {
+		req.Header.Set(idempotency.Header, key)
+	}
+	return serveRequest(t, s, req)
+}
+
+func TestRetryJob(t *testing.T) {
+	be := jobBackend()
+	be.requests = map[string]*backend.CreateContentRequest{
+		jobBroken: {JobID: jobBroken, Topic: "a video about otters", Format: "video", Options: map[string]any{"length": float64(30)}, OwnerID: "user-1"},
+	}
+	rec := retryJob(t, &Server{Backend: be}, jobBroken, "")
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	var got JobAccepted
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.JobID == "" || got.JobID == jobBroken || got.RetryOf != jobBroken {
+		t.Errorf("body = %+v", got)
+	}
+	if loc := rec.Header().Get("Location"); loc != "/api/v1/jobs/"+got.JobID {
+		t.Errorf("Location = %q", loc)
+	}
+	if be.got == nil || be.got.JobID != got.JobID || be.got.Topic != "a video about otters" || be.got.Format != "video" ||
+		be.got.Options["length"] != float64(30) || be.got.OwnerID != "user-1" || be.got.RetryOf != jobBroken {
+		t.Errorf("backend request = %+v", be.got)
+	}
+}
+
+func TestRetryJobRefused(t *testing.T) {
+	tests := []struct {
+		id     string
+		status int
+		code   string
+	}{
+		{jobRunning, http.StatusConflict, "job_not_failed"},
+		{jobDone, http.StatusConflict, "the job is completed"},
+		{jobTheirs, http.StatusForbidden, "forbidden"},
+		{jobMissing, http.StatusNotFound, "not_found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			be := jobBackend()
+			rec := retryJob(t, &Server{Backend: be}, tt.id, "")
+			if rec.Code != tt.status || !strings.Contains(rec.Body.String(), tt.code) {
+				t.Errorf("response = %d %s, want %d %s", rec.Code, rec.Body, tt.status, tt.code)
+			}
+			if be.got != nil {
+				t.Error("job submitted")
+			}
+		})
+	}
+}
+
+func TestRetryJobRevalidates(t *testing.T) {
+	be := jobBackend()
+	be.requests = map[string]*backend.CreateContentRequest{jobBroken: {Topic: "otters", Format: "video"}}
+	s := &Server{Backend: be}
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/jobs/"+jobBroken+"/retry", nil)
+	req = req.WithContext(gateway.WithTenant(req.Context(), &gateway.Tenant{ID: "acme", Formats: []string{"article"}}))
+	rec := serveRequest(t, s, req)
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "not available to this tenant") {
+		t.Errorf("response = %d %s, want 422 for the tenant's formats", rec.Code, rec.Body)
+	}
+}
+
+func TestRetryJobIdempotent(t *testing.T) {
+	be := jobBackend()
+	be.requests = map[string]*backend.CreateContentRequest{jobBroken: {Topic: "otters", Format: "video"}}
+	s := &Server{Backend: be, Idempotency: idempotency.NewMemoryStore()}
+	first := retryJob(t, s, jobBroken, "retry-1")
+	be.got = nil
+	again := retryJob(t, s, jobBroken, "retry-1")
+	if first.Code != http.StatusAccepted || again.Body.String() != first.Body.String() || again.Header().Get(idempotency.ReplayedHeader) != "true" {
+		t.Errorf("repeat = %d %s, want a replay of %s", again.Code, again.Body, first.Body)
+	}
+	if be.got != nil {
+		t.Error("repeat submitted a second job")
+	}
+}
+
+// racingBackend reports a job's state and then moves it on, as though it
+// progressed between the gateway's read and its cancellation.
+type racingBackend struct{ *fakeBackend }
+
+func (b racingBackend) GetJobStatus(ctx context.Context, jobID string) (*backend.JobStatusResponse, error) {
+	job, err := b.fakeBackend.GetJobStatus(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	seen := *job
+	job.ProgressPercent += 5
+	job.UpdatedAt = job.UpdatedAt.Add(time.Second)
+	return &seen, nil
+}
+
+func TestCancelJobRacingUpdate(t *testing.T) {
+	be := jobBackend()
+	tag := getJob(t, be, jobRunning, "").Header().Get("ETag")
+	rec := cancelJob(t, racingBackend{be}, jobRunning, tag)
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("status = %d, want 412; body %s", rec.Code, rec.Body)
+	}
+	if be.jobs[jobRunning].Status != StatusProcessing {
+		t.Errorf("job status = %q, want it left running", be.jobs[jobRunning].Status)
+	}
+}
+
+// heldBackend holds GetJobStatus calls until release is closed.
+type heldBackend struct {
+	*fakeBackend
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (b *heldBackend) GetJobStatus(ctx context.Context, jobID string) (*backend.JobStatusResponse, error) {
+	b.calls.Add(1)
+	<-b.release
+	return b.fakeBackend.GetJobStatus(ctx, jobID)
+}
+
+// concurrentGets makes n simultaneous GETs of path on s, releasing be once
+// the first call reached it and the rest have had time to join it.
+func concurrentGets(t *testing.T, s *Server, be *heldBackend, n int, path string) []*httptest.ResponseRecorder {
+	t.Helper()
+	rt := newRouter(s)
+	recs := make([]*httptest.ResponseRecorder, n)
+	var wg sync.WaitGroup
+	for i := range recs {
+		wg.Go(func() {
+			recs[i] = httptest.NewRecorder()
+			rt.ServeHTTP(recs[i], httptest.NewRequest(http.MethodGet, path, nil))
+		})
+	}
+	for be.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(be.release)
+	wg.Wait()
+	return recs
+}
+
+func TestGetJobSharesBackendCall(t *testing.T) {
+	be := &heldBackend{fakeBackend: jobBackend(), release: make(chan struct{})}
+	s := &Server{Backend: be}
+	before := deduplicated.With("GetJobStatus").Value()
+
+	for i, rec := range concurrentGets(t, s, be, 5, "/api/v1/jobs/"+jobDone) {
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"job_id":"`+jobDone+`"`) {
+			t.Errorf("request %d: %d %s", i, rec.Code, rec.Body)
+		}
+	}
+	if got := be.calls.Load(); got != 1 {
+		t.Errorf("backend called %d times, want 1", got)
+	}
+	if got := deduplicated.With("GetJobStatus").Value() - before; got != 4 {
+		t.Errorf("deduplicated = %v, want 4", got)
+	}
+
+	// Later requests make calls of their own.
+	be.release = make(chan struct{})
+	close(be.release)
+	serveRequest(t, s, httptest.NewRequest(http.MethodGet, "/api/v1/jobs/"+jobDone, nil))
+	if got := be.calls.Load(); got != 2 {
+		t.Errorf("backend called %d times after the flight, want 2", got)
+	}
+}
+
+func TestSharedBackendErrorReachesEveryone(t *testing.T) {
+	be := &heldBackend{fakeBackend: jobBackend(), release: make(chan struct{})}
+	be.err = rpc.Errorf(rpc.Unavailable, "backend down")
+	for i, rec := range concurrentGets(t, &Server{Backend: be}, be, 3, "/api/v1/jobs/"+jobDone) {
+		if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), `"code":"backend_unavailable"`) {
+			t.Errorf("request %d: %d %s", i, rec.Code, rec.Body)
+		}
+	}
+	if got := be.calls.Load(); got != 1 {
+		t.Errorf("backend called %d times, want 1", got)
+	}
+}
+
+func TestSharedCallSurvivesLeaderCancel(t *testing.T) {
+	be := &heldBackend{fakeBackend: jobBackend(), release: make(chan struct{})}
+	rt := newRouter(&Server{Backend: be})
+	get := func(ctx context.Context, done chan<- *httptest.ResponseRecorder) {
+		rec := httptest.NewRecorder()
+		rt.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/jobs/"+jobDone, nil).WithContext(ctx))
+		done <- rec
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := make(chan *httptest.ResponseRecorder)
+	go get(ctx, leader)
+	for be.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	follower := make(chan *httptest.ResponseRecorder)
+	go get(context.Background(), follower)
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	<-leader
+	close(be.release)
+	if rec := <-follower; rec.Code != http.StatusOK {
+		t.Errorf("follower after the leader left: %d %s", rec.Code, rec.Body)
+	}
+	if got := be.calls.Load(); got != 1 {
+		t.Errorf("backend called %d times, want 1", got)
+	}
+}
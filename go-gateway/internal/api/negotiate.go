@@ -0,0 +1,28 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/jsonbody"
+	"github.com/content-factory/go-gateway/internal/respond"
+)
+
+// acceptable answers 406 to requests whose Accept header takes neither
+// JSON nor MessagePack. It guards the routes that write documents, not
+// downloads and event streams, which have media types of their own.
+func acceptable(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := respond.Negotiate(r); !ok {
+			apierror.Write(w, r, apierror.New(http.StatusNotAcceptable, apierror.CodeNotAcceptable,
+				"responses are available as "+respond.MediaJSON+" or "+respond.MediaMsgPack))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// bodyOptions are the jsonbody options of the content request bodies.
+func (s *Server) bodyOptions() jsonbody.Options {
+	return jsonbody.Options{AllowUnknown: !s.StrictJSON}
+}
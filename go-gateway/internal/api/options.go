@@ -0,0 +1,145 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/openapi"
+)
+
+// optionKind is the JSON type an option must have.
+type optionKind int
+
+const (
+	kindNumber optionKind = iota
+	kindInteger
+	kindString
+	kindBool
+)
+
+// optionSpec constrains one key of ContentRequest.Options. Min and Max
+// bound numbers and integers, MaxLen strings; Enum, if set, lists the only
+// allowed strings.
+type optionSpec struct {
+	kind     optionKind
+	min, max float64
+	maxLen   int
+	enum     []string
+}
+
+// maxTokensCap bounds max_tokens; the text models accept no more.
+const maxTokensCap = 32768
+
+// optionSchema lists every option the content service understands.
+var optionSchema = map[string]optionSpec{
+	"temperature":  {kind: kindNumber, min: 0, max: 2},
+	"max_tokens":   {kind: kindInteger, min: 1, max: maxTokensCap},
+	"length":       {kind: kindInteger, min: 1, max: 3600}, // seconds of audio or video
+	"style":        {kind: kindString, maxLen: 200},
+	"tone":         {kind: kindString, maxLen: 200},
+	"voice":        {kind: kindString, maxLen: 100},
+	"language":     {kind: kindString, maxLen: 35}, // BCP 47 tag
+	"aspect_ratio": {kind: kindString, enum: []string{"16:9", "9:16", "1:1", "4:5"}},
+	"resolution":   {kind: kindString, enum: []string{"720p", "1080p", "4k"}},
+	"captions":     {kind: kindBool},
+}
+
+// validateOptions checks opts against optionSchema and trims surrounding
+// whitespace from string values in place. Problems are reported in key
+// order so responses are stable.
+func validateOptions(opts map[string]any) []apierror.FieldError {
+	keys := make([]string, 0, len(opts))
+	for k := range opts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var errs []apierror.FieldError
+	for _, k := range keys {
+		field := "options." + k
+		spec, ok := optionSchema[k]
+		if !ok {
+			errs = append(errs, apierror.FieldError{Field: field, Message: "is not a supported option"})
+			continue
+		}
+		v, bad := spec.check(field, opts[k])
+		if bad != nil {
+			errs = append(errs, bad...)
+			continue
+		}
+		opts[k] = v
+	}
+	return errs
+}
+
+// check returns v, sanitized, or the problems with it, reported against
+// field. The JSON type is checked here; bounds and allowed values by the
+// validator, against tag.
+func (s optionSpec) check(field string, v any) (any, []apierror.FieldError) {
+	invalid := func(msg string) (any, []apierror.FieldError) {
+		return nil, []apierror.FieldError{{Field: field, Message: msg}}
+	}
+	switch s.kind {
+	case kindNumber, kindInteger:
+		n, ok := v.(float64)
+		if !ok {
+			return invalid("must be a number")
+		}
+		if s.kind == kindInteger && n != math.Trunc(n) {
+			return invalid("must be an integer")
+		}
+		v = n
+	case kindString:
+		str, ok := v.(string)
+		if !ok {
+			return invalid("must be a string")
+		}
+		v = strings.TrimSpace(str)
+	case kindBool:
+		if _, ok := v.(bool); !ok {
+			return invalid("must be true or false")
+		}
+		return v, nil
+	}
+	if errs := fieldErrors(field, bodies.Var(v, s.tag())); errs != nil {
+		return nil, errs
+	}
+	return v, nil
+}
+
+// tag is the validator tag enforcing the spec's bounds.
+func (s optionSpec) tag() string {
+	switch {
+	case s.kind == kindNumber || s.kind == kindInteger:
+		return fmt.Sprintf("min=%g,max=%g", s.min, s.max)
+	case s.enum != nil:
+		return "oneof=" + strings.Join(s.enum, " ")
+	}
+	return "required,max=" + strconv.Itoa(s.maxLen)
+}
+
+// schema documents the option for the OpenAPI description.
+func (s optionSpec) schema() *openapi.Schema {
+	switch s.kind {
+	case kindNumber, kindInteger:
+		out := &openapi.Schema{Type: "number", Minimum: &s.min, Maximum: &s.max}
+		if s.kind == kindInteger {
+			out.Type = "integer"
+		}
+		return out
+	case kindString:
+		out := &openapi.Schema{Type: "string"}
+		for _, v := range s.enum {
+			out.Enum = append(out.Enum, v)
+		}
+		if s.enum == nil {
+			out.MaxLength = &s.maxLen
+		}
+		return out
+	}
+	return &openapi.Schema{Type: "boolean"}
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/backend"
+)
+
+// Page sizes of list endpoints, when Server.DefaultPageSize and
+// Server.MaxPageSize are zero. Used by the config package.
+const (
+	DefaultPageSize    = 20
+	DefaultMaxPageSize = 100
+)
+
+// Page is the envelope of list responses.
+type Page[T any] struct {
+	Items []T `json:"items"`
+	// NextCursor fetches the following page; "" once the list is exhausted.
+	NextCursor string `json:"next_cursor"`
+	// Total counts the items of every page, when the client asked for it
+	// with include_total. TotalApproximate marks an estimate.
+	Total            *int64 `json:"total,omitempty"`
+	TotalApproximate bool   `json:"total_approximate,omitempty"`
+}
+
+// cursor is the decoded form of a pagination cursor. Clients only ever
+// see it base64-encoded, so its contents can change without breaking them.
+type cursor struct {
+	PageToken string `json:"t"`
+}
+
+var errBadCursor = errors.New("malformed cursor")
+
+func encodeCursor(c cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	if s == "" {
+		return c, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || json.Unmarshal(b, &c) != nil || c.PageToken == "" {
+		return c, errBadCursor
+	}
+	return c, nil
+}
+
+// pageParams reads the limit and cursor query parameters, limit being
+// def when absent. It writes a 400 and reports false when either is
+// malformed, or limit is above max: clamping it would hide the mistake of
+// a client paging by its own page size.
+func pageParams(w http.ResponseWriter, r *http.Request, def, max int) (limit int, c cursor, ok bool) {
+	q := r.URL.Query()
+	limit = def
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			apierror.Write(w, r, queryError("limit", "must be a positive integer"))
+			return 0, c, false
+		}
+		if n > max {
+			apierror.Write(w, r, queryError("limit", "must be at most "+strconv.Itoa(max)))
+			return 0, c, false
+		}
+		limit = n
+	}
+	c, err := decodeCursor(q.Get("cursor"))
+	if err != nil {
+		apierror.Write(w, r, queryError("cursor", "must be a next_cursor returned by a previous page"))
+		return 0, c, false
+	}
+	return limit, c, true
+}
+
+// totalParam reads the include_total query parameter as the total to ask
+// the backend for: true or exact counts exactly, approximate asks for a
+// cheaper estimate, and false or none counts nothing. It writes a 400 and
+// reports false for any other value.
+func totalParam(w http.ResponseWriter, r *http.Request) (total string, ok bool) {
+	switch v := r.URL.Query().Get("include_total"); v {
+	case "", "false":
+		return "", true
+	case "true", backend.TotalExact:
+		return backend.TotalExact, true
+	case backend.TotalApproximate:
+		return backend.TotalApproximate, true
+	default:
+		apierror.Write(w, r, queryError("include_total", "must be true, false, exact or approximate"))
+		return "", false
+	}
+}
+
+func queryError(param, msg string) *apierror.Error {
+	return apierror.InvalidRequest("query parameters failed validation", apierror.FieldError{Field: param, Message: msg})
+}
+
+// setNextLink adds an RFC 8288 Link header pointing at the page after r's,
+// keeping r's other query parameters.
+func setNextLink(w http.ResponseWriter, r *http.Request, next string, limit int) {
+	if next == "" {
+		return
+	}
+	q := r.URL.Query()
+	q.Set("cursor", next)
+	q.Set("limit", strconv.Itoa(limit))
+	u := url.URL{Path: r.URL.Path, RawQuery: q.Encode()}
+	w.Header().Add("Link", "<"+u.String()+`>; rel="next"`)
+}
+
+func (s *Server) defaultPageSize() int {
+	if s.DefaultPageSize <= 0 {
+		return min(DefaultPageSize, s.maxPageSize())
+	}
+	return s.DefaultPageSize
+}
+
+func (s *Server) maxPageSize() int {
+	if s.MaxPageSize <= 0 {
+		return DefaultMaxPageSize
+	}
+	return s.MaxPageSize
+}
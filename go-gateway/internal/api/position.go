@@ -0,0 +1,104 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/backend"
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/openapi"
+	"github.com/content-factory/go-gateway/internal/queue"
+	"github.com/content-factory/go-gateway/internal/router"
+)
+
+// JobPosition is the body of GET /api/v1/jobs/{id}/position.
+type JobPosition struct {
+	JobID string `json:"job_id"`
+	// Status is queued while the job waits in the gateway's submission
+	// queue, and else the job's status per the content service.
+	Status string `json:"status"`
+	// Position is the job's place in the queue while it waits there, 1
+	// being the job submitted next.
+	Position int `json:"position,omitempty"`
+	// Estimate is when a waiting job might leave the queue, once the
+	// gateway has seen how fast jobs leave it.
+	Estimate *WaitEstimate `json:"estimate,omitempty"`
+	// Progress is that of a job that has left the queue.
+	Progress float64 `json:"progress,omitempty"`
+}
+
+// DescribeSchema implements openapi.Describer.
+func (JobPosition) DescribeSchema(s *openapi.Schema) {
+	s.Properties["status"].Enum = []any{StatusQueued, StatusProcessing, StatusCompleted, StatusFailed, StatusCancelled}
+}
+
+// WaitEstimate is a rough forecast from the recent throughput of the
+// queue; Approximate is always true, to say so.
+type WaitEstimate struct {
+	// WaitSeconds is how long until the job is submitted, rounded up.
+	WaitSeconds int64 `json:"wait_seconds"`
+	// ThroughputPerSecond is the moving average of jobs leaving the queue
+	// per second that WaitSeconds is worked out from.
+	ThroughputPerSecond float64 `json:"throughput_per_second"`
+	Approximate         bool    `json:"approximate"`
+}
+
+// getJobPosition reports where a job waits in the submission queue to its
+// owner, with a guess at how long it will wait, or its status once it has
+// left the queue. Waiting jobs are found without calling the backend, so
+// clients may poll it.
+func (s *Server) getJobPosition(w http.ResponseWriter, r *http.Request) {
+	id := router.Param(r, "id")
+	ctx := r.Context()
+	claims := gateway.ClaimsFromContext(ctx)
+	if s.Queue != nil {
+		place, err := s.Queue.Position(ctx, id)
+		switch {
+		case err == nil:
+			if claims == nil || place.OwnerID != claims.Subject || place.Tenant != gateway.TenantID(ctx) {
+				forbid(w, r, "the job belongs to another user")
+				return
+			}
+			writeWithETag(w, r, JobPosition{JobID: id, Status: StatusQueued, Position: place.Position, Estimate: s.waitEstimate(place.Position)})
+			return
+		case !errors.Is(err, queue.ErrNotQueued):
+			gateway.Logger(ctx).ErrorContext(ctx, "failed to find a job in the queue", "job_id", id, "error", err)
+			apierror.Write(w, r, apierror.New(http.StatusServiceUnavailable, apierror.CodeQueueUnavailable, "the submission queue is unavailable"))
+			return
+		}
+	}
+	// Shared with the polls of GET /api/v1/jobs/{id}.
+	status, err := shared(s, ctx, "GetJobStatus", []string{principal(ctx), id}, func(ctx context.Context) (*backend.JobStatusResponse, error) {
+		return s.Backend.GetJobStatus(ctx, id)
+	})
+	if err != nil {
+		apierror.Write(w, r, apierror.FromRPC(err))
+		return
+	}
+	if claims == nil || status.OwnerID != claims.Subject {
+		forbid(w, r, "the job belongs to another user")
+		return
+	}
+	writeWithETag(w, r, JobPosition{JobID: id, Status: status.Status, Progress: status.ProgressPercent})
+}
+
+// waitEstimate is the estimate for a job at position, or nil while the
+// queue's throughput is unknown or nothing is leaving it.
+func (s *Server) waitEstimate(position int) *WaitEstimate {
+	if s.QueueThroughput == nil {
+		return nil
+	}
+	wait, ok := s.QueueThroughput.Wait(position)
+	if !ok {
+		return nil
+	}
+	rate, _ := s.QueueThroughput.Rate()
+	return &WaitEstimate{
+		WaitSeconds:         int64(math.Ceil(wait.Seconds())),
+		ThroughputPerSecond: math.Round(rate*1000) / 1000,
+		Approximate:         true,
+	}
+}
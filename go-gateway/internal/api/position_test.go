@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/backend"
+	"github.com/content-factory/go-gateway/internal/queue"
+)
+
+func getPosition(t *testing.T, s *Server, id string) (*httptest.ResponseRecorder, JobPosition) {
+	t.Helper()
+	rec := serveRequest(t, s, httptest.NewRequest(http.MethodGet, "/api/v1/jobs/"+id+"/position", nil))
+	var got JobPosition
+	json.Unmarshal(rec.Body.Bytes(), &got)
+	return rec, got
+}
+
+func TestGetJobPosition(t *testing.T) {
+	ctx := context.Background()
+	q := queue.NewMemory(10)
+	s := &Server{Backend: jobBackend(), Queue: q, QueueThroughput: &queue.Throughput{}}
+	const (
+		first  = "6f1c2a9e-0b4d-4c1e-9a52-1b7d3e000010"
+		mine   = "6f1c2a9e-0b4d-4c1e-9a52-1b7d3e000011"
+		theirs = "6f1c2a9e-0b4d-4c1e-9a52-1b7d3e000012"
+	)
+	for _, j := range []struct{ id, owner string }{{first, "user-1"}, {mine, "user-1"}, {theirs, "user-2"}} {
+		q.Enqueue(ctx, &queue.Job{Request: backend.CreateContentRequest{JobID: j.id, OwnerID: j.owner}})
+	}
+
+	rec, got := getPosition(t, s, mine)
+	if rec.Code != http.StatusOK || got.Status != StatusQueued || got.Position != 2 || got.Estimate != nil {
+		t.Fatalf("before any throughput: status %d, body %s", rec.Code, rec.Body)
+	}
+	if rec.Header().Get("ETag") == "" {
+		t.Error("no ETag")
+	}
+
+	start := time.Now()
+	s.QueueThroughput.Observe(0, 3, start)
+	s.QueueThroughput.Observe(4, 3, start.Add(10*time.Second))
+	rec, got = getPosition(t, s, mine)
+	if e := got.Estimate; rec.Code != http.StatusOK || e == nil || !e.Approximate || e.WaitSeconds != 5 || e.ThroughputPerSecond != 0.4 {
+		t.Errorf("with throughput: status %d, body %s", rec.Code, rec.Body)
+	}
+
+	if rec, _ := getPosition(t, s, theirs); rec.Code != http.StatusForbidden {
+		t.Errorf("another user's queued job: status %d", rec.Code)
+	}
+
+	// Jobs that have left the queue, or were never on it, are the
+	// backend's.
+	rec, got = getPosition(t, s, jobRunning)
+	if rec.Code != http.StatusOK || got.Status != StatusProcessing || got.Progress != 40 || got.Position != 0 || got.Estimate != nil {
+		t.Errorf("running job: status %d, body %s", rec.Code, rec.Body)
+	}
+	for id, want := range map[string]int{jobTheirs: http.StatusForbidden, jobMissing: http.StatusNotFound} {
+		if rec, _ := getPosition(t, s, id); rec.Code != want {
+			t.Errorf("%s: status %d, want %d", id, rec.Code, want)
+		}
+	}
+
+	s.Queue = brokenQueue{}
+	if rec, _ := getPosition(t, s, mine); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("broken queue: status %d, body %s", rec.Code, rec.Body)
+	}
+
+	// Without a queue the backend answers for every job.
+	rec, got = getPosition(t, &Server{Backend: jobBackend()}, jobDone)
+	if rec.Code != http.StatusOK || got.Status != StatusCompleted {
+		t.Errorf("without a queue: status %d, body %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/audit"
+	"github.com/content-factory/go-gateway/internal/backend"
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/jsonbody"
+	"github.com/content-factory/go-gateway/internal/router"
+)
+
+// PublicContentPrefix is the path the jobs their owners made public are
+// served under without credentials, followed by the job ID.
+const PublicContentPrefix = "/api/v1/public/content/"
+
+// Defaults of the public content rate limit, used by the config package.
+// They are well below ratelimit's, since anyone may call the route.
+const (
+	DefaultPublicRPS   = 1
+	DefaultPublicBurst = 10
+)
+
+// publicMaxAge is how long browsers and shared caches may keep public
+// content, and so how long a job made private again may still be seen.
+const publicMaxAge = time.Minute
+
+// JobVisibility is the body of PUT /api/v1/jobs/{id}/visibility.
+type JobVisibility struct {
+	// Public lets anyone read the job, once it has completed, at
+	// GET /api/v1/public/content/{id}; false makes it private again.
+	Public *bool `json:"public" validate:"required"`
+}
+
+// PublicContent is a completed job its owner made public, as anyone may
+// read it: its result, without the owner's own details.
+type PublicContent struct {
+	JobID     string    `json:"job_id"`
+	Result    JobResult `json:"result"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// setJobVisibility makes one of the caller's jobs public, or private
+// again. The 200 response is the job with its new ETag.
+func (s *Server) setJobVisibility(w http.ResponseWriter, r *http.Request) {
+	var req JobVisibility
+	if e := jsonbody.Decode(r, &req, s.bodyOptions()); e != nil {
+		apierror.Write(w, r, e)
+		return
+	}
+	if errs := bodyErrors(&req); errs != nil {
+		apierror.Write(w, r, apierror.Validation(errs...))
+		return
+	}
+	ctx := r.Context()
+	id := router.Param(r, "id")
+	current, err := s.Backend.GetJobStatus(ctx, id)
+	if err != nil {
+		apierror.Write(w, r, apierror.FromRPC(err))
+		return
+	}
+	claims := gateway.ClaimsFromContext(ctx)
+	if claims == nil || current.OwnerID != claims.Subject {
+		forbid(w, r, "the job belongs to another user")
+		return
+	}
+	updated, err := s.Backend.SetJobVisibility(ctx, &backend.SetJobVisibilityRequest{JobID: id, Public: *req.Public})
+	if err != nil {
+		audit.Record(ctx, audit.ActionJobVisibility, audit.OutcomeFailure, slog.String("job_id", id), slog.String("error", err.Error()))
+		apierror.Write(w, r, apierror.FromRPC(err))
+		return
+	}
+	audit.Record(ctx, audit.ActionJobVisibility, audit.OutcomeSuccess, slog.String("job_id", id), slog.Bool("public", updated.Public))
+	s.invalidate(ctx, "/api/v1/jobs", "/api/v1/jobs/"+id)
+	writeWithETag(w, r, s.jobStatus(ctx, updated))
+}
+
+// publicURL is where anyone can read the public job id. A tenant's job
+// carries its tenant, signed, so that the read reaches the tenant's
+// content service; the config package ensures there is a key to sign it
+// with.
+func (s *Server) publicURL(ctx context.Context, id string) string {
+	tenant := gateway.TenantID(ctx)
+	if tenant == "" || s.SignedURLs == nil {
+		return PublicContentPrefix + id
+	}
+	return s.SignedURLs.Link(PublicContentPrefix+id, url.Values{tenantParam: {tenant}})
+}
+
+// publicContent serves a completed job its owner made public to anyone,
+// without credentials. A job that is private, unfinished or unknown is
+// not found alike, so the route cannot tell a caller which IDs exist,
+// and so is one whose URL names a tenant without a valid signature.
+// Shared caches may keep the response for publicMaxAge.
+func (s *Server) publicContent(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if query := r.URL.Query(); query.Has(tenantParam) {
+		if s.SignedURLs == nil || s.SignedURLs.VerifyLink(r.URL.Path, query) != nil {
+			apierror.Write(w, r, publicNotFound())
+			return
+		}
+		ctx = gateway.WithTenant(ctx, &gateway.Tenant{ID: query.Get(tenantParam)})
+		r = r.WithContext(ctx)
+	}
+	id := router.Param(r, "id")
+	// Popular links share one backend call; there is no principal.
+	status, err := shared(s, ctx, "GetJobStatus", []string{"", id}, func(ctx context.Context) (*backend.JobStatusResponse, error) {
+		return s.Backend.GetJobStatus(ctx, id)
+	})
+	if err != nil {
+		e := apierror.FromRPC(err)
+		if e.Code == apierror.CodeNotFound {
+			e = publicNotFound()
+		}
+		apierror.Write(w, r, e)
+		return
+	}
+	if !status.Public || status.Status != StatusCompleted {
+		apierror.Write(w, r, publicNotFound())
+		return
+	}
+	writeTagged(w, r, PublicContent{
+		JobID:     status.JobID,
+		Result:    JobResult{URL: status.ResultURL, ThumbnailURL: status.ThumbnailURL},
+		CreatedAt: status.CreatedAt,
+		UpdatedAt: status.UpdatedAt,
+	}, "public, max-age="+strconv.Itoa(int(publicMaxAge/time.Second)))
+}
+
+func publicNotFound() *apierror.Error {
+	return apierror.NotFound("no public content with this ID")
+}
@@ -0,0 +1,194 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/backend"
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/router"
+	"github.com/content-factory/go-gateway/internal/signedurl"
+)
+
+// publicServer serves s's routes, the public ones without credentials,
+// and counts the requests that go through s.PublicContent.
+func publicServer(s *Server) (*router.Router, *int) {
+	var limited int
+	s.PublicContent = func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limited++
+			h.ServeHTTP(w, r)
+		})
+	}
+	return signedServer(s), &limited
+}
+
+func setVisibility(rt *router.Router, id, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/jobs/"+id+"/visibility", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	rt.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestSetJobVisibility(t *testing.T) {
+	be := jobBackend()
+	rt, _ := publicServer(&Server{Backend: be})
+
+	rec := setVisibility(rt, jobDone, `{"public": true}`)
+	var got JobStatus
+	json.Unmarshal(rec.Body.Bytes(), &got)
+	if rec.Code != http.StatusOK || !got.Public || got.PublicURL != PublicContentPrefix+jobDone || rec.Header().Get("ETag") == "" {
+		t.Fatalf("status %d, body %s", rec.Code, rec.Body)
+	}
+	if !be.jobs[jobDone].Public {
+		t.Error("the backend job was not made public")
+	}
+	rec = setVisibility(rt, jobDone, `{"public": false}`)
+	if rec.Code != http.StatusOK || be.jobs[jobDone].Public {
+		t.Errorf("making it private: status %d, body %s", rec.Code, rec.Body)
+	}
+
+	for name, tt := range map[string]struct {
+		id, body string
+		status   int
+	}{
+		"another user's": {jobTheirs, `{"public": true}`, http.StatusForbidden},
+		"unknown":        {jobMissing, `{"public": true}`, http.StatusNotFound},
+		"no public":      {jobDone, `{}`, http.StatusUnprocessableEntity},
+		"not JSON":       {jobDone, `public`, http.StatusBadRequest},
+	} {
+		if rec := setVisibility(rt, tt.id, tt.body); rec.Code != tt.status {
+			t.Errorf("%s: status %d, want %d; body %s", name, rec.Code, tt.status, rec.Body)
+		}
+	}
+	if be.jobs[jobTheirs].Public {
+		t.Error("another user's job was made public")
+	}
+}
+
+func TestPublicContent(t *testing.T) {
+	be := jobBackend()
+	be.jobs[jobDone].Public = true
+	be.jobs[jobRunning].Public = true
+	rt, limited := publicServer(&Server{Backend: be})
+
+	rec := fetch(rt, PublicContentPrefix+jobDone)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d, body %s", rec.Code, rec.Body)
+	}
+	var got PublicContent
+	json.Unmarshal(rec.Body.Bytes(), &got)
+	if got.JobID != jobDone || got.Result.URL != "https://cdn.example.com/done.mp4" {
+		t.Errorf("body = %s", rec.Body)
+	}
+	if strings.Contains(rec.Body.String(), "user-1") {
+		t.Errorf("body %s names the owner", rec.Body)
+	}
+	if cc := rec.Header().Get("Cache-Control"); cc != "public, max-age=60" {
+		t.Errorf("Cache-Control = %q", cc)
+	}
+	if *limited != 1 {
+		t.Errorf("went through the public content chain %d times, want 1", *limited)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, PublicContentPrefix+jobDone, nil)
+	req.Header.Set("If-None-Match", rec.Header().Get("ETag"))
+	rec = httptest.NewRecorder()
+	rt.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("revalidation: status %d", rec.Code)
+	}
+
+	// Private, unfinished and unknown jobs cannot be told apart.
+	missing := fetch(rt, PublicContentPrefix+jobMissing)
+	for name, id := range map[string]string{"private": jobBroken, "unfinished": jobRunning, "another user's": jobTheirs} {
+		rec := fetch(rt, PublicContentPrefix+id)
+		if rec.Code != http.StatusNotFound || rec.Body.String() != missing.Body.String() {
+			t.Errorf("%s: status %d, body %s; unknown got %d %s", name, rec.Code, rec.Body, missing.Code, missing.Body)
+		}
+	}
+}
+
+func TestPublicContentOff(t *testing.T) {
+	be := jobBackend()
+	be.jobs[jobDone].Public = true
+	rt := signedServer(&Server{Backend: be})
+	if rec := setVisibility(rt, jobDone, `{"public": true}`); rec.Code != http.StatusNotFound {
+		t.Errorf("visibility without public content: status %d", rec.Code)
+	}
+	if rec := fetch(rt, PublicContentPrefix+jobDone); rec.Code != http.StatusNotFound {
+		t.Errorf("public read without public content: status %d", rec.Code)
+	}
+}
+
+// tenantBackend sends job reads and changes to the backend of the
+// request's tenant, and the others' to the default one, as tenant.Backends
+// does.
+type tenantBackend struct {
+	*fakeBackend
+	tenants map[string]*fakeBackend
+}
+
+func (b tenantBackend) route(ctx context.Context) *fakeBackend {
+	if be, ok := b.tenants[gateway.TenantID(ctx)]; ok {
+		return be
+	}
+	return b.fakeBackend
+}
+
+func (b tenantBackend) GetJobStatus(ctx context.Context, jobID string) (*backend.JobStatusResponse, error) {
+	return b.route(ctx).GetJobStatus(ctx, jobID)
+}
+
+func (b tenantBackend) SetJobVisibility(ctx context.Context, req *backend.SetJobVisibilityRequest) (*backend.JobStatusResponse, error) {
+	return b.route(ctx).SetJobVisibility(ctx, req)
+}
+
+func TestPublicContentOfTenant(t *testing.T) {
+	acme := jobBackend()
+	be := tenantBackend{fakeBackend: &fakeBackend{}, tenants: map[string]*fakeBackend{"acme": acme}}
+	s := &Server{
+		Backend:       be,
+		SignedURLs:    signedurl.New(signedurl.Config{Key: signingKey, TTL: time.Minute}),
+		PublicContent: func(h http.Handler) http.Handler { return h },
+	}
+	// The protected routes are served as user-1 of acme.
+	rt := router.New()
+	s.Register(rt, func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := gateway.WithClaims(r.Context(), &gateway.Claims{Subject: "user-1", Tenant: "acme"})
+			h.ServeHTTP(w, r.WithContext(gateway.WithTenant(ctx, &gateway.Tenant{ID: "acme"})))
+		})
+	})
+	s.RegisterPublic(rt, func(h http.Handler) http.Handler { return h })
+
+	rec := setVisibility(rt, jobDone, `{"public": true}`)
+	var job JobStatus
+	json.Unmarshal(rec.Body.Bytes(), &job)
+	if rec.Code != http.StatusOK || !strings.HasPrefix(job.PublicURL, PublicContentPrefix+jobDone+"?") {
+		t.Fatalf("status %d, body %s", rec.Code, rec.Body)
+	}
+	if rec := fetch(rt, job.PublicURL); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), jobDone) {
+		t.Errorf("public URL: status %d, body %s", rec.Code, rec.Body)
+	}
+	// Without the tenant the read goes to the default backend.
+	if rec := fetch(rt, PublicContentPrefix+jobDone); rec.Code != http.StatusNotFound {
+		t.Errorf("without the tenant: status %d", rec.Code)
+	}
+	u, _ := url.Parse(job.PublicURL)
+	q := u.Query()
+	q.Set(tenantParam, "globex")
+	if rec := fetch(rt, u.Path+"?"+q.Encode()); rec.Code != http.StatusNotFound {
+		t.Errorf("another tenant: status %d, body %s", rec.Code, rec.Body)
+	}
+	if rec := fetch(rt, u.Path+"?"+tenantParam+"=acme"); rec.Code != http.StatusNotFound {
+		t.Errorf("unsigned tenant: status %d, body %s", rec.Code, rec.Body)
+	}
+}
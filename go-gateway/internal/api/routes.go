@@ -0,0 +1,365 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/openapi"
+	"github.com/content-factory/go-gateway/internal/signedurl"
+)
+
+// mountKind selects the middleware a route is served behind.
+type mountKind int
+
+const (
+	mountPlain mountKind = iota
+	mountCached
+	mountStream
+	mountUpload
+)
+
+// route is one API endpoint: how it is served and how it is documented.
+// Register and Describe both read s.routes, so the OpenAPI document lists
+// exactly the routes served.
+type route struct {
+	method, pattern string
+	mount           mountKind
+	handler         http.Handler
+	doc             openapi.Operation
+	// flag, if set, names the feature flag the route is served behind.
+	// The route answers 404 while the flag is off, and is left out of the
+	// OpenAPI document.
+	flag string
+	// weight, if above one, is how many tokens of the client's rate limit
+	// each request takes.
+	weight int
+	// chain, on a public route, replaces the chain RegisterPublic is
+	// given.
+	chain func(http.Handler) http.Handler
+}
+
+func (s *Server) routes() []route {
+	idParam := openapi.Param{Name: "id", In: "path"}
+	routes := []route{
+		{
+			method: http.MethodPost, pattern: "/api/v1/content",
+			handler: s.contentHandler(),
+			doc: openapi.Operation{
+				Summary: "Submit a content generation job",
+				Tags:    []string{"content"},
+				Params: []openapi.Param{
+					{Name: "Idempotency-Key", In: "header",
+						Description: "Makes retries safe: a repeat with the same key and body replays the first response."},
+					{Name: "dry_run", In: "query", Type: false,
+						Description: "Validate and price the request without submitting it."},
+					{Name: DryRunHeader, In: "header", Description: "Like dry_run."},
+				},
+				Request: openapi.JSON(ContentRequest{}),
+				Responses: withErrors([]openapi.Response{
+					{Status: http.StatusAccepted, Description: "The job was queued.",
+						Body: openapi.JSON(JobAccepted{}), Headers: []string{"Location"}},
+					{Status: http.StatusOK, Description: "Dry run: the request would be accepted at this estimated cost.",
+						Body: openapi.JSON(ContentEstimate{})},
+				}, http.StatusBadRequest, http.StatusConflict, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity),
+			},
+		},
+		{
+			method: http.MethodPost, pattern: "/api/v1/content/batch",
+			handler: s.available(CapabilitySubmission, s.idempotent(http.HandlerFunc(s.createContentBatch))),
+			doc: openapi.Operation{
+				Summary: "Submit several content generation jobs",
+				Description: "Each request is validated and submitted on its own, at most " + strconv.Itoa(s.maxBatchItems()) +
+					" per batch. The results are in input order; a request that fails has the status and error body it would have got on its own.",
+				Tags: []string{"content"},
+				Params: []openapi.Param{{Name: "Idempotency-Key", In: "header",
+					Description: "Makes retries safe: a repeat with the same key and body replays the first response."}},
+				Request: openapi.JSON([]ContentRequest{}),
+				Responses: withErrors([]openapi.Response{{
+					Status: http.StatusMultiStatus, Description: "The result of each request.",
+					Body: openapi.JSON([]BatchResult{}),
+				}}, http.StatusBadRequest, http.StatusConflict, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity),
+			},
+		},
+		{
+			method: http.MethodPost, pattern: "/api/v1/content/stream",
+			mount:   mountStream,
+			handler: http.HandlerFunc(s.streamContent),
+			doc: openapi.Operation{
+				Summary: "Generate text and stream it as it is produced",
+				Description: "Creates no job. Each line of the 200 response is a StreamEvent, flushed as it is generated: " +
+					"token events with the next piece of text, then a done event with the usage, or an error event " +
+					"if generation failed midway. Only article and social_post can be streamed.",
+				Tags:    []string{"content"},
+				Request: openapi.JSON(ContentRequest{}),
+				Responses: withErrors([]openapi.Response{{
+					Status: http.StatusOK, Description: "The generated text, one event per line.",
+					Body: &openapi.Body{ContentType: MediaNDJSON, Type: StreamEvent{}},
+				}}, http.StatusBadRequest, http.StatusNotAcceptable, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity),
+			},
+		},
+		{
+			method: http.MethodGet, pattern: "/api/v1/content/{id:uuid}/download",
+			mount:   mountStream,
+			handler: http.HandlerFunc(s.downloadContent),
+			doc: openapi.Operation{
+				Summary: "Download a finished content file",
+				Tags:    []string{"content"},
+				Params: []openapi.Param{idParam,
+					{Name: "Range", In: "header", Description: "A single byte range, e.g. bytes=1000-."},
+					{Name: "If-Range", In: "header", Description: "The ETag the range applies to."}},
+				Responses: withErrors([]openapi.Response{
+					{Status: http.StatusOK, Description: "The file.", Body: &openapi.Body{ContentType: "application/octet-stream"},
+						Headers: []string{"Content-Disposition", "ETag", "Accept-Ranges"}},
+					{Status: http.StatusPartialContent, Description: "The requested range of the file.",
+						Body: &openapi.Body{ContentType: "application/octet-stream"}, Headers: []string{"Content-Range"}},
+				}, http.StatusForbidden, http.StatusNotFound, http.StatusRequestedRangeNotSatisfiable),
+			},
+		},
+		{
+			method: http.MethodPost, pattern: "/api/v1/content/{id:uuid}/assets",
+			mount:   mountUpload,
+			handler: http.HandlerFunc(s.uploadAssets),
+			doc: openapi.Operation{
+				Summary:     "Attach reference files to a job",
+				Description: "Each file part must be one of the allowed asset types, checked against its contents.",
+				Tags:        []string{"content"},
+				Params:      []openapi.Param{idParam},
+				Request:     &openapi.Body{ContentType: "multipart/form-data"},
+				Responses: withErrors([]openapi.Response{{
+					Status: http.StatusCreated, Description: "The stored files.", Body: openapi.JSON([]Asset{}),
+				}}, http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusRequestEntityTooLarge,
+					http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity),
+			},
+		},
+		{
+			method: http.MethodGet, pattern: "/api/v1/jobs",
+			mount:   mountCached,
+			handler: http.HandlerFunc(s.listJobs),
+			doc: openapi.Operation{
+				Summary: "List the caller's jobs, newest first",
+				Tags:    []string{"jobs"},
+				Params: []openapi.Param{
+					{Name: "limit", In: "query", Type: 0,
+						Description: "Page size, default " + strconv.Itoa(s.defaultPageSize()) + ", at most " + strconv.Itoa(s.maxPageSize()) + "; larger is a 400."},
+					{Name: "cursor", In: "query", Description: "The next_cursor of the previous page."},
+					{Name: "include_total", In: "query",
+						Description: "true or exact to count all of the caller's jobs in total, approximate for a cheaper estimate. Off by default, since counting is expensive."},
+				},
+				Responses: withErrors([]openapi.Response{{
+					Status: http.StatusOK, Description: "One page of jobs.",
+					Body: openapi.JSON(Page[JobStatus]{}), Headers: []string{"Link"},
+				}}, http.StatusBadRequest),
+			},
+		},
+		{
+			method: http.MethodPost, pattern: "/api/v1/jobs/status",
+			mount:   mountPlain,
+			handler: http.HandlerFunc(s.getJobStatuses),
+			weight:  BulkStatusWeight,
+			doc: openapi.Operation{
+				Summary: "Get the current state of several jobs",
+				Description: "At most " + strconv.Itoa(s.maxStatusIDs()) + " IDs per request. The results are in request order; " +
+					"a job that cannot be fetched has the status and error body GET /api/v1/jobs/{id} would have got, " +
+					"except that another user's job is reported as not found. Each request counts as " +
+					strconv.Itoa(BulkStatusWeight) + " against the rate limit.",
+				Tags:    []string{"jobs"},
+				Request: openapi.JSON(BulkStatusRequest{}),
+				Responses: withErrors([]openapi.Response{{
+					Status: http.StatusOK, Description: "The state of each job.",
+					Body: openapi.JSON(BulkStatusResponse{}),
+				}}, http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity),
+			},
+		},
+		{
+			method: http.MethodGet, pattern: "/api/v1/jobs/{id:uuid}",
+			mount:   mountCached,
+			handler: http.HandlerFunc(s.getJob),
+			doc: openapi.Operation{
+				Summary: "Get a job's current state",
+				Tags:    []string{"jobs"},
+				Params:  []openapi.Param{idParam, {Name: "If-None-Match", In: "header"}},
+				Responses: withErrors([]openapi.Response{
+					{Status: http.StatusOK, Description: "The job.", Body: openapi.JSON(JobStatus{}), Headers: []string{"ETag"}},
+					{Status: http.StatusNotModified, Description: "The job is unchanged since the given ETag."},
+				}, http.StatusForbidden, http.StatusNotFound),
+			},
+		},
+		{
+			method: http.MethodGet, pattern: "/api/v1/jobs/{id:uuid}/position",
+			mount:   mountCached,
+			handler: http.HandlerFunc(s.getJobPosition),
+			doc: openapi.Operation{
+				Summary: "Get a job's place in the submission queue",
+				Description: "While the job waits in the gateway's submission queue, its position, 1 being next, and, once " +
+					"the gateway has seen jobs leave the queue, an approximate estimate of its wait from the moving average " +
+					"of jobs leaving per second. Once it has left the queue, its status and progress instead. " +
+					"Finding a waiting job does not call the content service, so the route is cheap to poll.",
+				Tags:   []string{"jobs"},
+				Params: []openapi.Param{idParam, {Name: "If-None-Match", In: "header"}},
+				Responses: withErrors([]openapi.Response{
+					{Status: http.StatusOK, Description: "The job's place or status.", Body: openapi.JSON(JobPosition{}), Headers: []string{"ETag"}},
+					{Status: http.StatusNotModified, Description: "The answer is unchanged since the given ETag."},
+				}, http.StatusForbidden, http.StatusNotFound, http.StatusServiceUnavailable),
+			},
+		},
+		{
+			method: http.MethodPost, pattern: "/api/v1/jobs/{id:uuid}/cancel",
+			mount:   mountPlain,
+			handler: http.HandlerFunc(s.cancelJob),
+			doc: openapi.Operation{
+				Summary: "Cancel a queued or processing job",
+				Tags:    []string{"jobs"},
+				Params: []openapi.Param{idParam, {Name: "If-Match", In: "header", Required: true,
+					Description: "The job's current ETag, from GET /api/v1/jobs/{id}."}},
+				Responses: withErrors([]openapi.Response{
+					{Status: http.StatusOK, Description: "The cancelled job.", Body: openapi.JSON(JobStatus{}), Headers: []string{"ETag"}},
+				}, http.StatusForbidden, http.StatusNotFound, http.StatusConflict,
+					http.StatusPreconditionFailed, http.StatusPreconditionRequired),
+			},
+		},
+		{
+			method: http.MethodPost, pattern: "/api/v1/jobs/{id:uuid}/retry",
+			mount:   mountPlain,
+			handler: s.available(CapabilitySubmission, s.idempotent(http.HandlerFunc(s.retryJob))),
+			doc: openapi.Operation{
+				Summary:     "Retry a failed job",
+				Description: "Submits the failed job's parameters again as a new job, whose retry_of is the failed job.",
+				Tags:        []string{"jobs"},
+				Params: []openapi.Param{idParam, {Name: "Idempotency-Key", In: "header",
+					Description: "Makes retries safe: a repeat with the same key replays the first response."}},
+				Responses: withErrors([]openapi.Response{
+					{Status: http.StatusAccepted, Description: "The new job was queued.",
+						Body: openapi.JSON(JobAccepted{}), Headers: []string{"Location"}},
+				}, http.StatusForbidden, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity),
+			},
+		},
+	}
+	if s.SignedURLs != nil {
+		routes = append(routes, s.signedURLRoutes(idParam)...)
+	}
+	if s.PublicContent != nil {
+		routes = append(routes, s.publicContentRoutes(idParam)...)
+	}
+	return routes
+}
+
+// publicContentRoutes are the routes of public content: making a job
+// public, and reading it without credentials.
+func (s *Server) publicContentRoutes(idParam openapi.Param) []route {
+	readResponses := []openapi.Response{
+		{Status: http.StatusOK, Description: "The job's result.", Body: openapi.JSON(PublicContent{}),
+			Headers: []string{"ETag", "Cache-Control"}},
+		{Status: http.StatusNotModified, Description: "The content is unchanged since the given ETag."},
+	}
+	for _, status := range []int{http.StatusNotFound, http.StatusTooManyRequests} {
+		readResponses = append(readResponses, openapi.Response{
+			Status: status, Description: http.StatusText(status), Body: openapi.JSON(apierror.Envelope{}),
+		})
+	}
+	return []route{
+		{
+			method: http.MethodPut, pattern: "/api/v1/jobs/{id:uuid}/visibility",
+			mount:   mountPlain,
+			handler: http.HandlerFunc(s.setJobVisibility),
+			doc: openapi.Operation{
+				Summary: "Make a job public or private",
+				Description: "Once it has completed, a public job can be read by anyone at its public_url, GET " + PublicContentPrefix +
+					"{id} with a signed tenant for a tenant's job. Making it private again takes up to " + publicMaxAge.String() + " to reach shared caches.",
+				Tags:    []string{"jobs"},
+				Params:  []openapi.Param{idParam},
+				Request: openapi.JSON(JobVisibility{}),
+				Responses: withErrors([]openapi.Response{
+					{Status: http.StatusOK, Description: "The job.", Body: openapi.JSON(JobStatus{}), Headers: []string{"ETag"}},
+				}, http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusUnprocessableEntity),
+			},
+		},
+		{
+			method: http.MethodGet, pattern: PublicContentPrefix + "{id:uuid}",
+			mount:   mountPlain,
+			handler: http.HandlerFunc(s.publicContent),
+			chain:   s.PublicContent,
+			doc: openapi.Operation{
+				Summary: "Read a completed job its owner made public",
+				Description: "Needs no credentials, and may be read from any origin. A job that is private, unfinished " +
+					"or unknown is not found alike. Requests are rate limited by client IP.",
+				Tags:      []string{"jobs"},
+				Public:    true,
+				Params:    []openapi.Param{idParam, {Name: "If-None-Match", In: "header"}},
+				Responses: readResponses,
+			},
+		},
+	}
+}
+
+// signedURLRoutes are the routes of signed download URLs: issuing one,
+// and the download it points at.
+func (s *Server) signedURLRoutes(idParam openapi.Param) []route {
+	fileResponses := []openapi.Response{
+		{Status: http.StatusOK, Description: "The file.", Body: &openapi.Body{ContentType: "application/octet-stream"},
+			Headers: []string{"Content-Disposition", "ETag", "Accept-Ranges"}},
+		{Status: http.StatusPartialContent, Description: "The requested range of the file.",
+			Body: &openapi.Body{ContentType: "application/octet-stream"}, Headers: []string{"Content-Range"}},
+	}
+	for _, status := range []int{http.StatusForbidden, http.StatusNotFound, http.StatusRequestedRangeNotSatisfiable, http.StatusTooManyRequests} {
+		fileResponses = append(fileResponses, openapi.Response{
+			Status: status, Description: http.StatusText(status), Body: openapi.JSON(apierror.Envelope{}),
+		})
+	}
+	return []route{
+		{
+			method: http.MethodGet, pattern: "/api/v1/content/{id:uuid}/signed-url",
+			mount:   mountPlain,
+			handler: http.HandlerFunc(s.signContentURL),
+			doc: openapi.Operation{
+				Summary: "Get a signed URL to download a finished content file",
+				Description: "The URL needs no credentials and expires after " + s.SignedURLs.TTL().String() +
+					". Anyone holding it can download the file until then.",
+				Tags:   []string{"content"},
+				Params: []openapi.Param{idParam},
+				Responses: withErrors([]openapi.Response{
+					{Status: http.StatusOK, Description: "The signed URL.", Body: openapi.JSON(SignedURL{})},
+				}, http.StatusForbidden, http.StatusNotFound, http.StatusConflict),
+			},
+		},
+		{
+			method: http.MethodGet, pattern: SignedFilesPrefix + "{id:uuid}",
+			mount:   mountStream,
+			handler: http.HandlerFunc(s.signedDownload),
+			doc: openapi.Operation{
+				Summary:     "Download a content file through a signed URL",
+				Description: "Issued by GET /api/v1/content/{id}/signed-url. An expired or altered URL is refused with 403.",
+				Tags:        []string{"content"},
+				Public:      true,
+				Params: []openapi.Param{idParam,
+					{Name: signedurl.ExpiresParam, In: "query", Required: true},
+					{Name: signedurl.SignatureParam, In: "query", Required: true},
+					{Name: "Range", In: "header", Description: "A single byte range, e.g. bytes=1000-."},
+					{Name: "If-Range", In: "header", Description: "The ETag the range applies to."}},
+				Responses: fileResponses,
+			},
+		},
+	}
+}
+
+// Describe adds the API routes to spec.
+func (s *Server) Describe(spec *openapi.Spec) {
+	for _, rte := range s.routes() {
+		if rte.flag == "" {
+			spec.Add(rte.method, rte.pattern, rte.doc)
+		}
+	}
+}
+
+// withErrors appends the error responses every protected route can give,
+// 401 and 429, and those with the given statuses, to resps.
+func withErrors(resps []openapi.Response, statuses ...int) []openapi.Response {
+	for _, status := range append([]int{http.StatusUnauthorized, http.StatusTooManyRequests}, statuses...) {
+		resps = append(resps, openapi.Response{
+			Status:      status,
+			Description: http.StatusText(status),
+			Body:        openapi.JSON(apierror.Envelope{}),
+		})
+	}
+	return resps
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/content-factory/go-gateway/internal/openapi"
+	"github.com/content-factory/go-gateway/internal/router"
+)
+
+func TestDescribeCoversRegisteredRoutes(t *testing.T) {
+	s := &Server{Backend: &fakeBackend{}}
+	spec := openapi.New("test", "1")
+	s.Describe(spec)
+	body, err := json.Marshal(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]struct {
+					Enum       []string                   `json:"enum"`
+					Properties map[string]json.RawMessage `json:"properties"`
+				} `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	rt := router.New()
+	s.Register(rt, func(h http.Handler) http.Handler { return h })
+	for _, rte := range s.routes() {
+		if _, ok := doc.Paths[router.Template(rte.pattern)][strings.ToLower(rte.method)]; !ok {
+			t.Errorf("%s %s is not documented", rte.method, rte.pattern)
+		}
+		rec := httptest.NewRecorder()
+		rt.ServeHTTP(rec, httptest.NewRequest(http.MethodOptions, rte.pattern, nil))
+		if rec.Code == http.StatusNotFound {
+			t.Errorf("%s %s is documented but not served", rte.method, rte.pattern)
+		}
+	}
+
+	req := doc.Components.Schemas["ContentRequest"]
+	if got := req.Properties["format"].Enum; len(got) != len(Formats) {
+		t.Errorf("format enum = %v, want %v", got, Formats)
+	}
+	for name := range optionSchema {
+		if _, ok := req.Properties["options"].Properties[name]; !ok {
+			t.Errorf("option %q is not documented", name)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/audit"
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/respond"
+	"github.com/content-factory/go-gateway/internal/router"
+	"github.com/content-factory/go-gateway/internal/signedurl"
+)
+
+// SignedFilesPrefix is the path signed download URLs are served under,
+// followed by the content ID.
+const SignedFilesPrefix = "/files/"
+
+// tenantParam carries, signed, the tenant a signed URL was issued for, so
+// its download reaches that tenant's content service.
+const tenantParam = "tenant"
+
+// SignedURL is the body of GET /api/v1/content/{id}/signed-url.
+type SignedURL struct {
+	// URL downloads the file without credentials until ExpiresAt.
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// signContentURL issues a signed download URL for a finished content file,
+// to its owner only. Content that is unknown, another user's or not yet
+// finished gets the error its download would.
+func (s *Server) signContentURL(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := router.Param(r, "id")
+	info, err := s.Backend.GetContentInfo(ctx, id)
+	if err != nil {
+		apierror.Write(w, r, apierror.FromRPC(err))
+		return
+	}
+	claims := gateway.ClaimsFromContext(ctx)
+	if claims == nil || info.OwnerID != claims.Subject {
+		forbid(w, r, "the content belongs to another user")
+		return
+	}
+	params := url.Values{}
+	if tenant := gateway.TenantID(ctx); tenant != "" {
+		params.Set(tenantParam, tenant)
+	}
+	signed, expires := s.SignedURLs.Sign(SignedFilesPrefix+id, params)
+	// The URL is a credential: keep it out of shared caches.
+	w.Header().Set("Cache-Control", "no-store")
+	respond.Write(w, r, http.StatusOK, SignedURL{URL: signed, ExpiresAt: expires})
+}
+
+// signedDownload serves a signed download URL. The signature stands in
+// for authentication, so it is checked before anything else; expired and
+// tampered URLs are refused with 403.
+func (s *Server) signedDownload(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	err := s.SignedURLs.Verify(r.URL.Path, query)
+	if err != nil {
+		audit.Record(r.Context(), audit.ActionAuthenticate, audit.OutcomeFailure, slog.String("method", "signed_url"),
+			slog.String("reason", strings.TrimPrefix(err.Error(), "signedurl: ")), slog.String("path", r.URL.Path))
+	}
+	switch {
+	case errors.Is(err, signedurl.ErrExpired):
+		apierror.Write(w, r, apierror.New(http.StatusForbidden, apierror.CodeSignatureExpired, "the download URL has expired; ask for a new one"))
+		return
+	case err != nil:
+		apierror.Write(w, r, apierror.New(http.StatusForbidden, apierror.CodeInvalidSignature, "the download URL's signature is missing or invalid"))
+		return
+	}
+	ctx := r.Context()
+	if tenant := query.Get(tenantParam); tenant != "" {
+		// Only the ID matters past this point: it routes the backend calls.
+		ctx = gateway.WithTenant(ctx, &gateway.Tenant{ID: tenant})
+		r = r.WithContext(ctx)
+	}
+	id := router.Param(r, "id")
+	info, err := s.Backend.GetContentInfo(ctx, id)
+	if err != nil {
+		apierror.Write(w, r, apierror.FromRPC(err))
+		return
+	}
+	s.serveContent(w, r, id, info)
+}
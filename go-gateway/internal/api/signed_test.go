@@ -0,0 +1,126 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/router"
+	"github.com/content-factory/go-gateway/internal/signedurl"
+)
+
+var signingKey = []byte("0123456789abcdef0123456789abcdef")
+
+// signedServer serves s's routes, the public ones without credentials.
+func signedServer(s *Server) *router.Router {
+	rt := newRouter(s)
+	s.RegisterPublic(rt, func(h http.Handler) http.Handler { return h })
+	return rt
+}
+
+// issue asks for a signed URL of content id as user-1.
+func issue(t *testing.T, rt *router.Router, id string) (*httptest.ResponseRecorder, SignedURL) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	rt.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/content/"+id+"/signed-url", nil))
+	var body SignedURL
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	return rec, body
+}
+
+func fetch(rt *router.Router, target string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	rt.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	return rec
+}
+
+func TestSignedURL(t *testing.T) {
+	be, _ := fileBackend()
+	rt := signedServer(&Server{Backend: be, SignedURLs: signedurl.New(signedurl.Config{Key: signingKey, TTL: time.Hour})})
+
+	rec, signed := issue(t, rt, fileID)
+	if rec.Code != http.StatusOK || !strings.HasPrefix(signed.URL, SignedFilesPrefix+fileID+"?") {
+		t.Fatalf("status %d, body %s", rec.Code, rec.Body)
+	}
+	if rec.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("Cache-Control = %q, want no-store", rec.Header().Get("Cache-Control"))
+	}
+	if d := time.Until(signed.ExpiresAt); d <= 59*time.Minute || d > time.Hour {
+		t.Errorf("expires_at %v, want an hour on", signed.ExpiresAt)
+	}
+
+	// The URL alone downloads the file, ranges included.
+	rec = fetch(rt, signed.URL)
+	if rec.Code != http.StatusOK || rec.Body.String() != fileBody || rec.Header().Get("Content-Type") != "video/mp4" {
+		t.Fatalf("download: status %d, body %q", rec.Code, rec.Body)
+	}
+	req := httptest.NewRequest(http.MethodGet, signed.URL, nil)
+	req.Header.Set("Range", "bytes=5-9")
+	rec = httptest.NewRecorder()
+	rt.ServeHTTP(rec, req)
+	if rec.Code != http.StatusPartialContent || rec.Body.String() != "56789" {
+		t.Errorf("ranged download: status %d, body %q", rec.Code, rec.Body)
+	}
+}
+
+func TestSignedURLRefused(t *testing.T) {
+	be, _ := fileBackend()
+	rt := signedServer(&Server{Backend: be, SignedURLs: signedurl.New(signedurl.Config{Key: signingKey})})
+	for id, want := range map[string]int{
+		jobTheirs:  http.StatusForbidden,
+		jobMissing: http.StatusNotFound,
+	} {
+		if rec, signed := issue(t, rt, id); rec.Code != want || signed.URL != "" {
+			t.Errorf("%s: status %d, body %s, want %d", id, rec.Code, rec.Body, want)
+		}
+	}
+}
+
+func TestSignedDownloadRejectsBadSignatures(t *testing.T) {
+	be, _ := fileBackend()
+	s := &Server{Backend: be, SignedURLs: signedurl.New(signedurl.Config{Key: signingKey})}
+	rt := signedServer(s)
+	_, signed := issue(t, rt, fileID)
+	u, _ := url.Parse(signed.URL)
+	q := u.Query()
+
+	otherFile := SignedFilesPrefix + jobTheirs + "?" + q.Encode()
+	q.Set(signedurl.ExpiresParam, "9999999999")
+	longer := u.Path + "?" + q.Encode()
+	expired, _ := signedurl.New(signedurl.Config{Key: signingKey, TTL: time.Nanosecond}).Sign(SignedFilesPrefix+fileID, nil)
+	forged, _ := signedurl.New(signedurl.Config{Key: []byte("another key of thirty-two bytes!")}).Sign(SignedFilesPrefix+fileID, nil)
+
+	for name, tt := range map[string]struct {
+		target string
+		code   apierror.Code
+	}{
+		"unsigned":        {SignedFilesPrefix + fileID, apierror.CodeInvalidSignature},
+		"other file":      {otherFile, apierror.CodeInvalidSignature},
+		"extended expiry": {longer, apierror.CodeInvalidSignature},
+		"other key":       {forged, apierror.CodeInvalidSignature},
+		"expired":         {expired, apierror.CodeSignatureExpired},
+	} {
+		rec := fetch(rt, tt.target)
+		var body struct{ Error apierror.Error }
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		if rec.Code != http.StatusForbidden || body.Error.Code != tt.code {
+			t.Errorf("%s: got %d %s, want 403 %s", name, rec.Code, rec.Body, tt.code)
+		}
+	}
+}
+
+func TestSignedURLsOff(t *testing.T) {
+	be, _ := fileBackend()
+	rt := signedServer(&Server{Backend: be})
+	if rec, _ := issue(t, rt, fileID); rec.Code != http.StatusNotFound {
+		t.Errorf("signed-url without a signer: status %d", rec.Code)
+	}
+	if rec := fetch(rt, SignedFilesPrefix+fileID); rec.Code != http.StatusNotFound {
+		t.Errorf("signed download without a signer: status %d", rec.Code)
+	}
+}
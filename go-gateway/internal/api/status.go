@@ -0,0 +1,150 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/audit"
+	"github.com/content-factory/go-gateway/internal/backend"
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/jsonbody"
+	"github.com/content-factory/go-gateway/internal/respond"
+	"github.com/content-factory/go-gateway/internal/uuid"
+)
+
+const (
+	// DefaultMaxStatusIDs caps the job IDs of a bulk status request when
+	// Server.MaxStatusIDs is zero.
+	DefaultMaxStatusIDs = 100
+	// BulkStatusWeight is how many tokens of the client's rate limit a
+	// bulk status request takes, since it makes many backend calls. A
+	// route override's rate_limit_weight replaces it.
+	BulkStatusWeight = 5
+)
+
+// BulkStatusRequest is the body of POST /api/v1/jobs/status.
+type BulkStatusRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// BulkStatusResult is the status of one job of a bulk status request, or
+// the error GET /api/v1/jobs/{id} would have got for it.
+type BulkStatusResult struct {
+	ID     string          `json:"id"`
+	Status int             `json:"status"`
+	Job    *JobStatus      `json:"job,omitempty"`
+	Error  *apierror.Error `json:"error,omitempty"`
+}
+
+// BulkStatusResponse is the body of a bulk status response: one result
+// per requested ID, in request order.
+type BulkStatusResponse struct {
+	Jobs []BulkStatusResult `json:"jobs"`
+}
+
+// getJobStatuses serves POST /api/v1/jobs/status, the current state of
+// several of the caller's jobs at once. Each job is fetched on its own,
+// so one that cannot be does not fail the others. A job of another user
+// is reported as not found, like an unknown one, so the endpoint cannot
+// tell a caller which IDs exist.
+func (s *Server) getJobStatuses(w http.ResponseWriter, r *http.Request) {
+	var req BulkStatusRequest
+	if e := jsonbody.Decode(r, &req, s.bodyOptions()); e != nil {
+		apierror.Write(w, r, e)
+		return
+	}
+	if n, limit := len(req.IDs), s.maxStatusIDs(); n == 0 || n > limit {
+		apierror.Write(w, r, apierror.New(http.StatusUnprocessableEntity, apierror.CodeInvalidBatch,
+			fmt.Sprintf("ids must hold between 1 and %d job IDs, got %d", limit, n)))
+		return
+	}
+	ctx := r.Context()
+	claims := gateway.ClaimsFromContext(ctx)
+	if claims == nil {
+		forbid(w, r, "reading jobs requires an authenticated user")
+		return
+	}
+
+	results := make([]BulkStatusResult, len(req.IDs))
+	// Each distinct job is fetched once, however often it is listed.
+	byID := make(map[string][]int, len(req.IDs))
+	var ids []string
+	for i, raw := range req.IDs {
+		results[i].ID = raw
+		id, ok := uuid.Parse(raw)
+		if !ok {
+			results[i].fail(apierror.InvalidRequest("not a job ID"))
+			continue
+		}
+		if _, seen := byID[id]; !seen {
+			ids = append(ids, id)
+		}
+		byID[id] = append(byID[id], i)
+	}
+
+	todo := make(chan string)
+	var wg sync.WaitGroup
+	for range min(s.batchConcurrency(), len(ids)) {
+		wg.Go(func() {
+			for id := range todo {
+				job, e := s.ownJobStatus(ctx, claims.Subject, id)
+				for _, i := range byID[id] {
+					if e != nil {
+						results[i].fail(e)
+						continue
+					}
+					results[i].Status, results[i].Job = http.StatusOK, job
+				}
+			}
+		})
+	}
+	for _, id := range ids {
+		todo <- id
+	}
+	close(todo)
+	wg.Wait()
+
+	respond.Write(w, r, http.StatusOK, BulkStatusResponse{Jobs: results})
+}
+
+// ownJobStatus fetches job id for its owner. A job owned by someone else
+// gets the same error as one that does not exist.
+func (s *Server) ownJobStatus(ctx context.Context, owner, id string) (*JobStatus, *apierror.Error) {
+	// Shared with GET /api/v1/jobs/{id}, which has the same key.
+	status, err := shared(s, ctx, "GetJobStatus", []string{principal(ctx), id}, func(ctx context.Context) (*backend.JobStatusResponse, error) {
+		return s.Backend.GetJobStatus(ctx, id)
+	})
+	if err != nil {
+		e := apierror.FromRPC(err)
+		if e.Code == apierror.CodeNotFound {
+			e = jobNotFound()
+		}
+		return nil, e
+	}
+	if status.OwnerID != owner {
+		audit.Record(ctx, audit.ActionAuthorize, audit.OutcomeDenied,
+			slog.String("reason", "the job belongs to another user"), slog.String("job_id", id))
+		return nil, jobNotFound()
+	}
+	job := s.jobStatus(ctx, status)
+	return &job, nil
+}
+
+func jobNotFound() *apierror.Error {
+	return apierror.NotFound("no job with this ID")
+}
+
+func (r *BulkStatusResult) fail(e *apierror.Error) {
+	r.Status, r.Error = e.Status, e
+}
+
+func (s *Server) maxStatusIDs() int {
+	if s.MaxStatusIDs <= 0 {
+		return DefaultMaxStatusIDs
+	}
+	return s.MaxStatusIDs
+}
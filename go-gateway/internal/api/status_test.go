@@ -0,0 +1,179 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/backend"
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/ratelimit"
+	"github.com/content-factory/go-gateway/internal/router"
+	"github.com/content-factory/go-gateway/internal/rpc"
+)
+
+// jobUnavailable is a job whose status lookups fail as if the backend
+// were down.
+const jobUnavailable = "6f1c2a9e-0b4d-4c1e-9a52-1b7d3e000006"
+
+// statusBackend serves jobBackend's jobs concurrently, and records the
+// lookups it got and the most it saw at once.
+type statusBackend struct {
+	*fakeBackend
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+	lookups  []string
+}
+
+func (b *statusBackend) GetJobStatus(ctx context.Context, jobID string) (*backend.JobStatusResponse, error) {
+	b.mu.Lock()
+	b.inFlight++
+	b.peak = max(b.peak, b.inFlight)
+	b.lookups = append(b.lookups, jobID)
+	b.mu.Unlock()
+	time.Sleep(5 * time.Millisecond)
+	b.mu.Lock()
+	b.inFlight--
+	b.mu.Unlock()
+	if jobID == jobUnavailable {
+		return nil, rpc.Errorf(rpc.Unavailable, "backend down")
+	}
+	return b.fakeBackend.GetJobStatus(ctx, jobID)
+}
+
+func postStatuses(t *testing.T, s *Server, ids ...string) (*httptest.ResponseRecorder, BulkStatusResponse) {
+	t.Helper()
+	body, _ := json.Marshal(BulkStatusRequest{IDs: ids})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/jobs/status", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	rec := serveRequest(t, s, req)
+	var resp BulkStatusResponse
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rec, resp
+}
+
+func TestBulkStatus(t *testing.T) {
+	be := &statusBackend{fakeBackend: jobBackend()}
+	ids := []string{jobDone, jobTheirs, jobMissing, "job-1", jobUnavailable, strings.ToUpper(jobRunning), jobDone}
+	rec, resp := postStatuses(t, &Server{Backend: be}, ids...)
+	if rec.Code != http.StatusOK || len(resp.Jobs) != len(ids) {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+
+	for i, want := range []struct {
+		status int
+		code   string
+	}{
+		{http.StatusOK, ""},
+		// Another user's job looks like an unknown one.
+		{http.StatusNotFound, "not_found"},
+		{http.StatusNotFound, "not_found"},
+		{http.StatusBadRequest, "invalid_request"},
+		{http.StatusServiceUnavailable, "backend_unavailable"},
+		{http.StatusOK, ""},
+		{http.StatusOK, ""},
+	} {
+		got := resp.Jobs[i]
+		if got.ID != ids[i] || got.Status != want.status {
+			t.Errorf("result %d = %+v, want %s with status %d", i, got, ids[i], want.status)
+			continue
+		}
+		if want.code == "" {
+			if got.Job == nil || got.Error != nil {
+				t.Errorf("result %d = %+v, want a job", i, got)
+			}
+			continue
+		}
+		if got.Job != nil || got.Error == nil || string(got.Error.Code) != want.code {
+			t.Errorf("result %d = %+v, want error %s", i, got, want.code)
+		}
+	}
+	if j := resp.Jobs[5].Job; j.JobID != jobRunning || j.Progress != 40 {
+		t.Errorf("running job = %+v", j)
+	}
+	if resp.Jobs[1].Error.Message != resp.Jobs[2].Error.Message {
+		t.Errorf("another user's job reads %q, an unknown one %q", resp.Jobs[1].Error.Message, resp.Jobs[2].Error.Message)
+	}
+	// The repeated job and the malformed ID cost no lookups.
+	if len(be.lookups) != 5 {
+		t.Errorf("backend lookups = %v, want one per valid distinct ID", be.lookups)
+	}
+}
+
+func TestBulkStatusConcurrency(t *testing.T) {
+	be := &statusBackend{fakeBackend: &fakeBackend{jobs: map[string]*backend.JobStatusResponse{}}}
+	var ids []string
+	for i := range 20 {
+		id := fmt.Sprintf("6f1c2a9e-0b4d-4c1e-9a52-1b7d3e1000%02d", i)
+		be.jobs[id] = &backend.JobStatusResponse{JobID: id, Status: StatusQueued, OwnerID: "user-1"}
+		ids = append(ids, id)
+	}
+	rec, resp := postStatuses(t, &Server{Backend: be, BatchConcurrency: 3}, ids...)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	for i, res := range resp.Jobs {
+		if res.Status != http.StatusOK || res.Job.JobID != ids[i] {
+			t.Errorf("result %d = %+v, want job %s", i, res, ids[i])
+		}
+	}
+	if be.peak != 3 {
+		t.Errorf("peak concurrent lookups = %d, want 3", be.peak)
+	}
+}
+
+func TestBulkStatusRejectsBadRequests(t *testing.T) {
+	s := &Server{Backend: jobBackend(), MaxStatusIDs: 2}
+	for name, ids := range map[string][]string{
+		"none":     {},
+		"too many": {jobDone, jobRunning, jobBroken},
+	} {
+		rec, _ := postStatuses(t, s, ids...)
+		var body struct{ Error struct{ Code string } }
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		if rec.Code != http.StatusUnprocessableEntity || body.Error.Code != "invalid_batch" {
+			t.Errorf("%s: status = %d, body %s", name, rec.Code, rec.Body)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/jobs/status", strings.NewReader(`["`+jobDone+`"]`))
+	req.Header.Set("Content-Type", "application/json")
+	if rec := serveRequest(t, s, req); rec.Code != http.StatusBadRequest {
+		t.Errorf("array body: status = %d, body %s", rec.Code, rec.Body)
+	}
+}
+
+func TestBulkStatusWeight(t *testing.T) {
+	s := &Server{Backend: jobBackend()}
+	weights := map[string]int{}
+	rt := router.New()
+	s.Register(rt, func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			weights[r.Method+" "+r.URL.Path] = ratelimit.WeightOf(r.Context())
+			ctx := gateway.WithClaims(r.Context(), &gateway.Claims{Subject: "user-1"})
+			h.ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/jobs/status", strings.NewReader(`{"ids":["`+jobDone+`"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	rt.ServeHTTP(httptest.NewRecorder(), req)
+	rt.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/jobs/"+jobDone, nil))
+
+	if got := weights["POST /api/v1/jobs/status"]; got != BulkStatusWeight {
+		t.Errorf("bulk status weight = %d, want %d", got, BulkStatusWeight)
+	}
+	if got := weights["GET /api/v1/jobs/"+jobDone]; got != 1 {
+		t.Errorf("single job weight = %d, want 1", got)
+	}
+}
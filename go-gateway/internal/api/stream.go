@@ -0,0 +1,155 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/backend"
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/jsonbody"
+	"github.com/content-factory/go-gateway/internal/middleware"
+	"github.com/content-factory/go-gateway/internal/respond"
+)
+
+// MediaNDJSON is the media type of POST /api/v1/content/stream
+// responses: one JSON object per line.
+const MediaNDJSON = "application/x-ndjson"
+
+// StreamFormats are the formats whose text can be streamed as it is
+// generated.
+var StreamFormats = []string{"article", "social_post"}
+
+// Stream event types.
+const (
+	StreamToken = "token"
+	StreamDone  = "done"
+	StreamError = "error"
+)
+
+// StreamEvent is one line of a POST /api/v1/content/stream response: a
+// piece of generated text, then either the final "done" event with the
+// usage or, if generation failed midway, an "error" event.
+type StreamEvent struct {
+	Type         string                   `json:"type"`
+	Text         string                   `json:"text,omitempty"`
+	Usage        *backend.GenerationUsage `json:"usage,omitempty"`
+	FinishReason string                   `json:"finish_reason,omitempty"`
+	Error        *apierror.Error          `json:"error,omitempty"`
+}
+
+// errStreamTruncated is what a generation stream ending without its final
+// message is reported as.
+var errStreamTruncated = apierror.New(http.StatusBadGateway, apierror.CodeBadGateway,
+	"the generation stream ended without its final message")
+
+// streamContent serves POST /api/v1/content/stream, relaying the text the
+// backend generates for the request as it is produced. Problems found
+// before the first token are answered with an ordinary error response;
+// once the 200 is sent, a failure is reported by a final error event, so a
+// client never mistakes a truncated text for a finished one. The request
+// context is the backend stream's, so a client that disconnects stops the
+// generation.
+func (s *Server) streamContent(w http.ResponseWriter, r *http.Request) {
+	if !respond.Accepts(r, MediaNDJSON) {
+		apierror.Write(w, r, apierror.New(http.StatusNotAcceptable, apierror.CodeNotAcceptable,
+			"generated text is streamed as "+MediaNDJSON))
+		return
+	}
+	var req ContentRequest
+	if e := jsonbody.Decode(r, &req, s.bodyOptions()); e != nil {
+		apierror.Write(w, r, e)
+		return
+	}
+	errs := append(req.Validate(), tenantErrors(r.Context(), &req)...)
+	if errs = append(errs, streamErrors(&req)...); errs != nil {
+		apierror.Write(w, r, apierror.Validation(errs...))
+		return
+	}
+
+	ctx := r.Context()
+	submissions.With("stream").Inc()
+	stream, err := s.Backend.GenerateStream(ctx, backendRequest(ctx, &req, ""))
+	if err != nil {
+		apierror.Write(w, r, apierror.FromRPC(err))
+		return
+	}
+	defer stream.Close()
+	chunk, err := stream.Recv()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			apierror.Write(w, r, errStreamTruncated)
+		} else {
+			apierror.Write(w, r, apierror.FromRPC(err))
+		}
+		return
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", MediaNDJSON)
+	h.Set("Cache-Control", "no-cache")
+	// Ask nginx-style proxies not to buffer the stream.
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	rc := http.NewResponseController(w)
+	enc := json.NewEncoder(w)
+	send := func(ev StreamEvent) bool {
+		if err := enc.Encode(ev); err != nil {
+			return false // the client went away; the deferred Close stops the backend
+		}
+		rc.Flush()
+		return true
+	}
+
+	tokens := 0
+	for ; err == nil; chunk, err = stream.Recv() {
+		if chunk.Text != "" {
+			if !send(StreamEvent{Type: StreamToken, Text: chunk.Text}) {
+				return
+			}
+			tokens++
+		}
+		if chunk.Usage != nil || chunk.FinishReason != "" {
+			middleware.AddLogAttrs(ctx, slog.Int("stream_tokens", tokens))
+			send(StreamEvent{Type: StreamDone, Usage: chunk.Usage, FinishReason: chunk.FinishReason})
+			return
+		}
+	}
+	if gateway.ClientGone(ctx) {
+		return // nobody is listening
+	}
+	e := errStreamTruncated
+	if !errors.Is(err, io.EOF) {
+		e = apierror.FromRPC(err)
+	}
+	body := *e
+	body.RequestID = gateway.RequestIDFromContext(ctx)
+	gateway.Logger(ctx).WarnContext(ctx, "content stream interrupted", "tokens", tokens, "error", err)
+	middleware.AddLogAttrs(ctx, slog.Int("stream_tokens", tokens), slog.String("stream_error", string(e.Code)))
+	send(StreamEvent{Type: StreamError, Error: &body})
+}
+
+// streamErrors returns the problems with req that only matter for a
+// streamed generation, which creates no job.
+func streamErrors(req *ContentRequest) []apierror.FieldError {
+	var errs []apierror.FieldError
+	if req.Format != "" && validFormat(req.Format) && !isStreamFormat(req.Format) {
+		errs = append(errs, apierror.FieldError{Field: "format", Message: "must be article or social_post to stream"})
+	}
+	if req.WebhookURL != "" {
+		errs = append(errs, apierror.FieldError{Field: "webhook_url", Message: "is not supported when streaming"})
+	}
+	return errs
+}
+
+func isStreamFormat(f string) bool {
+	for _, allowed := range StreamFormats {
+		if f == allowed {
+			return true
+		}
+	}
+	return false
+}
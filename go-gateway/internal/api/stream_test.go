@@ -0,0 +1,192 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/backend"
+	"github.com/content-factory/go-gateway/internal/rpc"
+	pb "github.com/content-factory/go-gateway/protos/content_factory"
+)
+
+func (f *fakeBackend) GenerateStream(ctx context.Context, req *backend.CreateContentRequest) (*backend.TokenStream, error) {
+	return backend.New(f.generated).GenerateStream(ctx, req)
+}
+
+// fakeGeneration streams chunks, then fails with err if set. With block
+// set it waits after the first chunk until the call is cancelled.
+type fakeGeneration struct {
+	got       *pb.ContentCreationRequest
+	chunks    []backend.GenerationChunk
+	err       error
+	block     bool
+	cancelled chan struct{}
+}
+
+func (g *fakeGeneration) Invoke(ctx context.Context, method string, req, resp any) error {
+	return rpc.Errorf(rpc.Unimplemented, "unused")
+}
+
+func (g *fakeGeneration) NewStream(ctx context.Context, method string, req any) (rpc.ClientStream, error) {
+	g.got = req.(*pb.ContentCreationRequest)
+	return &generationStream{ctx: ctx, gen: g}, nil
+}
+
+type generationStream struct {
+	ctx  context.Context
+	gen  *fakeGeneration
+	sent int
+}
+
+func (s *generationStream) Recv(msg any) error {
+	if s.gen.block && s.sent == 1 {
+		<-s.ctx.Done()
+		close(s.gen.cancelled)
+		return rpc.Errorf(rpc.Canceled, "canceled")
+	}
+	if s.sent == len(s.gen.chunks) {
+		if s.gen.err != nil {
+			return s.gen.err
+		}
+		return io.EOF
+	}
+	c, m := s.gen.chunks[s.sent], msg.(*pb.GenerationChunk)
+	m.Text, m.FinishReason = c.Text, c.FinishReason
+	if u := c.Usage; u != nil {
+		m.Usage = &pb.GenerationUsage{PromptTokens: u.PromptTokens, CompletionTokens: u.CompletionTokens, TotalTokens: u.TotalTokens}
+	}
+	s.sent++
+	return nil
+}
+
+func (s *generationStream) Close() error { return nil }
+
+func streamRequest(t *testing.T, gen *fakeGeneration, body string, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/content/stream", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		if v != "" {
+			req.Header.Set(k, v)
+		}
+	}
+	return serveRequest(t, &Server{Backend: &fakeBackend{generated: gen}}, req)
+}
+
+func streamEvents(t *testing.T, body string) []StreamEvent {
+	t.Helper()
+	var events []StreamEvent
+	for line := range strings.SplitSeq(strings.TrimSuffix(body, "\n"), "\n") {
+		var ev StreamEvent
+		if err := json.Unmarshal([]byte(line), &ev); err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+		events = append(events, ev)
+	}
+	return events
+}
+
+const streamBody = `{"prompt": "otters", "format": "article"}`
+
+func TestStreamContent(t *testing.T) {
+	usage := &backend.GenerationUsage{PromptTokens: 3, CompletionTokens: 2, TotalTokens: 5}
+	gen := &fakeGeneration{chunks: []backend.GenerationChunk{
+		{Text: "Sea "}, {Text: "otters"}, {Usage: usage, FinishReason: "stop"},
+	}}
+	rec := streamRequest(t, gen, streamBody, map[string]string{"Accept": MediaNDJSON})
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != MediaNDJSON {
+		t.Fatalf("status = %d, Content-Type %q, body %s", rec.Code, rec.Header().Get("Content-Type"), rec.Body)
+	}
+	events := streamEvents(t, rec.Body.String())
+	if len(events) != 3 || events[0] != (StreamEvent{Type: StreamToken, Text: "Sea "}) || events[1].Text != "otters" {
+		t.Fatalf("events = %+v", events)
+	}
+	if done := events[2]; done.Type != StreamDone || done.FinishReason != "stop" || *done.Usage != *usage {
+		t.Errorf("final event = %+v", done)
+	}
+	if gen.got.GetTopic() != "otters" || gen.got.GetOwnerId() != "user-1" || gen.got.GetJobId() != "" {
+		t.Errorf("backend request = %+v", gen.got)
+	}
+	if !rec.Flushed {
+		t.Error("stream was not flushed")
+	}
+}
+
+func TestStreamContentErrorMidStream(t *testing.T) {
+	for name, err := range map[string]error{
+		"backend error": rpc.Errorf(rpc.Unavailable, "model crashed"),
+		"truncated":     nil,
+	} {
+		t.Run(name, func(t *testing.T) {
+			gen := &fakeGeneration{chunks: []backend.GenerationChunk{{Text: "Sea "}}, err: err}
+			rec := streamRequest(t, gen, streamBody, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d", rec.Code)
+			}
+			events := streamEvents(t, rec.Body.String())
+			if len(events) != 2 || events[1].Type != StreamError || events[1].Error == nil {
+				t.Fatalf("events = %+v, want a token then an error", events)
+			}
+			want := apierror.CodeBadGateway
+			if err != nil {
+				want = apierror.CodeBackendUnavailable
+			}
+			if events[1].Error.Code != want {
+				t.Errorf("error = %+v, want %s", events[1].Error, want)
+			}
+		})
+	}
+}
+
+func TestStreamContentRejections(t *testing.T) {
+	tests := []struct {
+		name, body, accept string
+		gen                *fakeGeneration
+		want               int
+	}{
+		{"not acceptable", streamBody, "application/json", &fakeGeneration{}, http.StatusNotAcceptable},
+		{"bad json", "{", "", &fakeGeneration{}, http.StatusBadRequest},
+		{"video", `{"prompt": "otters", "format": "video"}`, "", &fakeGeneration{}, http.StatusUnprocessableEntity},
+		{"webhook", `{"prompt": "otters", "format": "article", "webhook_url": "https://example.com/hook"}`, "", &fakeGeneration{}, http.StatusUnprocessableEntity},
+		{"fails before the first token", streamBody, "", &fakeGeneration{err: rpc.Errorf(rpc.ResourceExhausted, "quota")}, http.StatusTooManyRequests},
+		{"ends before the first token", streamBody, "", &fakeGeneration{}, http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := streamRequest(t, tt.gen, tt.body, map[string]string{"Accept": tt.accept})
+			if rec.Code != tt.want || !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") {
+				t.Errorf("status = %d, Content-Type %q, want %d with a JSON error", rec.Code, rec.Header().Get("Content-Type"), tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamContentClientDisconnectCancelsBackend(t *testing.T) {
+	gen := &fakeGeneration{chunks: []backend.GenerationChunk{{Text: "Sea "}}, block: true, cancelled: make(chan struct{})}
+	srv := httptest.NewServer(newRouter(&Server{Backend: &fakeBackend{generated: gen}}))
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/api/v1/content/stream", "application/json", strings.NewReader(streamBody))
+	if err != nil {
+		t.Fatal(err)
+	}
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil || !strings.Contains(line, `"Sea "`) {
+		t.Fatalf("first line = %q, %v", line, err)
+	}
+	resp.Body.Close()
+
+	select {
+	case <-gen.cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("backend stream not cancelled after client disconnect")
+	}
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+)
+
+// bodies checks request bodies against their `validate` struct tags, see
+// github.com/go-playground/validator. Fields are named by their JSON keys,
+// as clients know them.
+var bodies = func() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	return v
+}()
+
+// bodyErrors returns the fields of body, a pointer to a request body,
+// that break its validate tags, in field order.
+func bodyErrors(body any) []apierror.FieldError {
+	return fieldErrors("", bodies.Struct(body))
+}
+
+// fieldErrors turns the validation failures in err into FieldErrors. An
+// error of a single value, from Var, is reported against field; those of
+// a struct keep the fields' own names.
+func fieldErrors(field string, err error) []apierror.FieldError {
+	var invalid validator.ValidationErrors
+	if !errors.As(err, &invalid) {
+		if err != nil {
+			// Only a bad tag or a nil body get here: a bug, not bad input.
+			panic("api: " + err.Error())
+		}
+		return nil
+	}
+	out := make([]apierror.FieldError, 0, len(invalid))
+	for _, fe := range invalid {
+		name := field
+		if name == "" {
+			name = fe.Field()
+		}
+		out = append(out, apierror.FieldError{Field: name, Message: message(fe)})
+	}
+	return out
+}
+
+// message describes the failed check of fe the way the gateway's other
+// validation errors read, e.g. "must be at most 4000 characters".
+func message(fe validator.FieldError) string {
+	unit := ""
+	if fe.Kind() == reflect.String {
+		unit = " characters"
+	}
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "min", "gte":
+		return "must be at least " + fe.Param() + unit
+	case "max", "lte":
+		return "must be at most " + fe.Param() + unit
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(fe.Param()), ", ")
+	}
+	return "fails the " + fe.Tag() + " check"
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"context"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/gateway"
+)
+
+// validateWebhook checks req's webhook_url, if any: webhooks must be
+// enabled, the caller must have a signing secret, and the URL must pass
+// the SSRF guard.
+func (s *Server) validateWebhook(ctx context.Context, req *ContentRequest) []apierror.FieldError {
+	if req.WebhookURL == "" {
+		return nil
+	}
+	invalid := func(msg string) []apierror.FieldError {
+		return []apierror.FieldError{{Field: "webhook_url", Message: msg}}
+	}
+	if s.Webhooks == nil {
+		return invalid("webhooks are not enabled on this gateway")
+	}
+	if _, ok := s.Webhooks.Secret(principal(ctx)); !ok {
+		return invalid("no webhook signing secret is configured for this client")
+	}
+	if err := s.Webhooks.ValidateURL(req.WebhookURL); err != nil {
+		return invalid(err.Error())
+	}
+	return nil
+}
+
+// watchWebhook starts following the job submitted as jobID from req, if
+// req asked for a webhook.
+func (s *Server) watchWebhook(ctx context.Context, jobID string, req *ContentRequest) {
+	if req.WebhookURL == "" || s.Webhooks == nil {
+		return
+	}
+	s.Webhooks.Watch(ctx, jobID, principal(ctx), req.WebhookURL)
+}
+
+// principal is the authenticated caller's subject, or "".
+func principal(ctx context.Context) string {
+	if claims := gateway.ClaimsFromContext(ctx); claims != nil {
+		return claims.Subject
+	}
+	return ""
+}
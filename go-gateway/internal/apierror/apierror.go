@@ -0,0 +1,242 @@
+// Package apierror defines the gateway's error responses: one envelope,
+// in JSON or the MessagePack the client accepts,
+//
+//	{"error": {"code": "rate_limited", "message": "...", "request_id": "..."}}
+//
+// and the machine-readable codes clients can rely on. Handlers and
+// middleware build an *Error with the constructor for its code, or New for
+// a code without one, and send it with Write.
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/respond"
+)
+
+// Code is a machine-readable error code. The codes below are stable: new
+// ones may be added, but existing ones keep their meaning.
+type Code string
+
+// Request errors.
+const (
+	CodeInvalidRequest         Code = "invalid_request"
+	CodeInvalidJSON            Code = "invalid_json"
+	CodeBodyTooLarge           Code = "body_too_large"
+	CodeInvalidDryRun          Code = "invalid_dry_run"
+	CodeInvalidBatch           Code = "invalid_batch"
+	CodeInvalidRequestTimeout  Code = "invalid_request_timeout"
+	CodeInvalidIdempotencyKey  Code = "invalid_idempotency_key"
+	CodeIdempotencyKeyReused   Code = "idempotency_key_reused"
+	CodeInvalidLastEventID     Code = "invalid_last_event_id"
+	CodeInvalidCursor          Code = "invalid_cursor"
+	CodeInvalidMultipart       Code = "invalid_multipart"
+	CodeTooManyFiles           Code = "too_many_files"
+	CodeAssetTooLarge          Code = "asset_too_large"
+	CodeUnsupportedMediaType   Code = "unsupported_media_type"
+	CodeNotAcceptable          Code = "not_acceptable"
+	CodeUnsupportedAssetType   Code = "unsupported_asset_type"
+	CodeWebSocketRequired      Code = "websocket_required"
+	CodeInvalidHandshake       Code = "invalid_websocket_handshake"
+	CodeUnsupportedSubprotocol Code = "unsupported_subprotocol"
+	CodeHTTP11Required         Code = "http_1_1_required"
+	CodeMethodNotAllowed       Code = "method_not_allowed"
+	CodeNotFound               Code = "not_found"
+	CodeRangeNotSatisfiable    Code = "range_not_satisfiable"
+	CodePreconditionRequired   Code = "precondition_required"
+	CodePreconditionFailed     Code = "precondition_failed"
+	CodeJobFinished            Code = "job_finished"
+	CodeJobNotFailed           Code = "job_not_failed"
+	CodeIdempotencyInProgress  Code = "idempotency_in_progress"
+	CodeAlreadyExists          Code = "already_exists"
+	CodeAborted                Code = "aborted"
+	CodeFailedPrecondition     Code = "failed_precondition"
+	CodeCanceled               Code = "canceled"
+)
+
+// Authentication and authorisation errors.
+const (
+	CodeInvalidToken      Code = "invalid_token"
+	CodeInvalidAPIKey     Code = "invalid_api_key"
+	CodeUnauthenticated   Code = "unauthenticated"
+	CodeForbidden         Code = "forbidden"
+	CodeInsufficientScope Code = "insufficient_scope"
+	CodeInvalidSignature  Code = "invalid_signature"
+	CodeSignatureExpired  Code = "signature_expired"
+)
+
+// Capacity errors, which are worth retrying later.
+const (
+	CodeRateLimited            Code = "rate_limited"
+	CodeTooManyConnections     Code = "too_many_connections"
+	CodeResourceExhausted      Code = "resource_exhausted"
+	CodeOverloaded             Code = "overloaded"
+	CodeShuttingDown           Code = "shutting_down"
+	CodeMaintenance            Code = "maintenance"
+	CodeIdempotencyUnavailable Code = "idempotency_unavailable"
+	CodeQueueFull              Code = "queue_full"
+	CodeQueueUnavailable       Code = "queue_unavailable"
+	CodeCapabilityUnavailable  Code = "capability_unavailable"
+)
+
+// Server and upstream errors.
+const (
+	CodeInternal             Code = "internal_error"
+	CodeStreamingUnsupported Code = "streaming_unsupported"
+	CodeTimeout              Code = "timeout"
+	CodeBackendError         Code = "backend_error"
+	CodeBackendUnavailable   Code = "backend_unavailable"
+	CodeBackendTimeout       Code = "backend_timeout"
+	CodeNotImplemented       Code = "not_implemented"
+	CodeBadGateway           Code = "bad_gateway"
+	CodeGatewayTimeout       Code = "gateway_timeout"
+)
+
+// Error is an error response: the HTTP status and the body inside the
+// envelope.
+type Error struct {
+	Status    int          `json:"-"`
+	Code      Code         `json:"code"`
+	Message   string       `json:"message"`
+	RequestID string       `json:"request_id,omitempty"`
+	Fields    []FieldError `json:"fields,omitempty"`
+	// Details are the structured error details of a failed backend call,
+	// passed through as the backend sent them.
+	Details []json.RawMessage `json:"details,omitempty"`
+}
+
+// FieldError describes one invalid field of a request body or query.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Envelope is the JSON body of every error response.
+type Envelope struct {
+	Error *Error `json:"error"`
+}
+
+// New returns an error with the given status, code and message.
+func New(status int, code Code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	return string(e.Code) + ": " + e.Message
+}
+
+// Write sends e as the response to r, tagged with r's request ID, in the
+// media type r accepts.
+func Write(w http.ResponseWriter, r *http.Request, e *Error) {
+	body := *e
+	if body.RequestID == "" {
+		body.RequestID = gateway.RequestIDFromContext(r.Context())
+	}
+	respond.Write(w, r, e.Status, Envelope{Error: &body})
+}
+
+// InvalidJSON is a 400 for a body that could not be decoded.
+func InvalidJSON(message string) *Error {
+	return New(http.StatusBadRequest, CodeInvalidJSON, message)
+}
+
+// InvalidRequest is a 400 for a request whose parameters are wrong, listing
+// the offending ones.
+func InvalidRequest(message string, fields ...FieldError) *Error {
+	e := New(http.StatusBadRequest, CodeInvalidRequest, message)
+	e.Fields = fields
+	return e
+}
+
+// Validation is a 422 for a well-formed body whose values are wrong.
+func Validation(fields ...FieldError) *Error {
+	e := New(http.StatusUnprocessableEntity, CodeInvalidRequest, "request body failed validation")
+	e.Fields = fields
+	return e
+}
+
+// BodyTooLarge is the 413 for a request body over limit bytes. Its code
+// lets clients tell an oversized payload from a malformed one.
+func BodyTooLarge(limit int64) *Error {
+	return New(http.StatusRequestEntityTooLarge, CodeBodyTooLarge,
+		"request body exceeds the limit of "+strconv.FormatInt(limit, 10)+" bytes")
+}
+
+// InvalidToken is a 401 for a missing, malformed or expired bearer token.
+func InvalidToken(message string) *Error {
+	return New(http.StatusUnauthorized, CodeInvalidToken, message)
+}
+
+// InvalidAPIKey is a 401 for an unknown or revoked API key.
+func InvalidAPIKey(message string) *Error {
+	return New(http.StatusUnauthorized, CodeInvalidAPIKey, message)
+}
+
+// Forbidden is a 403 for a resource the caller may not touch.
+func Forbidden(message string) *Error {
+	return New(http.StatusForbidden, CodeForbidden, message)
+}
+
+// InsufficientScope is a 403 for credentials without a required scope.
+func InsufficientScope(message string) *Error {
+	return New(http.StatusForbidden, CodeInsufficientScope, message)
+}
+
+// NotFound is a 404.
+func NotFound(message string) *Error {
+	return New(http.StatusNotFound, CodeNotFound, message)
+}
+
+// MethodNotAllowed is a 405; the caller sets the Allow header.
+func MethodNotAllowed(message string) *Error {
+	return New(http.StatusMethodNotAllowed, CodeMethodNotAllowed, message)
+}
+
+// PreconditionFailed is a 412 for an If-Match naming a stale version.
+func PreconditionFailed(message string) *Error {
+	return New(http.StatusPreconditionFailed, CodePreconditionFailed, message)
+}
+
+// PreconditionRequired is a 428 for a write that must be conditional.
+func PreconditionRequired(message string) *Error {
+	return New(http.StatusPreconditionRequired, CodePreconditionRequired, message)
+}
+
+// RateLimited is a 429 for a client over its request rate.
+func RateLimited(message string) *Error {
+	return New(http.StatusTooManyRequests, CodeRateLimited, message)
+}
+
+// TooManyConnections is a 429 for a client holding its most streams.
+func TooManyConnections(message string) *Error {
+	return New(http.StatusTooManyRequests, CodeTooManyConnections, message)
+}
+
+// Internal is a 500 for a failure of the gateway itself.
+func Internal(message string) *Error {
+	return New(http.StatusInternalServerError, CodeInternal, message)
+}
+
+// Overloaded is a 503 for a request shed at capacity.
+func Overloaded(message string) *Error {
+	return New(http.StatusServiceUnavailable, CodeOverloaded, message)
+}
+
+// ShuttingDown is a 503 for a request that arrived while draining.
+func ShuttingDown(message string) *Error {
+	return New(http.StatusServiceUnavailable, CodeShuttingDown, message)
+}
+
+// Maintenance is a 503 for a request that arrived while the gateway is in
+// maintenance mode.
+func Maintenance(message string) *Error {
+	return New(http.StatusServiceUnavailable, CodeMaintenance, message)
+}
+
+// Timeout is a 504 for a request that ran past its deadline.
+func Timeout(message string) *Error {
+	return New(http.StatusGatewayTimeout, CodeTimeout, message)
+}
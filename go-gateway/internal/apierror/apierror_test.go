@@ -0,0 +1,69 @@
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/rpc"
+)
+
+func TestWriteEnvelope(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r = r.WithContext(gateway.WithRequestID(r.Context(), "req-1"))
+	rec := httptest.NewRecorder()
+	Write(rec, r, Validation(FieldError{Field: "prompt", Message: "is required"}))
+
+	if rec.Code != http.StatusUnprocessableEntity || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("status = %d, Content-Type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var body map[string]map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	e := body["error"]
+	if e["code"] != "invalid_request" || e["message"] != "request body failed validation" || e["request_id"] != "req-1" {
+		t.Errorf("error = %v", e)
+	}
+	if fields, _ := e["fields"].([]any); len(fields) != 1 {
+		t.Errorf("fields = %v", e["fields"])
+	}
+	if _, ok := e["status"]; ok {
+		t.Error("status leaked into the body")
+	}
+}
+
+func TestWriteLeavesErrorUntouched(t *testing.T) {
+	e := NotFound("no such job")
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	Write(httptest.NewRecorder(), r.WithContext(gateway.WithRequestID(r.Context(), "req-1")), e)
+	if e.RequestID != "" {
+		t.Errorf("Write set RequestID %q on a shared error", e.RequestID)
+	}
+}
+
+func TestFromRPC(t *testing.T) {
+	detail := json.RawMessage(`{"@type":"type.googleapis.com/google.rpc.BadRequest"}`)
+	tests := []struct {
+		err     error
+		status  int
+		code    Code
+		message string
+		details int
+	}{
+		{&rpc.Error{Code: rpc.InvalidArgument, Message: "topic is banned", Details: []json.RawMessage{detail}},
+			http.StatusBadRequest, CodeInvalidRequest, "topic is banned", 1},
+		{rpc.Errorf(rpc.NotFound, ""), http.StatusNotFound, CodeNotFound, "resource not found", 0},
+		{rpc.Errorf(rpc.Unavailable, "pod 3 refused"), http.StatusServiceUnavailable, CodeBackendUnavailable, "the content service is unavailable", 0},
+		{rpc.Errorf(rpc.Internal, "stack trace"), http.StatusInternalServerError, CodeBackendError, "the content service failed to handle the request", 0},
+		{rpc.ErrResponseTooLarge, http.StatusBadGateway, CodeBadGateway, "the content service sent a larger response than the gateway accepts", 0},
+	}
+	for _, tt := range tests {
+		e := FromRPC(tt.err)
+		if e.Status != tt.status || e.Code != tt.code || e.Message != tt.message || len(e.Details) != tt.details {
+			t.Errorf("FromRPC(%v) = %+v", tt.err, e)
+		}
+	}
+}
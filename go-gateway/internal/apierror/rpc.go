@@ -0,0 +1,56 @@
+package apierror
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/content-factory/go-gateway/internal/rpc"
+)
+
+// rpcErrors holds the error code and fallback message sent for each
+// status code of a failed backend call.
+var rpcErrors = map[rpc.Code]struct {
+	code    Code
+	message string
+}{
+	rpc.Canceled:           {CodeCanceled, "the request was cancelled"},
+	rpc.InvalidArgument:    {CodeInvalidRequest, "the content service rejected the request"},
+	rpc.OutOfRange:         {CodeInvalidRequest, "the request is outside the valid range"},
+	rpc.DeadlineExceeded:   {CodeBackendTimeout, "the content service did not respond in time"},
+	rpc.NotFound:           {CodeNotFound, "resource not found"},
+	rpc.AlreadyExists:      {CodeAlreadyExists, "the resource already exists"},
+	rpc.Aborted:            {CodeAborted, "the request conflicted with another and was aborted"},
+	rpc.FailedPrecondition: {CodeFailedPrecondition, "the resource is not in a state that allows this request, for example an unfinished job"},
+	rpc.PermissionDenied:   {CodeForbidden, "access denied"},
+	rpc.Unauthenticated:    {CodeUnauthenticated, "the content service did not accept the caller's credentials"},
+	rpc.ResourceExhausted:  {CodeResourceExhausted, "the content service is out of capacity for this request"},
+	rpc.Unimplemented:      {CodeNotImplemented, "the content service does not support this request"},
+	rpc.Unavailable:        {CodeBackendUnavailable, "the content service is unavailable"},
+}
+
+// FromRPC returns the error response for a failed backend call, with the
+// HTTP status rpc.HTTPStatus gives its code, or 502 for a response over
+// the size cap. When the failure is the
+// caller's, a 4xx, the backend's message and error details are passed on,
+// since they say what to fix; for server-side failures they may describe
+// the gateway's internals and a fixed message is sent instead.
+func FromRPC(err error) *Error {
+	if errors.Is(err, rpc.ErrResponseTooLarge) {
+		return New(http.StatusBadGateway, CodeBadGateway, "the content service sent a larger response than the gateway accepts")
+	}
+	code := rpc.CodeOf(err)
+	status := rpc.HTTPStatus(code)
+	e, ok := rpcErrors[code]
+	if !ok {
+		e.code, e.message = CodeBackendError, "the content service failed to handle the request"
+	}
+	out := New(status, e.code, e.message)
+	var rerr *rpc.Error
+	if status < http.StatusInternalServerError && errors.As(err, &rerr) {
+		if rerr.Message != "" {
+			out.Message = rerr.Message
+		}
+		out.Details = rerr.Details
+	}
+	return out
+}
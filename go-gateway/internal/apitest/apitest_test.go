@@ -0,0 +1,209 @@
+package apitest
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/api"
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/backend"
+	"github.com/content-factory/go-gateway/internal/rpc"
+)
+
+const (
+	mine   = "0b5e2f0c-6a7d-4d8e-9f10-2a3b4c5d6e01"
+	theirs = "0b5e2f0c-6a7d-4d8e-9f10-2a3b4c5d6e02"
+	absent = "0b5e2f0c-6a7d-4d8e-9f10-2a3b4c5d6e03"
+)
+
+func jobs() map[string]*backend.JobStatusResponse {
+	created := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	return map[string]*backend.JobStatusResponse{
+		mine:   {JobID: mine, Status: api.StatusProcessing, ProgressPercent: 40, OwnerID: DefaultSubject, CreatedAt: created, UpdatedAt: created},
+		theirs: {JobID: theirs, Status: api.StatusQueued, OwnerID: "user-2", CreatedAt: created, UpdatedAt: created},
+	}
+}
+
+func TestGetJob(t *testing.T) {
+	h := New(t, &Backend{Jobs: jobs()})
+	tests := []struct {
+		name   string
+		id     string
+		status int
+		code   apierror.Code
+		body   string
+	}{
+		{"own job", mine, http.StatusOK, "", `{"job_id": "` + mine + `", "status": "processing", "progress": 40}`},
+		{"uppercase id", strings.ToUpper(mine), http.StatusOK, "", `{"job_id": "` + mine + `"}`},
+		{"another user's job", theirs, http.StatusForbidden, apierror.CodeForbidden, ""},
+		{"unknown job", absent, http.StatusNotFound, apierror.CodeNotFound, ""},
+		{"malformed id", "job-1", http.StatusBadRequest, apierror.CodeInvalidRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := h.Get("/api/v1/jobs/" + tt.id)
+			if tt.code != "" {
+				resp.AssertError(t, tt.status, tt.code)
+				return
+			}
+			resp.AssertStatus(t, tt.status).
+				AssertHeader(t, "Content-Type", "application/json").
+				AssertJSONContains(t, tt.body)
+		})
+	}
+}
+
+func TestSubmitThenPoll(t *testing.T) {
+	h := New(t, nil)
+	var accepted api.JobAccepted
+	h.Post("/api/v1/content", map[string]any{"prompt": "a video about otters", "format": "video"}).
+		AssertStatus(t, http.StatusAccepted).
+		DecodeJSON(t, &accepted)
+
+	job, ok := h.Backend.Job(accepted.JobID)
+	if !ok || job.OwnerID != DefaultSubject || job.Status != api.StatusQueued {
+		t.Fatalf("backend job = %+v, %v", job, ok)
+	}
+	h.Get("/api/v1/jobs/"+accepted.JobID).
+		AssertStatus(t, http.StatusOK).
+		AssertJSONContains(t, api.JobStatus{JobID: accepted.JobID, Status: api.StatusQueued, CreatedAt: job.CreatedAt, UpdatedAt: job.UpdatedAt})
+	h.Get("/api/v1/jobs").
+		AssertStatus(t, http.StatusOK).
+		AssertJSONContains(t, `{"items": [{"job_id": "`+accepted.JobID+`"}]}`)
+
+	var methods []string
+	for _, c := range h.Backend.Calls() {
+		methods = append(methods, c.Method)
+	}
+	if got := strings.Join(methods, " "); got != "CreateContent GetJobStatus ListJobs" {
+		t.Errorf("backend calls = %s", got)
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	h := New(t, &Backend{Jobs: jobs()})
+	h.Do(NewRequest(t, http.MethodGet, "/api/v1/jobs/"+mine, nil)).
+		AssertError(t, http.StatusUnauthorized, apierror.CodeInvalidToken).
+		AssertHeader(t, "WWW-Authenticate", `Bearer error="invalid_token"`)
+
+	// Tokens are only good for the harness that minted them.
+	other := New(t, nil)
+	req := NewRequest(t, http.MethodGet, "/api/v1/jobs/"+mine, nil)
+	req.Header.Set("Authorization", "Bearer "+other.Token(DefaultSubject))
+	h.Do(req).AssertError(t, http.StatusUnauthorized, apierror.CodeInvalidToken)
+
+	// The owner of theirs can read it.
+	h.Do(h.Authorize(NewRequest(t, http.MethodGet, "/api/v1/jobs/"+theirs, nil), "user-2")).
+		AssertStatus(t, http.StatusOK)
+}
+
+func TestBackendErrors(t *testing.T) {
+	h := New(t, &Backend{Jobs: jobs(), Errors: map[string]error{
+		"GetJobStatus": rpc.Errorf(rpc.Unavailable, "down for a moment"),
+	}})
+	h.Get("/api/v1/jobs/"+mine).AssertError(t, http.StatusServiceUnavailable, apierror.CodeBackendUnavailable)
+}
+
+func TestConfigure(t *testing.T) {
+	h := New(t, nil, func(s *api.Server) { s.MaxBodyBytes = 16 })
+	h.Post("/api/v1/content", map[string]any{"prompt": strings.Repeat("otters ", 10), "format": "video"}).
+		AssertError(t, http.StatusRequestEntityTooLarge, apierror.CodeBodyTooLarge)
+}
+
+func TestStreams(t *testing.T) {
+	be := &Backend{
+		Jobs: jobs(),
+		Files: map[string]File{mine: {
+			Info: backend.ContentInfo{ContentID: mine, ContentType: "text/plain", Filename: "otters.txt", Size: 10, OwnerID: DefaultSubject},
+			Data: []byte("0123456789"),
+		}},
+		Generation: []backend.GenerationChunk{{Text: "Otters "}, {Text: "swim."}, {FinishReason: "stop"}},
+	}
+	h := New(t, be)
+
+	req := h.Request(http.MethodGet, "/api/v1/content/"+mine+"/download", nil)
+	req.Header.Set("Range", "bytes=2-5")
+	resp := h.Do(req).AssertStatus(t, http.StatusPartialContent).AssertHeader(t, "Content-Range", "bytes 2-5/10")
+	if got := resp.Body.String(); got != "2345" {
+		t.Errorf("range body = %q, want 2345", got)
+	}
+
+	req = h.Request(http.MethodPost, "/api/v1/content/stream", `{"prompt": "otters", "format": "article"}`)
+	req.Header.Set("Accept", api.MediaNDJSON)
+	resp = h.Do(req).AssertStatus(t, http.StatusOK).AssertHeader(t, "Content-Type", api.MediaNDJSON)
+	lines := strings.Split(strings.TrimSpace(resp.Body.String()), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[2], `"done"`) {
+		t.Errorf("stream = %q", lines)
+	}
+}
+
+// recorder is a testing.TB that records failures, so the assertions can be
+// tested failing.
+type recorder struct {
+	testing.TB
+	failures []string
+	stopped  bool
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.Errorf(format, args...)
+	r.stopped = true
+	runtime.Goexit()
+}
+
+// check runs assert against a recorder and returns it.
+func check(assert func(tb testing.TB)) *recorder {
+	r := &recorder{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert(r)
+	}()
+	<-done
+	return r
+}
+
+func TestAssertionsReportFailures(t *testing.T) {
+	h := New(t, &Backend{Jobs: jobs()})
+	resp := h.Get("/api/v1/jobs/" + mine)
+	list := h.Get("/api/v1/jobs")
+
+	tests := []struct {
+		name    string
+		assert  func(tb testing.TB)
+		fail    string // in the failure message; empty for none
+		stopped bool
+	}{
+		{"status", func(tb testing.TB) { resp.AssertStatus(tb, http.StatusNotFound) }, "status = 200, want 404", true},
+		{"header", func(tb testing.TB) { resp.AssertHeader(tb, "Content-Type", "text/plain") }, `Content-Type = "application/json"`, false},
+		{"missing field", func(tb testing.TB) { resp.AssertJSONContains(tb, `{"webhook": {}}`) }, "$.webhook is missing", false},
+		{"wrong value", func(tb testing.TB) { resp.AssertJSONContains(tb, `{"progress": 50}`) }, "$.progress = 40, want 50", false},
+		{"not an error", func(tb testing.TB) { resp.AssertError(tb, http.StatusOK, apierror.CodeNotFound) }, "not an error envelope", false},
+		{"array length", func(tb testing.TB) { list.AssertJSONContains(tb, `{"items": []}`) }, "$.items = ", false},
+		{"subset", func(tb testing.TB) { resp.AssertJSONContains(tb, map[string]any{"status": "processing"}) }, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := check(tt.assert)
+			switch {
+			case tt.fail == "" && len(r.failures) > 0:
+				t.Errorf("failures = %q, want none", r.failures)
+			case tt.fail != "" && (len(r.failures) != 1 || !strings.Contains(r.failures[0], tt.fail)):
+				t.Errorf("failures = %q, want one mentioning %q", r.failures, tt.fail)
+			}
+			if r.stopped != tt.stopped {
+				t.Errorf("stopped = %v, want %v", r.stopped, tt.stopped)
+			}
+		})
+	}
+}
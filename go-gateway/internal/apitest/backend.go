@@ -0,0 +1,361 @@
+package apitest
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/content-factory/go-gateway/internal/api"
+	"github.com/content-factory/go-gateway/internal/backend"
+	"github.com/content-factory/go-gateway/internal/rpc"
+	pb "github.com/content-factory/go-gateway/protos/content_factory"
+)
+
+var _ api.Backend = (*Backend)(nil)
+
+// Backend is an in-memory content service implementing api.Backend, the
+// interface the handlers call the real backend.Client through. It behaves
+// like the service for the jobs, files and generated text it is given;
+// set Errors to make a call fail instead.
+//
+// Set its fields before serving requests; afterwards read its state
+// through Job and Calls, which are safe while requests are in flight.
+type Backend struct {
+	// Jobs are the jobs the service knows, by ID, and Requests the
+	// requests they were submitted with. CreateContent adds to both.
+	Jobs     map[string]*backend.JobStatusResponse
+	Requests map[string]*backend.CreateContentRequest
+	// Files are the content files, by content ID.
+	Files map[string]File
+	// Generation is what GenerateStream streams, chunk by chunk.
+	Generation []backend.GenerationChunk
+	// Estimate is what EstimateContent answers; zero means DefaultEstimate.
+	Estimate backend.ContentEstimate
+	// Errors fail the calls of the methods they are keyed by, such as
+	// "GetJobStatus". Use rpc.Errorf to fail them with a gRPC code.
+	Errors map[string]error
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+// File is a content file Backend serves.
+type File struct {
+	Info backend.ContentInfo
+	Data []byte
+}
+
+// Call is a backend call Backend received.
+type Call struct {
+	// Method is the api.Backend method called, such as "CreateContent".
+	Method string
+	// Request is its request, or the ID it was given.
+	Request any
+}
+
+// DefaultEstimate is what EstimateContent answers when Backend.Estimate is
+// unset.
+var DefaultEstimate = backend.ContentEstimate{CostUSD: 0.25, Tokens: 1000, DurationSeconds: 60}
+
+// Calls returns the calls b received, in order.
+func (b *Backend) Calls() []Call {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Call(nil), b.calls...)
+}
+
+// Job returns a copy of the job id, and whether b knows it.
+func (b *Backend) Job(id string) (backend.JobStatusResponse, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	job, ok := b.Jobs[id]
+	if !ok {
+		return backend.JobStatusResponse{}, false
+	}
+	return *job, true
+}
+
+// call records a call and returns the error it should fail with.
+func (b *Backend) call(method string, req any) error {
+	b.calls = append(b.calls, Call{Method: method, Request: req})
+	return b.Errors[method]
+}
+
+// CreateContent adds a queued job owned by the request's owner.
+func (b *Backend) CreateContent(ctx context.Context, req *backend.CreateContentRequest) (*backend.CreateContentResponse, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call("CreateContent", req); err != nil {
+		return nil, err
+	}
+	if _, ok := b.Jobs[req.JobID]; ok {
+		return nil, rpc.Errorf(rpc.AlreadyExists, "job %s exists", req.JobID)
+	}
+	if b.Jobs == nil {
+		b.Jobs = make(map[string]*backend.JobStatusResponse)
+	}
+	if b.Requests == nil {
+		b.Requests = make(map[string]*backend.CreateContentRequest)
+	}
+	now := time.Now().UTC()
+	b.Jobs[req.JobID] = &backend.JobStatusResponse{
+		JobID: req.JobID, Status: api.StatusQueued, OwnerID: req.OwnerID, CreatedAt: now, UpdatedAt: now, RetryOf: req.RetryOf,
+	}
+	stored := *req
+	b.Requests[req.JobID] = &stored
+	return &backend.CreateContentResponse{ContentID: req.JobID, Status: api.StatusQueued}, nil
+}
+
+// EstimateContent answers Estimate.
+func (b *Backend) EstimateContent(ctx context.Context, req *backend.CreateContentRequest) (*backend.ContentEstimate, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call("EstimateContent", req); err != nil {
+		return nil, err
+	}
+	est := b.Estimate
+	if est == (backend.ContentEstimate{}) {
+		est = DefaultEstimate
+	}
+	return &est, nil
+}
+
+// GetJobStatus returns the job, or NotFound.
+func (b *Backend) GetJobStatus(ctx context.Context, jobID string) (*backend.JobStatusResponse, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call("GetJobStatus", jobID); err != nil {
+		return nil, err
+	}
+	job, ok := b.Jobs[jobID]
+	if !ok {
+		return nil, rpc.Errorf(rpc.NotFound, "no job %s", jobID)
+	}
+	out := *job
+	return &out, nil
+}
+
+// GetJobRequest returns the request the job was submitted with, or
+// NotFound.
+func (b *Backend) GetJobRequest(ctx context.Context, jobID string) (*backend.CreateContentRequest, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call("GetJobRequest", jobID); err != nil {
+		return nil, err
+	}
+	req, ok := b.Requests[jobID]
+	if !ok {
+		return nil, rpc.Errorf(rpc.NotFound, "no job %s", jobID)
+	}
+	out := *req
+	return &out, nil
+}
+
+// ListJobs pages through the owner's jobs, newest first. Like the
+// service's, the page token is the position of the previous page's last
+// job, its creation time and ID, so jobs submitted or deleted between
+// pages do not shift the pages after it.
+func (b *Backend) ListJobs(ctx context.Context, req *backend.ListJobsRequest) (*backend.ListJobsResponse, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call("ListJobs", req); err != nil {
+		return nil, err
+	}
+	var owned []backend.JobStatusResponse
+	for _, job := range b.Jobs {
+		if job.OwnerID == req.OwnerID {
+			owned = append(owned, *job)
+		}
+	}
+	before := func(a, b *backend.JobStatusResponse) bool {
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.JobID < b.JobID
+	}
+	sort.Slice(owned, func(i, j int) bool { return before(&owned[i], &owned[j]) })
+	start := 0
+	if req.PageToken != "" {
+		nanos, id, ok := strings.Cut(req.PageToken, "_")
+		n, err := strconv.ParseInt(nanos, 10, 64)
+		if !ok || err != nil {
+			return nil, rpc.Errorf(rpc.InvalidArgument, "bad page token %q", req.PageToken)
+		}
+		last := &backend.JobStatusResponse{JobID: id, CreatedAt: time.Unix(0, n).UTC()}
+		start = sort.Search(len(owned), func(i int) bool { return before(last, &owned[i]) })
+	}
+	end := len(owned)
+	if req.PageSize > 0 {
+		end = min(start+req.PageSize, end)
+	}
+	resp := &backend.ListJobsResponse{Jobs: owned[start:end]}
+	if end < len(owned) {
+		last := owned[end-1]
+		resp.NextPageToken = strconv.FormatInt(last.CreatedAt.UnixNano(), 10) + "_" + last.JobID
+	}
+	if req.Total != "" {
+		resp.TotalCount, resp.TotalApproximate = int64(len(owned)), req.Total == backend.TotalApproximate
+	}
+	return resp, nil
+}
+
+// CancelJob cancels a queued or processing job unchanged since
+// req.IfUpdatedAt, failing as the service does otherwise.
+func (b *Backend) CancelJob(ctx context.Context, req *backend.CancelJobRequest) (*backend.JobStatusResponse, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call("CancelJob", req); err != nil {
+		return nil, err
+	}
+	job, ok := b.Jobs[req.JobID]
+	switch {
+	case !ok:
+		return nil, rpc.Errorf(rpc.NotFound, "no job %s", req.JobID)
+	case req.IfUpdatedAt != nil && !req.IfUpdatedAt.Equal(job.UpdatedAt):
+		return nil, rpc.Errorf(rpc.Aborted, "job %s changed", req.JobID)
+	case job.Status != api.StatusQueued && job.Status != api.StatusProcessing:
+		return nil, rpc.Errorf(rpc.FailedPrecondition, "job %s is %s", req.JobID, job.Status)
+	}
+	job.Status = api.StatusCancelled
+	job.UpdatedAt = job.UpdatedAt.Add(time.Second)
+	out := *job
+	return &out, nil
+}
+
+// SetJobVisibility makes the job public or private, or fails with
+// NotFound.
+func (b *Backend) SetJobVisibility(ctx context.Context, req *backend.SetJobVisibilityRequest) (*backend.JobStatusResponse, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call("SetJobVisibility", req); err != nil {
+		return nil, err
+	}
+	job, ok := b.Jobs[req.JobID]
+	if !ok {
+		return nil, rpc.Errorf(rpc.NotFound, "no job %s", req.JobID)
+	}
+	if job.Public != req.Public {
+		job.Public = req.Public
+		job.UpdatedAt = job.UpdatedAt.Add(time.Second)
+	}
+	out := *job
+	return &out, nil
+}
+
+// GetContentInfo returns the file's Info, or NotFound.
+func (b *Backend) GetContentInfo(ctx context.Context, contentID string) (*backend.ContentInfo, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call("GetContentInfo", contentID); err != nil {
+		return nil, err
+	}
+	file, ok := b.Files[contentID]
+	if !ok {
+		return nil, rpc.Errorf(rpc.NotFound, "no content %s", contentID)
+	}
+	info := file.Info
+	return &info, nil
+}
+
+// DownloadContent streams the requested bytes of the file in one chunk.
+func (b *Backend) DownloadContent(ctx context.Context, contentID string, offset, length int64) (*backend.ContentStream, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	req := backend.DownloadRequest{ContentID: contentID, Offset: offset, Length: length}
+	if err := b.call("DownloadContent", req); err != nil {
+		return nil, err
+	}
+	file, ok := b.Files[contentID]
+	if !ok {
+		return nil, rpc.Errorf(rpc.NotFound, "no content %s", contentID)
+	}
+	if offset > int64(len(file.Data)) {
+		return nil, rpc.Errorf(rpc.OutOfRange, "offset %d past the end", offset)
+	}
+	data := file.Data[offset:]
+	if length > 0 && length < int64(len(data)) {
+		data = data[:length]
+	}
+	return backend.New(streamConn{&pb.ContentChunk{Data: data}}).DownloadContent(ctx, contentID, offset, length)
+}
+
+// GenerateStream streams Generation.
+func (b *Backend) GenerateStream(ctx context.Context, req *backend.CreateContentRequest) (*backend.TokenStream, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.call("GenerateStream", req); err != nil {
+		return nil, err
+	}
+	msgs := make(streamConn, len(b.Generation))
+	for i, c := range b.Generation {
+		chunk := &pb.GenerationChunk{Text: c.Text, FinishReason: c.FinishReason}
+		if u := c.Usage; u != nil {
+			chunk.Usage = &pb.GenerationUsage{PromptTokens: u.PromptTokens, CompletionTokens: u.CompletionTokens, TotalTokens: u.TotalTokens}
+		}
+		msgs[i] = chunk
+	}
+	return backend.New(msgs).GenerateStream(ctx, req)
+}
+
+// UploadAsset reads the file and returns it as an asset of the job.
+func (b *Backend) UploadAsset(ctx context.Context, req *backend.UploadAssetRequest, body io.Reader) (*backend.Asset, error) {
+	b.mu.Lock()
+	if err := b.call("UploadAsset", req); err != nil {
+		b.mu.Unlock()
+		return nil, err
+	}
+	_, known := b.Jobs[req.JobID]
+	id := "asset-" + strconv.Itoa(len(b.calls))
+	b.mu.Unlock()
+	if !known {
+		return nil, rpc.Errorf(rpc.NotFound, "no job %s", req.JobID)
+	}
+	n, err := io.Copy(io.Discard, body)
+	if err != nil {
+		return nil, err
+	}
+	return &backend.Asset{
+		AssetID: id, JobID: req.JobID, Filename: req.Filename, ContentType: req.ContentType, Size: n,
+	}, nil
+}
+
+// streamConn is an rpc.Conn whose every stream sends its messages, so
+// Backend can hand out the backend package's streams.
+type streamConn []proto.Message
+
+func (c streamConn) Invoke(ctx context.Context, method string, req, resp any) error {
+	return rpc.Errorf(rpc.Unimplemented, "apitest: unary call %s", method)
+}
+
+func (c streamConn) NewStream(ctx context.Context, method string, req any) (rpc.ClientStream, error) {
+	return &messageStream{ctx: ctx, msgs: c}, nil
+}
+
+func (c streamConn) Close() error { return nil }
+
+type messageStream struct {
+	ctx  context.Context
+	msgs []proto.Message
+}
+
+func (s *messageStream) Recv(msg any) error {
+	if err := s.ctx.Err(); err != nil {
+		return rpc.Errorf(rpc.Canceled, "%v", err)
+	}
+	if len(s.msgs) == 0 {
+		return io.EOF
+	}
+	m := msg.(proto.Message)
+	proto.Reset(m)
+	proto.Merge(m, s.msgs[0])
+	s.msgs = s.msgs[1:]
+	return nil
+}
+
+func (s *messageStream) Close() error { return nil }
@@ -0,0 +1,140 @@
+package apitest
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+)
+
+// Response is a recorded response. Its assertions report to the test they
+// are given and return the response, so they chain.
+type Response struct {
+	*httptest.ResponseRecorder
+}
+
+// AssertStatus stops the test unless the status is want. The failure shows
+// the body, which for an error names what went wrong.
+func (r *Response) AssertStatus(tb testing.TB, want int) *Response {
+	tb.Helper()
+	if r.Code != want {
+		tb.Fatalf("status = %d, want %d; body %s", r.Code, want, r.Body)
+	}
+	return r
+}
+
+// AssertHeader fails the test unless header name is want; an empty want
+// means the header must be absent.
+func (r *Response) AssertHeader(tb testing.TB, name, want string) *Response {
+	tb.Helper()
+	if got := r.Header().Get(name); got != want {
+		tb.Errorf("%s = %q, want %q", name, got, want)
+	}
+	return r
+}
+
+// DecodeJSON stops the test unless the body decodes into v.
+func (r *Response) DecodeJSON(tb testing.TB, v any) *Response {
+	tb.Helper()
+	if err := json.Unmarshal(r.Body.Bytes(), v); err != nil {
+		tb.Fatalf("decode body %q: %v", r.Body, err)
+	}
+	return r
+}
+
+// AssertJSONContains fails the test unless the body is JSON holding want,
+// given as JSON text or as a value to marshal. Objects may have members
+// want leaves out, so a test names only the fields it checks; arrays must
+// have as many elements as want's, each holding its counterpart; other
+// values must be equal.
+func (r *Response) AssertJSONContains(tb testing.TB, want any) *Response {
+	tb.Helper()
+	var got, expected any
+	r.DecodeJSON(tb, &got)
+	if err := json.Unmarshal(jsonOf(tb, want), &expected); err != nil {
+		tb.Fatalf("apitest: decode expected JSON: %v", err)
+	}
+	if diffs := contains(got, expected, "$"); len(diffs) > 0 {
+		tb.Errorf("body %s:\n%s", r.Body, strings.Join(diffs, "\n"))
+	}
+	return r
+}
+
+// AssertError fails the test unless the response is the error envelope
+// with status and code.
+func (r *Response) AssertError(tb testing.TB, status int, code apierror.Code) *Response {
+	tb.Helper()
+	r.AssertStatus(tb, status)
+	var body struct {
+		Error *apierror.Error `json:"error"`
+	}
+	r.DecodeJSON(tb, &body)
+	switch {
+	case body.Error == nil:
+		tb.Errorf("body %s is not an error envelope", r.Body)
+	case body.Error.Code != code:
+		tb.Errorf("error code = %q, want %q; body %s", body.Error.Code, code, r.Body)
+	}
+	return r
+}
+
+func jsonOf(tb testing.TB, v any) []byte {
+	switch v := v.(type) {
+	case string:
+		return []byte(v)
+	case []byte:
+		return v
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		tb.Fatalf("apitest: encode expected JSON: %v", err)
+	}
+	return b
+}
+
+// contains returns where got, at path, does not hold want.
+func contains(got, want any, path string) []string {
+	if wantArr, ok := want.([]any); ok {
+		gotArr, ok := got.([]any)
+		if !ok || len(gotArr) != len(wantArr) {
+			return []string{fmt.Sprintf("%s = %s, want %d elements like %s", path, text(got), len(wantArr), text(want))}
+		}
+		var diffs []string
+		for i := range wantArr {
+			diffs = append(diffs, contains(gotArr[i], wantArr[i], fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return diffs
+	}
+	wantObj, ok := want.(map[string]any)
+	if !ok {
+		if !reflect.DeepEqual(got, want) {
+			return []string{fmt.Sprintf("%s = %s, want %s", path, text(got), text(want))}
+		}
+		return nil
+	}
+	gotObj, ok := got.(map[string]any)
+	if !ok {
+		return []string{fmt.Sprintf("%s = %s, want an object", path, text(got))}
+	}
+	var diffs []string
+	for _, k := range slices.Sorted(maps.Keys(wantObj)) {
+		g, ok := gotObj[k]
+		if !ok {
+			diffs = append(diffs, fmt.Sprintf("%s.%s is missing", path, k))
+			continue
+		}
+		diffs = append(diffs, contains(g, wantObj[k], path+"."+k)...)
+	}
+	return diffs
+}
+
+func text(v any) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
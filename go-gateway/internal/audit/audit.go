@@ -0,0 +1,136 @@
+// Package audit writes the gateway's audit trail: one JSON record per
+// security-relevant event, such as a caller authenticating or being
+// refused, an operator switching maintenance mode, or a job being
+// submitted, cancelled or finished. The trail is kept apart from the application
+// log so it can be sent elsewhere, and is written whatever LOG_LEVEL is.
+//
+// Handlers call Record with the request context, from which the record
+// takes the principal, API key, tenant, client IP and request ID. Until
+// SetDefault is called, records are dropped.
+package audit
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync/atomic"
+
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/jobs"
+)
+
+// Actions recorded.
+const (
+	// ActionAuthenticate is a caller presenting credentials, or none, to a
+	// route that requires them.
+	ActionAuthenticate = "auth.authenticate"
+	// ActionAuthorize is an authenticated caller refused a resource or
+	// route: a missing scope, another user's job, a tenant mismatch.
+	ActionAuthorize = "auth.authorize"
+
+	ActionMaintenance       = "admin.maintenance"
+	ActionCloseConnection   = "admin.connection.close"
+	ActionRateLimitReset    = "admin.rate_limit.reset"
+	ActionRateLimitOverride = "admin.rate_limit.override"
+	ActionRateLimitClear    = "admin.rate_limit.clear_override"
+
+	ActionJobCreate     = "job.create"
+	ActionJobCancel     = "job.cancel"
+	ActionJobVisibility = "job.visibility"
+	// ActionJobFinish is a job reaching its terminal stage, as streamed
+	// from the backend rather than asked for by a caller.
+	ActionJobFinish = "job.finish"
+)
+
+// Outcomes of an action.
+const (
+	OutcomeSuccess = "success"
+	// OutcomeFailure is an action that did not happen: credentials that
+	// did not check out, or a submission the backend refused.
+	OutcomeFailure = "failure"
+	// OutcomeDenied is an authenticated caller refused permission.
+	OutcomeDenied = "denied"
+)
+
+// Logger writes audit records.
+type Logger struct {
+	logger *slog.Logger
+}
+
+// New returns a Logger writing JSON lines to w. Every record is written:
+// the Logger has no level.
+func New(w io.Writer) *Logger {
+	// Records are written at info level; a handler level below every
+	// record's keeps them all.
+	h := slog.NewJSONHandler(w, &slog.HandlerOptions{Level: slog.LevelDebug})
+	return &Logger{logger: slog.New(h).With("log", "audit")}
+}
+
+// Record writes a record of action with outcome for the request of ctx,
+// with attrs added. A nil Logger records nothing.
+func (l *Logger) Record(ctx context.Context, action, outcome string, attrs ...slog.Attr) {
+	if l == nil {
+		return
+	}
+	base := []slog.Attr{
+		slog.String("action", action),
+		slog.String("outcome", outcome),
+	}
+	if claims := gateway.ClaimsFromContext(ctx); claims != nil && claims.Subject != "" {
+		base = append(base, slog.String("principal", claims.Subject))
+	}
+	if k := gateway.APIKeyFromContext(ctx); k != nil {
+		base = append(base, slog.String("api_key", k.ID))
+	}
+	if id := gateway.TenantID(ctx); id != "" {
+		base = append(base, slog.String("tenant", id))
+	}
+	if ip := gateway.ClientIPFromContext(ctx); ip != "" {
+		base = append(base, slog.String("client_ip", ip))
+	}
+	if id := gateway.RequestIDFromContext(ctx); id != "" {
+		base = append(base, slog.String("request_id", id))
+	}
+	l.logger.LogAttrs(ctx, slog.LevelInfo, "audit", append(base, attrs...)...)
+}
+
+var std atomic.Pointer[Logger]
+
+// SetDefault makes l the Logger Record writes to. A nil l drops the
+// records again.
+func SetDefault(l *Logger) { std.Store(l) }
+
+// Record writes a record with the default Logger, see Logger.Record.
+func Record(ctx context.Context, action, outcome string, attrs ...slog.Attr) {
+	std.Load().Record(ctx, action, outcome, attrs...)
+}
+
+// RecordJobEvents records the terminal event of each job among events, as
+// ActionJobFinish, until the channel is closed. Jobs that failed carry the
+// backend's message as their reason. A StageError event, the gateway
+// giving up on a job's stream, is no finish of the job and is skipped.
+func (l *Logger) RecordJobEvents(events <-chan jobs.Event) {
+	for ev := range events {
+		l.recordJobEvent(ev)
+	}
+}
+
+// RecordJobEvents records job events with the default Logger, see
+// Logger.RecordJobEvents.
+func RecordJobEvents(events <-chan jobs.Event) {
+	for ev := range events {
+		std.Load().recordJobEvent(ev)
+	}
+}
+
+func (l *Logger) recordJobEvent(ev jobs.Event) {
+	if !ev.Terminal() || ev.Stage == jobs.StageError {
+		return
+	}
+	outcome, attrs := OutcomeSuccess, []slog.Attr{slog.String("job_id", ev.JobID), slog.String("stage", ev.Stage)}
+	if ev.Stage != jobs.StageDone {
+		outcome = OutcomeFailure
+		attrs = append(attrs, slog.String("reason", ev.Message))
+	}
+	l.Record(context.Background(), ActionJobFinish, outcome, attrs...)
+}
@@ -0,0 +1,111 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/jobs"
+)
+
+func decode(t *testing.T, buf *bytes.Buffer) map[string]any {
+	t.Helper()
+	var rec map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("record %q: %v", buf, err)
+	}
+	return rec
+}
+
+func TestRecord(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf)
+	ctx := gateway.WithClaims(context.Background(), &gateway.Claims{Subject: "svc-reports"})
+	ctx = gateway.WithAPIKey(ctx, &gateway.APIKey{ID: "k1"})
+	ctx = gateway.WithTenant(ctx, &gateway.Tenant{ID: "acme"})
+	ctx = gateway.WithClientIP(ctx, "203.0.113.7")
+	ctx = gateway.WithRequestID(ctx, "req-1")
+	l.Record(ctx, ActionJobCancel, OutcomeSuccess, slog.String("job_id", "j1"))
+
+	rec := decode(t, &buf)
+	for key, want := range map[string]any{
+		"log":        "audit",
+		"msg":        "audit",
+		"action":     ActionJobCancel,
+		"outcome":    OutcomeSuccess,
+		"principal":  "svc-reports",
+		"api_key":    "k1",
+		"tenant":     "acme",
+		"client_ip":  "203.0.113.7",
+		"request_id": "req-1",
+		"job_id":     "j1",
+	} {
+		if rec[key] != want {
+			t.Errorf("%s = %v, want %v", key, rec[key], want)
+		}
+	}
+	if _, ok := rec["time"]; !ok {
+		t.Error("record has no time")
+	}
+}
+
+func TestRecordWithoutIdentity(t *testing.T) {
+	var buf bytes.Buffer
+	New(&buf).Record(gateway.WithClientIP(context.Background(), "198.51.100.1"), ActionAuthenticate, OutcomeFailure)
+	rec := decode(t, &buf)
+	if rec["client_ip"] != "198.51.100.1" || rec["outcome"] != OutcomeFailure {
+		t.Errorf("record = %v", rec)
+	}
+	for _, key := range []string{"principal", "api_key", "tenant", "request_id"} {
+		if _, ok := rec[key]; ok {
+			t.Errorf("record has %s for an anonymous request: %v", key, rec)
+		}
+	}
+}
+
+func TestDefault(t *testing.T) {
+	ctx := context.Background()
+	Record(ctx, ActionMaintenance, OutcomeSuccess) // dropped, not a panic
+
+	var buf bytes.Buffer
+	SetDefault(New(&buf))
+	t.Cleanup(func() { SetDefault(nil) })
+	// The application log's level does not apply.
+	defer slog.SetLogLoggerLevel(slog.SetLogLoggerLevel(slog.LevelError))
+	Record(ctx, ActionMaintenance, OutcomeSuccess, slog.Bool("enabled", true))
+	if rec := decode(t, &buf); rec["action"] != ActionMaintenance || rec["enabled"] != true {
+		t.Errorf("record = %v", rec)
+	}
+
+	var nilLogger *Logger
+	nilLogger.Record(ctx, ActionMaintenance, OutcomeSuccess)
+}
+
+func TestRecordJobEvents(t *testing.T) {
+	events := make(chan jobs.Event, 4)
+	events <- jobs.Event{JobID: "j1", Seq: 1, Stage: "drafting"}
+	events <- jobs.Event{JobID: "j1", Seq: 2, Stage: jobs.StageDone}
+	events <- jobs.Event{JobID: "j2", Seq: 3, Stage: jobs.StageFailed, Message: "render failed"}
+	events <- jobs.Event{JobID: "j3", Seq: 1, Stage: jobs.StageError, Message: "stream lost"}
+	close(events)
+	var buf bytes.Buffer
+	New(&buf).RecordJobEvents(events)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d records, want one per finished job: %s", len(lines), buf.String())
+	}
+	var done, failed map[string]any
+	json.Unmarshal([]byte(lines[0]), &done)
+	json.Unmarshal([]byte(lines[1]), &failed)
+	if done["action"] != ActionJobFinish || done["outcome"] != OutcomeSuccess || done["job_id"] != "j1" || done["stage"] != jobs.StageDone {
+		t.Errorf("done record = %v", done)
+	}
+	if failed["outcome"] != OutcomeFailure || failed["job_id"] != "j2" || failed["reason"] != "render failed" {
+		t.Errorf("failed record = %v", failed)
+	}
+}
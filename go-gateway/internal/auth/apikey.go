@@ -0,0 +1,192 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/audit"
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/respond"
+)
+
+// APIKeyHeader carries API keys.
+const APIKeyHeader = "X-API-Key"
+
+// ErrUnknownKey is returned by KeyStore.Lookup for keys it does not hold.
+var ErrUnknownKey = errors.New("unknown API key")
+
+// APIKey is a credential for server-side integrations that cannot obtain
+// JWTs. Requests made with it act as Principal with Scopes.
+type APIKey struct {
+	// ID names the key in logs and rate-limit buckets; it is not secret.
+	ID        string
+	Key       string
+	Principal string
+	Scopes    []string
+	// Enabled is cleared to revoke the key without deleting it.
+	Enabled bool
+	// RPS and Burst override the default rate limit when positive.
+	RPS   float64
+	Burst int
+	// Tenant, if set, is the tenant the key's requests act for.
+	Tenant string
+}
+
+// KeyStore resolves presented API keys. Implementations must compare
+// secrets in constant time.
+type KeyStore interface {
+	Lookup(ctx context.Context, key string) (*APIKey, error)
+}
+
+// MemoryKeyStore is a KeyStore backed by a map, loaded from configuration.
+// It is safe for concurrent use, and keys can be revoked or replaced at
+// runtime.
+type MemoryKeyStore struct {
+	mu     sync.RWMutex
+	byHash map[[sha256.Size]byte]*APIKey
+}
+
+// NewMemoryKeyStore returns a store holding keys.
+func NewMemoryKeyStore(keys []APIKey) (*MemoryKeyStore, error) {
+	s := &MemoryKeyStore{}
+	if err := s.Replace(keys); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Replace swaps the store's contents for keys.
+func (s *MemoryKeyStore) Replace(keys []APIKey) error {
+	byHash := make(map[[sha256.Size]byte]*APIKey, len(keys))
+	ids := make(map[string]bool, len(keys))
+	for i := range keys {
+		k := keys[i]
+		switch {
+		case k.ID == "":
+			return fmt.Errorf("api key %d: id is required", i)
+		case k.Key == "":
+			return fmt.Errorf("api key %q: key is required", k.ID)
+		case k.Principal == "":
+			return fmt.Errorf("api key %q: principal is required", k.ID)
+		case ids[k.ID]:
+			return fmt.Errorf("api key %q: duplicate id", k.ID)
+		}
+		h := sha256.Sum256([]byte(k.Key))
+		if _, dup := byHash[h]; dup {
+			return fmt.Errorf("api key %q: duplicate key", k.ID)
+		}
+		ids[k.ID] = true
+		byHash[h] = &k
+	}
+	s.mu.Lock()
+	s.byHash = byHash
+	s.mu.Unlock()
+	return nil
+}
+
+// SetEnabled enables or revokes the key with the given ID. It reports
+// whether the key exists.
+func (s *MemoryKeyStore) SetEnabled(id string, enabled bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for h, k := range s.byHash {
+		if k.ID == id {
+			updated := *k
+			updated.Enabled = enabled
+			s.byHash[h] = &updated
+			return true
+		}
+	}
+	return false
+}
+
+// Lookup implements KeyStore. Keys are indexed by their SHA-256 digest, so
+// the map lookup reveals nothing about the secret, and the final match is
+// confirmed with a constant-time comparison.
+func (s *MemoryKeyStore) Lookup(ctx context.Context, key string) (*APIKey, error) {
+	h := sha256.Sum256([]byte(key))
+	s.mu.RLock()
+	k, ok := s.byHash[h]
+	s.mu.RUnlock()
+	if !ok || subtle.ConstantTimeCompare([]byte(k.Key), []byte(key)) != 1 {
+		return nil, ErrUnknownKey
+	}
+	return k, nil
+}
+
+// Middleware authenticates requests with a bearer JWT, checked by JWT, or,
+// when Keys is set, an X-API-Key header, or, when Signatures is set, a
+// signature naming its key in X-Key-Id. Whichever was used, the caller's
+// identity ends up in gateway.ClaimsFromContext, so handlers need not
+// care. Every response it passes or writes varies on Authorization and,
+// with Keys, X-API-Key, and with Signatures, X-Key-Id.
+type Middleware struct {
+	// JWT verifies bearer tokens; without it they are all refused.
+	JWT        Authenticator
+	Keys       KeyStore
+	Signatures *SignatureVerifier
+}
+
+// Require rejects requests that carry neither a valid JWT, a valid,
+// enabled API key nor a valid signature by an enabled signing key.
+// Authenticated requests' gateway.Logger carries their principal.
+func (m *Middleware) Require(next http.Handler) http.Handler {
+	return m.require(next, false)
+}
+
+// RequireQuery is Require that also accepts a JWT in the access_token
+// query parameter, for browser WebSocket and EventSource clients.
+func (m *Middleware) RequireQuery(next http.Handler) http.Handler {
+	return m.require(next, true)
+}
+
+func (m *Middleware) require(next http.Handler, allowQuery bool) http.Handler {
+	h := requireBearer(m.JWT, next, allowQuery)
+	if m.Keys != nil {
+		h = requireAPIKey(m.Keys, h, next)
+	}
+	if m.Signatures != nil {
+		h = requireSignature(m.Signatures, h, next)
+	}
+	return h
+}
+
+// requireAPIKey authenticates requests carrying an X-API-Key with keys,
+// and passes the others on to jwt.
+func requireAPIKey(keys KeyStore, jwt, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		respond.Vary(w, "Authorization", APIKeyHeader)
+		presented := strings.TrimSpace(r.Header.Get(APIKeyHeader))
+		if presented == "" {
+			jwt.ServeHTTP(w, r)
+			return
+		}
+		key, err := keys.Lookup(r.Context(), presented)
+		if err != nil || !key.Enabled {
+			reason := "unknown key"
+			if err == nil {
+				reason = "disabled key " + key.ID
+			}
+			auditFailure(r, methodAPIKey, reason)
+			apierror.Write(w, r, apierror.InvalidAPIKey("the API key is invalid or revoked"))
+			return
+		}
+		ctx := gateway.WithClaims(r.Context(), &gateway.Claims{
+			Subject: key.Principal,
+			Scope:   strings.Join(key.Scopes, " "),
+			Tenant:  key.Tenant,
+		})
+		ctx = gateway.WithAPIKey(ctx, &gateway.APIKey{ID: key.ID, RPS: key.RPS, Burst: key.Burst})
+		ctx = gateway.WithLogAttrs(ctx, "principal", key.Principal)
+		audit.Record(ctx, audit.ActionAuthenticate, audit.OutcomeSuccess, slog.String("method", methodAPIKey))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/audit"
+	"github.com/content-factory/go-gateway/internal/gateway"
+)
+
+func testKeys() []APIKey {
+	return []APIKey{
+		{ID: "partner", Key: "k-partner-0123456789", Principal: "svc-partner", Scopes: []string{"content:read", "content:write"}, Enabled: true, RPS: 5, Burst: 10},
+		{ID: "old", Key: "k-old-0123456789", Principal: "svc-old", Enabled: false},
+	}
+}
+
+func TestMemoryKeyStoreLookup(t *testing.T) {
+	s, err := NewMemoryKeyStore(testKeys())
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := s.Lookup(context.Background(), "k-partner-0123456789")
+	if err != nil || k.ID != "partner" {
+		t.Fatalf("Lookup = %+v, %v", k, err)
+	}
+	if _, err := s.Lookup(context.Background(), "k-partner-012345678"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("prefix of a key: err = %v, want ErrUnknownKey", err)
+	}
+
+	if !s.SetEnabled("partner", false) {
+		t.Fatal("SetEnabled reported missing key")
+	}
+	if k, _ := s.Lookup(context.Background(), "k-partner-0123456789"); k.Enabled {
+		t.Error("key still enabled after revocation")
+	}
+	if s.SetEnabled("nope", true) {
+		t.Error("SetEnabled reported unknown key as present")
+	}
+}
+
+func TestMemoryKeyStoreRejectsInvalid(t *testing.T) {
+	for name, keys := range map[string][]APIKey{
+		"missing id":    {{Key: "k", Principal: "p"}},
+		"missing key":   {{ID: "a", Principal: "p"}},
+		"duplicate id":  {{ID: "a", Key: "k1", Principal: "p"}, {ID: "a", Key: "k2", Principal: "p"}},
+		"duplicate key": {{ID: "a", Key: "k", Principal: "p"}, {ID: "b", Key: "k", Principal: "p"}},
+	} {
+		if _, err := NewMemoryKeyStore(keys); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestMiddlewareAPIKey(t *testing.T) {
+	store, err := NewMemoryKeyStore(testKeys())
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := []byte("s3cret")
+	m := &Middleware{
+		JWT:  &JWTVerifier{Algorithm: HS256, Secret: secret, now: func() time.Time { return testNow }},
+		Keys: store,
+	}
+	var claims *gateway.Claims
+	var key *gateway.APIKey
+	h := m.Require(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims = gateway.ClaimsFromContext(r.Context())
+		key = gateway.APIKeyFromContext(r.Context())
+	}))
+
+	tests := []struct {
+		name    string
+		header  string
+		value   string
+		want    int
+		subject string
+	}{
+		{"valid key", APIKeyHeader, "k-partner-0123456789", http.StatusOK, "svc-partner"},
+		{"disabled key", APIKeyHeader, "k-old-0123456789", http.StatusUnauthorized, ""},
+		{"unknown key", APIKeyHeader, "guess", http.StatusUnauthorized, ""},
+		{"jwt fallback", "Authorization", "Bearer " + signHS256(t, secret, HS256, validClaims()), http.StatusOK, "user-1"},
+		{"no credentials", "", "", http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, key = nil, nil
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if got := strings.Join(rec.Header().Values("Vary"), ", "); !strings.HasPrefix(got, "Authorization, X-Api-Key") {
+				t.Errorf("Vary = %q, want Authorization and X-Api-Key", got)
+			}
+			if tt.subject != "" && (claims == nil || claims.Subject != tt.subject) {
+				t.Errorf("claims = %+v, want subject %q", claims, tt.subject)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(APIKeyHeader, "k-partner-0123456789")
+	h.ServeHTTP(rec, req)
+	if claims.Scope != "content:read content:write" {
+		t.Errorf("scope = %q", claims.Scope)
+	}
+	if key == nil || key.ID != "partner" || key.RPS != 5 || key.Burst != 10 {
+		t.Errorf("API key in context = %+v", key)
+	}
+}
+
+func TestMiddlewareAudits(t *testing.T) {
+	var buf bytes.Buffer
+	audit.SetDefault(audit.New(&buf))
+	t.Cleanup(func() { audit.SetDefault(nil) })
+	store, err := NewMemoryKeyStore(testKeys())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := (&Middleware{Keys: store}).Require(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	for _, key := range []string{"k-partner-0123456789", "k-old-0123456789"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(APIKeyHeader, key)
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	var recs []map[string]any
+	for line := range strings.Lines(buf.String()) {
+		var rec map[string]any
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatal(err)
+		}
+		recs = append(recs, rec)
+	}
+	if len(recs) != 2 {
+		t.Fatalf("got %d records, want 2:\n%s", len(recs), &buf)
+	}
+	if r := recs[0]; r["action"] != audit.ActionAuthenticate || r["outcome"] != audit.OutcomeSuccess ||
+		r["principal"] != "svc-partner" || r["api_key"] != "partner" || r["method"] != methodAPIKey {
+		t.Errorf("valid key: %v", r)
+	}
+	if r := recs[1]; r["outcome"] != audit.OutcomeFailure || r["reason"] != "disabled key old" || r["principal"] != nil {
+		t.Errorf("disabled key: %v", r)
+	}
+}
@@ -0,0 +1,329 @@
+// Package auth implements the gateway's authentication middleware.
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/audit"
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/respond"
+)
+
+// Supported JWT signing algorithms.
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+)
+
+var errInvalidToken = errors.New("invalid token")
+
+// Authenticator verifies bearer tokens. *JWTVerifier, for a static key,
+// and *OIDCVerifier, for an OpenID Connect provider's rotating keys,
+// implement it.
+type Authenticator interface {
+	// Authenticate checks token and returns the caller's claims.
+	Authenticate(ctx context.Context, token string) (*gateway.Claims, error)
+}
+
+// JWTVerifier validates bearer tokens signed with a single, fixed
+// algorithm. Tokens whose header names any other algorithm (including
+// "none") are rejected, which rules out algorithm-downgrade attacks.
+type JWTVerifier struct {
+	Algorithm string
+	Secret    []byte         // HS256 key
+	PublicKey *rsa.PublicKey // RS256 key
+
+	now func() time.Time
+}
+
+// Config selects the verification key.
+type Config struct {
+	// Algorithm pins the expected algorithm; empty means whichever key
+	// is set.
+	Algorithm string
+	// Secret is the HS256 shared secret.
+	Secret string
+	// PublicKeyFile is a PEM-encoded RSA public key for RS256.
+	PublicKeyFile string
+	// OIDCIssuer, if set, verifies tokens with the keys of this OpenID
+	// Connect provider instead, for OIDCAudience; see OIDCVerifier.
+	OIDCIssuer          string
+	OIDCAudience        string
+	OIDCRefreshInterval time.Duration
+}
+
+// NewAuthenticator returns the bearer token Authenticator cfg selects: an
+// OIDCVerifier if it names an issuer, or else a JWTVerifier. It returns
+// nil and no error when nothing is configured.
+func NewAuthenticator(cfg Config) (Authenticator, error) {
+	if cfg.OIDCIssuer != "" {
+		if cfg.OIDCAudience == "" {
+			return nil, errors.New("OIDC requires an audience")
+		}
+		return NewOIDCVerifier(cfg.OIDCIssuer, cfg.OIDCAudience, cfg.OIDCRefreshInterval), nil
+	}
+	v, err := NewJWTVerifier(cfg)
+	if v == nil || err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// NewJWTVerifier builds a verifier from cfg. It returns nil and no error
+// when neither key is configured.
+func NewJWTVerifier(cfg Config) (*JWTVerifier, error) {
+	secret, keyFile := cfg.Secret, cfg.PublicKeyFile
+	alg := strings.ToUpper(cfg.Algorithm)
+
+	if alg == "" {
+		switch {
+		case keyFile != "":
+			alg = RS256
+		case secret != "":
+			alg = HS256
+		default:
+			return nil, nil
+		}
+	}
+
+	switch alg {
+	case HS256:
+		if secret == "" {
+			return nil, errors.New("HS256 requires a JWT secret")
+		}
+		return &JWTVerifier{Algorithm: HS256, Secret: []byte(secret)}, nil
+	case RS256:
+		if keyFile == "" {
+			return nil, errors.New("RS256 requires a JWT public key file")
+		}
+		pemBytes, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read JWT public key: %w", err)
+		}
+		key, err := ParseRSAPublicKey(pemBytes)
+		if err != nil {
+			return nil, err
+		}
+		return &JWTVerifier{Algorithm: RS256, PublicKey: key}, nil
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", alg)
+	}
+}
+
+// ParseRSAPublicKey decodes a PEM-encoded PKIX or PKCS#1 RSA public key.
+func ParseRSAPublicKey(pemBytes []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errors.New("JWT public key: no PEM block found")
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("JWT public key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("JWT public key: not an RSA key")
+	}
+	return key, nil
+}
+
+// RequireJWT rejects requests without a valid bearer token and stores the
+// verified claims in the request context for gateway.ClaimsFromContext.
+// A nil verifier (authentication not configured) rejects every request.
+// Responses vary on Authorization.
+func (v *JWTVerifier) RequireJWT(next http.Handler) http.Handler {
+	return v.require(next, false)
+}
+
+// RequireJWTQuery is RequireJWT that also accepts the token in an
+// access_token query parameter, for browser WebSocket clients that cannot
+// set an Authorization header.
+func (v *JWTVerifier) RequireJWTQuery(next http.Handler) http.Handler {
+	return v.require(next, true)
+}
+
+func (v *JWTVerifier) require(next http.Handler, allowQuery bool) http.Handler {
+	if v == nil {
+		return requireBearer(nil, next, allowQuery)
+	}
+	return requireBearer(v, next, allowQuery)
+}
+
+// requireBearer authenticates requests to next by their bearer token with
+// a. A nil a rejects every request.
+func requireBearer(a Authenticator, next http.Handler, allowQuery bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		respond.Vary(w, "Authorization")
+		token, ok := bearerToken(r)
+		if !ok && allowQuery {
+			token = r.URL.Query().Get("access_token")
+			ok = token != ""
+		}
+		if !ok || a == nil {
+			auditFailure(r, methodJWT, "no credentials")
+			unauthorized(w, r)
+			return
+		}
+		claims, err := a.Authenticate(r.Context(), token)
+		if err != nil {
+			auditFailure(r, methodJWT, "invalid token")
+			unauthorized(w, r)
+			return
+		}
+		ctx := gateway.WithClaims(r.Context(), claims)
+		ctx = gateway.WithLogAttrs(ctx, "principal", claims.Subject)
+		audit.Record(ctx, audit.ActionAuthenticate, audit.OutcomeSuccess, slog.String("method", methodJWT))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Authenticate implements Authenticator.
+func (v *JWTVerifier) Authenticate(ctx context.Context, token string) (*gateway.Claims, error) {
+	return v.Verify(token)
+}
+
+// Verify checks the token's signature and validity window and returns its
+// claims.
+func (v *JWTVerifier) Verify(token string) (*gateway.Claims, error) {
+	jwt, err := parseJWT(token)
+	if err != nil || jwt.alg != v.Algorithm {
+		return nil, errInvalidToken
+	}
+	if err := v.verifySignature(jwt.signingInput, jwt.sig); err != nil {
+		return nil, errInvalidToken
+	}
+	if err := validAt(&jwt.claims, clock(v.now)); err != nil {
+		return nil, err
+	}
+	return &jwt.claims, nil
+}
+
+// parsedJWT is a compact JWT, decoded but not yet verified.
+type parsedJWT struct {
+	alg, kid     string
+	signingInput string
+	sig          []byte
+	claims       gateway.Claims
+}
+
+// parseJWT decodes token's segments, checking only that they are well
+// formed.
+func parseJWT(token string) (*parsedJWT, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errInvalidToken
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, errInvalidToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errInvalidToken
+	}
+	jwt := &parsedJWT{alg: header.Alg, kid: header.Kid, signingInput: parts[0] + "." + parts[1], sig: sig}
+	if err := decodeSegment(parts[1], &jwt.claims); err != nil {
+		return nil, errInvalidToken
+	}
+	return jwt, nil
+}
+
+// validAt checks that claims, which must expire, are valid at now.
+func validAt(claims *gateway.Claims, now time.Time) error {
+	if claims.ExpiresAt == 0 || !now.Before(claims.ExpiresAt.Time()) {
+		return errInvalidToken
+	}
+	if claims.NotBefore != 0 && now.Before(claims.NotBefore.Time()) {
+		return errInvalidToken
+	}
+	return nil
+}
+
+// clock returns now(), or the current time if now is nil.
+func clock(now func() time.Time) time.Time {
+	if now != nil {
+		return now()
+	}
+	return time.Now()
+}
+
+func (v *JWTVerifier) verifySignature(signingInput string, sig []byte) error {
+	switch v.Algorithm {
+	case HS256:
+		mac := hmac.New(sha256.New, v.Secret)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return errInvalidToken
+		}
+		return nil
+	case RS256:
+		if v.PublicKey == nil {
+			return errInvalidToken
+		}
+		return verifyRS256(v.PublicKey, signingInput, sig)
+	default:
+		return errInvalidToken
+	}
+}
+
+func verifyRS256(key *rsa.PublicKey, signingInput string, sig []byte) error {
+	digest := sha256.Sum256([]byte(signingInput))
+	return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig)
+}
+
+func decodeSegment(seg string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// Authentication methods, as audited.
+const (
+	methodJWT       = "jwt"
+	methodAPIKey    = "api_key"
+	methodSignature = "signature"
+)
+
+// auditFailure records r's failure to authenticate by method, for reason.
+func auditFailure(r *http.Request, method, reason string) {
+	audit.Record(r.Context(), audit.ActionAuthenticate, audit.OutcomeFailure,
+		slog.String("method", method), slog.String("reason", reason))
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	apierror.Write(w, r, apierror.InvalidToken("a valid bearer token is required"))
+}
@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/gateway"
+)
+
+var testNow = time.Unix(1_700_000_000, 0)
+
+func encodeSegment(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func signHS256(t *testing.T, secret []byte, alg string, claims map[string]any) string {
+	t.Helper()
+	input := encodeSegment(t, map[string]string{"alg": alg, "typ": "JWT"}) + "." + encodeSegment(t, claims)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(input))
+	return input + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, claims map[string]any) string {
+	t.Helper()
+	input := encodeSegment(t, map[string]string{"alg": RS256, "typ": "JWT"}) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(input))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func validClaims() map[string]any {
+	return map[string]any{
+		"sub": "user-1",
+		"exp": testNow.Add(time.Hour).Unix(),
+		"nbf": testNow.Add(-time.Minute).Unix(),
+	}
+}
+
+func TestVerifyHS256(t *testing.T) {
+	secret := []byte("s3cret")
+	v := &JWTVerifier{Algorithm: HS256, Secret: secret, now: func() time.Time { return testNow }}
+
+	expired := validClaims()
+	expired["exp"] = testNow.Add(-time.Second).Unix()
+	early := validClaims()
+	early["nbf"] = testNow.Add(time.Minute).Unix()
+	noExp := validClaims()
+	delete(noExp, "exp")
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"valid", signHS256(t, secret, HS256, validClaims()), false},
+		{"wrong secret", signHS256(t, []byte("other"), HS256, validClaims()), true},
+		{"expired", signHS256(t, secret, HS256, expired), true},
+		{"not yet valid", signHS256(t, secret, HS256, early), true},
+		{"missing exp", signHS256(t, secret, HS256, noExp), true},
+		{"alg none", encodeSegment(t, map[string]string{"alg": "none"}) + "." + encodeSegment(t, validClaims()) + ".", true},
+		{"malformed", "not-a-jwt", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := v.Verify(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && claims.Subject != "user-1" {
+				t.Errorf("Subject = %q, want user-1", claims.Subject)
+			}
+		})
+	}
+}
+
+func TestVerifyRS256RejectsHS256Downgrade(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := &JWTVerifier{Algorithm: RS256, PublicKey: &key.PublicKey, now: func() time.Time { return testNow }}
+
+	if _, err := v.Verify(signRS256(t, key, validClaims())); err != nil {
+		t.Fatalf("valid RS256 token rejected: %v", err)
+	}
+	// An HS256 token "signed" with the public modulus must not pass.
+	forged := signHS256(t, key.PublicKey.N.Bytes(), HS256, validClaims())
+	if _, err := v.Verify(forged); err == nil {
+		t.Fatal("HS256 token accepted by RS256 verifier")
+	}
+}
+
+func TestRequireJWT(t *testing.T) {
+	secret := []byte("s3cret")
+	v := &JWTVerifier{Algorithm: HS256, Secret: secret, now: func() time.Time { return testNow }}
+	var gotSubject string
+	h := v.RequireJWT(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSubject = gateway.ClaimsFromContext(r.Context()).Subject
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+signHS256(t, secret, HS256, validClaims()))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || gotSubject != "user-1" {
+		t.Fatalf("valid token: status %d subject %q", rec.Code, gotSubject)
+	}
+	if got := rec.Header().Get("Vary"); got != "Authorization" {
+		t.Errorf("Vary = %q, want Authorization", got)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("missing token: status %d, want 401", rec.Code)
+	}
+	if got := rec.Body.String(); !strings.Contains(got, `"code":"invalid_token"`) {
+		t.Errorf("body = %q", got)
+	}
+}
@@ -0,0 +1,235 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/gateway"
+)
+
+const (
+	// DefaultKeyRefreshInterval is how long an OIDC provider's key set is
+	// used before it is fetched again. Used by the config package.
+	DefaultKeyRefreshInterval = time.Hour
+	// minKeyRefresh spaces out key set fetches, so tokens naming unknown
+	// keys cannot make the gateway fetch on every request.
+	minKeyRefresh = 10 * time.Second
+	// fetchTimeout bounds each discovery and key set request.
+	fetchTimeout = 10 * time.Second
+	// maxDocumentBytes caps the discovery document and key set read.
+	maxDocumentBytes = 1 << 20
+)
+
+// DiscoveryPath is where an OpenID Connect issuer serves its discovery
+// document.
+const DiscoveryPath = "/.well-known/openid-configuration"
+
+// OIDCVerifier validates RS256 bearer tokens issued by an OpenID Connect
+// provider, with the keys of the JWKS its discovery document names. The
+// key set is fetched on first use, again once it is RefreshInterval old,
+// and early when a token names a key it does not hold, so the provider can
+// rotate keys. If a fetch fails the keys already held are kept. Tokens
+// must carry the provider's iss and Audience among their aud.
+type OIDCVerifier struct {
+	Issuer   string
+	Audience string
+	// RefreshInterval is how long a key set is used; zero means
+	// DefaultKeyRefreshInterval.
+	RefreshInterval time.Duration
+	// Client makes the discovery and key set requests; nil means
+	// http.DefaultClient.
+	Client *http.Client
+
+	now func() time.Time
+
+	// refreshMu serialises fetches and guards attempted; mu guards the
+	// discovered key set.
+	refreshMu sync.Mutex
+	attempted time.Time
+	mu        sync.RWMutex
+	jwksURI   string
+	keys      map[string]*rsa.PublicKey
+	fetched   time.Time
+}
+
+// NewOIDCVerifier returns a verifier for tokens of issuer meant for
+// audience. It fetches nothing until Refresh or the first token.
+func NewOIDCVerifier(issuer, audience string, refresh time.Duration) *OIDCVerifier {
+	return &OIDCVerifier{Issuer: strings.TrimSuffix(issuer, "/"), Audience: audience, RefreshInterval: refresh}
+}
+
+// Authenticate implements Authenticator.
+func (v *OIDCVerifier) Authenticate(ctx context.Context, token string) (*gateway.Claims, error) {
+	jwt, err := parseJWT(token)
+	if err != nil || jwt.alg != RS256 {
+		return nil, errInvalidToken
+	}
+	key := v.key(ctx, jwt.kid)
+	if key == nil || verifyRS256(key, jwt.signingInput, jwt.sig) != nil {
+		return nil, errInvalidToken
+	}
+	claims := &jwt.claims
+	if strings.TrimSuffix(claims.Issuer, "/") != v.Issuer || !slices.Contains(claims.Audience, v.Audience) {
+		return nil, errInvalidToken
+	}
+	if err := validAt(claims, clock(v.now)); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// key returns the key kid names, refreshing the key set when it is due or
+// does not hold kid. A token without a kid may use the only key of a set
+// holding one.
+func (v *OIDCVerifier) key(ctx context.Context, kid string) *rsa.PublicKey {
+	lookup := func() (*rsa.PublicKey, bool) {
+		v.mu.RLock()
+		defer v.mu.RUnlock()
+		stale := clock(v.now).Sub(v.fetched) >= v.refreshInterval()
+		if kid == "" && len(v.keys) == 1 {
+			for _, k := range v.keys {
+				return k, stale
+			}
+		}
+		return v.keys[kid], stale
+	}
+	k, stale := lookup()
+	if k != nil && !stale {
+		return k
+	}
+	v.refreshMu.Lock()
+	// Fetches are spaced out, including for callers that waited here while
+	// another one fetched.
+	if now := clock(v.now); now.Sub(v.attempted) >= minKeyRefresh {
+		v.attempted = now
+		if err := v.refreshLocked(ctx); err != nil {
+			gateway.Logger(ctx).WarnContext(ctx, "failed to refresh the OIDC key set; keeping the keys held",
+				"issuer", v.Issuer, "error", err)
+		}
+	}
+	v.refreshMu.Unlock()
+	k, _ = lookup()
+	return k
+}
+
+// Refresh fetches the provider's discovery document, if it has not been
+// yet, and its current key set.
+func (v *OIDCVerifier) Refresh(ctx context.Context) error {
+	v.refreshMu.Lock()
+	defer v.refreshMu.Unlock()
+	v.attempted = clock(v.now)
+	return v.refreshLocked(ctx)
+}
+
+func (v *OIDCVerifier) refreshLocked(ctx context.Context) error {
+	// A fetch serves every later caller, so the one that made it going
+	// away does not cancel it.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), fetchTimeout)
+	defer cancel()
+
+	v.mu.RLock()
+	jwksURI := v.jwksURI
+	v.mu.RUnlock()
+	if jwksURI == "" {
+		var doc struct {
+			Issuer  string `json:"issuer"`
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := v.fetch(ctx, v.Issuer+DiscoveryPath, &doc); err != nil {
+			return fmt.Errorf("discovery: %w", err)
+		}
+		if strings.TrimSuffix(doc.Issuer, "/") != v.Issuer {
+			return fmt.Errorf("discovery: document is for issuer %q", doc.Issuer)
+		}
+		if doc.JWKSURI == "" {
+			return errors.New("discovery: document has no jwks_uri")
+		}
+		jwksURI = doc.JWKSURI
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := v.fetch(ctx, jwksURI, &set); err != nil {
+		return fmt.Errorf("key set: %w", err)
+	}
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if key, ok := k.rsaKey(); ok {
+			keys[k.Kid] = key
+		}
+	}
+	if len(keys) == 0 {
+		return errors.New("key set: no RS256 signing keys")
+	}
+	v.mu.Lock()
+	v.jwksURI, v.keys, v.fetched = jwksURI, keys, clock(v.now)
+	v.mu.Unlock()
+	return nil
+}
+
+// fetch decodes the JSON document at url into dst.
+func (v *OIDCVerifier) fetch(ctx context.Context, url string, dst any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	client := v.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxDocumentBytes)).Decode(dst)
+}
+
+func (v *OIDCVerifier) refreshInterval() time.Duration {
+	if v.RefreshInterval <= 0 {
+		return DefaultKeyRefreshInterval
+	}
+	return v.RefreshInterval
+}
+
+// jwk is one key of a JSON Web Key Set.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// rsaKey returns k as an RSA signing key, and false for any other key.
+func (k jwk) rsaKey() (*rsa.PublicKey, bool) {
+	if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") || (k.Alg != "" && k.Alg != RS256) {
+		return nil, false
+	}
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil || len(n) == 0 {
+		return nil, false
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil || len(e) == 0 || len(e) > 4 {
+		return nil, false
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, true
+}
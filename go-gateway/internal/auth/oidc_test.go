@@ -0,0 +1,258 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/gateway"
+)
+
+// provider is an OpenID Connect provider serving a discovery document and
+// the key set in keys.
+type provider struct {
+	*httptest.Server
+	mu        sync.Mutex
+	keys      map[string]*rsa.PrivateKey
+	down      bool
+	jwksCalls atomic.Int32
+}
+
+func newProvider(t *testing.T, kids ...string) *provider {
+	t.Helper()
+	p := &provider{keys: map[string]*rsa.PrivateKey{}}
+	for _, kid := range kids {
+		p.rotate(t, kid)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+DiscoveryPath, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"issuer": p.URL, "jwks_uri": p.URL + "/keys"})
+	})
+	mux.HandleFunc("GET /keys", func(w http.ResponseWriter, r *http.Request) {
+		p.jwksCalls.Add(1)
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if p.down {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		var set []map[string]string
+		for kid, k := range p.keys {
+			set = append(set, map[string]string{
+				"kty": "RSA", "kid": kid, "use": "sig", "alg": RS256,
+				"n": base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+				"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+			})
+		}
+		// A key of another type is skipped.
+		set = append(set, map[string]string{"kty": "EC", "kid": "ec-1", "crv": "P-256"})
+		json.NewEncoder(w).Encode(map[string]any{"keys": set})
+	})
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+	return p
+}
+
+// rotate replaces the provider's keys with a new one named kid.
+func (p *provider) rotate(t *testing.T, kid string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keys = map[string]*rsa.PrivateKey{kid: key}
+}
+
+// signKid issues an RS256 token for claims, signed with key and naming
+// kid.
+func signKid(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]any) string {
+	t.Helper()
+	input := encodeSegment(t, map[string]string{"alg": RS256, "typ": "JWT", "kid": kid}) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(input))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func (p *provider) key(kid string) *rsa.PrivateKey {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.keys[kid]
+}
+
+func (p *provider) claims() map[string]any {
+	c := validClaims()
+	c["iss"], c["aud"] = p.URL, []string{"other-api", "gateway"}
+	return c
+}
+
+// fakeClock is a settable time for verifiers.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestOIDCVerifier(p *provider) (*OIDCVerifier, *fakeClock) {
+	clk := &fakeClock{now: testNow}
+	v := NewOIDCVerifier(p.URL+"/", "gateway", time.Hour)
+	v.now = clk.Now
+	return v, clk
+}
+
+func TestOIDCVerifier(t *testing.T) {
+	p := newProvider(t, "k1")
+	v, _ := newTestOIDCVerifier(p)
+	ctx := context.Background()
+	key := p.key("k1")
+
+	claims, err := v.Authenticate(ctx, signKid(t, key, "k1", p.claims()))
+	if err != nil || claims.Subject != "user-1" {
+		t.Fatalf("valid token: %+v, %v", claims, err)
+	}
+	// A token without a kid may use the only key.
+	if _, err := v.Authenticate(ctx, signKid(t, key, "", p.claims())); err != nil {
+		t.Errorf("token without kid: %v", err)
+	}
+
+	bad := map[string]map[string]any{
+		"wrong issuer":   {"iss": "https://evil.example.com"},
+		"wrong audience": {"aud": "other-api"},
+		"no audience":    {"aud": nil},
+		"expired":        {"exp": testNow.Add(-time.Minute).Unix()},
+	}
+	for name, override := range bad {
+		c := p.claims()
+		for k, val := range override {
+			c[k] = val
+		}
+		if _, err := v.Authenticate(ctx, signKid(t, key, "k1", c)); err == nil {
+			t.Errorf("%s: token accepted", name)
+		}
+	}
+	other, _ := rsa.GenerateKey(rand.Reader, 2048)
+	if _, err := v.Authenticate(ctx, signKid(t, other, "k1", p.claims())); err == nil {
+		t.Error("token signed with another key accepted")
+	}
+	if _, err := v.Authenticate(ctx, signHS256(t, []byte("s3cret"), HS256, p.claims())); err == nil {
+		t.Error("HS256 token accepted")
+	}
+	if n := p.jwksCalls.Load(); n != 1 {
+		t.Errorf("key set fetched %d times, want 1", n)
+	}
+}
+
+func TestOIDCKeyRotation(t *testing.T) {
+	p := newProvider(t, "k1")
+	v, clk := newTestOIDCVerifier(p)
+	v.RefreshInterval = time.Minute
+	ctx := context.Background()
+	if err := v.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// A token naming a key the verifier does not hold yet fetches the key
+	// set again.
+	clk.Add(minKeyRefresh)
+	p.rotate(t, "k2")
+	if _, err := v.Authenticate(ctx, signKid(t, p.key("k2"), "k2", p.claims())); err != nil {
+		t.Fatalf("token with the rotated key: %v", err)
+	}
+	// Unknown keys do not make it fetch more often than minKeyRefresh.
+	for range 3 {
+		if _, err := v.Authenticate(ctx, signKid(t, p.key("k2"), "k3", p.claims())); err == nil {
+			t.Fatal("token with an unknown kid accepted")
+		}
+	}
+	if n := p.jwksCalls.Load(); n != 2 {
+		t.Errorf("key set fetched %d times, want 2", n)
+	}
+
+	// Once stale, the key set is fetched again; one that cannot be is
+	// kept.
+	clk.Add(v.RefreshInterval)
+	p.mu.Lock()
+	p.down = true
+	p.mu.Unlock()
+	if _, err := v.Authenticate(ctx, signKid(t, p.key("k2"), "k2", p.claims())); err != nil {
+		t.Errorf("token while the provider is down: %v", err)
+	}
+	if n := p.jwksCalls.Load(); n != 3 {
+		t.Errorf("key set fetched %d times, want 3", n)
+	}
+}
+
+func TestOIDCRejectsForeignDiscovery(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"issuer": "https://id.example.com", "jwks_uri": "https://id.example.com/keys"})
+	}))
+	defer srv.Close()
+	if err := NewOIDCVerifier(srv.URL, "gateway", 0).Refresh(context.Background()); err == nil {
+		t.Error("Refresh accepted a discovery document for another issuer")
+	}
+}
+
+func TestNewAuthenticator(t *testing.T) {
+	a, err := NewAuthenticator(Config{Secret: "s3cret", OIDCIssuer: "https://id.example.com/", OIDCAudience: "gateway"})
+	if v, ok := a.(*OIDCVerifier); err != nil || !ok || v.Issuer != "https://id.example.com" {
+		t.Errorf("OIDC: %T %v", a, err)
+	}
+	if _, err := NewAuthenticator(Config{OIDCIssuer: "https://id.example.com"}); err == nil {
+		t.Error("OIDC without an audience accepted")
+	}
+	if a, err := NewAuthenticator(Config{Secret: "s3cret"}); err != nil || a.(*JWTVerifier).Algorithm != HS256 {
+		t.Errorf("static secret: %T %v", a, err)
+	}
+	if a, err := NewAuthenticator(Config{}); a != nil || err != nil {
+		t.Errorf("nothing configured: %v %v, want nil", a, err)
+	}
+}
+
+func TestMiddlewareWithOIDC(t *testing.T) {
+	p := newProvider(t, "k1")
+	v, _ := newTestOIDCVerifier(p)
+	h := (&Middleware{JWT: v}).Require(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gateway.Logger(r.Context()).Info("authenticated")
+	}))
+	var logged bytes.Buffer
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(gateway.WithLogger(req.Context(), slog.New(slog.NewJSONHandler(&logged, nil))))
+	req.Header.Set("Authorization", "Bearer "+signKid(t, p.key("k1"), "k1", p.claims()))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	if !strings.Contains(logged.String(), `"principal":"user-1"`) {
+		t.Errorf("request logger = %s, want the principal", logged.String())
+	}
+}
@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/audit"
+	"github.com/content-factory/go-gateway/internal/gateway"
+)
+
+// HasScope reports whether the space-separated scope claim c carries
+// includes scope. API keys' scopes are put in the same claim, see
+// Middleware.
+func HasScope(c *gateway.Claims, scope string) bool {
+	if c == nil {
+		return false
+	}
+	return slices.Contains(strings.Fields(c.Scope), scope)
+}
+
+// RequireScope rejects authenticated requests whose claims lack any of
+// scopes with 403, naming the first scope missing. It goes after Require,
+// which establishes the claims; unauthenticated requests get 401.
+func RequireScope(scopes ...string) func(http.Handler) http.Handler {
+	return requireScopes(func(c *gateway.Claims) (string, bool) {
+		for _, s := range scopes {
+			if !HasScope(c, s) {
+				return s, false
+			}
+		}
+		return "", true
+	}, func(missing string) string {
+		return "this endpoint requires the " + missing + " scope"
+	})
+}
+
+// RequireAnyScope is RequireScope for endpoints that need only one of
+// scopes; the 403 lists them all.
+func RequireAnyScope(scopes ...string) func(http.Handler) http.Handler {
+	required := strings.Join(scopes, " ")
+	return requireScopes(func(c *gateway.Claims) (string, bool) {
+		if slices.ContainsFunc(scopes, func(s string) bool { return HasScope(c, s) }) {
+			return "", true
+		}
+		return required, false
+	}, func(string) string {
+		return "this endpoint requires one of the " + strings.Join(scopes, ", ") + " scopes"
+	})
+}
+
+// requireScopes runs check on the claims of each request; when it fails,
+// the client is told the missing scopes, space-separated as in RFC 6750,
+// and msg describes them. The claims themselves are never echoed.
+func requireScopes(check func(*gateway.Claims) (missing string, ok bool), msg func(missing string) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims := gateway.ClaimsFromContext(r.Context())
+			if claims == nil {
+				unauthorized(w, r)
+				return
+			}
+			if missing, ok := check(claims); !ok {
+				audit.Record(r.Context(), audit.ActionAuthorize, audit.OutcomeDenied,
+					slog.String("reason", "missing scope"), slog.String("scope", missing), slog.String("path", r.URL.Path))
+				w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+missing+`"`)
+				apierror.Write(w, r, apierror.InsufficientScope(msg(missing)))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/content-factory/go-gateway/internal/gateway"
+)
+
+func TestRequireScope(t *testing.T) {
+	h := RequireScope("admin")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	tests := []struct {
+		name   string
+		claims *gateway.Claims
+		want   int
+	}{
+		{"unauthenticated", nil, http.StatusUnauthorized},
+		{"no scopes", &gateway.Claims{Subject: "u"}, http.StatusForbidden},
+		{"other scopes", &gateway.Claims{Subject: "u", Scope: "content:read administrator"}, http.StatusForbidden},
+		{"has scope", &gateway.Claims{Subject: "u", Scope: "content:read admin"}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+			if tt.claims != nil {
+				req = req.WithContext(gateway.WithClaims(req.Context(), tt.claims))
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestScopeSemantics(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	claims := &gateway.Claims{Subject: "u", Scope: "content:read secret:internal"}
+	tests := []struct {
+		name    string
+		mw      func(http.Handler) http.Handler
+		want    int
+		missing string
+	}{
+		{"all present", RequireScope("content:read"), http.StatusOK, ""},
+		{"all, one missing", RequireScope("content:read", "content:write"), http.StatusForbidden, "content:write"},
+		{"any, one present", RequireAnyScope("content:write", "content:read"), http.StatusOK, ""},
+		{"any, none present", RequireAnyScope("content:write", "admin"), http.StatusForbidden, "content:write admin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req = req.WithContext(gateway.WithClaims(req.Context(), claims))
+			rec := httptest.NewRecorder()
+			tt.mw(ok).ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if tt.missing == "" {
+				return
+			}
+			if got := rec.Header().Get("WWW-Authenticate"); !strings.Contains(got, `scope="`+tt.missing+`"`) {
+				t.Errorf("WWW-Authenticate = %q, want scope %q", got, tt.missing)
+			}
+			if strings.Contains(rec.Body.String(), "secret:internal") {
+				t.Errorf("body leaks the claimed scopes: %s", rec.Body)
+			}
+		})
+	}
+}
+
+func TestScopeFromAPIKey(t *testing.T) {
+	store, err := NewMemoryKeyStore(testKeys())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &Middleware{JWT: &JWTVerifier{Algorithm: HS256, Secret: []byte("s3cret")}, Keys: store}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	for scope, want := range map[string]int{"content:write": http.StatusOK, "admin": http.StatusForbidden} {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set(APIKeyHeader, "k-partner-0123456789")
+		rec := httptest.NewRecorder()
+		m.Require(RequireScope(scope)(ok)).ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("%s: status = %d, want %d", scope, rec.Code, want)
+		}
+	}
+}
@@ -0,0 +1,381 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/audit"
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/respond"
+)
+
+// The headers of a signed request.
+const (
+	KeyIDHeader     = "X-Key-Id"
+	SignatureHeader = "X-Signature"
+	TimestampHeader = "X-Timestamp"
+)
+
+// DefaultMaxClockSkew is how far a signed request's timestamp may be from
+// the gateway's clock when NewSignatureVerifier is given no skew.
+const DefaultMaxClockSkew = 5 * time.Minute
+
+// signedBodyBuffer is how much of a signed body is held in memory while
+// it is checked; the rest of a longer one is spilled to a temporary file.
+const signedBodyBuffer = 1 << 20
+
+var (
+	// ErrUnknownSigningKey is returned by SignatureVerifier.Verify for a
+	// key ID it does not hold.
+	ErrUnknownSigningKey = errors.New("unknown signing key")
+	// ErrBadSignature is returned by SignatureVerifier.Verify for a
+	// signature that is missing, malformed or does not match.
+	ErrBadSignature = errors.New("signature is missing or invalid")
+	// ErrClockSkew is returned by SignatureVerifier.Verify for a request
+	// whose timestamp is missing or too far from the gateway's clock.
+	ErrClockSkew = errors.New("timestamp is missing or outside the allowed clock skew")
+
+	errDisabledSigningKey = errors.New("disabled signing key")
+)
+
+// SigningKey is a secret shared with a partner that signs its requests
+// instead of holding a long-lived token. Requests it signs act as
+// Principal with Scopes.
+type SigningKey struct {
+	// ID is sent in X-Key-Id to name the key; it is not secret.
+	ID        string
+	Secret    string
+	Principal string
+	Scopes    []string
+	// Enabled is cleared to revoke the key without deleting it.
+	Enabled bool
+	// Tenant, if set, is the tenant the key's requests act for.
+	Tenant string
+}
+
+// SignatureVerifier checks requests signed with an HMAC-SHA256 of their
+// method, path and query, timestamp and body, see Sign. A request is only
+// accepted within the clock skew of its timestamp, so a captured one
+// cannot be replayed later.
+type SignatureVerifier struct {
+	keys    map[string]*SigningKey
+	maxSkew time.Duration
+
+	// now is time.Now; tests replace it.
+	now func() time.Time
+}
+
+// NewSignatureVerifier returns a verifier holding keys, accepting
+// timestamps up to maxSkew either side of the gateway's clock, or
+// DefaultMaxClockSkew if maxSkew is zero.
+func NewSignatureVerifier(keys []SigningKey, maxSkew time.Duration) (*SignatureVerifier, error) {
+	if maxSkew <= 0 {
+		maxSkew = DefaultMaxClockSkew
+	}
+	byID := make(map[string]*SigningKey, len(keys))
+	for i := range keys {
+		k := keys[i]
+		switch {
+		case k.ID == "":
+			return nil, fmt.Errorf("signing key %d: id is required", i)
+		case k.Secret == "":
+			return nil, fmt.Errorf("signing key %q: secret is required", k.ID)
+		case k.Principal == "":
+			return nil, fmt.Errorf("signing key %q: principal is required", k.ID)
+		case byID[k.ID] != nil:
+			return nil, fmt.Errorf("signing key %q: duplicate id", k.ID)
+		}
+		byID[k.ID] = &k
+	}
+	return &SignatureVerifier{keys: byID, maxSkew: maxSkew, now: time.Now}, nil
+}
+
+// Verify checks the signature of r and returns the key that made it,
+// which may be disabled. A request with a body is checked when the body
+// is first read, so that it is read under the route's own limit: r's body
+// is replaced by one that reads the whole body, holding what does not fit
+// in 1 MiB in a temporary file, before it returns any of it, and whose
+// reads fail with ErrBadSignature if it does not match. Closing it
+// removes the file.
+func (v *SignatureVerifier) Verify(r *http.Request) (*SigningKey, error) {
+	return v.verify(r, nil)
+}
+
+// verify is Verify, calling mismatch, if set, when a body fails its
+// check.
+func (v *SignatureVerifier) verify(r *http.Request, mismatch func()) (*SigningKey, error) {
+	k := v.keys[strings.TrimSpace(r.Header.Get(KeyIDHeader))]
+	if k == nil {
+		return nil, ErrUnknownSigningKey
+	}
+	timestamp := r.Header.Get(TimestampHeader)
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return k, ErrClockSkew
+	}
+	if skew := v.now().Sub(time.Unix(unix, 0)).Abs(); skew > v.maxSkew {
+		return k, ErrClockSkew
+	}
+	sig, err := hex.DecodeString(r.Header.Get(SignatureHeader))
+	if err != nil || len(sig) == 0 {
+		return k, ErrBadSignature
+	}
+	method, requestURI := r.Method, r.URL.RequestURI()
+	matches := func(digest []byte) bool {
+		return hmac.Equal(sig, signature(k.Secret, method, requestURI, timestamp, digest))
+	}
+	if r.Body == nil || r.Body == http.NoBody {
+		if digest := sha256.Sum256(nil); !matches(digest[:]) {
+			return k, ErrBadSignature
+		}
+		return k, nil
+	}
+	r.Body = &signedBody{body: r.Body, digest: sha256.New(), matches: matches, mismatch: mismatch}
+	return k, nil
+}
+
+// Sign returns the hex-encoded signature of a request, as a partner sends
+// it in X-Signature: the HMAC-SHA256, under secret, of the method, the
+// request URI (the escaped path and any query), the timestamp in Unix
+// seconds and the hex SHA-256 of the body, each on a line of its own.
+func Sign(secret, method, requestURI, timestamp string, body []byte) string {
+	digest := sha256.Sum256(body)
+	return hex.EncodeToString(signature(secret, method, requestURI, timestamp, digest[:]))
+}
+
+func signature(secret, method, requestURI, timestamp string, digest []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(method + "\n" + requestURI + "\n" + timestamp + "\n" + hex.EncodeToString(digest)))
+	return mac.Sum(nil)
+}
+
+// signedBody checks a signed request's body on its first read, reading
+// all of it first, and fails with ErrBadSignature if it does not match.
+// Up to signedBodyBuffer bytes are held in memory, and the rest in spill.
+type signedBody struct {
+	body     io.ReadCloser
+	digest   hash.Hash
+	matches  func(digest []byte) bool
+	mismatch func()
+
+	r     io.Reader
+	spill *os.File
+	err   error
+}
+
+func (b *signedBody) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	if b.r == nil {
+		if err := b.readAll(); err != nil {
+			b.err = err
+			return 0, err
+		}
+		if err := b.check(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := b.r.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) {
+		b.err = err
+	}
+	return n, err
+}
+
+// readAll reads and hashes the whole body, setting b.r to read it again.
+func (b *signedBody) readAll() error {
+	head, err := io.ReadAll(io.LimitReader(b.body, signedBodyBuffer+1))
+	if err != nil {
+		// Such as *http.MaxBytesError, for the handler to answer.
+		return err
+	}
+	b.digest.Write(head)
+	if len(head) <= signedBodyBuffer {
+		b.r = bytes.NewReader(head)
+		return nil
+	}
+	if b.spill, err = os.CreateTemp("", "gateway-signed-body-*"); err != nil {
+		return err
+	}
+	if _, err := io.Copy(io.MultiWriter(b.spill, b.digest), b.body); err != nil {
+		return err
+	}
+	if _, err := b.spill.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	b.r = io.MultiReader(bytes.NewReader(head), b.spill)
+	return nil
+}
+
+// check compares the digest of the whole body with the signature, once.
+func (b *signedBody) check() error {
+	if b.matches == nil {
+		return nil
+	}
+	ok := b.matches(b.digest.Sum(nil))
+	b.matches = nil
+	if !ok {
+		b.err = ErrBadSignature
+		if b.mismatch != nil {
+			b.mismatch()
+		}
+	}
+	return b.err
+}
+
+// Close closes the body and removes any file it was spilled to.
+func (b *signedBody) Close() error {
+	if b.spill != nil {
+		b.spill.Close()
+		os.Remove(b.spill.Name())
+		b.spill = nil
+	}
+	return b.body.Close()
+}
+
+// signedWriter is the response writer of a signed request with a body.
+// Once the body fails its check, what the handler writes is dropped, so
+// that requireSignature can answer 401 in its place.
+type signedWriter struct {
+	http.ResponseWriter
+
+	mu        sync.Mutex
+	failed    bool
+	committed bool
+	discard   http.Header
+}
+
+// fail marks the body as not matching.
+func (w *signedWriter) fail() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.failed = true
+}
+
+// replaceable reports whether the body failed before the handler
+// committed its own response.
+func (w *signedWriter) replaceable() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.failed && !w.committed
+}
+
+// live reports whether the handler's writes go through, and marks them
+// as committing the response if so.
+func (w *signedWriter) live() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.failed && !w.committed {
+		return false
+	}
+	w.committed = true
+	return true
+}
+
+func (w *signedWriter) Header() http.Header {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.failed && !w.committed {
+		if w.discard == nil {
+			w.discard = http.Header{}
+		}
+		return w.discard
+	}
+	return w.ResponseWriter.Header()
+}
+
+func (w *signedWriter) WriteHeader(status int) {
+	if w.live() {
+		w.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (w *signedWriter) Write(b []byte) (int, error) {
+	if !w.live() {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *signedWriter) Flush() {
+	if w.live() {
+		http.NewResponseController(w.ResponseWriter).Flush()
+	}
+}
+
+func (w *signedWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// requireSignature authenticates requests naming a key in X-Key-Id with
+// v, and passes the others on to fallback.
+func requireSignature(v *SignatureVerifier, fallback, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		respond.Vary(w, KeyIDHeader)
+		if r.Header.Get(KeyIDHeader) == "" {
+			fallback.ServeHTTP(w, r)
+			return
+		}
+		sw := &signedWriter{ResponseWriter: w}
+		key, err := v.verify(r, sw.fail)
+		if err == nil && !key.Enabled {
+			err = errDisabledSigningKey
+		}
+		switch {
+		case err == nil:
+		case errors.Is(err, ErrClockSkew):
+			auditFailure(r, methodSignature, key.ID+": "+err.Error())
+			apierror.Write(w, r, apierror.New(http.StatusUnauthorized, apierror.CodeSignatureExpired,
+				"X-Timestamp must be the current time in Unix seconds, give or take "+v.maxSkew.String()))
+			return
+		default:
+			reason := err.Error()
+			if key != nil {
+				reason = key.ID + ": " + reason
+			}
+			auditFailure(r, methodSignature, reason)
+			writeInvalidSignature(w, r)
+			return
+		}
+		ctx := gateway.WithClaims(r.Context(), &gateway.Claims{
+			Subject: key.Principal,
+			Scope:   strings.Join(key.Scopes, " "),
+			Tenant:  key.Tenant,
+		})
+		ctx = gateway.WithLogAttrs(ctx, "principal", key.Principal)
+		audit.Record(ctx, audit.ActionAuthenticate, audit.OutcomeSuccess,
+			slog.String("method", methodSignature), slog.String("key_id", key.ID))
+		body, checked := r.Body.(*signedBody)
+		if !checked {
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+		// The server closes the body it made, not this one.
+		defer body.Close()
+		next.ServeHTTP(sw, r.WithContext(ctx))
+		// The handler answered a body that did not match as it would any
+		// unreadable one; the client is told why instead.
+		if sw.replaceable() {
+			auditFailure(r, methodSignature, key.ID+": "+ErrBadSignature.Error())
+			writeInvalidSignature(w, r)
+		}
+	})
+}
+
+func writeInvalidSignature(w http.ResponseWriter, r *http.Request) {
+	apierror.Write(w, r, apierror.New(http.StatusUnauthorized, apierror.CodeInvalidSignature,
+		"the request signature or its signing key is invalid or revoked"))
+}
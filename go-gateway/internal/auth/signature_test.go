@@ -0,0 +1,277 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/audit"
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/middleware"
+)
+
+const partnerSecret = "partner-secret-0123456789"
+
+func testVerifier(t *testing.T) *SignatureVerifier {
+	t.Helper()
+	v, err := NewSignatureVerifier([]SigningKey{
+		{ID: "partner", Secret: partnerSecret, Principal: "svc-partner", Scopes: []string{"content:write"}, Enabled: true, Tenant: "acme"},
+		{ID: "old", Secret: "old-secret-0123456789", Principal: "svc-old"},
+	}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.now = func() time.Time { return testNow }
+	return v
+}
+
+// signed returns a request for target with body, signed by the partner
+// key at at.
+func signed(method, target, body string, at time.Time) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	ts := strconv.FormatInt(at.Unix(), 10)
+	req.Header.Set(KeyIDHeader, "partner")
+	req.Header.Set(TimestampHeader, ts)
+	req.Header.Set(SignatureHeader, Sign(partnerSecret, method, req.URL.RequestURI(), ts, []byte(body)))
+	return req
+}
+
+func TestNewSignatureVerifierRejectsInvalid(t *testing.T) {
+	for name, keys := range map[string][]SigningKey{
+		"missing id":        {{Secret: "s", Principal: "p"}},
+		"missing secret":    {{ID: "a", Principal: "p"}},
+		"missing principal": {{ID: "a", Secret: "s"}},
+		"duplicate id":      {{ID: "a", Secret: "s1", Principal: "p"}, {ID: "a", Secret: "s2", Principal: "p"}},
+	} {
+		if _, err := NewSignatureVerifier(keys, 0); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestMiddlewareSignature(t *testing.T) {
+	m := &Middleware{
+		JWT:        &JWTVerifier{Algorithm: HS256, Secret: []byte("s3cret"), now: func() time.Time { return testNow }},
+		Signatures: testVerifier(t),
+	}
+	var claims *gateway.Claims
+	var body string
+	h := m.Require(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims = gateway.ClaimsFromContext(r.Context())
+		b, err := readBody(w, r)
+		if err == nil {
+			body = string(b)
+		}
+	}))
+
+	const payload = `{"topic":"volcanoes"}`
+	tests := []struct {
+		name string
+		req  func() *http.Request
+		want int
+		code apierror.Code
+	}{
+		{"valid", func() *http.Request { return signed(http.MethodPost, "/api/v1/jobs?dry_run=true", payload, testNow) }, http.StatusOK, ""},
+		{"within skew", func() *http.Request {
+			return signed(http.MethodPost, "/api/v1/jobs", payload, testNow.Add(50*time.Second))
+		}, http.StatusOK, ""},
+		{"no body", func() *http.Request { return signed(http.MethodGet, "/api/v1/jobs", "", testNow) }, http.StatusOK, ""},
+		{"stale", func() *http.Request {
+			return signed(http.MethodPost, "/api/v1/jobs", payload, testNow.Add(-2*time.Minute))
+		}, http.StatusUnauthorized, apierror.CodeSignatureExpired},
+		{"future", func() *http.Request {
+			return signed(http.MethodPost, "/api/v1/jobs", payload, testNow.Add(2*time.Minute))
+		}, http.StatusUnauthorized, apierror.CodeSignatureExpired},
+		{"no timestamp", func() *http.Request {
+			req := signed(http.MethodPost, "/api/v1/jobs", payload, testNow)
+			req.Header.Del(TimestampHeader)
+			return req
+		}, http.StatusUnauthorized, apierror.CodeSignatureExpired},
+		{"other timestamp", func() *http.Request {
+			req := signed(http.MethodPost, "/api/v1/jobs", payload, testNow)
+			req.Header.Set(TimestampHeader, strconv.FormatInt(testNow.Unix()+1, 10))
+			return req
+		}, http.StatusUnauthorized, apierror.CodeInvalidSignature},
+		{"other body", func() *http.Request {
+			req := signed(http.MethodPost, "/api/v1/jobs", payload, testNow)
+			req.Body = io.NopCloser(strings.NewReader(`{"topic":"glaciers"}`))
+			return req
+		}, http.StatusUnauthorized, apierror.CodeInvalidSignature},
+		{"other path", func() *http.Request {
+			req := signed(http.MethodPost, "/api/v1/jobs", payload, testNow)
+			req.URL.Path = "/api/v1/jobs/batch"
+			return req
+		}, http.StatusUnauthorized, apierror.CodeInvalidSignature},
+		{"other query", func() *http.Request {
+			req := signed(http.MethodPost, "/api/v1/jobs?dry_run=true", payload, testNow)
+			req.URL.RawQuery = ""
+			return req
+		}, http.StatusUnauthorized, apierror.CodeInvalidSignature},
+		{"other method", func() *http.Request {
+			req := signed(http.MethodPost, "/api/v1/jobs", payload, testNow)
+			req.Method = http.MethodPut
+			return req
+		}, http.StatusUnauthorized, apierror.CodeInvalidSignature},
+		{"no signature", func() *http.Request {
+			req := signed(http.MethodPost, "/api/v1/jobs", payload, testNow)
+			req.Header.Del(SignatureHeader)
+			return req
+		}, http.StatusUnauthorized, apierror.CodeInvalidSignature},
+		{"unknown key", func() *http.Request {
+			req := signed(http.MethodPost, "/api/v1/jobs", payload, testNow)
+			req.Header.Set(KeyIDHeader, "nobody")
+			return req
+		}, http.StatusUnauthorized, apierror.CodeInvalidSignature},
+		{"disabled key", func() *http.Request {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/jobs", nil)
+			ts := strconv.FormatInt(testNow.Unix(), 10)
+			req.Header.Set(KeyIDHeader, "old")
+			req.Header.Set(TimestampHeader, ts)
+			req.Header.Set(SignatureHeader, Sign("old-secret-0123456789", http.MethodGet, "/api/v1/jobs", ts, nil))
+			return req
+		}, http.StatusUnauthorized, apierror.CodeInvalidSignature},
+		{"too large", func() *http.Request {
+			req := signed(http.MethodPost, "/api/v1/jobs", payload, testNow)
+			req.Body = http.MaxBytesReader(nil, req.Body, 8)
+			return req
+		}, http.StatusRequestEntityTooLarge, apierror.CodeBodyTooLarge},
+		{"jwt fallback", func() *http.Request {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+signHS256(t, []byte("s3cret"), HS256, validClaims()))
+			return req
+		}, http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, body = nil, ""
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, tt.req())
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if got := strings.Join(rec.Header().Values("Vary"), ", "); !strings.Contains(got, KeyIDHeader) {
+				t.Errorf("Vary = %q, want %s", got, KeyIDHeader)
+			}
+			if tt.code != "" {
+				var e struct{ Error apierror.Error }
+				if err := json.Unmarshal(rec.Body.Bytes(), &e); err != nil || e.Error.Code != tt.code {
+					t.Errorf("error = %s, want code %s", rec.Body, tt.code)
+				}
+			}
+		})
+	}
+
+	h.ServeHTTP(httptest.NewRecorder(), signed(http.MethodPost, "/api/v1/jobs", payload, testNow))
+	if claims == nil || claims.Subject != "svc-partner" || claims.Scope != "content:write" || claims.Tenant != "acme" {
+		t.Errorf("claims = %+v", claims)
+	}
+	if body != payload {
+		t.Errorf("handler read body %q, want %q", body, payload)
+	}
+}
+
+// readBody reads r's body as a handler does, answering a failure as one
+// would.
+func readBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	b, err := io.ReadAll(r.Body)
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		apierror.Write(w, r, apierror.BodyTooLarge(tooLarge.Limit))
+	case err != nil:
+		apierror.Write(w, r, apierror.InvalidRequest("the request body could not be read"))
+	}
+	return b, err
+}
+
+func TestMiddlewareSignatureChecksLargeBodies(t *testing.T) {
+	v := testVerifier(t)
+	v.now = time.Now
+	t.Setenv("TMPDIR", t.TempDir())
+	var got int
+	// The gateway-wide limit runs before authentication and an upload
+	// route's larger one after it, as in the server.
+	h := middleware.MaxBytes(1 << 20)((&Middleware{Signatures: v}).Require(middleware.BodyLimit(4 << 20)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			buf := make([]byte, 64<<10)
+			n, _ := r.Body.Read(buf)
+			got += n
+			rest, err := readBody(w, r)
+			got += len(rest)
+			if err == nil {
+				w.WriteHeader(http.StatusOK)
+			}
+		}))))
+
+	payload := bytes.Repeat([]byte("0123456789abcdef"), 2<<20/16)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, signed(http.MethodPost, "/api/v1/jobs/1/assets", string(payload), time.Now()))
+	if rec.Code != http.StatusOK || got != len(payload) {
+		t.Fatalf("status = %d, handler read %d of %d bytes: %s", rec.Code, got, len(payload), rec.Body)
+	}
+
+	// The handler gets none of a tampered body, however long.
+	got = 0
+	req := signed(http.MethodPost, "/api/v1/jobs/1/assets", string(payload), time.Now())
+	tampered := bytes.Clone(payload)
+	tampered[len(tampered)-1] = 'X'
+	req.Body = io.NopCloser(bytes.NewReader(tampered))
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	var e struct{ Error apierror.Error }
+	if json.Unmarshal(rec.Body.Bytes(), &e); rec.Code != http.StatusUnauthorized || e.Error.Code != apierror.CodeInvalidSignature || got != 0 {
+		t.Errorf("tampered body: status = %d, handler read %d bytes: %s", rec.Code, got, rec.Body)
+	}
+
+	// A body over the route's limit is not read past it.
+	got = 0
+	large := bytes.Repeat([]byte("x"), 5<<20)
+	req = signed(http.MethodPost, "/api/v1/jobs/1/assets", string(large), time.Now())
+	req.ContentLength = -1
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge || got != 0 {
+		t.Errorf("body over the limit: status = %d, handler read %d bytes: %s", rec.Code, got, rec.Body)
+	}
+
+	if left, _ := os.ReadDir(os.TempDir()); len(left) != 0 {
+		t.Errorf("temporary files left: %v", left)
+	}
+}
+
+func TestMiddlewareSignatureAudits(t *testing.T) {
+	var buf bytes.Buffer
+	audit.SetDefault(audit.New(&buf))
+	t.Cleanup(func() { audit.SetDefault(nil) })
+	h := (&Middleware{Signatures: testVerifier(t)}).Require(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	h.ServeHTTP(httptest.NewRecorder(), signed(http.MethodGet, "/", "", testNow))
+	h.ServeHTTP(httptest.NewRecorder(), signed(http.MethodGet, "/", "", testNow.Add(-time.Hour)))
+	var recs []map[string]any
+	for line := range strings.Lines(buf.String()) {
+		var rec map[string]any
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatal(err)
+		}
+		recs = append(recs, rec)
+	}
+	if len(recs) != 2 {
+		t.Fatalf("got %d records, want 2:\n%s", len(recs), &buf)
+	}
+	if r := recs[0]; r["outcome"] != audit.OutcomeSuccess || r["principal"] != "svc-partner" ||
+		r["method"] != methodSignature || r["key_id"] != "partner" {
+		t.Errorf("valid signature: %v", r)
+	}
+	if r := recs[1]; r["outcome"] != audit.OutcomeFailure || !strings.HasPrefix(r["reason"].(string), "partner: timestamp") {
+		t.Errorf("stale signature: %v", r)
+	}
+}
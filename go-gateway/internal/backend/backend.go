@@ -0,0 +1,538 @@
+// Package backend is the typed client for the Python ContentOrchestrator
+// service defined in protos/content_factory.proto.
+package backend
+
+//go:generate protoc -I../../../protos --go_out=../.. --go_opt=module=github.com/content-factory/go-gateway --go-grpc_out=../.. --go-grpc_opt=module=github.com/content-factory/go-gateway ../../../protos/content_factory.proto
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"path"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/content-factory/go-gateway/internal/rpc"
+	pb "github.com/content-factory/go-gateway/protos/content_factory"
+)
+
+// Fully-qualified ContentOrchestrator method names.
+const (
+	MethodCreateContent   = pb.ContentOrchestrator_CreateContent_FullMethodName
+	MethodEstimateContent = pb.ContentOrchestrator_EstimateContent_FullMethodName
+	MethodStreamProgress  = pb.ContentOrchestrator_StreamProgress_FullMethodName
+	MethodGetJobStatus    = pb.ContentOrchestrator_GetJobStatus_FullMethodName
+	MethodGetJobRequest   = pb.ContentOrchestrator_GetJobRequest_FullMethodName
+	MethodListJobs        = pb.ContentOrchestrator_ListJobs_FullMethodName
+	MethodCancelJob       = pb.ContentOrchestrator_CancelJob_FullMethodName
+	MethodSetVisibility   = pb.ContentOrchestrator_SetJobVisibility_FullMethodName
+	MethodGetContentInfo  = pb.ContentOrchestrator_GetContentInfo_FullMethodName
+	MethodDownload        = pb.ContentOrchestrator_DownloadContent_FullMethodName
+	MethodGenerateStream  = pb.ContentOrchestrator_GenerateStream_FullMethodName
+
+	MethodStartAssetUpload  = pb.ContentOrchestrator_StartAssetUpload_FullMethodName
+	MethodUploadAssetChunk  = pb.ContentOrchestrator_UploadAssetChunk_FullMethodName
+	MethodFinishAssetUpload = pb.ContentOrchestrator_FinishAssetUpload_FullMethodName
+)
+
+// methodsByName holds every method by its short name, such as
+// CreateContent.
+var methodsByName = func() map[string]string {
+	m := make(map[string]string)
+	for _, method := range []string{
+		MethodCreateContent, MethodEstimateContent, MethodStreamProgress, MethodGetJobStatus,
+		MethodGetJobRequest, MethodListJobs, MethodCancelJob, MethodSetVisibility,
+		MethodGetContentInfo, MethodDownload, MethodGenerateStream,
+		MethodStartAssetUpload, MethodUploadAssetChunk, MethodFinishAssetUpload,
+	} {
+		m[path.Base(method)] = method
+	}
+	return m
+}()
+
+// MethodNamed returns the fully-qualified name of the method called name,
+// as configuration names methods, and whether the service has one.
+func MethodNamed(name string) (string, bool) {
+	method, ok := methodsByName[name]
+	return method, ok
+}
+
+// idempotent lists the methods that are safe to retry. Reads always are;
+// a write belongs here only if the service deduplicates repeats of it.
+// CreateContent does not: a retry after a lost response would start a
+// second job.
+var idempotent = map[string]bool{
+	MethodEstimateContent: true,
+	MethodStreamProgress:  true,
+	MethodGetJobStatus:    true,
+	MethodGetJobRequest:   true,
+	MethodListJobs:        true,
+	MethodGetContentInfo:  true,
+	MethodDownload:        true,
+}
+
+// IsIdempotent reports whether method may be retried after a transient
+// failure.
+func IsIdempotent(method string) bool {
+	return idempotent[method]
+}
+
+// IsUncapped reports whether method's stream is exempt from the cap on the
+// total size of a response: downloads carry whole files, and progress
+// streams last as long as the job.
+func IsUncapped(method string) bool {
+	return method == MethodDownload || method == MethodStreamProgress
+}
+
+// Conn is the transport the client runs on; *grpcpool.Pool implements it.
+type Conn interface {
+	Invoke(ctx context.Context, method string, req, resp any) error
+	NewStream(ctx context.Context, method string, req any) (rpc.ClientStream, error)
+}
+
+// Client calls the ContentOrchestrator service through its generated gRPC
+// client, converting between the gateway's types and the messages of
+// protos/content_factory.proto.
+type Client struct {
+	conn Conn
+	pb   pb.ContentOrchestratorClient
+}
+
+// New returns a Client using conn.
+func New(conn Conn) *Client {
+	return &Client{conn: conn, pb: pb.NewContentOrchestratorClient(rpc.AsGRPC(conn))}
+}
+
+// streams returns a client for opening one stream, and the stream it
+// opens once it has, so that the stream can be closed.
+func (c *Client) streams() (pb.ContentOrchestratorClient, func() rpc.ClientStream) {
+	sc := &streamConn{Conn: c.conn}
+	return pb.NewContentOrchestratorClient(rpc.AsGRPC(sc)), func() rpc.ClientStream { return sc.stream }
+}
+
+// streamConn records the stream opened through it.
+type streamConn struct {
+	Conn
+	stream rpc.ClientStream
+}
+
+func (c *streamConn) NewStream(ctx context.Context, method string, req any) (rpc.ClientStream, error) {
+	s, err := c.Conn.NewStream(ctx, method, req)
+	c.stream = s
+	return s, err
+}
+
+// ProgressUpdate is one message of the StreamProgress stream.
+type ProgressUpdate struct {
+	ContentID       string    `json:"content_id"`
+	Stage           string    `json:"stage"`
+	ProgressPercent float64   `json:"progress_percent"`
+	Message         string    `json:"message,omitempty"`
+	Timestamp       time.Time `json:"timestamp"`
+	ResultURL       string    `json:"result_url,omitempty"`
+	Sequence        int64     `json:"sequence,omitempty"`
+}
+
+// ProgressStream yields ProgressUpdates until io.EOF.
+type ProgressStream struct {
+	stream grpc.ServerStreamingClient[pb.ProgressUpdate]
+	conn   rpc.ClientStream
+}
+
+// Recv returns the next update.
+func (s *ProgressStream) Recv() (*ProgressUpdate, error) {
+	u, err := s.stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	return progressUpdateFromProto(u), nil
+}
+
+// Close abandons the stream.
+func (s *ProgressStream) Close() error { return s.conn.Close() }
+
+// StreamProgress subscribes to progress updates for a job, starting after
+// update number after (0 for all). Cancelling ctx ends the subscription
+// upstream.
+func (c *Client) StreamProgress(ctx context.Context, jobID string, after int64) (*ProgressStream, error) {
+	client, opened := c.streams()
+	s, err := client.StreamProgress(ctx, &pb.JobStatusRequest{JobId: jobID, AfterSequence: after})
+	if err != nil {
+		return nil, err
+	}
+	return &ProgressStream{stream: s, conn: opened()}, nil
+}
+
+// CreateContentRequest is ContentCreationRequest.
+type CreateContentRequest struct {
+	JobID   string         `json:"job_id"`
+	Topic   string         `json:"topic"`
+	Format  string         `json:"format"`
+	Options map[string]any `json:"options,omitempty"`
+	OwnerID string         `json:"owner_id,omitempty"`
+	// RetryOf is the ID of the failed job this one retries, if it is a
+	// retry.
+	RetryOf string `json:"retry_of,omitempty"`
+}
+
+// CreateContentResponse is ContentCreationResponse.
+type CreateContentResponse struct {
+	ContentID string `json:"content_id"`
+	Status    string `json:"status"`
+}
+
+// CreateContent submits a content-generation job.
+func (c *Client) CreateContent(ctx context.Context, req *CreateContentRequest) (*CreateContentResponse, error) {
+	in, err := req.proto()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.pb.CreateContent(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return &CreateContentResponse{ContentID: resp.GetContentId(), Status: resp.GetStatus()}, nil
+}
+
+// ContentEstimate is what a CreateContentRequest is expected to cost.
+type ContentEstimate struct {
+	CostUSD         float64 `json:"estimated_cost_usd"`
+	Tokens          int64   `json:"estimated_tokens"`
+	DurationSeconds int32   `json:"estimated_duration_seconds"`
+}
+
+// EstimateContent prices req without creating a job.
+func (c *Client) EstimateContent(ctx context.Context, req *CreateContentRequest) (*ContentEstimate, error) {
+	in, err := req.proto()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.pb.EstimateContent(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return &ContentEstimate{
+		CostUSD:         resp.GetEstimatedCostUsd(),
+		Tokens:          resp.GetEstimatedTokens(),
+		DurationSeconds: resp.GetEstimatedDurationSeconds(),
+	}, nil
+}
+
+// JobStatusResponse is the current state of a job.
+type JobStatusResponse struct {
+	JobID           string    `json:"job_id"`
+	Status          string    `json:"status"`
+	ProgressPercent float64   `json:"progress_percent"`
+	ErrorMessage    string    `json:"error_message,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	OwnerID         string    `json:"owner_id,omitempty"`
+	ResultURL       string    `json:"result_url,omitempty"`
+	ThumbnailURL    string    `json:"thumbnail_url,omitempty"`
+	RetryOf         string    `json:"retry_of,omitempty"`
+	// Public is set once the job's owner has shared it.
+	Public bool `json:"public,omitempty"`
+}
+
+// GetJobStatus returns the current state of a job. It fails with NotFound
+// for unknown jobs.
+func (c *Client) GetJobStatus(ctx context.Context, jobID string) (*JobStatusResponse, error) {
+	resp, err := c.pb.GetJobStatus(ctx, &pb.JobStatusRequest{JobId: jobID})
+	if err != nil {
+		return nil, err
+	}
+	return jobStatusFromProto(resp), nil
+}
+
+// GetJobRequest returns the request a job was submitted with, as the
+// service recorded it. It fails with NotFound for unknown jobs.
+func (c *Client) GetJobRequest(ctx context.Context, jobID string) (*CreateContentRequest, error) {
+	resp, err := c.pb.GetJobRequest(ctx, &pb.JobStatusRequest{JobId: jobID})
+	if err != nil {
+		return nil, err
+	}
+	return createContentRequestFromProto(resp), nil
+}
+
+// CancelJobRequest asks for a job to be cancelled. If IfUpdatedAt is set
+// the job is only cancelled if it has not changed since; otherwise the call
+// fails with Aborted.
+type CancelJobRequest struct {
+	JobID       string     `json:"job_id"`
+	IfUpdatedAt *time.Time `json:"if_updated_at,omitempty"`
+}
+
+// CancelJob cancels a queued or processing job and returns its new state.
+// It fails with FailedPrecondition for a job that has already finished.
+func (c *Client) CancelJob(ctx context.Context, req *CancelJobRequest) (*JobStatusResponse, error) {
+	in := &pb.CancelJobRequest{JobId: req.JobID}
+	if req.IfUpdatedAt != nil {
+		in.IfUpdatedAt = timestamppb.New(*req.IfUpdatedAt)
+	}
+	resp, err := c.pb.CancelJob(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return jobStatusFromProto(resp), nil
+}
+
+// SetJobVisibilityRequest makes a job public, or private again.
+type SetJobVisibilityRequest struct {
+	JobID  string `json:"job_id"`
+	Public bool   `json:"public"`
+}
+
+// SetJobVisibility makes a job public or private and returns its new
+// state. It fails with NotFound for unknown jobs.
+func (c *Client) SetJobVisibility(ctx context.Context, req *SetJobVisibilityRequest) (*JobStatusResponse, error) {
+	resp, err := c.pb.SetJobVisibility(ctx, &pb.SetJobVisibilityRequest{JobId: req.JobID, Public: req.Public})
+	if err != nil {
+		return nil, err
+	}
+	return jobStatusFromProto(resp), nil
+}
+
+// Totals a ListJobsRequest may ask for.
+const (
+	TotalExact       = "exact"
+	TotalApproximate = "approximate"
+)
+
+// ListJobsRequest asks for one page of a principal's jobs, newest first.
+type ListJobsRequest struct {
+	OwnerID  string `json:"owner_id"`
+	PageSize int    `json:"page_size"`
+	// PageToken is the NextPageToken of the previous page, "" for the first.
+	// It marks the position after that page's last job, so jobs submitted
+	// or deleted between pages do not shift the pages after it.
+	PageToken string `json:"page_token,omitempty"`
+	// Total, TotalExact or TotalApproximate, asks for the owner's jobs to
+	// be counted; "" does not count them, which is cheaper.
+	Total string `json:"total,omitempty"`
+}
+
+// ListJobsResponse is one page of jobs. NextPageToken is "" on the last
+// page. TotalCount is set when the request asked for a total, and is an
+// estimate if TotalApproximate.
+type ListJobsResponse struct {
+	Jobs             []JobStatusResponse `json:"jobs"`
+	NextPageToken    string              `json:"next_page_token,omitempty"`
+	TotalCount       int64               `json:"total_count,omitempty"`
+	TotalApproximate bool                `json:"total_approximate,omitempty"`
+}
+
+// ListJobs returns one page of the jobs submitted by req.OwnerID.
+func (c *Client) ListJobs(ctx context.Context, req *ListJobsRequest) (*ListJobsResponse, error) {
+	resp, err := c.pb.ListJobs(ctx, &pb.ListJobsRequest{
+		OwnerId:   req.OwnerID,
+		PageSize:  int32(min(req.PageSize, math.MaxInt32)),
+		PageToken: req.PageToken,
+		Total:     req.Total,
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := &ListJobsResponse{
+		Jobs:             make([]JobStatusResponse, 0, len(resp.GetJobs())),
+		NextPageToken:    resp.GetNextPageToken(),
+		TotalCount:       resp.GetTotalCount(),
+		TotalApproximate: resp.GetTotalApproximate(),
+	}
+	for _, j := range resp.GetJobs() {
+		out.Jobs = append(out.Jobs, *jobStatusFromProto(j))
+	}
+	return out, nil
+}
+
+// ContentInfo describes a finished content item's file.
+type ContentInfo struct {
+	ContentID   string `json:"content_id"`
+	ContentType string `json:"content_type"`
+	Filename    string `json:"filename"`
+	// Size is the file length in bytes, 0 if unknown.
+	Size    int64  `json:"size"`
+	OwnerID string `json:"owner_id,omitempty"`
+	// ETag changes whenever the file's bytes do.
+	ETag string `json:"etag,omitempty"`
+}
+
+// GetContentInfo returns the metadata of a content item's file. It fails
+// with NotFound for unknown content and FailedPrecondition while the job
+// is unfinished.
+func (c *Client) GetContentInfo(ctx context.Context, contentID string) (*ContentInfo, error) {
+	resp, err := c.pb.GetContentInfo(ctx, &pb.ContentInfoRequest{ContentId: contentID})
+	if err != nil {
+		return nil, err
+	}
+	return &ContentInfo{
+		ContentID:   resp.GetContentId(),
+		ContentType: resp.GetContentType(),
+		Filename:    resp.GetFilename(),
+		Size:        resp.GetSize(),
+		OwnerID:     resp.GetOwnerId(),
+		ETag:        resp.GetEtag(),
+	}, nil
+}
+
+// DownloadRequest selects the bytes of a content file to stream. A Length
+// of 0 reads to the end.
+type DownloadRequest struct {
+	ContentID string `json:"content_id"`
+	Offset    int64  `json:"offset,omitempty"`
+	Length    int64  `json:"length,omitempty"`
+}
+
+// ContentStream yields consecutive pieces of a content file until io.EOF.
+type ContentStream struct {
+	stream grpc.ServerStreamingClient[pb.ContentChunk]
+	conn   rpc.ClientStream
+}
+
+// Recv returns the next piece of the file.
+func (s *ContentStream) Recv() ([]byte, error) {
+	c, err := s.stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	return c.GetData(), nil
+}
+
+// Close abandons the stream.
+func (s *ContentStream) Close() error { return s.conn.Close() }
+
+// DownloadContent streams length bytes of a content file starting at
+// offset. Cancelling ctx stops the transfer upstream.
+func (c *Client) DownloadContent(ctx context.Context, contentID string, offset, length int64) (*ContentStream, error) {
+	client, opened := c.streams()
+	s, err := client.DownloadContent(ctx, &pb.DownloadRequest{ContentId: contentID, Offset: offset, Length: length})
+	if err != nil {
+		return nil, err
+	}
+	return &ContentStream{stream: s, conn: opened()}, nil
+}
+
+// GenerationUsage is what a streamed generation consumed.
+type GenerationUsage struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+}
+
+// GenerationChunk is one message of the GenerateStream stream: a piece of
+// the generated text, or, as the last message, the usage and why the
+// generation stopped.
+type GenerationChunk struct {
+	Text         string           `json:"text,omitempty"`
+	Usage        *GenerationUsage `json:"usage,omitempty"`
+	FinishReason string           `json:"finish_reason,omitempty"`
+}
+
+// TokenStream yields GenerationChunks until io.EOF.
+type TokenStream struct {
+	stream grpc.ServerStreamingClient[pb.GenerationChunk]
+	conn   rpc.ClientStream
+}
+
+// Recv returns the next chunk.
+func (s *TokenStream) Recv() (*GenerationChunk, error) {
+	c, err := s.stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	out := &GenerationChunk{Text: c.GetText(), FinishReason: c.GetFinishReason()}
+	if u := c.GetUsage(); u != nil {
+		out.Usage = &GenerationUsage{
+			PromptTokens:     u.GetPromptTokens(),
+			CompletionTokens: u.GetCompletionTokens(),
+			TotalTokens:      u.GetTotalTokens(),
+		}
+	}
+	return out, nil
+}
+
+// Close abandons the stream.
+func (s *TokenStream) Close() error { return s.conn.Close() }
+
+// GenerateStream generates the text req describes, streaming it as it is
+// produced, without creating a job. Cancelling ctx stops the generation
+// upstream.
+func (c *Client) GenerateStream(ctx context.Context, req *CreateContentRequest) (*TokenStream, error) {
+	in, err := req.proto()
+	if err != nil {
+		return nil, err
+	}
+	client, opened := c.streams()
+	s, err := client.GenerateStream(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenStream{stream: s, conn: opened()}, nil
+}
+
+// UploadChunkSize is the most file data sent per UploadAssetChunk call.
+const UploadChunkSize = 64 << 10
+
+// UploadAssetRequest describes a file to attach to a job.
+type UploadAssetRequest struct {
+	JobID       string `json:"job_id"`
+	OwnerID     string `json:"owner_id,omitempty"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+}
+
+// Asset is a file attached to a job.
+type Asset struct {
+	AssetID     string `json:"asset_id"`
+	JobID       string `json:"job_id"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+}
+
+// UploadAsset stores the contents of body as an asset of req.JobID. It
+// sends body in UploadChunkSize pieces as it reads them, so the file is
+// never held in memory whole. An error reading body abandons the upload
+// and is returned as is; the service discards uploads left unfinished.
+func (c *Client) UploadAsset(ctx context.Context, req *UploadAssetRequest, body io.Reader) (*Asset, error) {
+	started, err := c.pb.StartAssetUpload(ctx, &pb.StartAssetUploadRequest{
+		JobId:       req.JobID,
+		OwnerId:     req.OwnerID,
+		Filename:    req.Filename,
+		ContentType: req.ContentType,
+	})
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, UploadChunkSize)
+	var offset int64
+	for {
+		n, err := io.ReadFull(body, buf)
+		if n > 0 {
+			chunk := &pb.AssetChunk{UploadId: started.GetUploadId(), Offset: offset, Data: buf[:n]}
+			if _, err := c.pb.UploadAssetChunk(ctx, chunk); err != nil {
+				return nil, err
+			}
+			offset += int64(n)
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	asset, err := c.pb.FinishAssetUpload(ctx, &pb.FinishAssetUploadRequest{UploadId: started.GetUploadId(), Size: offset})
+	if err != nil {
+		return nil, err
+	}
+	return &Asset{
+		AssetID:     asset.GetAssetId(),
+		JobID:       asset.GetJobId(),
+		Filename:    asset.GetFilename(),
+		ContentType: asset.GetContentType(),
+		Size:        asset.GetSize(),
+	}, nil
+}
@@ -0,0 +1,138 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/content-factory/go-gateway/internal/rpc"
+	pb "github.com/content-factory/go-gateway/protos/content_factory"
+)
+
+var created = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// orchestrator serves a few ContentOrchestrator methods the way the
+// Python service does.
+type orchestrator struct {
+	pb.UnimplementedContentOrchestratorServer
+	got *pb.ContentCreationRequest
+}
+
+func (o *orchestrator) CreateContent(ctx context.Context, req *pb.ContentCreationRequest) (*pb.ContentCreationResponse, error) {
+	o.got = req
+	return &pb.ContentCreationResponse{ContentId: req.GetJobId(), Status: "queued"}, nil
+}
+
+func (o *orchestrator) GetJobStatus(ctx context.Context, req *pb.JobStatusRequest) (*pb.JobStatusResponse, error) {
+	if req.GetJobId() != "j1" {
+		return nil, status.Errorf(codes.NotFound, "no job %s", req.GetJobId())
+	}
+	return &pb.JobStatusResponse{JobId: "j1", Status: "processing", ProgressPercent: 33.3, CreatedAt: timestamppb.New(created)}, nil
+}
+
+func (o *orchestrator) CancelJob(ctx context.Context, req *pb.CancelJobRequest) (*pb.JobStatusResponse, error) {
+	if !req.GetIfUpdatedAt().AsTime().Equal(created) {
+		return nil, status.Error(codes.Aborted, "the job has changed")
+	}
+	return &pb.JobStatusResponse{JobId: req.GetJobId(), Status: "cancelled"}, nil
+}
+
+func (o *orchestrator) StreamProgress(req *pb.JobStatusRequest, stream grpc.ServerStreamingServer[pb.ProgressUpdate]) error {
+	for seq := req.GetAfterSequence() + 1; seq <= 3; seq++ {
+		if err := stream.Send(&pb.ProgressUpdate{ContentId: req.GetJobId(), Stage: "drafting", Sequence: seq}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func serve(t *testing.T) (*Client, *orchestrator) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := &orchestrator{}
+	srv := grpc.NewServer()
+	pb.RegisterContentOrchestratorServer(srv, o)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	conn, err := rpc.Dial(lis.Addr().String(), rpc.DialOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return New(conn), o
+}
+
+func TestClientUnaryCalls(t *testing.T) {
+	c, o := serve(t)
+	ctx := context.Background()
+
+	resp, err := c.CreateContent(ctx, &CreateContentRequest{
+		JobID: "j1", Topic: "otters", Format: "video", OwnerID: "user-1",
+		Options: map[string]any{"duration": 30.0, "tags": []any{"cute"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.ContentID != "j1" || resp.Status != "queued" {
+		t.Errorf("response = %+v", resp)
+	}
+	if opts := o.got.GetOptions().AsMap(); o.got.GetTopic() != "otters" || opts["duration"] != 30.0 || len(opts["tags"].([]any)) != 1 {
+		t.Errorf("service got %v", o.got)
+	}
+	if _, err := c.CreateContent(ctx, &CreateContentRequest{Options: map[string]any{"bad": struct{}{}}}); rpc.CodeOf(err) != rpc.InvalidArgument {
+		t.Errorf("unencodable options: %v, want invalid argument", err)
+	}
+
+	job, err := c.GetJobStatus(ctx, "j1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.ProgressPercent != 33.3 || !job.CreatedAt.Equal(created) || !job.UpdatedAt.IsZero() {
+		t.Errorf("job = %+v", job)
+	}
+	if _, err := c.GetJobStatus(ctx, "j2"); rpc.CodeOf(err) != rpc.NotFound {
+		t.Errorf("unknown job: %v, want not found", err)
+	}
+
+	if _, err := c.CancelJob(ctx, &CancelJobRequest{JobID: "j1", IfUpdatedAt: &created}); err != nil {
+		t.Errorf("cancel: %v", err)
+	}
+	stale := created.Add(-time.Second)
+	if _, err := c.CancelJob(ctx, &CancelJobRequest{JobID: "j1", IfUpdatedAt: &stale}); rpc.CodeOf(err) != rpc.Aborted {
+		t.Errorf("stale cancel: %v, want aborted", err)
+	}
+}
+
+func TestClientStreamProgress(t *testing.T) {
+	c, _ := serve(t)
+	s, err := c.StreamProgress(context.Background(), "j1", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	var seqs []int64
+	for {
+		u, err := s.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		seqs = append(seqs, u.Sequence)
+	}
+	if len(seqs) != 2 || seqs[0] != 2 || seqs[1] != 3 {
+		t.Errorf("sequences = %v, want [2 3]", seqs)
+	}
+}
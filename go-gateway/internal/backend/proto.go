@@ -0,0 +1,89 @@
+package backend
+
+import (
+	"strconv"
+	"time"
+
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/content-factory/go-gateway/internal/rpc"
+	pb "github.com/content-factory/go-gateway/protos/content_factory"
+)
+
+// proto returns r as the service's ContentCreationRequest. It fails with
+// InvalidArgument for options that are not plain JSON values.
+func (r *CreateContentRequest) proto() (*pb.ContentCreationRequest, error) {
+	out := &pb.ContentCreationRequest{
+		JobId:   r.JobID,
+		Topic:   r.Topic,
+		Format:  r.Format,
+		OwnerId: r.OwnerID,
+		RetryOf: r.RetryOf,
+	}
+	if len(r.Options) > 0 {
+		options, err := structpb.NewStruct(r.Options)
+		if err != nil {
+			return nil, rpc.Errorf(rpc.InvalidArgument, "options: %v", err)
+		}
+		out.Options = options
+	}
+	return out, nil
+}
+
+func createContentRequestFromProto(r *pb.ContentCreationRequest) *CreateContentRequest {
+	out := &CreateContentRequest{
+		JobID:   r.GetJobId(),
+		Topic:   r.GetTopic(),
+		Format:  r.GetFormat(),
+		OwnerID: r.GetOwnerId(),
+		RetryOf: r.GetRetryOf(),
+	}
+	if opts := r.GetOptions(); len(opts.GetFields()) > 0 {
+		out.Options = opts.AsMap()
+	}
+	return out
+}
+
+func jobStatusFromProto(r *pb.JobStatusResponse) *JobStatusResponse {
+	return &JobStatusResponse{
+		JobID:           r.GetJobId(),
+		Status:          r.GetStatus(),
+		ProgressPercent: percent(r.GetProgressPercent()),
+		ErrorMessage:    r.GetErrorMessage(),
+		CreatedAt:       timeOf(r.GetCreatedAt()),
+		UpdatedAt:       timeOf(r.GetUpdatedAt()),
+		OwnerID:         r.GetOwnerId(),
+		ResultURL:       r.GetResultUrl(),
+		ThumbnailURL:    r.GetThumbnailUrl(),
+		RetryOf:         r.GetRetryOf(),
+		Public:          r.GetPublic(),
+	}
+}
+
+func progressUpdateFromProto(u *pb.ProgressUpdate) *ProgressUpdate {
+	return &ProgressUpdate{
+		ContentID:       u.GetContentId(),
+		Stage:           u.GetStage(),
+		ProgressPercent: percent(u.GetProgressPercent()),
+		Message:         u.GetMessage(),
+		Timestamp:       timeOf(u.GetTimestamp()),
+		ResultURL:       u.GetResultUrl(),
+		Sequence:        u.GetSequence(),
+	}
+}
+
+// percent widens a float percentage to the float64 nearest its shortest
+// decimal form, so that 33.3 is not shown as 33.29999923706055.
+func percent(f float32) float64 {
+	out, _ := strconv.ParseFloat(strconv.FormatFloat(float64(f), 'g', -1, 32), 64)
+	return out
+}
+
+// timeOf returns ts as a time, the zero time if it is unset.
+func timeOf(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
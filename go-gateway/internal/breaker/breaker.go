@@ -0,0 +1,228 @@
+// Package breaker implements a circuit breaker for backend calls, so a slow
+// or failing content service is shed quickly instead of piling up requests.
+package breaker
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/metrics"
+	"github.com/content-factory/go-gateway/internal/rpc"
+)
+
+// Defaults used by the config package.
+const (
+	DefaultFailureThreshold = 5
+	DefaultCooldown         = 30 * time.Second
+)
+
+// Config tunes a Breaker.
+type Config struct {
+	// FailureThreshold is the number of consecutive failures that opens
+	// the breaker.
+	FailureThreshold int
+	// Cooldown is how long the breaker stays open before letting a single
+	// probe call through.
+	Cooldown time.Duration
+}
+
+// State is the breaker position.
+type State int
+
+// Breaker states. The values are exported as the state gauge.
+const (
+	Closed State = iota
+	Open
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half_open"
+	}
+	return "closed"
+}
+
+// ErrOpen is returned without calling the backend while the breaker is
+// open. Its Unavailable code makes handlers answer 503.
+var ErrOpen = rpc.Errorf(rpc.Unavailable, "circuit breaker is open")
+
+var (
+	stateGauge = metrics.NewGaugeVec("gateway_circuit_breaker_state",
+		"Circuit breaker state: 0 closed, 1 open, 2 half-open.",
+		"name")
+	transitions = metrics.NewCounterVec("gateway_circuit_breaker_transitions_total",
+		"Circuit breaker state changes, by the state entered.",
+		"name", "state")
+	rejected = metrics.NewCounterVec("gateway_circuit_breaker_rejected_total",
+		"Calls rejected without reaching the backend because the breaker was open.",
+		"name")
+)
+
+// IsFailure reports whether err counts against the backend. Only errors
+// that signal an unhealthy service do; a rejected argument or a missing
+// job says nothing about the backend's health.
+func IsFailure(err error) bool {
+	switch rpc.CodeOf(err) {
+	case rpc.Unavailable, rpc.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+// Breaker is a consecutive-failure circuit breaker. In the half-open state
+// exactly one probe call is admitted; its outcome closes or re-opens the
+// breaker.
+type Breaker struct {
+	name string
+	cfg  Config
+	now  func() time.Time
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// New returns a closed breaker. name labels its metrics and logs.
+func New(name string, cfg Config) *Breaker {
+	if cfg.FailureThreshold < 1 {
+		cfg.FailureThreshold = DefaultFailureThreshold
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = DefaultCooldown
+	}
+	b := &Breaker{name: name, cfg: cfg, now: time.Now}
+	stateGauge.With(name).Set(float64(Closed))
+	return b
+}
+
+// State returns the current state, reporting an open breaker whose
+// cooldown has elapsed as half-open.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == Open && b.now().Sub(b.openedAt) >= b.cfg.Cooldown {
+		return HalfOpen
+	}
+	return b.state
+}
+
+// Allow reserves a call. It returns ErrOpen when the call must be
+// rejected; otherwise the caller must pass the call's result to done.
+func (b *Breaker) Allow() (done func(error), err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case Open:
+		if b.now().Sub(b.openedAt) < b.cfg.Cooldown {
+			rejected.With(b.name).Inc()
+			return nil, ErrOpen
+		}
+		b.setState(HalfOpen)
+		fallthrough
+	case HalfOpen:
+		if b.probing {
+			rejected.With(b.name).Inc()
+			return nil, ErrOpen
+		}
+		b.probing = true
+		return b.recordProbe, nil
+	}
+	return b.record, nil
+}
+
+func (b *Breaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state != Closed {
+		// A call admitted before the breaker tripped; the probe decides.
+		return
+	}
+	if !IsFailure(err) {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.cfg.FailureThreshold {
+		b.trip(err)
+	}
+}
+
+func (b *Breaker) recordProbe(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if IsFailure(err) {
+		b.trip(err)
+		return
+	}
+	b.failures = 0
+	b.setState(Closed)
+}
+
+// trip opens the breaker. Callers hold b.mu.
+func (b *Breaker) trip(err error) {
+	b.openedAt = b.now()
+	b.failures = 0
+	b.setState(Open)
+	slog.Warn("circuit breaker opened", "breaker", b.name, "cooldown", b.cfg.Cooldown.String(), "error", err)
+}
+
+// setState records a transition. Callers hold b.mu.
+func (b *Breaker) setState(s State) {
+	if b.state == s {
+		return
+	}
+	prev := b.state
+	b.state = s
+	stateGauge.With(b.name).Set(float64(s))
+	transitions.With(b.name, s.String()).Inc()
+	if s != Open {
+		slog.Info("circuit breaker state changed", "breaker", b.name, "from", prev.String(), "to", s.String())
+	}
+}
+
+// Conn is the backend transport; it matches backend.Conn.
+type Conn interface {
+	Invoke(ctx context.Context, method string, req, resp any) error
+	NewStream(ctx context.Context, method string, req any) (rpc.ClientStream, error)
+}
+
+// Wrap guards every call on conn with b. For streams only opening the
+// stream is counted; a long-lived stream's later errors are not.
+func Wrap(conn Conn, b *Breaker) Conn {
+	return &guarded{conn: conn, b: b}
+}
+
+type guarded struct {
+	conn Conn
+	b    *Breaker
+}
+
+func (g *guarded) Invoke(ctx context.Context, method string, req, resp any) error {
+	done, err := g.b.Allow()
+	if err != nil {
+		return err
+	}
+	err = g.conn.Invoke(ctx, method, req, resp)
+	done(err)
+	return err
+}
+
+func (g *guarded) NewStream(ctx context.Context, method string, req any) (rpc.ClientStream, error) {
+	done, err := g.b.Allow()
+	if err != nil {
+		return nil, err
+	}
+	s, err := g.conn.NewStream(ctx, method, req)
+	done(err)
+	return s, err
+}
@@ -0,0 +1,126 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/rpc"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func newTestBreaker(threshold int, cooldown time.Duration) (*Breaker, *fakeClock) {
+	clock := &fakeClock{t: time.Unix(1_700_000_000, 0)}
+	b := New("test", Config{FailureThreshold: threshold, Cooldown: cooldown})
+	b.now = clock.now
+	return b, clock
+}
+
+func call(t *testing.T, b *Breaker, result error) error {
+	t.Helper()
+	done, err := b.Allow()
+	if err != nil {
+		return err
+	}
+	done(result)
+	return nil
+}
+
+func TestOpensAfterConsecutiveFailures(t *testing.T) {
+	b, _ := newTestBreaker(3, time.Minute)
+	unavailable := rpc.Errorf(rpc.Unavailable, "down")
+
+	call(t, b, unavailable)
+	call(t, b, unavailable)
+	call(t, b, nil) // a success resets the streak
+	call(t, b, unavailable)
+	call(t, b, unavailable)
+	if b.State() != Closed {
+		t.Fatalf("state = %v after 2 consecutive failures, want closed", b.State())
+	}
+	call(t, b, rpc.Errorf(rpc.DeadlineExceeded, "slow"))
+	if b.State() != Open {
+		t.Fatalf("state = %v, want open", b.State())
+	}
+	if err := call(t, b, nil); !errors.Is(err, ErrOpen) {
+		t.Errorf("Allow while open = %v, want ErrOpen", err)
+	}
+	if rpc.CodeOf(ErrOpen) != rpc.Unavailable {
+		t.Errorf("ErrOpen code = %v, want unavailable", rpc.CodeOf(ErrOpen))
+	}
+}
+
+func TestClientErrorsAreNotFailures(t *testing.T) {
+	b, _ := newTestBreaker(2, time.Minute)
+	for i := 0; i < 5; i++ {
+		call(t, b, rpc.Errorf(rpc.InvalidArgument, "bad"))
+		call(t, b, rpc.Errorf(rpc.NotFound, "missing"))
+	}
+	if b.State() != Closed {
+		t.Errorf("state = %v, want closed", b.State())
+	}
+}
+
+func TestHalfOpenProbe(t *testing.T) {
+	b, clock := newTestBreaker(1, time.Minute)
+	call(t, b, rpc.Errorf(rpc.Unavailable, "down"))
+
+	clock.t = clock.t.Add(time.Minute)
+	if b.State() != HalfOpen {
+		t.Fatalf("state = %v after cooldown, want half-open", b.State())
+	}
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatalf("probe rejected: %v", err)
+	}
+	if _, err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Errorf("second call during probe = %v, want ErrOpen", err)
+	}
+	done(rpc.Errorf(rpc.Unavailable, "still down"))
+	if b.State() != Open {
+		t.Fatalf("state = %v after failed probe, want open", b.State())
+	}
+
+	clock.t = clock.t.Add(time.Minute)
+	if err := call(t, b, nil); err != nil {
+		t.Fatal(err)
+	}
+	if b.State() != Closed {
+		t.Errorf("state = %v after successful probe, want closed", b.State())
+	}
+}
+
+type fakeConn struct {
+	calls int
+	err   error
+}
+
+func (c *fakeConn) Invoke(ctx context.Context, method string, req, resp any) error {
+	c.calls++
+	return c.err
+}
+
+func (c *fakeConn) NewStream(ctx context.Context, method string, req any) (rpc.ClientStream, error) {
+	c.calls++
+	return nil, c.err
+}
+
+func TestWrapShortCircuits(t *testing.T) {
+	b, _ := newTestBreaker(2, time.Minute)
+	conn := &fakeConn{err: rpc.Errorf(rpc.Unavailable, "down")}
+	g := Wrap(conn, b)
+
+	for i := 0; i < 5; i++ {
+		g.Invoke(context.Background(), "/svc/M", nil, nil)
+	}
+	if _, err := g.NewStream(context.Background(), "/svc/S", nil); !errors.Is(err, ErrOpen) {
+		t.Errorf("NewStream = %v, want ErrOpen", err)
+	}
+	if conn.calls != 2 {
+		t.Errorf("backend reached %d times, want 2", conn.calls)
+	}
+}
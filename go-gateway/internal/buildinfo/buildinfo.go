@@ -0,0 +1,54 @@
+// Package buildinfo exposes build metadata injected at link time, e.g.
+//
+//	go build -ldflags "-X github.com/content-factory/go-gateway/internal/buildinfo.Version=1.2.0 \
+//	  -X github.com/content-factory/go-gateway/internal/buildinfo.Commit=$(git rev-parse --short HEAD) \
+//	  -X github.com/content-factory/go-gateway/internal/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+package buildinfo
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/content-factory/go-gateway/internal/respond"
+)
+
+// Set via -ldflags; the defaults identify a local development build.
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildTime = "unknown"
+)
+
+// Info is the body of GET /version.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the running binary's build metadata. When Commit or
+// BuildTime were not stamped, the VCS details the go command records for
+// builds inside a repository are used instead, if present.
+func Get() Info {
+	info := Info{Version: Version, Commit: Commit, BuildTime: BuildTime, GoVersion: runtime.Version()}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch {
+			case s.Key == "vcs.revision" && info.Commit == "unknown":
+				info.Commit = s.Value
+			case s.Key == "vcs.time" && info.BuildTime == "unknown":
+				info.BuildTime = s.Value
+			}
+		}
+	}
+	return info
+}
+
+// Handler serves Get as JSON.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		respond.Write(w, r, http.StatusOK, Get())
+	})
+}
@@ -0,0 +1,35 @@
+package buildinfo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	defer func(v, c, b string) { Version, Commit, BuildTime = v, c, b }(Version, Commit, BuildTime)
+	Version, Commit, BuildTime = "1.2.0", "abc1234", "2026-01-01T12:00:00Z"
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/version", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("status = %d, Content-Type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var got Info
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := Info{Version: "1.2.0", Commit: "abc1234", BuildTime: "2026-01-01T12:00:00Z", GoVersion: runtime.Version()}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	info := Get()
+	if info.Version != "dev" || info.Commit == "" || info.BuildTime == "" || info.GoVersion == "" {
+		t.Errorf("info = %+v", info)
+	}
+}
@@ -0,0 +1,243 @@
+package cache
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/metrics"
+	"github.com/content-factory/go-gateway/internal/respond"
+	"github.com/content-factory/go-gateway/internal/routeconf"
+	"github.com/content-factory/go-gateway/internal/router"
+)
+
+// StatusHeader reports HIT or MISS on cached routes, and STALE for a
+// stale response served because the backend failed.
+const StatusHeader = "X-Cache"
+
+// Values of StatusHeader.
+const (
+	statusHit   = "HIT"
+	statusMiss  = "MISS"
+	statusStale = "STALE"
+)
+
+// maxBody bounds the responses kept; larger ones pass through uncached.
+const maxBody = 1 << 20
+
+// storedHeaders are the response headers kept with a response. Headers
+// set outside the handler, like X-Request-ID, belong to each request.
+var storedHeaders = []string{"Content-Type", "Cache-Control", "ETag", "Last-Modified", "Link", "Vary"}
+
+// keyedVary are the request headers a response may vary on and still be
+// cached. Key covers Accept with the negotiated media type, and
+// Authorization, X-API-Key, X-Key-Id and X-Tenant-ID with the principal
+// and tenant they resolve to; the middleware in front of the cache
+// handles Origin and Accept-Encoding afresh for each response it
+// replays. A response varying on anything else, or on *, is not cached,
+// since one entry would serve every value.
+var keyedVary = map[string]bool{
+	"Accept":          true,
+	"Authorization":   true,
+	"X-Api-Key":       true,
+	"X-Key-Id":        true,
+	"X-Tenant-Id":     true,
+	"Origin":          true,
+	"Accept-Encoding": true,
+}
+
+var lookups = metrics.NewCounterVec("gateway_cache_requests_total",
+	"Lookups in the response cache by result: hit, miss or bypass.",
+	"route", "result")
+
+// Middleware answers GET requests from store, caching 200 responses of
+// next for ttl. Entries are keyed by the caller's principal, path, query
+// and negotiated media type, so one user never sees another's response,
+// and filed under the path for Invalidate. A request sending
+// Cache-Control: no-cache skips the lookup and refreshes the entry;
+// no-store leaves the cache untouched. A hit whose ETag matches
+// If-None-Match gets 304. Responses varying on a request header the key
+// does not cover, see keyedVary, are never stored.
+//
+// On routes with a StaleIfError override in routes, entries are kept that
+// much longer than ttl. A request finding one past its ttl waits for
+// next's response, and if it is a 5xx error, as when the backend is down
+// or its circuit breaker open, gets the stale entry instead; see
+// serveStale.
+//
+// It must run after authentication, which supplies the principal, and
+// inside the router, which names the route for metrics and overrides.
+func Middleware(store Store, ttl time.Duration, routes routeconf.Table) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ctx := r.Context()
+			route := router.Pattern(r)
+			directives := r.Header.Get("Cache-Control")
+			if hasDirective(directives, "no-store") {
+				lookups.With(route, "bypass").Inc()
+				next.ServeHTTP(w, r)
+				return
+			}
+			key := Key(r)
+			o, _ := routes.Lookup(r)
+			var stale *Response
+			if hasDirective(directives, "no-cache") {
+				lookups.With(route, "bypass").Inc()
+			} else {
+				resp, ok, err := store.Get(ctx, key)
+				if err != nil {
+					gateway.Logger(ctx).WarnContext(ctx, "response cache unavailable", "error", err)
+				}
+				if ok && (resp.Stored.IsZero() || time.Since(resp.Stored) < ttl) {
+					lookups.With(route, "hit").Inc()
+					replay(w, r, resp, statusHit)
+					return
+				}
+				if ok {
+					stale = resp
+				}
+				lookups.With(route, "miss").Inc()
+			}
+
+			w.Header().Set(StatusHeader, statusMiss)
+			rec := &recorder{ResponseWriter: w, status: http.StatusOK, cacheable: true}
+			if stale != nil {
+				held := holdResponse(w.Header())
+				next.ServeHTTP(held, r)
+				if held.status >= 500 {
+					serveStale(w, r, stale, held.status)
+					return
+				}
+				held.writeTo(rec)
+			} else {
+				next.ServeHTTP(rec, r)
+			}
+			if !rec.cacheable || rec.status != http.StatusOK || hasDirective(w.Header().Get("Cache-Control"), "no-store") || !keyed(w.Header()) {
+				return
+			}
+			resp := &Response{Status: rec.status, Header: make(http.Header), Body: rec.body.Bytes(), Stored: time.Now()}
+			for _, name := range storedHeaders {
+				if v := w.Header().Values(name); len(v) > 0 {
+					resp.Header[http.CanonicalHeaderKey(name)] = v
+				}
+			}
+			if err := store.Set(ctx, r.URL.Path, key, resp, ttl+max(0, o.StaleIfError)); err != nil {
+				gateway.Logger(ctx).WarnContext(ctx, "failed to cache response", "error", err)
+			}
+		})
+	}
+}
+
+// Key is the cache key of r: tenant, principal, path and query with its
+// parameters sorted, and the media type Accept negotiates.
+func Key(r *http.Request) string {
+	var principal string
+	if claims := gateway.ClaimsFromContext(r.Context()); claims != nil {
+		principal = claims.Subject
+	}
+	media, _ := respond.Negotiate(r)
+	return gateway.TenantID(r.Context()) + "\x00" + principal + "\x00" + r.URL.Path + "?" + r.URL.Query().Encode() + "\x00" + media
+}
+
+// ETagMatches reports whether an If-None-Match or If-Match header value
+// lists etag. "*" matches anything; weak tags compare equal to their
+// strong form, as If-None-Match requires.
+func ETagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// keyed reports whether every header h varies on is covered by Key.
+func keyed(h http.Header) bool {
+	for _, name := range respond.Varies(h) {
+		if !keyedVary[name] {
+			return false
+		}
+	}
+	return true
+}
+
+func hasDirective(header, directive string) bool {
+	for _, d := range strings.Split(header, ",") {
+		if strings.EqualFold(strings.TrimSpace(d), directive) {
+			return true
+		}
+	}
+	return false
+}
+
+// replay answers r with resp, reporting status in StatusHeader.
+func replay(w http.ResponseWriter, r *http.Request, resp *Response, status string) {
+	h := w.Header()
+	for name, v := range resp.Header {
+		if name == "Vary" {
+			// Keep what the middleware in front already declared.
+			respond.Vary(w, respond.Varies(resp.Header)...)
+			continue
+		}
+		h[name] = v
+	}
+	h.Set(StatusHeader, status)
+	if status == statusStale {
+		h.Set("Warning", staleWarning)
+		h.Set("Cache-Control", "private, max-age="+strconv.Itoa(int(staleMaxAge/time.Second)))
+		h.Set("Age", strconv.Itoa(int(time.Since(resp.Stored)/time.Second)))
+	}
+	if tag := resp.Header.Get("ETag"); tag != "" {
+		if inm := r.Header.Get("If-None-Match"); inm != "" && ETagMatches(inm, tag) {
+			h.Del("Content-Type")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	w.WriteHeader(resp.Status)
+	w.Write(resp.Body)
+}
+
+// recorder copies the response it passes through, giving up on bodies
+// that are too large or streamed.
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+	cacheable   bool
+}
+
+func (w *recorder) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recorder) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	if w.cacheable && w.body.Len()+len(b) <= maxBody {
+		w.body.Write(b)
+	} else {
+		w.cacheable = false
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recorder) Flush() {
+	w.cacheable = false
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *recorder) Unwrap() http.ResponseWriter { return w.ResponseWriter }
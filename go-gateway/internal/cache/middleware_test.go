@@ -0,0 +1,240 @@
+package cache
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/auth"
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/respond"
+	"github.com/content-factory/go-gateway/internal/routeconf"
+	"github.com/content-factory/go-gateway/internal/router"
+)
+
+// counting serves a body naming how many times it has run, so a cached
+// response is told apart from a fresh one.
+type counting struct {
+	calls  int
+	status int
+	vary   []string
+}
+
+func (h *counting) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.calls++
+	w.Header().Set("Content-Type", "text/plain")
+	w.Header().Set("ETag", `"v`+strconv.Itoa(h.calls)+`"`)
+	w.Header().Set("X-Request-ID", "req-"+strconv.Itoa(h.calls))
+	respond.Vary(w, h.vary...)
+	if h.status != 0 {
+		w.WriteHeader(h.status)
+	}
+	w.Write([]byte("call " + strconv.Itoa(h.calls)))
+}
+
+func setup(store Store) (*counting, http.Handler) {
+	h := &counting{}
+	rt := router.New()
+	rt.Handle(http.MethodGet, "/items/{id}", Middleware(store, time.Hour, nil)(h))
+	return h, rt
+}
+
+func get(h http.Handler, path, subject string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	if subject != "" {
+		req = req.WithContext(gateway.WithClaims(req.Context(), &gateway.Claims{Subject: subject}))
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHitReplaysResponse(t *testing.T) {
+	counter, h := setup(NewLRU(10))
+
+	first := get(h, "/items/1?b=2&a=1", "alice")
+	if first.Header().Get(StatusHeader) != "MISS" || first.Body.String() != "call 1" {
+		t.Fatalf("first: %s %q", first.Header().Get(StatusHeader), first.Body)
+	}
+	second := get(h, "/items/1?a=1&b=2", "alice")
+	if second.Header().Get(StatusHeader) != "HIT" || second.Body.String() != "call 1" || second.Code != http.StatusOK {
+		t.Fatalf("second: %d %s %q", second.Code, second.Header().Get(StatusHeader), second.Body)
+	}
+	if second.Header().Get("Content-Type") != "text/plain" || second.Header().Get("ETag") != `"v1"` {
+		t.Errorf("replayed headers = %v", second.Header())
+	}
+	if second.Header().Get("X-Request-ID") != "" {
+		t.Error("per-request header replayed")
+	}
+	if counter.calls != 1 {
+		t.Errorf("handler ran %d times", counter.calls)
+	}
+}
+
+func TestKeyIncludesPrincipalAndQuery(t *testing.T) {
+	counter, h := setup(NewLRU(10))
+	get(h, "/items/1", "alice")
+	get(h, "/items/1", "bob")
+	get(h, "/items/1?page=2", "alice")
+	if counter.calls != 3 {
+		t.Errorf("handler ran %d times, want 3 distinct entries", counter.calls)
+	}
+}
+
+func TestNoCacheBypassesAndRefreshes(t *testing.T) {
+	counter, h := setup(NewLRU(10))
+	get(h, "/items/1", "alice")
+
+	rec := get(h, "/items/1", "alice", "Cache-Control", "no-cache")
+	if rec.Body.String() != "call 2" {
+		t.Fatalf("no-cache served %q", rec.Body)
+	}
+	if rec := get(h, "/items/1", "alice"); rec.Body.String() != "call 2" {
+		t.Errorf("entry not refreshed: %q", rec.Body)
+	}
+
+	get(h, "/items/1", "alice", "Cache-Control", "no-store")
+	if rec := get(h, "/items/1", "alice"); rec.Body.String() != "call 2" || counter.calls != 3 {
+		t.Errorf("no-store replaced the entry: %q after %d calls", rec.Body, counter.calls)
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	store := NewLRU(10)
+	_, h := setup(store)
+	get(h, "/items/1", "alice")
+	store.Invalidate(context.Background(), "/items/1")
+	if rec := get(h, "/items/1", "alice"); rec.Body.String() != "call 2" {
+		t.Errorf("served %q after invalidation", rec.Body)
+	}
+}
+
+func TestErrorsAreNotCached(t *testing.T) {
+	counter, h := setup(NewLRU(10))
+	counter.status = http.StatusBadGateway
+	get(h, "/items/1", "alice")
+	get(h, "/items/1", "alice")
+	if counter.calls != 2 {
+		t.Errorf("handler ran %d times, want error not cached", counter.calls)
+	}
+}
+
+func TestConditionalHit(t *testing.T) {
+	_, h := setup(NewLRU(10))
+	get(h, "/items/1", "alice")
+	rec := get(h, "/items/1", "alice", "If-None-Match", `"v1"`)
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("status = %d, body %q", rec.Code, rec.Body)
+	}
+}
+
+func TestVary(t *testing.T) {
+	tests := []struct {
+		vary   []string
+		cached bool
+	}{
+		{[]string{"Accept", "Authorization", "X-API-Key", "X-Key-Id"}, true},
+		{[]string{"Accept-Encoding", "Origin"}, true},
+		{[]string{"Accept-Language"}, false},
+		{[]string{"Accept", "Cookie"}, false},
+		{[]string{"*"}, false},
+	}
+	for _, tt := range tests {
+		counter, h := setup(NewLRU(10))
+		counter.vary = tt.vary
+		get(h, "/items/1", "alice")
+		rec := get(h, "/items/1", "alice")
+		if got := rec.Header().Get(StatusHeader) == "HIT"; got != tt.cached {
+			t.Errorf("Vary %v: cached = %v, want %v", tt.vary, got, tt.cached)
+		}
+	}
+}
+
+func TestCachesSignedRequests(t *testing.T) {
+	const secret = "partner-secret-0123456789"
+	v, err := auth.NewSignatureVerifier([]auth.SigningKey{{ID: "partner", Secret: secret, Principal: "svc-partner", Enabled: true}}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counter, inner := setup(NewLRU(10))
+	h := (&auth.Middleware{Signatures: v}).Require(inner)
+	signed := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/items/1", nil)
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(auth.KeyIDHeader, "partner")
+		req.Header.Set(auth.TimestampHeader, ts)
+		req.Header.Set(auth.SignatureHeader, auth.Sign(secret, http.MethodGet, "/items/1", ts, nil))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	signed()
+	rec := signed()
+	if rec.Code != http.StatusOK || rec.Header().Get(StatusHeader) != "HIT" || counter.calls != 1 {
+		t.Errorf("second signed request: status %d, %s %q, %d backend calls", rec.Code, StatusHeader, rec.Header().Get(StatusHeader), counter.calls)
+	}
+}
+
+func TestHitKeepsOuterVary(t *testing.T) {
+	counter, inner := setup(NewLRU(10))
+	counter.vary = []string{"Authorization"}
+	// Like the CORS middleware, which runs in front of the cache and
+	// varies only requests that have an Origin.
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Origin") != "" {
+			respond.Vary(w, "Origin")
+		}
+		inner.ServeHTTP(w, r)
+	})
+	get(h, "/items/1", "alice")
+	rec := get(h, "/items/1", "alice", "Origin", "https://app.example.com")
+	if rec.Header().Get(StatusHeader) != "HIT" {
+		t.Fatal("second request missed")
+	}
+	if got := respond.Varies(rec.Header()); len(got) != 2 || got[0] != "Origin" || got[1] != "Authorization" {
+		t.Errorf("Vary = %q, want Origin, Authorization", got)
+	}
+}
+
+func TestStaleIfError(t *testing.T) {
+	h := &counting{}
+	rt := router.New()
+	routes := routeconf.Table{"GET /items/{id}": {StaleIfError: time.Hour}}
+	rt.Handle(http.MethodGet, "/items/{id}", Middleware(NewLRU(10), time.Millisecond, routes)(h))
+	rt.Handle(http.MethodGet, "/other/{id}", Middleware(NewLRU(10), time.Millisecond, routes)(h))
+
+	get(rt, "/items/1", "alice")
+	time.Sleep(5 * time.Millisecond)
+	h.status = http.StatusServiceUnavailable
+	rec := get(rt, "/items/1", "alice")
+	if rec.Code != http.StatusOK || rec.Body.String() != "call 1" || rec.Header().Get(StatusHeader) != "STALE" {
+		t.Fatalf("backend down: %d %s %q", rec.Code, rec.Header().Get(StatusHeader), rec.Body)
+	}
+	if rec.Header().Get("Warning") != `110 - "Response is Stale"` || rec.Header().Get("Cache-Control") != "private, max-age=5" {
+		t.Errorf("stale headers = %v", rec.Header())
+	}
+	// Nothing of the failed response is kept.
+	if rec.Header().Get("X-Request-ID") != "" || rec.Header().Get("ETag") != `"v1"` {
+		t.Errorf("failed response leaked into the stale one: %v", rec.Header())
+	}
+
+	// Once the backend answers, its response replaces the stale entry.
+	h.status = 0
+	if rec := get(rt, "/items/1", "alice"); rec.Body.String() != "call 3" || rec.Header().Get(StatusHeader) != "MISS" {
+		t.Errorf("backend back: %s %q", rec.Header().Get(StatusHeader), rec.Body)
+	}
+
+	// Routes without the override pass errors on.
+	get(rt, "/other/1", "alice")
+	time.Sleep(5 * time.Millisecond)
+	h.status = http.StatusServiceUnavailable
+	if rec := get(rt, "/other/1", "alice"); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("route without stale_if_error: %d %q", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"bytes"
+	"maps"
+	"net/http"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/metrics"
+	"github.com/content-factory/go-gateway/internal/router"
+)
+
+// staleWarning marks stale responses, as RFC 7234 section 5.5.1 does.
+const staleWarning = `110 - "Response is Stale"`
+
+// staleMaxAge is how long clients may keep a stale response, short so
+// they come back for a fresh one once the backend recovers.
+const staleMaxAge = 5 * time.Second
+
+var staleServed = metrics.NewCounterVec("gateway_cache_stale_served_total",
+	"Cached responses served past their TTL because the backend failed, by route.",
+	"route")
+
+// serveStale answers r with the stale resp in place of next's response,
+// an error with status. It carries a Warning, X-Cache: STALE, its Age and
+// a Cache-Control max-age of staleMaxAge.
+func serveStale(w http.ResponseWriter, r *http.Request, resp *Response, status int) {
+	staleServed.With(router.Pattern(r)).Inc()
+	gateway.Logger(r.Context()).WarnContext(r.Context(), "serving a stale cached response in place of an error",
+		"status", status, "age", time.Since(resp.Stored).Round(time.Second))
+	replay(w, r, resp, statusStale)
+}
+
+// heldResponse buffers a response, so it can be discarded for a stale one
+// if it turns out to be an error.
+type heldResponse struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+// holdResponse returns a heldResponse starting with a copy of header, the
+// one already set in front of it.
+func holdResponse(header http.Header) *heldResponse {
+	return &heldResponse{header: header.Clone(), status: http.StatusOK}
+}
+
+func (h *heldResponse) Header() http.Header { return h.header }
+
+func (h *heldResponse) WriteHeader(status int) {
+	if !h.wroteHeader {
+		h.status = status
+		h.wroteHeader = true
+	}
+}
+
+func (h *heldResponse) Write(b []byte) (int, error) {
+	h.wroteHeader = true
+	return h.body.Write(b)
+}
+
+// writeTo sends the held response to w.
+func (h *heldResponse) writeTo(w http.ResponseWriter) {
+	maps.DeleteFunc(w.Header(), func(string, []string) bool { return true })
+	maps.Copy(w.Header(), h.header)
+	w.WriteHeader(h.status)
+	w.Write(h.body.Bytes())
+}
@@ -0,0 +1,135 @@
+// Package cache caches GET responses so repeated identical reads are
+// answered without a backend call. Entries are filed under the resource
+// (the request path) they represent, so handlers that change a resource
+// can drop every cached view of it.
+package cache
+
+import (
+	"container/list"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Defaults used by the config package.
+const (
+	DefaultMaxEntries = 10000
+	DefaultTTL        = 2 * time.Second
+)
+
+// Response is a cached response.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+	// Stored is when the response was cached; zero means it is always
+	// fresh.
+	Stored time.Time
+}
+
+// Store holds cached responses.
+type Store interface {
+	// Get returns the live entry for key, if any.
+	Get(ctx context.Context, key string) (*Response, bool, error)
+	// Set caches resp under key for ttl, filed under resource.
+	Set(ctx context.Context, resource, key string, resp *Response, ttl time.Duration) error
+	// Invalidate drops every entry filed under resource.
+	Invalidate(ctx context.Context, resource string) error
+}
+
+type lruEntry struct {
+	key      string
+	resource string
+	resp     *Response
+	expires  time.Time
+}
+
+// LRU is an in-process Store holding at most a fixed number of entries,
+// evicting the least recently used first. Entries are not shared between
+// replicas.
+type LRU struct {
+	max int
+	now func() time.Time
+
+	mu        sync.Mutex
+	order     *list.List // front is most recently used
+	entries   map[string]*list.Element
+	resources map[string]map[string]bool
+}
+
+// NewLRU returns an empty LRU holding up to maxEntries responses.
+func NewLRU(maxEntries int) *LRU {
+	return &LRU{
+		max:       maxEntries,
+		now:       time.Now,
+		order:     list.New(),
+		entries:   make(map[string]*list.Element),
+		resources: make(map[string]map[string]bool),
+	}
+}
+
+// Get implements Store.
+func (c *LRU) Get(ctx context.Context, key string) (*Response, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	e := el.Value.(*lruEntry)
+	if !c.now().Before(e.expires) {
+		c.remove(el)
+		return nil, false, nil
+	}
+	c.order.MoveToFront(el)
+	return e.resp, true, nil
+}
+
+// Set implements Store.
+func (c *LRU) Set(ctx context.Context, resource, key string, resp *Response, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+	e := &lruEntry{key: key, resource: resource, resp: resp, expires: c.now().Add(ttl)}
+	c.entries[key] = c.order.PushFront(e)
+	if c.resources[resource] == nil {
+		c.resources[resource] = make(map[string]bool)
+	}
+	c.resources[resource][key] = true
+	for c.order.Len() > c.max {
+		c.remove(c.order.Back())
+	}
+	return nil
+}
+
+// Invalidate implements Store.
+func (c *LRU) Invalidate(ctx context.Context, resource string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.resources[resource] {
+		c.remove(c.entries[key])
+	}
+	return nil
+}
+
+// Len returns the number of entries held, including expired ones not yet
+// evicted.
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *LRU) remove(el *list.Element) {
+	e := c.order.Remove(el).(*lruEntry)
+	delete(c.entries, e.key)
+	if keys := c.resources[e.resource]; keys != nil {
+		delete(keys, e.key)
+		if len(keys) == 0 {
+			delete(c.resources, e.resource)
+		}
+	}
+}
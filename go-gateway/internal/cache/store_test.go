@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(2)
+	c.Set(ctx, "/a", "a", &Response{Status: 200}, time.Hour)
+	c.Set(ctx, "/b", "b", &Response{Status: 200}, time.Hour)
+	c.Get(ctx, "a") // b is now the oldest
+	c.Set(ctx, "/c", "c", &Response{Status: 200}, time.Hour)
+
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok, _ := c.Get(ctx, key); ok != want {
+			t.Errorf("Get(%q) found = %v, want %v", key, ok, want)
+		}
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len = %d", c.Len())
+	}
+}
+
+func TestLRUExpiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	c := NewLRU(10)
+	c.now = func() time.Time { return now }
+	c.Set(ctx, "/a", "a", &Response{Status: 200}, time.Minute)
+
+	now = now.Add(59 * time.Second)
+	if _, ok, _ := c.Get(ctx, "a"); !ok {
+		t.Fatal("entry expired early")
+	}
+	now = now.Add(time.Second)
+	if _, ok, _ := c.Get(ctx, "a"); ok {
+		t.Fatal("expired entry returned")
+	}
+	if c.Len() != 0 {
+		t.Errorf("expired entry kept, Len = %d", c.Len())
+	}
+}
+
+func TestLRUInvalidate(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(10)
+	c.Set(ctx, "/jobs", "alice:/jobs?limit=1", &Response{Status: 200}, time.Hour)
+	c.Set(ctx, "/jobs", "bob:/jobs?", &Response{Status: 200}, time.Hour)
+	c.Set(ctx, "/jobs/1", "alice:/jobs/1?", &Response{Status: 200}, time.Hour)
+
+	c.Invalidate(ctx, "/jobs")
+	if c.Len() != 1 {
+		t.Fatalf("Len = %d after invalidating /jobs, want 1", c.Len())
+	}
+	if _, ok, _ := c.Get(ctx, "alice:/jobs/1?"); !ok {
+		t.Error("unrelated resource invalidated")
+	}
+	c.Invalidate(ctx, "/unknown")
+}
@@ -0,0 +1,152 @@
+// Package clientip resolves the address of the client behind a request
+// that may have come through proxies.
+package clientip
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+
+	"github.com/content-factory/go-gateway/internal/gateway"
+)
+
+// TrustAll lists the ranges that trust every peer, the behaviour of the
+// older TRUST_PROXY setting.
+var TrustAll = []string{"0.0.0.0/0", "::/0"}
+
+// Resolver finds the client IP of requests. X-Forwarded-For is believed
+// only as far as it was written by trusted proxies: the header is walked
+// from the right, the entry the nearest proxy added, and the first address
+// outside the trusted ranges is the client. A request whose peer is not a
+// trusted proxy is taken at its RemoteAddr, whatever the header says.
+type Resolver struct {
+	trusted atomic.Pointer[[]netip.Prefix]
+}
+
+// New returns a Resolver trusting the proxies in the given CIDR ranges or
+// single addresses. With none, X-Forwarded-For is ignored.
+func New(trusted []string) (*Resolver, error) {
+	res := &Resolver{}
+	if err := res.SetTrusted(trusted); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// SetTrusted replaces the trusted ranges while serving.
+func (res *Resolver) SetTrusted(trusted []string) error {
+	prefixes, err := Parse(trusted)
+	if err != nil {
+		return err
+	}
+	res.trusted.Store(&prefixes)
+	return nil
+}
+
+// Parse parses CIDR ranges and single addresses.
+func Parse(ranges []string) ([]netip.Prefix, error) {
+	out := make([]netip.Prefix, 0, len(ranges))
+	for _, s := range ranges {
+		s = strings.TrimSpace(s)
+		if p, err := netip.ParsePrefix(s); err == nil {
+			out = append(out, p.Masked())
+			continue
+		}
+		a, err := netip.ParseAddr(s)
+		if err != nil {
+			return nil, fmt.Errorf("clientip: %q is not a CIDR range or IP address", s)
+		}
+		a = a.Unmap()
+		out = append(out, netip.PrefixFrom(a, a.BitLen()))
+	}
+	return out, nil
+}
+
+func (res *Resolver) trusts(a netip.Addr) bool {
+	for _, p := range *res.trusted.Load() {
+		if p.Contains(a) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve returns the client IP of r.
+func (res *Resolver) Resolve(r *http.Request) string {
+	peer := remoteHost(r.RemoteAddr)
+	client, err := netip.ParseAddr(peer)
+	if err != nil {
+		return peer
+	}
+	client = client.Unmap()
+	if !res.trusts(client) {
+		return client.String()
+	}
+	hops := forwardedFor(r.Header)
+	for i := len(hops) - 1; i >= 0; i-- {
+		a, ok := parseHop(hops[i])
+		if !ok {
+			// Everything left of a garbled entry is unverifiable; the
+			// proxy that wrote it is the last address we know.
+			break
+		}
+		client = a
+		if !res.trusts(a) {
+			break
+		}
+	}
+	return client.String()
+}
+
+// Middleware stores each request's client IP for Of. Install it before
+// anything that logs or limits by IP.
+func (res *Resolver) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := gateway.WithClientIP(r.Context(), res.Resolve(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Of returns the client IP Middleware resolved for r, or the host of its
+// RemoteAddr outside that middleware.
+func Of(r *http.Request) string {
+	if ip := gateway.ClientIPFromContext(r.Context()); ip != "" {
+		return ip
+	}
+	return remoteHost(r.RemoteAddr)
+}
+
+func remoteHost(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// forwardedFor returns the entries of every X-Forwarded-For header of h,
+// in order.
+func forwardedFor(h http.Header) []string {
+	var hops []string
+	for _, v := range h.Values("X-Forwarded-For") {
+		for hop := range strings.SplitSeq(v, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	return hops
+}
+
+// parseHop parses an X-Forwarded-For entry, which some proxies write with
+// a port.
+func parseHop(s string) (netip.Addr, bool) {
+	if a, err := netip.ParseAddr(s); err == nil {
+		return a.Unmap(), true
+	}
+	if ap, err := netip.ParseAddrPort(s); err == nil {
+		return ap.Addr().Unmap(), true
+	}
+	return netip.Addr{}, false
+}
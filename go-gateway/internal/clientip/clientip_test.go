@@ -0,0 +1,76 @@
+package clientip
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	res, err := New([]string{"10.0.0.0/8", "192.0.2.7"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		remote string
+		xff    []string
+		want   string
+	}{
+		{"no proxy", "203.0.113.9:4000", nil, "203.0.113.9"},
+		{"untrusted peer ignores header", "203.0.113.9:4000", []string{"198.51.100.1"}, "203.0.113.9"},
+		{"trusted peer without header", "10.1.2.3:4000", nil, "10.1.2.3"},
+		{"one proxy", "10.1.2.3:4000", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"spoofed left entries skipped", "10.1.2.3:4000", []string{"1.1.1.1, 198.51.100.1"}, "198.51.100.1"},
+		{"proxy chain", "10.1.2.3:4000", []string{"198.51.100.1, 192.0.2.7", "10.9.9.9"}, "198.51.100.1"},
+		{"all trusted", "10.1.2.3:4000", []string{"10.0.0.5, 10.0.0.6"}, "10.0.0.5"},
+		{"garbled entry stops the walk", "10.1.2.3:4000", []string{"198.51.100.1, bogus, 10.0.0.6"}, "10.0.0.6"},
+		{"port in entry", "10.1.2.3:4000", []string{"198.51.100.1:5555"}, "198.51.100.1"},
+		{"ipv6", "[2001:db8::1]:4000", []string{"198.51.100.1"}, "2001:db8::1"},
+		{"mapped peer", "[::ffff:10.1.2.3]:4000", []string{"198.51.100.1"}, "198.51.100.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remote
+			for _, v := range tt.xff {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+			if got := res.Resolve(r); got != tt.want {
+				t.Errorf("Resolve = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMiddlewareAndOf(t *testing.T) {
+	res, _ := New(TrustAll)
+	var got string
+	h := res.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { got = Of(r) }))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("X-Forwarded-For", "203.0.113.9, 10.0.0.1")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if got != "203.0.113.9" {
+		t.Errorf("Of = %q inside the middleware", got)
+	}
+	if ip := Of(r); ip != "10.0.0.1" {
+		t.Errorf("Of = %q outside it, want the peer", ip)
+	}
+}
+
+func TestParse(t *testing.T) {
+	if _, err := Parse([]string{"10.0.0.0/8", "::1", "fd00::/8"}); err != nil {
+		t.Error(err)
+	}
+	if _, err := Parse([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("accepted an invalid range")
+	}
+	res, _ := New(nil)
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("X-Forwarded-For", "203.0.113.9")
+	if got := res.Resolve(r); got != "10.0.0.1" {
+		t.Errorf("with no trusted proxies: %q", got)
+	}
+}
@@ -0,0 +1,1549 @@
+// Package config loads the gateway's configuration. Values come from
+// built-in defaults, then an optional YAML or JSON file named by
+// CONFIG_FILE, then environment variables, each layer overriding the one
+// before. main loads it once and hands each component its section.
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/api"
+	"github.com/content-factory/go-gateway/internal/auth"
+	"github.com/content-factory/go-gateway/internal/backend"
+	"github.com/content-factory/go-gateway/internal/breaker"
+	"github.com/content-factory/go-gateway/internal/cache"
+	"github.com/content-factory/go-gateway/internal/clientip"
+	"github.com/content-factory/go-gateway/internal/cors"
+	"github.com/content-factory/go-gateway/internal/fairqueue"
+	"github.com/content-factory/go-gateway/internal/flags"
+	"github.com/content-factory/go-gateway/internal/grpcpool"
+	"github.com/content-factory/go-gateway/internal/headers"
+	"github.com/content-factory/go-gateway/internal/health"
+	"github.com/content-factory/go-gateway/internal/idempotency"
+	"github.com/content-factory/go-gateway/internal/jobs"
+	"github.com/content-factory/go-gateway/internal/loadshed"
+	"github.com/content-factory/go-gateway/internal/maintenance"
+	"github.com/content-factory/go-gateway/internal/methodlimit"
+	"github.com/content-factory/go-gateway/internal/middleware"
+	"github.com/content-factory/go-gateway/internal/proxy"
+	"github.com/content-factory/go-gateway/internal/queue"
+	"github.com/content-factory/go-gateway/internal/ratelimit"
+	"github.com/content-factory/go-gateway/internal/realtime"
+	"github.com/content-factory/go-gateway/internal/retry"
+	"github.com/content-factory/go-gateway/internal/routeconf"
+	"github.com/content-factory/go-gateway/internal/signedurl"
+	"github.com/content-factory/go-gateway/internal/tenant"
+	"github.com/content-factory/go-gateway/internal/tlsreload"
+	"github.com/content-factory/go-gateway/internal/tracing"
+	"github.com/content-factory/go-gateway/internal/webhook"
+	"github.com/content-factory/go-gateway/internal/websocket"
+)
+
+// Config is the complete gateway configuration. The json tag names a
+// field's key in the config file; the env tag lists the environment
+// variables that override it, first match wins.
+type Config struct {
+	Port            int           `json:"port" env:"PORT"`
+	LogLevel        string        `json:"log_level" env:"LOG_LEVEL"`
+	ShutdownTimeout time.Duration `json:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+	RequestTimeout  time.Duration `json:"request_timeout" env:"REQUEST_TIMEOUT"`
+	MaxRequestBytes int64         `json:"max_request_bytes" env:"MAX_REQUEST_BYTES"`
+	RedisURL        string        `json:"redis_url" env:"REDIS_URL"`
+	TrustedProxies  []string      `json:"trusted_proxies" env:"TRUSTED_PROXIES"`
+	EnablePprof     bool          `json:"enable_pprof" env:"ENABLE_PPROF"`
+	// SlowRequestThreshold is how long a request may take before it is
+	// logged as slow; zero turns the log off except where routes set one.
+	SlowRequestThreshold time.Duration `json:"slow_request_threshold" env:"SLOW_REQUEST_THRESHOLD"`
+
+	Server        Server        `json:"server"`
+	AccessLog     AccessLog     `json:"access_log"`
+	AuditLog      AuditLog      `json:"audit_log"`
+	TLS           TLS           `json:"tls"`
+	Backend       Backend       `json:"backend"`
+	Auth          Auth          `json:"auth"`
+	RateLimit     RateLimit     `json:"rate_limit"`
+	CORS          CORS          `json:"cors"`
+	Breaker       Breaker       `json:"breaker"`
+	Retry         Retry         `json:"retry"`
+	API           API           `json:"api"`
+	Compress      Compress      `json:"compress"`
+	Cache         Cache         `json:"cache"`
+	Tracing       Tracing       `json:"tracing"`
+	LoadShed      LoadShed      `json:"load_shed"`
+	FairQueue     FairQueue     `json:"fair_queue"`
+	Realtime      Realtime      `json:"realtime"`
+	Queue         Queue         `json:"queue"`
+	Webhooks      Webhooks      `json:"webhooks"`
+	Legacy        Legacy        `json:"legacy"`
+	SignedURLs    SignedURLs    `json:"signed_urls"`
+	PublicContent PublicContent `json:"public_content"`
+	Maintenance   Maintenance   `json:"maintenance"`
+	Startup       Startup       `json:"startup"`
+	Routes        []Route       `json:"routes"`
+	// Headers can only be set in the config file.
+	Headers Headers `json:"headers"`
+	// FeatureFlags can only be set in the config file.
+	FeatureFlags []FeatureFlag `json:"feature_flags"`
+	// Tenants can only be set in the config file. With none the gateway is
+	// not multi-tenant; DefaultTenant then must be empty.
+	Tenants       []Tenant `json:"tenants"`
+	DefaultTenant string   `json:"default_tenant" env:"DEFAULT_TENANT"`
+
+	ClientTimeout ClientTimeout `json:"client_timeout"`
+}
+
+// Server configures the connection timeouts of the HTTP server, see
+// middleware.ServerTimeouts; zero turns one off. WebSocket, SSE, download,
+// upload and legacy proxy routes are exempt from ReadTimeout and
+// WriteTimeout, and requests with a longer timeout from WriteTimeout.
+// H2C serves HTTP/2 without TLS, for a service mesh's internal hops,
+// alongside HTTP/1.1.
+type Server struct {
+	ReadHeaderTimeout time.Duration `json:"read_header_timeout" env:"HTTP_READ_HEADER_TIMEOUT"`
+	ReadTimeout       time.Duration `json:"read_timeout" env:"HTTP_READ_TIMEOUT"`
+	WriteTimeout      time.Duration `json:"write_timeout" env:"HTTP_WRITE_TIMEOUT"`
+	IdleTimeout       time.Duration `json:"idle_timeout" env:"HTTP_IDLE_TIMEOUT"`
+	H2C               bool          `json:"h2c" env:"ENABLE_H2C"`
+}
+
+// AccessLog configures the request log, one line per request, which is
+// kept apart from the application log. Format is json or combined, the
+// Apache Combined Log Format. File, if set, is appended to instead of
+// standard output. Level, if set, replaces log_level for the request log;
+// requests the client abandoned are logged at debug.
+type AccessLog struct {
+	Format string `json:"format" env:"ACCESS_LOG_FORMAT"`
+	File   string `json:"file" env:"ACCESS_LOG_FILE"`
+	Level  string `json:"level" env:"ACCESS_LOG_LEVEL"`
+}
+
+// AuditLog configures the audit trail of authentication, authorisation,
+// admin and job events, see package audit. It is always written, whatever
+// log_level is. File, if set, is appended to instead of standard output,
+// where each record is marked "log":"audit".
+type AuditLog struct {
+	File string `json:"file" env:"AUDIT_LOG_FILE"`
+}
+
+// TLS configures HTTPS. The gateway serves plain HTTP when no certificate
+// is set.
+type TLS struct {
+	CertFile       string        `json:"cert_file" env:"TLS_CERT_FILE"`
+	KeyFile        string        `json:"key_file" env:"TLS_KEY_FILE"`
+	ReloadInterval time.Duration `json:"reload_interval" env:"TLS_RELOAD_INTERVAL"`
+}
+
+// Enabled reports whether a certificate is configured.
+func (t TLS) Enabled() bool { return t.CertFile != "" }
+
+// Backend configures the connection pool to the content service. Addr
+// lists its endpoints, comma-separated, each optionally tagged with its
+// zone as host:port@zone; PoolSize connections are kept to each.
+// PreferredZone, when set, must be the zone of at least one endpoint.
+// DeadlineMargin is taken off the time a request has left before each
+// call, so the gateway can still answer once the service gives up.
+// MaxResponseBytes caps a unary response or a stream, other than a
+// download or progress stream, and each message of those. MethodLimits
+// can only be set in the config file.
+type Backend struct {
+	Addr                string        `json:"addr" env:"CONTENT_SERVICE_ADDR,PYTHON_ORCHESTRATOR_ADDR"`
+	PoolSize            int           `json:"pool_size" env:"GRPC_POOL_SIZE"`
+	DialTimeout         time.Duration `json:"dial_timeout" env:"GRPC_DIAL_TIMEOUT"`
+	KeepaliveTime       time.Duration `json:"keepalive_time" env:"GRPC_KEEPALIVE_TIME"`
+	KeepaliveTimeout    time.Duration `json:"keepalive_timeout" env:"GRPC_KEEPALIVE_TIMEOUT"`
+	DeadlineMargin      time.Duration `json:"deadline_margin" env:"GRPC_DEADLINE_MARGIN"`
+	MaxResponseBytes    int64         `json:"max_response_bytes" env:"BACKEND_MAX_RESPONSE_BYTES"`
+	PreferredZone       string        `json:"preferred_zone" env:"PREFERRED_ZONE"`
+	HealthCheckInterval time.Duration `json:"health_check_interval" env:"BACKEND_HEALTH_CHECK_INTERVAL"`
+	MethodLimits        []MethodLimit `json:"method_limits"`
+}
+
+// MethodLimit is one entry of backend.method_limits: how many calls of
+// Method, named as in the proto, such as CreateContent, may run at once,
+// and how many more may wait up to MaxWait for a slot, see package
+// methodlimit.
+type MethodLimit struct {
+	Method      string        `json:"method"`
+	MaxInFlight int           `json:"max_in_flight"`
+	MaxQueued   int           `json:"max_queued"`
+	MaxWait     time.Duration `json:"max_wait"`
+}
+
+// Auth configures bearer token verification, API keys and signing keys.
+type Auth struct {
+	JWTAlgorithm     string `json:"jwt_algorithm" env:"JWT_ALGORITHM"`
+	JWTSecret        string `json:"jwt_secret" env:"JWT_SECRET"`
+	JWTPublicKeyFile string `json:"jwt_public_key_file" env:"JWT_PUBLIC_KEY_FILE"`
+	// OIDCIssuer replaces the static JWT key with the rotating keys of an
+	// OpenID Connect provider, found through its discovery document.
+	// Tokens must be for OIDCAudience.
+	OIDCIssuer          string        `json:"oidc_issuer" env:"OIDC_ISSUER"`
+	OIDCAudience        string        `json:"oidc_audience" env:"OIDC_AUDIENCE"`
+	OIDCRefreshInterval time.Duration `json:"oidc_refresh_interval" env:"OIDC_REFRESH_INTERVAL"`
+	APIKeys             []APIKey      `json:"api_keys"`
+	SigningKeys         []SigningKey  `json:"signing_keys"`
+	// SignatureMaxClockSkew is how far a signed request's X-Timestamp may
+	// be from the gateway's clock, either way.
+	SignatureMaxClockSkew time.Duration `json:"signature_max_clock_skew" env:"SIGNATURE_MAX_CLOCK_SKEW"`
+}
+
+// APIKey is one entry of auth.api_keys. Keys can only be set in the config
+// file, which keeps secrets out of process listings.
+type APIKey struct {
+	ID        string   `json:"id"`
+	Key       string   `json:"key"`
+	Principal string   `json:"principal"`
+	Scopes    []string `json:"scopes"`
+	Enabled   bool     `json:"enabled"`
+	RPS       float64  `json:"rps"`
+	Burst     int      `json:"burst"`
+	Tenant    string   `json:"tenant"`
+}
+
+func (k *APIKey) setDefaults() { k.Enabled = true }
+
+// SigningKey is one entry of auth.signing_keys, a secret a partner signs
+// its requests with, see auth.SignatureVerifier. Like API keys, signing
+// keys can only be set in the config file.
+type SigningKey struct {
+	ID        string   `json:"id"`
+	Secret    string   `json:"secret"`
+	Principal string   `json:"principal"`
+	Scopes    []string `json:"scopes"`
+	Enabled   bool     `json:"enabled"`
+	Tenant    string   `json:"tenant"`
+}
+
+func (k *SigningKey) setDefaults() { k.Enabled = true }
+
+// RateLimit configures the per-client token bucket.
+type RateLimit struct {
+	RPS   float64 `json:"rps" env:"RATE_LIMIT_RPS"`
+	Burst int     `json:"burst" env:"RATE_LIMIT_BURST"`
+	// TrustProxy, if TrustedProxies is empty, trusts every peer to set
+	// X-Forwarded-For. It predates TrustedProxies.
+	TrustProxy bool `json:"trust_proxy" env:"TRUST_PROXY"`
+}
+
+// CORS configures cross-origin access for browser clients.
+type CORS struct {
+	AllowedOrigins   []string      `json:"allowed_origins" env:"CORS_ALLOWED_ORIGINS"`
+	AllowedMethods   []string      `json:"allowed_methods" env:"CORS_ALLOWED_METHODS"`
+	AllowedHeaders   []string      `json:"allowed_headers" env:"CORS_ALLOWED_HEADERS"`
+	AllowCredentials bool          `json:"allow_credentials" env:"CORS_ALLOW_CREDENTIALS"`
+	MaxAge           time.Duration `json:"max_age" env:"CORS_MAX_AGE"`
+}
+
+// Breaker configures the circuit breaker around backend calls.
+type Breaker struct {
+	FailureThreshold int           `json:"failure_threshold" env:"BREAKER_FAILURE_THRESHOLD"`
+	Cooldown         time.Duration `json:"cooldown" env:"BREAKER_COOLDOWN"`
+}
+
+// Retry configures retries of idempotent backend calls. BudgetMaxTokens
+// and BudgetTokenRatio size the process-wide retry budget, see
+// retry.Budget; 0 tokens turns it off.
+type Retry struct {
+	MaxAttempts      int           `json:"max_attempts" env:"RETRY_MAX_ATTEMPTS"`
+	InitialBackoff   time.Duration `json:"initial_backoff" env:"RETRY_INITIAL_BACKOFF"`
+	MaxBackoff       time.Duration `json:"max_backoff" env:"RETRY_MAX_BACKOFF"`
+	MaxElapsed       time.Duration `json:"max_elapsed" env:"RETRY_MAX_ELAPSED"`
+	BudgetMaxTokens  int           `json:"budget_max_tokens" env:"RETRY_BUDGET_MAX_TOKENS"`
+	BudgetTokenRatio float64       `json:"budget_token_ratio" env:"RETRY_BUDGET_TOKEN_RATIO"`
+}
+
+// API configures the REST handlers.
+type API struct {
+	MaxBodyBytes     int64         `json:"max_body_bytes" env:"API_MAX_BODY_BYTES"`
+	MaxUploadBytes   int64         `json:"max_upload_bytes" env:"API_MAX_UPLOAD_BYTES"`
+	MaxAssetBytes    int64         `json:"max_asset_bytes" env:"API_MAX_ASSET_BYTES"`
+	AssetTypes       []string      `json:"asset_types" env:"API_ASSET_TYPES"`
+	StrictJSON       bool          `json:"strict_json" env:"API_STRICT_JSON"`
+	IdempotencyTTL   time.Duration `json:"idempotency_ttl" env:"IDEMPOTENCY_TTL"`
+	DefaultPageSize  int           `json:"default_page_size" env:"API_DEFAULT_PAGE_SIZE"`
+	MaxPageSize      int           `json:"max_page_size" env:"API_MAX_PAGE_SIZE"`
+	MaxBatchItems    int           `json:"max_batch_items" env:"API_MAX_BATCH_ITEMS"`
+	BatchConcurrency int           `json:"batch_concurrency" env:"API_BATCH_CONCURRENCY"`
+	MaxStatusIDs     int           `json:"max_status_ids" env:"API_MAX_STATUS_IDS"`
+}
+
+// Compress configures response compression.
+type Compress struct {
+	MinSize int `json:"min_size" env:"COMPRESS_MIN_SIZE"`
+}
+
+// Cache configures the response cache of read endpoints. MaxEntries 0
+// turns it off.
+type Cache struct {
+	MaxEntries int           `json:"max_entries" env:"CACHE_MAX_ENTRIES"`
+	TTL        time.Duration `json:"ttl" env:"CACHE_TTL"`
+}
+
+// Tracing configures span export. Tracing is off when Endpoint is empty.
+type Tracing struct {
+	Endpoint    string `json:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	ServiceName string `json:"service_name" env:"OTEL_SERVICE_NAME"`
+}
+
+// Maintenance configures maintenance mode. Enabled and Message are only
+// its state at startup: POST /admin/maintenance switches it while the
+// gateway runs, and a SIGHUP reload leaves it as it was. DrainStreams
+// closes the open streams when it is switched on.
+type Maintenance struct {
+	Enabled      bool          `json:"enabled" env:"MAINTENANCE_MODE"`
+	Message      string        `json:"message" env:"MAINTENANCE_MESSAGE"`
+	RetryAfter   time.Duration `json:"retry_after" env:"MAINTENANCE_RETRY_AFTER"`
+	DrainStreams bool          `json:"drain_streams" env:"MAINTENANCE_DRAIN_STREAMS"`
+}
+
+// What Startup.OnTimeout may be.
+const (
+	StartupExit     = "exit"
+	StartupDegraded = "degraded"
+)
+
+// Startup configures waiting for the content service at startup. With
+// WaitForBackend the gateway reports "starting" on /readyz until the
+// backend passes its health check, retried with backoff for up to MaxWait.
+// Then OnTimeout decides: exit fails the process; degraded serves anyway,
+// reporting readiness as the checks find it.
+type Startup struct {
+	WaitForBackend bool          `json:"wait_for_backend" env:"WAIT_FOR_BACKEND"`
+	MaxWait        time.Duration `json:"max_wait" env:"STARTUP_MAX_WAIT"`
+	InitialBackoff time.Duration `json:"initial_backoff" env:"STARTUP_INITIAL_BACKOFF"`
+	MaxBackoff     time.Duration `json:"max_backoff" env:"STARTUP_MAX_BACKOFF"`
+	OnTimeout      string        `json:"on_timeout" env:"STARTUP_ON_TIMEOUT"`
+}
+
+// LoadShed configures rejection of requests over an in-flight limit.
+// MaxInFlight 0 turns it off. It is re-read on SIGHUP.
+type LoadShed struct {
+	MaxInFlight int `json:"max_in_flight" env:"LOAD_SHED_MAX_IN_FLIGHT"`
+}
+
+// FairQueue configures the scheduler that shares the content service
+// between principals: once MaxInFlight calls are running, further calls
+// wait, at most MaxQueued of them per principal, and are served round-robin
+// across principals. MaxInFlight 0 turns it off.
+type FairQueue struct {
+	MaxInFlight int `json:"max_in_flight" env:"FAIR_QUEUE_MAX_IN_FLIGHT"`
+	MaxQueued   int `json:"max_queued_per_principal" env:"FAIR_QUEUE_MAX_QUEUED"`
+}
+
+// Realtime configures the job event streams. ClientBuffer is how many
+// events a client may fall behind; Overflow, disconnect or drop_oldest,
+// is what happens when it falls further. AuthGrace is how long a
+// WebSocket outlives its JWT without a refresh. MaxPerIP and
+// MaxPerPrincipal cap the streams one client IP and one principal may
+// hold open; 0 means no limit. PollTimeout is how long a long-poll waits
+// for an event before answering 204. Compression negotiates
+// permessage-deflate with WebSocket clients that offer it, at
+// CompressionLevel, for messages of at least CompressionThreshold bytes;
+// without ContextTakeover each message is compressed on its own.
+// MaxMessageBytes caps the messages WebSocket clients send.
+// EventBusBuffer is how many events the audit log and job metrics may
+// fall behind the job event bus before they miss some.
+type Realtime struct {
+	ClientBuffer    int           `json:"client_buffer" env:"REALTIME_CLIENT_BUFFER"`
+	Overflow        string        `json:"overflow_policy" env:"REALTIME_OVERFLOW_POLICY"`
+	AuthGrace       time.Duration `json:"auth_grace" env:"REALTIME_AUTH_GRACE"`
+	MaxPerIP        int           `json:"max_connections_per_ip" env:"REALTIME_MAX_CONNECTIONS_PER_IP"`
+	MaxPerPrincipal int           `json:"max_connections_per_principal" env:"REALTIME_MAX_CONNECTIONS_PER_PRINCIPAL"`
+	PollTimeout     time.Duration `json:"poll_timeout" env:"REALTIME_POLL_TIMEOUT"`
+	EventBusBuffer  int           `json:"event_bus_buffer" env:"REALTIME_EVENT_BUS_BUFFER"`
+
+	Compression          bool `json:"ws_compression" env:"REALTIME_WS_COMPRESSION"`
+	CompressionLevel     int  `json:"ws_compression_level" env:"REALTIME_WS_COMPRESSION_LEVEL"`
+	CompressionThreshold int  `json:"ws_compression_threshold" env:"REALTIME_WS_COMPRESSION_THRESHOLD"`
+	ContextTakeover      bool `json:"ws_context_takeover" env:"REALTIME_WS_CONTEXT_TAKEOVER"`
+
+	MaxMessageBytes int64 `json:"ws_max_message_bytes" env:"REALTIME_WS_MAX_MESSAGE_BYTES"`
+}
+
+// Queue configures buffering of content submissions. Backend is memory
+// or redis, which keeps the queue in REDIS_URL under RedisKey; empty
+// submits to the content service directly. Capacity is how many jobs may
+// wait, Workers how many are submitted at once. Once HighWater of
+// Capacity is waiting, content submission is unavailable and the gateway
+// degraded until the queue drains.
+type Queue struct {
+	Backend   string  `json:"backend" env:"QUEUE_BACKEND"`
+	Capacity  int     `json:"capacity" env:"QUEUE_CAPACITY"`
+	Workers   int     `json:"workers" env:"QUEUE_WORKERS"`
+	RedisKey  string  `json:"redis_key" env:"QUEUE_REDIS_KEY"`
+	HighWater float64 `json:"high_water" env:"QUEUE_HIGH_WATER"`
+}
+
+// Webhooks configures delivery of job webhooks. They are enabled once
+// Secrets holds a secret for at least one principal; only those
+// principals may submit a webhook_url. Secrets can only be set in the
+// config file.
+type Webhooks struct {
+	MaxAttempts    int             `json:"max_attempts" env:"WEBHOOK_MAX_ATTEMPTS"`
+	InitialBackoff time.Duration   `json:"initial_backoff" env:"WEBHOOK_INITIAL_BACKOFF"`
+	MaxBackoff     time.Duration   `json:"max_backoff" env:"WEBHOOK_MAX_BACKOFF"`
+	Timeout        time.Duration   `json:"timeout" env:"WEBHOOK_TIMEOUT"`
+	AllowPrivate   bool            `json:"allow_private_networks" env:"WEBHOOK_ALLOW_PRIVATE_NETWORKS"`
+	Secrets        []WebhookSecret `json:"secrets"`
+}
+
+// WebhookSecret is one entry of webhooks.secrets: the key principal's
+// webhooks are signed with.
+type WebhookSecret struct {
+	Principal string `json:"principal"`
+	Secret    string `json:"secret"`
+}
+
+// FeatureFlag is one entry of feature_flags, see package flags.
+type FeatureFlag struct {
+	Name        string   `json:"name"`
+	Enabled     bool     `json:"enabled"`
+	Percent     float64  `json:"percent"`
+	Principals  []string `json:"principals"`
+	AllowHeader bool     `json:"allow_header"`
+}
+
+// Headers rewrites the headers of every request as it arrives and of
+// every response as it leaves, see package headers.
+type Headers struct {
+	Request  []HeaderRule `json:"request"`
+	Response []HeaderRule `json:"response"`
+}
+
+// HeaderRule is one header rewriting rule: action set, add or remove, the
+// header name, or for remove a prefix ending in "*", and the value.
+type HeaderRule struct {
+	Action string `json:"action"`
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+}
+
+// Tenant is one entry of tenants, see package tenant. BackendAddr, if set,
+// sends the tenant's backend calls to a content service of its own, given
+// like backend.addr and dialled with the rest of the backend settings.
+type Tenant struct {
+	ID          string   `json:"id"`
+	Enabled     bool     `json:"enabled"`
+	RPS         float64  `json:"rps"`
+	Burst       int      `json:"burst"`
+	Formats     []string `json:"formats"`
+	BackendAddr string   `json:"backend_addr"`
+	Metrics     bool     `json:"metrics"`
+}
+
+func (t *Tenant) setDefaults() { t.Enabled, t.Metrics = true, true }
+
+// ClientTimeout bounds the timeout clients ask for with the
+// X-Request-Timeout header. A zero Max is the request's own timeout, so
+// clients can shorten it but not extend it.
+type ClientTimeout struct {
+	Min time.Duration `json:"min" env:"CLIENT_TIMEOUT_MIN"`
+	Max time.Duration `json:"max" env:"CLIENT_TIMEOUT_MAX"`
+}
+
+// Legacy configures the reverse proxy to the HTTP service still serving
+// routes not yet on gRPC. Requests under Prefix are forwarded to URL; the
+// proxy is off when URL is empty. Responses over MaxResponseBytes get a
+// 502, apart from event streams and attachments.
+type Legacy struct {
+	URL              string `json:"url" env:"LEGACY_BACKEND_URL"`
+	Prefix           string `json:"prefix" env:"LEGACY_PREFIX"`
+	MaxResponseBytes int64  `json:"max_response_bytes" env:"LEGACY_MAX_RESPONSE_BYTES"`
+	// RequestHeaders and ResponseHeaders rewrite what is sent to and
+	// received from the legacy backend, after authentication; they can
+	// only be set in the config file.
+	RequestHeaders  []HeaderRule `json:"request_headers"`
+	ResponseHeaders []HeaderRule `json:"response_headers"`
+}
+
+// SignedURLs configures signed download URLs, which let clients fetch a
+// finished file for TTL without credentials. They are on once Secret is
+// set. BaseURL, if set, is where the URLs point instead of the gateway
+// itself; it must pass their paths on unchanged.
+type SignedURLs struct {
+	Secret  string        `json:"secret" env:"SIGNED_URL_SECRET"`
+	TTL     time.Duration `json:"ttl" env:"SIGNED_URL_TTL"`
+	BaseURL string        `json:"base_url" env:"SIGNED_URL_BASE"`
+}
+
+// PublicContent configures public content: the completed jobs their
+// owners made public, which anyone may read without credentials, for
+// sharing links and embedding in other sites. The reads are rate limited
+// by client IP apart from other requests, by default more strictly, and
+// take a CORS policy of their own that never allows credentials; by
+// default any origin may make them.
+type PublicContent struct {
+	Enabled        bool     `json:"enabled" env:"PUBLIC_CONTENT_ENABLED"`
+	RPS            float64  `json:"rps" env:"PUBLIC_CONTENT_RATE_LIMIT_RPS"`
+	Burst          int      `json:"burst" env:"PUBLIC_CONTENT_RATE_LIMIT_BURST"`
+	AllowedOrigins []string `json:"allowed_origins" env:"PUBLIC_CONTENT_CORS_ALLOWED_ORIGINS"`
+}
+
+// Route overrides the request timeout, rate limit, rate-limit weight,
+// body size limit and slow-request threshold of the routes Match names: a
+// route template, optionally after a method, such as
+// "POST /api/v1/content" or "/api/v1/jobs/{id}". Zero fields keep the
+// global setting, or for Weight the route's own; a negative SlowThreshold
+// never logs the route as slow. CoalesceWindow, on submission routes,
+// answers identical submissions within it with the first one's response.
+// StaleIfError, on cached GET routes, serves a cached response up to that
+// long past its TTL when the backend fails. Routes can only be set in the
+// config file.
+type Route struct {
+	Match          string        `json:"match"`
+	Timeout        time.Duration `json:"timeout"`
+	RPS            float64       `json:"rate_limit_rps"`
+	Burst          int           `json:"rate_limit_burst"`
+	Weight         int           `json:"rate_limit_weight"`
+	MaxBodyBytes   int64         `json:"max_body_bytes"`
+	SlowThreshold  time.Duration `json:"slow_request_threshold"`
+	CoalesceWindow time.Duration `json:"coalesce_window"`
+	StaleIfError   time.Duration `json:"stale_if_error"`
+}
+
+// split separates Match into its method, if any, and template.
+func (rt Route) split() (method, pattern string, hasMethod bool) {
+	method, pattern, hasMethod = strings.Cut(rt.Match, " ")
+	if !hasMethod {
+		return "", rt.Match, false
+	}
+	return method, pattern, true
+}
+
+// Default returns the configuration used when nothing is overridden.
+func Default() *Config {
+	return &Config{
+		Port:                 8080,
+		LogLevel:             "info",
+		ShutdownTimeout:      15 * time.Second,
+		RequestTimeout:       middleware.DefaultTimeout,
+		MaxRequestBytes:      middleware.DefaultMaxRequestBytes,
+		SlowRequestThreshold: middleware.DefaultSlowThreshold,
+		AccessLog:            AccessLog{Format: middleware.AccessLogJSON},
+		TLS:                  TLS{ReloadInterval: tlsreload.DefaultInterval},
+		Server: Server{
+			ReadHeaderTimeout: middleware.DefaultReadHeaderTimeout,
+			ReadTimeout:       middleware.DefaultReadTimeout,
+			WriteTimeout:      middleware.DefaultWriteTimeout,
+			IdleTimeout:       middleware.DefaultIdleTimeout,
+		},
+		Auth: Auth{OIDCRefreshInterval: auth.DefaultKeyRefreshInterval, SignatureMaxClockSkew: auth.DefaultMaxClockSkew},
+		Backend: Backend{
+			Addr:                grpcpool.DefaultAddr,
+			PoolSize:            grpcpool.DefaultSize,
+			DialTimeout:         grpcpool.DefaultDialTimeout,
+			KeepaliveTime:       grpcpool.DefaultKeepaliveTime,
+			KeepaliveTimeout:    grpcpool.DefaultKeepaliveTimeout,
+			DeadlineMargin:      grpcpool.DefaultDeadlineMargin,
+			MaxResponseBytes:    grpcpool.DefaultMaxResponseBytes,
+			HealthCheckInterval: grpcpool.DefaultHealthCheckInterval,
+		},
+		RateLimit: RateLimit{RPS: ratelimit.DefaultRPS, Burst: ratelimit.DefaultBurst},
+		CORS: CORS{
+			AllowedMethods: cors.DefaultMethods,
+			AllowedHeaders: cors.DefaultHeaders,
+			MaxAge:         cors.DefaultMaxAge,
+		},
+		Breaker: Breaker{
+			FailureThreshold: breaker.DefaultFailureThreshold,
+			Cooldown:         breaker.DefaultCooldown,
+		},
+		Retry: Retry{
+			MaxAttempts:      retry.DefaultMaxAttempts,
+			InitialBackoff:   retry.DefaultInitialBackoff,
+			MaxBackoff:       retry.DefaultMaxBackoff,
+			MaxElapsed:       retry.DefaultMaxElapsed,
+			BudgetMaxTokens:  retry.DefaultBudgetMaxTokens,
+			BudgetTokenRatio: retry.DefaultBudgetTokenRatio,
+		},
+		API: API{
+			MaxBodyBytes:     api.DefaultMaxBodyBytes,
+			MaxUploadBytes:   api.DefaultMaxUploadBytes,
+			MaxAssetBytes:    api.DefaultMaxAssetBytes,
+			AssetTypes:       api.DefaultAssetTypes,
+			StrictJSON:       true,
+			IdempotencyTTL:   idempotency.DefaultTTL,
+			MaxPageSize:      api.DefaultMaxPageSize,
+			MaxBatchItems:    api.DefaultMaxBatchItems,
+			BatchConcurrency: api.DefaultBatchConcurrency,
+			MaxStatusIDs:     api.DefaultMaxStatusIDs,
+		},
+		Cache:       Cache{MaxEntries: cache.DefaultMaxEntries, TTL: cache.DefaultTTL},
+		Tracing:     Tracing{ServiceName: tracing.DefaultServiceName},
+		LoadShed:    LoadShed{MaxInFlight: loadshed.DefaultMaxInFlight},
+		FairQueue:   FairQueue{MaxQueued: fairqueue.DefaultMaxQueued},
+		Maintenance: Maintenance{RetryAfter: maintenance.DefaultRetryAfter},
+		Startup: Startup{
+			MaxWait:        health.DefaultMaxWait,
+			InitialBackoff: health.DefaultInitialBackoff,
+			MaxBackoff:     health.DefaultMaxBackoff,
+			OnTimeout:      StartupExit,
+		},
+		Realtime: Realtime{
+			ClientBuffer:    realtime.DefaultClientBuffer,
+			Overflow:        string(realtime.Disconnect),
+			AuthGrace:       realtime.DefaultAuthGrace,
+			MaxPerIP:        realtime.DefaultMaxConnectionsPerIP,
+			MaxPerPrincipal: realtime.DefaultMaxConnectionsPerPrincipal,
+			PollTimeout:     realtime.DefaultPollTimeout,
+			EventBusBuffer:  jobs.DefaultBusBuffer,
+
+			CompressionLevel:     websocket.DefaultCompressionLevel,
+			CompressionThreshold: websocket.DefaultCompressionThreshold,
+			ContextTakeover:      true,
+
+			MaxMessageBytes: realtime.DefaultMaxMessageBytes,
+		},
+		Queue: Queue{
+			Capacity:  queue.DefaultCapacity,
+			Workers:   queue.DefaultWorkers,
+			RedisKey:  queue.DefaultRedisKey,
+			HighWater: queue.DefaultHighWater,
+		},
+		Webhooks: Webhooks{
+			MaxAttempts:    webhook.DefaultMaxAttempts,
+			InitialBackoff: webhook.DefaultInitialBackoff,
+			MaxBackoff:     webhook.DefaultMaxBackoff,
+			Timeout:        webhook.DefaultTimeout,
+		},
+		ClientTimeout: ClientTimeout{Min: middleware.DefaultMinClientTimeout},
+		Legacy:        Legacy{Prefix: proxy.DefaultPrefix, MaxResponseBytes: proxy.DefaultMaxResponseBytes},
+		SignedURLs:    SignedURLs{TTL: signedurl.DefaultTTL},
+		PublicContent: PublicContent{RPS: api.DefaultPublicRPS, Burst: api.DefaultPublicBurst, AllowedOrigins: []string{"*"}},
+	}
+}
+
+// Error lists every problem found while loading configuration, so an
+// operator can fix them all in one pass.
+type Error struct {
+	Problems []string
+}
+
+func (e *Error) Error() string {
+	return "invalid configuration: " + strings.Join(e.Problems, "; ")
+}
+
+func (e *Error) addf(format string, args ...any) {
+	e.Problems = append(e.Problems, fmt.Sprintf(format, args...))
+}
+
+// Load reads the configuration from CONFIG_FILE (if set) and the process
+// environment. The returned error is an *Error unless the file could not
+// be read or parsed at all.
+func Load() (*Config, error) {
+	return load(os.Getenv("CONFIG_FILE"), os.LookupEnv)
+}
+
+func load(path string, lookup func(string) (string, bool)) (*Config, error) {
+	cfg := Default()
+	errs := &Error{}
+	if path != "" {
+		tree, err := readFile(path)
+		if err != nil {
+			return nil, err
+		}
+		decodeTree(cfg, tree, errs)
+	}
+	applyEnv(cfg, lookup, errs)
+	cfg.validate(errs)
+	if len(errs.Problems) > 0 {
+		return nil, errs
+	}
+	return cfg, nil
+}
+
+// readFile parses a config file into a generic tree. Files ending in .json
+// are JSON; anything else is treated as YAML.
+func readFile(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config file: %w", err)
+	}
+	var tree map[string]any
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		tree, err = parseJSON(data)
+	} else {
+		tree, err = parseYAML(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	return tree, nil
+}
+
+func (c *Config) validate(errs *Error) {
+	if c.Port < 1 || c.Port > 65535 {
+		errs.addf("port: must be between 1 and 65535, got %d", c.Port)
+	}
+	if _, ok := parseLogLevel(c.LogLevel); !ok {
+		errs.addf("log_level: must be debug, info, warn or error, got %q", c.LogLevel)
+	}
+	if f := c.AccessLog.Format; f != middleware.AccessLogJSON && f != middleware.AccessLogCombined {
+		errs.addf("access_log.format: must be json or combined, got %q", f)
+	}
+	if _, ok := parseLogLevel(c.AccessLog.Level); c.AccessLog.Level != "" && !ok {
+		errs.addf("access_log.level: must be debug, info, warn or error, got %q", c.AccessLog.Level)
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs.addf("shutdown_timeout: must be positive")
+	}
+	if c.RequestTimeout <= 0 {
+		errs.addf("request_timeout: must be positive")
+	}
+	if c.SlowRequestThreshold < 0 {
+		errs.addf("slow_request_threshold: must not be negative")
+	}
+	if c.ClientTimeout.Min < 0 || c.ClientTimeout.Max < 0 {
+		errs.addf("client_timeout: min and max must not be negative")
+	} else if c.ClientTimeout.Max > 0 && c.ClientTimeout.Min > c.ClientTimeout.Max {
+		errs.addf("client_timeout.min: must not exceed max, got %v > %v", c.ClientTimeout.Min, c.ClientTimeout.Max)
+	}
+	if _, err := clientip.Parse(c.TrustedProxies); err != nil {
+		errs.addf("trusted_proxies: %v", strings.TrimPrefix(err.Error(), "clientip: "))
+	}
+	if c.MaxRequestBytes < 1 {
+		errs.addf("max_request_bytes: must be at least 1, got %d", c.MaxRequestBytes)
+	}
+	if c.CORS.MaxAge < 0 {
+		errs.addf("cors.max_age: must not be negative, got %v", c.CORS.MaxAge)
+	}
+
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		errs.addf("tls: cert_file and key_file must be set together")
+	}
+	if c.TLS.ReloadInterval <= 0 {
+		errs.addf("tls.reload_interval: must be positive")
+	}
+
+	if strings.TrimSpace(c.Backend.Addr) == "" {
+		errs.addf("backend.addr: must not be empty")
+	} else if eps, err := grpcpool.ParseEndpoints(c.Backend.Addr); err != nil {
+		errs.addf("backend.addr: %v", err)
+	} else if zone := c.Backend.PreferredZone; zone != "" && !slices.ContainsFunc(eps, func(e grpcpool.Endpoint) bool { return e.Zone == zone }) {
+		errs.addf("backend.preferred_zone: no endpoint in backend.addr is in zone %q", zone)
+	}
+	if c.Backend.PoolSize < 1 {
+		errs.addf("backend.pool_size: must be at least 1, got %d", c.Backend.PoolSize)
+	}
+	if c.Backend.DialTimeout <= 0 {
+		errs.addf("backend.dial_timeout: must be positive")
+	}
+	if c.Backend.KeepaliveTime <= 0 {
+		errs.addf("backend.keepalive_time: must be positive")
+	}
+	if c.Backend.KeepaliveTimeout <= 0 {
+		errs.addf("backend.keepalive_timeout: must be positive")
+	}
+	if c.Backend.DeadlineMargin < 0 {
+		errs.addf("backend.deadline_margin: must not be negative, got %v", c.Backend.DeadlineMargin)
+	}
+	if c.Backend.MaxResponseBytes < 1 {
+		errs.addf("backend.max_response_bytes: must be at least 1, got %d", c.Backend.MaxResponseBytes)
+	}
+	if c.Backend.HealthCheckInterval <= 0 {
+		errs.addf("backend.health_check_interval: must be positive")
+	}
+	limited := make(map[string]bool, len(c.Backend.MethodLimits))
+	for i, ml := range c.Backend.MethodLimits {
+		path := fmt.Sprintf("backend.method_limits[%d]", i)
+		if _, ok := backend.MethodNamed(ml.Method); !ok {
+			errs.addf("%s.method: unknown method %q", path, ml.Method)
+		} else if limited[ml.Method] {
+			errs.addf("%s.method: duplicate method %q", path, ml.Method)
+		}
+		limited[ml.Method] = true
+		if ml.MaxInFlight < 1 {
+			errs.addf("%s.max_in_flight: must be at least 1, got %d", path, ml.MaxInFlight)
+		}
+		if ml.MaxQueued < 0 || ml.MaxWait < 0 {
+			errs.addf("%s: max_queued and max_wait must not be negative", path)
+		}
+	}
+
+	switch strings.ToUpper(c.Auth.JWTAlgorithm) {
+	case "":
+	case auth.HS256:
+		if c.Auth.JWTSecret == "" {
+			errs.addf("auth.jwt_secret: required when jwt_algorithm is HS256")
+		}
+	case auth.RS256:
+		if c.Auth.JWTPublicKeyFile == "" {
+			errs.addf("auth.jwt_public_key_file: required when jwt_algorithm is RS256")
+		}
+	default:
+		errs.addf("auth.jwt_algorithm: must be HS256 or RS256, got %q", c.Auth.JWTAlgorithm)
+	}
+	if iss := c.Auth.OIDCIssuer; iss != "" {
+		if u, err := url.Parse(iss); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs.addf("auth.oidc_issuer: must be an http or https URL, got %q", iss)
+		}
+		if c.Auth.OIDCAudience == "" {
+			errs.addf("auth.oidc_audience: required when oidc_issuer is set")
+		}
+		if c.Auth.JWTSecret != "" || c.Auth.JWTPublicKeyFile != "" {
+			errs.addf("auth.oidc_issuer: cannot be combined with jwt_secret or jwt_public_key_file")
+		}
+	}
+	if c.Auth.OIDCRefreshInterval <= 0 {
+		errs.addf("auth.oidc_refresh_interval: must be positive")
+	}
+
+	ids := make(map[string]bool, len(c.Auth.APIKeys))
+	for i, k := range c.Auth.APIKeys {
+		path := fmt.Sprintf("auth.api_keys[%d]", i)
+		if k.ID == "" {
+			errs.addf("%s.id: must not be empty", path)
+		} else if ids[k.ID] {
+			errs.addf("%s.id: duplicate id %q", path, k.ID)
+		}
+		ids[k.ID] = true
+		if len(k.Key) < 16 {
+			errs.addf("%s.key: must be at least 16 characters", path)
+		}
+		if k.Principal == "" {
+			errs.addf("%s.principal: must not be empty", path)
+		}
+		if k.RPS < 0 || k.Burst < 0 {
+			errs.addf("%s: rps and burst must not be negative", path)
+		}
+		if k.Tenant != "" && !slices.ContainsFunc(c.Tenants, func(t Tenant) bool { return t.ID == k.Tenant }) {
+			errs.addf("%s.tenant: no tenant has id %q", path, k.Tenant)
+		}
+	}
+
+	ids = make(map[string]bool, len(c.Auth.SigningKeys))
+	for i, k := range c.Auth.SigningKeys {
+		path := fmt.Sprintf("auth.signing_keys[%d]", i)
+		if k.ID == "" {
+			errs.addf("%s.id: must not be empty", path)
+		} else if ids[k.ID] {
+			errs.addf("%s.id: duplicate id %q", path, k.ID)
+		}
+		ids[k.ID] = true
+		if n := len(k.Secret); n < minSigningSecret {
+			errs.addf("%s.secret: must be at least %d bytes, got %d", path, minSigningSecret, n)
+		}
+		if k.Principal == "" {
+			errs.addf("%s.principal: must not be empty", path)
+		}
+		if k.Tenant != "" && !slices.ContainsFunc(c.Tenants, func(t Tenant) bool { return t.ID == k.Tenant }) {
+			errs.addf("%s.tenant: no tenant has id %q", path, k.Tenant)
+		}
+	}
+	if c.Auth.SignatureMaxClockSkew <= 0 {
+		errs.addf("auth.signature_max_clock_skew: must be positive")
+	}
+
+	tenants := make(map[string]bool, len(c.Tenants))
+	for i, t := range c.Tenants {
+		path := fmt.Sprintf("tenants[%d]", i)
+		if !validTenantID(t.ID) {
+			errs.addf("%s.id: must be 1 to 64 lowercase letters, digits, hyphens and underscores, got %q", path, t.ID)
+		} else if tenants[t.ID] {
+			errs.addf("%s.id: duplicate id %q", path, t.ID)
+		}
+		tenants[t.ID] = true
+		if t.RPS < 0 || t.Burst < 0 {
+			errs.addf("%s: rps and burst must not be negative", path)
+		}
+		for _, f := range t.Formats {
+			if !slices.Contains(api.Formats, f) {
+				errs.addf("%s.formats: unknown format %q, want %s", path, f, strings.Join(api.Formats, ", "))
+			}
+		}
+		if t.BackendAddr != "" {
+			if _, err := grpcpool.ParseEndpoints(t.BackendAddr); err != nil {
+				errs.addf("%s.backend_addr: %v", path, err)
+			}
+		}
+	}
+	if d := c.DefaultTenant; d != "" && !tenants[d] {
+		errs.addf("default_tenant: no tenant has id %q", d)
+	}
+
+	matches := make(map[string]bool, len(c.Routes))
+	for i, rt := range c.Routes {
+		path := fmt.Sprintf("routes[%d]", i)
+		method, pattern, hasMethod := rt.split()
+		switch key := routeconf.Key(method, pattern); {
+		case !strings.HasPrefix(pattern, "/") || (hasMethod && (method == "" || method != strings.ToUpper(method))):
+			errs.addf("%s.match: must be a route template like /api/v1/jobs/{id}, optionally after a method, got %q", path, rt.Match)
+		case matches[key]:
+			errs.addf("%s.match: duplicate route %q", path, rt.Match)
+		default:
+			matches[key] = true
+		}
+		if rt.Timeout < 0 || rt.MaxBodyBytes < 0 || rt.RPS < 0 || rt.Burst < 0 || rt.Weight < 0 || rt.CoalesceWindow < 0 || rt.StaleIfError < 0 {
+			errs.addf("%s: timeout, rate_limit_rps, rate_limit_burst, rate_limit_weight, max_body_bytes, coalesce_window and stale_if_error must not be negative", path)
+		}
+		if rt.StaleIfError > 0 && hasMethod && method != http.MethodGet {
+			errs.addf("%s.stale_if_error: applies to GET routes only, got %s", path, method)
+		}
+		if (rt.RPS > 0) != (rt.Burst > 0) {
+			errs.addf("%s: rate_limit_rps and rate_limit_burst must be set together", path)
+		}
+	}
+
+	st := c.Server
+	if st.ReadHeaderTimeout < 0 || st.ReadTimeout < 0 || st.WriteTimeout < 0 || st.IdleTimeout < 0 {
+		errs.addf("server: read_header_timeout, read_timeout, write_timeout and idle_timeout must not be negative")
+	}
+	if st.ReadTimeout > 0 && st.ReadHeaderTimeout > st.ReadTimeout {
+		errs.addf("server.read_header_timeout: must not exceed read_timeout, got %v > %v", st.ReadHeaderTimeout, st.ReadTimeout)
+	}
+	if st.H2C && c.TLS.Enabled() {
+		errs.addf("server.h2c: is HTTP/2 without TLS and cannot be used with tls, which serves HTTP/2 already")
+	}
+
+	if c.RateLimit.RPS <= 0 {
+		errs.addf("rate_limit.rps: must be positive, got %g", c.RateLimit.RPS)
+	}
+	if c.RateLimit.Burst < 1 {
+		errs.addf("rate_limit.burst: must be at least 1, got %d", c.RateLimit.Burst)
+	}
+
+	if c.Breaker.FailureThreshold < 1 {
+		errs.addf("breaker.failure_threshold: must be at least 1, got %d", c.Breaker.FailureThreshold)
+	}
+	if c.Breaker.Cooldown <= 0 {
+		errs.addf("breaker.cooldown: must be positive")
+	}
+
+	if c.Retry.MaxAttempts < 1 {
+		errs.addf("retry.max_attempts: must be at least 1, got %d", c.Retry.MaxAttempts)
+	}
+	if c.Retry.InitialBackoff <= 0 {
+		errs.addf("retry.initial_backoff: must be positive")
+	}
+	if c.Retry.MaxBackoff < c.Retry.InitialBackoff {
+		errs.addf("retry.max_backoff: must not be less than initial_backoff")
+	}
+	if c.Retry.MaxElapsed <= 0 {
+		errs.addf("retry.max_elapsed: must be positive")
+	}
+	if c.Retry.BudgetMaxTokens < 0 {
+		errs.addf("retry.budget_max_tokens: must not be negative, got %d", c.Retry.BudgetMaxTokens)
+	}
+	if c.Retry.BudgetTokenRatio <= 0 || c.Retry.BudgetTokenRatio > 1 {
+		errs.addf("retry.budget_token_ratio: must be greater than 0 and at most 1, got %g", c.Retry.BudgetTokenRatio)
+	}
+
+	if c.API.MaxBodyBytes < 1 {
+		errs.addf("api.max_body_bytes: must be at least 1, got %d", c.API.MaxBodyBytes)
+	}
+	if c.API.MaxUploadBytes < 1 {
+		errs.addf("api.max_upload_bytes: must be at least 1, got %d", c.API.MaxUploadBytes)
+	}
+	if c.API.MaxAssetBytes < 1 || c.API.MaxAssetBytes > c.API.MaxUploadBytes {
+		errs.addf("api.max_asset_bytes: must be between 1 and max_upload_bytes, got %d", c.API.MaxAssetBytes)
+	}
+	for _, t := range c.API.AssetTypes {
+		if _, _, err := mime.ParseMediaType(t); err != nil || strings.Contains(t, ";") {
+			errs.addf("api.asset_types: %q is not a media type", t)
+		}
+	}
+	if c.API.IdempotencyTTL <= 0 {
+		errs.addf("api.idempotency_ttl: must be positive")
+	}
+	if c.API.MaxPageSize < 1 {
+		errs.addf("api.max_page_size: must be at least 1, got %d", c.API.MaxPageSize)
+	}
+	// Zero is api.DefaultPageSize, or max_page_size if that is smaller.
+	if c.API.DefaultPageSize < 0 || c.API.DefaultPageSize > c.API.MaxPageSize {
+		errs.addf("api.default_page_size: must be between 0 and max_page_size, got %d", c.API.DefaultPageSize)
+	}
+	if c.API.MaxBatchItems < 1 {
+		errs.addf("api.max_batch_items: must be at least 1, got %d", c.API.MaxBatchItems)
+	}
+	if c.API.BatchConcurrency < 1 {
+		errs.addf("api.batch_concurrency: must be at least 1, got %d", c.API.BatchConcurrency)
+	}
+	if c.API.MaxStatusIDs < 1 {
+		errs.addf("api.max_status_ids: must be at least 1, got %d", c.API.MaxStatusIDs)
+	}
+	if c.Compress.MinSize < 0 {
+		errs.addf("compress.min_size: must not be negative, got %d", c.Compress.MinSize)
+	}
+
+	if c.Cache.MaxEntries < 0 {
+		errs.addf("cache.max_entries: must not be negative, got %d", c.Cache.MaxEntries)
+	}
+	if c.Cache.TTL <= 0 {
+		errs.addf("cache.ttl: must be positive")
+	}
+
+	if e := c.Tracing.Endpoint; e != "" {
+		if u, err := url.Parse(e); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs.addf("tracing.endpoint: must be an http or https URL, got %q", e)
+		}
+	}
+	if c.Tracing.ServiceName == "" {
+		errs.addf("tracing.service_name: must not be empty")
+	}
+
+	if c.Maintenance.RetryAfter < time.Second {
+		errs.addf("maintenance.retry_after: must be at least 1s, got %s", c.Maintenance.RetryAfter)
+	}
+	// It is the close reason of drained WebSocket streams.
+	if len(c.Maintenance.Message) > 123 {
+		errs.addf("maintenance.message: must be at most 123 bytes, got %d", len(c.Maintenance.Message))
+	}
+	if c.LoadShed.MaxInFlight < 0 {
+		errs.addf("load_shed.max_in_flight: must not be negative, got %d", c.LoadShed.MaxInFlight)
+	}
+	if c.FairQueue.MaxInFlight < 0 {
+		errs.addf("fair_queue.max_in_flight: must not be negative, got %d", c.FairQueue.MaxInFlight)
+	}
+	if c.FairQueue.MaxQueued < 1 {
+		errs.addf("fair_queue.max_queued_per_principal: must be at least 1, got %d", c.FairQueue.MaxQueued)
+	}
+
+	if c.Realtime.ClientBuffer < 1 {
+		errs.addf("realtime.client_buffer: must be at least 1, got %d", c.Realtime.ClientBuffer)
+	}
+	if c.Realtime.EventBusBuffer < 1 {
+		errs.addf("realtime.event_bus_buffer: must be at least 1, got %d", c.Realtime.EventBusBuffer)
+	}
+	if c.Realtime.AuthGrace <= 0 {
+		errs.addf("realtime.auth_grace: must be positive")
+	}
+	if c.Realtime.PollTimeout <= 0 {
+		errs.addf("realtime.poll_timeout: must be positive")
+	}
+	if c.Realtime.MaxPerIP < 0 {
+		errs.addf("realtime.max_connections_per_ip: must not be negative, got %d", c.Realtime.MaxPerIP)
+	}
+	if c.Realtime.MaxPerPrincipal < 0 {
+		errs.addf("realtime.max_connections_per_principal: must not be negative, got %d", c.Realtime.MaxPerPrincipal)
+	}
+	switch realtime.OverflowPolicy(c.Realtime.Overflow) {
+	case realtime.Disconnect, realtime.DropOldest:
+	default:
+		errs.addf("realtime.overflow_policy: must be disconnect or drop_oldest, got %q", c.Realtime.Overflow)
+	}
+	if c.Realtime.CompressionLevel < 1 || c.Realtime.CompressionLevel > 9 {
+		errs.addf("realtime.ws_compression_level: must be between 1 and 9, got %d", c.Realtime.CompressionLevel)
+	}
+	if c.Realtime.MaxMessageBytes < 1 {
+		errs.addf("realtime.ws_max_message_bytes: must be at least 1, got %d", c.Realtime.MaxMessageBytes)
+	}
+	if c.Realtime.CompressionThreshold < 0 {
+		errs.addf("realtime.ws_compression_threshold: must not be negative, got %d", c.Realtime.CompressionThreshold)
+	}
+
+	if c.Startup.MaxWait <= 0 || c.Startup.InitialBackoff <= 0 || c.Startup.MaxBackoff <= 0 {
+		errs.addf("startup: max_wait, initial_backoff and max_backoff must be positive")
+	} else if c.Startup.InitialBackoff > c.Startup.MaxBackoff {
+		errs.addf("startup.initial_backoff: must not exceed max_backoff, got %v > %v", c.Startup.InitialBackoff, c.Startup.MaxBackoff)
+	}
+	switch c.Startup.OnTimeout {
+	case StartupExit, StartupDegraded:
+	default:
+		errs.addf("startup.on_timeout: must be exit or degraded, got %q", c.Startup.OnTimeout)
+	}
+
+	switch c.Queue.Backend {
+	case "", queue.BackendMemory:
+	case queue.BackendRedis:
+		if c.RedisURL == "" {
+			errs.addf("queue.backend: redis requires redis_url")
+		}
+		if c.Queue.RedisKey == "" {
+			errs.addf("queue.redis_key: must not be empty")
+		}
+	default:
+		errs.addf("queue.backend: must be memory or redis, got %q", c.Queue.Backend)
+	}
+	if c.Queue.Capacity < 1 {
+		errs.addf("queue.capacity: must be at least 1, got %d", c.Queue.Capacity)
+	}
+	if c.Queue.Workers < 1 {
+		errs.addf("queue.workers: must be at least 1, got %d", c.Queue.Workers)
+	}
+	if c.Queue.HighWater <= 0 || c.Queue.HighWater > 1 {
+		errs.addf("queue.high_water: must be greater than 0 and at most 1, got %g", c.Queue.HighWater)
+	}
+
+	if c.Webhooks.MaxAttempts < 1 {
+		errs.addf("webhooks.max_attempts: must be at least 1, got %d", c.Webhooks.MaxAttempts)
+	}
+	if c.Webhooks.InitialBackoff <= 0 {
+		errs.addf("webhooks.initial_backoff: must be positive")
+	}
+	if c.Webhooks.MaxBackoff < c.Webhooks.InitialBackoff {
+		errs.addf("webhooks.max_backoff: must not be less than initial_backoff")
+	}
+	if c.Webhooks.Timeout <= 0 {
+		errs.addf("webhooks.timeout: must be positive")
+	}
+	principals := make(map[string]bool, len(c.Webhooks.Secrets))
+	for i, ws := range c.Webhooks.Secrets {
+		path := fmt.Sprintf("webhooks.secrets[%d]", i)
+		if ws.Principal == "" {
+			errs.addf("%s.principal: must not be empty", path)
+		} else if principals[ws.Principal] {
+			errs.addf("%s.principal: duplicate principal %q", path, ws.Principal)
+		}
+		principals[ws.Principal] = true
+		if len(ws.Secret) < 16 {
+			errs.addf("%s.secret: must be at least 16 characters", path)
+		}
+	}
+
+	names := make(map[string]bool, len(c.FeatureFlags))
+	for i, f := range c.FeatureFlags {
+		path := fmt.Sprintf("feature_flags[%d]", i)
+		if !validFlagName(f.Name) {
+			errs.addf("%s.name: must be lowercase letters, digits and underscores, got %q", path, f.Name)
+		} else if names[f.Name] {
+			errs.addf("%s.name: duplicate flag %q", path, f.Name)
+		}
+		names[f.Name] = true
+		if f.Percent < 0 || f.Percent > 100 {
+			errs.addf("%s.percent: must be between 0 and 100, got %v", path, f.Percent)
+		}
+	}
+
+	checkHeaderRules(errs, "headers.request", c.Headers.Request, false)
+	checkHeaderRules(errs, "headers.response", c.Headers.Response, true)
+	checkHeaderRules(errs, "legacy.request_headers", c.Legacy.RequestHeaders, false)
+	checkHeaderRules(errs, "legacy.response_headers", c.Legacy.ResponseHeaders, true)
+
+	if u := c.Legacy.URL; u != "" {
+		if parsed, err := url.Parse(u); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			errs.addf("legacy.url: must be an http or https URL, got %q", u)
+		}
+	}
+	if p := c.Legacy.Prefix; len(p) < 3 || !strings.HasPrefix(p, "/") || !strings.HasSuffix(p, "/") {
+		errs.addf("legacy.prefix: must be a path like /legacy/, got %q", p)
+	}
+	if c.Legacy.MaxResponseBytes < 1 {
+		errs.addf("legacy.max_response_bytes: must be at least 1, got %d", c.Legacy.MaxResponseBytes)
+	}
+
+	if n := len(c.SignedURLs.Secret); n > 0 && n < minSignedURLSecret {
+		errs.addf("signed_urls.secret: must be at least %d bytes, got %d", minSignedURLSecret, n)
+	}
+	if c.SignedURLs.TTL <= 0 {
+		errs.addf("signed_urls.ttl: must be positive")
+	}
+	if u := c.SignedURLs.BaseURL; u != "" {
+		if parsed, err := url.Parse(u); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			errs.addf("signed_urls.base_url: must be an http or https URL, got %q", u)
+		}
+	}
+
+	if c.PublicContent.RPS <= 0 {
+		errs.addf("public_content.rps: must be positive, got %g", c.PublicContent.RPS)
+	}
+	if c.PublicContent.Burst < 1 {
+		errs.addf("public_content.burst: must be at least 1, got %d", c.PublicContent.Burst)
+	}
+	// A tenant's public links carry the tenant, signed with the signed URL
+	// key, to reach the tenant's content service.
+	if c.PublicContent.Enabled && len(c.Tenants) > 0 && c.SignedURLs.Secret == "" {
+		errs.addf("public_content.enabled: tenants need signed_urls.secret to sign their public links")
+	}
+}
+
+// checkHeaderRules validates the header rules at path, for responses if
+// response is set.
+func checkHeaderRules(errs *Error, path string, rules []HeaderRule, response bool) {
+	for i, r := range toHeaderRules(rules) {
+		if err := r.Check(response); err != nil {
+			errs.addf("%s[%d]: %v", path, i, err)
+		}
+	}
+}
+
+// validFlagName reports whether name is a usable feature flag name, like
+// new_summarizer.
+func validFlagName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// validTenantID reports whether id is a usable tenant ID, like acme-media.
+// IDs hold no colons, which separate them in rate limit keys.
+func validTenantID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' && c != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// AccessLogLevel returns AccessLog.Level as a slog level, and false if it
+// is unset and the request log follows LogLevel.
+func (c *Config) AccessLogLevel() (slog.Level, bool) {
+	if c.AccessLog.Level == "" {
+		return 0, false
+	}
+	level, _ := parseLogLevel(c.AccessLog.Level)
+	return level, true
+}
+
+// SlogLevel returns LogLevel as a slog level.
+func (c *Config) SlogLevel() slog.Level {
+	level, _ := parseLogLevel(c.LogLevel)
+	return level
+}
+
+func parseLogLevel(v string) (slog.Level, bool) {
+	switch strings.ToLower(v) {
+	case "debug":
+		return slog.LevelDebug, true
+	case "", "info":
+		return slog.LevelInfo, true
+	case "warn", "warning":
+		return slog.LevelWarn, true
+	case "error":
+		return slog.LevelError, true
+	}
+	return slog.LevelInfo, false
+}
+
+// PoolConfig returns the backend connection pool settings.
+func (c *Config) PoolConfig() grpcpool.Config {
+	return grpcpool.Config{
+		Addr:                c.Backend.Addr,
+		Size:                c.Backend.PoolSize,
+		DialTimeout:         c.Backend.DialTimeout,
+		KeepaliveTime:       c.Backend.KeepaliveTime,
+		KeepaliveTimeout:    c.Backend.KeepaliveTimeout,
+		DeadlineMargin:      c.Backend.DeadlineMargin,
+		MaxResponseBytes:    c.Backend.MaxResponseBytes,
+		PreferredZone:       c.Backend.PreferredZone,
+		HealthCheckInterval: c.Backend.HealthCheckInterval,
+	}
+}
+
+// MethodLimits returns the concurrency limits of backend methods, by
+// fully-qualified method name, or nil if no method is limited.
+func (c *Config) MethodLimits() map[string]methodlimit.Limit {
+	if len(c.Backend.MethodLimits) == 0 {
+		return nil
+	}
+	limits := make(map[string]methodlimit.Limit, len(c.Backend.MethodLimits))
+	for _, ml := range c.Backend.MethodLimits {
+		method, _ := backend.MethodNamed(ml.Method)
+		limits[method] = methodlimit.Limit{MaxInFlight: ml.MaxInFlight, MaxQueued: ml.MaxQueued, MaxWait: ml.MaxWait}
+	}
+	return limits
+}
+
+// AuthConfig returns the bearer token verifier settings.
+func (c *Config) AuthConfig() auth.Config {
+	return auth.Config{
+		Algorithm:           c.Auth.JWTAlgorithm,
+		Secret:              c.Auth.JWTSecret,
+		PublicKeyFile:       c.Auth.JWTPublicKeyFile,
+		OIDCIssuer:          c.Auth.OIDCIssuer,
+		OIDCAudience:        c.Auth.OIDCAudience,
+		OIDCRefreshInterval: c.Auth.OIDCRefreshInterval,
+	}
+}
+
+// APIKeys returns the configured API keys.
+func (c *Config) APIKeys() []auth.APIKey {
+	keys := make([]auth.APIKey, len(c.Auth.APIKeys))
+	for i, k := range c.Auth.APIKeys {
+		keys[i] = auth.APIKey{
+			ID:        k.ID,
+			Key:       k.Key,
+			Principal: k.Principal,
+			Scopes:    k.Scopes,
+			Enabled:   k.Enabled,
+			RPS:       k.RPS,
+			Burst:     k.Burst,
+			Tenant:    k.Tenant,
+		}
+	}
+	return keys
+}
+
+// SigningKeys returns the configured signing keys.
+func (c *Config) SigningKeys() []auth.SigningKey {
+	keys := make([]auth.SigningKey, len(c.Auth.SigningKeys))
+	for i, k := range c.Auth.SigningKeys {
+		keys[i] = auth.SigningKey{
+			ID:        k.ID,
+			Secret:    k.Secret,
+			Principal: k.Principal,
+			Scopes:    k.Scopes,
+			Enabled:   k.Enabled,
+			Tenant:    k.Tenant,
+		}
+	}
+	return keys
+}
+
+// TenantList returns the tenants, without their backends.
+func (c *Config) TenantList() []tenant.Tenant {
+	out := make([]tenant.Tenant, len(c.Tenants))
+	for i, t := range c.Tenants {
+		out[i] = tenant.Tenant{
+			ID:      t.ID,
+			Enabled: t.Enabled,
+			RPS:     t.RPS,
+			Burst:   t.Burst,
+			Formats: t.Formats,
+			Metrics: t.Metrics,
+		}
+	}
+	return out
+}
+
+// TenantPoolConfig returns the connection pool settings of the content
+// service of tenant t, which has a backend_addr.
+func (c *Config) TenantPoolConfig(t Tenant) grpcpool.Config {
+	cfg := c.PoolConfig()
+	cfg.Addr = t.BackendAddr
+	return cfg
+}
+
+// minSignedURLSecret is the shortest signed_urls.secret accepted, so the
+// URLs' signatures cannot be forged by guessing it.
+const minSignedURLSecret = 32
+
+// minSigningSecret is the shortest auth.signing_keys secret accepted, for
+// the same reason.
+const minSigningSecret = 32
+
+// SignedURLConfig returns the signed URL settings, and false if signed
+// URLs are off.
+func (c *Config) SignedURLConfig() (signedurl.Config, bool) {
+	return signedurl.Config{
+		Key:     []byte(c.SignedURLs.Secret),
+		TTL:     c.SignedURLs.TTL,
+		BaseURL: c.SignedURLs.BaseURL,
+	}, c.SignedURLs.Secret != ""
+}
+
+// WebhookSecrets returns the webhook signing secrets by principal, or nil
+// if there are none and webhooks are off.
+func (c *Config) WebhookSecrets() map[string]string {
+	if len(c.Webhooks.Secrets) == 0 {
+		return nil
+	}
+	secrets := make(map[string]string, len(c.Webhooks.Secrets))
+	for _, ws := range c.Webhooks.Secrets {
+		secrets[ws.Principal] = ws.Secret
+	}
+	return secrets
+}
+
+// Flags returns the feature flags.
+func (c *Config) Flags() []flags.Flag {
+	out := make([]flags.Flag, len(c.FeatureFlags))
+	for i, f := range c.FeatureFlags {
+		out[i] = flags.Flag{
+			Name:        f.Name,
+			Enabled:     f.Enabled,
+			Percent:     f.Percent,
+			Principals:  f.Principals,
+			AllowHeader: f.AllowHeader,
+		}
+	}
+	return out
+}
+
+// HeaderRules returns the rules rewriting the headers of every request
+// and response.
+func (c *Config) HeaderRules() (request, response []headers.Rule) {
+	return toHeaderRules(c.Headers.Request), toHeaderRules(c.Headers.Response)
+}
+
+// LegacyHeaderRules returns the rules rewriting the headers of requests to
+// and responses from the legacy backend.
+func (c *Config) LegacyHeaderRules() (request, response []headers.Rule) {
+	return toHeaderRules(c.Legacy.RequestHeaders), toHeaderRules(c.Legacy.ResponseHeaders)
+}
+
+func toHeaderRules(rules []HeaderRule) []headers.Rule {
+	if len(rules) == 0 {
+		return nil
+	}
+	out := make([]headers.Rule, len(rules))
+	for i, r := range rules {
+		out[i] = headers.Rule{Action: headers.Action(r.Action), Name: r.Name, Value: r.Value}
+	}
+	return out
+}
+
+// RateLimitConfig returns the rate limiter settings.
+func (c *Config) RateLimitConfig() ratelimit.Config {
+	return ratelimit.Config{
+		RPS:     c.RateLimit.RPS,
+		Burst:   c.RateLimit.Burst,
+		IdleTTL: ratelimit.DefaultIdleTTL,
+		Routes:  c.RouteTable(),
+	}
+}
+
+// PublicContentRateLimitConfig returns the settings of the rate limiter
+// of public content reads. Route overrides apply to them as to the rest.
+func (c *Config) PublicContentRateLimitConfig() ratelimit.Config {
+	return ratelimit.Config{
+		RPS:     c.PublicContent.RPS,
+		Burst:   c.PublicContent.Burst,
+		IdleTTL: ratelimit.DefaultIdleTTL,
+		Routes:  c.RouteTable(),
+	}
+}
+
+// TrustedProxyRanges returns the ranges of the trusted proxies, all
+// addresses under the older rate_limit.trust_proxy.
+func (c *Config) TrustedProxyRanges() []string {
+	if len(c.TrustedProxies) == 0 && c.RateLimit.TrustProxy {
+		return clientip.TrustAll
+	}
+	return c.TrustedProxies
+}
+
+// ServerProtocols returns the protocols the HTTP server speaks: HTTP/1.1,
+// and HTTP/2 over TLS or, with h2c, without it.
+func (c *Config) ServerProtocols() *http.Protocols {
+	p := new(http.Protocols)
+	p.SetHTTP1(true)
+	p.SetHTTP2(c.TLS.Enabled())
+	p.SetUnencryptedHTTP2(c.Server.H2C)
+	return p
+}
+
+// WebSocketCompression returns the permessage-deflate settings of job
+// event WebSockets, or nil if compression is off.
+func (c *Config) WebSocketCompression() *websocket.Compression {
+	if !c.Realtime.Compression {
+		return nil
+	}
+	return &websocket.Compression{
+		Level:             c.Realtime.CompressionLevel,
+		Threshold:         c.Realtime.CompressionThreshold,
+		NoContextTakeover: !c.Realtime.ContextTakeover,
+	}
+}
+
+// ServerTimeouts returns the connection timeouts of the HTTP server.
+func (c *Config) ServerTimeouts() middleware.ServerTimeouts {
+	return middleware.ServerTimeouts{
+		ReadHeader: c.Server.ReadHeaderTimeout,
+		Read:       c.Server.ReadTimeout,
+		Write:      c.Server.WriteTimeout,
+		Idle:       c.Server.IdleTimeout,
+	}
+}
+
+// RouteTable returns the per-route overrides.
+func (c *Config) RouteTable() routeconf.Table {
+	if len(c.Routes) == 0 {
+		return nil
+	}
+	t := make(routeconf.Table, len(c.Routes))
+	for _, rt := range c.Routes {
+		method, pattern, _ := rt.split()
+		t[routeconf.Key(method, pattern)] = routeconf.Override{
+			Timeout:        rt.Timeout,
+			RPS:            rt.RPS,
+			Burst:          rt.Burst,
+			Weight:         rt.Weight,
+			MaxBodyBytes:   rt.MaxBodyBytes,
+			SlowThreshold:  rt.SlowThreshold,
+			CoalesceWindow: rt.CoalesceWindow,
+			StaleIfError:   rt.StaleIfError,
+		}
+	}
+	return t
+}
+
+// CORSConfig returns the CORS policy.
+func (c *Config) CORSConfig() cors.Config {
+	methods := make([]string, len(c.CORS.AllowedMethods))
+	for i, m := range c.CORS.AllowedMethods {
+		methods[i] = strings.ToUpper(m)
+	}
+	return cors.Config{
+		AllowedOrigins:   c.CORS.AllowedOrigins,
+		AllowedMethods:   methods,
+		AllowedHeaders:   c.CORS.AllowedHeaders,
+		ExposedHeaders:   cors.DefaultExposedHeaders,
+		AllowCredentials: c.CORS.AllowCredentials,
+		MaxAge:           c.CORS.MaxAge,
+	}
+}
+
+// PublicContentCORSConfig returns the CORS policy of public content
+// reads, which browsers make without credentials from the pages embedding
+// them.
+func (c *Config) PublicContentCORSConfig() cors.Config {
+	return cors.Config{
+		AllowedOrigins: c.PublicContent.AllowedOrigins,
+		AllowedMethods: []string{http.MethodGet, http.MethodHead},
+		AllowedHeaders: []string{"Accept", "If-None-Match", "X-Request-ID"},
+		ExposedHeaders: []string{"ETag", "Retry-After", "X-Request-ID"},
+		MaxAge:         c.CORS.MaxAge,
+	}
+}
+
+// BreakerConfig returns the circuit breaker settings.
+func (c *Config) BreakerConfig() breaker.Config {
+	return breaker.Config{
+		FailureThreshold: c.Breaker.FailureThreshold,
+		Cooldown:         c.Breaker.Cooldown,
+	}
+}
+
+// FairQueueConfig returns the fair scheduler settings.
+func (c *Config) FairQueueConfig() fairqueue.Config {
+	return fairqueue.Config{
+		MaxInFlight: c.FairQueue.MaxInFlight,
+		MaxQueued:   c.FairQueue.MaxQueued,
+	}
+}
+
+// StartupWaitConfig returns how long, and how often, to check the backend
+// at startup.
+func (c *Config) StartupWaitConfig() health.WaitConfig {
+	return health.WaitConfig{
+		MaxWait:        c.Startup.MaxWait,
+		InitialBackoff: c.Startup.InitialBackoff,
+		MaxBackoff:     c.Startup.MaxBackoff,
+	}
+}
+
+// RetryConfig returns the backend retry settings. The retry budget, which
+// is shared, is left to the caller to create with RetryBudget and set.
+func (c *Config) RetryConfig() retry.Config {
+	return retry.Config{
+		MaxAttempts:    c.Retry.MaxAttempts,
+		InitialBackoff: c.Retry.InitialBackoff,
+		MaxBackoff:     c.Retry.MaxBackoff,
+		MaxElapsed:     c.Retry.MaxElapsed,
+	}
+}
+
+// RetryBudget returns a new retry budget as configured, or nil if it is
+// turned off. The process should create one and share it.
+func (c *Config) RetryBudget() *retry.Budget {
+	return retry.NewBudget(c.Retry.BudgetMaxTokens, c.Retry.BudgetTokenRatio)
+}
@@ -0,0 +1,981 @@
+package config
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/auth"
+	"github.com/content-factory/go-gateway/internal/backend"
+	"github.com/content-factory/go-gateway/internal/fairqueue"
+	"github.com/content-factory/go-gateway/internal/headers"
+	"github.com/content-factory/go-gateway/internal/health"
+	"github.com/content-factory/go-gateway/internal/middleware"
+	"github.com/content-factory/go-gateway/internal/retry"
+	"github.com/content-factory/go-gateway/internal/signedurl"
+	"github.com/content-factory/go-gateway/internal/websocket"
+)
+
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDefaults(t *testing.T) {
+	cfg, err := load("", env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != 8080 || cfg.Backend.Addr != "orchestrator:50051" || cfg.RateLimit.Burst != 20 {
+		t.Errorf("defaults = %+v", cfg)
+	}
+}
+
+func TestPrecedence(t *testing.T) {
+	path := writeFile(t, "gateway.yaml", `
+port: 9000
+log_level: debug
+backend:
+  addr: file-backend:50051   # overridden below
+  pool_size: 8
+cors:
+  allowed_origins:
+    - https://app.example.com
+    - "https://admin.example.com"
+`)
+	cfg, err := load(path, env(map[string]string{
+		"CONTENT_SERVICE_ADDR": "env-backend:50051",
+		"SHUTDOWN_TIMEOUT":     "45s",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != 9000 || cfg.LogLevel != "debug" || cfg.Backend.PoolSize != 8 {
+		t.Errorf("file values not applied: %+v", cfg)
+	}
+	if cfg.Backend.Addr != "env-backend:50051" {
+		t.Errorf("backend.addr = %q, want env override", cfg.Backend.Addr)
+	}
+	if cfg.ShutdownTimeout != 45*time.Second {
+		t.Errorf("shutdown_timeout = %v", cfg.ShutdownTimeout)
+	}
+	if got := cfg.CORS.AllowedOrigins; len(got) != 2 || got[1] != "https://admin.example.com" {
+		t.Errorf("allowed_origins = %v", got)
+	}
+	if cfg.RateLimit.RPS != 10 {
+		t.Errorf("untouched default changed: rps = %v", cfg.RateLimit.RPS)
+	}
+}
+
+func TestJSONFile(t *testing.T) {
+	path := writeFile(t, "gateway.json", `{"port": 7000, "rate_limit": {"rps": 2.5, "trust_proxy": true}, "breaker": {"cooldown": "1m"}}`)
+	cfg, err := load(path, env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != 7000 || cfg.RateLimit.RPS != 2.5 || !cfg.RateLimit.TrustProxy || cfg.Breaker.Cooldown != time.Minute {
+		t.Errorf("cfg = %+v", cfg)
+	}
+	if got := cfg.TrustedProxyRanges(); len(got) != 2 || got[0] != "0.0.0.0/0" {
+		t.Errorf("trust_proxy without trusted_proxies: ranges = %v, want every address", got)
+	}
+}
+
+func TestEnvFallback(t *testing.T) {
+	cfg, err := load("", env(map[string]string{"PYTHON_ORCHESTRATOR_ADDR": "legacy:50051"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Backend.Addr != "legacy:50051" {
+		t.Errorf("addr = %q", cfg.Backend.Addr)
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	path := writeFile(t, "gateway.yaml", `
+auth:
+  api_keys:
+    - id: partner-a
+      key: 0123456789abcdef0123
+      principal: svc-partner-a
+      scopes: [content:write]
+      rps: 50
+      burst: 100
+    - id: partner-b
+      key: fedcba98765432100123
+      principal: svc-partner-b
+      enabled: false
+`)
+	cfg, err := load(path, env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := cfg.APIKeys()
+	if len(keys) != 2 {
+		t.Fatalf("keys = %+v", keys)
+	}
+	if a := keys[0]; !a.Enabled || a.Principal != "svc-partner-a" || a.Burst != 100 || len(a.Scopes) != 1 {
+		t.Errorf("partner-a = %+v", a)
+	}
+	if keys[1].Enabled {
+		t.Error("partner-b should be disabled")
+	}
+
+	path = writeFile(t, "bad.yaml", `
+auth:
+  api_keys:
+    - id: dup
+      key: short
+      principal: p
+    - id: dup
+      key: 0123456789abcdef0123
+      principle: typo
+`)
+	_, err = load(path, env(nil))
+	for _, want := range []string{
+		"auth.api_keys[0].key: must be at least 16 characters",
+		"auth.api_keys[1].id: duplicate id",
+		"auth.api_keys[1].principle: unknown field",
+		"auth.api_keys[1].principal: must not be empty",
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("error %v does not mention %q", err, want)
+		}
+	}
+}
+
+func TestSigningKeys(t *testing.T) {
+	path := writeFile(t, "gateway.yaml", `
+auth:
+  signature_max_clock_skew: 2m
+  signing_keys:
+    - id: partner-a
+      secret: 0123456789abcdef0123456789abcdef
+      principal: svc-partner-a
+      scopes: [content:write]
+    - id: partner-b
+      secret: fedcba9876543210fedcba9876543210
+      principal: svc-partner-b
+      enabled: false
+`)
+	cfg, err := load(path, env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := cfg.SigningKeys()
+	if len(keys) != 2 || !keys[0].Enabled || keys[0].Principal != "svc-partner-a" || len(keys[0].Scopes) != 1 || keys[1].Enabled {
+		t.Errorf("keys = %+v", keys)
+	}
+	if cfg.Auth.SignatureMaxClockSkew != 2*time.Minute {
+		t.Errorf("skew = %v", cfg.Auth.SignatureMaxClockSkew)
+	}
+	if cfg, _ := load("", env(nil)); cfg.Auth.SignatureMaxClockSkew != auth.DefaultMaxClockSkew {
+		t.Errorf("default skew = %v", cfg.Auth.SignatureMaxClockSkew)
+	}
+
+	path = writeFile(t, "bad.yaml", `
+auth:
+  signing_keys:
+    - id: dup
+      secret: short
+      principal: p
+      tenant: umbrella
+    - id: dup
+      secret: 0123456789abcdef0123456789abcdef
+`)
+	_, err = load(path, env(map[string]string{"SIGNATURE_MAX_CLOCK_SKEW": "0s"}))
+	for _, want := range []string{
+		"auth.signing_keys[0].secret: must be at least 32 bytes, got 5",
+		`auth.signing_keys[0].tenant: no tenant has id "umbrella"`,
+		`auth.signing_keys[1].id: duplicate id "dup"`,
+		"auth.signing_keys[1].principal: must not be empty",
+		"auth.signature_max_clock_skew: must be positive",
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("error %v does not mention %q", err, want)
+		}
+	}
+}
+
+func TestWebhookSecrets(t *testing.T) {
+	cfg, err := load("", env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.WebhookSecrets() != nil {
+		t.Errorf("webhooks enabled by default")
+	}
+
+	path := writeFile(t, "gateway.yaml", `
+webhooks:
+  max_backoff: 1m
+  secrets:
+    - principal: svc-partner-a
+      secret: 0123456789abcdef0123
+`)
+	cfg, err = load(path, env(map[string]string{"WEBHOOK_MAX_ATTEMPTS": "3"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.WebhookSecrets(); got["svc-partner-a"] != "0123456789abcdef0123" || cfg.Webhooks.MaxAttempts != 3 || cfg.Webhooks.MaxBackoff != time.Minute {
+		t.Errorf("webhooks = %+v, secrets %v", cfg.Webhooks, got)
+	}
+
+	path = writeFile(t, "bad.yaml", `
+webhooks:
+  secrets:
+    - principal: p
+      secret: short
+    - principal: p
+      secret: 0123456789abcdef0123
+`)
+	_, err = load(path, env(nil))
+	for _, want := range []string{
+		"webhooks.secrets[0].secret: must be at least 16 characters",
+		`webhooks.secrets[1].principal: duplicate principal "p"`,
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("error %v does not mention %q", err, want)
+		}
+	}
+}
+
+func TestFeatureFlags(t *testing.T) {
+	path := writeFile(t, "gateway.yaml", `
+feature_flags:
+  - name: new_summarizer
+    percent: 10
+    principals: [user-1]
+    allow_header: true
+`)
+	cfg, err := load(path, env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := cfg.Flags()
+	if len(got) != 1 || got[0].Name != "new_summarizer" || got[0].Percent != 10 || got[0].Principals[0] != "user-1" || !got[0].AllowHeader || got[0].Enabled {
+		t.Errorf("flags = %+v", got)
+	}
+
+	path = writeFile(t, "bad.yaml", `
+feature_flags:
+  - name: New-Summarizer
+  - name: beta
+    percent: 101
+  - name: beta
+`)
+	_, err = load(path, env(nil))
+	for _, want := range []string{
+		`feature_flags[0].name: must be lowercase letters, digits and underscores, got "New-Summarizer"`,
+		"feature_flags[1].percent: must be between 0 and 100, got 101",
+		`feature_flags[2].name: duplicate flag "beta"`,
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("error %v does not mention %q", err, want)
+		}
+	}
+}
+
+func TestHeaderRules(t *testing.T) {
+	path := writeFile(t, "gateway.yaml", `
+headers:
+  request:
+    - action: remove
+      name: "X-Debug-*"
+    - action: set
+      name: X-Env
+      value: staging
+  response:
+    - action: remove
+      name: Server
+legacy:
+  url: http://python-api:8000
+  request_headers:
+    - action: remove
+      name: Authorization
+`)
+	cfg, err := load(path, env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request, response := cfg.HeaderRules()
+	if len(request) != 2 || request[1] != (headers.Rule{Action: headers.Set, Name: "X-Env", Value: "staging"}) || len(response) != 1 {
+		t.Errorf("header rules = %+v, %+v", request, response)
+	}
+	if request, response := cfg.LegacyHeaderRules(); len(request) != 1 || request[0].Name != "Authorization" || response != nil {
+		t.Errorf("legacy header rules = %+v, %+v", request, response)
+	}
+
+	path = writeFile(t, "bad.yaml", `
+headers:
+  request:
+    - action: replace
+      name: X-Env
+      value: a
+  response:
+    - action: set
+      name: "X-Debug-*"
+      value: a
+    - action: remove
+      name: Vary
+legacy:
+  request_headers:
+    - action: remove
+      name: Origin
+`)
+	_, err = load(path, env(nil))
+	for _, want := range []string{
+		`headers.request[0]: action must be set, add or remove, got "replace"`,
+		`headers.response[0]: a prefix like "X-Debug-*" can only be removed`,
+		"headers.response[1]: Vary is managed by the gateway",
+		"legacy.request_headers[0]: Origin is managed by the gateway",
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("error %v does not mention %q", err, want)
+		}
+	}
+}
+
+func TestTenants(t *testing.T) {
+	path := writeFile(t, "gateway.yaml", `
+default_tenant: acme
+tenants:
+  - id: acme
+    rps: 50
+    burst: 100
+    formats: [article, social_post]
+  - id: globex
+    enabled: false
+    metrics: false
+    backend_addr: globex-content:50051
+auth:
+  jwt_secret: test-secret
+  api_keys:
+    - id: globex-ci
+      key: 0123456789abcdef0123
+      principal: ci
+      tenant: globex
+`)
+	cfg, err := load(path, env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := cfg.TenantList()
+	if len(got) != 2 || got[0].ID != "acme" || !got[0].Enabled || !got[0].Metrics || got[0].RPS != 50 || got[0].Formats[1] != "social_post" ||
+		got[1].Enabled || got[1].Metrics {
+		t.Errorf("tenants = %+v", got)
+	}
+	if pc := cfg.TenantPoolConfig(cfg.Tenants[1]); pc.Addr != "globex-content:50051" || pc.Size != cfg.Backend.PoolSize {
+		t.Errorf("globex pool = %+v", pc)
+	}
+	if keys := cfg.APIKeys(); keys[0].Tenant != "globex" {
+		t.Errorf("api key tenant = %q", keys[0].Tenant)
+	}
+
+	path = writeFile(t, "bad.yaml", `
+default_tenant: initech
+tenants:
+  - id: Acme:1
+  - id: acme
+    formats: [video, hologram]
+    rps: -1
+  - id: acme
+auth:
+  jwt_secret: test-secret
+  api_keys:
+    - id: k
+      key: 0123456789abcdef0123
+      principal: p
+      tenant: umbrella
+`)
+	_, err = load(path, env(nil))
+	for _, want := range []string{
+		`tenants[0].id: must be 1 to 64 lowercase letters, digits, hyphens and underscores, got "Acme:1"`,
+		`tenants[1].formats: unknown format "hologram"`,
+		"tenants[1]: rps and burst must not be negative",
+		`tenants[2].id: duplicate id "acme"`,
+		`default_tenant: no tenant has id "initech"`,
+		`auth.api_keys[0].tenant: no tenant has id "umbrella"`,
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("error %v does not mention %q", err, want)
+		}
+	}
+}
+
+func TestMethodLimits(t *testing.T) {
+	cfg, err := load("", env(nil))
+	if err != nil || cfg.MethodLimits() != nil {
+		t.Fatalf("default method limits = %v, %v", cfg.MethodLimits(), err)
+	}
+	path := writeFile(t, "gateway.yaml", `
+backend:
+  method_limits:
+    - method: CreateContent
+      max_in_flight: 4
+      max_queued: 8
+      max_wait: 2s
+    - method: GenerateStream
+      max_in_flight: 2
+`)
+	cfg, err = load(path, env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	limits := cfg.MethodLimits()
+	if l := limits[backend.MethodCreateContent]; l.MaxInFlight != 4 || l.MaxQueued != 8 || l.MaxWait != 2*time.Second {
+		t.Errorf("CreateContent limit = %+v", l)
+	}
+	if l := limits[backend.MethodGenerateStream]; l.MaxInFlight != 2 || l.MaxQueued != 0 || len(limits) != 2 {
+		t.Errorf("limits = %+v", limits)
+	}
+
+	path = writeFile(t, "gateway.yaml", `
+backend:
+  method_limits:
+    - method: MakeVideo
+      max_in_flight: 1
+    - method: CreateContent
+      max_in_flight: 0
+      max_queued: -1
+    - method: CreateContent
+      max_in_flight: 1
+`)
+	_, err = load(path, env(nil))
+	for _, want := range []string{
+		`backend.method_limits[0].method: unknown method "MakeVideo"`,
+		"backend.method_limits[1].max_in_flight: must be at least 1, got 0",
+		"backend.method_limits[1]: max_queued and max_wait must not be negative",
+		`backend.method_limits[2].method: duplicate method "CreateContent"`,
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("error %v does not mention %q", err, want)
+		}
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	path := writeFile(t, "gateway.yaml", `
+routes:
+  - match: POST /api/v1/content
+    timeout: 2m
+    max_body_bytes: 65536
+    slow_request_threshold: 30s
+    coalesce_window: 2s
+  - match: /api/v1/jobs/{id}
+    rate_limit_rps: 50
+    rate_limit_burst: 100
+    slow_request_threshold: -1s
+    stale_if_error: 5m
+  - match: POST /api/v1/jobs/status
+    rate_limit_weight: 10
+`)
+	cfg, err := load(path, env(map[string]string{"SLOW_REQUEST_THRESHOLD": "500ms"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.SlowRequestThreshold != 500*time.Millisecond {
+		t.Errorf("slow request threshold = %v, want 500ms", cfg.SlowRequestThreshold)
+	}
+	table := cfg.RouteTable()
+	if o := table["POST /api/v1/content"]; o.Timeout != 2*time.Minute || o.MaxBodyBytes != 65536 || o.SlowThreshold != 30*time.Second || o.CoalesceWindow != 2*time.Second {
+		t.Errorf("content override = %+v", o)
+	}
+	if o := table["/api/v1/jobs/{id}"]; o.RPS != 50 || o.Burst != 100 || o.SlowThreshold >= 0 || o.StaleIfError != 5*time.Minute {
+		t.Errorf("job override = %+v", o)
+	}
+	if o := table["POST /api/v1/jobs/status"]; o.Weight != 10 || o.RPS != 0 {
+		t.Errorf("bulk status override = %+v", o)
+	}
+	if got := cfg.RateLimitConfig().Routes; len(got) != 3 {
+		t.Errorf("rate limit routes = %v", got)
+	}
+
+	path = writeFile(t, "bad.yaml", `
+routes:
+  - match: api/v1/jobs
+  - match: get /api/v1/jobs
+  - match: /api/v1/jobs/{id}
+    rate_limit_rps: 5
+  - match: /api/v1/jobs/{id}
+    timeout: -1s
+    rate_limit_weight: -2
+  - match: POST /api/v1/content
+    stale_if_error: 1m
+`)
+	_, err = load(path, env(map[string]string{"SLOW_REQUEST_THRESHOLD": "-1s"}))
+	for _, want := range []string{
+		`routes[0].match: must be a route template like /api/v1/jobs/{id}, optionally after a method, got "api/v1/jobs"`,
+		`routes[1].match: must be a route template`,
+		"routes[2]: rate_limit_rps and rate_limit_burst must be set together",
+		`routes[3].match: duplicate route "/api/v1/jobs/{id}"`,
+		"routes[3]: timeout, rate_limit_rps, rate_limit_burst, rate_limit_weight, max_body_bytes, coalesce_window and stale_if_error must not be negative",
+		"routes[4].stale_if_error: applies to GET routes only, got POST",
+		"slow_request_threshold: must not be negative",
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("error %v does not mention %q", err, want)
+		}
+	}
+}
+
+func TestErrorsAreAggregated(t *testing.T) {
+	path := writeFile(t, "gateway.yaml", `
+port: 70000
+backend:
+  addr: ""
+  pool_sise: 3
+`)
+	_, err := load(path, env(map[string]string{
+		"RATE_LIMIT_BURST":              "lots",
+		"JWT_ALGORITHM":                 "none",
+		"TLS_CERT_FILE":                 "/etc/tls/tls.crt",
+		"OTEL_EXPORTER_OTLP_ENDPOINT":   "collector:4318",
+		"REALTIME_OVERFLOW_POLICY":      "block",
+		"REALTIME_POLL_TIMEOUT":         "0s",
+		"REALTIME_EVENT_BUS_BUFFER":     "0",
+		"REALTIME_WS_COMPRESSION_LEVEL": "0",
+		"RETRY_BUDGET_TOKEN_RATIO":      "2",
+		"LEGACY_BACKEND_URL":            "python-api:8000",
+		"CLIENT_TIMEOUT_MIN":            "10s",
+		"CLIENT_TIMEOUT_MAX":            "5s",
+		"TRUSTED_PROXIES":               "10.0.0.0/8,10.0.0.300",
+		"QUEUE_BACKEND":                 "redis",
+		"QUEUE_WORKERS":                 "0",
+		"QUEUE_HIGH_WATER":              "0",
+		"ACCESS_LOG_FORMAT":             "clf",
+		"ACCESS_LOG_LEVEL":              "verbose",
+		"FAIR_QUEUE_MAX_QUEUED":         "0",
+		"SIGNED_URL_SECRET":             "too short",
+		"SIGNED_URL_BASE":               "downloads.example.com",
+		"HTTP_READ_HEADER_TIMEOUT":      "2m",
+		"PUBLIC_CONTENT_RATE_LIMIT_RPS": "0",
+		"API_DEFAULT_PAGE_SIZE":         "500",
+		"REALTIME_WS_MAX_MESSAGE_BYTES": "0",
+	}))
+	var cfgErr *Error
+	if !errors.As(err, &cfgErr) {
+		t.Fatalf("err = %v, want *Error", err)
+	}
+	for _, want := range []string{
+		"backend.pool_sise: unknown field",
+		"RATE_LIMIT_BURST: want an integer",
+		"port: must be between 1 and 65535",
+		"backend.addr: must not be empty",
+		"auth.jwt_algorithm: must be HS256 or RS256",
+		"tls: cert_file and key_file must be set together",
+		"tracing.endpoint: must be an http or https URL",
+		`realtime.overflow_policy: must be disconnect or drop_oldest, got "block"`,
+		"realtime.event_bus_buffer: must be at least 1, got 0",
+		"realtime.poll_timeout: must be positive",
+		"realtime.ws_compression_level: must be between 1 and 9, got 0",
+		"retry.budget_token_ratio: must be greater than 0 and at most 1, got 2",
+		"legacy.url: must be an http or https URL",
+		"client_timeout.min: must not exceed max, got 10s > 5s",
+		`trusted_proxies: "10.0.0.300" is not a CIDR range or IP address`,
+		"queue.backend: redis requires redis_url",
+		"queue.workers: must be at least 1, got 0",
+		"queue.high_water: must be greater than 0 and at most 1, got 0",
+		`access_log.format: must be json or combined, got "clf"`,
+		`access_log.level: must be debug, info, warn or error, got "verbose"`,
+		"fair_queue.max_queued_per_principal: must be at least 1, got 0",
+		"signed_urls.secret: must be at least 32 bytes, got 9",
+		`signed_urls.base_url: must be an http or https URL, got "downloads.example.com"`,
+		"public_content.rps: must be positive, got 0",
+		"server.read_header_timeout: must not exceed read_timeout, got 2m0s > 1m0s",
+		"api.default_page_size: must be between 0 and max_page_size, got 500",
+		"realtime.ws_max_message_bytes: must be at least 1, got 0",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	cfg, err := load("", env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, own := cfg.AccessLogLevel(); cfg.AccessLog.Format != "json" || cfg.AccessLog.File != "" || own {
+		t.Errorf("default access_log = %+v", cfg.AccessLog)
+	}
+	if cfg.AuditLog.File != "" {
+		t.Errorf("default audit_log = %+v", cfg.AuditLog)
+	}
+	cfg, err = load("", env(map[string]string{
+		"ACCESS_LOG_FORMAT": "combined",
+		"ACCESS_LOG_FILE":   "/var/log/gateway/access.log",
+		"ACCESS_LOG_LEVEL":  "debug",
+		"AUDIT_LOG_FILE":    "/var/log/gateway/audit.log",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if level, own := cfg.AccessLogLevel(); cfg.AccessLog.Format != "combined" || !own || level != slog.LevelDebug {
+		t.Errorf("access_log = %+v", cfg.AccessLog)
+	}
+	if cfg.AuditLog.File != "/var/log/gateway/audit.log" {
+		t.Errorf("audit_log = %+v", cfg.AuditLog)
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	cfg, err := load("", env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.RetryBudget() == nil || cfg.Retry.BudgetMaxTokens != retry.DefaultBudgetMaxTokens || cfg.Retry.BudgetTokenRatio != retry.DefaultBudgetTokenRatio {
+		t.Errorf("default retry budget = %d tokens, ratio %g", cfg.Retry.BudgetMaxTokens, cfg.Retry.BudgetTokenRatio)
+	}
+	cfg, err = load("", env(map[string]string{"RETRY_BUDGET_MAX_TOKENS": "0"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := cfg.RetryBudget(); b != nil {
+		t.Errorf("RETRY_BUDGET_MAX_TOKENS=0: budget %+v, want none", b)
+	}
+}
+
+func TestFairQueue(t *testing.T) {
+	cfg, err := load("", env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fq := cfg.FairQueueConfig(); fq.MaxInFlight != 0 || fq.MaxQueued != fairqueue.DefaultMaxQueued {
+		t.Errorf("default fair_queue = %+v", fq)
+	}
+	cfg, err = load("", env(map[string]string{
+		"FAIR_QUEUE_MAX_IN_FLIGHT": "64",
+		"FAIR_QUEUE_MAX_QUEUED":    "4",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fq := cfg.FairQueueConfig(); fq.MaxInFlight != 64 || fq.MaxQueued != 4 {
+		t.Errorf("fair_queue = %+v", fq)
+	}
+}
+
+func TestServerTimeouts(t *testing.T) {
+	cfg, err := load("", env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := middleware.ServerTimeouts{ReadHeader: 10 * time.Second, Read: time.Minute, Write: time.Minute, Idle: 2 * time.Minute}
+	if got := cfg.ServerTimeouts(); got != want {
+		t.Errorf("default server timeouts = %+v, want %+v", got, want)
+	}
+	cfg, err = load("", env(map[string]string{
+		"HTTP_READ_HEADER_TIMEOUT": "5s",
+		"HTTP_READ_TIMEOUT":        "0",
+		"HTTP_WRITE_TIMEOUT":       "90s",
+		"HTTP_IDLE_TIMEOUT":        "30s",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = middleware.ServerTimeouts{ReadHeader: 5 * time.Second, Write: 90 * time.Second, Idle: 30 * time.Second}
+	if got := cfg.ServerTimeouts(); got != want {
+		t.Errorf("server timeouts = %+v, want %+v", got, want)
+	}
+}
+
+func TestOIDC(t *testing.T) {
+	cfg, err := load("", env(map[string]string{
+		"OIDC_ISSUER":           "https://id.example.com",
+		"OIDC_AUDIENCE":         "gateway",
+		"OIDC_REFRESH_INTERVAL": "15m",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := cfg.AuthConfig(); a.OIDCIssuer != "https://id.example.com" || a.OIDCAudience != "gateway" || a.OIDCRefreshInterval != 15*time.Minute {
+		t.Errorf("auth config = %+v", a)
+	}
+	if cfg, _ := load("", env(nil)); cfg.Auth.OIDCRefreshInterval != auth.DefaultKeyRefreshInterval {
+		t.Errorf("default refresh interval = %v", cfg.Auth.OIDCRefreshInterval)
+	}
+
+	_, err = load("", env(map[string]string{"OIDC_ISSUER": "id.example.com", "JWT_SECRET": "s3cret"}))
+	for _, want := range []string{
+		`auth.oidc_issuer: must be an http or https URL, got "id.example.com"`,
+		"auth.oidc_audience: required when oidc_issuer is set",
+		"auth.oidc_issuer: cannot be combined with jwt_secret or jwt_public_key_file",
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("error %v does not mention %q", err, want)
+		}
+	}
+}
+
+func TestWebSocketCompression(t *testing.T) {
+	cfg, err := load("", env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := cfg.WebSocketCompression(); c != nil {
+		t.Errorf("default compression = %+v, want off", c)
+	}
+	cfg, err = load("", env(map[string]string{
+		"REALTIME_WS_COMPRESSION":           "true",
+		"REALTIME_WS_COMPRESSION_LEVEL":     "6",
+		"REALTIME_WS_COMPRESSION_THRESHOLD": "1024",
+		"REALTIME_WS_CONTEXT_TAKEOVER":      "false",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := websocket.Compression{Level: 6, Threshold: 1024, NoContextTakeover: true}
+	if c := cfg.WebSocketCompression(); c == nil || *c != want {
+		t.Errorf("compression = %+v, want %+v", c, want)
+	}
+}
+
+func TestServerProtocols(t *testing.T) {
+	cfg, err := load("", env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := cfg.ServerProtocols(); !p.HTTP1() || p.HTTP2() || p.UnencryptedHTTP2() {
+		t.Errorf("default protocols = %v, want HTTP/1.1 only", p)
+	}
+	cfg, err = load("", env(map[string]string{"ENABLE_H2C": "true"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := cfg.ServerProtocols(); !p.HTTP1() || !p.UnencryptedHTTP2() {
+		t.Errorf("h2c protocols = %v, want HTTP/1.1 and h2c", p)
+	}
+
+	tls := map[string]string{"TLS_CERT_FILE": "/etc/tls/tls.crt", "TLS_KEY_FILE": "/etc/tls/tls.key"}
+	cfg, err = load("", env(tls))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := cfg.ServerProtocols(); !p.HTTP1() || !p.HTTP2() || p.UnencryptedHTTP2() {
+		t.Errorf("TLS protocols = %v, want HTTP/1.1 and h2", p)
+	}
+	tls["ENABLE_H2C"] = "true"
+	_, err = load("", env(tls))
+	if err == nil || !strings.Contains(err.Error(), "server.h2c: is HTTP/2 without TLS") {
+		t.Errorf("h2c with TLS: err = %v", err)
+	}
+}
+
+func TestSignedURLs(t *testing.T) {
+	cfg, err := load("", env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sc, on := cfg.SignedURLConfig(); on || sc.TTL != signedurl.DefaultTTL {
+		t.Errorf("default signed_urls = %+v, on %v", sc, on)
+	}
+	cfg, err = load("", env(map[string]string{
+		"SIGNED_URL_SECRET": strings.Repeat("k", 32),
+		"SIGNED_URL_TTL":    "5m",
+		"SIGNED_URL_BASE":   "https://downloads.example.com",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sc, on := cfg.SignedURLConfig(); !on || len(sc.Key) != 32 || sc.TTL != 5*time.Minute || sc.BaseURL != "https://downloads.example.com" {
+		t.Errorf("signed_urls = %+v, on %v", sc, on)
+	}
+}
+
+func TestPublicContent(t *testing.T) {
+	cfg, err := load("", env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.PublicContent.Enabled {
+		t.Error("public content on by default")
+	}
+	if rl := cfg.PublicContentRateLimitConfig(); rl.RPS >= cfg.RateLimitConfig().RPS || rl.Burst >= cfg.RateLimitConfig().Burst {
+		t.Errorf("default public rate limit %+v is not stricter than %+v", rl, cfg.RateLimitConfig())
+	}
+	if cc := cfg.PublicContentCORSConfig(); !slices.Equal(cc.AllowedOrigins, []string{"*"}) || cc.AllowCredentials {
+		t.Errorf("default public CORS = %+v, want any origin without credentials", cc)
+	}
+	cfg, err = load("", env(map[string]string{
+		"PUBLIC_CONTENT_ENABLED":              "true",
+		"PUBLIC_CONTENT_RATE_LIMIT_RPS":       "0.5",
+		"PUBLIC_CONTENT_RATE_LIMIT_BURST":     "3",
+		"PUBLIC_CONTENT_CORS_ALLOWED_ORIGINS": "https://blog.example.com",
+		"CORS_ALLOW_CREDENTIALS":              "true",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rl := cfg.PublicContentRateLimitConfig(); !cfg.PublicContent.Enabled || rl.RPS != 0.5 || rl.Burst != 3 {
+		t.Errorf("public content = %+v, rate limit %+v", cfg.PublicContent, rl)
+	}
+	if cc := cfg.PublicContentCORSConfig(); !slices.Equal(cc.AllowedOrigins, []string{"https://blog.example.com"}) || cc.AllowCredentials {
+		t.Errorf("public CORS = %+v", cc)
+	}
+
+	// Tenants' public links are signed.
+	path := writeFile(t, "gateway.yaml", "public_content:\n  enabled: true\ntenants:\n  - id: acme\n")
+	if _, err := load(path, env(nil)); err == nil || !strings.Contains(err.Error(), "public_content.enabled: tenants need signed_urls.secret") {
+		t.Errorf("tenants without a signed URL secret: err = %v", err)
+	}
+	if _, err := load(path, env(map[string]string{"SIGNED_URL_SECRET": "0123456789abcdef0123456789abcdef"})); err != nil {
+		t.Errorf("tenants with a signed URL secret: %v", err)
+	}
+}
+
+func TestUnreadableFile(t *testing.T) {
+	if _, err := load(filepath.Join(t.TempDir(), "missing.yaml"), env(nil)); err == nil {
+		t.Error("expected error for missing file")
+	}
+	path := writeFile(t, "bad.yaml", "port: 1\n  nested: oops\n")
+	if _, err := load(path, env(nil)); err == nil {
+		t.Error("expected parse error")
+	}
+}
+
+func TestRestartRequired(t *testing.T) {
+	cur, _ := load("", env(nil))
+	next, err := load("", env(map[string]string{
+		"PORT":                 "9000",
+		"TLS_CERT_FILE":        "/etc/tls/cert.pem",
+		"TLS_KEY_FILE":         "/etc/tls/key.pem",
+		"LOG_LEVEL":            "debug",
+		"RATE_LIMIT_RPS":       "50",
+		"CORS_ALLOWED_ORIGINS": "https://app.example.com",
+		"REQUEST_TIMEOUT":      "5s",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(cur.RestartRequired(next), ",")
+	if got != "port,tls" {
+		t.Errorf("RestartRequired = %q, want port,tls", got)
+	}
+	if keys := cur.RestartRequired(cur); len(keys) != 0 {
+		t.Errorf("unchanged config needs restart for %v", keys)
+	}
+}
+
+func TestBackendEndpoints(t *testing.T) {
+	cfg, err := load("", env(map[string]string{
+		"CONTENT_SERVICE_ADDR": "orch-a:50051@zone-a, orch-b:50051@zone-b",
+		"PREFERRED_ZONE":       "zone-b",
+		"GRPC_DEADLINE_MARGIN": "200ms",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pc := cfg.PoolConfig(); pc.PreferredZone != "zone-b" || pc.HealthCheckInterval <= 0 || pc.DeadlineMargin != 200*time.Millisecond {
+		t.Errorf("PoolConfig = %+v", pc)
+	}
+	_, err = load("", env(map[string]string{
+		"GRPC_DEADLINE_MARGIN":       "-1s",
+		"BACKEND_MAX_RESPONSE_BYTES": "0",
+		"LEGACY_MAX_RESPONSE_BYTES":  "-1",
+	}))
+	for _, want := range []string{
+		"backend.deadline_margin: must not be negative",
+		"backend.max_response_bytes: must be at least 1, got 0",
+		"legacy.max_response_bytes: must be at least 1, got -1",
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("error %v does not mention %q", err, want)
+		}
+	}
+
+	for addr, want := range map[string]string{
+		"orch-a:50051,,orch-b:50051":              "backend.addr: empty endpoint",
+		"orch-a:50051@zone-a,orch-a:50051@b":      `backend.addr: duplicate endpoint "orch-a:50051"`,
+		"orch-a:50051@zone-a,orch-c:50051@zone-c": `backend.preferred_zone: no endpoint in backend.addr is in zone "zone-b"`,
+	} {
+		_, err := load("", env(map[string]string{"CONTENT_SERVICE_ADDR": addr, "PREFERRED_ZONE": "zone-b"}))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: error %v does not mention %q", addr, err, want)
+		}
+	}
+}
+
+func TestStartup(t *testing.T) {
+	cfg, err := load("", env(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st := cfg.Startup; st.WaitForBackend || st.OnTimeout != StartupExit || st.MaxWait != health.DefaultMaxWait {
+		t.Errorf("default startup = %+v", st)
+	}
+
+	cfg, err = load("", env(map[string]string{
+		"WAIT_FOR_BACKEND":        "true",
+		"STARTUP_MAX_WAIT":        "2m",
+		"STARTUP_INITIAL_BACKOFF": "1s",
+		"STARTUP_MAX_BACKOFF":     "15s",
+		"STARTUP_ON_TIMEOUT":      "degraded",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Startup.WaitForBackend || cfg.Startup.OnTimeout != StartupDegraded {
+		t.Errorf("startup = %+v", cfg.Startup)
+	}
+	if w := cfg.StartupWaitConfig(); w != (health.WaitConfig{MaxWait: 2 * time.Minute, InitialBackoff: time.Second, MaxBackoff: 15 * time.Second}) {
+		t.Errorf("StartupWaitConfig = %+v", w)
+	}
+
+	_, err = load("", env(map[string]string{
+		"STARTUP_INITIAL_BACKOFF": "20s",
+		"STARTUP_ON_TIMEOUT":      "retry",
+	}))
+	for _, want := range []string{
+		"startup.initial_backoff: must not exceed max_backoff",
+		`startup.on_timeout: must be exit or degraded, got "retry"`,
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("error %v does not mention %q", err, want)
+		}
+	}
+}
+
+func TestMaintenance(t *testing.T) {
+	cfg, err := load("", env(map[string]string{
+		"MAINTENANCE_MODE":          "true",
+		"MAINTENANCE_RETRY_AFTER":   "5m",
+		"MAINTENANCE_DRAIN_STREAMS": "true",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := cfg.Maintenance; !m.Enabled || m.RetryAfter != 5*time.Minute || !m.DrainStreams {
+		t.Errorf("maintenance = %+v", m)
+	}
+
+	_, err = load("", env(map[string]string{
+		"MAINTENANCE_RETRY_AFTER": "10ms",
+		"MAINTENANCE_MESSAGE":     strings.Repeat("x", 124),
+	}))
+	for _, want := range []string{
+		"maintenance.retry_after: must be at least 1s, got 10ms",
+		"maintenance.message: must be at most 123 bytes, got 124",
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("error %v does not mention %q", err, want)
+		}
+	}
+}
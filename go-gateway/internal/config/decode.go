@@ -0,0 +1,219 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func parseJSON(data []byte) (map[string]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var tree map[string]any
+	if err := dec.Decode(&tree); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// decodeTree copies the values of a parsed config file into cfg, keyed by
+// json tag. Keys the struct does not know are reported, which catches
+// typos that would otherwise be silently ignored.
+func decodeTree(cfg *Config, tree map[string]any, errs *Error) {
+	decodeStruct(reflect.ValueOf(cfg).Elem(), tree, "", errs)
+}
+
+func decodeStruct(v reflect.Value, tree map[string]any, prefix string, errs *Error) {
+	fields := make(map[string]reflect.Value)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		fields[name] = v.Field(i)
+	}
+	for _, key := range slices.Sorted(maps.Keys(tree)) {
+		node := tree[key]
+		path := prefix + key
+		field, ok := fields[key]
+		if !ok {
+			errs.addf("%s: unknown field", path)
+			continue
+		}
+		if node == nil {
+			continue
+		}
+		if field.Kind() == reflect.Struct {
+			sub, ok := node.(map[string]any)
+			if !ok {
+				errs.addf("%s: want a mapping", path)
+				continue
+			}
+			decodeStruct(field, sub, path+".", errs)
+			continue
+		}
+		if field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.Struct {
+			decodeStructList(field, node, path, errs)
+			continue
+		}
+		if err := setValue(field, node); err != nil {
+			errs.addf("%s: %v", path, err)
+		}
+	}
+}
+
+// defaulter is implemented by list element types whose zero value is not
+// their default, so that omitted keys in each entry keep the default.
+type defaulter interface {
+	setDefaults()
+}
+
+// decodeStructList decodes a sequence of mappings into a slice of structs.
+// The file's list replaces the default one rather than extending it.
+func decodeStructList(field reflect.Value, node any, path string, errs *Error) {
+	items, ok := node.([]any)
+	if !ok {
+		errs.addf("%s: want a list", path)
+		return
+	}
+	out := reflect.MakeSlice(field.Type(), 0, len(items))
+	for i, item := range items {
+		sub, ok := item.(map[string]any)
+		if !ok {
+			errs.addf("%s[%d]: want a mapping", path, i)
+			continue
+		}
+		elem := reflect.New(field.Type().Elem())
+		if d, ok := elem.Interface().(defaulter); ok {
+			d.setDefaults()
+		}
+		decodeStruct(elem.Elem(), sub, fmt.Sprintf("%s[%d].", path, i), errs)
+		out = reflect.Append(out, elem.Elem())
+	}
+	field.Set(out)
+}
+
+// applyEnv overrides cfg from the environment variables named in env tags.
+func applyEnv(cfg *Config, lookup func(string) (string, bool), errs *Error) {
+	applyEnvStruct(reflect.ValueOf(cfg).Elem(), lookup, errs)
+}
+
+func applyEnvStruct(v reflect.Value, lookup func(string) (string, bool), errs *Error) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			applyEnvStruct(field, lookup, errs)
+			continue
+		}
+		tag := t.Field(i).Tag.Get("env")
+		if tag == "" {
+			continue
+		}
+		for _, name := range strings.Split(tag, ",") {
+			s, ok := lookup(name)
+			if !ok || s == "" {
+				continue
+			}
+			if err := setValue(field, s); err != nil {
+				errs.addf("%s: %v", name, err)
+			}
+			break
+		}
+	}
+}
+
+// setValue stores node, a string from the environment or YAML or a value
+// decoded from JSON, into field.
+func setValue(field reflect.Value, node any) error {
+	if field.Type() == durationType {
+		s, ok := node.(string)
+		if !ok {
+			return fmt.Errorf("want a duration such as \"30s\", got %v", node)
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("want a duration such as \"30s\", got %q", s)
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	if field.Kind() == reflect.Slice {
+		var items []string
+		switch n := node.(type) {
+		case string:
+			items = splitList(n)
+		case []any:
+			for _, item := range n {
+				s, ok := scalarString(item)
+				if !ok {
+					return fmt.Errorf("want a list of strings")
+				}
+				items = append(items, s)
+			}
+		default:
+			return fmt.Errorf("want a list of strings")
+		}
+		field.Set(reflect.ValueOf(items))
+		return nil
+	}
+
+	s, ok := scalarString(node)
+	if !ok {
+		return fmt.Errorf("want a scalar value")
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(s)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("want an integer, got %q", s)
+		}
+		field.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("want a number, got %q", s)
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("want true or false, got %q", s)
+		}
+		field.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
+
+func scalarString(node any) (string, bool) {
+	switch n := node.(type) {
+	case string:
+		return n, true
+	case json.Number:
+		return n.String(), true
+	case bool:
+		return strconv.FormatBool(n), true
+	}
+	return "", false
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// reloadable names the top-level keys whose settings the gateway applies
+// on SIGHUP without a restart.
+var reloadable = map[string]bool{
+	"log_level":       true,
+	"request_timeout": true,
+	"rate_limit":      true,
+	"cors":            true,
+	"load_shed":       true,
+	"client_timeout":  true,
+	"trusted_proxies": true,
+	"feature_flags":   true,
+}
+
+// RestartRequired lists the top-level keys that differ between c and next
+// but can only take effect after a restart, such as port and tls.
+func (c *Config) RestartRequired(next *Config) []string {
+	var keys []string
+	cur, nxt := reflect.ValueOf(c).Elem(), reflect.ValueOf(next).Elem()
+	for i := range cur.NumField() {
+		key, _, _ := strings.Cut(cur.Type().Field(i).Tag.Get("json"), ",")
+		if reloadable[key] {
+			continue
+		}
+		if !reflect.DeepEqual(cur.Field(i).Interface(), nxt.Field(i).Interface()) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,305 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseYAML parses the subset of YAML a config file needs: nested block
+// mappings, block and flow sequences of scalars, block sequences of
+// mappings, quoted strings and comments. Plain scalars are returned as strings and converted when they
+// are stored, so `port: 8080` and `port: "8080"` mean the same thing.
+// Anchors, tags, multi-line scalars and multiple documents are rejected or
+// unsupported.
+func parseYAML(data []byte) (map[string]any, error) {
+	lines, err := yamlLines(string(data))
+	if err != nil {
+		return nil, err
+	}
+	p := &yamlParser{lines: lines}
+	if len(lines) == 0 {
+		return map[string]any{}, nil
+	}
+	if lines[0].indent != 0 {
+		return nil, fmt.Errorf("line %d: unexpected indentation", lines[0].num)
+	}
+	root, err := p.mapping(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", p.lines[p.pos].num)
+	}
+	return root, nil
+}
+
+type yamlLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+func yamlLines(src string) ([]yamlLine, error) {
+	var out []yamlLine
+	for i, raw := range strings.Split(src, "\n") {
+		raw = strings.TrimRight(raw, "\r")
+		trimmed := strings.TrimLeft(raw, " ")
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", i+1)
+		}
+		text := strings.TrimSpace(stripComment(trimmed))
+		if text == "" || (len(out) == 0 && text == "---") {
+			continue
+		}
+		out = append(out, yamlLine{num: i + 1, indent: len(raw) - len(trimmed), text: text})
+	}
+	return out, nil
+}
+
+// stripComment removes a trailing "# ..." that is not inside quotes.
+func stripComment(s string) string {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || s[i-1] == ' '):
+			return s[:i]
+		}
+	}
+	return s
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+func isSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+func (p *yamlParser) mapping(indent int) (map[string]any, error) {
+	out := make(map[string]any)
+	for p.pos < len(p.lines) {
+		ln := p.lines[p.pos]
+		if ln.indent < indent {
+			break
+		}
+		if ln.indent > indent {
+			return nil, fmt.Errorf("line %d: unexpected indentation", ln.num)
+		}
+		if isSeqItem(ln.text) {
+			return nil, fmt.Errorf("line %d: sequence item where a key was expected", ln.num)
+		}
+		key, rest, err := splitKey(ln.text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", ln.num, err)
+		}
+		if _, dup := out[key]; dup {
+			return nil, fmt.Errorf("line %d: duplicate key %q", ln.num, key)
+		}
+		p.pos++
+
+		if rest != "" {
+			v, err := parseFlow(rest)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", ln.num, err)
+			}
+			out[key] = v
+			continue
+		}
+		// A key with no inline value introduces a nested block, which may
+		// be a sequence at the key's own indentation.
+		if p.pos < len(p.lines) {
+			next := p.lines[p.pos]
+			switch {
+			case next.indent > indent && isSeqItem(next.text):
+				out[key], err = p.sequence(next.indent)
+			case next.indent > indent:
+				out[key], err = p.mapping(next.indent)
+			case next.indent == indent && isSeqItem(next.text):
+				out[key], err = p.sequence(indent)
+			default:
+				out[key] = nil
+			}
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			out[key] = nil
+		}
+	}
+	return out, nil
+}
+
+func (p *yamlParser) sequence(indent int) ([]any, error) {
+	var out []any
+	for p.pos < len(p.lines) {
+		ln := p.lines[p.pos]
+		if ln.indent != indent || !isSeqItem(ln.text) {
+			if ln.indent > indent {
+				return nil, fmt.Errorf("line %d: unexpected indentation", ln.num)
+			}
+			break
+		}
+		item := strings.TrimSpace(strings.TrimPrefix(ln.text, "-"))
+		if item == "" {
+			return nil, fmt.Errorf("line %d: nested sequence items are not supported", ln.num)
+		}
+		if isMappingItem(item) {
+			// "- key: value" opens a mapping whose keys line up with the
+			// first one; reparse the line as that mapping's first entry.
+			itemIndent := ln.indent + len(ln.text) - len(item)
+			p.lines[p.pos] = yamlLine{num: ln.num, indent: itemIndent, text: item}
+			m, err := p.mapping(itemIndent)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, m)
+			continue
+		}
+		p.pos++
+		v, err := parseFlow(item)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", ln.num, err)
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+// isMappingItem reports whether a sequence item is a plain "key: value"
+// entry rather than a scalar or flow value.
+func isMappingItem(item string) bool {
+	switch item[0] {
+	case '"', '\'', '[', '{':
+		return false
+	}
+	_, _, err := splitKey(item)
+	return err == nil
+}
+
+// splitKey splits "key: value" at the first colon followed by a space or
+// the end of the line.
+func splitKey(text string) (key, rest string, err error) {
+	if text[0] == '"' || text[0] == '\'' {
+		end := closingQuote(text)
+		if end < 0 {
+			return "", "", fmt.Errorf("unterminated quoted key")
+		}
+		key, err = unquote(text[:end+1])
+		if err != nil {
+			return "", "", err
+		}
+		after := text[end+1:]
+		if !strings.HasPrefix(after, ":") {
+			return "", "", fmt.Errorf("expected ':' after key")
+		}
+		return key, strings.TrimSpace(after[1:]), nil
+	}
+	for i := 0; i < len(text); i++ {
+		if text[i] == ':' && (i == len(text)-1 || text[i+1] == ' ') {
+			return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:]), nil
+		}
+	}
+	return "", "", fmt.Errorf("expected 'key: value'")
+}
+
+func closingQuote(s string) int {
+	q := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case q == '"' && s[i] == '\\':
+			i++
+		case q == '\'' && s[i] == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			i++
+		case s[i] == q:
+			return i
+		}
+	}
+	return -1
+}
+
+// parseFlow parses an inline value: a flow sequence or a scalar.
+func parseFlow(s string) (any, error) {
+	switch s[0] {
+	case '[':
+		if !strings.HasSuffix(s, "]") {
+			return nil, fmt.Errorf("unterminated flow sequence")
+		}
+		var out []any
+		for _, item := range splitFlow(s[1 : len(s)-1]) {
+			v, err := parseScalar(item)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+		return out, nil
+	case '{':
+		return nil, fmt.Errorf("flow mappings are not supported")
+	case '&', '*', '!', '|', '>':
+		return nil, fmt.Errorf("anchors, tags and block scalars are not supported")
+	}
+	return parseScalar(s)
+}
+
+func splitFlow(s string) []string {
+	var items []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			items = append(items, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	if last := strings.TrimSpace(s[start:]); last != "" || len(items) > 0 {
+		items = append(items, last)
+	}
+	return items
+}
+
+func parseScalar(s string) (any, error) {
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil, nil
+	}
+	if s[0] == '"' || s[0] == '\'' {
+		return unquote(s)
+	}
+	return s, nil
+}
+
+func unquote(s string) (string, error) {
+	if len(s) < 2 || s[len(s)-1] != s[0] {
+		return "", fmt.Errorf("unterminated string %s", s)
+	}
+	if s[0] == '\'' {
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	}
+	v, err := strconv.Unquote(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid string %s", s)
+	}
+	return v, nil
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	src := `---
+# gateway settings
+port: 8080
+name: "quoted # not a comment"
+single: 'it''s'
+empty:
+flow: [a, "b, c", d]
+nested:
+  child: value
+  deeper:
+    leaf: 1
+list:
+- x
+- y
+records:
+  - id: one
+    tags: [a]
+  - id: two
+    nested:
+      k: v
+`
+	got, err := parseYAML([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"port":   "8080",
+		"name":   "quoted # not a comment",
+		"single": "it's",
+		"empty":  nil,
+		"flow":   []any{"a", "b, c", "d"},
+		"nested": map[string]any{
+			"child":  "value",
+			"deeper": map[string]any{"leaf": "1"},
+		},
+		"list": []any{"x", "y"},
+		"records": []any{
+			map[string]any{"id": "one", "tags": []any{"a"}},
+			map[string]any{"id": "two", "nested": map[string]any{"k": "v"}},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %#v\nwant %#v", got, want)
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	for name, src := range map[string]string{
+		"tab indent":    "a:\n\tb: 1\n",
+		"bad indent":    "a: 1\n  b: 2\n",
+		"duplicate key": "a: 1\na: 2\n",
+		"no colon":      "just text\n",
+		"anchor":        "a: &x 1\n",
+		"unterminated":  "a: \"open\n",
+	} {
+		if _, err := parseYAML([]byte(src)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+// Package cors answers CORS preflights and decorates responses to allowed
+// browser origins.
+package cors
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/respond"
+)
+
+// Defaults used by the config package.
+var (
+	DefaultMethods        = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	DefaultHeaders        = []string{"Authorization", "Content-Type", "X-Request-ID", "X-Request-Timeout"}
+	DefaultExposedHeaders = []string{"Location", "Retry-After", "X-Request-ID", "X-Request-Timeout"}
+)
+
+// DefaultMaxAge is how long browsers may cache a preflight, by default of
+// the config package. Browsers cap it themselves: Chromium at two hours,
+// Firefox at a day.
+const DefaultMaxAge = 10 * time.Minute
+
+// Config is the CORS policy. A zero Config allows no origins, so the
+// middleware is a no-op.
+type Config struct {
+	// AllowedOrigins lists exact origins ("https://app.example.com"); "*"
+	// allows any origin.
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+	// ExposedHeaders are response headers scripts may read.
+	ExposedHeaders []string
+	// AllowCredentials lets browsers send cookies and Authorization. The
+	// matched origin is then echoed instead of "*", as the spec requires.
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight's answer, sent in
+	// whole seconds as Access-Control-Max-Age. Zero sends no header, and
+	// browsers cache for five seconds.
+	MaxAge time.Duration
+}
+
+type policy struct {
+	cfg       Config
+	anyOrigin bool
+	origins   map[string]bool
+	methods   map[string]bool
+	headers   map[string]bool
+	anyHeader bool
+}
+
+// Middleware applies cfg. Preflight requests from allowed origins are
+// answered with 204 and never reach next. Requests from other origins, and
+// requests without an Origin header, pass through untouched so
+// server-to-server callers are unaffected; browsers enforce the policy by
+// the absence of the headers.
+func Middleware(cfg Config) func(http.Handler) http.Handler {
+	return New(cfg).Middleware
+}
+
+// Policy is a CORS policy that can be replaced while serving.
+type Policy struct {
+	p atomic.Pointer[policy]
+}
+
+// New returns a Policy applying cfg.
+func New(cfg Config) *Policy {
+	c := &Policy{}
+	c.Set(cfg)
+	return c
+}
+
+// Set replaces the policy; each request sees either the old or the new
+// one in full.
+func (c *Policy) Set(cfg Config) { c.p.Store(newPolicy(cfg)) }
+
+// Middleware applies the policy, as the package-level Middleware does.
+func (c *Policy) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := c.p.Load()
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		respond.Vary(w, "Origin")
+		allowed := p.allowOrigin(origin)
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			respond.Vary(w, "Access-Control-Request-Method", "Access-Control-Request-Headers")
+			if allowed && p.preflightOK(r) {
+				p.writeOriginHeaders(w, origin)
+				w.Header().Set("Access-Control-Allow-Methods", strings.Join(p.cfg.AllowedMethods, ", "))
+				if secs := int64(p.cfg.MaxAge / time.Second); secs > 0 {
+					w.Header().Set("Access-Control-Max-Age", strconv.FormatInt(secs, 10))
+				}
+				if reqHeaders := r.Header.Get("Access-Control-Request-Headers"); reqHeaders != "" {
+					if p.anyHeader {
+						w.Header().Set("Access-Control-Allow-Headers", reqHeaders)
+					} else {
+						w.Header().Set("Access-Control-Allow-Headers", strings.Join(p.cfg.AllowedHeaders, ", "))
+					}
+				}
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if allowed {
+			p.writeOriginHeaders(w, origin)
+			if len(p.cfg.ExposedHeaders) > 0 {
+				w.Header().Set("Access-Control-Expose-Headers", strings.Join(p.cfg.ExposedHeaders, ", "))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Prefixed applies p to requests whose path starts with prefix and def
+// to the others, so the routes under prefix can be opened to more origins
+// without loosening the rest.
+func Prefixed(def *Policy, prefix string, p *Policy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		under, other := p.Middleware(next), def.Middleware(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				under.ServeHTTP(w, r)
+				return
+			}
+			other.ServeHTTP(w, r)
+		})
+	}
+}
+
+func newPolicy(cfg Config) *policy {
+	p := &policy{
+		cfg:     cfg,
+		origins: make(map[string]bool),
+		methods: make(map[string]bool),
+		headers: make(map[string]bool),
+	}
+	for _, o := range cfg.AllowedOrigins {
+		if o == "*" {
+			p.anyOrigin = true
+		}
+		p.origins[strings.ToLower(o)] = true
+	}
+	for _, m := range cfg.AllowedMethods {
+		p.methods[strings.ToUpper(m)] = true
+	}
+	for _, h := range cfg.AllowedHeaders {
+		if h == "*" {
+			p.anyHeader = true
+		}
+		p.headers[http.CanonicalHeaderKey(h)] = true
+	}
+	return p
+}
+
+func (p *policy) allowOrigin(origin string) bool {
+	return p.anyOrigin || p.origins[strings.ToLower(origin)]
+}
+
+// preflightOK reports whether the requested method and headers are all
+// within the policy.
+func (p *policy) preflightOK(r *http.Request) bool {
+	if !p.methods[strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))] {
+		return false
+	}
+	if p.anyHeader {
+		return true
+	}
+	for _, h := range splitList(r.Header.Get("Access-Control-Request-Headers")) {
+		if !p.headers[http.CanonicalHeaderKey(h)] {
+			return false
+		}
+	}
+	return true
+}
+
+// splitList splits a comma-separated header value, dropping empty items.
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+func (p *policy) writeOriginHeaders(w http.ResponseWriter, origin string) {
+	h := w.Header()
+	if p.anyOrigin && !p.cfg.AllowCredentials {
+		h.Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	h.Set("Access-Control-Allow-Origin", origin)
+	if p.cfg.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
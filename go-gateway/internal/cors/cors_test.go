@@ -0,0 +1,170 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func do(cfg Config, method, origin string, hdr map[string]string) (*httptest.ResponseRecorder, bool) {
+	var reached bool
+	h := Middleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+		w.WriteHeader(http.StatusOK)
+	}))
+	req := httptest.NewRequest(method, "/api/v1/content", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	for k, v := range hdr {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec, reached
+}
+
+func testConfig() Config {
+	return Config{
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowedMethods: DefaultMethods,
+		AllowedHeaders: DefaultHeaders,
+		ExposedHeaders: DefaultExposedHeaders,
+		MaxAge:         DefaultMaxAge,
+	}
+}
+
+func TestPreflight(t *testing.T) {
+	tests := []struct {
+		name       string
+		origin     string
+		reqMethod  string
+		reqHeaders string
+		wantAllow  bool
+	}{
+		{"allowed", "https://app.example.com", "POST", "content-type, authorization", true},
+		{"origin case-insensitive", "https://APP.example.com", "POST", "", true},
+		{"disallowed origin", "https://evil.example", "POST", "", false},
+		{"disallowed method", "https://app.example.com", "TRACE", "", false},
+		{"disallowed header", "https://app.example.com", "POST", "X-Secret", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hdr := map[string]string{"Access-Control-Request-Method": tt.reqMethod}
+			if tt.reqHeaders != "" {
+				hdr["Access-Control-Request-Headers"] = tt.reqHeaders
+			}
+			rec, reached := do(testConfig(), http.MethodOptions, tt.origin, hdr)
+			if reached {
+				t.Error("preflight reached the handler")
+			}
+			if rec.Code != http.StatusNoContent {
+				t.Errorf("status = %d, want 204", rec.Code)
+			}
+			got := rec.Header().Get("Access-Control-Allow-Origin")
+			if tt.wantAllow && got != tt.origin {
+				t.Errorf("Allow-Origin = %q, want %q", got, tt.origin)
+			}
+			if !tt.wantAllow && got != "" {
+				t.Errorf("Allow-Origin = %q, want none", got)
+			}
+			if tt.wantAllow && rec.Header().Get("Access-Control-Allow-Methods") == "" {
+				t.Error("missing Allow-Methods")
+			}
+			if got := rec.Header().Get("Access-Control-Max-Age"); tt.wantAllow != (got == "600") || !tt.wantAllow && got != "" {
+				t.Errorf("Max-Age = %q", got)
+			}
+		})
+	}
+}
+
+func TestSimpleRequests(t *testing.T) {
+	rec, reached := do(testConfig(), http.MethodGet, "https://app.example.com", nil)
+	if !reached || rec.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" {
+		t.Errorf("allowed origin: reached=%v headers=%v", reached, rec.Header())
+	}
+	if rec.Header().Get("Access-Control-Expose-Headers") == "" {
+		t.Error("missing Expose-Headers")
+	}
+
+	rec, reached = do(testConfig(), http.MethodGet, "https://evil.example", nil)
+	if !reached {
+		t.Error("disallowed origin was blocked; it should pass through")
+	}
+	if rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Error("disallowed origin got CORS headers")
+	}
+
+	rec, reached = do(testConfig(), http.MethodGet, "", nil)
+	if !reached || rec.Header().Get("Vary") != "" {
+		t.Errorf("no origin: reached=%v headers=%v", reached, rec.Header())
+	}
+}
+
+func TestWildcard(t *testing.T) {
+	cfg := testConfig()
+	cfg.AllowedOrigins = []string{"*"}
+	rec, _ := do(cfg, http.MethodGet, "https://anything.example", nil)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("without credentials Allow-Origin = %q, want *", got)
+	}
+
+	cfg.AllowCredentials = true
+	rec, _ = do(cfg, http.MethodGet, "https://anything.example", nil)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://anything.example" {
+		t.Errorf("with credentials Allow-Origin = %q, want echoed origin", got)
+	}
+	if rec.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Error("missing Allow-Credentials")
+	}
+}
+
+func TestPolicySet(t *testing.T) {
+	p := New(testConfig())
+	h := p.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	allowOrigin := func(origin string) string {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Origin", origin)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Header().Get("Access-Control-Allow-Origin")
+	}
+
+	if allowOrigin("https://new.example.com") != "" {
+		t.Fatal("origin allowed before Set")
+	}
+	cfg := testConfig()
+	cfg.AllowedOrigins = []string{"https://new.example.com"}
+	p.Set(cfg)
+	if allowOrigin("https://new.example.com") != "https://new.example.com" {
+		t.Error("new origin not allowed after Set")
+	}
+	if allowOrigin("https://app.example.com") != "" {
+		t.Error("old origin still allowed after Set")
+	}
+}
+
+func TestPrefixed(t *testing.T) {
+	open := testConfig()
+	open.AllowedOrigins, open.AllowedMethods = []string{"*"}, []string{http.MethodGet}
+	h := Prefixed(New(testConfig()), "/api/v1/public/", New(open))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	preflight := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodOptions, path, nil)
+		req.Header.Set("Origin", "https://blog.example")
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if got := preflight("/api/v1/public/content/1").Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("under the prefix Allow-Origin = %q, want *", got)
+	}
+	rec := preflight("/api/v1/jobs/1")
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("elsewhere Allow-Origin = %q, want none", got)
+	}
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("elsewhere status = %d, want 204", rec.Code)
+	}
+}
@@ -0,0 +1,259 @@
+// Package fairqueue shares the content service fairly between the
+// principals calling it. Once the gateway has as many calls in flight as
+// it allows, further calls wait in a queue per principal, and each slot
+// that frees up goes to the next principal in turn rather than to the
+// oldest call, so one client flooding the gateway cannot starve the rest.
+package fairqueue
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/metrics"
+	"github.com/content-factory/go-gateway/internal/rpc"
+)
+
+// DefaultMaxQueued is how many calls a principal may have waiting when
+// Config.MaxQueued is zero.
+const DefaultMaxQueued = 16
+
+// Config tunes a Scheduler.
+type Config struct {
+	// MaxInFlight is how many calls may run at once; 0 lets every call
+	// through unscheduled.
+	MaxInFlight int
+	// MaxQueued is how many calls each principal may have waiting for a
+	// slot. Calls beyond it fail with ErrQueueFull.
+	MaxQueued int
+}
+
+// ErrQueueFull is returned for a call that would exceed its principal's
+// queue. Its ResourceExhausted code makes handlers answer 429.
+var ErrQueueFull = rpc.Errorf(rpc.ResourceExhausted, "too many of the caller's requests are already waiting for the content service; retry later")
+
+// Internal is the principal of calls the gateway makes on its own behalf,
+// such as queued submissions and webhook checks.
+const Internal = "gateway"
+
+var (
+	inFlightGauge = metrics.NewGaugeVec("gateway_fair_queue_in_flight",
+		"Backend calls admitted by the fair scheduler and running.")
+	depthGauge = metrics.NewGaugeVec("gateway_fair_queue_depth",
+		"Backend calls waiting for a slot, by principal. Principals with none waiting are not listed.",
+		"principal")
+	waitSeconds = metrics.NewHistogramVec("gateway_fair_queue_wait_seconds",
+		"Time backend calls waited for a slot, by outcome: admitted or canceled.",
+		nil, "outcome")
+	rejectedTotal = metrics.NewCounterVec("gateway_fair_queue_rejected_total",
+		"Backend calls refused with 429 because their principal's queue was full.")
+)
+
+// Scheduler admits calls up to its in-flight limit and queues the rest by
+// principal, serving the queues round-robin.
+type Scheduler struct {
+	cfg Config
+
+	mu      sync.Mutex
+	running int
+	queues  map[string][]*waiter
+	// turn lists the principals with calls waiting, next to be served
+	// first.
+	turn []string
+}
+
+type waiter struct {
+	// ready is closed once the call is given a slot.
+	ready chan struct{}
+}
+
+// New returns a Scheduler. It lets every call through if cfg.MaxInFlight
+// is 0.
+func New(cfg Config) *Scheduler {
+	if cfg.MaxQueued <= 0 {
+		cfg.MaxQueued = DefaultMaxQueued
+	}
+	return &Scheduler{cfg: cfg, queues: make(map[string][]*waiter)}
+}
+
+// Acquire waits for a slot for a call of principal's. It fails with
+// ErrQueueFull if principal already has its limit of calls waiting, or
+// with ctx's error if ctx is done first, in which case the call leaves
+// the queue. Otherwise the caller must call release once the call is
+// over.
+func (s *Scheduler) Acquire(ctx context.Context, principal string) (release func(), err error) {
+	if s.cfg.MaxInFlight <= 0 {
+		return func() {}, nil
+	}
+	s.mu.Lock()
+	if s.running < s.cfg.MaxInFlight && len(s.turn) == 0 {
+		s.admit()
+		s.mu.Unlock()
+		waitSeconds.With("admitted").Observe(0)
+		return s.releaser(), nil
+	}
+	q := s.queues[principal]
+	if len(q) >= s.cfg.MaxQueued {
+		s.mu.Unlock()
+		rejectedTotal.With().Inc()
+		return nil, ErrQueueFull
+	}
+	w := &waiter{ready: make(chan struct{})}
+	if len(q) == 0 {
+		s.turn = append(s.turn, principal)
+	}
+	s.queues[principal] = append(q, w)
+	depthGauge.With(principal).Set(float64(len(q) + 1))
+	s.mu.Unlock()
+
+	start := time.Now()
+	select {
+	case <-w.ready:
+		waitSeconds.With("admitted").Observe(time.Since(start).Seconds())
+		return s.releaser(), nil
+	case <-ctx.Done():
+	}
+	s.mu.Lock()
+	select {
+	case <-w.ready:
+		// Given a slot as ctx ended: pass it on.
+		s.mu.Unlock()
+		s.release()
+	default:
+		s.remove(principal, w)
+		s.mu.Unlock()
+	}
+	waitSeconds.With("canceled").Observe(time.Since(start).Seconds())
+	return nil, ctx.Err()
+}
+
+// releaser returns the release function of an admitted call, which is
+// safe to call more than once.
+func (s *Scheduler) releaser() func() {
+	var once sync.Once
+	return func() { once.Do(s.release) }
+}
+
+func (s *Scheduler) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running--
+	inFlightGauge.With().Dec()
+	s.dispatch()
+}
+
+// admit takes a slot. Callers hold s.mu.
+func (s *Scheduler) admit() {
+	s.running++
+	inFlightGauge.With().Inc()
+}
+
+// dispatch hands free slots to the first call of each principal in turn,
+// moving a principal with more calls waiting to the back. Callers hold
+// s.mu.
+func (s *Scheduler) dispatch() {
+	for s.running < s.cfg.MaxInFlight && len(s.turn) > 0 {
+		principal := s.turn[0]
+		s.turn = s.turn[1:]
+		q := s.queues[principal]
+		w := q[0]
+		s.setQueue(principal, q[1:])
+		if len(q) > 1 {
+			s.turn = append(s.turn, principal)
+		}
+		s.admit()
+		close(w.ready)
+	}
+}
+
+// remove takes w out of principal's queue. Callers hold s.mu.
+func (s *Scheduler) remove(principal string, w *waiter) {
+	q := s.queues[principal]
+	for i, other := range q {
+		if other == w {
+			q = append(q[:i:i], q[i+1:]...)
+			break
+		}
+	}
+	s.setQueue(principal, q)
+	if len(q) > 0 {
+		return
+	}
+	for i, p := range s.turn {
+		if p == principal {
+			s.turn = append(s.turn[:i:i], s.turn[i+1:]...)
+			break
+		}
+	}
+}
+
+// setQueue replaces principal's queue, forgetting it once empty. Callers
+// hold s.mu.
+func (s *Scheduler) setQueue(principal string, q []*waiter) {
+	if len(q) == 0 {
+		delete(s.queues, principal)
+		depthGauge.Delete(principal)
+		return
+	}
+	s.queues[principal] = q
+	depthGauge.With(principal).Set(float64(len(q)))
+}
+
+// Principal returns the principal a call made for ctx is scheduled as,
+// named as the rate limiter keys clients: by API key, JWT subject or
+// client IP, after the tenant if there is one. Calls with none of these
+// are the gateway's own, Internal.
+func Principal(ctx context.Context) string {
+	var prefix string
+	if id := gateway.TenantID(ctx); id != "" {
+		prefix = "tenant:" + id + ":"
+	}
+	if k := gateway.APIKeyFromContext(ctx); k != nil {
+		return prefix + "apikey:" + k.ID
+	}
+	if claims := gateway.ClaimsFromContext(ctx); claims != nil && claims.Subject != "" {
+		return prefix + "user:" + claims.Subject
+	}
+	if ip := gateway.ClientIPFromContext(ctx); ip != "" {
+		return prefix + "ip:" + ip
+	}
+	return prefix + Internal
+}
+
+// Conn is the backend transport; it matches backend.Conn.
+type Conn interface {
+	Invoke(ctx context.Context, method string, req, resp any) error
+	NewStream(ctx context.Context, method string, req any) (rpc.ClientStream, error)
+}
+
+// Wrap schedules every call on conn with s, by the Principal of its
+// context. A unary call holds its slot until it returns; a stream holds
+// one only while it is opened, since progress streams and downloads may
+// last as long as the client likes.
+func Wrap(conn Conn, s *Scheduler) Conn {
+	return &scheduled{conn: conn, s: s}
+}
+
+type scheduled struct {
+	conn Conn
+	s    *Scheduler
+}
+
+func (c *scheduled) Invoke(ctx context.Context, method string, req, resp any) error {
+	release, err := c.s.Acquire(ctx, Principal(ctx))
+	if err != nil {
+		return err
+	}
+	defer release()
+	return c.conn.Invoke(ctx, method, req, resp)
+}
+
+func (c *scheduled) NewStream(ctx context.Context, method string, req any) (rpc.ClientStream, error) {
+	release, err := c.s.Acquire(ctx, Principal(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return c.conn.NewStream(ctx, method, req)
+}
@@ -0,0 +1,206 @@
+package fairqueue
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/rpc"
+)
+
+// waiting returns how many calls principal has queued on s.
+func waiting(s *Scheduler, principal string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.queues[principal])
+}
+
+// inFlight returns how many calls s has admitted and not released.
+func inFlight(s *Scheduler) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running
+}
+
+// enqueue starts a call for principal and waits until it is queued. The
+// call records name in order once admitted, then releases its slot.
+func enqueue(t *testing.T, s *Scheduler, wg *sync.WaitGroup, mu *sync.Mutex, order *[]string, principal, name string) {
+	t.Helper()
+	before := waiting(s, principal)
+	wg.Go(func() {
+		release, err := s.Acquire(context.Background(), principal)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			return
+		}
+		mu.Lock()
+		*order = append(*order, name)
+		mu.Unlock()
+		release()
+	})
+	for waiting(s, principal) == before {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRoundRobin(t *testing.T) {
+	s := New(Config{MaxInFlight: 1})
+	hold, err := s.Acquire(context.Background(), "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		order []string
+	)
+	for _, name := range []string{"a1", "a2", "a3"} {
+		enqueue(t, s, &wg, &mu, &order, "alice", name)
+	}
+	enqueue(t, s, &wg, &mu, &order, "bob", "b1")
+	enqueue(t, s, &wg, &mu, &order, "carol", "c1")
+	hold()
+	wg.Wait()
+
+	if got := strings.Join(order, ","); got != "a1,b1,c1,a2,a3" {
+		t.Errorf("admitted %s, want a1,b1,c1,a2,a3", got)
+	}
+	if s.running != 0 || len(s.turn) != 0 || len(s.queues) != 0 {
+		t.Errorf("after draining: running %d, turn %v, queues %v", s.running, s.turn, s.queues)
+	}
+}
+
+func TestQueueFull(t *testing.T) {
+	s := New(Config{MaxInFlight: 1, MaxQueued: 2})
+	hold, _ := s.Acquire(context.Background(), "alice")
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		order []string
+	)
+	enqueue(t, s, &wg, &mu, &order, "alice", "a1")
+	enqueue(t, s, &wg, &mu, &order, "alice", "a2")
+
+	before := rejectedTotal.With().Value()
+	if _, err := s.Acquire(context.Background(), "alice"); !errors.Is(err, ErrQueueFull) || rpc.CodeOf(err) != rpc.ResourceExhausted {
+		t.Errorf("third queued call: %v, want ErrQueueFull", err)
+	}
+	if rejectedTotal.With().Value() != before+1 {
+		t.Error("rejection not counted")
+	}
+	if depthGauge.With("alice").Value() != 2 {
+		t.Errorf("depth = %v, want 2", depthGauge.With("alice").Value())
+	}
+	// Another principal still gets a place.
+	enqueue(t, s, &wg, &mu, &order, "bob", "b1")
+	hold()
+	wg.Wait()
+}
+
+func TestCancelLeavesQueue(t *testing.T) {
+	s := New(Config{MaxInFlight: 1})
+	hold, _ := s.Acquire(context.Background(), "alice")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := s.Acquire(ctx, "alice")
+		done <- err
+	}()
+	for waiting(s, "alice") == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled call: %v", err)
+	}
+	if waiting(s, "alice") != 0 || len(s.turn) != 0 {
+		t.Errorf("cancelled call still queued: turn %v", s.turn)
+	}
+
+	// The slot goes to the next caller, not the cancelled one.
+	hold()
+	release, err := s.Acquire(context.Background(), "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	release()
+	if s.running != 0 {
+		t.Errorf("running = %d after release, want 0", s.running)
+	}
+}
+
+func TestUnlimited(t *testing.T) {
+	s := New(Config{})
+	for range 100 {
+		if _, err := s.Acquire(context.Background(), "alice"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPrincipal(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		ctx  context.Context
+		want string
+	}{
+		{ctx, Internal},
+		{gateway.WithClientIP(ctx, "203.0.113.7"), "ip:203.0.113.7"},
+		{gateway.WithClaims(gateway.WithClientIP(ctx, "203.0.113.7"), &gateway.Claims{Subject: "u1"}), "user:u1"},
+		{gateway.WithAPIKey(gateway.WithClaims(ctx, &gateway.Claims{Subject: "svc"}), &gateway.APIKey{ID: "k1"}), "apikey:k1"},
+		{gateway.WithTenant(gateway.WithClaims(ctx, &gateway.Claims{Subject: "u1"}), &gateway.Tenant{ID: "acme"}), "tenant:acme:user:u1"},
+	}
+	for _, tt := range tests {
+		if got := Principal(tt.ctx); got != tt.want {
+			t.Errorf("Principal = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+// blockingConn holds unary calls until release is closed and opens
+// streams at once.
+type blockingConn struct {
+	release chan struct{}
+}
+
+func (c blockingConn) Invoke(ctx context.Context, method string, req, resp any) error {
+	<-c.release
+	return nil
+}
+
+func (c blockingConn) NewStream(ctx context.Context, method string, req any) (rpc.ClientStream, error) {
+	return nil, nil
+}
+
+func TestWrap(t *testing.T) {
+	s := New(Config{MaxInFlight: 1, MaxQueued: 1})
+	conn := Wrap(blockingConn{release: make(chan struct{})}, s)
+	ctx := gateway.WithClaims(context.Background(), &gateway.Claims{Subject: "u1"})
+
+	// A stream gives its slot back once open.
+	for range 3 {
+		if _, err := conn.NewStream(ctx, "/svc/Watch", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	conn = Wrap(blockingConn{release: make(chan struct{})}, s)
+	release := conn.(*scheduled).conn.(blockingConn).release
+	var wg sync.WaitGroup
+	wg.Go(func() { conn.Invoke(ctx, "/svc/Get", nil, nil) })
+	for inFlight(s) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	short, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := conn.Invoke(short, "/svc/Get", nil, nil); rpc.CodeOf(err) != rpc.DeadlineExceeded {
+		t.Errorf("call queued past its deadline: %v", err)
+	}
+	close(release)
+	wg.Wait()
+}
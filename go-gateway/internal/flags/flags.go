@@ -0,0 +1,180 @@
+// Package flags implements the gateway's feature flags, which ship
+// endpoints and behaviour to part of the traffic before everyone gets
+// them.
+//
+// A flag is on for a request when it is enabled outright, when the
+// caller's principal is listed or falls inside its percentage rollout, or,
+// for flags that allow it, when the request names it in X-Feature-Flags.
+// Handlers ask with Enabled. Whole routes are gated with Visible and
+// Require, which answer 404 while the flag is off so the route's existence
+// stays private.
+package flags
+
+import (
+	"context"
+	"hash/fnv"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/gateway"
+)
+
+// Header lists, comma-separated, the flags a request opts in to. Only
+// flags with AllowHeader set honour it.
+const Header = "X-Feature-Flags"
+
+// Flag is one feature flag's settings.
+type Flag struct {
+	Name string
+	// Enabled turns the flag on for every request.
+	Enabled bool
+	// Percent turns the flag on for that share of principals, from 0 to
+	// 100. Each principal's place is a hash of the principal and the flag
+	// name, so raising the share keeps everyone who already had the flag.
+	Percent float64
+	// Principals always get the flag.
+	Principals []string
+	// AllowHeader lets any request turn the flag on through Header, for
+	// testing in production.
+	AllowHeader bool
+}
+
+// on reports whether f is on for principal, having been asked for in the
+// request's Header if requested.
+func (f *Flag) on(principal string, requested bool) bool {
+	switch {
+	case f.Enabled, f.AllowHeader && requested:
+		return true
+	case principal == "":
+		return false
+	case slices.Contains(f.Principals, principal):
+		return true
+	}
+	return f.Percent > 0 && float64(bucket(f.Name, principal)) < f.Percent*100
+}
+
+// reachable reports whether f may be on for some caller of a request,
+// before the caller is known.
+func (f *Flag) reachable(requested bool) bool {
+	return f.Enabled || f.AllowHeader && requested || f.Percent > 0 || len(f.Principals) > 0
+}
+
+// bucket places principal in one of 10000 buckets for flag name.
+func bucket(name, principal string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	h.Write([]byte{0})
+	h.Write([]byte(principal))
+	return h.Sum32() % 10000
+}
+
+// Set holds the flags in force. Its settings can be replaced while
+// serving; a request keeps the ones it started with.
+type Set struct {
+	flags atomic.Pointer[map[string]*Flag]
+}
+
+// New returns a Set of flags.
+func New(flags []Flag) *Set {
+	s := &Set{}
+	s.Set(flags)
+	return s
+}
+
+// Set replaces the flags, for requests that start from now on.
+func (s *Set) Set(flags []Flag) {
+	m := make(map[string]*Flag, len(flags))
+	for _, f := range flags {
+		f.Principals = slices.Clone(f.Principals)
+		m[f.Name] = &f
+	}
+	s.flags.Store(&m)
+}
+
+type evalKey struct{}
+
+// evaluation is what a request's flags are decided from: the flags in
+// force when it started and the ones it asked for.
+type evaluation struct {
+	flags     map[string]*Flag
+	requested map[string]bool
+}
+
+// Middleware fixes the flags of each request, for Enabled to read. It can
+// run before authentication: the principal is looked up when a flag is
+// asked about.
+func (s *Set) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, s.attach(r))
+	})
+}
+
+// attach returns r carrying its evaluation, unless it already does.
+func (s *Set) attach(r *http.Request) *http.Request {
+	if _, ok := r.Context().Value(evalKey{}).(*evaluation); ok {
+		return r
+	}
+	ev := &evaluation{flags: *s.flags.Load()}
+	for _, value := range r.Header.Values(Header) {
+		for name := range strings.SplitSeq(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				if ev.requested == nil {
+					ev.requested = make(map[string]bool)
+				}
+				ev.requested[name] = true
+			}
+		}
+	}
+	return r.WithContext(context.WithValue(r.Context(), evalKey{}, ev))
+}
+
+// Enabled reports whether the named flag is on for the request ctx
+// belongs to. Unknown flags, and requests that did not pass through a
+// Set's Middleware, are off. Percentage and principal rollouts need the
+// caller, so ask after authentication.
+func Enabled(ctx context.Context, name string) bool {
+	ev, ok := ctx.Value(evalKey{}).(*evaluation)
+	if !ok {
+		return false
+	}
+	f, ok := ev.flags[name]
+	if !ok {
+		return false
+	}
+	var principal string
+	if claims := gateway.ClaimsFromContext(ctx); claims != nil {
+		principal = claims.Subject
+	}
+	return f.on(principal, ev.requested[name])
+}
+
+// Visible reports, for router.HandleWhen, whether a route behind the named
+// flag may exist for r: whether the flag can be on for some caller of r,
+// before authentication tells who it is. The router answers requests it
+// is not visible to as if the route did not exist, so even
+// unauthenticated requests cannot learn of it.
+func (s *Set) Visible(name string) func(*http.Request) bool {
+	return func(r *http.Request) bool {
+		ev := s.attach(r).Context().Value(evalKey{}).(*evaluation)
+		f, ok := ev.flags[name]
+		return ok && f.reachable(ev.requested[name])
+	}
+}
+
+// Require serves a route's handler only while the named flag is on for
+// the request, answering 404 like an unknown path otherwise. Mount it
+// after authentication, together with Visible.
+func Require(name string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !Enabled(r.Context(), name) {
+				apierror.Write(w, r, apierror.NotFound("no route matches "+r.URL.Path))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package flags
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/router"
+)
+
+// check runs one request as principal through s and reports whether the
+// named flag was on for it.
+func check(s *Set, name, principal, header string) bool {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if header != "" {
+		req.Header.Set(Header, header)
+	}
+	if principal != "" {
+		req = req.WithContext(gateway.WithClaims(req.Context(), &gateway.Claims{Subject: principal}))
+	}
+	var on bool
+	s.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		on = Enabled(r.Context(), name)
+	})).ServeHTTP(httptest.NewRecorder(), req)
+	return on
+}
+
+func TestEnabled(t *testing.T) {
+	s := New([]Flag{
+		{Name: "everyone", Enabled: true},
+		{Name: "listed", Principals: []string{"user-1"}},
+		{Name: "opt_in", AllowHeader: true},
+		{Name: "no_opt_in"},
+	})
+	tests := []struct {
+		name, principal, header string
+		want                    bool
+	}{
+		{"everyone", "", "", true},
+		{"listed", "user-1", "", true},
+		{"listed", "user-2", "", false},
+		{"listed", "", "", false},
+		{"opt_in", "", "opt_in", true},
+		{"opt_in", "", "other, opt_in", true},
+		{"opt_in", "user-1", "", false},
+		{"no_opt_in", "user-1", "no_opt_in", false},
+		{"unknown", "user-1", "unknown", false},
+	}
+	for _, tt := range tests {
+		if got := check(s, tt.name, tt.principal, tt.header); got != tt.want {
+			t.Errorf("Enabled(%s) for %q with %q = %v, want %v", tt.name, tt.principal, tt.header, got, tt.want)
+		}
+	}
+	if Enabled(httptest.NewRequest(http.MethodGet, "/", nil).Context(), "everyone") {
+		t.Error("enabled without the middleware")
+	}
+}
+
+func TestPercentRollout(t *testing.T) {
+	s := New([]Flag{{Name: "new_summarizer", Percent: 25}})
+	var on []string
+	for i := range 2000 {
+		if p := fmt.Sprintf("user-%d", i); check(s, "new_summarizer", p, "") {
+			on = append(on, p)
+		}
+	}
+	if n := len(on); n < 400 || n > 600 {
+		t.Errorf("%d of 2000 principals in a 25%% rollout", n)
+	}
+	// Growing the rollout keeps everyone already in it.
+	s.Set([]Flag{{Name: "new_summarizer", Percent: 50}})
+	for _, p := range on {
+		if !check(s, "new_summarizer", p, "") {
+			t.Fatalf("%s dropped out when the rollout grew", p)
+		}
+	}
+	if check(s, "new_summarizer", "", "") {
+		t.Error("anonymous request in a percentage rollout")
+	}
+}
+
+func TestRequestKeepsItsFlags(t *testing.T) {
+	s := New([]Flag{{Name: "beta", Enabled: true}})
+	var before, after bool
+	s.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		before = Enabled(r.Context(), "beta")
+		s.Set(nil)
+		after = Enabled(r.Context(), "beta")
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if !before || !after {
+		t.Errorf("before = %v, after = %v", before, after)
+	}
+}
+
+func TestGatedRoute(t *testing.T) {
+	s := New([]Flag{{Name: "beta", Principals: []string{"user-1"}}})
+	rt := router.New()
+	authenticate := func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			sub := r.Header.Get("Authorization")
+			if sub == "" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			h.ServeHTTP(w, r.WithContext(gateway.WithClaims(r.Context(), &gateway.Claims{Subject: sub})))
+		})
+	}
+	rt.HandleWhen(http.MethodGet, "/beta", authenticate(Require("beta")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("beta"))
+	}))), s.Visible("beta"))
+	serve := func(sub string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/beta", nil)
+		if sub != "" {
+			req.Header.Set("Authorization", sub)
+		}
+		rec := httptest.NewRecorder()
+		s.Middleware(rt).ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := serve("user-1"); rec.Code != http.StatusOK || rec.Body.String() != "beta" {
+		t.Errorf("listed principal: %d %q", rec.Code, rec.Body)
+	}
+	if rec := serve("user-2"); rec.Code != http.StatusNotFound {
+		t.Errorf("other principal: %d, want 404", rec.Code)
+	}
+
+	s.Set([]Flag{{Name: "beta"}})
+	for _, sub := range []string{"", "user-1"} {
+		if rec := serve(sub); rec.Code != http.StatusNotFound {
+			t.Errorf("flag off, principal %q: %d, want 404", sub, rec.Code)
+		}
+	}
+}
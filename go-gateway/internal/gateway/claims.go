@@ -0,0 +1,46 @@
+package gateway
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+)
+
+// NumericDate is a JWT timestamp: seconds since the Unix epoch. Issuers
+// are allowed to send fractional seconds, so it decodes from any JSON
+// number.
+type NumericDate int64
+
+// Time converts d to a time.Time.
+func (d NumericDate) Time() time.Time {
+	return time.Unix(int64(d), 0)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *NumericDate) UnmarshalJSON(b []byte) error {
+	var f float64
+	if err := json.Unmarshal(b, &f); err != nil {
+		return err
+	}
+	*d = NumericDate(math.Floor(f))
+	return nil
+}
+
+// Audience is the JWT "aud" claim, which may be a single string or an
+// array of strings.
+type Audience []string
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (a *Audience) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
@@ -0,0 +1,127 @@
+// Package gateway holds the request-scoped values that middleware attaches
+// to a request context and that handlers read back.
+package gateway
+
+import (
+	"context"
+	"errors"
+)
+
+type claimsKey struct{}
+
+// Claims is the verified payload of a caller's JWT.
+type Claims struct {
+	Subject   string      `json:"sub"`
+	Issuer    string      `json:"iss,omitempty"`
+	Audience  Audience    `json:"aud,omitempty"`
+	ExpiresAt NumericDate `json:"exp,omitempty"`
+	NotBefore NumericDate `json:"nbf,omitempty"`
+	IssuedAt  NumericDate `json:"iat,omitempty"`
+	Scope     string      `json:"scope,omitempty"`
+	// Tenant names the tenant the caller belongs to, see package tenant.
+	Tenant string `json:"tenant,omitempty"`
+}
+
+// WithClaims returns a copy of ctx carrying c.
+func WithClaims(ctx context.Context, c *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, c)
+}
+
+// ClaimsFromContext returns the claims stored by the authentication
+// middleware, or nil when the request was not authenticated.
+func ClaimsFromContext(ctx context.Context) *Claims {
+	c, _ := ctx.Value(claimsKey{}).(*Claims)
+	return c
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the ID assigned to the current request, or
+// "" outside a request.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+type apiKeyKey struct{}
+
+// APIKey describes the API key a request authenticated with. RPS and
+// Burst, when positive, replace the default rate limit for the key.
+type APIKey struct {
+	ID    string
+	RPS   float64
+	Burst int
+}
+
+// WithAPIKey returns a copy of ctx carrying k.
+func WithAPIKey(ctx context.Context, k *APIKey) context.Context {
+	return context.WithValue(ctx, apiKeyKey{}, k)
+}
+
+// APIKeyFromContext returns the API key the request authenticated with,
+// or nil for JWT-authenticated and anonymous requests.
+func APIKeyFromContext(ctx context.Context) *APIKey {
+	k, _ := ctx.Value(apiKeyKey{}).(*APIKey)
+	return k
+}
+
+type tenantKey struct{}
+
+// Tenant is the tenant a request acts for. RPS and Burst, when positive,
+// replace the default rate limit of the tenant's clients; Formats, when
+// not empty, are the only content formats the tenant may request.
+type Tenant struct {
+	ID      string
+	RPS     float64
+	Burst   int
+	Formats []string
+}
+
+// WithTenant returns a copy of ctx carrying t.
+func WithTenant(ctx context.Context, t *Tenant) context.Context {
+	return context.WithValue(ctx, tenantKey{}, t)
+}
+
+// TenantFromContext returns the tenant the request acts for, or nil when
+// the gateway is not multi-tenant.
+func TenantFromContext(ctx context.Context) *Tenant {
+	t, _ := ctx.Value(tenantKey{}).(*Tenant)
+	return t
+}
+
+// TenantID returns the ID of the tenant the request acts for, or "".
+// Keys of anything shared between requests, like cached responses, start
+// with it, so tenants never see each other's entries.
+func TenantID(ctx context.Context) string {
+	if t := TenantFromContext(ctx); t != nil {
+		return t.ID
+	}
+	return ""
+}
+
+type clientIPKey struct{}
+
+// WithClientIP returns a copy of ctx carrying the client IP resolved for
+// the request.
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// ClientIPFromContext returns the client IP stored by WithClientIP, or "".
+func ClientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
+
+// ClientGone reports whether ctx, a request's context or one derived from
+// it, ended because the client went away: it was cancelled, where the
+// gateway's own timeouts end it with context.DeadlineExceeded. Work cut
+// short this way is neither the gateway's failure nor the backend's.
+func ClientGone(ctx context.Context) bool {
+	return errors.Is(ctx.Err(), context.Canceled)
+}
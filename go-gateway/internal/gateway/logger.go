@@ -0,0 +1,33 @@
+package gateway
+
+import (
+	"context"
+	"log/slog"
+)
+
+type loggerKey struct{}
+
+// WithLogger returns a copy of ctx carrying l as the request's logger.
+func WithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// WithLogAttrs returns a copy of ctx whose Logger also adds args, as
+// slog.Logger.With takes them, to every line.
+func WithLogAttrs(ctx context.Context, args ...any) context.Context {
+	return WithLogger(ctx, Logger(ctx).With(args...))
+}
+
+// Logger returns the logger of the request ctx belongs to. The request ID
+// middleware starts it with the request's request_id and method, the
+// router adds its route and authentication its principal, so lines logged
+// with it can be told apart by request. Contexts derived from ctx carry it
+// too, including context.WithoutCancel ones handed to goroutines that
+// outlive the request. Outside a request it returns slog.Default(), so it
+// is never nil.
+func Logger(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
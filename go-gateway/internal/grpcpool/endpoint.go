@@ -0,0 +1,209 @@
+package grpcpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/health"
+	"github.com/content-factory/go-gateway/internal/metrics"
+	"github.com/content-factory/go-gateway/internal/rpc"
+)
+
+// Outlier detection: an endpoint is ejected for ejectDuration once at
+// least ejectMinCalls of its last failureWindow calls were made and
+// ejectFailureRatio of them failed.
+const (
+	failureWindow     = 20
+	ejectMinCalls     = 10
+	ejectFailureRatio = 0.5
+	ejectDuration     = 30 * time.Second
+)
+
+var (
+	endpointHealthy = metrics.NewGaugeVec("gateway_backend_endpoint_healthy",
+		"Whether each content service endpoint is in rotation: 1, or 0 while its health check fails or it is ejected for failing calls.",
+		"endpoint", "zone")
+	endpointSelected = metrics.NewCounterVec("gateway_backend_endpoint_selected_total",
+		"Calls sent to each content service endpoint.",
+		"endpoint", "zone")
+	endpointEjections = metrics.NewCounterVec("gateway_backend_endpoint_ejections_total",
+		"Times each content service endpoint was taken out of rotation for failing too many recent calls.",
+		"endpoint", "zone")
+)
+
+// EndpointHealth reports the state of one endpoint.
+type EndpointHealth struct {
+	Addr      string `json:"address"`
+	Zone      string `json:"zone,omitempty"`
+	Preferred bool   `json:"preferred,omitempty"`
+	// Healthy reports whether the endpoint is in rotation.
+	Healthy bool `json:"healthy"`
+	// HealthCheck is the error of the last failed health check.
+	HealthCheck string `json:"health_check_error,omitempty"`
+	// EjectedUntil is set while the endpoint sits out for failing calls.
+	EjectedUntil *time.Time   `json:"ejected_until,omitempty"`
+	Connections  []ConnHealth `json:"connections"`
+}
+
+type endpoint struct {
+	Endpoint
+	preferred bool
+	slots     []*slot
+	next      atomic.Uint64
+
+	selected *metrics.Counter
+	healthy  *metrics.Gauge
+	ejected  *metrics.Counter
+
+	mu           sync.Mutex
+	checkErr     error // of the last health check; nil while it passes
+	ejectedUntil time.Time
+	outcomes     [failureWindow]bool // true for a failed call
+	calls        int                 // recorded in outcomes, at most failureWindow
+	pos          int
+}
+
+func newEndpoint(ep Endpoint, preferred bool) *endpoint {
+	e := &endpoint{
+		Endpoint:  ep,
+		preferred: preferred,
+		selected:  endpointSelected.With(ep.Addr, ep.Zone),
+		healthy:   endpointHealthy.With(ep.Addr, ep.Zone),
+		ejected:   endpointEjections.With(ep.Addr, ep.Zone),
+	}
+	e.healthy.Set(1)
+	return e
+}
+
+// usable reports whether e is in rotation at now.
+func (e *endpoint) usable(now time.Time) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.usableLocked(now)
+}
+
+func (e *endpoint) usableLocked(now time.Time) bool {
+	return e.checkErr == nil && !now.Before(e.ejectedUntil)
+}
+
+// failed reports whether a call outcome counts against the endpoint. Like
+// a connection, an endpoint that answers with an application error works;
+// cancellations are the caller's doing.
+func failed(err error) bool {
+	switch rpc.CodeOf(err) {
+	case rpc.Unavailable, rpc.DeadlineExceeded, rpc.Internal, rpc.Unknown:
+		return true
+	}
+	return false
+}
+
+// observe records a call's outcome, ejecting e if too many recent calls
+// failed.
+func (e *endpoint) observe(err error) {
+	if rpc.CodeOf(err) == rpc.Canceled {
+		return
+	}
+	now := time.Now()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.outcomes[e.pos] = failed(err)
+	e.pos = (e.pos + 1) % failureWindow
+	e.calls = min(e.calls+1, failureWindow)
+	if e.calls < ejectMinCalls || !e.usableLocked(now) {
+		return
+	}
+	failures := 0
+	for i := range e.calls {
+		if e.outcomes[i] {
+			failures++
+		}
+	}
+	if float64(failures) < ejectFailureRatio*float64(e.calls) {
+		return
+	}
+	e.ejectedUntil = now.Add(ejectDuration)
+	// It starts afresh when it returns.
+	e.calls, e.pos = 0, 0
+	e.ejected.Inc()
+	e.healthy.Set(0)
+	logTransition(e, false, "too many failed calls")
+}
+
+// check runs e's gRPC health check and updates its state. A backend
+// without the health service passes, as it does for readiness.
+func (e *endpoint) check(ctx context.Context, p *Pool) {
+	s, conn, ok := e.acquire(p)
+	if !ok {
+		s = e.slots[0]
+		s.mu.Lock()
+		conn = s.conn
+		s.mu.Unlock()
+	}
+	err := health.GRPCProbe(&probeConn{slot: s, conn: conn}, "")(ctx)
+	var degraded *health.DegradedError
+	if errors.As(err, &degraded) {
+		err = nil
+	}
+
+	now := time.Now()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	was := e.usableLocked(now)
+	e.checkErr = err
+	is := e.usableLocked(now)
+	if is {
+		e.healthy.Set(1)
+	} else {
+		e.healthy.Set(0)
+	}
+	switch {
+	case was && !is:
+		logTransition(e, false, "health check failed: "+err.Error())
+	case !was && is:
+		logTransition(e, true, "")
+	}
+}
+
+// probeConn makes the health check on one connection, recording its
+// outcome like any call.
+type probeConn struct {
+	slot *slot
+	conn rpc.Conn
+}
+
+func (c *probeConn) Invoke(ctx context.Context, method string, req, resp any) error {
+	err := c.conn.Invoke(ctx, method, req, resp)
+	c.slot.record(c.conn, err)
+	return err
+}
+
+func (e *endpoint) connHealth() []ConnHealth {
+	out := make([]ConnHealth, len(e.slots))
+	for i, s := range e.slots {
+		s.mu.Lock()
+		out[i] = ConnHealth{Endpoint: e.Addr, Index: i, State: s.state.String(), Since: s.since}
+		if s.lastErr != nil && s.state == TransientFailure {
+			out[i].LastError = s.lastErr.Error()
+		}
+		s.mu.Unlock()
+	}
+	return out
+}
+
+func (e *endpoint) health(now time.Time) EndpointHealth {
+	out := EndpointHealth{Addr: e.Addr, Zone: e.Zone, Preferred: e.preferred, Connections: e.connHealth()}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	out.Healthy = e.usableLocked(now)
+	if e.checkErr != nil {
+		out.HealthCheck = e.checkErr.Error()
+	}
+	if now.Before(e.ejectedUntil) {
+		until := e.ejectedUntil
+		out.EjectedUntil = &until
+	}
+	return out
+}
@@ -0,0 +1,420 @@
+// Package grpcpool maintains fixed-size pools of connections to the
+// backend content service's endpoints and spreads calls across them
+// round-robin.
+//
+// With several endpoints, say one per availability zone, the pool fails
+// over between them: an endpoint whose gRPC health check fails, or whose
+// recent calls mostly fail, is routed around until it recovers. Endpoints
+// in the preferred zone take all the traffic while any of them is usable.
+package grpcpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/stats"
+
+	"github.com/content-factory/go-gateway/internal/rpc"
+)
+
+// Defaults used by the config package.
+const (
+	DefaultAddr                = "orchestrator:50051"
+	DefaultSize                = 4
+	DefaultDialTimeout         = 5 * time.Second
+	DefaultKeepaliveTime       = 30 * time.Second
+	DefaultKeepaliveTimeout    = 10 * time.Second
+	DefaultHealthCheckInterval = 5 * time.Second
+	DefaultDeadlineMargin      = 50 * time.Millisecond
+	DefaultMaxResponseBytes    = 16 << 20
+)
+
+// reconnectBackoff is how long a failed connection sits out of rotation
+// before the pool tries to re-establish it.
+const reconnectBackoff = time.Second
+
+// Config describes the backend and how to connect to it.
+type Config struct {
+	// Addr lists the endpoints, comma-separated, see ParseEndpoints.
+	Addr string
+	// Size is the number of connections to each endpoint.
+	Size             int
+	DialTimeout      time.Duration
+	KeepaliveTime    time.Duration
+	KeepaliveTimeout time.Duration
+	// DeadlineMargin is taken off the time left on each call, see
+	// rpc.DialOptions.
+	DeadlineMargin time.Duration
+	// MaxResponseBytes caps a call's response, and Uncapped exempts
+	// streams from it, see rpc.DialOptions.
+	MaxResponseBytes int64
+	Uncapped         func(method string) bool
+	// StatsHandler, if set, observes every call, see rpc.DialOptions.
+	StatsHandler stats.Handler
+	// PreferredZone, if set, sends every call to the endpoints of that
+	// zone while any of them is usable.
+	PreferredZone string
+	// HealthCheckInterval is how often each endpoint's gRPC health service
+	// is asked about it; zero means DefaultHealthCheckInterval and a
+	// negative value turns the checks off.
+	HealthCheckInterval time.Duration
+}
+
+// Endpoint is one address of the content service.
+type Endpoint struct {
+	Addr string
+	Zone string
+}
+
+// ParseEndpoints parses a comma-separated endpoint list. Each entry is a
+// host:port, optionally followed by @ and its zone, as in
+// "orch-a:50051@us-east-1a,orch-b:50051@us-east-1b".
+func ParseEndpoints(list string) ([]Endpoint, error) {
+	var out []Endpoint
+	seen := make(map[string]bool)
+	for entry := range strings.SplitSeq(list, ",") {
+		entry = strings.TrimSpace(entry)
+		addr, zone, _ := strings.Cut(entry, "@")
+		if addr == "" {
+			return nil, fmt.Errorf("empty endpoint in %q", list)
+		}
+		if seen[addr] {
+			return nil, fmt.Errorf("duplicate endpoint %q", addr)
+		}
+		seen[addr] = true
+		out = append(out, Endpoint{Addr: addr, Zone: zone})
+	}
+	return out, nil
+}
+
+// State is the health of a single pooled connection.
+type State int
+
+// Connection states, loosely following gRPC's connectivity states.
+const (
+	Idle State = iota
+	Ready
+	TransientFailure
+)
+
+func (s State) String() string {
+	switch s {
+	case Ready:
+		return "ready"
+	case TransientFailure:
+		return "transient_failure"
+	}
+	return "idle"
+}
+
+// ConnHealth reports the state of one pooled connection.
+type ConnHealth struct {
+	Endpoint  string    `json:"endpoint"`
+	Index     int       `json:"index"`
+	State     string    `json:"state"`
+	Since     time.Time `json:"since"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// ErrClosed is returned by Invoke after Close.
+var ErrClosed = rpc.Errorf(rpc.Unavailable, "grpcpool: pool is closed")
+
+// DialFunc creates a connection to addr. It is a field on Pool so tests can
+// substitute fakes.
+type DialFunc func(addr string, opts rpc.DialOptions) (rpc.Conn, error)
+
+func defaultDial(addr string, opts rpc.DialOptions) (rpc.Conn, error) {
+	return rpc.Dial(addr, opts)
+}
+
+type slot struct {
+	mu      sync.Mutex
+	conn    rpc.Conn
+	state   State
+	since   time.Time
+	lastErr error
+}
+
+// Pool is a round-robin pool of backend connections. Connections that fail
+// at the transport level are taken out of rotation and lazily re-dialled
+// the next time they are picked after a short backoff.
+type Pool struct {
+	cfg       Config
+	dial      DialFunc
+	endpoints []*endpoint
+	next      atomic.Uint64
+	closed    atomic.Bool
+	stop      chan struct{}
+	checks    sync.WaitGroup
+}
+
+// New creates a pool. Connections are dialled lazily, so New does not fail
+// when the backend is down.
+func New(cfg Config) (*Pool, error) {
+	return newPool(cfg, defaultDial)
+}
+
+func newPool(cfg Config, dial DialFunc) (*Pool, error) {
+	if strings.TrimSpace(cfg.Addr) == "" {
+		return nil, errors.New("grpcpool: backend address is required")
+	}
+	eps, err := ParseEndpoints(cfg.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("grpcpool: %w", err)
+	}
+	if cfg.Size < 1 {
+		cfg.Size = DefaultSize
+	}
+	p := &Pool{cfg: cfg, dial: dial, stop: make(chan struct{})}
+	now := time.Now()
+	var dialled []rpc.Conn
+	for _, ep := range eps {
+		e := newEndpoint(ep, ep.Zone != "" && ep.Zone == cfg.PreferredZone)
+		e.slots = make([]*slot, cfg.Size)
+		for i := range e.slots {
+			conn, err := dial(ep.Addr, p.dialOptions())
+			if err != nil {
+				for _, c := range dialled {
+					c.Close()
+				}
+				return nil, fmt.Errorf("grpcpool: dial %s: %w", ep.Addr, err)
+			}
+			dialled = append(dialled, conn)
+			e.slots[i] = &slot{conn: conn, since: now}
+		}
+		p.endpoints = append(p.endpoints, e)
+	}
+	interval := cfg.HealthCheckInterval
+	if interval == 0 {
+		interval = DefaultHealthCheckInterval
+	}
+	if interval > 0 {
+		p.checks.Go(func() { p.checkHealth(interval) })
+	}
+	return p, nil
+}
+
+// Addr returns the endpoint list the pool connects to.
+func (p *Pool) Addr() string { return p.cfg.Addr }
+
+func (p *Pool) dialOptions() rpc.DialOptions {
+	return rpc.DialOptions{
+		DialTimeout:      p.cfg.DialTimeout,
+		KeepaliveTime:    p.cfg.KeepaliveTime,
+		KeepaliveTimeout: p.cfg.KeepaliveTimeout,
+		DeadlineMargin:   p.cfg.DeadlineMargin,
+		MaxResponseBytes: p.cfg.MaxResponseBytes,
+		Uncapped:         p.cfg.Uncapped,
+		StatsHandler:     p.cfg.StatsHandler,
+	}
+}
+
+// Invoke makes a unary call on the next healthy connection.
+func (p *Pool) Invoke(ctx context.Context, method string, req, resp any) error {
+	if p.closed.Load() {
+		return ErrClosed
+	}
+	e, s, conn := p.pick()
+	err := conn.Invoke(ctx, method, req, resp)
+	s.record(conn, err)
+	e.observe(err)
+	return err
+}
+
+// NewStream starts a server-streaming call on the next healthy connection.
+func (p *Pool) NewStream(ctx context.Context, method string, req any) (rpc.ClientStream, error) {
+	if p.closed.Load() {
+		return nil, ErrClosed
+	}
+	e, s, conn := p.pick()
+	stream, err := conn.NewStream(ctx, method, req)
+	s.record(conn, err)
+	e.observe(err)
+	return stream, err
+}
+
+// pick returns the next connection in rotation among the endpoints calls
+// should go to, skipping connections that are backing off after a failure
+// and re-dialling ones whose backoff expired.
+func (p *Pool) pick() (*endpoint, *slot, rpc.Conn) {
+	start := p.next.Add(1)
+	tier := p.candidates(time.Now())
+	n := uint64(len(tier))
+	for i := range n {
+		e := tier[(start+i)%n]
+		if s, conn, ok := e.acquire(p); ok {
+			e.selected.Inc()
+			return e, s, conn
+		}
+	}
+	// Everything is failing; try the nominal slot anyway rather than
+	// failing without making a call.
+	e := tier[start%n]
+	s := e.slots[e.next.Add(1)%uint64(len(e.slots))]
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.selected.Inc()
+	return e, s, s.conn
+}
+
+// candidates returns the endpoints to call: the usable ones in the
+// preferred zone, else every usable one, else, when none is usable, all of
+// them.
+func (p *Pool) candidates(now time.Time) []*endpoint {
+	var preferred, usable []*endpoint
+	for _, e := range p.endpoints {
+		if !e.usable(now) {
+			continue
+		}
+		usable = append(usable, e)
+		if e.preferred {
+			preferred = append(preferred, e)
+		}
+	}
+	switch {
+	case len(preferred) > 0:
+		return preferred
+	case len(usable) > 0:
+		return usable
+	}
+	return p.endpoints
+}
+
+// acquire returns the next connection of e in rotation that is not backing
+// off.
+func (e *endpoint) acquire(p *Pool) (*slot, rpc.Conn, bool) {
+	start := e.next.Add(1)
+	n := uint64(len(e.slots))
+	for i := range n {
+		s := e.slots[(start+i)%n]
+		if conn, ok := s.acquire(p, e.Addr); ok {
+			return s, conn, true
+		}
+	}
+	return nil, nil, false
+}
+
+func (s *slot) acquire(p *Pool, addr string) (rpc.Conn, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state != TransientFailure {
+		return s.conn, true
+	}
+	if time.Since(s.since) < reconnectBackoff {
+		return nil, false
+	}
+	conn, err := p.dial(addr, p.dialOptions())
+	if err != nil {
+		s.lastErr = err
+		s.since = time.Now()
+		return nil, false
+	}
+	s.conn.Close()
+	s.conn = conn
+	s.state = Idle
+	s.since = time.Now()
+	return s.conn, true
+}
+
+// record updates the slot's state from a call outcome. Any reply from the
+// backend, including an application error, proves the connection works;
+// only Unavailable indicates a broken transport.
+func (s *slot) record(conn rpc.Conn, err error) {
+	code := rpc.CodeOf(err)
+	if code == rpc.Canceled || code == rpc.DeadlineExceeded {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != conn {
+		return // re-dialled concurrently
+	}
+	next := Ready
+	if code == rpc.Unavailable {
+		next = TransientFailure
+		s.lastErr = err
+	}
+	if next != s.state {
+		s.state = next
+		s.since = time.Now()
+	}
+}
+
+// Health reports the state of each pooled connection, endpoint by
+// endpoint.
+func (p *Pool) Health() []ConnHealth {
+	var out []ConnHealth
+	for _, e := range p.endpoints {
+		out = append(out, e.connHealth()...)
+	}
+	return out
+}
+
+// Endpoints reports the health of each endpoint and its connections.
+func (p *Pool) Endpoints() []EndpointHealth {
+	now := time.Now()
+	out := make([]EndpointHealth, len(p.endpoints))
+	for i, e := range p.endpoints {
+		out[i] = e.health(now)
+	}
+	return out
+}
+
+// Close stops the health checks and closes every connection. Calls made
+// after Close fail with ErrClosed.
+func (p *Pool) Close() error {
+	if p.closed.Swap(true) {
+		return nil
+	}
+	close(p.stop)
+	p.checks.Wait()
+	var errs []error
+	for _, e := range p.endpoints {
+		for _, s := range e.slots {
+			s.mu.Lock()
+			if err := s.conn.Close(); err != nil {
+				errs = append(errs, err)
+			}
+			s.mu.Unlock()
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkHealth asks every endpoint's health service about it each interval
+// until the pool is closed.
+func (p *Pool) checkHealth(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-t.C:
+		}
+		var wg sync.WaitGroup
+		for _, e := range p.endpoints {
+			wg.Go(func() {
+				ctx, cancel := context.WithTimeout(context.Background(), min(interval, 2*time.Second))
+				defer cancel()
+				e.check(ctx, p)
+			})
+		}
+		wg.Wait()
+	}
+}
+
+// logTransition logs an endpoint leaving or rejoining the rotation.
+func logTransition(e *endpoint, usable bool, reason string) {
+	if usable {
+		slog.Info("backend endpoint back in rotation", "endpoint", e.Addr, "zone", e.Zone)
+		return
+	}
+	slog.Warn("backend endpoint taken out of rotation", "endpoint", e.Addr, "zone", e.Zone, "reason", reason)
+}
@@ -0,0 +1,306 @@
+package grpcpool
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/content-factory/go-gateway/internal/health"
+	"github.com/content-factory/go-gateway/internal/rpc"
+)
+
+type fakeConn struct {
+	id     int
+	addr   string
+	mu     sync.Mutex
+	err    error
+	status string // answered to health checks
+	calls  int
+	closed bool
+}
+
+func (c *fakeConn) Invoke(ctx context.Context, method string, req, resp any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if method == health.MethodHealthCheck {
+		if c.status == "" {
+			return rpc.Errorf(rpc.Unimplemented, "unknown service")
+		}
+		resp.(*healthpb.HealthCheckResponse).Status = healthpb.HealthCheckResponse_ServingStatus(healthpb.HealthCheckResponse_ServingStatus_value[c.status])
+		return nil
+	}
+	c.calls++
+	return c.err
+}
+
+func (c *fakeConn) NewStream(ctx context.Context, method string, req any) (rpc.ClientStream, error) {
+	return nil, c.Invoke(ctx, method, req, nil)
+}
+
+func (c *fakeConn) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+	return nil
+}
+
+type fakeDialer struct {
+	mu    sync.Mutex
+	conns []*fakeConn
+}
+
+func (d *fakeDialer) dial(addr string, opts rpc.DialOptions) (rpc.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	c := &fakeConn{id: len(d.conns), addr: addr}
+	d.conns = append(d.conns, c)
+	return c, nil
+}
+
+func TestRoundRobin(t *testing.T) {
+	d := &fakeDialer{}
+	p, err := newPool(Config{Addr: "backend:1", Size: 3}, d.dial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 9; i++ {
+		if err := p.Invoke(context.Background(), "/svc/M", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, c := range d.conns {
+		if c.calls != 3 {
+			t.Errorf("conn %d got %d calls, want 3", c.id, c.calls)
+		}
+	}
+}
+
+func TestFailedConnIsSkippedAndRedialled(t *testing.T) {
+	d := &fakeDialer{}
+	p, _ := newPool(Config{Addr: "backend:1", Size: 2}, d.dial)
+	broken := d.conns[0]
+	broken.err = rpc.Errorf(rpc.Unavailable, "connection refused")
+
+	// Drive calls until the broken connection has been tried once.
+	for broken.calls == 0 {
+		p.Invoke(context.Background(), "/svc/M", nil, nil)
+	}
+	if got := p.Health()[0].State; got != "transient_failure" {
+		t.Fatalf("state = %s, want transient_failure", got)
+	}
+
+	before := broken.calls
+	for i := 0; i < 4; i++ {
+		if err := p.Invoke(context.Background(), "/svc/M", nil, nil); err != nil {
+			t.Fatalf("call %d routed to failed conn: %v", i, err)
+		}
+	}
+	if broken.calls != before {
+		t.Fatal("failed connection was not taken out of rotation")
+	}
+
+	// After the backoff the slot is re-dialled with a fresh connection.
+	p.endpoints[0].slots[0].mu.Lock()
+	p.endpoints[0].slots[0].since = time.Now().Add(-2 * reconnectBackoff)
+	p.endpoints[0].slots[0].mu.Unlock()
+	for i := 0; i < 2; i++ {
+		p.Invoke(context.Background(), "/svc/M", nil, nil)
+	}
+	if !broken.closed || len(d.conns) != 3 {
+		t.Fatalf("expected broken conn closed and redialled, closed=%v dials=%d", broken.closed, len(d.conns))
+	}
+	if got := p.Health()[0].State; got != "ready" {
+		t.Errorf("state after redial = %s, want ready", got)
+	}
+}
+
+func TestApplicationErrorsKeepConnReady(t *testing.T) {
+	d := &fakeDialer{}
+	p, _ := newPool(Config{Addr: "backend:1", Size: 1}, d.dial)
+	d.conns[0].err = rpc.Errorf(rpc.NotFound, "no such job")
+	p.Invoke(context.Background(), "/svc/M", nil, nil)
+	if got := p.Health()[0].State; got != "ready" {
+		t.Errorf("state = %s, want ready", got)
+	}
+}
+
+func TestClose(t *testing.T) {
+	d := &fakeDialer{}
+	p, _ := newPool(Config{Addr: "backend:1", Size: 2}, d.dial)
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range d.conns {
+		if !c.closed {
+			t.Errorf("conn %d not closed", c.id)
+		}
+	}
+	if err := p.Invoke(context.Background(), "/svc/M", nil, nil); err != ErrClosed {
+		t.Errorf("Invoke after Close = %v, want ErrClosed", err)
+	}
+}
+
+// callsTo counts the calls made to the connections of addr.
+func (d *fakeDialer) callsTo(addr string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n := 0
+	for _, c := range d.conns {
+		if c.addr == addr {
+			c.mu.Lock()
+			n += c.calls
+			c.mu.Unlock()
+		}
+	}
+	return n
+}
+
+func (d *fakeDialer) set(addr string, err error, status string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, c := range d.conns {
+		if c.addr == addr {
+			c.mu.Lock()
+			c.err, c.status = err, status
+			c.mu.Unlock()
+		}
+	}
+}
+
+func invokeN(p *Pool, n int) {
+	for range n {
+		p.Invoke(context.Background(), "/svc/M", nil, nil)
+	}
+}
+
+func TestParseEndpoints(t *testing.T) {
+	eps, err := ParseEndpoints("orch-a:50051@zone-a, orch-b:50051")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Endpoint{{"orch-a:50051", "zone-a"}, {"orch-b:50051", ""}}
+	if len(eps) != 2 || eps[0] != want[0] || eps[1] != want[1] {
+		t.Errorf("ParseEndpoints = %+v, want %+v", eps, want)
+	}
+	for _, bad := range []string{"a:1,", "@zone", "a:1,a:1@zone"} {
+		if _, err := ParseEndpoints(bad); err == nil {
+			t.Errorf("ParseEndpoints(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestCallsSpreadAcrossEndpoints(t *testing.T) {
+	d := &fakeDialer{}
+	p, _ := newPool(Config{Addr: "a:1,b:1", Size: 2, HealthCheckInterval: -1}, d.dial)
+	defer p.Close()
+	invokeN(p, 10)
+	if a, b := d.callsTo("a:1"), d.callsTo("b:1"); a != 5 || b != 5 {
+		t.Errorf("calls = %d to a, %d to b, want 5 each", a, b)
+	}
+	if got := len(p.Health()); got != 4 {
+		t.Errorf("Health reports %d connections, want 4", got)
+	}
+}
+
+func TestPreferredZone(t *testing.T) {
+	d := &fakeDialer{}
+	p, _ := newPool(Config{Addr: "a:1@zone-a,b:1@zone-b,c:1@zone-b", Size: 1, PreferredZone: "zone-b", HealthCheckInterval: -1}, d.dial)
+	defer p.Close()
+	invokeN(p, 10)
+	if a := d.callsTo("a:1"); a != 0 {
+		t.Errorf("%d calls left the preferred zone", a)
+	}
+	if b, c := d.callsTo("b:1"), d.callsTo("c:1"); b != 5 || c != 5 {
+		t.Errorf("calls = %d to b, %d to c, want 5 each", b, c)
+	}
+
+	// With the whole zone failing its health checks, calls go elsewhere.
+	d.set("b:1", nil, health.StatusNotServing)
+	d.set("c:1", nil, health.StatusNotServing)
+	for _, e := range p.endpoints {
+		e.check(context.Background(), p)
+	}
+	invokeN(p, 4)
+	if a := d.callsTo("a:1"); a != 4 {
+		t.Errorf("a got %d calls while zone-b was down, want 4", a)
+	}
+}
+
+func TestHealthCheckFailover(t *testing.T) {
+	d := &fakeDialer{}
+	p, _ := newPool(Config{Addr: "a:1,b:1", Size: 1, HealthCheckInterval: -1}, d.dial)
+	defer p.Close()
+	d.set("a:1", nil, health.StatusServing)
+	d.set("b:1", nil, health.StatusNotServing)
+	for _, e := range p.endpoints {
+		e.check(context.Background(), p)
+	}
+	if eps := p.Endpoints(); !eps[0].Healthy || eps[1].Healthy || eps[1].HealthCheck != "backend health status NOT_SERVING" {
+		t.Fatalf("Endpoints = %+v", eps)
+	}
+	if got := endpointHealthy.With("b:1", "").Value(); got != 0 {
+		t.Errorf("healthy gauge for b = %v, want 0", got)
+	}
+	invokeN(p, 6)
+	if b := d.callsTo("b:1"); b != 0 {
+		t.Errorf("%d calls routed to the unhealthy endpoint", b)
+	}
+
+	d.set("b:1", nil, health.StatusServing)
+	p.endpoints[1].check(context.Background(), p)
+	invokeN(p, 6)
+	if b := d.callsTo("b:1"); b != 3 {
+		t.Errorf("recovered endpoint got %d calls, want 3", b)
+	}
+}
+
+func TestFailingEndpointIsEjected(t *testing.T) {
+	d := &fakeDialer{}
+	p, _ := newPool(Config{Addr: "a:1,b:1", Size: 1, HealthCheckInterval: -1}, d.dial)
+	defer p.Close()
+	// Internal errors keep the connection up but count against the
+	// endpoint.
+	d.set("b:1", rpc.Errorf(rpc.Internal, "boom"), "")
+	ejections := endpointEjections.With("b:1", "").Value()
+	invokeN(p, 2*ejectMinCalls)
+	if got := endpointEjections.With("b:1", "").Value() - ejections; got != 1 {
+		t.Fatalf("ejections = %v, want 1", got)
+	}
+	if eps := p.Endpoints(); eps[1].Healthy || eps[1].EjectedUntil == nil {
+		t.Fatalf("b not reported ejected: %+v", eps[1])
+	}
+	before := d.callsTo("b:1")
+	invokeN(p, 6)
+	if b := d.callsTo("b:1"); b != before {
+		t.Errorf("ejected endpoint got %d calls", b-before)
+	}
+
+	// Once the ejection lapses it is back in rotation.
+	e := p.endpoints[1]
+	e.mu.Lock()
+	e.ejectedUntil = time.Now().Add(-time.Second)
+	e.mu.Unlock()
+	d.set("b:1", nil, "")
+	invokeN(p, 6)
+	if b := d.callsTo("b:1"); b != before+3 {
+		t.Errorf("endpoint got %d calls after its ejection, want 3", b-before)
+	}
+}
+
+func TestAllEndpointsDownStillCalls(t *testing.T) {
+	d := &fakeDialer{}
+	p, _ := newPool(Config{Addr: "a:1,b:1", Size: 1, HealthCheckInterval: -1}, d.dial)
+	defer p.Close()
+	d.set("a:1", nil, health.StatusNotServing)
+	d.set("b:1", nil, health.StatusNotServing)
+	for _, e := range p.endpoints {
+		e.check(context.Background(), p)
+	}
+	invokeN(p, 4)
+	if a, b := d.callsTo("a:1"), d.callsTo("b:1"); a+b != 4 {
+		t.Errorf("calls = %d, want 4 spread over the unhealthy endpoints", a+b)
+	}
+}
@@ -0,0 +1,241 @@
+// Package headers rewrites request and response headers by configured
+// rules, so a deployment can add headers of its own, such as X-Env, or
+// strip internal ones, such as X-Debug-*, without code changes.
+//
+// A Rule sets, adds or removes one header, or removes every header with a
+// prefix. Rules apply in the order given: the request rules to the request
+// before it is passed on, the response rules to the response headers just
+// before they are sent, after the handler and the middleware inside the
+// Rewriter have set theirs.
+//
+// The headers the gateway itself manages for CORS, compression, framing
+// and WebSocket upgrades are out of the rules' reach: a rule naming one is
+// invalid, and a prefix rule leaves them alone.
+package headers
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/textproto"
+	"strings"
+)
+
+// Action is what a Rule does.
+type Action string
+
+const (
+	// Set replaces the header's values with the rule's.
+	Set Action = "set"
+	// Add appends the rule's value to the header's.
+	Add Action = "add"
+	// Remove deletes the header, or every header its prefix matches.
+	Remove Action = "remove"
+)
+
+// Rule is one header transformation. Name is a header name, matched
+// without regard to case, or for Remove a prefix ending in "*", such as
+// "X-Debug-*".
+type Rule struct {
+	Action Action
+	Name   string
+	Value  string
+}
+
+// managed reports whether the gateway owns the header name, canonical, in
+// responses if response is set, else in requests: the rules must not
+// clobber what CORS, compression, message framing or a WebSocket
+// handshake depend on.
+func managed(name string, response bool) bool {
+	switch name {
+	case "Connection", "Content-Length", "Transfer-Encoding", "Upgrade", "Te", "Trailer":
+		return true
+	}
+	if strings.HasPrefix(name, "Access-Control-") || strings.HasPrefix(name, "Sec-Websocket-") {
+		return true
+	}
+	if response {
+		return name == "Content-Encoding" || name == "Vary"
+	}
+	return name == "Origin"
+}
+
+// prefix returns the prefix of a prefix rule's Name.
+func (r Rule) prefix() (string, bool) {
+	p, ok := strings.CutSuffix(r.Name, "*")
+	return textproto.CanonicalMIMEHeaderKey(p), ok
+}
+
+// Check reports what is wrong with r as a response rule if response is
+// set, else as a request rule.
+func (r Rule) Check(response bool) error {
+	if r.Action != Set && r.Action != Add && r.Action != Remove {
+		return fmt.Errorf("action must be set, add or remove, got %q", r.Action)
+	}
+	name, isPrefix := r.prefix()
+	switch base := strings.TrimSuffix(r.Name, "*"); {
+	case r.Name == "":
+		return errors.New("name must not be empty")
+	case isPrefix && base == "":
+		return errors.New(`name must not be a bare "*", which would remove every header`)
+	case !validName(base):
+		return fmt.Errorf("name %q is not a valid header name", r.Name)
+	case isPrefix && r.Action != Remove:
+		return fmt.Errorf("a prefix like %q can only be removed", r.Name)
+	case !isPrefix && managed(name, response):
+		return fmt.Errorf("%s is managed by the gateway", name)
+	}
+	switch {
+	case r.Action == Remove && r.Value != "":
+		return errors.New("remove takes no value")
+	case r.Action != Remove && r.Value == "":
+		return fmt.Errorf("%s needs a value", r.Action)
+	case strings.ContainsAny(r.Value, "\r\n\x00"):
+		return errors.New("value must not contain CR, LF or NUL")
+	}
+	return nil
+}
+
+// validName reports whether s is an HTTP token, as header names are.
+func validName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range []byte(s) {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// rules is a checked list of rules for requests or responses.
+type rules struct {
+	list     []Rule
+	response bool
+}
+
+// apply rewrites h by the rules, in order.
+func (rs rules) apply(h http.Header) {
+	for _, r := range rs.list {
+		if p, ok := r.prefix(); ok {
+			for name := range h {
+				canonical := textproto.CanonicalMIMEHeaderKey(name)
+				if strings.HasPrefix(canonical, p) && !managed(canonical, rs.response) {
+					delete(h, name)
+				}
+			}
+			continue
+		}
+		switch r.Action {
+		case Set:
+			h.Set(r.Name, r.Value)
+		case Add:
+			h.Add(r.Name, r.Value)
+		case Remove:
+			h.Del(r.Name)
+		}
+	}
+}
+
+// Rewriter applies request and response rules to the requests through its
+// Middleware.
+type Rewriter struct {
+	request  rules
+	response rules
+}
+
+// New returns a Rewriter applying request and response, or the first
+// rule's error if one is invalid. With no rules at all it returns nil,
+// whose Middleware passes requests through untouched.
+func New(request, response []Rule) (*Rewriter, error) {
+	if len(request) == 0 && len(response) == 0 {
+		return nil, nil
+	}
+	for i, r := range request {
+		if err := r.Check(false); err != nil {
+			return nil, fmt.Errorf("headers: request rule %d: %w", i, err)
+		}
+	}
+	for i, r := range response {
+		if err := r.Check(true); err != nil {
+			return nil, fmt.Errorf("headers: response rule %d: %w", i, err)
+		}
+	}
+	return &Rewriter{request: rules{list: request}, response: rules{list: response, response: true}}, nil
+}
+
+// Middleware applies the request rules to each request, then the
+// response rules to its response headers as they are written. Responses
+// answered by hijacking the connection are not rewritten.
+func (rw *Rewriter) Middleware(next http.Handler) http.Handler {
+	if rw == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(rw.request.list) > 0 {
+			// The rules rewrite headers the caller may have shared.
+			r = r.Clone(r.Context())
+			rw.request.apply(r.Header)
+		}
+		if len(rw.response.list) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		hw := &writer{ResponseWriter: w, rules: rw.response}
+		next.ServeHTTP(hw, r)
+		hw.rewrite()
+	})
+}
+
+// writer applies response rules once, before the status line goes out.
+type writer struct {
+	http.ResponseWriter
+	rules     rules
+	rewritten bool
+}
+
+func (w *writer) rewrite() {
+	if !w.rewritten {
+		w.rewritten = true
+		w.rules.apply(w.ResponseWriter.Header())
+	}
+}
+
+func (w *writer) WriteHeader(status int) {
+	// Informational responses go out with the headers so far; the rules
+	// apply to the final response.
+	if status >= 200 {
+		w.rewrite()
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *writer) Write(b []byte) (int, error) {
+	w.rewrite()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *writer) Flush() {
+	w.rewrite()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *writer) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("headers: underlying ResponseWriter does not support hijacking")
+	}
+	w.rewritten = true
+	return h.Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *writer) Unwrap() http.ResponseWriter { return w.ResponseWriter }
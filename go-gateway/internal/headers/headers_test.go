@@ -0,0 +1,199 @@
+package headers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/content-factory/go-gateway/internal/cors"
+	"github.com/content-factory/go-gateway/internal/middleware"
+)
+
+func mustNew(t *testing.T, request, response []Rule) *Rewriter {
+	t.Helper()
+	rw, err := New(request, response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rw
+}
+
+func TestRequestRules(t *testing.T) {
+	rw := mustNew(t, []Rule{
+		{Action: Remove, Name: "X-Debug-*"},
+		{Action: Set, Name: "x-env", Value: "staging"},
+		{Action: Add, Name: "X-Tag", Value: "edge"},
+		// Later rules see the earlier ones' work.
+		{Action: Set, Name: "X-Debug-Gateway", Value: "1"},
+	}, nil)
+
+	var got http.Header
+	h := rw.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { got = r.Header }))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Debug-Trace", "on")
+	req.Header["x-debug-lower"] = []string{"on"} // not canonical
+	req.Header.Set("X-Env", "client")
+	req.Header.Set("X-Tag", "client")
+	req.Header.Set("Authorization", "Bearer t")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	want := http.Header{
+		"X-Env":           {"staging"},
+		"X-Tag":           {"client", "edge"},
+		"X-Debug-Gateway": {"1"},
+		"Authorization":   {"Bearer t"},
+	}
+	if len(got) != len(want) {
+		t.Errorf("headers = %v, want %v", got, want)
+	}
+	for name, values := range want {
+		if strings.Join(got[name], ",") != strings.Join(values, ",") {
+			t.Errorf("%s = %q, want %q", name, got[name], values)
+		}
+	}
+	if req.Header.Get("X-Debug-Trace") != "on" {
+		t.Error("the caller's request was rewritten")
+	}
+}
+
+func TestResponseRules(t *testing.T) {
+	rw := mustNew(t, nil, []Rule{
+		{Action: Remove, Name: "X-Debug-*"},
+		{Action: Remove, Name: "Server"},
+		{Action: Set, Name: "X-Env", Value: "staging"},
+	})
+	for name, handler := range map[string]http.HandlerFunc{
+		"write": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Debug-Sql", "select 1")
+			w.Header().Set("Server", "python")
+			w.Write([]byte("ok"))
+		},
+		"write header": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Debug-Sql", "select 1")
+			w.WriteHeader(http.StatusAccepted)
+		},
+		"nothing written": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Debug-Sql", "select 1")
+		},
+		"flushed": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Debug-Sql", "select 1")
+			if err := http.NewResponseController(w).Flush(); err != nil {
+				t.Errorf("flush: %v", err)
+			}
+			w.Header().Set("X-Debug-Late", "too late to be sent")
+		},
+	} {
+		rec := httptest.NewRecorder()
+		rw.Middleware(handler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		if got := rec.Header(); got.Get("X-Env") != "staging" || got.Get("X-Debug-Sql") != "" || got.Get("Server") != "" {
+			t.Errorf("%s: headers = %v", name, got)
+		}
+	}
+}
+
+// TestManagedHeaders has rules that would strip or override the headers
+// of the CORS and compression middleware, on either side of them.
+func TestManagedHeaders(t *testing.T) {
+	response := []Rule{
+		{Action: Remove, Name: "Access-*"},
+		{Action: Remove, Name: "Content-*"},
+		{Action: Remove, Name: "V*"},
+	}
+	rw := mustNew(t, nil, response)
+	policy := cors.Middleware(cors.Config{AllowedOrigins: []string{"https://app.example.com"}})
+	compress := middleware.Compress(16)
+	body := strings.Repeat("compressible ", 100)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Content-Language", "en")
+		w.Write([]byte(body))
+	})
+
+	for name, h := range map[string]http.Handler{
+		"outside":    middleware.NewChain(rw.Middleware, compress, policy).Then(handler),
+		"inside":     middleware.NewChain(compress, policy, rw.Middleware).Then(handler),
+		"in between": middleware.NewChain(compress, rw.Middleware, policy).Then(handler),
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		req.Header.Set("Accept-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		got := rec.Header()
+		if got.Get("Access-Control-Allow-Origin") != "https://app.example.com" {
+			t.Errorf("%s: CORS headers stripped: %v", name, got)
+		}
+		if got.Get("Content-Encoding") != "gzip" || !strings.Contains(strings.Join(got.Values("Vary"), ","), "Accept-Encoding") {
+			t.Errorf("%s: compression headers stripped: %v", name, got)
+		}
+		if !strings.Contains(strings.Join(got.Values("Vary"), ","), "Origin") {
+			t.Errorf("%s: Vary: Origin stripped: %v", name, got)
+		}
+		if got.Get("Content-Language") != "" {
+			t.Errorf("%s: Content-Language = %q, want it removed", name, got.Get("Content-Language"))
+		}
+	}
+
+	// A preflight's request headers are out of reach too.
+	rw = mustNew(t, []Rule{{Action: Remove, Name: "Access-Control-*"}, {Action: Remove, Name: "Or*"}}, nil)
+	h := middleware.NewChain(rw.Middleware, cors.Middleware(cors.Config{
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowedMethods: []string{http.MethodPost},
+	})).Then(http.NotFoundHandler())
+	req := httptest.NewRequest(http.MethodOptions, "/", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Methods") != http.MethodPost {
+		t.Errorf("preflight: status %d, headers %v", rec.Code, rec.Header())
+	}
+}
+
+func TestCheck(t *testing.T) {
+	for _, tt := range []struct {
+		rule     Rule
+		response bool
+		want     string
+	}{
+		{Rule{Action: "replace", Name: "X-Env", Value: "a"}, false, "action must be set, add or remove"},
+		{Rule{Action: Set, Value: "a"}, false, "name must not be empty"},
+		{Rule{Action: Remove, Name: "*"}, false, `bare "*"`},
+		{Rule{Action: Set, Name: "X Env", Value: "a"}, false, "not a valid header name"},
+		{Rule{Action: Set, Name: "X-Debug-*", Value: "a"}, false, "can only be removed"},
+		{Rule{Action: Remove, Name: "vary"}, true, "Vary is managed by the gateway"},
+		{Rule{Action: Set, Name: "Access-Control-Allow-Origin", Value: "*"}, true, "managed by the gateway"},
+		{Rule{Action: Remove, Name: "Origin"}, false, "managed by the gateway"},
+		{Rule{Action: Remove, Name: "Content-Length"}, false, "managed by the gateway"},
+		{Rule{Action: Remove, Name: "X-Env", Value: "a"}, false, "remove takes no value"},
+		{Rule{Action: Add, Name: "X-Env"}, false, "add needs a value"},
+		{Rule{Action: Set, Name: "X-Env", Value: "a\r\nX-Evil: 1"}, true, "must not contain CR, LF or NUL"},
+	} {
+		err := tt.rule.Check(tt.response)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%+v: err = %v, want %q", tt.rule, err, tt.want)
+		}
+	}
+	// Only responses carry Vary and Content-Encoding, only requests Origin.
+	for _, ok := range []struct {
+		rule     Rule
+		response bool
+	}{
+		{Rule{Action: Remove, Name: "Vary"}, false},
+		{Rule{Action: Remove, Name: "Origin"}, true},
+		{Rule{Action: Remove, Name: "Authorization"}, false},
+		{Rule{Action: Set, Name: "X-Env", Value: "prod"}, true},
+	} {
+		if err := ok.rule.Check(ok.response); err != nil {
+			t.Errorf("%+v: %v", ok.rule, err)
+		}
+	}
+
+	if rw, err := New(nil, nil); rw != nil || err != nil {
+		t.Errorf("New without rules = %v, %v; want nil, nil", rw, err)
+	}
+	if _, err := New(nil, []Rule{{Action: Set, Name: "X-Env", Value: "a"}, {Action: Remove, Name: "Vary"}}); err == nil || !strings.Contains(err.Error(), "response rule 1") {
+		t.Errorf("New with an invalid rule: err = %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Statuses of a CapabilityResult.
+const (
+	CapabilityAvailable   = "available"
+	CapabilityUnavailable = "unavailable"
+)
+
+// CapabilityResult is the availability of one capability in a Report.
+type CapabilityResult struct {
+	Name   string   `json:"name"`
+	Status string   `json:"status"`
+	Needs  []string `json:"needs"`
+	Reason string   `json:"reason,omitempty"`
+}
+
+type capability struct {
+	name  string
+	needs []string
+}
+
+// AddCapability declares a capability of the gateway and the checks it
+// needs: it is available only while all of them pass or are degraded.
+// Every check must already be registered.
+func (c *Checker) AddCapability(name string, needs ...string) {
+	for _, n := range needs {
+		if c.lookup(n) == nil {
+			panic(fmt.Sprintf("health: capability %s needs unknown check %q", name, n))
+		}
+	}
+	c.capabilities = append(c.capabilities, &capability{name: name, needs: needs})
+}
+
+// Capability reports whether capability name is available, running only
+// the checks it needs. The error, when it is not, says why. A capability
+// that was never declared is always available.
+func (c *Checker) Capability(ctx context.Context, name string) error {
+	cp := c.capabilityNamed(name)
+	if cp == nil {
+		return nil
+	}
+	checks := make([]*check, len(cp.needs))
+	for i, n := range cp.needs {
+		checks[i] = c.lookup(n)
+	}
+	byName := make(map[string]CheckResult, len(checks))
+	for _, r := range c.runChecks(ctx, checks) {
+		byName[r.Name] = r
+	}
+	res := cp.result(byName)
+	if res.Status == CapabilityAvailable {
+		return nil
+	}
+	return &UnavailableError{Capability: name, Reason: res.Reason}
+}
+
+// UnavailableError is the error Capability returns for a capability that
+// is unavailable.
+type UnavailableError struct {
+	Capability string
+	Reason     string
+}
+
+func (e *UnavailableError) Error() string {
+	return e.Capability + " is unavailable: " + e.Reason
+}
+
+// result works out the capability's availability from the results of its
+// checks, and records it in gateway_capability_available.
+func (cp *capability) result(byName map[string]CheckResult) CapabilityResult {
+	res := CapabilityResult{Name: cp.name, Status: CapabilityAvailable, Needs: cp.needs}
+	var reasons []string
+	for _, n := range cp.needs {
+		if r := byName[n]; r.Status == "failing" {
+			reasons = append(reasons, n+": "+r.Error)
+		}
+	}
+	if len(reasons) > 0 {
+		res.Status, res.Reason = CapabilityUnavailable, strings.Join(reasons, "; ")
+	}
+	capabilityUp.With(cp.name).Set(boolGauge(len(reasons) == 0))
+	return res
+}
+
+func (c *Checker) lookup(name string) *check {
+	for _, chk := range c.checks {
+		if chk.name == name {
+			return chk
+		}
+	}
+	return nil
+}
+
+func (c *Checker) capabilityNamed(name string) *capability {
+	for _, cp := range c.capabilities {
+		if cp.name == name {
+			return cp
+		}
+	}
+	return nil
+}
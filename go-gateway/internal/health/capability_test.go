@@ -0,0 +1,108 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFailingSourceDegrades(t *testing.T) {
+	var saturated atomic.Bool
+	c := &Checker{}
+	c.Add("backend", func(ctx context.Context) error { return nil })
+	c.AddSource("queue", func(ctx context.Context) error {
+		if saturated.Load() {
+			return errors.New("queue is saturated")
+		}
+		return nil
+	}, 0)
+	c.AddCapability("content_submission", "backend", "queue")
+	c.AddCapability("job_reads", "backend")
+
+	if report := c.Run(context.Background()); report.Status != StatusReady {
+		t.Fatalf("status = %q, want ready", report.Status)
+	}
+
+	saturated.Store(true)
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 while degraded", rec.Code)
+	}
+	var report Report
+	json.NewDecoder(rec.Body).Decode(&report)
+	if report.Status != StatusDegraded {
+		t.Fatalf("status = %q, want degraded", report.Status)
+	}
+	want := map[string]string{"content_submission": CapabilityUnavailable, "job_reads": CapabilityAvailable}
+	for _, cp := range report.Capabilities {
+		if cp.Status != want[cp.Name] {
+			t.Errorf("%s = %s, want %s", cp.Name, cp.Status, want[cp.Name])
+		}
+	}
+	if got := report.Capabilities[0].Reason; got != "queue: queue is saturated" {
+		t.Errorf("reason = %q", got)
+	}
+	if stateGauge.With(StatusDegraded).Value() != 1 || stateGauge.With(StatusReady).Value() != 0 {
+		t.Error("gateway_health_status does not report degraded")
+	}
+	if capabilityUp.With("content_submission").Value() != 0 || capabilityUp.With("job_reads").Value() != 1 {
+		t.Error("gateway_capability_available does not match the report")
+	}
+}
+
+func TestFailingCheckStillFailsReadiness(t *testing.T) {
+	c := &Checker{}
+	c.Add("backend", func(ctx context.Context) error { return errors.New("connection refused") })
+	c.AddSource("queue", func(ctx context.Context) error { return errors.New("queue is saturated") }, 0)
+	c.AddCapability("content_submission", "backend", "queue")
+	if report := c.Run(context.Background()); report.Status != StatusNotReady || report.Ready() {
+		t.Errorf("status = %q, want not_ready", report.Status)
+	}
+
+	c = &Checker{Draining: func() bool { return true }}
+	c.AddSource("queue", func(ctx context.Context) error { return errors.New("queue is saturated") }, 0)
+	c.AddCapability("content_submission", "queue")
+	if report := c.Run(context.Background()); report.Status != StatusDraining {
+		t.Errorf("status = %q, want draining to override degraded", report.Status)
+	}
+}
+
+func TestCapability(t *testing.T) {
+	var calls, skipped atomic.Int32
+	c := &Checker{}
+	c.Add("backend", func(ctx context.Context) error { skipped.Add(1); return nil })
+	c.AddSource("queue", func(ctx context.Context) error {
+		calls.Add(1)
+		return errors.New("queue is saturated")
+	}, time.Minute)
+	c.AddCapability("content_submission", "queue")
+
+	for range 3 {
+		err := c.Capability(context.Background(), "content_submission")
+		var unavailable *UnavailableError
+		if !errors.As(err, &unavailable) || unavailable.Reason != "queue: queue is saturated" {
+			t.Fatalf("Capability = %v, want the queue's reason", err)
+		}
+	}
+	if calls.Load() != 1 || skipped.Load() != 0 {
+		t.Errorf("queue probed %d times, backend %d; want the cached queue answer only", calls.Load(), skipped.Load())
+	}
+	if err := c.Capability(context.Background(), "undeclared"); err != nil {
+		t.Errorf("undeclared capability = %v, want available", err)
+	}
+}
+
+func TestAddCapabilityRejectsUnknownCheck(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("AddCapability with an unknown check did not panic")
+		}
+	}()
+	(&Checker{}).AddCapability("content_submission", "queue")
+}
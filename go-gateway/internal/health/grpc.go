@@ -0,0 +1,49 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/content-factory/go-gateway/internal/rpc"
+)
+
+// DefaultCacheTTL is how long main reuses a backend health answer.
+const DefaultCacheTTL = 5 * time.Second
+
+// MethodHealthCheck is the standard gRPC health checking method.
+const MethodHealthCheck = healthpb.Health_Check_FullMethodName
+
+// ServingStatus values of grpc.health.v1.HealthCheckResponse.
+const (
+	StatusServing    = "SERVING"
+	StatusNotServing = "NOT_SERVING"
+	StatusUnknown    = "UNKNOWN"
+)
+
+// Invoker makes unary backend calls; *grpcpool.Pool implements it.
+type Invoker interface {
+	Invoke(ctx context.Context, method string, req, resp any) error
+}
+
+// GRPCProbe asks the backend's grpc.health.v1.Health service about
+// service ("" for the server as a whole). Only SERVING passes. A backend
+// that answers but does not implement the health service counts as
+// degraded rather than failing: it is reachable, just not introspectable.
+func GRPCProbe(conn Invoker, service string) Probe {
+	return func(ctx context.Context) error {
+		var resp healthpb.HealthCheckResponse
+		err := conn.Invoke(ctx, MethodHealthCheck, &healthpb.HealthCheckRequest{Service: service}, &resp)
+		switch {
+		case rpc.CodeOf(err) == rpc.Unimplemented:
+			return &DegradedError{Reason: "backend does not implement grpc.health.v1.Health; reachability only"}
+		case err != nil:
+			return err
+		case resp.GetStatus() != healthpb.HealthCheckResponse_SERVING:
+			return fmt.Errorf("backend health status %s", resp.GetStatus())
+		}
+		return nil
+	}
+}
@@ -0,0 +1,83 @@
+package health
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/content-factory/go-gateway/internal/rpc"
+)
+
+// fakeHealth answers Health/Check with status, or fails with err.
+type fakeHealth struct {
+	status string
+	err    error
+	calls  atomic.Int32
+}
+
+func (f *fakeHealth) Invoke(ctx context.Context, method string, req, resp any) error {
+	f.calls.Add(1)
+	if method != MethodHealthCheck {
+		return rpc.Errorf(rpc.Unimplemented, "unexpected method %s", method)
+	}
+	if f.err != nil {
+		return f.err
+	}
+	resp.(*healthpb.HealthCheckResponse).Status = healthpb.HealthCheckResponse_ServingStatus(healthpb.HealthCheckResponse_ServingStatus_value[f.status])
+	return nil
+}
+
+func TestGRPCProbe(t *testing.T) {
+	tests := []struct {
+		name   string
+		be     *fakeHealth
+		status string
+		ready  bool
+	}{
+		{"serving", &fakeHealth{status: StatusServing}, "ok", true},
+		{"not serving", &fakeHealth{status: StatusNotServing}, "failing", false},
+		{"unset status", &fakeHealth{}, "failing", false},
+		{"no health service", &fakeHealth{err: rpc.Errorf(rpc.Unimplemented, "unknown service")}, "degraded", true},
+		{"unreachable", &fakeHealth{err: rpc.Errorf(rpc.Unavailable, "connection refused")}, "failing", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Checker{}
+			c.Add("content_service", GRPCProbe(tt.be, ""))
+			report := c.Run(context.Background())
+			if got := report.Checks[0].Status; got != tt.status {
+				t.Errorf("check status = %q, want %q (error %q)", got, tt.status, report.Checks[0].Error)
+			}
+			if report.Ready() != tt.ready {
+				t.Errorf("ready = %v, want %v", report.Ready(), tt.ready)
+			}
+		})
+	}
+}
+
+func TestCachedCheck(t *testing.T) {
+	be := &fakeHealth{status: StatusServing}
+	c := &Checker{}
+	c.AddCached("content_service", GRPCProbe(be, ""), time.Hour)
+
+	first := c.Run(context.Background())
+	second := c.Run(context.Background())
+	if n := be.calls.Load(); n != 1 {
+		t.Errorf("backend called %d times, want 1", n)
+	}
+	if first.Checks[0].CheckedAt.IsZero() || !second.Checks[0].CheckedAt.Equal(first.Checks[0].CheckedAt) {
+		t.Errorf("checked_at = %v then %v, want the cached time", first.Checks[0].CheckedAt, second.Checks[0].CheckedAt)
+	}
+
+	c = &Checker{}
+	c.AddCached("content_service", GRPCProbe(be, ""), time.Nanosecond)
+	c.Run(context.Background())
+	time.Sleep(time.Millisecond)
+	c.Run(context.Background())
+	if n := be.calls.Load(); n != 3 {
+		t.Errorf("backend called %d times after expiry, want 3", n)
+	}
+}
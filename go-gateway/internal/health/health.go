@@ -0,0 +1,231 @@
+// Package health runs readiness checks against the gateway's downstream
+// dependencies, and reports which of the gateway's capabilities they
+// leave available.
+//
+// A readiness check that fails makes the gateway not ready. A source is a
+// check that does not: it only takes down the capabilities that need it,
+// leaving the gateway degraded but still serving the rest, such as job
+// reads while content submission is disabled because the queue is
+// saturated.
+package health
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/metrics"
+	"github.com/content-factory/go-gateway/internal/respond"
+)
+
+// Overall states of a Report.
+const (
+	StatusReady    = "ready"
+	StatusDegraded = "degraded"
+	StatusNotReady = "not_ready"
+	StatusStarting = "starting"
+	StatusDraining = "draining"
+)
+
+var (
+	states       = []string{StatusReady, StatusDegraded, StatusNotReady, StatusStarting, StatusDraining}
+	stateGauge   = metrics.NewGaugeVec("gateway_health_status", "1 for the gateway's current readiness status, 0 for the others.", "status")
+	capabilityUp = metrics.NewGaugeVec("gateway_capability_available", "Whether each capability of the gateway is available (1) or not (0).", "capability")
+)
+
+// DefaultTimeout bounds each check so a hung dependency cannot stall the
+// probe.
+const DefaultTimeout = 2 * time.Second
+
+// Probe returns nil when a dependency is usable. A *DegradedError means it
+// is usable but could not be checked fully; the check passes with status
+// "degraded".
+type Probe func(ctx context.Context) error
+
+// DegradedError is returned by a Probe whose dependency works but offers
+// only a partial answer, such as a backend without a health service.
+type DegradedError struct {
+	Reason string
+}
+
+func (e *DegradedError) Error() string { return e.Reason }
+
+// CheckResult is one dependency's outcome in a Report.
+type CheckResult struct {
+	Name      string    `json:"name"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Duration  string    `json:"duration"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Report is the readiness document served by Handler.
+type Report struct {
+	Status       string             `json:"status"`
+	Checks       []CheckResult      `json:"checks"`
+	Capabilities []CapabilityResult `json:"capabilities,omitempty"`
+}
+
+// Ready reports whether the gateway should get traffic: every readiness
+// check passed, though some capabilities may be unavailable.
+func (r Report) Ready() bool { return r.Status == StatusReady || r.Status == StatusDegraded }
+
+type check struct {
+	name  string
+	probe Probe
+	ttl   time.Duration
+	// source checks only bear on the capabilities that need them.
+	source bool
+
+	mu      sync.Mutex // held while probing, so concurrent runs share one call
+	last    CheckResult
+	expires time.Time
+}
+
+// Checker runs a fixed set of named probes concurrently.
+type Checker struct {
+	Timeout time.Duration
+	// Draining, when set and returning true, fails readiness regardless of
+	// the checks, e.g. during shutdown.
+	Draining func() bool
+	// Starting, likewise, fails readiness with status "starting", e.g.
+	// while waiting for the backend at startup.
+	Starting func() bool
+
+	checks       []*check
+	capabilities []*capability
+}
+
+// Add registers a probe. Checks appear in the report in the order added.
+func (c *Checker) Add(name string, p Probe) {
+	c.checks = append(c.checks, &check{name: name, probe: p})
+}
+
+// AddCached registers a probe whose result is reused for ttl, for
+// dependencies that should not be asked on every readiness request.
+func (c *Checker) AddCached(name string, p Probe, ttl time.Duration) {
+	c.checks = append(c.checks, &check{name: name, probe: p, ttl: ttl})
+}
+
+// AddSource registers a probe, cached for ttl like AddCached, that only
+// the capabilities needing it depend on: when it fails they become
+// unavailable and the gateway degraded, not unready.
+func (c *Checker) AddSource(name string, p Probe, ttl time.Duration) {
+	c.checks = append(c.checks, &check{name: name, probe: p, ttl: ttl, source: true})
+}
+
+// Run executes every probe, each under its own timeout.
+func (c *Checker) Run(ctx context.Context) Report {
+	results := c.runChecks(ctx, c.checks)
+	report := Report{Status: StatusReady, Checks: results}
+	byName := make(map[string]CheckResult, len(results))
+	for i, r := range results {
+		byName[r.Name] = r
+		if r.Status == "failing" && !c.checks[i].source {
+			report.Status = StatusNotReady
+		}
+	}
+	for _, cp := range c.capabilities {
+		res := cp.result(byName)
+		report.Capabilities = append(report.Capabilities, res)
+		if res.Status != CapabilityAvailable && report.Status == StatusReady {
+			report.Status = StatusDegraded
+		}
+	}
+	switch {
+	case c.Draining != nil && c.Draining():
+		report.Status = StatusDraining
+	case c.Starting != nil && c.Starting():
+		report.Status = StatusStarting
+	}
+	for _, s := range states {
+		stateGauge.With(s).Set(boolGauge(s == report.Status))
+	}
+	return report
+}
+
+// runChecks runs checks concurrently, each under the timeout.
+func (c *Checker) runChecks(ctx context.Context, checks []*check) []CheckResult {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, chk := range checks {
+		wg.Go(func() { results[i] = chk.run(ctx, timeout) })
+	}
+	wg.Wait()
+	return results
+}
+
+func boolGauge(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (chk *check) run(ctx context.Context, timeout time.Duration) CheckResult {
+	if chk.ttl > 0 {
+		chk.mu.Lock()
+		defer chk.mu.Unlock()
+		if time.Now().Before(chk.expires) {
+			return chk.last
+		}
+	}
+	cctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	err := chk.probe(cctx)
+	res := CheckResult{
+		Name:      chk.name,
+		Status:    "ok",
+		Duration:  time.Since(start).Round(time.Microsecond).String(),
+		CheckedAt: start.UTC(),
+	}
+	var degraded *DegradedError
+	switch {
+	case errors.As(err, &degraded):
+		res.Status, res.Error = "degraded", err.Error()
+	case err != nil:
+		res.Status, res.Error = "failing", err.Error()
+	}
+	// A probe cut short by the caller's own cancellation says nothing
+	// about the dependency, so it is not cached.
+	if chk.ttl > 0 && ctx.Err() == nil {
+		chk.last, chk.expires = res, start.Add(chk.ttl)
+	}
+	return res
+}
+
+// ServeHTTP serves the readiness report: 200 when ready, 503 otherwise.
+func (c *Checker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	report := c.Run(r.Context())
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+	respond.Write(w, r, status, report)
+}
+
+// DialProbe checks that a TCP connection to addr can be established. addr
+// may be host:port or a URL such as redis://redis:6379/0.
+func DialProbe(addr string) Probe {
+	hostport := addr
+	if u, err := url.Parse(addr); err == nil && u.Host != "" {
+		hostport = u.Host
+	}
+	return func(ctx context.Context) error {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", hostport)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
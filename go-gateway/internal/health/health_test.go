@@ -0,0 +1,70 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReadyWhenAllChecksPass(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	c := &Checker{}
+	c.Add("backend", DialProbe(ln.Addr().String()))
+	c.Add("redis", DialProbe("redis://"+ln.Addr().String()+"/0"))
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d body %s", rec.Code, rec.Body)
+	}
+}
+
+func TestReportNamesFailingDependency(t *testing.T) {
+	c := &Checker{Timeout: 50 * time.Millisecond}
+	c.Add("backend", func(ctx context.Context) error { return nil })
+	c.Add("redis", func(ctx context.Context) error { return errors.New("connection refused") })
+	c.Add("slow", func(ctx context.Context) error { <-ctx.Done(); return ctx.Err() })
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", rec.Code)
+	}
+	var report Report
+	json.NewDecoder(rec.Body).Decode(&report)
+	want := map[string]string{"backend": "ok", "redis": "failing", "slow": "failing"}
+	for _, r := range report.Checks {
+		if r.Status != want[r.Name] {
+			t.Errorf("%s status = %s, want %s", r.Name, r.Status, want[r.Name])
+		}
+	}
+}
+
+func TestDrainingFailsReadiness(t *testing.T) {
+	c := &Checker{Draining: func() bool { return true }}
+	if report := c.Run(context.Background()); report.Ready() {
+		t.Errorf("report = %+v, want not ready while draining", report)
+	}
+}
+
+func TestStartingFailsReadiness(t *testing.T) {
+	starting := true
+	c := &Checker{Starting: func() bool { return starting }}
+	if report := c.Run(context.Background()); report.Status != "starting" {
+		t.Errorf("status = %q, want starting", report.Status)
+	}
+	starting = false
+	if report := c.Run(context.Background()); !report.Ready() {
+		t.Errorf("report = %+v, want ready once started", report)
+	}
+}
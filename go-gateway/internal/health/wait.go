@@ -0,0 +1,75 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Defaults used by the config package for WaitConfig.
+const (
+	DefaultMaxWait        = time.Minute
+	DefaultInitialBackoff = 500 * time.Millisecond
+	DefaultMaxBackoff     = 10 * time.Second
+)
+
+// WaitConfig bounds Wait.
+type WaitConfig struct {
+	// MaxWait is how long to keep trying before giving up.
+	MaxWait time.Duration
+	// InitialBackoff is the pause after the first failed attempt; each
+	// later pause doubles, up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// Wait runs probe until the dependency name passes it, logging each
+// attempt, with capped exponential backoff between attempts. Each attempt
+// gets DefaultTimeout, or what is left of MaxWait if less. A
+// *DegradedError passes, as it does in a Checker.
+//
+// Wait gives up with the last failure once another attempt could not
+// start within MaxWait, or when ctx is done.
+func Wait(ctx context.Context, name string, probe Probe, cfg WaitConfig) error {
+	if cfg.MaxWait <= 0 {
+		cfg.MaxWait = DefaultMaxWait
+	}
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = DefaultInitialBackoff
+	}
+	cfg.MaxBackoff = max(cfg.MaxBackoff, cfg.InitialBackoff)
+	start := time.Now()
+	deadline := start.Add(cfg.MaxWait)
+	backoff := cfg.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		actx, cancel := context.WithTimeout(ctx, min(DefaultTimeout, time.Until(deadline)))
+		err := probe(actx)
+		cancel()
+		var degraded *DegradedError
+		if err == nil || errors.As(err, &degraded) {
+			slog.Info("dependency ready", "dependency", name, "attempt", attempt,
+				"waited", time.Since(start).Round(time.Millisecond))
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if time.Now().Add(backoff).After(deadline) {
+			slog.Error("dependency not ready, giving up", "dependency", name, "attempt", attempt, "error", err)
+			return fmt.Errorf("%s not ready after %d attempts in %v: %w",
+				name, attempt, time.Since(start).Round(time.Millisecond), err)
+		}
+		slog.Warn("waiting for dependency", "dependency", name, "attempt", attempt,
+			"error", err, "retry_in", backoff)
+		t := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+		backoff = min(backoff*2, cfg.MaxBackoff)
+	}
+}
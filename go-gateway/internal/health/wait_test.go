@@ -0,0 +1,61 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// failing returns a probe that fails its first n calls, and a count of the
+// calls.
+func failing(n int) (Probe, *int) {
+	calls := 0
+	return func(ctx context.Context) error {
+		calls++
+		if calls <= n {
+			return errors.New("connection refused")
+		}
+		return nil
+	}, &calls
+}
+
+func TestWaitRetriesUntilReady(t *testing.T) {
+	probe, calls := failing(2)
+	cfg := WaitConfig{MaxWait: time.Second, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+	if err := Wait(context.Background(), "backend", probe, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if *calls != 3 {
+		t.Errorf("probe called %d times, want 3", *calls)
+	}
+
+	degraded := func(ctx context.Context) error { return &DegradedError{Reason: "no health service"} }
+	if err := Wait(context.Background(), "backend", degraded, cfg); err != nil {
+		t.Errorf("degraded backend: %v", err)
+	}
+}
+
+func TestWaitGivesUp(t *testing.T) {
+	probe, calls := failing(1000)
+	start := time.Now()
+	err := Wait(context.Background(), "backend", probe, WaitConfig{
+		MaxWait: 100 * time.Millisecond, InitialBackoff: 10 * time.Millisecond, MaxBackoff: 20 * time.Millisecond,
+	})
+	if err == nil || !strings.Contains(err.Error(), "backend not ready") || !strings.Contains(err.Error(), "connection refused") {
+		t.Fatalf("error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("gave up after %v, want about 100ms", elapsed)
+	}
+	if *calls < 3 || *calls > 11 {
+		t.Errorf("probe called %d times, want a handful", *calls)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Wait(ctx, "backend", probe, WaitConfig{MaxWait: time.Hour}); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled wait: %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package idempotency
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/metrics"
+	"github.com/content-factory/go-gateway/internal/routeconf"
+	"github.com/content-factory/go-gateway/internal/router"
+)
+
+var coalescedTotal = metrics.NewCounterVec("gateway_coalesced_submissions_total",
+	"Submissions answered with the response of an identical one made just before, by route.",
+	"route")
+
+// Coalescer answers duplicate submissions, such as those of a
+// double-click, without an Idempotency-Key: within a route's
+// CoalesceWindow of a submission, an identical one by the same principal
+// gets its response instead of being processed again. Unlike an
+// Idempotency-Key it is automatic and short-lived, and it is held in
+// process, so only duplicates reaching the same replica are merged.
+type Coalescer struct {
+	mu      sync.Mutex
+	flights map[string]*coalesced
+}
+
+// coalesced is one submission and, once done is closed, its response;
+// resp stays nil if the response is not for sharing.
+type coalesced struct {
+	done chan struct{}
+	resp *Response
+}
+
+// NewCoalescer returns a Coalescer holding no submissions.
+func NewCoalescer() *Coalescer {
+	return &Coalescer{flights: make(map[string]*coalesced)}
+}
+
+// Middleware coalesces the requests of routes with a CoalesceWindow in
+// routes. A duplicate arriving while the first request is still running
+// waits for it. Only 2xx responses are given again: after any other the
+// duplicate is processed on its own. Requests with an Idempotency-Key are
+// left to Middleware.
+//
+// The body is read up front to fingerprint it, at most maxBody bytes, and
+// handed on to the handler unchanged. It must run inside the router, for
+// the route's override, and after authentication, which supplies the
+// principal.
+func (c *Coalescer) Middleware(routes routeconf.Table, maxBody int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			o, _ := routes.Lookup(r)
+			if o.CoalesceWindow <= 0 || r.Header.Get(Header) != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBody))
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					apierror.Write(w, r, apierror.BodyTooLarge(tooLarge.Limit))
+					return
+				}
+				apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, "could not read request body"))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			ctx := r.Context()
+			var principal string
+			if claims := gateway.ClaimsFromContext(ctx); claims != nil {
+				principal = claims.Subject
+			}
+			key := gateway.TenantID(ctx) + "\x00" + principal + "\x00" + fingerprint(r, body)
+			for {
+				f, first := c.join(key)
+				if first {
+					c.run(f, key, o.CoalesceWindow, next, w, r)
+					return
+				}
+				select {
+				case <-f.done:
+				case <-ctx.Done():
+					apierror.Write(w, r, apierror.Timeout("the request timed out waiting for an identical one"))
+					return
+				}
+				if f.resp != nil {
+					coalescedTotal.With(router.Pattern(r)).Inc()
+					replay(w, f.resp)
+					return
+				}
+				// The first request's response was not for sharing; this
+				// one is processed, unless another duplicate got there
+				// first.
+			}
+		})
+	}
+}
+
+// join returns the submission under key, and whether the caller is its
+// first request, which must run it.
+func (c *Coalescer) join(key string) (f *coalesced, first bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if f, ok := c.flights[key]; ok {
+		return f, false
+	}
+	f = &coalesced{done: make(chan struct{})}
+	c.flights[key] = f
+	return f, true
+}
+
+// run serves the first request of f, keeping a 2xx response for the
+// rest of window, measured from its start.
+func (c *Coalescer) run(f *coalesced, key string, window time.Duration, next http.Handler, w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	forget := func() {
+		c.mu.Lock()
+		if c.flights[key] == f {
+			delete(c.flights, key)
+		}
+		c.mu.Unlock()
+	}
+	defer close(f.done)
+	rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+	completed := false
+	defer func() {
+		if !completed {
+			forget()
+		}
+	}()
+	next.ServeHTTP(rec, r)
+	completed = true
+
+	if rec.status < 200 || rec.status > 299 {
+		forget()
+		return
+	}
+	resp := &Response{Status: rec.status, Header: make(http.Header), Body: rec.body.Bytes()}
+	for _, name := range replayedHeaders {
+		if v := w.Header().Values(name); len(v) > 0 {
+			resp.Header[name] = v
+		}
+	}
+	f.resp = resp
+	time.AfterFunc(max(0, window-time.Since(start)), forget)
+}
@@ -0,0 +1,104 @@
+package idempotency
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/routeconf"
+	"github.com/content-factory/go-gateway/internal/router"
+)
+
+// coalescing serves next at POST /api/v1/content, coalesced for window,
+// and at POST /api/v1/other, which has no window.
+func coalescing(next http.Handler, window time.Duration) http.Handler {
+	rt := router.New()
+	table := routeconf.Table{"POST /api/v1/content": {CoalesceWindow: window}}
+	rt.Handle(http.MethodPost, "/api/v1/content", NewCoalescer().Middleware(table, 1<<20)(next))
+	rt.Handle(http.MethodPost, "/api/v1/other", NewCoalescer().Middleware(table, 1<<20)(next))
+	return rt
+}
+
+func TestCoalesce(t *testing.T) {
+	next := &countingHandler{status: http.StatusAccepted}
+	h := coalescing(next, 100*time.Millisecond)
+
+	first := post(h, "u1", "", `{"a":1}`)
+	again := post(h, "u1", "", `{"a":1}`)
+	if next.calls.Load() != 1 {
+		t.Fatalf("handler called %d times, want 1", next.calls.Load())
+	}
+	if again.Code != http.StatusAccepted || again.Body.String() != first.Body.String() || again.Header().Get("Location") != "/api/v1/jobs/j1" {
+		t.Errorf("duplicate = %d %q, want %d %q", again.Code, again.Body, first.Code, first.Body)
+	}
+	// The handler still read the whole body.
+	if first.Body.String() != `{"call":1,"echo":{"a":1}}` {
+		t.Errorf("body = %s", first.Body)
+	}
+
+	// Another body, another principal or a client-supplied key is not a
+	// duplicate.
+	post(h, "u1", "", `{"a":2}`)
+	post(h, "u2", "", `{"a":1}`)
+	post(h, "u1", "k1", `{"a":1}`)
+	if next.calls.Load() != 4 {
+		t.Errorf("handler called %d times, want 4", next.calls.Load())
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if rec := post(h, "u1", "", `{"a":1}`); rec.Body.String() == first.Body.String() {
+		t.Errorf("submission after the window got the first response %s", rec.Body)
+	}
+}
+
+func TestCoalesceWaitsForFirst(t *testing.T) {
+	next := &countingHandler{status: http.StatusAccepted, started: make(chan struct{}), release: make(chan struct{})}
+	h := coalescing(next, time.Minute)
+
+	var wg sync.WaitGroup
+	recs := make([]string, 3)
+	wg.Go(func() { recs[0] = post(h, "u1", "", `{"a":1}`).Body.String() })
+	<-next.started
+	for i := 1; i < len(recs); i++ {
+		wg.Go(func() { recs[i] = post(h, "u1", "", `{"a":1}`).Body.String() })
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(next.release)
+	wg.Wait()
+
+	if next.calls.Load() != 1 {
+		t.Fatalf("handler called %d times, want 1", next.calls.Load())
+	}
+	for i, body := range recs {
+		if body != recs[0] {
+			t.Errorf("response %d = %s, want %s", i, body, recs[0])
+		}
+	}
+}
+
+func TestCoalesceSharesOnlySuccess(t *testing.T) {
+	next := &countingHandler{status: http.StatusServiceUnavailable}
+	h := coalescing(next, time.Minute)
+	post(h, "u1", "", `{"a":1}`)
+	post(h, "u1", "", `{"a":1}`)
+	if next.calls.Load() != 2 {
+		t.Errorf("handler called %d times, want 2 after a failure", next.calls.Load())
+	}
+}
+
+func TestCoalesceOnlyConfiguredRoutes(t *testing.T) {
+	next := &countingHandler{status: http.StatusAccepted}
+	h := coalescing(next, time.Minute)
+	for range 2 {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/other", strings.NewReader(`{"a":1}`))
+		req = req.WithContext(gateway.WithClaims(req.Context(), &gateway.Claims{Subject: "u1"}))
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if next.calls.Load() != 2 {
+		t.Errorf("handler called %d times, want 2 on a route without a window", next.calls.Load())
+	}
+}
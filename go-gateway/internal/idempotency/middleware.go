@@ -0,0 +1,155 @@
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/gateway"
+)
+
+// Header carries the client's idempotency key.
+const Header = "Idempotency-Key"
+
+// ReplayedHeader is set to "true" on stored responses sent again.
+const ReplayedHeader = "Idempotent-Replayed"
+
+const maxKeyLen = 255
+
+// replayedHeaders are the response headers stored with a response. Headers
+// set outside the handler, like X-Request-ID, belong to each request.
+var replayedHeaders = []string{"Content-Type", "Location"}
+
+// Middleware processes each (principal, Idempotency-Key) pair once. The
+// first request runs normally and its response is stored for ttl; repeats
+// with the same body get that response back, marked with
+// Idempotent-Replayed. A repeat while the first is still running gets 409,
+// and a repeat with a different body 422. Server errors are not stored, so
+// the client can retry them. Requests without the header pass through.
+//
+// The body is read up front to fingerprint it, at most maxBody bytes. It
+// must run after authentication, which supplies the principal.
+func Middleware(store Store, ttl time.Duration, maxBody int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(Header)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxKeyLen {
+				apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.CodeInvalidIdempotencyKey, "Idempotency-Key must be at most 255 characters"))
+				return
+			}
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBody))
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					apierror.Write(w, r, apierror.BodyTooLarge(tooLarge.Limit))
+					return
+				}
+				apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequest, "could not read request body"))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			ctx := r.Context()
+			var principal string
+			if claims := gateway.ClaimsFromContext(ctx); claims != nil {
+				principal = claims.Subject
+			}
+			storeKey := gateway.TenantID(ctx) + "\x00" + principal + "\x00" + key
+			fp := fingerprint(r, body)
+
+			existing, reserved, err := store.Reserve(ctx, storeKey, fp, ttl)
+			if err != nil {
+				gateway.Logger(ctx).ErrorContext(ctx, "idempotency store unavailable", "error", err)
+				apierror.Write(w, r, apierror.New(http.StatusServiceUnavailable, apierror.CodeIdempotencyUnavailable, "the request could not be checked for duplicates; retry later"))
+				return
+			}
+			if !reserved {
+				switch {
+				case existing.Fingerprint != fp:
+					apierror.Write(w, r, apierror.New(http.StatusUnprocessableEntity, apierror.CodeIdempotencyKeyReused, "the Idempotency-Key was already used for a different request"))
+				case existing.Response == nil:
+					apierror.Write(w, r, apierror.New(http.StatusConflict, apierror.CodeIdempotencyInProgress, "a request with this Idempotency-Key is still being processed"))
+				default:
+					replay(w, existing.Response)
+				}
+				return
+			}
+
+			rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+			completed := false
+			defer func() {
+				if !completed {
+					// The handler panicked; let the client try again.
+					store.Release(ctx, storeKey)
+				}
+			}()
+			next.ServeHTTP(rec, r)
+			completed = true
+
+			if rec.status >= 500 {
+				store.Release(ctx, storeKey)
+				return
+			}
+			resp := &Response{Status: rec.status, Header: make(http.Header), Body: rec.body.Bytes()}
+			for _, name := range replayedHeaders {
+				if v := w.Header().Values(name); len(v) > 0 {
+					resp.Header[name] = v
+				}
+			}
+			if err := store.Complete(ctx, storeKey, resp); err != nil {
+				gateway.Logger(ctx).ErrorContext(ctx, "failed to store idempotent response", "error", err)
+			}
+		})
+	}
+}
+
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+"\n"+r.URL.Path+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func replay(w http.ResponseWriter, resp *Response) {
+	h := w.Header()
+	for name, v := range resp.Header {
+		h[name] = v
+	}
+	h.Set(ReplayedHeader, "true")
+	w.WriteHeader(resp.Status)
+	w.Write(resp.Body)
+}
+
+// recorder copies the response it passes through.
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (w *recorder) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recorder) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *recorder) Unwrap() http.ResponseWriter { return w.ResponseWriter }
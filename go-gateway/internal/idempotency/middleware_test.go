@@ -0,0 +1,130 @@
+package idempotency
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/gateway"
+)
+
+type countingHandler struct {
+	calls   atomic.Int32
+	status  int
+	release chan struct{}
+	started chan struct{}
+}
+
+func (h *countingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n := h.calls.Add(1)
+	if h.started != nil {
+		close(h.started)
+		<-h.release
+	}
+	body, _ := io.ReadAll(r.Body)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/v1/jobs/j"+strconv.Itoa(int(n)))
+	w.WriteHeader(h.status)
+	w.Write([]byte(`{"call":` + strconv.Itoa(int(n)) + `,"echo":` + string(body) + `}`))
+}
+
+func post(h http.Handler, subject, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/content", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(Header, key)
+	}
+	req = req.WithContext(gateway.WithClaims(req.Context(), &gateway.Claims{Subject: subject}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestReplay(t *testing.T) {
+	next := &countingHandler{status: http.StatusAccepted}
+	h := Middleware(NewMemoryStore(), time.Hour, 1<<20)(next)
+
+	first := post(h, "u1", "k1", `{"a":1}`)
+	again := post(h, "u1", "k1", `{"a":1}`)
+	if next.calls.Load() != 1 {
+		t.Fatalf("handler called %d times, want 1", next.calls.Load())
+	}
+	if again.Code != http.StatusAccepted || again.Body.String() != first.Body.String() {
+		t.Errorf("replay = %d %q, want %d %q", again.Code, again.Body, first.Code, first.Body)
+	}
+	if again.Header().Get("Location") != first.Header().Get("Location") || again.Header().Get(ReplayedHeader) != "true" {
+		t.Errorf("replay headers = %v", again.Header())
+	}
+	if first.Header().Get(ReplayedHeader) != "" {
+		t.Error("first response marked as replayed")
+	}
+
+	// Keys are scoped to the principal, and requests without one pass through.
+	post(h, "u2", "k1", `{"a":1}`)
+	post(h, "u1", "", `{"a":1}`)
+	if next.calls.Load() != 3 {
+		t.Errorf("handler called %d times, want 3", next.calls.Load())
+	}
+}
+
+func TestReusedKeyWithDifferentBody(t *testing.T) {
+	next := &countingHandler{status: http.StatusAccepted}
+	h := Middleware(NewMemoryStore(), time.Hour, 1<<20)(next)
+	post(h, "u1", "k1", `{"a":1}`)
+	if rec := post(h, "u1", "k1", `{"a":2}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d, want 422", rec.Code)
+	}
+}
+
+func TestConcurrentRepeatConflicts(t *testing.T) {
+	next := &countingHandler{status: http.StatusAccepted, started: make(chan struct{}), release: make(chan struct{})}
+	h := Middleware(NewMemoryStore(), time.Hour, 1<<20)(next)
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- post(h, "u1", "k1", `{}`) }()
+	<-next.started
+	if rec := post(h, "u1", "k1", `{}`); rec.Code != http.StatusConflict {
+		t.Errorf("in-flight repeat: status = %d, want 409", rec.Code)
+	}
+	close(next.release)
+	if rec := <-done; rec.Code != http.StatusAccepted {
+		t.Errorf("first request: status = %d", rec.Code)
+	}
+}
+
+func TestServerErrorsAreNotStored(t *testing.T) {
+	next := &countingHandler{status: http.StatusServiceUnavailable}
+	h := Middleware(NewMemoryStore(), time.Hour, 1<<20)(next)
+	post(h, "u1", "k1", `{}`)
+	post(h, "u1", "k1", `{}`)
+	if next.calls.Load() != 2 {
+		t.Errorf("handler called %d times, want a retry after a 503", next.calls.Load())
+	}
+}
+
+func TestKeysExpire(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Unix(1_700_000_000, 0)
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if _, ok, _ := store.Reserve(ctx, "k", "fp", time.Minute); !ok {
+		t.Fatal("first Reserve failed")
+	}
+	if _, ok, _ := store.Reserve(ctx, "k", "fp", time.Minute); ok {
+		t.Fatal("second Reserve succeeded while held")
+	}
+	now = now.Add(2 * time.Minute)
+	store.evictExpired()
+	if len(store.entries) != 0 {
+		t.Errorf("expired entry not evicted")
+	}
+	if _, ok, _ := store.Reserve(ctx, "k", "fp", time.Minute); !ok {
+		t.Error("Reserve after expiry failed")
+	}
+}
@@ -0,0 +1,123 @@
+// Package idempotency lets clients retry non-idempotent requests safely.
+// A request carrying an Idempotency-Key header is processed once per
+// (principal, key); repeats get the stored response instead of being
+// processed again. A Coalescer does the same, without a key, for
+// identical requests made within moments of each other.
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultTTL is how long a response is kept for replay. Used by the config
+// package.
+const DefaultTTL = 24 * time.Hour
+
+// Response is a stored response.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Entry is the state of one key. Response is nil while the first request
+// is still being processed.
+type Entry struct {
+	// Fingerprint identifies the request that claimed the key, so a key
+	// reused for a different request can be refused.
+	Fingerprint string
+	Response    *Response
+}
+
+// Store records idempotency keys. Implementations must make Reserve
+// atomic: of two concurrent requests with the same key, only one may
+// reserve it.
+type Store interface {
+	// Reserve claims key for a new request with the given fingerprint,
+	// for ttl. If the key is already held it returns the existing entry
+	// and reserved is false.
+	Reserve(ctx context.Context, key, fingerprint string, ttl time.Duration) (existing *Entry, reserved bool, err error)
+	// Complete stores the response of the request that reserved key.
+	Complete(ctx context.Context, key string, resp *Response) error
+	// Release forgets key, so the request can be retried from scratch.
+	Release(ctx context.Context, key string) error
+}
+
+type memoryEntry struct {
+	Entry
+	expires time.Time
+}
+
+// MemoryStore is an in-process Store. Entries are lost on restart and not
+// shared between replicas. Call Sweep in a goroutine to drop expired
+// entries.
+type MemoryStore struct {
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{now: time.Now, entries: make(map[string]*memoryEntry)}
+}
+
+// Reserve implements Store.
+func (s *MemoryStore) Reserve(ctx context.Context, key, fingerprint string, ttl time.Duration) (*Entry, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if e, ok := s.entries[key]; ok && now.Before(e.expires) {
+		existing := e.Entry
+		return &existing, false, nil
+	}
+	s.entries[key] = &memoryEntry{Entry: Entry{Fingerprint: fingerprint}, expires: now.Add(ttl)}
+	return nil, true, nil
+}
+
+// Complete implements Store.
+func (s *MemoryStore) Complete(ctx context.Context, key string, resp *Response) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[key]; ok {
+		e.Response = resp
+	}
+	return nil
+}
+
+// Release implements Store.
+func (s *MemoryStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// Sweep drops expired entries every interval until ctx is done.
+func (s *MemoryStore) Sweep(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.evictExpired()
+		}
+	}
+}
+
+func (s *MemoryStore) evictExpired() {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, e := range s.entries {
+		if !now.Before(e.expires) {
+			delete(s.entries, key)
+		}
+	}
+}
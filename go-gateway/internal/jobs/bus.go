@@ -0,0 +1,164 @@
+package jobs
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/content-factory/go-gateway/internal/metrics"
+)
+
+// DefaultBusBuffer is how many events a Bus subscriber may fall behind
+// before its overflow policy applies. Used by the config package.
+const DefaultBusBuffer = 1024
+
+// OverflowPolicy is what happens to a subscriber whose queue is full.
+type OverflowPolicy string
+
+const (
+	// Disconnect closes the subscriber's channel, ending its subscription.
+	Disconnect OverflowPolicy = "disconnect"
+	// DropOldest discards the subscriber's oldest queued event to make
+	// room. Terminal events are discarded only if nothing else is queued,
+	// since a job has no later event that would end it.
+	DropOldest OverflowPolicy = "drop_oldest"
+)
+
+var (
+	busDropped = metrics.NewCounterVec("gateway_event_bus_dropped_total",
+		"Job events the event bus did not deliver to a subscriber that fell behind, by subscriber and overflow policy.",
+		"subscriber", "policy")
+	jobEvents = metrics.NewCounterVec("gateway_job_events_total",
+		"Job events published on the event bus, by stage: progress for in-progress stages, or the terminal stage.",
+		"stage")
+)
+
+// Bus hands every job event published to it, by a Feed, to each of its
+// subscribers, such as the realtime hub, the webhooks, the audit log and
+// the job metrics, so whatever streams events from the backend need not
+// know who consumes them. Each subscriber has a bounded queue of its own:
+// Publish never blocks, and a subscriber that falls behind is handled by
+// its overflow policy instead of holding up the others.
+type Bus struct {
+	mu   sync.Mutex
+	subs map[*Subscription]bool
+}
+
+// NewBus returns a Bus without subscribers.
+func NewBus() *Bus {
+	return &Bus{subs: make(map[*Subscription]bool)}
+}
+
+// Subscription is one subscriber of a Bus.
+type Subscription struct {
+	bus      *Bus
+	name     string
+	overflow OverflowPolicy
+	ch       chan Event
+	dropped  atomic.Int64
+}
+
+// Subscribe adds a subscriber, named for the metrics, whose queue holds
+// buffer events (zero means DefaultBusBuffer) before overflow (empty
+// means Disconnect) applies. It receives the events published from now
+// on.
+func (b *Bus) Subscribe(name string, buffer int, overflow OverflowPolicy) *Subscription {
+	if buffer <= 0 {
+		buffer = DefaultBusBuffer
+	}
+	if overflow == "" {
+		overflow = Disconnect
+	}
+	s := &Subscription{bus: b, name: name, overflow: overflow, ch: make(chan Event, buffer)}
+	b.mu.Lock()
+	b.subs[s] = true
+	b.mu.Unlock()
+	return s
+}
+
+// Events returns the subscriber's events. The channel is closed by Close,
+// or when the subscriber is disconnected for falling behind.
+func (s *Subscription) Events() <-chan Event { return s.ch }
+
+// Dropped reports how many events the subscriber missed.
+func (s *Subscription) Dropped() int64 { return s.dropped.Load() }
+
+// Close ends the subscription and closes its channel.
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	s.bus.remove(s)
+}
+
+// remove forgets s and closes its channel. b.mu must be held.
+func (b *Bus) remove(s *Subscription) {
+	if b.subs[s] {
+		delete(b.subs, s)
+		close(s.ch)
+	}
+}
+
+// Publish hands ev to every subscriber without blocking.
+func (b *Bus) Publish(ev Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for s := range b.subs {
+		b.deliver(s, ev)
+	}
+}
+
+// deliver queues ev for s without blocking, applying s's overflow policy
+// if its queue is full. b.mu must be held.
+func (b *Bus) deliver(s *Subscription, ev Event) {
+	select {
+	case s.ch <- ev:
+		return
+	default:
+	}
+	s.dropped.Add(1)
+	busDropped.With(s.name, string(s.overflow)).Inc()
+	if s.overflow == Disconnect {
+		b.remove(s)
+		return
+	}
+	// Only Publish sends on s.ch, and it holds b.mu, so the queue can be
+	// taken out and put back, without its oldest event that is not
+	// terminal; if all of them are, ev goes instead, or the oldest if ev
+	// is terminal too.
+	queued := make([]Event, 0, cap(s.ch)+1)
+	for len(queued) < cap(s.ch) {
+		select {
+		case q := <-s.ch:
+			queued = append(queued, q)
+			continue
+		default:
+		}
+		break
+	}
+	queued = append(queued, ev)
+	drop := slices.IndexFunc(queued, func(q Event) bool { return !q.Terminal() })
+	if drop < 0 {
+		drop = 0
+	}
+	queued = slices.Delete(queued, drop, drop+1)
+	for _, q := range queued {
+		s.ch <- q
+	}
+}
+
+// CountEvents counts the events of a Bus subscription in the job event
+// metrics until the channel is closed. StageError events, which report
+// the gateway losing a job's stream rather than anything the job did, are
+// not counted.
+func CountEvents(events <-chan Event) {
+	for ev := range events {
+		if ev.Stage == StageError {
+			continue
+		}
+		stage := "progress"
+		if ev.Terminal() {
+			stage = ev.Stage
+		}
+		jobEvents.With(stage).Inc()
+	}
+}
@@ -0,0 +1,112 @@
+package jobs
+
+import (
+	"testing"
+)
+
+// drain returns the events queued for s without waiting for more.
+func drain(s *Subscription) []Event {
+	var out []Event
+	for {
+		select {
+		case ev, ok := <-s.Events():
+			if !ok {
+				return out
+			}
+			out = append(out, ev)
+		default:
+			return out
+		}
+	}
+}
+
+func seqs(events []Event) []int64 {
+	out := make([]int64, len(events))
+	for i, ev := range events {
+		out[i] = ev.Seq
+	}
+	return out
+}
+
+func TestBusFansOut(t *testing.T) {
+	bus := NewBus()
+	a := bus.Subscribe("a", 8, Disconnect)
+	b := bus.Subscribe("b", 8, DropOldest)
+
+	bus.Publish(Event{JobID: "j1", Seq: 1, Stage: "drafting"})
+	bus.Publish(Event{JobID: "j2", Seq: 1, Stage: "drafting"})
+	bus.Publish(Event{JobID: "j1", Seq: 2, Stage: StageDone})
+	for name, s := range map[string]*Subscription{"a": a, "b": b} {
+		if got := drain(s); len(got) != 3 || got[2].JobID != "j1" || got[2].Stage != StageDone {
+			t.Errorf("%s got %+v", name, got)
+		}
+	}
+}
+
+func TestBusDisconnectsSlowSubscriber(t *testing.T) {
+	bus := NewBus()
+	slow := bus.Subscribe("slow", 2, Disconnect)
+	fast := bus.Subscribe("fast", 8, Disconnect)
+	for seq := int64(1); seq <= 3; seq++ {
+		bus.Publish(Event{JobID: "j1", Seq: seq, Stage: "drafting"})
+		drain(fast)
+	}
+	if got := seqs(drain(slow)); len(got) != 2 {
+		t.Errorf("slow subscriber got seqs %v before its channel closed, want 1 2", got)
+	}
+	if _, ok := <-slow.Events(); ok || slow.Dropped() != 1 {
+		t.Errorf("slow subscriber still open, or dropped %d, want 1", slow.Dropped())
+	}
+	bus.Publish(Event{JobID: "j1", Seq: 4, Stage: StageDone})
+	if got := drain(fast); len(got) != 1 || got[0].Seq != 4 {
+		t.Errorf("fast subscriber got %+v after the slow one left", got)
+	}
+}
+
+func TestBusDropOldest(t *testing.T) {
+	bus := NewBus()
+	s := bus.Subscribe("s", 2, DropOldest)
+	for seq := int64(1); seq <= 4; seq++ {
+		bus.Publish(Event{JobID: "j1", Seq: seq, Stage: "drafting"})
+	}
+	if got := seqs(drain(s)); len(got) != 2 || got[0] != 3 || got[1] != 4 {
+		t.Errorf("got seqs %v, want 3 4", got)
+	}
+	if s.Dropped() != 2 {
+		t.Errorf("dropped %d, want 2", s.Dropped())
+	}
+}
+
+func TestBusDropOldestKeepsTerminalEvents(t *testing.T) {
+	bus := NewBus()
+	s := bus.Subscribe("s", 2, DropOldest)
+	bus.Publish(Event{JobID: "j1", Seq: 1, Stage: StageDone})
+	for seq := int64(1); seq <= 3; seq++ {
+		bus.Publish(Event{JobID: "j2", Seq: seq, Stage: "drafting"})
+	}
+	if got := drain(s); len(got) != 2 || got[0].JobID != "j1" || got[1].Seq != 3 {
+		t.Fatalf("got %+v, want j1's terminal event and j2's seq 3", got)
+	}
+
+	// With only terminal events queued, a progress event is the one missed.
+	bus.Publish(Event{JobID: "j1", Seq: 2, Stage: StageDone})
+	bus.Publish(Event{JobID: "j2", Seq: 4, Stage: StageFailed})
+	bus.Publish(Event{JobID: "j3", Seq: 1, Stage: "drafting"})
+	if got := drain(s); len(got) != 2 || !got[0].Terminal() || !got[1].Terminal() {
+		t.Errorf("got %+v, want both terminal events", got)
+	}
+	if s.Dropped() != 3 {
+		t.Errorf("dropped %d, want 3", s.Dropped())
+	}
+}
+
+func TestBusClose(t *testing.T) {
+	bus := NewBus()
+	s := bus.Subscribe("s", 0, "")
+	s.Close()
+	s.Close()
+	bus.Publish(Event{JobID: "j1", Seq: 1, Stage: "drafting"})
+	if _, ok := <-s.Events(); ok {
+		t.Error("closed subscription received an event")
+	}
+}
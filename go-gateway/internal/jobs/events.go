@@ -0,0 +1,127 @@
+// Package jobs models content-generation jobs and their progress events as
+// the gateway presents them to clients.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/backend"
+)
+
+// Terminal stages. Every other stage is an in-progress pipeline step such
+// as "drafting" or "video_assembly".
+const (
+	StageDone   = "done"
+	StageFailed = "failed"
+	StageError  = "error"
+)
+
+// Event is a progress notification for a job. Seq numbers a job's events
+// from 1 so a reconnecting client can resume after the last one it saw.
+// Tenant is set on the events of a Bus, for its subscribers to tell jobs
+// of the same ID apart.
+type Event struct {
+	JobID   string    `json:"job_id"`
+	Tenant  string    `json:"-"`
+	Seq     int64     `json:"seq"`
+	Stage   string    `json:"stage"`
+	Percent float64   `json:"percent"`
+	Message string    `json:"message,omitempty"`
+	URL     string    `json:"url,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// Terminal reports whether no further events follow e.
+func (e Event) Terminal() bool {
+	switch e.Stage {
+	case StageDone, StageFailed, StageError:
+		return true
+	}
+	return false
+}
+
+// Source produces the event stream for a job, starting after event number
+// after (0 for the whole stream). The channel is closed after a terminal
+// event, when the upstream ends, or when ctx is cancelled.
+type Source interface {
+	Subscribe(ctx context.Context, jobID string, after int64) (<-chan Event, error)
+}
+
+// BackendSource subscribes to the ContentOrchestrator progress stream.
+type BackendSource struct {
+	Client *backend.Client
+}
+
+// Subscribe implements Source. Errors opening the stream (such as an
+// unknown job) are returned directly; errors after that are delivered as a
+// terminal StageError event.
+//
+// Updates without a backend sequence number are numbered by their position
+// in the stream. Updates at or before after are dropped here as well, in
+// case the backend replays them.
+func (s *BackendSource) Subscribe(ctx context.Context, jobID string, after int64) (<-chan Event, error) {
+	stream, err := s.Client.StreamProgress(ctx, jobID, after)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan Event)
+	go func() {
+		defer close(ch)
+		defer stream.Close()
+		var seq int64
+		for {
+			u, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			var ev Event
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				ev = Event{JobID: jobID, Seq: seq + 1, Stage: StageError, Message: err.Error(), Time: time.Now()}
+			} else {
+				seq++
+				if u.Sequence > 0 {
+					seq = u.Sequence
+				}
+				if seq <= after {
+					continue
+				}
+				ev = fromUpdate(jobID, seq, u)
+			}
+			select {
+			case ch <- ev:
+			case <-ctx.Done():
+				return
+			}
+			if ev.Terminal() {
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+func fromUpdate(jobID string, seq int64, u *backend.ProgressUpdate) Event {
+	stage := u.Stage
+	if stage == "completed" {
+		stage = StageDone
+	}
+	ts := u.Timestamp
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	return Event{
+		JobID:   jobID,
+		Seq:     seq,
+		Stage:   stage,
+		Percent: u.ProgressPercent,
+		Message: u.Message,
+		URL:     u.ResultURL,
+		Time:    ts,
+	}
+}
@@ -0,0 +1,80 @@
+package jobs
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/content-factory/go-gateway/internal/backend"
+	"github.com/content-factory/go-gateway/internal/rpc"
+	pb "github.com/content-factory/go-gateway/protos/content_factory"
+)
+
+// replayConn serves StreamProgress by replaying updates from the start,
+// the worst case for resumption.
+type replayConn struct {
+	updates []backend.ProgressUpdate
+	req     *pb.JobStatusRequest
+}
+
+func (c *replayConn) Invoke(ctx context.Context, method string, req, resp any) error {
+	return rpc.Errorf(rpc.Unimplemented, "unused")
+}
+
+func (c *replayConn) NewStream(ctx context.Context, method string, req any) (rpc.ClientStream, error) {
+	c.req = req.(*pb.JobStatusRequest)
+	return &sliceStream{updates: c.updates}, nil
+}
+
+type sliceStream struct{ updates []backend.ProgressUpdate }
+
+func (s *sliceStream) Recv(msg any) error {
+	if len(s.updates) == 0 {
+		return io.EOF
+	}
+	u, m := s.updates[0], msg.(*pb.ProgressUpdate)
+	m.Stage, m.Sequence = u.Stage, u.Sequence
+	s.updates = s.updates[1:]
+	return nil
+}
+
+func (s *sliceStream) Close() error { return nil }
+
+func collect(t *testing.T, src Source, after int64) []Event {
+	t.Helper()
+	ch, err := src.Subscribe(context.Background(), "j1", after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []Event
+	for ev := range ch {
+		out = append(out, ev)
+	}
+	return out
+}
+
+func TestSubscribeResumesAfterSequence(t *testing.T) {
+	tests := []struct {
+		name    string
+		updates []backend.ProgressUpdate
+	}{
+		{"backend sequences", []backend.ProgressUpdate{
+			{Stage: "a", Sequence: 1}, {Stage: "b", Sequence: 2}, {Stage: "completed", Sequence: 3},
+		}},
+		{"positional", []backend.ProgressUpdate{
+			{Stage: "a"}, {Stage: "b"}, {Stage: "completed"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &replayConn{updates: tt.updates}
+			got := collect(t, &BackendSource{Client: backend.New(conn)}, 1)
+			if conn.req.GetAfterSequence() != 1 {
+				t.Errorf("after_sequence sent = %d, want 1", conn.req.GetAfterSequence())
+			}
+			if len(got) != 2 || got[0].Seq != 2 || got[0].Stage != "b" || got[1].Seq != 3 || got[1].Stage != StageDone {
+				t.Errorf("events = %+v", got)
+			}
+		})
+	}
+}
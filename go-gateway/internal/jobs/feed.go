@@ -0,0 +1,317 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/rpc"
+)
+
+// Defaults for the Feed fields left zero.
+const (
+	DefaultFollowAttempts = 5
+	DefaultFollowBackoff  = time.Second
+	// DefaultMaxFollowing is how many jobs a Feed follows at once.
+	DefaultMaxFollowing = 10000
+	maxFollowBackoff    = 30 * time.Second
+)
+
+const (
+	// maxFinished bounds the finished jobs a Feed remembers.
+	maxFinished = 10000
+	// historySize bounds the events a followed job keeps for History.
+	historySize = 256
+)
+
+var (
+	// ErrFinished is returned by Feed.Follow for a job whose terminal event
+	// the feed has already published.
+	ErrFinished = errors.New("job already finished")
+	// ErrTooManyJobs is returned by Feed.Follow for a job it cannot follow
+	// because it already follows MaxFollowing.
+	ErrTooManyJobs = rpc.Errorf(rpc.Unavailable, "the gateway is following as many jobs as it can; retry later")
+)
+
+// Feed streams jobs from a Source onto a Bus, so that the bus carries the
+// events of every job the gateway follows, whoever watches it. Each job
+// is followed with one subscription while anyone holds it, see Follow; a
+// stream that fails or ends early is reopened after the last event
+// published, and once the attempts run out a StageError event ends the
+// job on the bus. No event of a job is published twice.
+//
+// Callers check that whoever they follow a job for may see it: the feed
+// follows any job it is asked to.
+type Feed struct {
+	ctx    context.Context
+	source Source
+	bus    *Bus
+	// MaxAttempts caps the consecutive tries at reopening a job's stream;
+	// Backoff is the wait before the first, doubling after each. Zero
+	// means DefaultFollowAttempts and DefaultFollowBackoff.
+	MaxAttempts int
+	Backoff     time.Duration
+	// MaxFollowing caps the jobs followed at once; zero means
+	// DefaultMaxFollowing.
+	MaxFollowing int
+
+	mu        sync.Mutex
+	following map[string]*followed // by tenant and job
+	// finished holds the jobs that reached their terminal event; order
+	// lists them, oldest first.
+	finished map[string]bool
+	order    []string
+}
+
+// followed is one job being followed.
+type followed struct {
+	ready  chan struct{} // closed once the stream is open or failed
+	err    error         // set before ready is closed
+	cancel context.CancelFunc
+
+	// Guarded by Feed.mu.
+	holds   int
+	history []Event
+}
+
+// NewFeed returns a Feed publishing the jobs it follows from source on
+// bus. Its streams end when ctx is cancelled, as on shutdown.
+func NewFeed(ctx context.Context, source Source, bus *Bus) *Feed {
+	return &Feed{ctx: ctx, source: source, bus: bus, following: make(map[string]*followed), finished: make(map[string]bool)}
+}
+
+// Bus returns the bus f publishes on.
+func (f *Feed) Bus() *Bus {
+	return f.bus
+}
+
+// Follow holds jobID, for the tenant of ctx, streaming it unless it is
+// followed already, and waits for its stream to open. It returns the
+// error opening it, such as an unknown job, ErrFinished if the job's
+// events have all been published, or ErrTooManyJobs. The stream outlives
+// ctx: release gives the hold up, and once every hold is given up the
+// stream is closed, whether or not the job has finished.
+func (f *Feed) Follow(ctx context.Context, jobID string) (release func(), err error) {
+	tenant := gateway.TenantID(ctx)
+	key := tenant + "\x00" + jobID
+	f.mu.Lock()
+	if f.finished[key] {
+		f.mu.Unlock()
+		return nil, ErrFinished
+	}
+	j, ok := f.following[key]
+	if !ok {
+		if len(f.following) >= f.maxFollowing() {
+			f.mu.Unlock()
+			return nil, ErrTooManyJobs
+		}
+		// The stream is detached from the caller, which may go away before
+		// the job ends, but acts for its tenant.
+		stream := f.ctx
+		if t := gateway.TenantFromContext(ctx); t != nil {
+			stream = gateway.WithTenant(stream, t)
+		}
+		stream, cancel := context.WithCancel(stream)
+		j = &followed{ready: make(chan struct{}), cancel: cancel}
+		f.following[key] = j
+		go f.follow(stream, key, jobID, tenant, j)
+	}
+	j.holds++
+	f.mu.Unlock()
+	var once sync.Once
+	release = func() { once.Do(func() { f.release(key, j) }) }
+
+	select {
+	case <-j.ready:
+	case <-ctx.Done():
+		release()
+		return nil, ctx.Err()
+	}
+	if j.err != nil {
+		release()
+		return nil, j.err
+	}
+	return release, nil
+}
+
+// release gives up one hold of j, the job under key, closing its stream
+// after the last.
+func (f *Feed) release(key string, j *followed) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if j.holds--; j.holds > 0 {
+		return
+	}
+	if f.following[key] == j {
+		delete(f.following, key)
+	}
+	j.cancel()
+}
+
+// Start follows jobID in the background for the consumers of the bus,
+// for a job just submitted by its owner, until the job ends. A failure
+// to open its stream is only logged: the job is followed again by the
+// first Follow for it.
+func (f *Feed) Start(ctx context.Context, jobID string) {
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		if _, err := f.Follow(ctx, jobID); err != nil && !errors.Is(err, ErrFinished) {
+			slog.Warn("failed to follow submitted job", "job_id", jobID, "error", err)
+		}
+	}()
+}
+
+// History returns the published events after after of jobID, for the
+// tenant of ctx, up to the last historySize, and whether the job is being
+// followed.
+func (f *Feed) History(ctx context.Context, jobID string, after int64) ([]Event, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	j, ok := f.following[gateway.TenantID(ctx)+"\x00"+jobID]
+	if !ok {
+		return nil, false
+	}
+	var out []Event
+	for _, ev := range j.history {
+		if ev.Seq > after {
+			out = append(out, ev)
+		}
+	}
+	return out, true
+}
+
+// Replay subscribes to the source for jobID's events after after, without
+// publishing them, for a client joining a job that has finished.
+func (f *Feed) Replay(ctx context.Context, jobID string, after int64) (<-chan Event, error) {
+	return f.source.Subscribe(ctx, jobID, after)
+}
+
+// follow opens jobID's stream with ctx for j, the job under key, and
+// publishes its events until the terminal one, or until ctx is done.
+func (f *Feed) follow(ctx context.Context, key, jobID, tenant string, j *followed) {
+	defer j.cancel()
+	events, err := f.source.Subscribe(ctx, jobID, 0)
+	if err != nil {
+		f.mu.Lock()
+		j.err = err
+		if f.following[key] == j {
+			delete(f.following, key)
+		}
+		f.mu.Unlock()
+		close(j.ready)
+		return
+	}
+	close(j.ready)
+
+	var after int64
+	failures := 0
+	lost := "the job's progress stream ended before it finished"
+	for {
+		for ev := range events {
+			if ev.Stage == StageError {
+				// The stream failed rather than the job; it is reopened.
+				lost = ev.Message
+				continue
+			}
+			// A reopened stream may replay events already published.
+			if ev.Seq <= after {
+				continue
+			}
+			after, failures = ev.Seq, 0
+			ev.JobID, ev.Tenant = jobID, tenant
+			if !f.publish(key, j, ev) {
+				return
+			}
+		}
+		for events = nil; events == nil; {
+			if failures++; failures > f.maxAttempts() {
+				slog.Warn("gave up following job", "job_id", jobID, "error", lost)
+				f.publish(key, j, Event{JobID: jobID, Tenant: tenant, Seq: after + 1, Stage: StageError, Message: lost, Time: time.Now()})
+				return
+			}
+			if !sleep(ctx, f.backoff(failures)) {
+				return
+			}
+			if events, err = f.source.Subscribe(ctx, jobID, after); err != nil {
+				lost = err.Error()
+			}
+		}
+	}
+}
+
+// publish records ev in j's history and publishes it, reporting whether
+// j is still followed after it. A terminal event finishes j. Both happen
+// under f.mu, so that History and the bus never disagree on what has been
+// published.
+func (f *Feed) publish(key string, j *followed, ev Event) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.following[key] != j {
+		// Released while the event was in flight.
+		return false
+	}
+	if len(j.history) == historySize {
+		j.history = append(j.history[:0], j.history[1:]...)
+	}
+	j.history = append(j.history, ev)
+	f.bus.Publish(ev)
+	if !ev.Terminal() {
+		return true
+	}
+	delete(f.following, key)
+	f.finished[key] = true
+	f.order = append(f.order, key)
+	for len(f.order) > maxFinished {
+		delete(f.finished, f.order[0])
+		f.order = f.order[1:]
+	}
+	return false
+}
+
+// Following reports how many jobs are being followed.
+func (f *Feed) Following() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.following)
+}
+
+// backoff is the wait before the nth try at reopening a stream.
+func (f *Feed) backoff(n int) time.Duration {
+	wait := f.Backoff
+	if wait <= 0 {
+		wait = DefaultFollowBackoff
+	}
+	for i := 1; i < n && wait < maxFollowBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, maxFollowBackoff)
+}
+
+func (f *Feed) maxAttempts() int {
+	if f.MaxAttempts < 1 {
+		return DefaultFollowAttempts
+	}
+	return f.MaxAttempts
+}
+
+func (f *Feed) maxFollowing() int {
+	if f.MaxFollowing < 1 {
+		return DefaultMaxFollowing
+	}
+	return f.MaxFollowing
+}
+
+// sleep waits for d, reporting false if ctx is done first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
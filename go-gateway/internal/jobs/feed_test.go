@@ -0,0 +1,276 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/gateway"
+)
+
+// scriptSource answers each subscription with the next of its streams,
+// which end after their events, and then with empty ones. It records the
+// cursor each was opened after.
+type scriptSource struct {
+	err error
+
+	mu      sync.Mutex
+	streams [][]Event
+	afters  []int64
+}
+
+func (s *scriptSource) Subscribe(ctx context.Context, jobID string, after int64) (<-chan Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.afters = append(s.afters, after)
+	if s.err != nil {
+		return nil, s.err
+	}
+	var evs []Event
+	if len(s.streams) > 0 {
+		evs, s.streams = s.streams[0], s.streams[1:]
+	}
+	ch := make(chan Event, len(evs))
+	for _, ev := range evs {
+		ch <- ev
+	}
+	close(ch)
+	return ch, nil
+}
+
+func (s *scriptSource) opens() []int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]int64(nil), s.afters...)
+}
+
+// next returns the next event on s, failing the test after a while.
+func next(t *testing.T, s *Subscription) Event {
+	t.Helper()
+	select {
+	case ev := <-s.Events():
+		return ev
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for an event")
+		return Event{}
+	}
+}
+
+func TestFeedFollowsEachJobOnce(t *testing.T) {
+	src := &scriptSource{streams: [][]Event{{
+		{Seq: 1, Stage: "drafting"},
+		{Seq: 2, Stage: StageDone},
+	}}}
+	feed := NewFeed(context.Background(), src, NewBus())
+	all := feed.Bus().Subscribe("test", 8, Disconnect)
+	ctx := gateway.WithTenant(context.Background(), &gateway.Tenant{ID: "acme"})
+
+	if _, err := feed.Follow(ctx, "j1"); err != nil {
+		t.Fatal(err)
+	}
+	for want := int64(1); want <= 2; want++ {
+		if ev := next(t, all); ev.JobID != "j1" || ev.Tenant != "acme" || ev.Seq != want {
+			t.Fatalf("bus got %+v, want seq %d of acme's j1", ev, want)
+		}
+	}
+	if _, err := feed.Follow(ctx, "j1"); !errors.Is(err, ErrFinished) {
+		t.Errorf("following a finished job: %v, want ErrFinished", err)
+	}
+	if n := len(src.opens()); n != 1 {
+		t.Errorf("stream opened %d times, want 1", n)
+	}
+	if feed.Following() != 0 {
+		t.Errorf("still following %d jobs", feed.Following())
+	}
+}
+
+func TestFeedReopensLostStream(t *testing.T) {
+	src := &scriptSource{streams: [][]Event{
+		{{Seq: 1, Stage: "drafting"}, {Seq: 2, Stage: "drafting"}, {Seq: 3, Stage: StageError, Message: "connection reset"}},
+		{},
+		{{Seq: 2, Stage: "drafting"}, {Seq: 3, Stage: StageDone}},
+	}}
+	feed := NewFeed(context.Background(), src, NewBus())
+	feed.Backoff = time.Millisecond
+	all := feed.Bus().Subscribe("test", 8, Disconnect)
+
+	if _, err := feed.Follow(context.Background(), "j1"); err != nil {
+		t.Fatal(err)
+	}
+	var got []Event
+	for len(got) == 0 || !got[len(got)-1].Terminal() {
+		got = append(got, next(t, all))
+	}
+	if s := seqs(got); len(s) != 3 || s[2] != 3 || got[2].Stage != StageDone {
+		t.Errorf("bus got %+v, want 1 2 3 ending done", got)
+	}
+	if opens := src.opens(); len(opens) != 3 || opens[1] != 2 || opens[2] != 2 {
+		t.Errorf("stream opened after %v, want 0 2 2", opens)
+	}
+}
+
+func TestFeedGivesUp(t *testing.T) {
+	src := &scriptSource{streams: [][]Event{{{Seq: 1, Stage: "drafting"}}}}
+	feed := NewFeed(context.Background(), src, NewBus())
+	feed.MaxAttempts, feed.Backoff = 2, time.Millisecond
+	all := feed.Bus().Subscribe("test", 8, Disconnect)
+
+	if _, err := feed.Follow(context.Background(), "j1"); err != nil {
+		t.Fatal(err)
+	}
+	next(t, all)
+	if ev := next(t, all); ev.Seq != 2 || ev.Stage != StageError || ev.Message == "" {
+		t.Errorf("bus got %+v, want a StageError event after seq 1", ev)
+	}
+	if n := len(src.opens()); n != 3 {
+		t.Errorf("stream opened %d times, want 3", n)
+	}
+}
+
+func TestFeedOpenError(t *testing.T) {
+	src := &scriptSource{err: errors.New("job not found")}
+	feed := NewFeed(context.Background(), src, NewBus())
+	for range 2 {
+		if _, err := feed.Follow(context.Background(), "j1"); err != src.err {
+			t.Fatalf("err = %v", err)
+		}
+	}
+	// A failed open is not remembered.
+	if n := len(src.opens()); n != 2 || feed.Following() != 0 {
+		t.Errorf("opened %d times, following %d", n, feed.Following())
+	}
+}
+
+func TestFeedPublishesEachEventOnce(t *testing.T) {
+	// Each reopened stream replays the job from its start.
+	src := &scriptSource{streams: [][]Event{
+		{{Seq: 1, Stage: "drafting"}, {Seq: 2, Stage: "drafting"}},
+		{{Seq: 1, Stage: "drafting"}, {Seq: 2, Stage: "drafting"}, {Seq: 3, Stage: "rendering"}},
+		{{Seq: 1, Stage: "drafting"}, {Seq: 2, Stage: "drafting"}, {Seq: 3, Stage: "rendering"}, {Seq: 4, Stage: StageDone}},
+	}}
+	feed := NewFeed(context.Background(), src, NewBus())
+	feed.Backoff = time.Millisecond
+	all := feed.Bus().Subscribe("test", 16, Disconnect)
+
+	if _, err := feed.Follow(context.Background(), "j1"); err != nil {
+		t.Fatal(err)
+	}
+	var got []Event
+	for len(got) == 0 || !got[len(got)-1].Terminal() {
+		got = append(got, next(t, all))
+	}
+	if s := seqs(got); len(s) != 4 || s[0] != 1 || s[1] != 2 || s[2] != 3 || s[3] != 4 {
+		t.Errorf("bus got seqs %v, want 1 2 3 4", s)
+	}
+	if n := len(src.opens()); n != 3 {
+		t.Errorf("stream opened %d times, want 3", n)
+	}
+}
+
+// blockSource answers each subscription with a stream that stays open
+// until its context is done. It counts the streams still open.
+type blockSource struct {
+	open atomic.Int32
+}
+
+func (s *blockSource) Subscribe(ctx context.Context, jobID string, after int64) (<-chan Event, error) {
+	s.open.Add(1)
+	ch := make(chan Event)
+	context.AfterFunc(ctx, func() {
+		s.open.Add(-1)
+		close(ch)
+	})
+	return ch, nil
+}
+
+// waitFor fails the test if cond does not hold within a while.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestFeedClosesStreamAfterLastRelease(t *testing.T) {
+	src := &blockSource{}
+	feed := NewFeed(context.Background(), src, NewBus())
+
+	a, err := feed.Follow(context.Background(), "j1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := feed.Follow(context.Background(), "j1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a()
+	a()
+	if src.open.Load() != 1 || feed.Following() != 1 {
+		t.Fatal("stream closed while a hold remains")
+	}
+	b()
+	waitFor(t, "the stream to close", func() bool { return src.open.Load() == 0 })
+	if feed.Following() != 0 {
+		t.Errorf("still following %d jobs", feed.Following())
+	}
+}
+
+func TestFeedStopsWithItsContext(t *testing.T) {
+	src := &blockSource{}
+	ctx, cancel := context.WithCancel(context.Background())
+	feed := NewFeed(ctx, src, NewBus())
+
+	for _, job := range []string{"j1", "j2"} {
+		if _, err := feed.Follow(context.Background(), job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cancel()
+	waitFor(t, "the streams to close", func() bool { return src.open.Load() == 0 })
+}
+
+func TestFeedMaxFollowing(t *testing.T) {
+	src := &blockSource{}
+	feed := NewFeed(context.Background(), src, NewBus())
+	feed.MaxFollowing = 1
+
+	release, err := feed.Follow(context.Background(), "j1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := feed.Follow(context.Background(), "j1"); err != nil {
+		t.Errorf("following a followed job again: %v", err)
+	}
+	if _, err := feed.Follow(context.Background(), "j2"); !errors.Is(err, ErrTooManyJobs) {
+		t.Errorf("following one job too many: %v, want ErrTooManyJobs", err)
+	}
+	release()
+}
+
+func TestFeedHistory(t *testing.T) {
+	src := &scriptSource{streams: [][]Event{{{Seq: 1, Stage: "drafting"}, {Seq: 2, Stage: "rendering"}}}}
+	feed := NewFeed(context.Background(), src, NewBus())
+	feed.Backoff = time.Hour
+	all := feed.Bus().Subscribe("test", 8, Disconnect)
+
+	if _, ok := feed.History(context.Background(), "j1", 0); ok {
+		t.Error("history of a job not followed")
+	}
+	release, err := feed.Follow(context.Background(), "j1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+	next(t, all)
+	next(t, all)
+	if got, ok := feed.History(context.Background(), "j1", 1); !ok || len(got) != 1 || got[0].Seq != 2 {
+		t.Errorf("history after seq 1: %+v, %v; want seq 2", got, ok)
+	}
+}
@@ -0,0 +1,178 @@
+// Package jsonbody decodes request bodies for the gateway's handlers. It
+// rejects what encoding/json would let through quietly, fields the target
+// does not define and data after the document, and turns each way a body
+// can fail to decode into a 400 that tells the client what to fix: where
+// the syntax broke, which field had the wrong type or was not recognised.
+//
+// A body whose Content-Type is MessagePack is converted to JSON first and
+// then decoded the same way.
+package jsonbody
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/msgpack"
+	"github.com/content-factory/go-gateway/internal/respond"
+)
+
+// Options adjusts how a body is decoded. The zero value decodes a
+// required JSON object, rejecting unknown fields, within whatever limit
+// the body already has.
+type Options struct {
+	// What describes the body expected in messages, like "a JSON array of
+	// content requests". Empty means "a JSON object".
+	What string
+	// AllowUnknown ignores fields the target does not define instead of
+	// rejecting them.
+	AllowUnknown bool
+	// Optional accepts an empty body, leaving the target as it was.
+	Optional bool
+	// MaxBytes, if positive, caps the body, on top of any limit the
+	// MaxBytes middleware applies.
+	MaxBytes int64
+}
+
+func (o Options) what() string {
+	if o.What == "" {
+		return "a JSON object"
+	}
+	return o.What
+}
+
+// Decode decodes r's body into v. The error is the response to send: a
+// 413 for a body over its limit, otherwise a 400 with code invalid_json.
+func Decode(r *http.Request, v any, o Options) *apierror.Error {
+	var body io.Reader = r.Body
+	if body == nil {
+		body = http.NoBody
+	}
+	if o.MaxBytes > 0 {
+		body = http.MaxBytesReader(nil, io.NopCloser(body), o.MaxBytes)
+	}
+	if !respond.IsMsgPack(r.Header.Get("Content-Type")) {
+		return decode(body, v, o, true)
+	}
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		return readError(err)
+	}
+	if len(raw) == 0 {
+		return decode(bytes.NewReader(nil), v, o, false)
+	}
+	converted, err := msgpack.ToJSON(raw)
+	if err != nil {
+		return apierror.InvalidJSON("request body is not valid MessagePack")
+	}
+	// Offsets into the converted JSON would mean nothing to the client.
+	return decode(bytes.NewReader(converted), v, o, false)
+}
+
+// DecodeBytes decodes data, one JSON document, into v as Decode does a
+// body. It suits parts of a body decoded on their own, like the items of
+// a batch.
+func DecodeBytes(data []byte, v any, o Options) *apierror.Error {
+	return decode(bytes.NewReader(data), v, o, true)
+}
+
+func decode(body io.Reader, v any, o Options, offsets bool) *apierror.Error {
+	dec := json.NewDecoder(body)
+	if !o.AllowUnknown {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(v); err != nil {
+		if errors.Is(err, io.EOF) {
+			if o.Optional {
+				return nil
+			}
+			return apierror.InvalidJSON("request body is empty; it must be " + o.what())
+		}
+		return decodeError(err, o, offsets)
+	}
+	end := dec.InputOffset()
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return apierror.BodyTooLarge(tooLarge.Limit)
+		}
+		msg := "request body must hold a single JSON document; more data follows it"
+		if offsets {
+			msg += " at byte offset " + strconv.FormatInt(end, 10)
+		}
+		return apierror.InvalidJSON(msg)
+	}
+	return nil
+}
+
+// decodeError is the response to err, from decoding a body described by
+// o.
+func decodeError(err error, o Options, offsets bool) *apierror.Error {
+	at := func(offset int64) string {
+		if !offsets {
+			return ""
+		}
+		return " at byte offset " + strconv.FormatInt(offset, 10)
+	}
+	var (
+		syntax   *json.SyntaxError
+		mismatch *json.UnmarshalTypeError
+	)
+	switch {
+	case errors.As(err, &syntax):
+		return apierror.InvalidJSON("request body is not valid JSON: " +
+			strings.TrimPrefix(syntax.Error(), "json: ") + at(syntax.Offset))
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return apierror.InvalidJSON("request body is not valid JSON: it ends before the document is complete")
+	case errors.As(err, &mismatch):
+		if mismatch.Field == "" {
+			return apierror.InvalidJSON("request body must be " + o.what())
+		}
+		e := apierror.InvalidJSON("request body has a field of the wrong type" + at(mismatch.Offset))
+		e.Fields = []apierror.FieldError{{Field: mismatch.Field, Message: "must be " + kind(mismatch.Type) + ", not " + mismatch.Value}}
+		return e
+	}
+	if name, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		e := apierror.InvalidJSON("request body has a field that is not recognised")
+		e.Fields = []apierror.FieldError{{Field: strings.Trim(name, `"`), Message: "is not a recognised field"}}
+		return e
+	}
+	return readError(err)
+}
+
+// readError is the response to a body that could not be read.
+func readError(err error) *apierror.Error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return apierror.BodyTooLarge(tooLarge.Limit)
+	}
+	return apierror.InvalidJSON("request body could not be read")
+}
+
+// kind describes the JSON values that decode into t.
+func kind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.String:
+		return "a string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	case reflect.Pointer:
+		return kind(t.Elem())
+	}
+	return "a " + t.String()
+}
@@ -0,0 +1,119 @@
+package jsonbody
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/msgpack"
+)
+
+type request struct {
+	Name    string         `json:"name"`
+	Count   int            `json:"count"`
+	Options map[string]any `json:"options"`
+	Nested  struct {
+		Enabled bool `json:"enabled"`
+	} `json:"nested"`
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		opts    Options
+		status  int
+		message string // in the error message
+		field   string
+	}{
+		{"valid", `{"name":"x","count":2,"options":{"any":1}}`, Options{}, 0, "", ""},
+		{"surrounding whitespace", " \n{\"name\":\"x\"}\n", Options{}, 0, "", ""},
+		{"empty", ``, Options{}, http.StatusBadRequest, "request body is empty; it must be a JSON object", ""},
+		{"whitespace only", "  \n", Options{}, http.StatusBadRequest, "is empty", ""},
+		{"empty optional", ``, Options{Optional: true}, 0, "", ""},
+		{"empty described", ``, Options{What: "a JSON array"}, http.StatusBadRequest, "it must be a JSON array", ""},
+		{"syntax error", `{"name": "x",}`, Options{}, http.StatusBadRequest, "not valid JSON: invalid character '}' looking for beginning of object key string at byte offset 14", ""},
+		{"truncated", `{"name": "x"`, Options{}, http.StatusBadRequest, "ends before the document is complete", ""},
+		{"unknown field", `{"name":"x","nmae":"y"}`, Options{}, http.StatusBadRequest, "not recognised", "nmae"},
+		{"unknown nested field", `{"nested":{"enabld":true}}`, Options{}, http.StatusBadRequest, "not recognised", "enabld"},
+		{"unknown field allowed", `{"name":"x","nmae":"y"}`, Options{AllowUnknown: true}, 0, "", ""},
+		{"type mismatch", `{"name":"x","count":"two"}`, Options{}, http.StatusBadRequest, "wrong type at byte offset 25", "count"},
+		{"nested type mismatch", `{"nested":{"enabled":"yes"}}`, Options{}, http.StatusBadRequest, "wrong type", "nested.enabled"},
+		{"not an object", `[1, 2]`, Options{}, http.StatusBadRequest, "request body must be a JSON object", ""},
+		{"two objects", `{"name":"x"}{"name":"y"}`, Options{}, http.StatusBadRequest, "single JSON document; more data follows it at byte offset 12", ""},
+		{"trailing garbage", `{"name":"x"} ok`, Options{}, http.StatusBadRequest, "single JSON document", ""},
+		{"over MaxBytes", `{"name":"` + strings.Repeat("x", 64) + `"}`, Options{MaxBytes: 32}, http.StatusRequestEntityTooLarge, "limit of 32 bytes", ""},
+		{"within MaxBytes", `{"name":"x"}`, Options{MaxBytes: 32}, 0, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			var v request
+			e := Decode(r, &v, tt.opts)
+			if tt.status == 0 {
+				if e != nil {
+					t.Fatalf("Decode = %v, want nil", e)
+				}
+				return
+			}
+			check(t, e, tt.status, tt.message, tt.field)
+		})
+	}
+}
+
+func check(t *testing.T, e *apierror.Error, status int, message, field string) {
+	t.Helper()
+	if e == nil {
+		t.Fatal("Decode = nil, want an error")
+	}
+	if e.Status != status || !strings.Contains(e.Message, message) {
+		t.Errorf("Decode = %d %q, want %d mentioning %q", e.Status, e.Message, status, message)
+	}
+	if status == http.StatusBadRequest && e.Code != apierror.CodeInvalidJSON {
+		t.Errorf("code = %q, want %q", e.Code, apierror.CodeInvalidJSON)
+	}
+	switch {
+	case field == "" && len(e.Fields) > 0:
+		t.Errorf("fields = %+v, want none", e.Fields)
+	case field != "" && (len(e.Fields) != 1 || e.Fields[0].Field != field):
+		t.Errorf("fields = %+v, want %s", e.Fields, field)
+	}
+}
+
+func TestDecodeBodyLimit(t *testing.T) {
+	rec := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"`+strings.Repeat("x", 64)+`"}`))
+	r.Body = http.MaxBytesReader(rec, r.Body, 16)
+	var v request
+	check(t, Decode(r, &v, Options{}), http.StatusRequestEntityTooLarge, "limit of 16 bytes", "")
+}
+
+func TestDecodeMsgPack(t *testing.T) {
+	body, err := msgpack.FromJSON([]byte(`{"name":"x","count":"two"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	r.Header.Set("Content-Type", msgpack.ContentType)
+	var v request
+	e := Decode(r, &v, Options{})
+	check(t, e, http.StatusBadRequest, "wrong type", "count")
+	if strings.Contains(e.Message, "offset") {
+		t.Errorf("message %q gives an offset into the converted JSON", e.Message)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("\xc1"))
+	r.Header.Set("Content-Type", msgpack.ContentType)
+	check(t, Decode(r, &v, Options{}), http.StatusBadRequest, "not valid MessagePack", "")
+}
+
+func TestDecodeBytes(t *testing.T) {
+	var v request
+	if e := DecodeBytes([]byte(`{"name":"x"}`), &v, Options{}); e != nil || v.Name != "x" {
+		t.Fatalf("DecodeBytes = %v, %+v", e, v)
+	}
+	check(t, DecodeBytes([]byte(`{"name":1}`), &v, Options{}), http.StatusBadRequest, "wrong type", "name")
+}
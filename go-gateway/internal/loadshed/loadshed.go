@@ -0,0 +1,92 @@
+// Package loadshed rejects requests early once too many are in flight, so
+// an overloaded gateway answers some clients quickly instead of letting
+// every request queue until it times out.
+package loadshed
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/metrics"
+)
+
+// Defaults used by the config package.
+const (
+	DefaultMaxInFlight = 1000
+	DefaultRetryAfter  = 1 // seconds
+)
+
+// ExemptPaths are never shed or counted: probes and scrapes must keep
+// working while the gateway is overloaded, or orchestrators would restart
+// it just when it is busiest.
+var ExemptPaths = []string{"/health", "/livez", "/readyz", "/metrics", "/version"}
+
+var (
+	inFlightGauge = metrics.NewGaugeVec("gateway_load_shed_in_flight",
+		"Requests counted against the load-shedding limit that are being served.")
+	limitGauge = metrics.NewGaugeVec("gateway_load_shed_limit",
+		"The load-shedding limit in force; 0 means shedding is off.")
+	shedTotal = metrics.NewCounterVec("gateway_load_shed_requests_total",
+		"Requests rejected with 503 because the gateway was at its in-flight limit.")
+)
+
+// Shedder counts in-flight requests and rejects new ones above a limit
+// that can be changed while it runs.
+type Shedder struct {
+	limit    atomic.Int64
+	inFlight atomic.Int64
+	exempt   map[string]bool
+}
+
+// New returns a Shedder allowing maxInFlight concurrent requests, or any
+// number if maxInFlight is 0. Requests for ExemptPaths always pass.
+func New(maxInFlight int) *Shedder {
+	s := &Shedder{exempt: make(map[string]bool, len(ExemptPaths))}
+	for _, p := range ExemptPaths {
+		s.exempt[p] = true
+	}
+	s.SetLimit(maxInFlight)
+	return s
+}
+
+// SetLimit changes the limit; 0 turns shedding off. Requests already in
+// flight are unaffected.
+func (s *Shedder) SetLimit(maxInFlight int) {
+	s.limit.Store(int64(maxInFlight))
+	limitGauge.With().Set(float64(maxInFlight))
+}
+
+// Limit returns the limit in force.
+func (s *Shedder) Limit() int { return int(s.limit.Load()) }
+
+// InFlight returns how many counted requests are being served.
+func (s *Shedder) InFlight() int { return int(s.inFlight.Load()) }
+
+// Middleware sheds requests over the limit with 503 and Retry-After. It
+// belongs early in the chain, before any expensive work, but after the
+// logging and metrics middleware so shed requests are still recorded.
+// Long-lived requests such as WebSocket and SSE streams count for as long
+// as they stay open.
+func (s *Shedder) Middleware(next http.Handler) http.Handler {
+	gauge := inFlightGauge.With()
+	shed := shedTotal.With()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.exempt[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		n := s.inFlight.Add(1)
+		defer s.inFlight.Add(-1)
+		if limit := s.limit.Load(); limit > 0 && n > limit {
+			shed.Inc()
+			w.Header().Set("Retry-After", strconv.Itoa(DefaultRetryAfter))
+			apierror.Write(w, r, apierror.Overloaded("the gateway is at capacity, retry shortly"))
+			return
+		}
+		gauge.Inc()
+		defer gauge.Dec()
+		next.ServeHTTP(w, r)
+	})
+}
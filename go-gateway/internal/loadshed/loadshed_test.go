@@ -0,0 +1,92 @@
+package loadshed
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// hold serves requests until release is closed, signalling each arrival.
+func hold() (h http.Handler, arrived chan struct{}, release chan struct{}) {
+	arrived, release = make(chan struct{}, 16), make(chan struct{})
+	h = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		arrived <- struct{}{}
+		<-release
+	})
+	return h, arrived, release
+}
+
+func serve(h http.Handler, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+func TestShedsOverLimit(t *testing.T) {
+	s := New(2)
+	inner, arrived, release := hold()
+	h := s.Middleware(inner)
+
+	var wg sync.WaitGroup
+	for range 2 {
+		wg.Add(1)
+		go func() { defer wg.Done(); serve(h, "/api/v1/jobs") }()
+		<-arrived
+	}
+	if s.InFlight() != 2 {
+		t.Fatalf("in flight = %d", s.InFlight())
+	}
+
+	rec := serve(h, "/api/v1/jobs")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("no Retry-After")
+	}
+	if v := shedTotal.With().Value(); v < 1 {
+		t.Errorf("shed count = %v", v)
+	}
+
+	close(release)
+	wg.Wait()
+	if s.InFlight() != 0 {
+		t.Errorf("in flight after release = %d", s.InFlight())
+	}
+}
+
+func TestExemptPathsPass(t *testing.T) {
+	s := New(1)
+	inner, arrived, release := hold()
+	defer close(release)
+	go serve(s.Middleware(inner), "/api/v1/jobs")
+	<-arrived
+
+	ok := s.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, path := range []string{"/health", "/readyz", "/metrics"} {
+		if rec := serve(ok, path); rec.Code != http.StatusOK {
+			t.Errorf("%s: status = %d", path, rec.Code)
+		}
+	}
+}
+
+func TestSetLimit(t *testing.T) {
+	s := New(1)
+	inner, arrived, release := hold()
+	defer close(release)
+	h := s.Middleware(inner)
+	go serve(h, "/")
+	<-arrived
+
+	s.SetLimit(2)
+	go serve(h, "/")
+	<-arrived
+	if s.InFlight() != 2 {
+		t.Errorf("in flight = %d after raising the limit", s.InFlight())
+	}
+
+	s.SetLimit(0)
+	go serve(h, "/")
+	<-arrived
+}
@@ -0,0 +1,133 @@
+// Package maintenance implements the gateway's maintenance mode, in which
+// it turns away client traffic with 503 while deploys or incidents are
+// handled, but keeps answering probes and operators.
+package maintenance
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/loadshed"
+	"github.com/content-factory/go-gateway/internal/metrics"
+)
+
+// Defaults used by the config package.
+const (
+	DefaultRetryAfter = time.Minute
+	DefaultMessage    = "the gateway is down for maintenance, retry later"
+)
+
+// ExemptPrefixes are the operator paths that stay up in maintenance mode,
+// besides the probes of loadshed.ExemptPaths.
+var ExemptPrefixes = []string{"/admin/", "/debug/"}
+
+var (
+	enabledGauge = metrics.NewGaugeVec("gateway_maintenance_enabled",
+		"1 while the gateway is in maintenance mode.")
+	rejectedTotal = metrics.NewCounterVec("gateway_maintenance_rejected_requests_total",
+		"Requests answered with 503 because the gateway was in maintenance mode.")
+)
+
+// Status is the maintenance mode in force.
+type Status struct {
+	Enabled bool `json:"enabled"`
+	// Since is when the mode was last switched on.
+	Since *time.Time `json:"since,omitempty"`
+	// Message is what rejected requests are told.
+	Message string `json:"message,omitempty"`
+}
+
+// Mode holds the maintenance mode. It is switched while serving; requests
+// already being served are unaffected.
+type Mode struct {
+	status     atomic.Pointer[Status]
+	message    string
+	retryAfter string
+	exempt     map[string]bool
+}
+
+// New returns a Mode, on if enabled, whose rejections tell clients to retry
+// after retryAfter, rounded up to a second. message is the default for
+// Set; empty means DefaultMessage.
+func New(enabled bool, message string, retryAfter time.Duration) *Mode {
+	if message == "" {
+		message = DefaultMessage
+	}
+	m := &Mode{
+		message:    message,
+		retryAfter: strconv.Itoa(int((retryAfter + time.Second - 1) / time.Second)),
+		exempt:     make(map[string]bool, len(loadshed.ExemptPaths)),
+	}
+	for _, p := range loadshed.ExemptPaths {
+		m.exempt[p] = true
+	}
+	m.Set(enabled, "")
+	return m
+}
+
+// Set switches maintenance mode on or off and returns the new status. An
+// empty message means the one New was given, or the current one when the
+// mode is already on. Switching it on again keeps the original Since.
+func (m *Mode) Set(enabled bool, message string) Status {
+	next := &Status{Enabled: enabled}
+	if enabled {
+		now := time.Now()
+		next.Since = &now
+		if cur := m.status.Load(); cur != nil && cur.Enabled {
+			next.Since = cur.Since
+			if message == "" {
+				message = cur.Message
+			}
+		}
+		if message == "" {
+			message = m.message
+		}
+		next.Message = message
+		enabledGauge.With().Set(1)
+	} else {
+		enabledGauge.With().Set(0)
+	}
+	m.status.Store(next)
+	return *next
+}
+
+// Status returns the maintenance mode in force.
+func (m *Mode) Status() Status { return *m.status.Load() }
+
+// Enabled reports whether the gateway is in maintenance mode.
+func (m *Mode) Enabled() bool { return m.status.Load().Enabled }
+
+// exempted reports whether path stays up in maintenance mode.
+func (m *Mode) exempted(path string) bool {
+	if m.exempt[path] {
+		return true
+	}
+	for _, prefix := range ExemptPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware answers requests with 503 maintenance and Retry-After while
+// the mode is on, except for the probes and operator endpoints. It belongs
+// after the logging and metrics middleware, so the rejections are
+// recorded, and after CORS, so browsers can read them.
+func (m *Mode) Middleware(next http.Handler) http.Handler {
+	rejected := rejectedTotal.With()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := m.status.Load()
+		if !status.Enabled || m.exempted(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		rejected.Inc()
+		w.Header().Set("Retry-After", m.retryAfter)
+		apierror.Write(w, r, apierror.Maintenance(status.Message))
+	})
+}
@@ -0,0 +1,78 @@
+package maintenance
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+)
+
+func serve(h http.Handler, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+func TestRejectsWhileEnabled(t *testing.T) {
+	m := New(false, "", 90*time.Second+time.Millisecond)
+	h := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	if rec := serve(h, "/api/v1/jobs"); rec.Code != http.StatusOK {
+		t.Fatalf("disabled: status = %d, want 200", rec.Code)
+	}
+
+	status := m.Set(true, "upgrading the database")
+	if !status.Enabled || status.Since == nil || status.Message != "upgrading the database" {
+		t.Fatalf("status = %+v", status)
+	}
+	rec := serve(h, "/api/v1/jobs")
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "91" {
+		t.Fatalf("status = %d, Retry-After %q, want 503 after 91s", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	var body apierror.Envelope
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Error.Code != apierror.CodeMaintenance || body.Error.Message != "upgrading the database" {
+		t.Errorf("error = %+v", body.Error)
+	}
+
+	for _, path := range []string{"/health", "/readyz", "/livez", "/metrics", "/admin/maintenance", "/debug/stats"} {
+		if rec := serve(h, path); rec.Code != http.StatusOK {
+			t.Errorf("GET %s = %d in maintenance, want 200", path, rec.Code)
+		}
+	}
+
+	m.Set(false, "")
+	if rec := serve(h, "/api/v1/jobs"); rec.Code != http.StatusOK {
+		t.Errorf("switched off: status = %d, want 200", rec.Code)
+	}
+	if m.Status().Since != nil {
+		t.Errorf("switched off status = %+v", m.Status())
+	}
+}
+
+func TestSetKeepsSinceAndMessage(t *testing.T) {
+	m := New(true, "deploying", time.Second)
+	first := m.Status()
+	if !first.Enabled || first.Message != "deploying" {
+		t.Fatalf("status = %+v", first)
+	}
+	m.Set(true, "rolling back")
+	again := m.Set(true, "")
+	if !again.Since.Equal(*first.Since) || again.Message != "rolling back" {
+		t.Errorf("switching on again = %+v, want since %v and the current message", again, first.Since)
+	}
+	m.Set(false, "")
+	if got := m.Set(true, "").Message; got != "deploying" {
+		t.Errorf("message = %q, want the default given to New", got)
+	}
+	if v := enabledGauge.With().Value(); v != 1 {
+		t.Errorf("enabled gauge = %v", v)
+	}
+	if got := New(true, "", time.Second).Status().Message; got != DefaultMessage {
+		t.Errorf("message = %q, want DefaultMessage", got)
+	}
+}
@@ -0,0 +1,190 @@
+// Package methodlimit caps the backend calls of each method running at
+// once. Generating a video costs the content service far more than
+// reading a job's status, so expensive methods get a limit of their own,
+// and calls beyond it wait briefly for a slot or are shed, while methods
+// without a limit are let through untouched.
+package methodlimit
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/metrics"
+	"github.com/content-factory/go-gateway/internal/rpc"
+)
+
+// DefaultMaxWait is how long a call may wait for a slot when Limit.MaxWait
+// is zero.
+const DefaultMaxWait = time.Second
+
+// Limit caps the calls of one method.
+type Limit struct {
+	// MaxInFlight is how many calls may run at once.
+	MaxInFlight int
+	// MaxQueued is how many more may wait for a slot; 0 sheds every call
+	// over MaxInFlight at once.
+	MaxQueued int
+	// MaxWait is how long a call waits for a slot before it is shed; zero
+	// means DefaultMaxWait.
+	MaxWait time.Duration
+}
+
+// ErrBusy is returned for a call shed because its method has as many
+// calls running and waiting as it allows, or it waited MaxWait for a
+// slot. Its Unavailable code makes handlers answer 503.
+var ErrBusy = rpc.Errorf(rpc.Unavailable, "too many calls of this method are in flight; retry later")
+
+var (
+	inFlightGauge = metrics.NewGaugeVec("gateway_backend_method_in_flight",
+		"Backend calls of a limited method running, by method.",
+		"method")
+	queuedGauge = metrics.NewGaugeVec("gateway_backend_method_queued",
+		"Backend calls of a limited method waiting for a slot, by method.",
+		"method")
+	rejectedTotal = metrics.NewCounterVec("gateway_backend_method_rejected_total",
+		"Backend calls shed with 503 because their method was at its concurrency limit, by method.",
+		"method")
+)
+
+// Limiter holds the slots of each limited method.
+type Limiter struct {
+	sems map[string]*semaphore
+}
+
+// semaphore is the slots of one method. A call holds one by sending on
+// slots, and gives it back by receiving.
+type semaphore struct {
+	method  string
+	limit   Limit
+	slots   chan struct{}
+	waiting atomic.Int64
+}
+
+// New returns a Limiter applying limits, by fully-qualified method name.
+// Methods without a limit, or with a MaxInFlight below 1, are not limited.
+func New(limits map[string]Limit) *Limiter {
+	l := &Limiter{sems: make(map[string]*semaphore, len(limits))}
+	for method, limit := range limits {
+		if limit.MaxInFlight < 1 {
+			continue
+		}
+		if limit.MaxWait <= 0 {
+			limit.MaxWait = DefaultMaxWait
+		}
+		l.sems[method] = &semaphore{method: method, limit: limit, slots: make(chan struct{}, limit.MaxInFlight)}
+	}
+	return l
+}
+
+// Acquire takes a slot for a call of method, waiting up to its MaxWait
+// for one. It fails with ErrBusy if the call is shed, or with ctx's error
+// if ctx is done first. Otherwise the caller must call release once the
+// call is over; release is safe to call more than once.
+func (l *Limiter) Acquire(ctx context.Context, method string) (release func(), err error) {
+	s := l.sems[method]
+	if s == nil {
+		return func() {}, nil
+	}
+	select {
+	case s.slots <- struct{}{}:
+		return s.admitted(), nil
+	default:
+	}
+	if s.waiting.Add(1) > int64(s.limit.MaxQueued) {
+		s.waiting.Add(-1)
+		rejectedTotal.With(method).Inc()
+		return nil, ErrBusy
+	}
+	queuedGauge.With(method).Inc()
+	defer func() {
+		s.waiting.Add(-1)
+		queuedGauge.With(method).Dec()
+	}()
+	timer := time.NewTimer(s.limit.MaxWait)
+	defer timer.Stop()
+	select {
+	case s.slots <- struct{}{}:
+		return s.admitted(), nil
+	case <-timer.C:
+		rejectedTotal.With(method).Inc()
+		return nil, ErrBusy
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// admitted counts a call that took a slot and returns its release.
+func (s *semaphore) admitted() func() {
+	inFlightGauge.With(s.method).Inc()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			<-s.slots
+			inFlightGauge.With(s.method).Dec()
+		})
+	}
+}
+
+// Conn is the backend transport; it matches backend.Conn.
+type Conn interface {
+	Invoke(ctx context.Context, method string, req, resp any) error
+	NewStream(ctx context.Context, method string, req any) (rpc.ClientStream, error)
+}
+
+// Wrap limits the calls on conn with l. A unary call holds its slot until
+// it returns. A stream holds one until it ends, is closed, or its context
+// is done, since streamed generation is as expensive as the rest of it.
+func Wrap(conn Conn, l *Limiter) Conn {
+	return &limited{conn: conn, l: l}
+}
+
+type limited struct {
+	conn Conn
+	l    *Limiter
+}
+
+func (c *limited) Invoke(ctx context.Context, method string, req, resp any) error {
+	release, err := c.l.Acquire(ctx, method)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return c.conn.Invoke(ctx, method, req, resp)
+}
+
+func (c *limited) NewStream(ctx context.Context, method string, req any) (rpc.ClientStream, error) {
+	release, err := c.l.Acquire(ctx, method)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := c.conn.NewStream(ctx, method, req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	// A stream its caller abandons without closing still gives the slot
+	// back once its context is done.
+	stop := context.AfterFunc(ctx, release)
+	return &limitedStream{ClientStream: stream, release: func() { stop(); release() }}, nil
+}
+
+// limitedStream releases its slot when the stream ends or is closed.
+type limitedStream struct {
+	rpc.ClientStream
+	release func()
+}
+
+func (s *limitedStream) Recv(msg any) error {
+	err := s.ClientStream.Recv(msg)
+	if err != nil {
+		s.release()
+	}
+	return err
+}
+
+func (s *limitedStream) Close() error {
+	s.release()
+	return s.ClientStream.Close()
+}
@@ -0,0 +1,190 @@
+package methodlimit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/rpc"
+)
+
+const (
+	expensive = "/svc/Generate"
+	cheap     = "/svc/Status"
+)
+
+// held reports how many slots of method l has handed out.
+func held(l *Limiter, method string) int {
+	return len(l.sems[method].slots)
+}
+
+func TestLimitsOnlyLimitedMethods(t *testing.T) {
+	l := New(map[string]Limit{expensive: {MaxInFlight: 2}, "/svc/Off": {MaxInFlight: 0}})
+	ctx := context.Background()
+	first, err := l.Acquire(ctx, expensive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Acquire(ctx, expensive); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Acquire(ctx, expensive); err != ErrBusy {
+		t.Fatalf("third call: err = %v, want ErrBusy", err)
+	}
+	if rpc.CodeOf(ErrBusy) != rpc.Unavailable {
+		t.Errorf("ErrBusy code = %v", rpc.CodeOf(ErrBusy))
+	}
+	for _, method := range []string{cheap, "/svc/Off"} {
+		for range 10 {
+			if _, err := l.Acquire(ctx, method); err != nil {
+				t.Fatalf("%s: %v", method, err)
+			}
+		}
+	}
+
+	first()
+	first() // a second release gives back nothing more
+	if n := held(l, expensive); n != 1 {
+		t.Fatalf("slots held = %d, want 1", n)
+	}
+	if _, err := l.Acquire(ctx, expensive); err != nil {
+		t.Errorf("after a release: %v", err)
+	}
+}
+
+func TestQueuesBriefly(t *testing.T) {
+	l := New(map[string]Limit{expensive: {MaxInFlight: 1, MaxQueued: 1, MaxWait: time.Minute}})
+	ctx := context.Background()
+	hold, _ := l.Acquire(ctx, expensive)
+
+	var wg sync.WaitGroup
+	var queuedErr error
+	wg.Go(func() {
+		var release func()
+		release, queuedErr = l.Acquire(ctx, expensive)
+		if release != nil {
+			release()
+		}
+	})
+	for l.sems[expensive].waiting.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// The queue is full, so the next call is shed at once.
+	if _, err := l.Acquire(ctx, expensive); err != ErrBusy {
+		t.Errorf("call over the queue: err = %v, want ErrBusy", err)
+	}
+	hold()
+	wg.Wait()
+	if queuedErr != nil {
+		t.Errorf("queued call: %v", queuedErr)
+	}
+	if n := held(l, expensive); n != 0 {
+		t.Errorf("slots held = %d, want 0", n)
+	}
+}
+
+func TestShedsAfterMaxWait(t *testing.T) {
+	l := New(map[string]Limit{expensive: {MaxInFlight: 1, MaxQueued: 4, MaxWait: 20 * time.Millisecond}})
+	hold, _ := l.Acquire(context.Background(), expensive)
+	defer hold()
+	start := time.Now()
+	if _, err := l.Acquire(context.Background(), expensive); err != ErrBusy {
+		t.Fatalf("err = %v, want ErrBusy", err)
+	}
+	if waited := time.Since(start); waited < 20*time.Millisecond {
+		t.Errorf("shed after %v, before MaxWait", waited)
+	}
+	if n := l.sems[expensive].waiting.Load(); n != 0 {
+		t.Errorf("%d calls still counted as waiting", n)
+	}
+}
+
+func TestCanceledWaitLeavesQueue(t *testing.T) {
+	l := New(map[string]Limit{expensive: {MaxInFlight: 1, MaxQueued: 1, MaxWait: time.Minute}})
+	hold, _ := l.Acquire(context.Background(), expensive)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if _, err := l.Acquire(ctx, expensive); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want canceled", err)
+	}
+	hold()
+	// The canceled call took no slot and left no place in the queue.
+	if n := held(l, expensive); n != 0 {
+		t.Errorf("slots held = %d, want 0", n)
+	}
+	if n := l.sems[expensive].waiting.Load(); n != 0 {
+		t.Errorf("%d calls still counted as waiting", n)
+	}
+}
+
+// fakeConn answers unary calls at once and serves streams of three
+// messages.
+type fakeConn struct{}
+
+func (fakeConn) Invoke(ctx context.Context, method string, req, resp any) error { return nil }
+
+func (fakeConn) NewStream(ctx context.Context, method string, req any) (rpc.ClientStream, error) {
+	return &fakeStream{left: 3}, nil
+}
+
+type fakeStream struct{ left int }
+
+func (s *fakeStream) Recv(msg any) error {
+	if s.left == 0 {
+		return io.EOF
+	}
+	s.left--
+	return nil
+}
+
+func (s *fakeStream) Close() error { return nil }
+
+func TestWrapReleasesSlots(t *testing.T) {
+	l := New(map[string]Limit{expensive: {MaxInFlight: 1}})
+	conn := Wrap(fakeConn{}, l)
+	ctx := context.Background()
+	for range 3 {
+		if err := conn.Invoke(ctx, expensive, nil, nil); err != nil {
+			t.Fatalf("unary call: %v", err)
+		}
+	}
+
+	// A stream holds its slot until it ends.
+	stream, err := conn.NewStream(ctx, expensive, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Invoke(ctx, expensive, nil, nil); err != ErrBusy {
+		t.Errorf("call beside an open stream: err = %v, want ErrBusy", err)
+	}
+	for stream.Recv(nil) == nil {
+	}
+	if n := held(l, expensive); n != 0 {
+		t.Fatalf("slots held after the stream ended = %d", n)
+	}
+
+	// Or until it is closed.
+	stream, _ = conn.NewStream(ctx, expensive, nil)
+	stream.Close()
+	stream.Close()
+	if n := held(l, expensive); n != 0 {
+		t.Fatalf("slots held after Close = %d", n)
+	}
+
+	// Or until its context is done, if its caller abandons it.
+	streamCtx, cancel := context.WithCancel(ctx)
+	if _, err := conn.NewStream(streamCtx, expensive, nil); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	deadline := time.Now().Add(2 * time.Second)
+	for held(l, expensive) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("slot held after the stream's context was canceled")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,63 @@
+// Package metrics registers the gateway's Prometheus metrics, built on
+// github.com/prometheus/client_golang, and serves them with promhttp.
+// Its families take label values positionally, as in
+// requests.With("GET", "/api/v1/jobs/{id}", "200").Inc(), and its
+// metrics can be read back, so tests assert on what was counted.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// DefaultBuckets are latency buckets in seconds, the Prometheus client's
+// defaults.
+var DefaultBuckets = prometheus.DefBuckets
+
+// Registry holds a set of metric families.
+type Registry struct {
+	reg     *prometheus.Registry
+	handler http.Handler
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	reg := prometheus.NewRegistry()
+	return &Registry{reg: reg, handler: promhttp.HandlerFor(reg, promhttp.HandlerOpts{})}
+}
+
+// Default is the registry served by Handler. Besides the gateway's own
+// metrics it exports the Go runtime's and the process's.
+var Default = func() *Registry {
+	r := NewRegistry()
+	r.reg.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	return r
+}()
+
+// register adds c to r. It panics if a metric of the same name is
+// registered already.
+func (r *Registry) register(c prometheus.Collector) {
+	r.reg.MustRegister(c)
+}
+
+// ServeHTTP renders every registered metric, in the exposition format the
+// scraper asks for.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.handler.ServeHTTP(w, req)
+}
+
+// Handler serves the Default registry.
+func Handler() http.Handler { return Default }
+
+// read returns the current state of m.
+func read(m prometheus.Metric) *dto.Metric {
+	var out dto.Metric
+	if err := m.Write(&out); err != nil {
+		panic("metrics: " + err.Error())
+	}
+	return &out
+}
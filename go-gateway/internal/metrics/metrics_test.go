@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func scrape(r *Registry) string {
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	return rec.Body.String()
+}
+
+func TestExposition(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounterVec("requests_total", "Requests.", "code")
+	g := r.NewGaugeVec("in_flight", "In flight.")
+	h := r.NewHistogramVec("latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+
+	c.With("200").Add(2)
+	c.With(`we"ird`).Inc()
+	g.With().Set(3)
+	h.With("/a").Observe(0.05)
+	h.With("/a").Observe(0.5)
+	h.With("/a").Observe(5)
+
+	out := scrape(r)
+	for _, want := range []string{
+		"# TYPE requests_total counter",
+		`requests_total{code="200"} 2`,
+		`requests_total{code="we\"ird"} 1`,
+		"# TYPE in_flight gauge",
+		"in_flight 3",
+		`latency_seconds_bucket{route="/a",le="0.1"} 1`,
+		`latency_seconds_bucket{route="/a",le="1"} 2`,
+		`latency_seconds_bucket{route="/a",le="+Inf"} 3`,
+		`latency_seconds_sum{route="/a"} 5.55`,
+		`latency_seconds_count{route="/a"} 3`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestGaugeDelete(t *testing.T) {
+	r := NewRegistry()
+	g := r.NewGaugeVec("depth", "Depth.", "principal")
+	g.With("alice").Set(2)
+	g.With("bob").Set(1)
+	g.Delete("alice")
+	g.Delete("carol")
+	out := scrape(r)
+	if strings.Contains(out, "alice") || !strings.Contains(out, `depth{principal="bob"} 1`) {
+		t.Errorf("after Delete:\n%s", out)
+	}
+	if g.With("alice").Value() != 0 {
+		t.Error("deleted gauge kept its value")
+	}
+}
+
+func TestDuplicateRegistrationPanics(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("x_total", "X.")
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic on duplicate metric name")
+		}
+	}()
+	r.NewGaugeVec("x_total", "X again.")
+}
+
+func TestDefaultExportsRuntimeMetrics(t *testing.T) {
+	out := scrape(Default)
+	for _, want := range []string{"# TYPE go_goroutines gauge", "# TYPE process_cpu_seconds_total counter"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q", want)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Counter is a monotonically increasing value.
+type Counter struct{ prometheus.Counter }
+
+// Value returns the current count.
+func (c *Counter) Value() float64 { return read(c.Counter).GetCounter().GetValue() }
+
+// CounterVec is a counter partitioned by labels.
+type CounterVec struct{ vec *prometheus.CounterVec }
+
+// NewCounterVec registers a counter family on r.
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	v := prometheus.NewCounterVec(prometheus.CounterOpts{Name: name, Help: help}, labels)
+	r.register(v)
+	return &CounterVec{vec: v}
+}
+
+// NewCounterVec registers a counter family on the Default registry.
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return Default.NewCounterVec(name, help, labels...)
+}
+
+// With returns the counter for the given label values.
+func (v *CounterVec) With(values ...string) *Counter {
+	return &Counter{v.vec.WithLabelValues(values...)}
+}
+
+// Gauge is a value that can go up and down.
+type Gauge struct{ prometheus.Gauge }
+
+// Value returns the current value.
+func (g *Gauge) Value() float64 { return read(g.Gauge).GetGauge().GetValue() }
+
+// GaugeVec is a gauge partitioned by labels.
+type GaugeVec struct{ vec *prometheus.GaugeVec }
+
+// NewGaugeVec registers a gauge family on r.
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	v := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: name, Help: help}, labels)
+	r.register(v)
+	return &GaugeVec{vec: v}
+}
+
+// NewGaugeVec registers a gauge family on the Default registry.
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	return Default.NewGaugeVec(name, help, labels...)
+}
+
+// With returns the gauge for the given label values.
+func (v *GaugeVec) With(values ...string) *Gauge {
+	return &Gauge{v.vec.WithLabelValues(values...)}
+}
+
+// Delete drops the gauge for the given label values, so it is no longer
+// exported. Families labelled by unbounded values, such as principals,
+// delete the gauges that fall idle.
+func (v *GaugeVec) Delete(values ...string) { v.vec.DeleteLabelValues(values...) }
+
+// Histogram counts observations into cumulative buckets.
+type Histogram struct{ prometheus.Histogram }
+
+// Count returns the number of observations.
+func (h *Histogram) Count() uint64 { return read(h.Histogram).GetHistogram().GetSampleCount() }
+
+// Sum returns the total of all observations.
+func (h *Histogram) Sum() float64 { return read(h.Histogram).GetHistogram().GetSampleSum() }
+
+// HistogramVec is a histogram partitioned by labels.
+type HistogramVec struct{ vec *prometheus.HistogramVec }
+
+// NewHistogramVec registers a histogram family on r. A nil buckets slice
+// selects DefaultBuckets.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	v := prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: name, Help: help, Buckets: buckets}, labels)
+	r.register(v)
+	return &HistogramVec{vec: v}
+}
+
+// NewHistogramVec registers a histogram family on the Default registry.
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	return Default.NewHistogramVec(name, help, buckets, labels...)
+}
+
+// With returns the histogram for the given label values.
+func (v *HistogramVec) With(values ...string) *Histogram {
+	return &Histogram{v.vec.WithLabelValues(values...).(prometheus.Histogram)}
+}
@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/routeconf"
+)
+
+// DefaultMaxRequestBytes is the request body limit when nothing else is
+// configured. Used by the config package.
+const DefaultMaxRequestBytes = 1 << 20
+
+type bodyLimitKey struct{}
+
+// bodyLimit is the limit MaxBytes applies, adjustable by BodyLimit until
+// the body is first read.
+type bodyLimit struct {
+	mu      sync.Mutex
+	n       int64
+	started bool
+}
+
+// MaxBytes caps every request body at limit bytes, as http.MaxBytesReader
+// does: reading past it fails with *http.MaxBytesError. Routes that need a
+// different cap, like uploads, set it with BodyLimit. The cap is fixed
+// when the body is first read, so a handler cannot raise its own.
+func MaxBytes(limit int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+			bl := &bodyLimit{n: limit}
+			r = r.WithContext(context.WithValue(r.Context(), bodyLimitKey{}, bl))
+			r.Body = &lazyLimitedBody{w: w, body: r.Body, limit: bl}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// BodyLimit overrides the limit set by MaxBytes for one route, raising or
+// lowering it. Requests whose Content-Length already exceeds limit get a
+// 413 without reaching next. Without MaxBytes in front it limits the body
+// itself.
+func BodyLimit(limit int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			serveWithBodyLimit(w, r, next, limit)
+		})
+	}
+}
+
+// RouteBodyLimit is BodyLimit with the limit of the matched route's
+// override in routes, or limit for routes without one. It must run inside
+// the router.
+func RouteBodyLimit(routes routeconf.Table, limit int64) func(http.Handler) http.Handler {
+	if len(routes) == 0 {
+		return BodyLimit(limit)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := limit
+			if o, ok := routes.Lookup(r); ok && o.MaxBodyBytes > 0 {
+				n = o.MaxBodyBytes
+			}
+			serveWithBodyLimit(w, r, next, n)
+		})
+	}
+}
+
+func serveWithBodyLimit(w http.ResponseWriter, r *http.Request, next http.Handler, limit int64) {
+	if r.ContentLength > limit {
+		apierror.Write(w, r, apierror.BodyTooLarge(limit))
+		return
+	}
+	overridden := false
+	if bl, _ := r.Context().Value(bodyLimitKey{}).(*bodyLimit); bl != nil {
+		bl.mu.Lock()
+		if !bl.started {
+			bl.n, overridden = limit, true
+		}
+		bl.mu.Unlock()
+	}
+	if !overridden && r.Body != nil && r.Body != http.NoBody {
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+	}
+	next.ServeHTTP(w, r)
+}
+
+// lazyLimitedBody wraps the body in http.MaxBytesReader on first read,
+// with whatever limit is in force by then.
+type lazyLimitedBody struct {
+	w     http.ResponseWriter
+	body  io.ReadCloser
+	limit *bodyLimit
+	r     io.ReadCloser
+}
+
+func (b *lazyLimitedBody) Read(p []byte) (int, error) {
+	if b.r == nil {
+		b.limit.mu.Lock()
+		b.limit.started = true
+		n := b.limit.n
+		b.limit.mu.Unlock()
+		b.r = http.MaxBytesReader(b.w, b.body, n)
+	}
+	return b.r.Read(p)
+}
+
+func (b *lazyLimitedBody) Close() error { return b.body.Close() }
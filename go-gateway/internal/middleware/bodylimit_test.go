@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+)
+
+// readAll answers 413 when the body is over its limit and 200 otherwise.
+var readAll = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	if _, err := io.ReadAll(r.Body); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			apierror.Write(w, r, apierror.BodyTooLarge(tooLarge.Limit))
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+})
+
+// bodyRequest sends n bytes without a Content-Length, so only reading
+// the body can find it too large.
+func bodyRequest(n int) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(strings.Repeat("a", n)))
+	req.ContentLength = -1
+	return req
+}
+
+func TestMaxBytes(t *testing.T) {
+	h := MaxBytes(10)(readAll)
+	for n, want := range map[int]int{10: http.StatusOK, 11: http.StatusRequestEntityTooLarge} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, bodyRequest(n))
+		if rec.Code != want {
+			t.Errorf("%d bytes: status = %d, want %d", n, rec.Code, want)
+		}
+	}
+}
+
+func TestBodyLimitOverridesMaxBytes(t *testing.T) {
+	tests := []struct {
+		name          string
+		global, route int64
+		n             int
+		want          int
+	}{
+		{"raised", 10, 100, 50, http.StatusOK},
+		{"lowered", 100, 10, 50, http.StatusRequestEntityTooLarge},
+		{"without global", 0, 10, 50, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := BodyLimit(tt.route)(readAll)
+			if tt.global > 0 {
+				h = MaxBytes(tt.global)(h)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, bodyRequest(tt.n))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestBodyLimitRejectsContentLength(t *testing.T) {
+	called := false
+	h := MaxBytes(100)(BodyLimit(10)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(strings.Repeat("a", 50))))
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d, want 413", rec.Code)
+	}
+	if called {
+		t.Error("handler ran for an oversized Content-Length")
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	var body apierror.Envelope
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Error.Code != "body_too_large" || !strings.Contains(body.Error.Message, "10 bytes") {
+		t.Errorf("body = %+v", body)
+	}
+}
+
+func TestBodyLimitAfterReadKeepsLimit(t *testing.T) {
+	// A route limit set once the body is being read must not lift the
+	// limit already applied.
+	h := MaxBytes(10)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var b [1]byte
+		r.Body.Read(b[:])
+		BodyLimit(100)(readAll).ServeHTTP(w, r)
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, bodyRequest(50))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want 413", rec.Code)
+	}
+}
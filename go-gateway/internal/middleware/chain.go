@@ -0,0 +1,70 @@
+package middleware
+
+import "net/http"
+
+// Chain is an ordered list of middlewares. The first one listed is the
+// outermost: it sees the request first and the response last. A Chain is
+// immutable, so a shared base chain can be extended per route without
+// affecting other routes.
+type Chain struct {
+	mws []func(http.Handler) http.Handler
+}
+
+// NewChain returns a chain of mws, outermost first.
+func NewChain(mws ...func(http.Handler) http.Handler) Chain {
+	return Chain{mws: append([]func(http.Handler) http.Handler(nil), mws...)}
+}
+
+// Append returns a new chain running mws after (inside) those of c.
+func (c Chain) Append(mws ...func(http.Handler) http.Handler) Chain {
+	out := make([]func(http.Handler) http.Handler, 0, len(c.mws)+len(mws))
+	out = append(out, c.mws...)
+	return Chain{mws: append(out, mws...)}
+}
+
+// Extend returns a new chain running the middlewares of next after those
+// of c.
+func (c Chain) Extend(next Chain) Chain {
+	return c.Append(next.mws...)
+}
+
+// Then wraps h in the chain.
+func (c Chain) Then(h http.Handler) http.Handler {
+	for i := len(c.mws) - 1; i >= 0; i-- {
+		h = c.mws[i](h)
+	}
+	return h
+}
+
+// ThenFunc is Then for a handler function.
+func (c Chain) ThenFunc(fn http.HandlerFunc) http.Handler {
+	return c.Then(fn)
+}
+
+// Unless applies mw only to requests for which skip returns false, so a
+// middleware in a global chain can be switched off for some routes; for
+// example probes can bypass authentication:
+//
+//	NewChain(RequestID, Unless(Paths("/health"), auth))
+func Unless(skip func(*http.Request) bool, mw func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		wrapped := mw(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if skip(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			wrapped.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Paths returns a predicate for Unless matching requests for any of the
+// given exact paths.
+func Paths(paths ...string) func(*http.Request) bool {
+	set := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		set[p] = true
+	}
+	return func(r *http.Request) bool { return set[r.URL.Path] }
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+// recorder returns a middleware that logs its entry and exit to trace.
+func recorder(name string, trace *[]string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*trace = append(*trace, name+">")
+			next.ServeHTTP(w, r)
+			*trace = append(*trace, "<"+name)
+		})
+	}
+}
+
+func traceHandler(trace *[]string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*trace = append(*trace, "handler")
+	})
+}
+
+func run(h http.Handler, path string) {
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+}
+
+func TestChainOrder(t *testing.T) {
+	var trace []string
+	c := NewChain(recorder("a", &trace), recorder("b", &trace)).Append(recorder("c", &trace))
+	run(c.Then(traceHandler(&trace)), "/")
+
+	want := []string{"a>", "b>", "c>", "handler", "<c", "<b", "<a"}
+	if !slices.Equal(trace, want) {
+		t.Errorf("trace = %v, want %v", trace, want)
+	}
+}
+
+func TestChainIsImmutable(t *testing.T) {
+	var trace []string
+	base := NewChain(recorder("base", &trace))
+	withAuth := base.Append(recorder("auth", &trace))
+	withLimit := base.Append(recorder("limit", &trace))
+
+	run(withAuth.Then(traceHandler(&trace)), "/")
+	run(withLimit.Then(traceHandler(&trace)), "/")
+	run(base.Extend(NewChain(recorder("x", &trace), recorder("y", &trace))).Then(traceHandler(&trace)), "/")
+
+	want := []string{
+		"base>", "auth>", "handler", "<auth", "<base",
+		"base>", "limit>", "handler", "<limit", "<base",
+		"base>", "x>", "y>", "handler", "<y", "<x", "<base",
+	}
+	if !slices.Equal(trace, want) {
+		t.Errorf("trace = %v, want %v", trace, want)
+	}
+}
+
+func TestUnless(t *testing.T) {
+	var trace []string
+	h := NewChain(
+		recorder("log", &trace),
+		Unless(Paths("/health", "/livez"), recorder("auth", &trace)),
+	).Then(traceHandler(&trace))
+
+	run(h, "/health")
+	if want := []string{"log>", "handler", "<log"}; !slices.Equal(trace, want) {
+		t.Errorf("skipped: trace = %v, want %v", trace, want)
+	}
+	trace = nil
+	run(h, "/api/v1/content")
+	if want := []string{"log>", "auth>", "handler", "<auth", "<log"}; !slices.Equal(trace, want) {
+		t.Errorf("applied: trace = %v, want %v", trace, want)
+	}
+}
@@ -0,0 +1,235 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/content-factory/go-gateway/internal/respond"
+)
+
+// DefaultCompressMinSize is the smallest body worth compressing; below it
+// the gzip header and CPU cost outweigh the savings.
+const DefaultCompressMinSize = 1024
+
+var gzipPool = sync.Pool{New: func() any { return gzip.NewWriter(nil) }}
+
+// Compress gzips response bodies of at least minSize bytes for clients
+// that accept it. Bodies that are already compressed (images, audio,
+// video, archives, opaque binaries), event streams and responses that set their own
+// Content-Encoding are passed through untouched. It belongs inside
+// Metrics and Logger so they count the bytes actually sent.
+//
+// Brotli is not offered: the standard library has no encoder.
+func Compress(minSize int) func(http.Handler) http.Handler {
+	if minSize <= 0 {
+		minSize = DefaultCompressMinSize
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cw := &compressWriter{
+				ResponseWriter: w,
+				accept:         r.Method != http.MethodHead && acceptsGzip(r.Header.Get("Accept-Encoding")),
+				minSize:        minSize,
+			}
+			defer cw.close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// acceptsGzip reports whether an Accept-Encoding value allows gzip, either
+// by name or through "*", honouring q=0 refusals.
+func acceptsGzip(header string) bool {
+	gzipQ, starQ := -1.0, -1.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "gzip", "x-gzip":
+			gzipQ = q
+		case "*":
+			starQ = q
+		}
+	}
+	if gzipQ >= 0 {
+		return gzipQ > 0
+	}
+	return starQ > 0
+}
+
+// incompressible reports whether a Content-Type is already compressed or
+// must not be buffered.
+func incompressible(contentType string) bool {
+	mt, _, _ := strings.Cut(contentType, ";")
+	mt = strings.ToLower(strings.TrimSpace(mt))
+	switch {
+	case strings.HasPrefix(mt, "image/") && mt != "image/svg+xml",
+		strings.HasPrefix(mt, "video/"),
+		strings.HasPrefix(mt, "audio/"),
+		strings.HasPrefix(mt, "font/woff"):
+		return true
+	}
+	switch mt {
+	case "application/zip", "application/gzip", "application/x-gzip",
+		"application/x-bzip2", "application/x-xz", "application/zstd",
+		"application/x-7z-compressed", "application/pdf",
+		"application/octet-stream",
+		"text/event-stream", "application/x-ndjson":
+		return true
+	}
+	return false
+}
+
+// compressWriter holds back the first minSize bytes of the body to decide
+// whether compressing is worthwhile.
+type compressWriter struct {
+	http.ResponseWriter
+	accept  bool
+	minSize int
+
+	status      int
+	wroteHeader bool
+	decided     bool
+	varied      bool
+	buf         []byte
+	gz          *gzip.Writer
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	if w.wroteHeader || w.decided {
+		return
+	}
+	if status < 200 {
+		// Informational responses go straight through.
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.status = status
+	w.wroteHeader = true
+	if status == http.StatusNoContent || status == http.StatusNotModified || !w.eligible() {
+		w.passThrough()
+	}
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.decided {
+		if w.gz != nil {
+			return w.gz.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", http.DetectContentType(b))
+		if !w.eligible() {
+			w.passThrough()
+			return w.ResponseWriter.Write(b)
+		}
+	}
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= w.minSize {
+		if err := w.startGzip(); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// eligible reports whether the response may be compressed at all, and
+// records in Vary that its encoding depends on Accept-Encoding.
+func (w *compressWriter) eligible() bool {
+	h := w.Header()
+	if h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "" {
+		return false
+	}
+	if ct := h.Get("Content-Type"); ct != "" && incompressible(ct) {
+		return false
+	}
+	if !w.varied {
+		respond.Vary(w, "Accept-Encoding")
+		w.varied = true
+	}
+	return w.accept
+}
+
+func (w *compressWriter) passThrough() {
+	w.decided = true
+	w.ResponseWriter.WriteHeader(w.status)
+	if len(w.buf) > 0 {
+		w.ResponseWriter.Write(w.buf)
+		w.buf = nil
+	}
+}
+
+func (w *compressWriter) startGzip() error {
+	w.decided = true
+	h := w.Header()
+	h.Set("Content-Encoding", "gzip")
+	h.Del("Content-Length")
+	w.ResponseWriter.WriteHeader(w.status)
+	w.gz = gzipPool.Get().(*gzip.Writer)
+	w.gz.Reset(w.ResponseWriter)
+	_, err := w.gz.Write(w.buf)
+	w.buf = nil
+	return err
+}
+
+// Flush commits to compressing whatever has been buffered so far: a
+// handler that flushes is streaming and will not wait for minSize.
+func (w *compressWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.decided {
+		if len(w.buf) > 0 && w.accept {
+			w.startGzip()
+		} else {
+			w.passThrough()
+		}
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("middleware: underlying ResponseWriter does not support hijacking")
+	}
+	w.decided = true
+	return h.Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *compressWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// close finishes the response: a body that stayed under minSize is sent
+// as-is, a compressed one gets its gzip trailer.
+func (w *compressWriter) close() {
+	if !w.decided && w.wroteHeader {
+		w.passThrough()
+	}
+	if w.gz != nil {
+		w.gz.Close()
+		w.gz.Reset(nil)
+		gzipPool.Put(w.gz)
+		w.gz = nil
+	}
+}
@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/content-factory/go-gateway/internal/router"
+)
+
+func serveCompressed(t *testing.T, acceptEncoding, contentType, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	h := Compress(64)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if contentType != "" {
+			w.Header().Set("Content-Type", contentType)
+		}
+		io.WriteString(w, body)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCompressLargeJSON(t *testing.T) {
+	body := `{"items":"` + strings.Repeat("x", 500) + `"}`
+	rec := serveCompressed(t, "br;q=1, gzip;q=0.8", "application/json", body)
+
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", rec.Header().Get("Content-Encoding"))
+	}
+	if rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Errorf("Vary = %q", rec.Header().Values("Vary"))
+	}
+	if rec.Body.Len() >= len(body) {
+		t.Errorf("compressed size %d not smaller than %d", rec.Body.Len(), len(body))
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(zr)
+	if string(got) != body {
+		t.Error("decompressed body differs")
+	}
+}
+
+func TestCompressSkips(t *testing.T) {
+	large := strings.Repeat("a", 500)
+	tests := []struct {
+		name, accept, contentType, body string
+		wantVary                        bool
+	}{
+		{"small body", "gzip", "application/json", `{"ok":true}`, true},
+		{"not accepted", "identity", "application/json", large, true},
+		{"refused", "gzip;q=0, *", "application/json", large, true},
+		{"image", "gzip", "image/png", large, false},
+		{"event stream", "gzip", "text/event-stream", large, false},
+		{"ndjson stream", "gzip", "application/x-ndjson", large, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveCompressed(t, tt.accept, tt.contentType, tt.body)
+			if enc := rec.Header().Get("Content-Encoding"); enc != "" {
+				t.Errorf("Content-Encoding = %q, want none", enc)
+			}
+			if rec.Body.String() != tt.body {
+				t.Error("body altered")
+			}
+			if got := rec.Header().Get("Vary") != ""; got != tt.wantVary {
+				t.Errorf("Vary set = %v, want %v", got, tt.wantVary)
+			}
+		})
+	}
+}
+
+func TestCompressedBytesAreMetered(t *testing.T) {
+	rt := router.New()
+	rt.HandleFunc(http.MethodGet, "/big", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, strings.Repeat("y", 10_000))
+	})
+	req := httptest.NewRequest(http.MethodGet, "/big", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	Metrics(Compress(0)(rt)).ServeHTTP(rec, req)
+
+	sizes := responseSize.With("GET", "/big")
+	if sizes.Count() != 1 || sizes.Sum() != float64(rec.Body.Len()) {
+		t.Errorf("recorded %v bytes over %d responses, want %d compressed bytes", sizes.Sum(), sizes.Count(), rec.Body.Len())
+	}
+}
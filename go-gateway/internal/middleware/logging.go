@@ -0,0 +1,164 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/clientip"
+	"github.com/content-factory/go-gateway/internal/gateway"
+)
+
+// Access log formats, see AccessLog.
+const (
+	AccessLogJSON     = "json"
+	AccessLogCombined = "combined"
+)
+
+// Logger writes one structured line per request. Install it inside
+// RequestID and the clientip middleware so the line carries the request
+// ID and the client's address. Handlers add to the line with
+// AddLogAttrs.
+//
+// The line's outcome is success, client_error, server_error or canceled.
+// Requests the client abandoned are canceled, with status 499, and logged
+// at debug level: browsers navigating away are routine.
+func Logger(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			e := serveLogged(next, w, r)
+			attrs := []slog.Attr{
+				slog.String("request_id", gateway.RequestIDFromContext(r.Context())),
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("client_ip", clientip.Of(r)),
+				slog.Int("status", e.status),
+				slog.String("outcome", e.outcome),
+				slog.Duration("duration", e.duration),
+				slog.Int64("bytes", e.bytes),
+			}
+			logger.LogAttrs(r.Context(), e.level, "request", append(attrs, e.attrs...)...)
+		})
+	}
+}
+
+// CombinedLogger writes one line per request to w in the Apache Combined
+// Log Format, for log processors that expect it:
+//
+//	203.0.113.7 - - [14/Oct/2026:13:55:36 +0000] "GET /api/v1/jobs HTTP/1.1" 200 2326 "-" "curl/8.5.0"
+//
+// Lines are levelled as Logger's are, and those below level are not
+// written. Like Logger, it belongs inside the clientip middleware, which
+// supplies the client's address. The user field is always "-": the line
+// is written outside authentication.
+func CombinedLogger(w io.Writer, level slog.Leveler) func(http.Handler) http.Handler {
+	var mu sync.Mutex
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			e := serveLogged(next, rw, r)
+			if e.level < level.Level() {
+				return
+			}
+			target := r.RequestURI
+			if target == "" {
+				target = r.URL.RequestURI()
+			}
+			size := "-"
+			if e.bytes > 0 {
+				size = strconv.FormatInt(e.bytes, 10)
+			}
+			line := fmt.Sprintf("%s - - [%s] \"%s\" %d %s \"%s\" \"%s\"\n",
+				orDash(clientip.Of(r)),
+				e.start.Format("02/Jan/2006:15:04:05 -0700"),
+				escapeLog(r.Method+" "+target+" "+r.Proto),
+				e.status, size,
+				escapeLog(orDash(r.Referer())),
+				escapeLog(orDash(r.UserAgent())))
+			mu.Lock()
+			defer mu.Unlock()
+			io.WriteString(w, line)
+		})
+	}
+}
+
+// logEntry is what the access loggers record of a request.
+type logEntry struct {
+	start    time.Time
+	duration time.Duration
+	status   int
+	outcome  string
+	level    slog.Level
+	bytes    int64
+	// attrs are those handlers added with AddLogAttrs.
+	attrs []slog.Attr
+}
+
+// serveLogged serves r with next and returns the entry to log for it.
+func serveLogged(next http.Handler, w http.ResponseWriter, r *http.Request) logEntry {
+	start := time.Now()
+	rw := wrapResponseWriter(w)
+	extra := &logAttrs{}
+	next.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), logAttrsKey{}, extra)))
+
+	e := logEntry{start: start, duration: time.Since(start), bytes: rw.bytes, level: slog.LevelInfo}
+	e.status, e.outcome = outcome(r, rw)
+	if e.outcome == "canceled" {
+		e.level = slog.LevelDebug
+	}
+	extra.mu.Lock()
+	e.attrs = extra.attrs
+	extra.mu.Unlock()
+	return e
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// escapeLog escapes quotes, backslashes and unprintable bytes as Apache
+// does, so a client cannot break a line or forge a field.
+func escapeLog(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c >= 0x7f:
+			fmt.Fprintf(&b, "\\x%02x", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+type logAttrsKey struct{}
+
+// logAttrs collects what handlers add to their request's log line. The
+// request timeout runs handlers on their own goroutine, hence the lock.
+type logAttrs struct {
+	mu    sync.Mutex
+	attrs []slog.Attr
+}
+
+// AddLogAttrs adds attrs to the log line Logger writes for the request
+// ctx belongs to. Outside Logger it does nothing.
+func AddLogAttrs(ctx context.Context, attrs ...slog.Attr) {
+	extra, ok := ctx.Value(logAttrsKey{}).(*logAttrs)
+	if !ok {
+		return
+	}
+	extra.mu.Lock()
+	extra.attrs = append(extra.attrs, attrs...)
+	extra.mu.Unlock()
+}
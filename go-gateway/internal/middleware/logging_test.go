@@ -0,0 +1,212 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/content-factory/go-gateway/internal/clientip"
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/router"
+)
+
+func TestRequestIDPropagatedAndLogged(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	h := RequestID(Logger(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		AddLogAttrs(r.Context(), slog.Bool("dry_run", true))
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	})))
+
+	req := httptest.NewRequest(http.MethodPost, "/things", nil)
+	req.Header.Set(RequestIDHeader, "abc-123")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get(RequestIDHeader); got != "abc-123" {
+		t.Errorf("echoed request ID = %q", got)
+	}
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("log line %q: %v", buf.String(), err)
+	}
+	for key, want := range map[string]any{
+		"request_id": "abc-123", "method": "POST", "path": "/things",
+		"status": float64(201), "bytes": float64(5), "dry_run": true,
+	} {
+		if line[key] != want {
+			t.Errorf("log %s = %v, want %v", key, line[key], want)
+		}
+	}
+	if _, ok := line["duration"]; !ok {
+		t.Error("log line missing duration")
+	}
+}
+
+func TestRequestIDGeneratedWhenMissingOrUnsafe(t *testing.T) {
+	h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, incoming := range []string{"", "bad id\nwith newline"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(RequestIDHeader, incoming)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if got := rec.Header().Get(RequestIDHeader); len(got) != 32 {
+			t.Errorf("incoming %q: generated ID %q, want 32 hex chars", incoming, got)
+		}
+	}
+}
+
+func TestClientCancellationLoggedAtDebug(t *testing.T) {
+	tests := []struct {
+		name    string
+		write   int // status the handler writes; 0 for none
+		cancel  bool
+		status  float64
+		outcome string
+		level   string
+	}{
+		{"success", http.StatusOK, false, 200, "success", "INFO"},
+		{"client error", http.StatusNotFound, false, 404, "client_error", "INFO"},
+		{"server error", http.StatusBadGateway, false, 502, "server_error", "INFO"},
+		{"gone before answering", 0, true, 499, "canceled", "DEBUG"},
+		{"gone during a backend failure", http.StatusServiceUnavailable, true, 499, "canceled", "DEBUG"},
+		{"gone after answering", http.StatusNotFound, true, 404, "client_error", "INFO"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			h := Logger(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.cancel {
+					cancel()
+				}
+				if tt.write != 0 {
+					w.WriteHeader(tt.write)
+				}
+			}))
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequestWithContext(ctx, http.MethodGet, "/things", nil))
+
+			var line map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+				t.Fatalf("log line %q: %v", buf.String(), err)
+			}
+			if line["status"] != tt.status || line["outcome"] != tt.outcome || line["level"] != tt.level {
+				t.Errorf("logged status %v outcome %v level %v, want %v %s %s",
+					line["status"], line["outcome"], line["level"], tt.status, tt.outcome, tt.level)
+			}
+		})
+	}
+}
+
+func TestCombinedLogger(t *testing.T) {
+	var buf bytes.Buffer
+	resolver, err := clientip.New([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := resolver.Middleware(CombinedLogger(&buf, slog.LevelInfo)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	})))
+
+	req := httptest.NewRequest(http.MethodPost, "/things?page=2", nil)
+	req.RemoteAddr = "10.1.2.3:4567"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	req.Header.Set("Referer", "https://app.example.com/")
+	req.Header.Set("User-Agent", `curl/8.5.0 "quoted"`)
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	want := regexp.MustCompile(`^203\.0\.113\.7 - - \[\d{2}/[A-Z][a-z]{2}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] ` +
+		`"POST /things\?page=2 HTTP/1\.1" 201 5 "https://app\.example\.com/" "curl/8\.5\.0 \\"quoted\\""\n$`)
+	if !want.MatchString(buf.String()) {
+		t.Errorf("line = %q", buf.String())
+	}
+}
+
+func TestCombinedLoggerLevel(t *testing.T) {
+	tests := []struct {
+		name   string
+		level  slog.Level
+		cancel bool
+		want   string // the line's status and size, or "" for no line
+	}{
+		{"info", slog.LevelInfo, false, `" 200 - "`},
+		{"canceled at info", slog.LevelInfo, true, ""},
+		{"canceled at debug", slog.LevelDebug, true, `" 499 - "`},
+		{"warn", slog.LevelWarn, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			h := CombinedLogger(&buf, tt.level)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.cancel {
+					cancel()
+				}
+			}))
+			req := httptest.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+			req.Header.Set("User-Agent", "probe\n\x7f")
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			switch {
+			case tt.want == "" && buf.Len() > 0:
+				t.Errorf("logged %q", buf.String())
+			case tt.want != "" && (!strings.Contains(buf.String(), tt.want) || !strings.HasSuffix(buf.String(), `"probe\x0a\x7f"`+"\n")):
+				t.Errorf("line = %q, want %s", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	base := slog.New(slog.NewJSONHandler(&buf, nil))
+	done := make(chan struct{})
+	rt := router.New()
+	rt.HandleFunc(http.MethodGet, "/things/{id}", func(w http.ResponseWriter, r *http.Request) {
+		ctx := gateway.WithLogAttrs(r.Context(), "principal", "user-1")
+		gateway.Logger(ctx).Info("in handler")
+		// Work outliving the request keeps its logger.
+		go func(ctx context.Context) {
+			defer close(done)
+			gateway.Logger(ctx).Info("in goroutine")
+		}(context.WithoutCancel(ctx))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/things/t1", nil)
+	req.Header.Set(RequestIDHeader, "abc-123")
+	req = req.WithContext(gateway.WithLogger(req.Context(), base))
+	RequestID(rt).ServeHTTP(httptest.NewRecorder(), req)
+	<-done
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("logged %q, want two lines", buf.String())
+	}
+	for _, raw := range lines {
+		var line map[string]any
+		if err := json.Unmarshal(raw, &line); err != nil {
+			t.Fatalf("log line %q: %v", raw, err)
+		}
+		for key, want := range map[string]any{
+			"request_id": "abc-123", "method": "GET", "route": "/things/{id}", "principal": "user-1",
+		} {
+			if line[key] != want {
+				t.Errorf("%s: %s = %v, want %v", line["msg"], key, line[key], want)
+			}
+		}
+	}
+
+	if gateway.Logger(context.Background()) != slog.Default() {
+		t.Error("Logger outside a request is not slog.Default()")
+	}
+}
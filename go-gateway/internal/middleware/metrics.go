@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/buildinfo"
+	"github.com/content-factory/go-gateway/internal/metrics"
+	"github.com/content-factory/go-gateway/internal/router"
+	"github.com/content-factory/go-gateway/internal/tenant"
+)
+
+// MetricsPath is where the Prometheus scrape endpoint is mounted. Scrapes
+// are counted but kept out of the latency histogram.
+const MetricsPath = "/metrics"
+
+// unmatchedRoute labels requests that did not match any route, so probes
+// for random paths cannot inflate label cardinality.
+const unmatchedRoute = "unmatched"
+
+var (
+	requestsTotal = metrics.NewCounterVec("gateway_http_requests_total",
+		"HTTP requests handled, by method, route template and status code.",
+		"method", "route", "code")
+	requestDuration = metrics.NewHistogramVec("gateway_http_request_duration_seconds",
+		"HTTP request latency, by method, route template and status code.",
+		nil, "method", "route", "code")
+	responseSize = metrics.NewHistogramVec("gateway_http_response_size_bytes",
+		"HTTP response body size as sent on the wire (after compression), by method and route template.",
+		[]float64{100, 1_000, 10_000, 100_000, 1_000_000, 10_000_000}, "method", "route")
+	tenantRequests = metrics.NewCounterVec("gateway_tenant_requests_total",
+		"HTTP requests of tenants, by tenant, route template and status code. Tenants are labelled by configured ID, as other if left out of the metrics, or as unknown if not configured.",
+		"tenant", "route", "code")
+	requestsInFlight = metrics.NewGaugeVec("gateway_http_requests_in_flight",
+		"HTTP requests currently being served.")
+	buildInfo = metrics.NewGaugeVec("gateway_build_info",
+		"Build metadata of the running gateway; the value is always 1.",
+		"version", "commit")
+)
+
+func init() {
+	buildInfo.With(buildinfo.Version, buildinfo.Commit).Set(1)
+}
+
+// Metrics records request counts, latency and in-flight requests. Routes
+// are labelled by the template the router matched, e.g. "/content/{id}".
+// Requests the client abandoned are counted with code 499, never as the
+// 5xx their handler may have written, see outcome. Requests that reached
+// the tenant middleware are counted by tenant as well.
+func Metrics(next http.Handler) http.Handler {
+	inFlight := requestsInFlight.With()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		rw := wrapResponseWriter(w)
+		r, pattern := router.CapturePattern(r)
+		r, tenantLabel := tenant.Capture(r)
+		next.ServeHTTP(rw, r)
+
+		route := pattern()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		status, _ := outcome(r, rw)
+		code := strconv.Itoa(status)
+		requestsTotal.With(r.Method, route, code).Inc()
+		if label := tenantLabel(); label != "" {
+			tenantRequests.With(label, route, code).Inc()
+		}
+		responseSize.With(r.Method, route).Observe(float64(rw.bytes))
+		if route != MetricsPath {
+			requestDuration.With(r.Method, route, code).Observe(time.Since(start).Seconds())
+		}
+	})
+}
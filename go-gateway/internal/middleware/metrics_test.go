@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/content-factory/go-gateway/internal/router"
+	"github.com/content-factory/go-gateway/internal/tenant"
+)
+
+func TestMetricsLabelsByRouteTemplate(t *testing.T) {
+	rt := router.New()
+	rt.HandleFunc(http.MethodGet, "/content/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	h := Metrics(rt)
+
+	for _, path := range []string{"/content/a", "/content/b", "/nope"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	if got := requestsTotal.With("GET", "/content/{id}", "418").Value(); got != 2 {
+		t.Errorf("templated route count = %v, want 2", got)
+	}
+	if got := requestsTotal.With("GET", unmatchedRoute, "404").Value(); got != 1 {
+		t.Errorf("unmatched count = %v, want 1", got)
+	}
+	if got := requestDuration.With("GET", "/content/{id}", "418").Count(); got != 2 {
+		t.Errorf("histogram count = %v, want 2", got)
+	}
+}
+
+func TestMetricsLabelsByTenant(t *testing.T) {
+	tenants := tenant.New([]tenant.Tenant{{ID: "acme", Enabled: true, Metrics: true}}, "")
+	rt := router.New()
+	rt.Handle(http.MethodGet, "/tenanted/{id}", tenants.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	rt.HandleFunc(http.MethodGet, "/open", func(w http.ResponseWriter, r *http.Request) {})
+	h := Metrics(rt)
+
+	for _, id := range []string{"acme", "made-up-1", "made-up-2"} {
+		req := httptest.NewRequest(http.MethodGet, "/tenanted/x", nil)
+		req.Header.Set(tenant.Header, id)
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/open", nil))
+
+	if got := tenantRequests.With("acme", "/tenanted/{id}", "200").Value(); got != 1 {
+		t.Errorf("acme count = %v, want 1", got)
+	}
+	if got := tenantRequests.With(tenant.LabelUnknown, "/tenanted/{id}", "403").Value(); got != 2 {
+		t.Errorf("unknown tenant count = %v, want 2", got)
+	}
+	if got := tenantRequests.With("", "/open", "200").Value(); got != 0 {
+		t.Errorf("untenanted route counted for a tenant %v times", got)
+	}
+}
+
+func TestMetricsEndpointExcludedFromLatency(t *testing.T) {
+	rt := router.New()
+	rt.HandleFunc(http.MethodGet, MetricsPath, func(w http.ResponseWriter, r *http.Request) {})
+	Metrics(rt).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, MetricsPath, nil))
+
+	if got := requestsTotal.With("GET", MetricsPath, "200").Value(); got != 1 {
+		t.Errorf("scrape count = %v, want 1", got)
+	}
+	if got := requestDuration.With("GET", MetricsPath, "200").Count(); got != 0 {
+		t.Errorf("scrape latency observed %d times, want 0", got)
+	}
+}
+
+func TestMetricsCountCancellationsAs499(t *testing.T) {
+	rt := router.New()
+	rt.HandleFunc(http.MethodGet, "/slow/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow/gone" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusGatewayTimeout)
+	})
+	h := Metrics(rt)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequestWithContext(ctx, http.MethodGet, "/slow/gone", nil))
+	// A timeout of the gateway's own is still a failure.
+	ctx, cancel = context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequestWithContext(ctx, http.MethodGet, "/slow/timeout", nil))
+
+	if got := requestsTotal.With("GET", "/slow/{id}", "499").Value(); got != 1 {
+		t.Errorf("499 count = %v, want 1", got)
+	}
+	if got := requestsTotal.With("GET", "/slow/{id}", "503").Value(); got != 0 {
+		t.Errorf("503 count = %v, want 0", got)
+	}
+	if got := requestsTotal.With("GET", "/slow/{id}", "504").Value(); got != 1 {
+		t.Errorf("504 count = %v, want 1", got)
+	}
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/metrics"
+)
+
+var panicsTotal = metrics.NewCounterVec("gateway_http_panics_total",
+	"Handler panics recovered by the gateway.")
+
+// Recover turns a handler panic into a 500 response instead of a dropped
+// connection, and logs the panic with its stack and request ID. The client
+// only ever sees a generic error body. If the handler had already started
+// its response the status cannot change, so the response is left as it
+// is. Install it inside Logger and Metrics so they record the 500.
+func Recover(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		panics := panicsTotal.With()
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := wrapResponseWriter(w)
+			defer func() {
+				p := recover()
+				if p == nil {
+					return
+				}
+				if err, ok := p.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+					// A deliberate abort; let net/http close the connection.
+					panic(p)
+				}
+				panics.Inc()
+				logger.LogAttrs(r.Context(), slog.LevelError, "handler panicked",
+					slog.String("request_id", gateway.RequestIDFromContext(r.Context())),
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.Any("panic", p),
+					slog.String("stack", string(debug.Stack())),
+					slog.Bool("response_started", rw.wroteHeader),
+				)
+				if !rw.wroteHeader {
+					apierror.Write(rw, r, apierror.Internal("an unexpected error occurred"))
+				}
+			}()
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+)
+
+func TestRecoverReturnsJSON500(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/boom", func(w http.ResponseWriter, r *http.Request) {
+		var m map[string]int
+		m["x"] = 1 // nil map write
+	})
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "fine")
+	})
+	srv := httptest.NewServer(RequestID(Recover(logger)(mux)))
+	defer srv.Close()
+	before := panicsTotal.With().Value()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/boom", nil)
+	req.Header.Set(RequestIDHeader, "req-42")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body apierror.Envelope
+	json.NewDecoder(resp.Body).Decode(&body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError || body.Error.Code != "internal_error" {
+		t.Errorf("status = %d, body %+v", resp.StatusCode, body)
+	}
+	if strings.Contains(body.Error.Message, "nil map") {
+		t.Error("panic value leaked to client")
+	}
+	if !strings.Contains(logs.String(), `"request_id":"req-42"`) || !strings.Contains(logs.String(), "recover_test.go") {
+		t.Errorf("log lacks request ID or stack: %s", logs.String())
+	}
+	if got := panicsTotal.With().Value() - before; got != 1 {
+		t.Errorf("panic counter grew by %v, want 1", got)
+	}
+
+	// The server is still up.
+	resp, err = http.Get(srv.URL + "/ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(b) != "fine" {
+		t.Errorf("after panic: status = %d, body %q", resp.StatusCode, b)
+	}
+}
+
+func TestRecoverAfterResponseStarted(t *testing.T) {
+	h := Recover(slog.New(slog.NewTextHandler(io.Discard, nil)))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		io.WriteString(w, "partial")
+		panic("late")
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusAccepted || rec.Body.String() != "partial" {
+		t.Errorf("status = %d, body %q; want the started response untouched", rec.Code, rec.Body)
+	}
+}
+
+func TestRecoverPassesAbortHandler(t *testing.T) {
+	h := Recover(slog.New(slog.NewTextHandler(io.Discard, nil)))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	defer func() {
+		if p := recover(); p != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want ErrAbortHandler re-panicked", p)
+		}
+	}()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
@@ -0,0 +1,101 @@
+// Package middleware contains the HTTP middleware wrapped around the
+// gateway's router.
+package middleware
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/rpc"
+)
+
+// responseWriter records the status code and body size written through it,
+// and when the response started. It forwards Flush and Hijack so streaming
+// and WebSocket handlers keep working behind it.
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+	// started is when the status line was sent, or would have been by the
+	// first Write.
+	started time.Time
+	// streamed is set once the handler flushed or hijacked the connection.
+	streamed bool
+}
+
+func wrapResponseWriter(w http.ResponseWriter) *responseWriter {
+	if rw, ok := w.(*responseWriter); ok {
+		return rw
+	}
+	return &responseWriter{ResponseWriter: w, status: http.StatusOK}
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.start()
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// start records that the response started, if it had not.
+func (w *responseWriter) start() {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.started = time.Now()
+	}
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.start()
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+func (w *responseWriter) Flush() {
+	w.start()
+	w.streamed = true
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("middleware: underlying ResponseWriter does not support hijacking")
+	}
+	w.start()
+	w.streamed = true
+	w.status = http.StatusSwitchingProtocols
+	return h.Hijack()
+}
+
+// outcome classifies the response rw recorded for r, and returns the
+// status to report for it. A request whose client went away before it was
+// answered, or while a failure was being answered, is reported as 499
+// with outcome "canceled": the 5xx a handler writes for an abandoned
+// backend call, or the 200 of a response never written, says nothing
+// about the gateway's health.
+func outcome(r *http.Request, rw *responseWriter) (status int, outcome string) {
+	status = rw.status
+	if gateway.ClientGone(r.Context()) && (!rw.wroteHeader || status >= http.StatusInternalServerError || status == rpc.StatusClientClosedRequest) {
+		return rpc.StatusClientClosedRequest, "canceled"
+	}
+	switch {
+	case status >= http.StatusInternalServerError:
+		return status, "server_error"
+	case status >= http.StatusBadRequest:
+		return status, "client_error"
+	}
+	return status, "success"
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *responseWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+)
+
+// Defaults of ServerTimeouts, used by the config package.
+const (
+	DefaultReadHeaderTimeout = 10 * time.Second
+	DefaultReadTimeout       = time.Minute
+	DefaultWriteTimeout      = time.Minute
+	DefaultIdleTimeout       = 2 * time.Minute
+)
+
+// ServerTimeouts are the connection timeouts of the gateway's http.Server,
+// which bound the time a client may hold a connection without the handler
+// being involved: sending its headers (ReadHeader), its whole request
+// (Read), keeping an idle keep-alive connection open (Idle), and the time
+// from the end of the request headers to the end of the response (Write).
+// Zero leaves a timeout off.
+//
+// They sit beneath the request timeout of Timeout, which bounds the
+// handler rather than the connection. A request whose timeout is not
+// shorter than Write has its write deadline pushed back to
+// timeoutWriteMargin past its own, so that a route given a long timeout
+// can still answer, or get its 504. Write thus bounds the routes with no
+// request timeout, and clients slow to read a response. Long-lived routes
+// lift Read and Write with LongLived instead.
+type ServerTimeouts struct {
+	ReadHeader time.Duration
+	Read       time.Duration
+	Write      time.Duration
+	Idle       time.Duration
+}
+
+// Apply sets t on srv.
+func (t ServerTimeouts) Apply(srv *http.Server) {
+	srv.ReadHeaderTimeout = t.ReadHeader
+	srv.ReadTimeout = t.Read
+	srv.WriteTimeout = t.Write
+	srv.IdleTimeout = t.Idle
+}
+
+// timeoutWriteMargin is how long a request past its timeout has to write
+// its 504 before the server's write deadline.
+const timeoutWriteMargin = 5 * time.Second
+
+// outlastWriteTimeout pushes back the write deadline of r's connection if
+// the server's WriteTimeout would cut off a request with timeout d.
+func outlastWriteTimeout(w http.ResponseWriter, r *http.Request, d time.Duration) {
+	srv, _ := r.Context().Value(http.ServerContextKey).(*http.Server)
+	if srv == nil || srv.WriteTimeout <= 0 || d+timeoutWriteMargin <= srv.WriteTimeout {
+		return
+	}
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(d + timeoutWriteMargin))
+}
+
+// LongLived lifts the server's read and write timeouts off the
+// connection of each request, for routes whose transfers last as long as
+// they need to: WebSockets, event streams, downloads and uploads. They get
+// no request timeout either, but ServerTimeouts.ReadHeader has already
+// applied; WebSocket handlers set deadlines of their own.
+func LongLived(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc := http.NewResponseController(w)
+		// Writers that cannot set deadlines have none to lift.
+		rc.SetReadDeadline(time.Time{})
+		rc.SetWriteDeadline(time.Time{})
+		next.ServeHTTP(w, r)
+	})
+}
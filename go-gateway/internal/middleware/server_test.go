@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// startServer serves h with timeouts t.
+func startServer(t *testing.T, h http.Handler, timeouts ServerTimeouts) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(h)
+	timeouts.Apply(srv.Config)
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestReadHeaderTimeoutDisconnectsSlowClients(t *testing.T) {
+	var served atomic.Bool
+	srv := startServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served.Store(true)
+	}), ServerTimeouts{ReadHeader: 100 * time.Millisecond})
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	// A slowloris client: the request line and one header, then nothing.
+	if _, err := io.WriteString(conn, "GET / HTTP/1.1\r\nHost: gateway\r\n"); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = io.ReadAll(conn)
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		t.Fatal("connection still open after 5s")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("disconnected after %v, want about 100ms", elapsed)
+	}
+	if served.Load() {
+		t.Error("handler ran for an incomplete request")
+	}
+}
+
+// slowHandler answers "ok" after a pause longer than the write timeout.
+var slowHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	time.Sleep(300 * time.Millisecond)
+	io.WriteString(w, "ok")
+})
+
+// get fetches srv's root over a fresh connection, returning the body or
+// the error that cut it off.
+func get(srv *httptest.Server) (string, error) {
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	io.WriteString(conn, "GET / HTTP/1.1\r\nHost: gateway\r\nConnection: close\r\n\r\n")
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return string(body), err
+}
+
+func TestWriteTimeout(t *testing.T) {
+	timeouts := ServerTimeouts{Write: 100 * time.Millisecond}
+	if body, err := get(startServer(t, slowHandler, timeouts)); err == nil {
+		t.Errorf("slow response got through the write timeout: %q", body)
+	}
+	if body, err := get(startServer(t, LongLived(slowHandler), timeouts)); err != nil || body != "ok" {
+		t.Errorf("long-lived route: %q, %v", body, err)
+	}
+	// A request timeout longer than the write timeout lets the route
+	// finish.
+	if body, err := get(startServer(t, Timeout(time.Second)(slowHandler), timeouts)); err != nil || body != "ok" {
+		t.Errorf("route with a longer request timeout: %q, %v", body, err)
+	}
+}
+
+// TestWriteTimeoutOverH2C checks that the write timeout, which HTTP/2
+// applies per stream, is lifted the same way for HTTP/2 without TLS.
+func TestWriteTimeoutOverH2C(t *testing.T) {
+	protocols := new(http.Protocols)
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: protocols}}
+	get := func(h http.Handler) (string, error) {
+		srv := httptest.NewUnstartedServer(h)
+		ServerTimeouts{Write: 100 * time.Millisecond}.Apply(srv.Config)
+		srv.Config.Protocols = protocols
+		srv.Start()
+		defer srv.Close()
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err == nil && resp.ProtoMajor != 2 {
+			t.Errorf("served over %s", resp.Proto)
+		}
+		return string(body), err
+	}
+
+	if body, err := get(slowHandler); err == nil {
+		t.Errorf("slow response got through the write timeout: %q", body)
+	}
+	if body, err := get(LongLived(slowHandler)); err != nil || body != "ok" {
+		t.Errorf("long-lived route: %q, %v", body, err)
+	}
+	if body, err := get(Timeout(time.Second)(slowHandler)); err != nil || body != "ok" {
+		t.Errorf("route with a longer request timeout: %q, %v", body, err)
+	}
+}
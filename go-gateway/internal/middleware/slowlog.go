@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/metrics"
+	"github.com/content-factory/go-gateway/internal/routeconf"
+	"github.com/content-factory/go-gateway/internal/router"
+)
+
+// DefaultSlowThreshold is the default of the config package's
+// slow_request_threshold.
+const DefaultSlowThreshold = 2 * time.Second
+
+var slowRequests = metrics.NewCounterVec("gateway_http_slow_requests_total",
+	"HTTP requests that took longer than their route's slow-request threshold, by method and route template.",
+	"method", "route")
+
+// SlowRequests warns about each request that takes longer than threshold,
+// or its route's SlowThreshold override, with a "slow request" line of its
+// own besides Logger's. A threshold of zero or less only warns about the
+// routes with an override.
+//
+// Streamed responses, such as downloads, Server-Sent Events and
+// WebSockets, last as long as the client keeps them open, so for them the
+// time to the first byte is what counts. Install it between Logger and
+// Metrics.
+func SlowRequests(logger *slog.Logger, threshold time.Duration, routes routeconf.Table) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rw := wrapResponseWriter(w)
+			r, pattern := router.CapturePattern(r)
+			next.ServeHTTP(rw, r)
+
+			elapsed := time.Since(start)
+			route := pattern()
+			limit := threshold
+			if o, ok := routes.LookupPattern(r.Method, route); ok && o.SlowThreshold != 0 {
+				limit = o.SlowThreshold
+			}
+			measured := elapsed
+			if rw.streamed {
+				measured = rw.started.Sub(start)
+			}
+			if limit <= 0 || measured <= limit {
+				return
+			}
+			if route == "" {
+				route = unmatchedRoute
+			}
+			slowRequests.With(r.Method, route).Inc()
+			status, _ := outcome(r, rw)
+			attrs := []slog.Attr{
+				slog.String("request_id", gateway.RequestIDFromContext(r.Context())),
+				slog.String("method", r.Method),
+				slog.String("route", route),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", status),
+				slog.Duration("duration", elapsed),
+				slog.Duration("threshold", limit),
+			}
+			if rw.streamed {
+				attrs = append(attrs, slog.Duration("first_byte", measured))
+			}
+			logger.LogAttrs(r.Context(), slog.LevelWarn, "slow request", attrs...)
+		})
+	}
+}
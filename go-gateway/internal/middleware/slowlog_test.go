@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/routeconf"
+	"github.com/content-factory/go-gateway/internal/router"
+)
+
+func TestSlowRequests(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	sleep := func(d time.Duration) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(d)
+			w.WriteHeader(http.StatusAccepted)
+		}
+	}
+	rt := router.New()
+	rt.Handle(http.MethodGet, "/status/{id}", sleep(30*time.Millisecond))
+	rt.Handle(http.MethodPost, "/generate", sleep(30*time.Millisecond))
+	rt.Handle(http.MethodPost, "/quiet", sleep(30*time.Millisecond))
+	rt.HandleFunc(http.MethodGet, "/stream", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("first"))
+		http.NewResponseController(w).Flush()
+		time.Sleep(30 * time.Millisecond)
+	})
+	routes := routeconf.Table{
+		"POST /generate": {SlowThreshold: time.Second},
+		"/quiet":         {SlowThreshold: -1},
+	}
+	h := RequestID(SlowRequests(logger, 10*time.Millisecond, routes)(rt))
+
+	before := slowRequests.With(http.MethodGet, "/status/{id}").Value()
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/status/42", nil),
+		httptest.NewRequest(http.MethodPost, "/generate", nil),
+		httptest.NewRequest(http.MethodPost, "/quiet", nil),
+		httptest.NewRequest(http.MethodGet, "/stream", nil),
+	} {
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// Only the status check is over its threshold: generation has a higher
+	// one, the quiet route none, and the stream started at once.
+	var lines []map[string]any
+	sc := bufio.NewScanner(&buf)
+	for sc.Scan() {
+		var line map[string]any
+		if err := json.Unmarshal(sc.Bytes(), &line); err != nil {
+			t.Fatalf("log line %q: %v", sc.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != 1 {
+		t.Fatalf("logged %d slow requests, want 1: %v", len(lines), lines)
+	}
+	line := lines[0]
+	for key, want := range map[string]any{
+		"level": "WARN", "msg": "slow request", "method": "GET", "route": "/status/{id}",
+		"path": "/status/42", "status": float64(http.StatusAccepted),
+	} {
+		if line[key] != want {
+			t.Errorf("log %s = %v, want %v", key, line[key], want)
+		}
+	}
+	if d, _ := line["duration"].(float64); time.Duration(d) < 30*time.Millisecond {
+		t.Errorf("duration = %v, want at least 30ms", line["duration"])
+	}
+	if id, _ := line["request_id"].(string); id == "" {
+		t.Error("log line missing request_id")
+	}
+	if got := slowRequests.With(http.MethodGet, "/status/{id}").Value() - before; got != 1 {
+		t.Errorf("slow requests counted = %v, want 1", got)
+	}
+}
+
+func TestSlowRequestsStreamCountsFirstByte(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	h := SlowRequests(logger, 10*time.Millisecond, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		http.NewResponseController(w).Flush()
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/events", nil))
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("log line %q: %v", buf.String(), err)
+	}
+	if line["route"] != unmatchedRoute {
+		t.Errorf("route = %v, want %s", line["route"], unmatchedRoute)
+	}
+	if _, ok := line["first_byte"]; !ok {
+		t.Error("streamed request logged without first_byte")
+	}
+}
@@ -0,0 +1,254 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/routeconf"
+)
+
+// DefaultTimeout bounds a request when no other timeout is configured.
+const DefaultTimeout = 30 * time.Second
+
+// DefaultMinClientTimeout is the shortest timeout a client can ask for with
+// TimeoutHeader, used by the config package.
+const DefaultMinClientTimeout = time.Second
+
+// TimeoutHeader lets a client ask for a shorter timeout, as a Go duration
+// such as "5s". The response carries it back with the timeout the request
+// got.
+const TimeoutHeader = "X-Request-Timeout"
+
+// Timeout gives each request a context deadline of d, so backend calls made
+// with the request context are abandoned when it expires. If the handler
+// has not started its response by then, the client gets a 504 at once and
+// anything the handler writes afterwards is discarded; if it has, the
+// response is left to finish.
+//
+// Timeout is applied per route so long-lived streaming routes (WebSocket,
+// SSE) can be left out and slow routes can get a longer d. A d of zero or
+// less disables it.
+func Timeout(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if d <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			serveWithTimeout(w, r, next, d)
+		})
+	}
+}
+
+// TimeoutVar is a request timeout that can be changed while serving, as
+// slog.LevelVar is for a log level. Its zero value is no timeout.
+type TimeoutVar struct {
+	d      atomic.Int64
+	routes atomic.Pointer[routeconf.Table]
+	client atomic.Pointer[clientBounds]
+}
+
+// clientBounds clamps the timeouts clients ask for. A zero max is the
+// request's own timeout.
+type clientBounds struct{ min, max time.Duration }
+
+// Set changes the timeout of requests that start from now on.
+func (v *TimeoutVar) Set(d time.Duration) { v.d.Store(int64(d)) }
+
+// Get returns the current timeout.
+func (v *TimeoutVar) Get() time.Duration { return time.Duration(v.d.Load()) }
+
+// SetRoutes gives the routes in t their own timeout, see package
+// routeconf.
+func (v *TimeoutVar) SetRoutes(t routeconf.Table) { v.routes.Store(&t) }
+
+// For returns the timeout of r: its route's override, if any, or the
+// current timeout.
+func (v *TimeoutVar) For(r *http.Request) time.Duration {
+	if t := v.routes.Load(); t != nil {
+		if o, ok := t.Lookup(r); ok && o.Timeout != 0 {
+			return o.Timeout
+		}
+	}
+	return v.Get()
+}
+
+// SetClientBounds clamps the timeouts clients ask for with TimeoutHeader
+// to [min, max]. A max of zero or less is each request's own timeout, so
+// clients can only shorten it.
+func (v *TimeoutVar) SetClientBounds(min, max time.Duration) {
+	v.client.Store(&clientBounds{min: min, max: max})
+}
+
+// forClient returns the timeout r gets: the one For returns, or the one
+// its TimeoutHeader asks for, clamped to the client bounds. ok is false
+// if the header is not a positive duration.
+func (v *TimeoutVar) forClient(r *http.Request) (d time.Duration, ok bool) {
+	d = v.For(r)
+	h := r.Header.Get(TimeoutHeader)
+	if h == "" {
+		return d, true
+	}
+	want, err := time.ParseDuration(h)
+	if err != nil || want <= 0 {
+		return 0, false
+	}
+	var b clientBounds
+	if p := v.client.Load(); p != nil {
+		b = *p
+	}
+	limit := b.max
+	if limit <= 0 {
+		limit = d
+	}
+	want = max(want, b.min)
+	if limit > 0 {
+		want = min(want, limit)
+	}
+	return want, true
+}
+
+// Middleware is Timeout with the duration v holds for each request when
+// it starts; a request keeps its deadline if v changes. Clients can ask
+// for another timeout with TimeoutHeader, within the bounds set with
+// SetClientBounds. It must run inside the router for route overrides to
+// apply.
+func (v *TimeoutVar) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d, ok := v.forClient(r)
+		if !ok {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.CodeInvalidRequestTimeout,
+				TimeoutHeader+" must be a positive duration such as 5s"))
+			return
+		}
+		if d <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set(TimeoutHeader, d.String())
+		serveWithTimeout(w, r, next, d)
+	})
+}
+
+func serveWithTimeout(w http.ResponseWriter, r *http.Request, next http.Handler, d time.Duration) {
+	ctx, cancel := context.WithTimeout(r.Context(), d)
+	defer cancel()
+	outlastWriteTimeout(w, r, d)
+
+	tw := &timeoutWriter{w: w, header: make(http.Header), ctx: ctx}
+	done := make(chan struct{})
+	panicked := make(chan any, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				panicked <- p
+			}
+			close(done)
+		}()
+		next.ServeHTTP(tw, r.WithContext(ctx))
+	}()
+
+	select {
+	case <-done:
+		select {
+		case p := <-panicked:
+			panic(p)
+		default:
+		}
+		tw.mu.Lock()
+		expired := tw.timedOut
+		tw.mu.Unlock()
+		if !expired {
+			return
+		}
+	case <-ctx.Done():
+		tw.mu.Lock()
+		if tw.wroteHeader {
+			// Too late for a 504; let the handler finish the
+			// response it started.
+			tw.mu.Unlock()
+			<-done
+			return
+		}
+		tw.timedOut = true
+		tw.mu.Unlock()
+	}
+
+	if r.Context().Err() != nil {
+		// The client went away; nobody is listening.
+		return
+	}
+	apierror.Write(w, r, apierror.Timeout("the request took too long to process"))
+}
+
+// timeoutWriter guards the real ResponseWriter so that only one of the
+// handler and the timeout path writes the response. The handler gets its
+// own header map for the same reason.
+type timeoutWriter struct {
+	w      http.ResponseWriter
+	header http.Header
+	ctx    context.Context
+
+	mu          sync.Mutex
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (tw *timeoutWriter) Header() http.Header { return tw.header }
+
+func (tw *timeoutWriter) WriteHeader(status int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.expiredLocked() || tw.wroteHeader {
+		return
+	}
+	tw.writeHeaderLocked(status)
+}
+
+// expiredLocked reports whether the response belongs to the timeout path:
+// once the deadline passes before the handler has written anything, even a
+// handler that reacts to the cancellation immediately is too late.
+func (tw *timeoutWriter) expiredLocked() bool {
+	if !tw.wroteHeader && !tw.timedOut && tw.ctx.Err() == context.DeadlineExceeded {
+		tw.timedOut = true
+	}
+	return tw.timedOut
+}
+
+func (tw *timeoutWriter) writeHeaderLocked(status int) {
+	dst := tw.w.Header()
+	for k, v := range tw.header {
+		dst[k] = v
+	}
+	tw.wroteHeader = true
+	tw.w.WriteHeader(status)
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.expiredLocked() {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !tw.wroteHeader {
+		tw.writeHeaderLocked(http.StatusOK)
+	}
+	return tw.w.Write(b)
+}
+
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.expiredLocked() {
+		return
+	}
+	if !tw.wroteHeader {
+		tw.writeHeaderLocked(http.StatusOK)
+	}
+	if f, ok := tw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
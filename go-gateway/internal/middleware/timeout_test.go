@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeoutRespondsWith504(t *testing.T) {
+	cancelled := make(chan struct{})
+	h := Timeout(20 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		close(cancelled)
+		w.Header().Set("X-Late", "1")
+		w.Write([]byte("too late"))
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Fatalf("status = %d, want 504", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `"code":"timeout"`) {
+		t.Errorf("body = %s", rec.Body)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("handler context not cancelled")
+	}
+	time.Sleep(10 * time.Millisecond)
+	if strings.Contains(rec.Body.String(), "too late") {
+		t.Error("late handler write reached the client")
+	}
+}
+
+func TestTimeoutPassesFastResponses(t *testing.T) {
+	var deadline time.Time
+	h := Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline, _ = r.Context().Deadline()
+		w.Header().Set("X-Handler", "yes")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("ok"))
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusCreated || rec.Body.String() != "ok" || rec.Header().Get("X-Handler") != "yes" {
+		t.Errorf("response = %d %q %v", rec.Code, rec.Body, rec.Header())
+	}
+	if deadline.IsZero() || time.Until(deadline) > time.Second {
+		t.Errorf("deadline = %v, want within 1s", deadline)
+	}
+}
+
+func TestTimeoutLetsStartedResponseFinish(t *testing.T) {
+	h := Timeout(20 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("partial"))
+		<-r.Context().Done()
+		w.Write([]byte(" rest"))
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "partial rest" {
+		t.Errorf("response = %d %q", rec.Code, rec.Body)
+	}
+}
+
+func TestTimeoutRepanics(t *testing.T) {
+	h := Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+	defer func() {
+		if p := recover(); p != "boom" {
+			t.Errorf("recovered %v, want boom", p)
+		}
+	}()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+func TestTimeoutVar(t *testing.T) {
+	var v TimeoutVar
+	var deadline time.Time
+	var hasDeadline bool
+	h := v.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline, hasDeadline = r.Context().Deadline()
+	}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if hasDeadline {
+		t.Error("zero TimeoutVar set a deadline")
+	}
+
+	v.Set(time.Minute)
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if !hasDeadline || time.Until(deadline) > time.Minute || time.Until(deadline) < 50*time.Second {
+		t.Errorf("deadline = %v, %v", deadline, hasDeadline)
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	var v TimeoutVar
+	v.Set(30 * time.Second)
+	v.SetClientBounds(time.Second, 0)
+	var timeout time.Duration
+	h := v.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline, _ := r.Context().Deadline()
+		timeout = time.Until(deadline).Round(time.Second)
+	}))
+
+	for _, tc := range []struct {
+		header string
+		want   time.Duration
+	}{
+		{"", 30 * time.Second},
+		{"5s", 5 * time.Second},
+		{"10ms", time.Second},    // raised to the minimum
+		{"2m", 30 * time.Second}, // cannot extend the server's timeout
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tc.header != "" {
+			req.Header.Set(TimeoutHeader, tc.header)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if timeout != tc.want || rec.Header().Get(TimeoutHeader) != tc.want.String() {
+			t.Errorf("%s %q: timeout = %v, echoed %q, want %v", TimeoutHeader, tc.header, timeout, rec.Header().Get(TimeoutHeader), tc.want)
+		}
+	}
+
+	v.SetClientBounds(time.Second, time.Minute)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(TimeoutHeader, "2m")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if timeout != time.Minute {
+		t.Errorf("with a 1m maximum: timeout = %v", timeout)
+	}
+
+	for _, bad := range []string{"soon", "-5s", "0s"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(TimeoutHeader, bad)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "invalid_request_timeout") {
+			t.Errorf("%q: %d %s", bad, rec.Code, rec.Body)
+		}
+	}
+}
@@ -0,0 +1,444 @@
+// Package msgpack converts between JSON and MessagePack, so the gateway
+// can offer MessagePack bodies while its types keep describing themselves
+// with encoding/json tags and methods alone.
+//
+// Marshal encodes a value through its JSON form: a struct becomes a map
+// keyed by its JSON field names, in the order encoding/json writes them,
+// and a time.Time becomes its RFC 3339 string. ToJSON goes the other way
+// for request bodies, which are then decoded with encoding/json as usual.
+package msgpack
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+)
+
+// ContentType is the media type of MessagePack bodies.
+const ContentType = "application/msgpack"
+
+// maxDepth bounds the nesting of maps and arrays either way.
+const maxDepth = 100
+
+// ErrInvalid wraps every error decoding a malformed MessagePack document.
+var ErrInvalid = errors.New("msgpack: invalid document")
+
+// Marshal returns the MessagePack encoding of v's JSON form.
+func Marshal(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return FromJSON(data)
+}
+
+// FromJSON converts one JSON document to MessagePack. Integers become the
+// smallest MessagePack integer that holds them, other numbers float64;
+// object keys keep their order.
+func FromJSON(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var e encoder
+	if err := e.value(dec, 0); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("msgpack: trailing data after JSON document")
+	}
+	return e.buf.Bytes(), nil
+}
+
+// ToJSON converts one MessagePack document to JSON. Map keys must be
+// strings. Binary values become base64 strings, as encoding/json writes
+// []byte, and timestamps RFC 3339 strings; other extension types are
+// refused.
+func ToJSON(data []byte) ([]byte, error) {
+	d := decoder{data: data}
+	var out bytes.Buffer
+	if err := d.value(&out, 0); err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.data) {
+		return nil, fmt.Errorf("%w: %d trailing bytes", ErrInvalid, len(d.data)-d.pos)
+	}
+	return out.Bytes(), nil
+}
+
+type encoder struct {
+	buf bytes.Buffer
+}
+
+// value encodes the next JSON value of dec. Containers are encoded into a
+// scratch encoder first, since MessagePack puts their length up front.
+func (e *encoder) value(dec *json.Decoder, depth int) error {
+	if depth > maxDepth {
+		return errors.New("msgpack: document nested too deeply")
+	}
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		var inner encoder
+		n := 0
+		for dec.More() {
+			if t == '{' {
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				inner.str(key.(string))
+			}
+			if err := inner.value(dec, depth+1); err != nil {
+				return err
+			}
+			n++
+		}
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+		if t == '{' {
+			e.header(n, 0x80, 15, 0xde, 0xdf)
+		} else {
+			e.header(n, 0x90, 15, 0xdc, 0xdd)
+		}
+		e.buf.Write(inner.buf.Bytes())
+	case string:
+		e.str(t)
+	case json.Number:
+		e.number(t)
+	case bool:
+		if t {
+			e.buf.WriteByte(0xc3)
+		} else {
+			e.buf.WriteByte(0xc2)
+		}
+	case nil:
+		e.buf.WriteByte(0xc0)
+	}
+	return nil
+}
+
+// header writes the length of a map or array: fixed below fixMax, then
+// 16 or 32 bits.
+func (e *encoder) header(n int, fix byte, fixMax int, code16, code32 byte) {
+	switch {
+	case n <= fixMax:
+		e.buf.WriteByte(fix | byte(n))
+	case n <= math.MaxUint16:
+		e.buf.WriteByte(code16)
+		e.buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	default:
+		e.buf.WriteByte(code32)
+		e.buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	}
+}
+
+func (e *encoder) str(s string) {
+	n := len(s)
+	switch {
+	case n <= 31:
+		e.buf.WriteByte(0xa0 | byte(n))
+	case n <= math.MaxUint8:
+		e.buf.WriteByte(0xd9)
+		e.buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		e.buf.WriteByte(0xda)
+		e.buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	default:
+		e.buf.WriteByte(0xdb)
+		e.buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	}
+	e.buf.WriteString(s)
+}
+
+func (e *encoder) number(n json.Number) {
+	if i, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+		e.int(i)
+		return
+	}
+	if u, err := strconv.ParseUint(string(n), 10, 64); err == nil {
+		e.buf.WriteByte(0xcf)
+		e.buf.Write(binary.BigEndian.AppendUint64(nil, u))
+		return
+	}
+	// encoding/json only writes finite numbers.
+	f, _ := strconv.ParseFloat(string(n), 64)
+	e.buf.WriteByte(0xcb)
+	e.buf.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(f)))
+}
+
+func (e *encoder) int(i int64) {
+	b := &e.buf
+	switch {
+	case i >= 0 && i <= 127:
+		b.WriteByte(byte(i))
+	case i >= -32 && i < 0:
+		b.WriteByte(byte(int8(i)))
+	case i > 0 && i <= math.MaxUint8:
+		b.WriteByte(0xcc)
+		b.WriteByte(byte(i))
+	case i > 0 && i <= math.MaxUint16:
+		b.WriteByte(0xcd)
+		b.Write(binary.BigEndian.AppendUint16(nil, uint16(i)))
+	case i > 0 && i <= math.MaxUint32:
+		b.WriteByte(0xce)
+		b.Write(binary.BigEndian.AppendUint32(nil, uint32(i)))
+	case i > 0:
+		b.WriteByte(0xcf)
+		b.Write(binary.BigEndian.AppendUint64(nil, uint64(i)))
+	case i >= math.MinInt8:
+		b.WriteByte(0xd0)
+		b.WriteByte(byte(int8(i)))
+	case i >= math.MinInt16:
+		b.WriteByte(0xd1)
+		b.Write(binary.BigEndian.AppendUint16(nil, uint16(int16(i))))
+	case i >= math.MinInt32:
+		b.WriteByte(0xd2)
+		b.Write(binary.BigEndian.AppendUint32(nil, uint32(int32(i))))
+	default:
+		b.WriteByte(0xd3)
+		b.Write(binary.BigEndian.AppendUint64(nil, uint64(i)))
+	}
+}
+
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *decoder) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w at byte %d: %s", ErrInvalid, d.pos, fmt.Sprintf(format, args...))
+}
+
+// take returns the next n bytes.
+func (d *decoder) take(n int) ([]byte, error) {
+	if n < 0 || n > len(d.data)-d.pos {
+		return nil, d.errorf("truncated")
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+// uint reads a big-endian unsigned integer of size bytes.
+func (d *decoder) uint(size int) (uint64, error) {
+	b, err := d.take(size)
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+// length reads a length prefix of size bytes.
+func (d *decoder) length(size int) (int, error) {
+	n, err := d.uint(size)
+	if err != nil {
+		return 0, err
+	}
+	// Every element takes at least a byte, so longer cannot be valid.
+	if n > uint64(len(d.data)-d.pos) {
+		return 0, d.errorf("length %d exceeds the document", n)
+	}
+	return int(n), nil
+}
+
+// value converts the next MessagePack value to JSON on out.
+func (d *decoder) value(out *bytes.Buffer, depth int) error {
+	if depth > maxDepth {
+		return d.errorf("nested too deeply")
+	}
+	b, err := d.take(1)
+	if err != nil {
+		return err
+	}
+	c := b[0]
+	switch {
+	case c <= 0x7f:
+		out.WriteString(strconv.Itoa(int(c)))
+		return nil
+	case c >= 0xe0:
+		out.WriteString(strconv.Itoa(int(int8(c))))
+		return nil
+	case c&0xf0 == 0x80:
+		return d.object(out, int(c&0x0f), depth)
+	case c&0xf0 == 0x90:
+		return d.array(out, int(c&0x0f), depth)
+	case c&0xe0 == 0xa0:
+		return d.str(out, int(c&0x1f))
+	}
+
+	switch c {
+	case 0xc0:
+		out.WriteString("null")
+	case 0xc2:
+		out.WriteString("false")
+	case 0xc3:
+		out.WriteString("true")
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		v, err := d.uint(1 << (c - 0xcc))
+		if err != nil {
+			return err
+		}
+		out.WriteString(strconv.FormatUint(v, 10))
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (c - 0xd0)
+		v, err := d.uint(size)
+		if err != nil {
+			return err
+		}
+		// Sign-extend from size bytes.
+		shift := 64 - 8*size
+		out.WriteString(strconv.FormatInt(int64(v<<shift)>>shift, 10))
+	case 0xca, 0xcb:
+		var f float64
+		if c == 0xca {
+			v, err := d.uint(4)
+			if err != nil {
+				return err
+			}
+			f = float64(math.Float32frombits(uint32(v)))
+		} else {
+			v, err := d.uint(8)
+			if err != nil {
+				return err
+			}
+			f = math.Float64frombits(v)
+		}
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return d.errorf("%v has no JSON form", f)
+		}
+		out.WriteString(strconv.FormatFloat(f, 'g', -1, 64))
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.length(1 << (c - 0xd9))
+		if err != nil {
+			return err
+		}
+		return d.str(out, n)
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.length(1 << (c - 0xc4))
+		if err != nil {
+			return err
+		}
+		raw, err := d.take(n)
+		if err != nil {
+			return err
+		}
+		out.WriteByte('"')
+		out.WriteString(base64.StdEncoding.EncodeToString(raw))
+		out.WriteByte('"')
+	case 0xdc, 0xdd:
+		n, err := d.length(2 << (c - 0xdc))
+		if err != nil {
+			return err
+		}
+		return d.array(out, n, depth)
+	case 0xde, 0xdf:
+		n, err := d.length(2 << (c - 0xde))
+		if err != nil {
+			return err
+		}
+		return d.object(out, n, depth)
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return d.ext(out, 1<<(c-0xd4))
+	case 0xc7, 0xc8, 0xc9:
+		n, err := d.length(1 << (c - 0xc7))
+		if err != nil {
+			return err
+		}
+		return d.ext(out, n)
+	default:
+		return d.errorf("unknown type byte 0x%02x", c)
+	}
+	return nil
+}
+
+func (d *decoder) str(out *bytes.Buffer, n int) error {
+	raw, err := d.take(n)
+	if err != nil {
+		return err
+	}
+	quoted, _ := json.Marshal(string(raw))
+	out.Write(quoted)
+	return nil
+}
+
+func (d *decoder) array(out *bytes.Buffer, n, depth int) error {
+	out.WriteByte('[')
+	for i := range n {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		if err := d.value(out, depth+1); err != nil {
+			return err
+		}
+	}
+	out.WriteByte(']')
+	return nil
+}
+
+func (d *decoder) object(out *bytes.Buffer, n, depth int) error {
+	out.WriteByte('{')
+	for i := range n {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		start := out.Len()
+		if err := d.value(out, depth+1); err != nil {
+			return err
+		}
+		if out.Bytes()[start] != '"' {
+			return d.errorf("map key is not a string")
+		}
+		out.WriteByte(':')
+		if err := d.value(out, depth+1); err != nil {
+			return err
+		}
+	}
+	out.WriteByte('}')
+	return nil
+}
+
+// ext converts an extension value of n data bytes. Only the timestamp
+// type, -1, has a JSON form.
+func (d *decoder) ext(out *bytes.Buffer, n int) error {
+	typ, err := d.take(1)
+	if err != nil {
+		return err
+	}
+	data, err := d.take(n)
+	if err != nil {
+		return err
+	}
+	if int8(typ[0]) != -1 {
+		return d.errorf("extension type %d has no JSON form", int8(typ[0]))
+	}
+	var t time.Time
+	switch n {
+	case 4:
+		t = time.Unix(int64(binary.BigEndian.Uint32(data)), 0)
+	case 8:
+		v := binary.BigEndian.Uint64(data)
+		t = time.Unix(int64(v&(1<<34-1)), int64(v>>34))
+	case 12:
+		t = time.Unix(int64(binary.BigEndian.Uint64(data[4:])), int64(binary.BigEndian.Uint32(data)))
+	default:
+		return d.errorf("timestamp of %d bytes", n)
+	}
+	out.WriteByte('"')
+	out.WriteString(t.UTC().Format(time.RFC3339Nano))
+	out.WriteByte('"')
+	return nil
+}
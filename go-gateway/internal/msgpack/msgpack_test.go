@@ -0,0 +1,119 @@
+package msgpack
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFromJSON(t *testing.T) {
+	for in, want := range map[string]string{
+		`null`:                  "c0",
+		`true`:                  "c3",
+		`7`:                     "07",
+		`-5`:                    "fb",
+		`200`:                   "ccc8",
+		`-100`:                  "d09c",
+		`-200`:                  "d1ff38",
+		`70000`:                 "ce00011170",
+		`18446744073709551615`:  "cfffffffffffffffff",
+		`1.5`:                   "cb3ff8000000000000",
+		`"hi"`:                  "a26869",
+		`[1,"a"]`:               "9201a161",
+		`{"b":1,"a":[]}`:        "82a16201a16190",
+		`{"nested":{"x":null}}`: "81a66e657374656481a178c0",
+	} {
+		got, err := FromJSON([]byte(in))
+		if err != nil {
+			t.Errorf("FromJSON(%s): %v", in, err)
+			continue
+		}
+		if hex.EncodeToString(got) != want {
+			t.Errorf("FromJSON(%s) = %x, want %s", in, got, want)
+		}
+	}
+	if _, err := FromJSON([]byte(`{} {}`)); err == nil {
+		t.Error("trailing document accepted")
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	in := `{"prompt":"a video about otters","format":"video","options":{"length":30,"ratio":1.78,"tags":["a","b"],"loud":false,"none":null},"long":"` + strings.Repeat("x", 300) + `"}`
+	packed, err := FromJSON([]byte(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := ToJSON(packed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != in {
+		t.Errorf("round trip = %s", out)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	v := struct {
+		ID    string    `json:"id"`
+		Count int       `json:"count,omitempty"`
+		At    time.Time `json:"at"`
+	}{ID: "job-1", At: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+	packed, err := Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, _ := ToJSON(packed)
+	if string(out) != `{"id":"job-1","at":"2024-01-02T03:04:05Z"}` {
+		t.Errorf("Marshal = %s", out)
+	}
+}
+
+func TestToJSON(t *testing.T) {
+	for in, want := range map[string]string{
+		"d0ff":               `-1`,
+		"d3ffffffffffffffff": `-1`,
+		"cdffff":             `65535`,
+		"ca3fc00000":         `1.5`,
+		"c403010203":         `"AQID"`,
+		"d6ff00000000":       `"1970-01-01T00:00:00Z"`,
+		"dc0001c3":           `[true]`,
+		"de0001a1610a":       `{"a":10}`,
+		"a3e282ac":           `"€"`,
+		"d90122":             `"\""`,
+	} {
+		raw, _ := hex.DecodeString(in)
+		got, err := ToJSON(raw)
+		if err != nil {
+			t.Errorf("ToJSON(%s): %v", in, err)
+			continue
+		}
+		if string(got) != want {
+			t.Errorf("ToJSON(%s) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestToJSONInvalid(t *testing.T) {
+	for _, in := range []string{
+		"",                   // empty
+		"c1",                 // never used
+		"a3616263ff",         // trailing byte
+		"a5616263",           // truncated string
+		"dbffffffff",         // length past the end
+		"8101c0",             // integer key
+		"d50100ff",           // unknown extension
+		"cb7ff8000000000000", // NaN
+	} {
+		raw, _ := hex.DecodeString(in)
+		if _, err := ToJSON(raw); !errors.Is(err, ErrInvalid) {
+			t.Errorf("ToJSON(%s) = %v, want ErrInvalid", in, err)
+		}
+	}
+	deep := append(bytes.Repeat([]byte{0x91}, maxDepth+2), 0xc0)
+	if _, err := ToJSON(deep); !errors.Is(err, ErrInvalid) {
+		t.Errorf("deep nesting: %v", err)
+	}
+}
@@ -0,0 +1,35 @@
+package openapi
+
+import (
+	"html/template"
+	"net/http"
+)
+
+// swaggerUIVersion pins the Swagger UI release the docs page loads.
+const swaggerUIVersion = "5.17.14"
+
+var docsPage = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@{{.Version}}/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@{{.Version}}/swagger-ui-bundle.js" crossorigin></script>
+<script>
+window.onload = () => { window.ui = SwaggerUIBundle({url: {{.SpecURL}}, dom_id: "#swagger-ui"}); };
+</script>
+</body>
+</html>
+`))
+
+// DocsHandler serves a Swagger UI page rendering the document at specURL.
+// The UI's scripts and styles come from the unpkg CDN.
+func DocsHandler(title, specURL string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		docsPage.Execute(w, struct{ Title, Version, SpecURL string }{title, swaggerUIVersion, specURL})
+	})
+}
@@ -0,0 +1,144 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Schema is an OpenAPI 3.0 schema object, as much of one as the gateway's
+// types need.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties any                `json:"additionalProperties,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+}
+
+// Describer is implemented by types that add to the schema derived from
+// their fields, with constraints struct tags cannot express, such as the
+// values a string field accepts. DescribeSchema is called on the zero
+// value with the type's generated schema.
+type Describer interface {
+	DescribeSchema(s *Schema)
+}
+
+var (
+	timeType       = reflect.TypeFor[time.Time]()
+	rawMessageType = reflect.TypeFor[json.RawMessage]()
+	describerType  = reflect.TypeFor[Describer]()
+)
+
+// schemaOf returns the schema of t, adding named struct types to defs and
+// referring to them there, so each is described once.
+func schemaOf(t reflect.Type, defs map[string]*Schema) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawMessageType:
+		return &Schema{}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := schemaOf(t.Elem(), defs)
+		if s.Ref != "" {
+			return s
+		}
+		s.Nullable = true
+		return s
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: schemaOf(t.Elem(), defs)}
+	case reflect.Map:
+		if t.Elem().Kind() == reflect.Interface {
+			return &Schema{Type: "object", AdditionalProperties: true}
+		}
+		return &Schema{Type: "object", AdditionalProperties: schemaOf(t.Elem(), defs)}
+	case reflect.Struct:
+		name := schemaName(t)
+		if name == "" {
+			return structSchema(t, defs)
+		}
+		if _, ok := defs[name]; !ok {
+			defs[name] = nil // guards against recursive types
+			defs[name] = structSchema(t, defs)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	// Interfaces and anything else accept any JSON value.
+	return &Schema{}
+}
+
+// structSchema describes t's JSON-encoded fields. Fields not marked
+// omitempty are required.
+func structSchema(t reflect.Type, defs map[string]*Schema) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" || !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			// Embedded struct fields are encoded as if they were s's own.
+			embedded := structSchema(f.Type, defs)
+			for k, v := range embedded.Properties {
+				s.Properties[k] = v
+			}
+			s.Required = append(s.Required, embedded.Required...)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s.Properties[name] = schemaOf(f.Type, defs)
+		if !strings.Contains(opts, "omitempty") {
+			s.Required = append(s.Required, name)
+		}
+	}
+	if t.Implements(describerType) {
+		reflect.Zero(t).Interface().(Describer).DescribeSchema(s)
+	}
+	return s
+}
+
+// schemaName names t's entry in components/schemas: its Go name, with the
+// type arguments of generic types appended, e.g. Page_JobStatus. Unnamed
+// types get "".
+func schemaName(t reflect.Type) string {
+	name, args, generic := strings.Cut(t.Name(), "[")
+	if !generic {
+		return name
+	}
+	var parts []string
+	for _, arg := range strings.Split(strings.TrimSuffix(args, "]"), ",") {
+		parts = append(parts, arg[strings.LastIndex(arg, ".")+1:])
+	}
+	return name + "_" + strings.Join(parts, "_")
+}
@@ -0,0 +1,302 @@
+// Package openapi builds the gateway's OpenAPI 3 document from the same
+// Go types its handlers encode and decode, so the published contract
+// follows the code.
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Version is the OpenAPI version of the generated document.
+const Version = "3.0.3"
+
+// Security scheme names, usable in Operation.Security.
+const (
+	BearerAuth = "bearerAuth"
+	APIKeyAuth = "apiKeyAuth"
+	// SignatureAuth is a partner's HMAC request signature, which OpenAPI
+	// has no scheme for; it is declared by the key ID header it names.
+	SignatureAuth = "signatureAuth"
+)
+
+// Operation documents one method on one path. Request and response
+// bodies are given as values of the Go types the handler reads and
+// writes; only their types are used.
+type Operation struct {
+	Summary     string
+	Description string
+	Tags        []string
+	Params      []Param
+	Request     *Body
+	Responses   []Response
+	// Public operations need no credentials. The rest accept a bearer
+	// JWT, an API key or a request signature.
+	Public bool
+}
+
+// Param is a path, query or header parameter.
+type Param struct {
+	Name        string
+	In          string // "path", "query" or "header"
+	Description string
+	Required    bool
+	// Type is a value of the parameter's Go type; nil means string.
+	Type any
+}
+
+// Body is a request or response body. A nil Type means the body is not
+// JSON, such as a file download, and is documented as binary.
+type Body struct {
+	ContentType string
+	Type        any
+	// Optional marks a request body the client may leave out.
+	Optional bool
+}
+
+// Response is one possible answer of an operation.
+type Response struct {
+	Status      int
+	Description string
+	Body        *Body
+	Headers     []string
+}
+
+// JSON is a Body of application/json holding a value like v.
+func JSON(v any) *Body {
+	return &Body{ContentType: "application/json", Type: v}
+}
+
+// Spec accumulates operations into an OpenAPI document.
+type Spec struct {
+	title, version string
+
+	mu    sync.Mutex
+	paths map[string]map[string]*operation
+	defs  map[string]*Schema
+}
+
+// New returns an empty Spec for the API called title at version.
+func New(title, version string) *Spec {
+	return &Spec{
+		title:   title,
+		version: version,
+		paths:   make(map[string]map[string]*operation),
+		defs:    make(map[string]*Schema),
+	}
+}
+
+// Add documents op as method on path, a router pattern such as
+// /api/v1/jobs/{id:uuid}. Declared parameter formats describe the path
+// parameters that have no Type. Catch-all patterns cannot be expressed in
+// OpenAPI and are ignored.
+func (s *Spec) Add(method, path string, op Operation) {
+	if strings.Contains(path, "...}") {
+		return
+	}
+	path, formats := stripFormats(path)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := &operation{
+		Summary:     op.Summary,
+		Description: op.Description,
+		Tags:        op.Tags,
+		OperationID: operationID(method, path),
+		Responses:   make(map[string]*response),
+	}
+	for _, p := range op.Params {
+		def := &Schema{Type: "string"}
+		if p.In == "path" {
+			def = formatSchema(formats[p.Name])
+		}
+		out.Parameters = append(out.Parameters, &parameter{
+			Name:        p.Name,
+			In:          p.In,
+			Description: p.Description,
+			Required:    p.Required || p.In == "path",
+			Schema:      s.schema(p.Type, def),
+		})
+	}
+	if op.Request != nil {
+		out.RequestBody = &requestBody{Required: !op.Request.Optional, Content: s.content(op.Request)}
+	}
+	for _, r := range op.Responses {
+		resp := &response{Description: r.Description}
+		if r.Body != nil {
+			resp.Content = s.content(r.Body)
+		}
+		for _, h := range r.Headers {
+			if resp.Headers == nil {
+				resp.Headers = make(map[string]*header)
+			}
+			resp.Headers[h] = &header{Schema: &Schema{Type: "string"}}
+		}
+		out.Responses[strconv.Itoa(r.Status)] = resp
+	}
+	if op.Public {
+		out.Security = json.RawMessage("[]")
+	}
+	if s.paths[path] == nil {
+		s.paths[path] = make(map[string]*operation)
+	}
+	s.paths[path][strings.ToLower(method)] = out
+}
+
+func (s *Spec) schema(v any, def *Schema) *Schema {
+	if v == nil {
+		return def
+	}
+	return schemaOf(reflect.TypeOf(v), s.defs)
+}
+
+func (s *Spec) content(b *Body) map[string]*mediaType {
+	return map[string]*mediaType{
+		b.ContentType: {Schema: s.schema(b.Type, &Schema{Type: "string", Format: "binary"})},
+	}
+}
+
+// MarshalJSON encodes the OpenAPI document.
+func (s *Spec) MarshalJSON() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return json.Marshal(document{
+		OpenAPI: Version,
+		Info:    info{Title: s.title, Version: s.version},
+		Paths:   s.paths,
+		Components: components{
+			Schemas: s.defs,
+			SecuritySchemes: map[string]*securityScheme{
+				BearerAuth: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+				APIKeyAuth: {Type: "apiKey", In: "header", Name: "X-API-Key"},
+				SignatureAuth: {Type: "apiKey", In: "header", Name: "X-Key-Id",
+					Description: "HMAC-SHA256 signature in X-Signature of the method, request URI, X-Timestamp and body SHA-256, one per line, under the key's secret"},
+			},
+		},
+		Security: []map[string][]string{{BearerAuth: {}}, {APIKeyAuth: {}}, {SignatureAuth: {}}},
+	})
+}
+
+// Handler serves the document as JSON, encoding it on first use.
+func (s *Spec) Handler() http.Handler {
+	var once sync.Once
+	var body []byte
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() { body, _ = json.Marshal(s) })
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	})
+}
+
+// stripFormats returns path without the formats its parameters declare,
+// as in {id:uuid}, and those formats by parameter name.
+func stripFormats(path string) (string, map[string]string) {
+	segs := strings.Split(path, "/")
+	var formats map[string]string
+	for i, seg := range segs {
+		name, format, ok := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(seg, "{"), "}"), ":")
+		if !ok || !strings.HasPrefix(seg, "{") {
+			continue
+		}
+		if formats == nil {
+			formats = make(map[string]string)
+		}
+		formats[name] = format
+		segs[i] = "{" + name + "}"
+	}
+	return strings.Join(segs, "/"), formats
+}
+
+// formatSchema returns the schema of a path parameter of the router
+// format f.
+func formatSchema(f string) *Schema {
+	switch f {
+	case "uuid":
+		return &Schema{Type: "string", Format: "uuid"}
+	case "int":
+		zero := 0.0
+		return &Schema{Type: "integer", Minimum: &zero}
+	case "slug":
+		return &Schema{Type: "string", Pattern: "^[A-Za-z0-9]+(-[A-Za-z0-9]+)*$"}
+	}
+	return &Schema{Type: "string"}
+}
+
+// operationID derives a stable identifier from the route, e.g.
+// get_api_v1_jobs_id for GET /api/v1/jobs/{id}.
+func operationID(method, path string) string {
+	id := strings.ToLower(method)
+	for _, seg := range strings.Split(strings.Trim(path, "/"), "/") {
+		id += "_" + strings.Trim(seg, "{}")
+	}
+	return id
+}
+
+type document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       info                             `json:"info"`
+	Paths      map[string]map[string]*operation `json:"paths"`
+	Components components                       `json:"components"`
+	Security   []map[string][]string            `json:"security"`
+}
+
+type info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*securityScheme `json:"securitySchemes"`
+}
+
+type securityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+type operation struct {
+	Summary     string               `json:"summary,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	OperationID string               `json:"operationId"`
+	Parameters  []*parameter         `json:"parameters,omitempty"`
+	RequestBody *requestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*response `json:"responses"`
+	// Security is [] for public operations, overriding the document's.
+	Security json.RawMessage `json:"security,omitempty"`
+}
+
+type parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type requestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*mediaType `json:"content"`
+}
+
+type response struct {
+	Description string                `json:"description"`
+	Headers     map[string]*header    `json:"headers,omitempty"`
+	Content     map[string]*mediaType `json:"content,omitempty"`
+}
+
+type header struct {
+	Schema *Schema `json:"schema"`
+}
+
+type mediaType struct {
+	Schema *Schema `json:"schema"`
+}
@@ -0,0 +1,163 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+type item struct {
+	ID      string         `json:"id"`
+	Created time.Time      `json:"created_at"`
+	Parent  *item          `json:"parent,omitempty"`
+	Labels  map[string]any `json:"labels,omitempty"`
+	Score   *float64       `json:"score,omitempty"`
+	Kind    string         `json:"kind"`
+	secret  string
+	Skipped string `json:"-"`
+}
+
+func (item) DescribeSchema(s *Schema) {
+	s.Properties["kind"].Enum = []any{"a", "b"}
+}
+
+type list[T any] struct {
+	Items []T `json:"items"`
+}
+
+// decode marshals spec and decodes it into a generic JSON value.
+func decode(t *testing.T, spec *Spec) map[string]any {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	spec.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+// at walks doc along keys.
+func at(t *testing.T, doc any, keys ...string) any {
+	t.Helper()
+	for _, k := range keys {
+		m, ok := doc.(map[string]any)
+		if !ok {
+			t.Fatalf("%v: not an object at %q", keys, k)
+		}
+		doc = m[k]
+	}
+	return doc
+}
+
+func TestSchemas(t *testing.T) {
+	spec := New("Test", "1.0")
+	spec.Add(http.MethodGet, "/items", Operation{
+		Responses: []Response{{Status: 200, Description: "ok", Body: JSON(list[item]{})}},
+	})
+	doc := decode(t, spec)
+
+	if got := at(t, doc, "paths", "/items", "get", "responses", "200", "content", "application/json", "schema", "$ref"); got != "#/components/schemas/list_item" {
+		t.Errorf("response schema ref = %v", got)
+	}
+	schemas := at(t, doc, "components", "schemas")
+	if got := at(t, schemas, "list_item", "properties", "items", "items", "$ref"); got != "#/components/schemas/item" {
+		t.Errorf("items ref = %v", got)
+	}
+	props := at(t, schemas, "item", "properties").(map[string]any)
+	var names []string
+	for k := range props {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	if want := []string{"created_at", "id", "kind", "labels", "parent", "score"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("properties = %v, want %v", names, want)
+	}
+	for keys, want := range map[[2]string]any{
+		{"created_at", "format"}:           "date-time",
+		{"parent", "$ref"}:                 "#/components/schemas/item",
+		{"labels", "additionalProperties"}: true,
+		{"score", "nullable"}:              true,
+		{"score", "format"}:                "double",
+		{"kind", "type"}:                   "string",
+	} {
+		if got := at(t, props, keys[0], keys[1]); got != want {
+			t.Errorf("%s.%s = %v, want %v", keys[0], keys[1], got, want)
+		}
+	}
+	if got := at(t, props, "kind", "enum"); !reflect.DeepEqual(got, []any{"a", "b"}) {
+		t.Errorf("kind enum = %v, want the Describer's", got)
+	}
+	if got := at(t, schemas, "item", "required"); !reflect.DeepEqual(got, []any{"id", "created_at", "kind"}) {
+		t.Errorf("required = %v", got)
+	}
+}
+
+func TestSecurity(t *testing.T) {
+	spec := New("Test", "1.0")
+	spec.Add(http.MethodGet, "/private/{id}", Operation{Params: []Param{{Name: "id", In: "path"}}})
+	spec.Add(http.MethodGet, "/public", Operation{Public: true})
+	spec.Add(http.MethodGet, "/legacy/{path...}", Operation{})
+	doc := decode(t, spec)
+
+	if doc["openapi"] != Version {
+		t.Errorf("openapi = %v", doc["openapi"])
+	}
+	if got := at(t, doc, "components", "securitySchemes", BearerAuth, "bearerFormat"); got != "JWT" {
+		t.Errorf("bearer scheme format = %v", got)
+	}
+	if got := at(t, doc, "components", "securitySchemes", APIKeyAuth, "name"); got != "X-API-Key" {
+		t.Errorf("API key header = %v", got)
+	}
+	if got := at(t, doc, "components", "securitySchemes", SignatureAuth, "name"); got != "X-Key-Id" {
+		t.Errorf("signature key ID header = %v", got)
+	}
+	if got := doc["security"].([]any); len(got) != 3 {
+		t.Errorf("document security = %v, want any of the schemes", got)
+	}
+	if got, ok := at(t, doc, "paths", "/public", "get").(map[string]any)["security"].([]any); !ok || len(got) != 0 {
+		t.Errorf("public operation security = %v, want []", got)
+	}
+	if _, ok := at(t, doc, "paths", "/private/{id}", "get").(map[string]any)["security"]; ok {
+		t.Error("private operation overrides the document security")
+	}
+	param := at(t, doc, "paths", "/private/{id}", "get", "parameters").([]any)[0]
+	if at(t, param, "required") != true || at(t, param, "schema", "type") != "string" {
+		t.Errorf("path parameter = %v", param)
+	}
+	if _, ok := at(t, doc, "paths").(map[string]any)["/legacy/{path...}"]; ok {
+		t.Error("catch-all route documented")
+	}
+}
+
+func TestPathParamFormats(t *testing.T) {
+	spec := New("Test", "1.0")
+	spec.Add(http.MethodGet, "/jobs/{id:uuid}/pages/{page:int}", Operation{Params: []Param{
+		{Name: "id", In: "path"}, {Name: "page", In: "path"},
+	}})
+	spec.Add(http.MethodGet, "/tags/{tag:slug}", Operation{Params: []Param{{Name: "tag", In: "path"}}})
+	doc := decode(t, spec)
+
+	op := at(t, doc, "paths", "/jobs/{id}/pages/{page}", "get")
+	if got := at(t, op, "operationId"); got != "get_jobs_id_pages_page" {
+		t.Errorf("operationId = %v", got)
+	}
+	params := at(t, op, "parameters").([]any)
+	if got := at(t, params[0], "schema", "format"); got != "uuid" {
+		t.Errorf("id format = %v, want uuid", got)
+	}
+	if got := at(t, params[1], "schema", "type"); got != "integer" {
+		t.Errorf("page type = %v, want integer", got)
+	}
+	tag := at(t, doc, "paths", "/tags/{tag}", "get", "parameters").([]any)[0]
+	if got := at(t, tag, "schema", "pattern"); got == nil {
+		t.Error("slug parameter has no pattern")
+	}
+}
@@ -0,0 +1,163 @@
+// Package proxy forwards requests for routes that have not moved to the
+// gRPC content service yet to the HTTP service still serving them.
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/metrics"
+	"github.com/content-factory/go-gateway/internal/middleware"
+	"github.com/content-factory/go-gateway/internal/router"
+	"github.com/content-factory/go-gateway/internal/rpc"
+)
+
+// DefaultPrefix is the path prefix forwarded when nothing else is
+// configured. Used by the config package.
+const DefaultPrefix = "/legacy/"
+
+// DefaultMaxResponseBytes is the default of the config package's
+// legacy.max_response_bytes.
+const DefaultMaxResponseBytes = 16 << 20
+
+// dialTimeout bounds connecting to the backend, so an unreachable one
+// fails fast with 502.
+const dialTimeout = 5 * time.Second
+
+// New returns a handler forwarding requests under prefix to target, with
+// the prefix replaced by target's path: with prefix "/legacy/" and target
+// http://svc:8000/api, GET /legacy/brands?x=1 is sent as GET
+// http://svc:8000/api/brands?x=1.
+//
+// Request headers are passed on, apart from hop-by-hop ones, along with
+// X-Forwarded-For, -Host and -Proto and the gateway's X-Request-ID.
+// Responses are streamed to the client as they arrive. A backend that
+// cannot be reached gets a JSON 502.
+//
+// So that a misbehaving backend cannot exhaust the gateway's memory, a
+// response of more than maxResponseBytes is answered with a 502 instead;
+// one whose length is not given up front is read in full before it is
+// passed on, to find out. Event streams and attachments are exempt, being
+// meant to be long or large.
+func New(target *url.URL, prefix string, maxResponseBytes int64) http.Handler {
+	return &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			rest := strings.TrimPrefix(pr.In.URL.Path, strings.TrimSuffix(prefix, "/"))
+			pr.Out.URL.Path = "/" + strings.TrimPrefix(rest, "/")
+			pr.Out.URL.RawPath = ""
+			pr.SetURL(target)
+			pr.SetXForwarded()
+			if id := gateway.RequestIDFromContext(pr.In.Context()); id != "" {
+				pr.Out.Header.Set(middleware.RequestIDHeader, id)
+			}
+		},
+		Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			DialContext:           (&net.Dialer{Timeout: dialTimeout, KeepAlive: 30 * time.Second}).DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConnsPerHost:   32,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   dialTimeout,
+			ExpectContinueTimeout: time.Second,
+		},
+		// Flush every write, so event streams and long downloads reach the
+		// client without buffering.
+		FlushInterval: -1,
+		ModifyResponse: func(resp *http.Response) error {
+			// The gateway already set its own; a second would confuse
+			// clients correlating logs.
+			resp.Header.Del(middleware.RequestIDHeader)
+			return capBody(resp, maxResponseBytes)
+		},
+		ErrorHandler: writeError,
+	}
+}
+
+// methods are the request methods forwarded.
+var methods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodDelete, http.MethodOptions,
+}
+
+// Register mounts h on rt for every path under prefix and every method,
+// wrapped in protect.
+func Register(rt *router.Router, prefix string, h http.Handler, protect func(http.Handler) http.Handler) {
+	pattern := strings.TrimSuffix(prefix, "/") + "/{path...}"
+	for _, m := range methods {
+		rt.Handle(m, pattern, protect(h))
+	}
+}
+
+// errTooLarge fails a response over the size cap.
+var errTooLarge = errors.New("proxy: response exceeds the size limit")
+
+var oversized = metrics.NewCounterVec("gateway_legacy_oversized_responses_total",
+	"Legacy backend responses answered with 502 for exceeding the response size cap.")
+
+// capBody fails resp with errTooLarge if its body is over limit bytes,
+// reading a body of unknown length into memory to tell.
+func capBody(resp *http.Response, limit int64) error {
+	if limit <= 0 || uncapped(resp) {
+		return nil
+	}
+	if resp.ContentLength > limit {
+		resp.Body.Close()
+		return errTooLarge
+	}
+	if resp.ContentLength >= 0 {
+		// The transport reads no more than the length given.
+		return nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	if int64(len(body)) > limit {
+		return errTooLarge
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return nil
+}
+
+// uncapped reports whether resp is exempt from the size cap: an answer to
+// HEAD, which has no body, an event stream or an attachment.
+func uncapped(resp *http.Response) bool {
+	if resp.Request != nil && resp.Request.Method == http.MethodHead {
+		return true
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	disposition, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Disposition"))
+	return mediaType == "text/event-stream" || disposition == "attachment"
+}
+
+// writeError answers a request the backend could not serve.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	ctx := r.Context()
+	switch {
+	case gateway.ClientGone(ctx):
+		// Nobody reads the answer.
+		gateway.Logger(ctx).DebugContext(ctx, "legacy backend request abandoned by the client", "path", r.URL.Path, "error", err)
+		w.WriteHeader(rpc.StatusClientClosedRequest)
+	case errors.Is(err, errTooLarge):
+		oversized.With().Inc()
+		gateway.Logger(ctx).ErrorContext(ctx, "legacy backend response exceeds size limit", "path", r.URL.Path)
+		apierror.Write(w, r, apierror.New(http.StatusBadGateway, apierror.CodeBadGateway, "the legacy backend sent a larger response than the gateway accepts"))
+	case errors.Is(err, context.DeadlineExceeded):
+		apierror.Write(w, r, apierror.New(http.StatusGatewayTimeout, apierror.CodeGatewayTimeout, "the legacy backend did not respond in time"))
+	default:
+		gateway.Logger(ctx).WarnContext(ctx, "legacy backend request failed", "path", r.URL.Path, "error", err)
+		apierror.Write(w, r, apierror.New(http.StatusBadGateway, apierror.CodeBadGateway, "the legacy backend is unavailable"))
+	}
+}
@@ -0,0 +1,230 @@
+package proxy
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/headers"
+	"github.com/content-factory/go-gateway/internal/middleware"
+	"github.com/content-factory/go-gateway/internal/router"
+)
+
+func noop(h http.Handler) http.Handler { return h }
+
+// startGateway serves the proxy to backend behind the request ID middleware.
+func startGateway(t *testing.T, backend string) *httptest.Server {
+	t.Helper()
+	target, err := url.Parse(backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt := router.New()
+	Register(rt, "/legacy/", New(target, "/legacy/", 1<<20), noop)
+	srv := httptest.NewServer(middleware.RequestID(rt))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestForwardsRequest(t *testing.T) {
+	var got *http.Request
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		w.Header().Set(middleware.RequestIDHeader, "backend-id")
+		w.Header().Set("X-Backend", "yes")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer backend.Close()
+	gw := startGateway(t, backend.URL+"/api")
+
+	req, _ := http.NewRequest(http.MethodPost, gw.URL+"/legacy/brands/b1?full=1", strings.NewReader("{}"))
+	req.Header.Set(middleware.RequestIDHeader, "req-123")
+	req.Header.Set("X-Custom", "kept")
+	req.Header.Set("Connection", "X-Hop")
+	req.Header.Set("X-Hop", "dropped")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated || resp.Header.Get("X-Backend") != "yes" {
+		t.Errorf("response = %d %v", resp.StatusCode, resp.Header)
+	}
+	if ids := resp.Header.Values(middleware.RequestIDHeader); len(ids) != 1 || ids[0] != "req-123" {
+		t.Errorf("response X-Request-ID = %q, want only the gateway's", ids)
+	}
+	if got.Method != http.MethodPost || got.URL.Path != "/api/brands/b1" || got.URL.RawQuery != "full=1" {
+		t.Errorf("backend got %s %s", got.Method, got.URL)
+	}
+	for name, want := range map[string]string{
+		middleware.RequestIDHeader: "req-123",
+		"X-Custom":                 "kept",
+		"X-Hop":                    "",
+		"X-Forwarded-For":          "127.0.0.1",
+		"X-Forwarded-Proto":        "http",
+	} {
+		if v := got.Header.Get(name); v != want {
+			t.Errorf("backend %s = %q, want %q", name, v, want)
+		}
+	}
+}
+
+func TestHeaderRules(t *testing.T) {
+	var got http.Header
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header
+		w.Header().Set("X-Powered-By", "python")
+		w.Header().Set("X-Debug-Sql", "select 1")
+	}))
+	defer backend.Close()
+	target, _ := url.Parse(backend.URL)
+	rules, err := headers.New(
+		[]headers.Rule{{Action: headers.Remove, Name: "Authorization"}, {Action: headers.Set, Name: "X-Env", Value: "staging"}},
+		[]headers.Rule{{Action: headers.Remove, Name: "X-Powered-By"}, {Action: headers.Remove, Name: "X-Debug-*"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var authorized bool
+	// Authentication runs before the rules, and still sees the header.
+	authn := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorized = r.Header.Get("Authorization") == "Bearer t"
+			next.ServeHTTP(w, r)
+		})
+	}
+	rt := router.New()
+	Register(rt, "/legacy/", New(target, "/legacy/", 1<<20), middleware.NewChain(authn, rules.Middleware).Then)
+	gw := httptest.NewServer(rt)
+	defer gw.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, gw.URL+"/legacy/brands", nil)
+	req.Header.Set("Authorization", "Bearer t")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if !authorized {
+		t.Error("authentication did not see Authorization")
+	}
+	if got.Get("Authorization") != "" || got.Get("X-Env") != "staging" {
+		t.Errorf("backend got headers %v", got)
+	}
+	if resp.Header.Get("X-Powered-By") != "" || resp.Header.Get("X-Debug-Sql") != "" {
+		t.Errorf("response headers %v", resp.Header)
+	}
+}
+
+func TestStreamsResponse(t *testing.T) {
+	release := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: first\n\n"))
+		w.(http.Flusher).Flush()
+		<-release
+		w.Write([]byte("data: second\n\n"))
+	}))
+	defer backend.Close()
+	defer close(release)
+	gw := startGateway(t, backend.URL)
+
+	resp, err := http.Get(gw.URL + "/legacy/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	line := make(chan string, 1)
+	go func() {
+		s, _ := bufio.NewReader(resp.Body).ReadString('\n')
+		line <- s
+	}()
+	select {
+	case s := <-line:
+		if s != "data: first\n" {
+			t.Errorf("first line = %q", s)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("first event not flushed before the response finished")
+	}
+}
+
+func TestUnreachableBackendIs502(t *testing.T) {
+	backend := httptest.NewServer(http.NotFoundHandler())
+	backend.Close()
+	gw := startGateway(t, backend.URL)
+
+	resp, err := http.Get(gw.URL + "/legacy/brands")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway || resp.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("response = %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	var body apierror.Envelope
+	json.NewDecoder(resp.Body).Decode(&body)
+	if body.Error.Code != "bad_gateway" {
+		t.Errorf("error = %q", body.Error.Code)
+	}
+}
+
+func TestOversizedResponseIs502(t *testing.T) {
+	big := strings.Repeat("x", 2<<20)
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sized":
+			w.Header().Set("Content-Length", strconv.Itoa(len(big)))
+		case "/download":
+			w.Header().Set("Content-Disposition", `attachment; filename="big.bin"`)
+		case "/small":
+			w.Write([]byte("ok"))
+			w.(http.Flusher).Flush()
+			return
+		}
+		// Flushing first leaves the length unknown until the end.
+		w.(http.Flusher).Flush()
+		w.Write([]byte(big))
+	}))
+	defer backend.Close()
+	gw := startGateway(t, backend.URL)
+
+	before := oversized.With().Value()
+	for path, want := range map[string]int{
+		"/legacy/sized":    http.StatusBadGateway,
+		"/legacy/chunked":  http.StatusBadGateway,
+		"/legacy/download": http.StatusOK,
+		"/legacy/small":    http.StatusOK,
+	} {
+		resp, err := http.Get(gw.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("%s: status = %d, want %d", path, resp.StatusCode, want)
+			continue
+		}
+		var env apierror.Envelope
+		switch {
+		case want == http.StatusBadGateway && (json.Unmarshal(body, &env) != nil || env.Error.Code != apierror.CodeBadGateway):
+			t.Errorf("%s: body = %.100s", path, body)
+		case path == "/legacy/download" && len(body) != len(big):
+			t.Errorf("%s: read %d bytes, want %d", path, len(body), len(big))
+		case path == "/legacy/small" && string(body) != "ok":
+			t.Errorf("%s: body = %q", path, body)
+		}
+	}
+	if got := oversized.With().Value() - before; got != 2 {
+		t.Errorf("oversized counter rose by %v, want 2", got)
+	}
+}
@@ -0,0 +1,82 @@
+package queue
+
+import (
+	"context"
+	"sync"
+)
+
+// Memory is a Queue held in process memory. Its jobs are lost if the
+// gateway stops before Workers drain them.
+type Memory struct {
+	jobs chan *Job
+
+	// mu guards the waiting jobs by ID and the counts of jobs put on and
+	// taken off the queue; a waiting job's Seq less taken is its position.
+	mu       sync.Mutex
+	enqueued int64
+	taken    int64
+	waiting  map[string]*Job
+}
+
+// NewMemory returns an empty Memory queue holding up to capacity jobs.
+func NewMemory(capacity int) *Memory {
+	if capacity < 1 {
+		capacity = DefaultCapacity
+	}
+	return &Memory{jobs: make(chan *Job, capacity), waiting: make(map[string]*Job)}
+}
+
+// Enqueue implements Queue.
+func (m *Memory) Enqueue(ctx context.Context, job *Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// The send is under mu so that jobs enter the channel in seq order.
+	select {
+	case m.jobs <- job:
+		m.enqueued++
+		job.Seq = m.enqueued
+		m.waiting[job.Request.JobID] = job
+		return nil
+	default:
+		return ErrFull
+	}
+}
+
+// Dequeue implements Queue.
+func (m *Memory) Dequeue(ctx context.Context) (*Job, error) {
+	select {
+	case job := <-m.jobs:
+		m.mu.Lock()
+		m.taken++
+		delete(m.waiting, job.Request.JobID)
+		m.mu.Unlock()
+		return job, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Len implements Queue.
+func (m *Memory) Len(ctx context.Context) (int, error) {
+	return len(m.jobs), nil
+}
+
+// Position implements Queue.
+func (m *Memory) Position(ctx context.Context, jobID string) (*Place, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job := m.waiting[jobID]
+	if job == nil {
+		return nil, ErrNotQueued
+	}
+	p := placeOf(job)
+	p.Position = int(job.Seq - m.taken)
+	return &p, nil
+}
+
+// Taken implements Queue.
+func (m *Memory) Taken(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.taken, nil
+}
@@ -0,0 +1,121 @@
+// Package queue buffers content jobs between the submission handlers and
+// the content service, so a saturated backend delays jobs rather than
+// failing them. Handlers Enqueue; Workers drain the queue to the backend
+// at a bounded concurrency.
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/backend"
+	"github.com/content-factory/go-gateway/internal/metrics"
+)
+
+// Defaults used by the config package.
+const (
+	DefaultCapacity = 1000
+	DefaultWorkers  = 8
+	DefaultRedisKey = "gateway:content_jobs"
+	// DefaultHighWater is the fraction of its capacity at which a queue
+	// counts as saturated.
+	DefaultHighWater = 0.9
+)
+
+// Backends the config package can select.
+const (
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+)
+
+var (
+	// ErrFull is returned by Enqueue when the queue holds its capacity.
+	ErrFull = errors.New("queue: full")
+	// ErrNotQueued is returned by Position for a job that is not waiting
+	// in the queue, because it has been taken off it or was never on it.
+	ErrNotQueued = errors.New("queue: job not queued")
+)
+
+// Job is a content submission waiting for the content service. Tenant is
+// the ID of the tenant it was submitted for, if any, whose content service
+// it goes to.
+type Job struct {
+	Request    backend.CreateContentRequest `json:"request"`
+	Tenant     string                       `json:"tenant,omitempty"`
+	EnqueuedAt time.Time                    `json:"enqueued_at"`
+	// Seq is the job's number in the order of enqueueing, set by queues
+	// that need it to find the job's place.
+	Seq int64 `json:"seq,omitempty"`
+}
+
+// Place is where a waiting job is in a queue, with who it was submitted
+// by, so it is only shown to them.
+type Place struct {
+	// Position is 1 for the job the workers take next.
+	Position   int       `json:"-"`
+	OwnerID    string    `json:"owner_id"`
+	Tenant     string    `json:"tenant,omitempty"`
+	EnqueuedAt time.Time `json:"enqueued_at"`
+}
+
+func placeOf(job *Job) Place {
+	return Place{OwnerID: job.Request.OwnerID, Tenant: job.Tenant, EnqueuedAt: job.EnqueuedAt}
+}
+
+// Queue is a bounded FIFO of jobs. Implementations are safe for
+// concurrent use.
+type Queue interface {
+	// Enqueue adds job at the back, failing with ErrFull if there is no
+	// room.
+	Enqueue(ctx context.Context, job *Job) error
+	// Dequeue removes the job at the front, waiting for one until ctx is
+	// done.
+	Dequeue(ctx context.Context) (*Job, error)
+	// Len reports how many jobs are waiting.
+	Len(ctx context.Context) (int, error)
+	// Position reports where the job with the given ID waits, failing
+	// with ErrNotQueued if it is not waiting. It can lag a Dequeue by a
+	// moment.
+	Position(ctx context.Context, jobID string) (*Place, error)
+	// Taken reports how many jobs have been dequeued in all, by every
+	// gateway sharing the queue. Only its changes are meaningful.
+	Taken(ctx context.Context) (int64, error)
+}
+
+var (
+	depth = metrics.NewGaugeVec("gateway_queue_depth",
+		"Content jobs waiting in the submission queue.")
+	rejected = metrics.NewCounterVec("gateway_queue_rejected_total",
+		"Content submissions refused because the queue was full.")
+	processed = metrics.NewCounterVec("gateway_queue_jobs_total",
+		"Queued content jobs handed to the content service, by result: submitted or failed.",
+		"result")
+)
+
+// Enqueue adds job to q, counting refusals because it is full.
+func Enqueue(ctx context.Context, q Queue, job *Job) error {
+	err := q.Enqueue(ctx, job)
+	if errors.Is(err, ErrFull) {
+		rejected.With().Inc()
+	}
+	return err
+}
+
+// SaturationProbe returns a health probe that fails while q holds at
+// least highWater of its capacity, so submissions can be turned away
+// before Enqueue starts refusing them one by one.
+func SaturationProbe(q Queue, capacity int, highWater float64) func(context.Context) error {
+	limit := max(1, int(float64(capacity)*highWater))
+	return func(ctx context.Context) error {
+		n, err := q.Len(ctx)
+		if err != nil {
+			return err
+		}
+		if n >= limit {
+			return fmt.Errorf("queue is saturated (%d of %d jobs)", n, capacity)
+		}
+		return nil
+	}
+}
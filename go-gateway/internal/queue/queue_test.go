@@ -0,0 +1,510 @@
+package queue
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/backend"
+	"github.com/content-factory/go-gateway/internal/rpc"
+)
+
+func job(id string) *Job {
+	return &Job{Request: backend.CreateContentRequest{JobID: id, Topic: "t", Format: "video", OwnerID: "u-" + id}, Tenant: "acme"}
+}
+
+// testQueue checks the Queue contract on q, which holds capacity jobs.
+func testQueue(t *testing.T, q Queue, capacity int) {
+	ctx := context.Background()
+	for i := range capacity {
+		if err := q.Enqueue(ctx, job("j"+strconv.Itoa(i))); err != nil {
+			t.Fatalf("Enqueue %d: %v", i, err)
+		}
+	}
+	if err := q.Enqueue(ctx, job("over")); !errors.Is(err, ErrFull) {
+		t.Fatalf("Enqueue over capacity = %v, want ErrFull", err)
+	}
+	if n, err := q.Len(ctx); err != nil || n != capacity {
+		t.Fatalf("Len = %d, %v; want %d", n, err, capacity)
+	}
+	if p, err := q.Position(ctx, "j1"); err != nil || p.Position != 2 || p.OwnerID != "u-j1" || p.Tenant != "acme" {
+		t.Fatalf("Position of the second job = %+v, %v", p, err)
+	}
+	if _, err := q.Position(ctx, "over"); !errors.Is(err, ErrNotQueued) {
+		t.Fatalf("Position of a refused job = %v, want ErrNotQueued", err)
+	}
+	taken, err := q.Taken(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range capacity {
+		got, err := q.Dequeue(ctx)
+		if err != nil {
+			t.Fatalf("Dequeue %d: %v", i, err)
+		}
+		if want := "j" + strconv.Itoa(i); got.Request.JobID != want {
+			t.Fatalf("Dequeue %d = %s, want %s", i, got.Request.JobID, want)
+		}
+		if _, err := q.Position(ctx, got.Request.JobID); !errors.Is(err, ErrNotQueued) {
+			t.Fatalf("Position of a dequeued job = %v, want ErrNotQueued", err)
+		}
+		if i == 0 {
+			if p, err := q.Position(ctx, "j1"); err != nil || p.Position != 1 {
+				t.Fatalf("Position of the next job = %+v, %v", p, err)
+			}
+		}
+	}
+	if n, err := q.Taken(ctx); err != nil || n != taken+int64(capacity) {
+		t.Fatalf("Taken = %d, %v; want %d", n, err, taken+int64(capacity))
+	}
+
+	// A job put back goes to the back, and is numbered again.
+	first, second := job("a"), job("b")
+	q.Enqueue(ctx, first)
+	q.Enqueue(ctx, second)
+	got, _ := q.Dequeue(ctx)
+	q.Enqueue(ctx, got)
+	if p, err := q.Position(ctx, "a"); err != nil || p.Position != 2 {
+		t.Fatalf("Position of a requeued job = %+v, %v", p, err)
+	}
+	q.Dequeue(ctx)
+	q.Dequeue(ctx)
+
+	ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := q.Dequeue(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Dequeue on empty queue = %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	testQueue(t, NewMemory(3), 3)
+}
+
+func TestRedis(t *testing.T) {
+	srv := newFakeRedis(t, "secret")
+	q, err := NewRedis("redis://:secret@"+srv.addr+"/2", "jobs", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	testQueue(t, q, 3)
+	if srv.db.Load() != 2 {
+		t.Errorf("SELECT %d, want 2", srv.db.Load())
+	}
+
+	// The enqueued form carries the whole request.
+	in := &Job{Request: backend.CreateContentRequest{JobID: "j", Topic: "t", Format: "audio", OwnerID: "u1", Options: map[string]any{"voice": "calm"}}, EnqueuedAt: time.Unix(1700000000, 0).UTC()}
+	if err := q.Enqueue(context.Background(), in); err != nil {
+		t.Fatal(err)
+	}
+	out, err := q.Dequeue(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Request.OwnerID != "u1" || out.Request.Options["voice"] != "calm" || !out.EnqueuedAt.Equal(in.EnqueuedAt) {
+		t.Errorf("round trip = %+v, want %+v", out, in)
+	}
+}
+
+func TestRedisWrongPassword(t *testing.T) {
+	srv := newFakeRedis(t, "secret")
+	q, err := NewRedis("redis://:nope@"+srv.addr, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Len(context.Background()); err == nil || !strings.Contains(err.Error(), "AUTH") {
+		t.Errorf("Len = %v, want an AUTH error", err)
+	}
+}
+
+func TestNewRedisRejectsBadURL(t *testing.T) {
+	for _, u := range []string{"", "http://redis:6379", "redis://redis:6379/zero"} {
+		if _, err := NewRedis(u, "", 0); err == nil {
+			t.Errorf("NewRedis(%q) succeeded", u)
+		}
+	}
+}
+
+// submitter records the jobs handed to it, failing each with the errors
+// queued in fail first.
+type submitter struct {
+	mu   sync.Mutex
+	fail map[string][]error
+	done []string
+	sent chan string
+}
+
+func newSubmitter() *submitter {
+	return &submitter{fail: map[string][]error{}, sent: make(chan string, 100)}
+}
+
+func (s *submitter) Submit(ctx context.Context, req *backend.CreateContentRequest) (*backend.CreateContentResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if errs := s.fail[req.JobID]; len(errs) > 0 {
+		s.fail[req.JobID] = errs[1:]
+		return nil, errs[0]
+	}
+	s.done = append(s.done, req.JobID)
+	s.sent <- req.JobID
+	return &backend.CreateContentResponse{Status: "queued"}, nil
+}
+
+func (s *submitter) wait(t *testing.T, want string) {
+	t.Helper()
+	select {
+	case got := <-s.sent:
+		if got != want {
+			t.Fatalf("submitted %s, want %s", got, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("%s was never submitted", want)
+	}
+}
+
+func TestWorkersRetrySaturatedBackend(t *testing.T) {
+	q := NewMemory(10)
+	sub := newSubmitter()
+	sub.fail["busy"] = []error{rpc.Errorf(rpc.ResourceExhausted, "busy"), rpc.Errorf(rpc.Unavailable, "down")}
+	sub.fail["bad"] = []error{rpc.Errorf(rpc.InvalidArgument, "bad")}
+	w := &Workers{Queue: q, Submit: sub.Submit, Concurrency: 1, MaxBackoff: 10 * time.Millisecond}
+	w.Start()
+	defer w.Shutdown(context.Background(), false)
+
+	for _, id := range []string{"busy", "bad", "ok"} {
+		if err := q.Enqueue(context.Background(), job(id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// With one worker, busy holds it until the backend takes it; bad is
+	// dropped without a retry.
+	sub.wait(t, "busy")
+	sub.wait(t, "ok")
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if len(sub.fail["bad"]) != 0 || len(sub.done) != 2 {
+		t.Errorf("submitted %v, remaining failures %v", sub.done, sub.fail)
+	}
+}
+
+func TestWorkersShutdownDrains(t *testing.T) {
+	q := NewMemory(10)
+	var submitted atomic.Int32
+	w := &Workers{Queue: q, Concurrency: 2, Submit: func(ctx context.Context, req *backend.CreateContentRequest) (*backend.CreateContentResponse, error) {
+		time.Sleep(5 * time.Millisecond)
+		submitted.Add(1)
+		return &backend.CreateContentResponse{}, nil
+	}}
+	for i := range 6 {
+		q.Enqueue(context.Background(), job(strconv.Itoa(i)))
+	}
+	w.Start()
+	if err := w.Shutdown(context.Background(), true); err != nil {
+		t.Fatal(err)
+	}
+	if n := submitted.Load(); n != 6 {
+		t.Errorf("submitted %d jobs before shutdown, want 6", n)
+	}
+}
+
+func TestWorkersShutdownRequeuesJobInHand(t *testing.T) {
+	q := NewMemory(10)
+	started := make(chan struct{})
+	w := &Workers{Queue: q, Concurrency: 1, MaxBackoff: time.Hour, Submit: func(ctx context.Context, req *backend.CreateContentRequest) (*backend.CreateContentResponse, error) {
+		close(started)
+		return nil, rpc.Errorf(rpc.Unavailable, "down")
+	}}
+	q.Enqueue(context.Background(), job("held"))
+	w.Start()
+	<-started
+
+	if err := w.Shutdown(context.Background(), false); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	got, err := q.Dequeue(ctx)
+	if err != nil || got.Request.JobID != "held" {
+		t.Fatalf("queue after shutdown = %v, %v; want the held job back", got, err)
+	}
+}
+
+// fakeRedis serves the few commands Redis uses, with its scripts
+// emulated, on a loopback port.
+type fakeRedis struct {
+	addr     string
+	password string
+	db       atomic.Int32
+
+	mu       sync.Mutex
+	lists    map[string][]string
+	counters map[string]int64
+	hashes   map[string]map[string]string
+	push     chan struct{}
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	s := &fakeRedis{addr: l.Addr().String(), password: password, lists: map[string][]string{},
+		counters: map[string]int64{}, hashes: map[string]map[string]string{}, push: make(chan struct{}, 1)}
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { c.Close() })
+			go s.serve(c)
+		}
+	}()
+	return s
+}
+
+func (s *fakeRedis) serve(c net.Conn) {
+	// Commands are read ahead so that, like Redis, a BLPOP is abandoned
+	// when its client goes away rather than popping a job for nobody.
+	commands := make(chan []string)
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		r := bufio.NewReader(c)
+		for {
+			args, err := readCommand(r)
+			if err != nil {
+				return
+			}
+			commands <- args
+		}
+	}()
+	authed := s.password == ""
+	for {
+		var args []string
+		select {
+		case args = <-commands:
+		case <-gone:
+			return
+		}
+		if args[0] != "AUTH" && !authed {
+			io.WriteString(c, "-NOAUTH Authentication required.\r\n")
+			continue
+		}
+		switch args[0] {
+		case "AUTH":
+			if args[1] != s.password {
+				io.WriteString(c, "-WRONGPASS invalid password\r\n")
+				continue
+			}
+			authed = true
+			io.WriteString(c, "+OK\r\n")
+		case "SELECT":
+			db, _ := strconv.Atoi(args[1])
+			s.db.Store(int32(db))
+			io.WriteString(c, "+OK\r\n")
+		case "LLEN":
+			s.mu.Lock()
+			fmt.Fprintf(c, ":%d\r\n", len(s.lists[args[1]]))
+			s.mu.Unlock()
+		case "EVAL":
+			io.WriteString(c, s.eval(args[1], args[3:]))
+		case "GET":
+			s.mu.Lock()
+			if n, ok := s.counters[args[1]]; ok {
+				v := strconv.FormatInt(n, 10)
+				fmt.Fprintf(c, "$%d\r\n%s\r\n", len(v), v)
+			} else {
+				io.WriteString(c, "$-1\r\n")
+			}
+			s.mu.Unlock()
+		case "BLPOP":
+			secs, _ := strconv.Atoi(args[2])
+			if v, ok := s.pop(args[1], time.Duration(secs)*time.Second, gone); ok {
+				fmt.Fprintf(c, "*2\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n", len(args[1]), args[1], len(v), v)
+			} else {
+				io.WriteString(c, "*-1\r\n")
+			}
+		default:
+			fmt.Fprintf(c, "-ERR unknown command '%s'\r\n", args[0])
+		}
+	}
+}
+
+// eval runs one of the queue's scripts on args, its keys then its
+// arguments, and returns the encoded reply.
+func (s *fakeRedis) eval(script string, args []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch script {
+	case enqueueScript:
+		list, seqKey, places, payload, jobID, place := args[0], args[1], args[2], args[3], args[5], args[6]
+		capacity, _ := strconv.Atoi(args[4])
+		if len(s.lists[list]) >= capacity {
+			return ":-1\r\n"
+		}
+		s.counters[seqKey]++
+		seq := strconv.FormatInt(s.counters[seqKey], 10)
+		s.hset(places, jobID, seq+" "+place)
+		s.lists[list] = append(s.lists[list], `{"seq":`+seq+","+payload[1:])
+		select {
+		case s.push <- struct{}{}:
+		default:
+		}
+		return fmt.Sprintf(":%d\r\n", len(s.lists[list]))
+	case positionScript:
+		list, places, jobID := args[0], args[1], args[2]
+		place, ok := s.hashes[places][jobID]
+		if !ok {
+			return "$-1\r\n"
+		}
+		seqText, info, _ := strings.Cut(place, " ")
+		seq, _ := strconv.ParseInt(seqText, 10, 64)
+		var first int64
+		l := s.lists[list]
+		if len(l) > 0 {
+			if _, err := fmt.Sscanf(l[0], `{"seq":%d,`, &first); err != nil {
+				return "$-1\r\n"
+			}
+		}
+		if len(l) == 0 || seq < first {
+			delete(s.hashes[places], jobID)
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("*2\r\n:%d\r\n$%d\r\n%s\r\n", seq-first+1, len(info), info)
+	case takenScript:
+		places, taken, jobID := args[0], args[1], args[2]
+		delete(s.hashes[places], jobID)
+		s.counters[taken]++
+		return fmt.Sprintf(":%d\r\n", s.counters[taken])
+	}
+	return "-ERR unknown script\r\n"
+}
+
+func (s *fakeRedis) hset(key, field, value string) {
+	if s.hashes[key] == nil {
+		s.hashes[key] = map[string]string{}
+	}
+	s.hashes[key][field] = value
+}
+
+// pop removes the head of the list key, waiting up to wait for one or
+// until the client is gone.
+func (s *fakeRedis) pop(key string, wait time.Duration, gone <-chan struct{}) (string, bool) {
+	deadline := time.After(wait)
+	for {
+		select {
+		case <-gone:
+			return "", false
+		default:
+		}
+		s.mu.Lock()
+		if list := s.lists[key]; len(list) > 0 {
+			s.lists[key] = list[1:]
+			s.mu.Unlock()
+			return list[0], true
+		}
+		s.mu.Unlock()
+		select {
+		case <-s.push:
+		case <-time.After(10 * time.Millisecond):
+		case <-deadline:
+			return "", false
+		case <-gone:
+			return "", false
+		}
+	}
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil || line[0] != '*' {
+		return nil, fmt.Errorf("bad command %q", line)
+	}
+	args := make([]string, n)
+	for i := range args {
+		if line, err = r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func TestThroughput(t *testing.T) {
+	var tp Throughput
+	if _, ok := tp.Wait(3); ok {
+		t.Fatal("Wait before any sample = ok")
+	}
+	start := time.Unix(1700000000, 0)
+	at := func(s int) time.Time { return start.Add(time.Duration(s) * time.Second) }
+	tp.Observe(100, 10, at(0))
+	tp.Observe(102, 10, at(1))
+	if rate, ok := tp.Rate(); !ok || rate != 2 {
+		t.Fatalf("first rate = %v, %v; want 2", rate, ok)
+	}
+	if wait, ok := tp.Wait(3); !ok || wait != 1500*time.Millisecond {
+		t.Errorf("Wait(3) = %v, %v; want 1.5s", wait, ok)
+	}
+
+	// The average moves towards a new rate gradually.
+	tp.Observe(106, 10, at(2))
+	rate, _ := tp.Rate()
+	if rate <= 2 || rate >= 4 {
+		t.Errorf("rate after a faster second = %v, want between 2 and 4", rate)
+	}
+
+	// An idle, empty queue leaves the rate alone...
+	tp.Observe(108, 0, at(3))
+	rate, _ = tp.Rate()
+	tp.Observe(108, 0, at(60))
+	if idle, _ := tp.Rate(); idle != rate {
+		t.Errorf("rate after idling = %v, want %v", idle, rate)
+	}
+	// ...but one whose jobs are not leaving slows it down.
+	tp.Observe(108, 5, at(61))
+	tp.Observe(108, 5, at(121))
+	if stuck, _ := tp.Rate(); stuck >= rate {
+		t.Errorf("rate while stuck = %v, want below %v", stuck, rate)
+	}
+}
+
+func TestSaturationProbe(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemory(10)
+	probe := SaturationProbe(q, 10, 0.5)
+	for i := range 5 {
+		if err := probe(ctx); err != nil {
+			t.Fatalf("probe with %d jobs = %v, want nil", i, err)
+		}
+		q.Enqueue(ctx, job("j"+strconv.Itoa(i)))
+	}
+	if err := probe(ctx); err == nil || err.Error() != "queue is saturated (5 of 10 jobs)" {
+		t.Fatalf("probe at the high-water mark = %v", err)
+	}
+	q.Dequeue(ctx)
+	if err := probe(ctx); err != nil {
+		t.Fatalf("probe after draining = %v, want nil", err)
+	}
+}
@@ -0,0 +1,355 @@
+package queue
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	redisDialTimeout = 5 * time.Second
+	// redisPollInterval is how long one BLPOP waits before Dequeue
+	// issues the next.
+	redisPollInterval = time.Second
+	// redisIOTimeout bounds a command's round trip beyond any wait it
+	// asks the server for.
+	redisIOTimeout = 5 * time.Second
+	redisMaxIdle   = 16
+)
+
+// enqueueScript appends the job ARGV[1] to the list KEYS[1] unless it
+// already holds ARGV[2] entries, atomically, so gateways sharing the list
+// cannot overfill it together. The job's JSON gets a seq field first, the
+// next number of the counter KEYS[2], and the hash KEYS[3] files the job
+// ID ARGV[3] under that number and its Place, ARGV[4].
+const enqueueScript = `if redis.call('LLEN', KEYS[1]) >= tonumber(ARGV[2]) then return -1 end
+local seq = redis.call('INCR', KEYS[2])
+redis.call('HSET', KEYS[3], ARGV[3], seq .. ' ' .. ARGV[4])
+return redis.call('RPUSH', KEYS[1], '{"seq":' .. seq .. ',' .. string.sub(ARGV[1], 2))`
+
+// positionScript returns the position and Place of the job ID ARGV[1]:
+// how far its seq in the hash KEYS[2] is from that of the head of the
+// list KEYS[1]. A job behind the head, or in an emptied list, has been
+// taken, and its entry is dropped. An entry without a seq at the head,
+// put there by an older gateway, hides every position until it is taken.
+const positionScript = `local place = redis.call('HGET', KEYS[2], ARGV[1])
+if not place then return false end
+local seq, info = string.match(place, '^(%d+) (.*)$')
+local head = redis.call('LINDEX', KEYS[1], 0)
+local first = head and tonumber(string.match(head, '^{"seq":(%d+),'))
+if head and not first then return false end
+if not head or tonumber(seq) < first then
+  redis.call('HDEL', KEYS[2], ARGV[1])
+  return false
+end
+return {tonumber(seq) - first + 1, info}`
+
+// takenScript drops the job ID ARGV[1] from the hash KEYS[1] and counts
+// it as taken in KEYS[2].
+const takenScript = `redis.call('HDEL', KEYS[1], ARGV[1])
+return redis.call('INCR', KEYS[2])`
+
+// Redis is a Queue kept in a Redis list, shared by every gateway using
+// the same key and surviving their restarts.
+type Redis struct {
+	addr     string
+	password string
+	db       int
+	key      string
+	capacity int
+
+	mu   sync.Mutex
+	idle []*redisConn
+}
+
+// NewRedis returns a queue of up to capacity jobs in the list key on the
+// server at rawURL, such as redis://:password@redis:6379/0. The jobs'
+// places are kept beside the list, in key:seq, key:places and key:taken.
+// Connections are opened as needed.
+func NewRedis(rawURL, key string, capacity int) (*Redis, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "redis" || u.Host == "" {
+		return nil, fmt.Errorf("queue: %q is not a redis:// URL", rawURL)
+	}
+	q := &Redis{addr: u.Host, key: key, capacity: capacity}
+	if q.key == "" {
+		q.key = DefaultRedisKey
+	}
+	if q.capacity < 1 {
+		q.capacity = DefaultCapacity
+	}
+	if u.Port() == "" {
+		q.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		q.password, _ = u.User.Password()
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		if q.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("queue: database %q in %q is not a number", db, rawURL)
+		}
+	}
+	return q, nil
+}
+
+// Enqueue implements Queue.
+func (q *Redis) Enqueue(ctx context.Context, job *Job) error {
+	// The script numbers the job; a requeued one must not keep its old
+	// number.
+	unnumbered := *job
+	unnumbered.Seq = 0
+	payload, err := json.Marshal(&unnumbered)
+	if err != nil {
+		return err
+	}
+	place, err := json.Marshal(placeOf(job))
+	if err != nil {
+		return err
+	}
+	n, err := q.do(ctx, 0, "EVAL", enqueueScript, "3", q.key, q.key+":seq", q.key+":places",
+		string(payload), strconv.Itoa(q.capacity), job.Request.JobID, string(place))
+	if err != nil {
+		return err
+	}
+	if n, _ := n.(int64); n < 0 {
+		return ErrFull
+	}
+	return nil
+}
+
+// Dequeue implements Queue.
+func (q *Redis) Dequeue(ctx context.Context) (*Job, error) {
+	wait := strconv.Itoa(int(redisPollInterval / time.Second))
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		reply, err := q.do(ctx, redisPollInterval, "BLPOP", q.key, wait)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, err
+		}
+		// A nil reply means the wait ended with the list still empty.
+		pair, _ := reply.([]any)
+		if len(pair) != 2 {
+			continue
+		}
+		payload, _ := pair[1].(string)
+		var job Job
+		if err := json.Unmarshal([]byte(payload), &job); err != nil {
+			return nil, fmt.Errorf("queue: undecodable job in %s: %w", q.key, err)
+		}
+		// The job is out of the list either way, so a failure here only
+		// leaves its entry for Position to drop, and Taken a job short. It
+		// is recorded even if ctx is done now, as the job was taken.
+		if _, err := q.do(context.WithoutCancel(ctx), 0, "EVAL", takenScript, "2", q.key+":places", q.key+":taken", job.Request.JobID); err != nil {
+			slog.Warn("failed to record a job taken from the queue", "job_id", job.Request.JobID, "error", err)
+		}
+		return &job, nil
+	}
+}
+
+// Len implements Queue.
+func (q *Redis) Len(ctx context.Context) (int, error) {
+	n, err := q.do(ctx, 0, "LLEN", q.key)
+	if err != nil {
+		return 0, err
+	}
+	v, _ := n.(int64)
+	return int(v), nil
+}
+
+// Position implements Queue.
+func (q *Redis) Position(ctx context.Context, jobID string) (*Place, error) {
+	reply, err := q.do(ctx, 0, "EVAL", positionScript, "2", q.key, q.key+":places", jobID)
+	if err != nil {
+		return nil, err
+	}
+	pair, _ := reply.([]any)
+	if len(pair) != 2 {
+		return nil, ErrNotQueued
+	}
+	position, _ := pair[0].(int64)
+	info, _ := pair[1].(string)
+	var p Place
+	if err := json.Unmarshal([]byte(info), &p); err != nil {
+		return nil, fmt.Errorf("queue: undecodable place of job %s: %w", jobID, err)
+	}
+	p.Position = int(position)
+	return &p, nil
+}
+
+// Taken implements Queue.
+func (q *Redis) Taken(ctx context.Context) (int64, error) {
+	reply, err := q.do(ctx, 0, "GET", q.key+":taken")
+	if err != nil {
+		return 0, err
+	}
+	// The counter is missing until the first job is taken.
+	s, _ := reply.(string)
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+// do runs one command on an idle or new connection. wait is how long the
+// command itself may block on the server.
+func (q *Redis) do(ctx context.Context, wait time.Duration, args ...string) (any, error) {
+	c, err := q.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.nc.SetDeadline(time.Now().Add(wait + redisIOTimeout))
+	// A done ctx interrupts the command, blocking or not.
+	stop := context.AfterFunc(ctx, func() { c.nc.SetDeadline(time.Now()) })
+	reply, err := c.do(args...)
+	interrupted := !stop()
+	var rerr redisError
+	if err != nil && !errors.As(err, &rerr) || interrupted {
+		// The connection is in an unknown state.
+		c.nc.Close()
+		if interrupted {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("queue: redis %s: %w", args[0], err)
+	}
+	q.release(c)
+	if err != nil {
+		return nil, fmt.Errorf("queue: redis %s: %w", args[0], err)
+	}
+	return reply, nil
+}
+
+func (q *Redis) conn(ctx context.Context) (*redisConn, error) {
+	q.mu.Lock()
+	if n := len(q.idle); n > 0 {
+		c := q.idle[n-1]
+		q.idle = q.idle[:n-1]
+		q.mu.Unlock()
+		return c, nil
+	}
+	q.mu.Unlock()
+
+	d := net.Dialer{Timeout: redisDialTimeout}
+	nc, err := d.DialContext(ctx, "tcp", q.addr)
+	if err != nil {
+		return nil, fmt.Errorf("queue: redis: %w", err)
+	}
+	c := &redisConn{nc: nc, r: bufio.NewReader(nc)}
+	nc.SetDeadline(time.Now().Add(redisIOTimeout))
+	if q.password != "" {
+		if _, err := c.do("AUTH", q.password); err != nil {
+			nc.Close()
+			return nil, fmt.Errorf("queue: redis AUTH: %w", err)
+		}
+	}
+	if q.db != 0 {
+		if _, err := c.do("SELECT", strconv.Itoa(q.db)); err != nil {
+			nc.Close()
+			return nil, fmt.Errorf("queue: redis SELECT: %w", err)
+		}
+	}
+	return c, nil
+}
+
+func (q *Redis) release(c *redisConn) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.idle) >= redisMaxIdle {
+		c.nc.Close()
+		return
+	}
+	q.idle = append(q.idle, c)
+}
+
+// Close closes the idle connections.
+func (q *Redis) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, c := range q.idle {
+		c.nc.Close()
+	}
+	q.idle = nil
+	return nil
+}
+
+// redisConn speaks just enough RESP for the commands above.
+type redisConn struct {
+	nc net.Conn
+	r  *bufio.Reader
+}
+
+// redisError is an error reply; the connection stays usable after one.
+type redisError string
+
+func (e redisError) Error() string { return string(e) }
+
+func (c *redisConn) do(args ...string) (any, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if _, err := io.WriteString(c.nc, b.String()); err != nil {
+		return nil, err
+	}
+	return c.read()
+}
+
+// read parses one reply: a string, an int64, nil, a []any or a
+// redisError.
+func (c *redisConn) read() (any, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("empty reply")
+	}
+	body := line[1:]
+	switch line[0] {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, redisError(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = c.read(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("unexpected reply %q", line)
+}
@@ -0,0 +1,70 @@
+package queue
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// throughputWindow is the time constant of Throughput's moving average:
+// a change in the rate is mostly reflected after this long.
+const throughputWindow = time.Minute
+
+// Throughput is a moving average of how many jobs per second leave a
+// queue, fed by Workers or anything else calling Observe. Time the queue
+// spends empty and idle does not count, so the rate is that of a busy
+// queue. It is safe for concurrent use, and its zero value is ready to
+// use.
+type Throughput struct {
+	mu      sync.Mutex
+	rate    float64
+	known   bool
+	primed  bool
+	taken   int64
+	waiting int
+	at      time.Time
+}
+
+// Observe records the queue's Taken count, and the jobs still waiting on
+// it, as of at.
+func (t *Throughput) Observe(taken int64, waiting int, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	prev, prevWaiting, prevAt, primed := t.taken, t.waiting, t.at, t.primed
+	t.taken, t.waiting, t.at, t.primed = taken, waiting, at, true
+	dt := at.Sub(prevAt).Seconds()
+	// A counter that went back, as after the Redis keys were removed,
+	// starts the count again.
+	if !primed || dt <= 0 || taken < prev {
+		return
+	}
+	if taken == prev && prevWaiting == 0 {
+		return
+	}
+	instant := float64(taken-prev) / dt
+	if !t.known {
+		t.rate, t.known = instant, true
+		return
+	}
+	alpha := 1 - math.Exp(-dt/throughputWindow.Seconds())
+	t.rate += alpha * (instant - t.rate)
+}
+
+// Rate returns the average number of jobs leaving the queue per second,
+// and false until any have been seen to.
+func (t *Throughput) Rate() (float64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.rate, t.known
+}
+
+// Wait estimates how long a job at position has until it leaves the
+// queue, at the current rate. It returns false while the rate is unknown
+// or no jobs are leaving.
+func (t *Throughput) Wait(position int) (time.Duration, bool) {
+	rate, ok := t.Rate()
+	if !ok || rate <= 0 {
+		return 0, false
+	}
+	return time.Duration(float64(position) / rate * float64(time.Second)), true
+}
@@ -0,0 +1,211 @@
+package queue
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/backend"
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/rpc"
+)
+
+// Defaults for the Workers fields left zero.
+const (
+	DefaultSubmitTimeout = 30 * time.Second
+	DefaultMaxBackoff    = 5 * time.Second
+	defaultInitialWait   = 100 * time.Millisecond
+	depthInterval        = time.Second
+)
+
+// Workers drain a Queue to the content service. A job the service turns
+// away because it is saturated, with Unavailable or ResourceExhausted, is
+// retried with backoff while its worker waits, which slows the whole pool
+// down as the service asks; any other failure drops the job and is
+// logged.
+type Workers struct {
+	Queue Queue
+	// Submit sends one job, typically (*backend.Client).CreateContent.
+	Submit func(ctx context.Context, req *backend.CreateContentRequest) (*backend.CreateContentResponse, error)
+	// Concurrency is how many jobs are submitted at once; zero means
+	// DefaultWorkers.
+	Concurrency int
+	// SubmitTimeout bounds each attempt; zero means DefaultSubmitTimeout.
+	SubmitTimeout time.Duration
+	// MaxBackoff caps the wait between attempts at a job; zero means
+	// DefaultMaxBackoff.
+	MaxBackoff time.Duration
+	// Throughput, if set, is fed how fast jobs leave the queue, sampled
+	// with its depth.
+	Throughput *Throughput
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Start launches the workers and the sampler of the queue depth metric
+// and Throughput.
+func (w *Workers) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	n := w.Concurrency
+	if n < 1 {
+		n = DefaultWorkers
+	}
+	for range n {
+		w.wg.Go(func() { w.run(ctx) })
+	}
+	w.wg.Go(func() { w.sampleDepth(ctx) })
+}
+
+// Shutdown stops the workers. With drain set it first waits until the
+// queue is empty, for queues whose jobs would otherwise be lost. It then
+// waits for the jobs in hand to settle, or until ctx is done.
+func (w *Workers) Shutdown(ctx context.Context, drain bool) error {
+	if drain {
+		t := time.NewTicker(50 * time.Millisecond)
+		defer t.Stop()
+		for {
+			if n, err := w.Queue.Len(ctx); err != nil || n == 0 {
+				break
+			}
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				w.cancel()
+				return ctx.Err()
+			}
+		}
+	}
+	w.cancel()
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (w *Workers) run(ctx context.Context) {
+	for {
+		job, err := w.Queue.Dequeue(ctx)
+		if ctx.Err() != nil {
+			if job != nil {
+				w.requeue(job)
+			}
+			return
+		}
+		if err != nil {
+			slog.Warn("failed to take a job from the queue", "error", err)
+			// Do not spin against a queue that keeps failing.
+			if !sleep(ctx, time.Second) {
+				return
+			}
+			continue
+		}
+		w.process(ctx, job)
+	}
+}
+
+// process submits job until it is accepted, fails for good, or the
+// workers are stopped, in which case it goes back on the queue.
+func (w *Workers) process(ctx context.Context, job *Job) {
+	wait := defaultInitialWait
+	maxWait := w.MaxBackoff
+	if maxWait <= 0 {
+		maxWait = DefaultMaxBackoff
+	}
+	for {
+		err := w.submit(ctx, job)
+		switch {
+		case err == nil:
+			processed.With("submitted").Inc()
+			return
+		case ctx.Err() != nil:
+			w.requeue(job)
+			return
+		case !saturated(err):
+			processed.With("failed").Inc()
+			slog.Warn("queued content job was rejected", "job_id", job.Request.JobID, "error", err)
+			return
+		}
+		if !sleep(ctx, wait) {
+			w.requeue(job)
+			return
+		}
+		wait = min(wait*2, maxWait)
+	}
+}
+
+func (w *Workers) submit(ctx context.Context, job *Job) error {
+	timeout := w.SubmitTimeout
+	if timeout <= 0 {
+		timeout = DefaultSubmitTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if job.Tenant != "" {
+		// Only the ID matters to the backend the job is routed to.
+		ctx = gateway.WithTenant(ctx, &gateway.Tenant{ID: job.Tenant})
+	}
+	_, err := w.Submit(ctx, &job.Request)
+	return err
+}
+
+// requeue puts back a job the workers were stopped holding. It goes to
+// the back of the queue; if there is no room it is lost.
+func (w *Workers) requeue(job *Job) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := w.Queue.Enqueue(ctx, job); err != nil {
+		slog.Warn("dropped a queued content job at shutdown", "job_id", job.Request.JobID, "error", err)
+	}
+}
+
+func (w *Workers) sampleDepth(ctx context.Context) {
+	t := time.NewTicker(depthInterval)
+	defer t.Stop()
+	gauge := depth.With()
+	for {
+		if n, err := w.Queue.Len(ctx); err == nil {
+			gauge.Set(float64(n))
+			if w.Throughput != nil {
+				if taken, err := w.Queue.Taken(ctx); err == nil {
+					w.Throughput.Observe(taken, n, time.Now())
+				}
+			}
+		}
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// saturated reports whether err means the content service did not take
+// the job because it is busy or down, so submitting it again is safe.
+func saturated(err error) bool {
+	switch rpc.CodeOf(err) {
+	case rpc.Unavailable, rpc.ResourceExhausted:
+		return true
+	}
+	return false
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
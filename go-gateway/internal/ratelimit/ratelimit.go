@@ -0,0 +1,331 @@
+// Package ratelimit enforces a token-bucket request limit per client.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/clientip"
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/routeconf"
+	"github.com/content-factory/go-gateway/internal/router"
+)
+
+// Defaults used by the config package.
+const (
+	DefaultRPS     = 10
+	DefaultBurst   = 20
+	DefaultIdleTTL = 10 * time.Minute
+)
+
+// Config sets the per-client limit.
+type Config struct {
+	RPS   float64
+	Burst int
+	// IdleTTL is how long a client's bucket is kept after its last request.
+	IdleTTL time.Duration
+	// Routes gives routes their own limit, see package routeconf.
+	Routes routeconf.Table
+}
+
+// MaxOverrideTTL bounds how long an Override lasts, so a forgotten one
+// does not lift a client's limit for good.
+const MaxOverrideTTL = 24 * time.Hour
+
+type client struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// Override is a limit set for one client by an operator, in place of every
+// limit it would otherwise get, until Expires.
+type Override struct {
+	RPS     float64   `json:"rps"`
+	Burst   int       `json:"burst"`
+	Expires time.Time `json:"expires_at"`
+}
+
+// Bucket is the state of one of a client's token buckets. Route is empty
+// for the client's own bucket and names the route of a route override's.
+type Bucket struct {
+	Route    string    `json:"route,omitempty"`
+	RPS      float64   `json:"rps"`
+	Burst    int       `json:"burst"`
+	Tokens   float64   `json:"tokens"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// ClientState is what the Limiter holds for one client key.
+type ClientState struct {
+	Key      string    `json:"key"`
+	Buckets  []Bucket  `json:"buckets"`
+	Override *Override `json:"override,omitempty"`
+}
+
+// Limiter tracks one token bucket per client. Clients are identified by
+// their JWT subject when the request is authenticated and by IP otherwise.
+type Limiter struct {
+	cfg atomic.Pointer[Config]
+	now func() time.Time
+
+	mu        sync.Mutex
+	clients   map[string]*client // by bucket key, see Middleware
+	overrides map[string]Override
+}
+
+// New returns a Limiter. Call Sweep in a goroutine to evict idle clients.
+func New(cfg Config) *Limiter {
+	l := &Limiter{now: time.Now, clients: make(map[string]*client), overrides: make(map[string]Override)}
+	l.SetConfig(cfg)
+	return l
+}
+
+// SetConfig replaces the limit while serving. Each client's bucket takes
+// the new rate and burst on its next request.
+func (l *Limiter) SetConfig(cfg Config) {
+	if cfg.IdleTTL <= 0 {
+		cfg.IdleTTL = DefaultIdleTTL
+	}
+	l.cfg.Store(&cfg)
+}
+
+// Middleware rejects requests over the client's limit with 429. Install it
+// after authentication so requests are keyed by user rather than IP, and
+// inside the router so route overrides apply. A client's Override beats
+// a route override, which beats an API key's own limit, which beats its
+// tenant's, which beats the global one. Each request takes one token, or
+// the weight its route override or Weigh gives it.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg := l.cfg.Load()
+		rps, burst := cfg.RPS, cfg.Burst
+		id := Key(r)
+		key := id
+		if t := gateway.TenantFromContext(r.Context()); t != nil && t.RPS > 0 && t.Burst > 0 {
+			rps, burst = t.RPS, t.Burst
+		}
+		if k := gateway.APIKeyFromContext(r.Context()); k != nil && k.RPS > 0 && k.Burst > 0 {
+			rps, burst = k.RPS, k.Burst
+		}
+		weight := WeightOf(r.Context())
+		if o, ok := cfg.Routes.Lookup(r); ok {
+			if o.RPS > 0 {
+				// A separate bucket, so polling one route cannot use up
+				// the client's budget for the others.
+				rps, burst = o.RPS, o.Burst
+				key += " " + routeconf.Key(r.Method, router.Pattern(r))
+			}
+			if o.Weight > 0 {
+				weight = o.Weight
+			}
+		}
+		lim, burst := l.limiterFor(id, key, rps, burst)
+		now := l.now()
+		// A request weighing more than the whole burst could never pass.
+		res := lim.ReserveN(now, min(weight, burst))
+		delay := res.DelayFrom(now)
+
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(burst))
+		if !res.OK() || delay > 0 {
+			res.CancelAt(now)
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			apierror.Write(w, r, apierror.RateLimited("too many requests, retry later"))
+			return
+		}
+		remaining := int(math.Floor(lim.TokensAt(now)))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(max(remaining, 0)))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// limiterFor returns bucket key of client id, with the limit rps and
+// burst unless an override for the client is in force, and its burst.
+func (l *Limiter) limiterFor(id, key string, rps float64, burst int) (*rate.Limiter, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if o, ok := l.overrides[id]; ok {
+		if l.now().Before(o.Expires) {
+			rps, burst = o.RPS, o.Burst
+		} else {
+			delete(l.overrides, id)
+		}
+	}
+	c, ok := l.clients[key]
+	if !ok {
+		c = &client{limiter: rate.NewLimiter(rate.Limit(rps), burst)}
+		l.clients[key] = c
+	} else if c.limiter.Burst() != burst || c.limiter.Limit() != rate.Limit(rps) {
+		// The key's limit was reconfigured.
+		c.limiter.SetLimitAt(l.now(), rate.Limit(rps))
+		c.limiter.SetBurstAt(l.now(), burst)
+	}
+	c.lastSeen = l.now()
+	return c.limiter, burst
+}
+
+// State returns the buckets and override of the client key, as Key gives
+// it, and whether the Limiter holds any.
+func (l *Limiter) State(key string) (ClientState, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	st := ClientState{Key: key, Buckets: []Bucket{}}
+	if o, ok := l.overrides[key]; ok && now.Before(o.Expires) {
+		st.Override = &o
+	}
+	for k, c := range l.clients {
+		route, ok := routeOf(k, key)
+		if !ok {
+			continue
+		}
+		st.Buckets = append(st.Buckets, Bucket{
+			Route:    route,
+			RPS:      float64(c.limiter.Limit()),
+			Burst:    c.limiter.Burst(),
+			Tokens:   c.limiter.TokensAt(now),
+			LastSeen: c.lastSeen,
+		})
+	}
+	slices.SortFunc(st.Buckets, func(a, b Bucket) int { return strings.Compare(a.Route, b.Route) })
+	return st, len(st.Buckets) > 0 || st.Override != nil
+}
+
+// Reset empties the client's buckets, so its next requests start with a
+// full burst, and reports whether it had any.
+func (l *Limiter) Reset(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.reset(key)
+}
+
+func (l *Limiter) reset(key string) bool {
+	found := false
+	for k := range l.clients {
+		if _, ok := routeOf(k, key); ok {
+			delete(l.clients, k)
+			found = true
+		}
+	}
+	return found
+}
+
+// SetOverride gives the client key the limit rps and burst for ttl, at
+// most MaxOverrideTTL, after which its usual limits resume. The client
+// starts again with a full burst. It returns the override set.
+func (l *Limiter) SetOverride(key string, rps float64, burst int, ttl time.Duration) Override {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	o := Override{RPS: rps, Burst: burst, Expires: l.now().Add(min(ttl, MaxOverrideTTL))}
+	l.overrides[key] = o
+	l.reset(key)
+	return o
+}
+
+// ClearOverride ends the client's override early and reports whether it had
+// one in force.
+func (l *Limiter) ClearOverride(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	o, ok := l.overrides[key]
+	delete(l.overrides, key)
+	return ok && l.now().Before(o.Expires)
+}
+
+// routeOf reports whether bucket key k belongs to client id, returning
+// the route of a route override's bucket.
+func routeOf(k, id string) (string, bool) {
+	if k == id {
+		return "", true
+	}
+	route, ok := strings.CutPrefix(k, id+" ")
+	return route, ok
+}
+
+// Sweep evicts clients idle for longer than the configured TTL every
+// interval until ctx is cancelled.
+func (l *Limiter) Sweep(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			l.evictIdle()
+		}
+	}
+}
+
+func (l *Limiter) evictIdle() {
+	now := l.now()
+	cutoff := now.Add(-l.cfg.Load().IdleTTL)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for key, c := range l.clients {
+		if c.lastSeen.Before(cutoff) {
+			delete(l.clients, key)
+		}
+	}
+	for key, o := range l.overrides {
+		if !now.Before(o.Expires) {
+			delete(l.overrides, key)
+		}
+	}
+}
+
+// Key identifies the client behind r: "apikey:<id>" for API-key requests,
+// "user:<sub>" for JWT-authenticated requests, "ip:<addr>" otherwise, with
+// the address clientip resolved. Requests acting for a tenant have the
+// key prefixed with "tenant:<id>:", so each tenant's clients have their
+// own buckets.
+func Key(r *http.Request) string {
+	var prefix string
+	if id := gateway.TenantID(r.Context()); id != "" {
+		prefix = TenantPrefix + id + ":"
+	}
+	if k := gateway.APIKeyFromContext(r.Context()); k != nil {
+		return prefix + "apikey:" + k.ID
+	}
+	if claims := gateway.ClaimsFromContext(r.Context()); claims != nil && claims.Subject != "" {
+		return prefix + "user:" + claims.Subject
+	}
+	return prefix + "ip:" + clientip.Of(r)
+}
+
+// TenantPrefix starts the keys Key gives requests acting for a tenant.
+const TenantPrefix = "tenant:"
+
+type weightKey struct{}
+
+// Weigh makes the requests through it take weight tokens of the client's
+// limit, instead of one, unless a route override sets a weight of its own.
+// It must run before the Limiter's Middleware.
+func Weigh(weight int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if weight <= 1 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), weightKey{}, weight)))
+		})
+	}
+}
+
+// WeightOf returns the weight Weigh gave the request of ctx, 1 by default.
+func WeightOf(ctx context.Context) int {
+	if w, ok := ctx.Value(weightKey{}).(int); ok {
+		return w
+	}
+	return 1
+}
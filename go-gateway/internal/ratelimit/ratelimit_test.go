@@ -0,0 +1,292 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/routeconf"
+	"github.com/content-factory/go-gateway/internal/router"
+)
+
+func newTestLimiter(cfg Config) (*Limiter, *time.Time) {
+	now := time.Unix(1_700_000_000, 0)
+	l := New(cfg)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func TestLimitAndHeaders(t *testing.T) {
+	l, _ := newTestLimiter(Config{RPS: 1, Burst: 2})
+	h := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	var codes []int
+	var last *httptest.ResponseRecorder
+	for i := 0; i < 3; i++ {
+		last = httptest.NewRecorder()
+		h.ServeHTTP(last, httptest.NewRequest(http.MethodGet, "/", nil))
+		codes = append(codes, last.Code)
+	}
+	if codes[0] != 200 || codes[1] != 200 || codes[2] != http.StatusTooManyRequests {
+		t.Fatalf("codes = %v, want [200 200 429]", codes)
+	}
+	if got := last.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q, want 1", got)
+	}
+	if got := last.Header().Get("X-RateLimit-Remaining"); got != "0" {
+		t.Errorf("X-RateLimit-Remaining = %q, want 0", got)
+	}
+}
+
+func TestKeyPrefersAuthenticatedUser(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("X-Forwarded-For", "203.0.113.9, 10.0.0.1")
+
+	if got := Key(r); got != "ip:10.0.0.1" {
+		t.Errorf("unresolved key = %q", got)
+	}
+	r = r.WithContext(gateway.WithClientIP(r.Context(), "203.0.113.9"))
+	if got := Key(r); got != "ip:203.0.113.9" {
+		t.Errorf("resolved key = %q", got)
+	}
+	r = r.WithContext(gateway.WithClaims(r.Context(), &gateway.Claims{Subject: "u1"}))
+	if got := Key(r); got != "user:u1" {
+		t.Errorf("authenticated key = %q", got)
+	}
+	r = r.WithContext(gateway.WithAPIKey(r.Context(), &gateway.APIKey{ID: "partner"}))
+	if got := Key(r); got != "apikey:partner" {
+		t.Errorf("API key = %q", got)
+	}
+	r = r.WithContext(gateway.WithTenant(r.Context(), &gateway.Tenant{ID: "acme"}))
+	if got := Key(r); got != "tenant:acme:apikey:partner" {
+		t.Errorf("tenant's API key = %q", got)
+	}
+}
+
+func TestTenantLimit(t *testing.T) {
+	l, _ := newTestLimiter(Config{RPS: 1, Burst: 1})
+	h := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	acme := gateway.WithTenant(context.Background(), &gateway.Tenant{ID: "acme", RPS: 1, Burst: 3})
+	serve := func(ctx context.Context) int {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+		return rec.Code
+	}
+
+	var codes []int
+	for i := 0; i < 4; i++ {
+		codes = append(codes, serve(acme))
+	}
+	if codes[2] != http.StatusOK || codes[3] != http.StatusTooManyRequests {
+		t.Errorf("codes = %v, want burst of 3 then 429", codes)
+	}
+	// The same client acting for another tenant has a bucket of its own.
+	if code := serve(gateway.WithTenant(context.Background(), &gateway.Tenant{ID: "globex"})); code != http.StatusOK {
+		t.Errorf("globex: status = %d, want 200", code)
+	}
+	// An API key's own limit beats its tenant's.
+	keyed := gateway.WithAPIKey(acme, &gateway.APIKey{ID: "bulk", RPS: 1, Burst: 1})
+	if first, second := serve(keyed), serve(keyed); first != http.StatusOK || second != http.StatusTooManyRequests {
+		t.Errorf("API key codes = %d, %d, want burst of 1 then 429", first, second)
+	}
+}
+
+func TestAPIKeyLimitOverride(t *testing.T) {
+	l, _ := newTestLimiter(Config{RPS: 1, Burst: 1})
+	h := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ctx := gateway.WithAPIKey(context.Background(), &gateway.APIKey{ID: "bulk", RPS: 1, Burst: 3})
+
+	var codes []int
+	for i := 0; i < 4; i++ {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+		codes = append(codes, rec.Code)
+	}
+	if codes[2] != http.StatusOK || codes[3] != http.StatusTooManyRequests {
+		t.Errorf("codes = %v, want burst of 3 then 429", codes)
+	}
+}
+
+func TestWeight(t *testing.T) {
+	l, _ := newTestLimiter(Config{RPS: 1, Burst: 10, Routes: routeconf.Table{
+		"POST /cheap": {Weight: 1},
+	}})
+	rt := router.New()
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	rt.Handle(http.MethodPost, "/heavy", Weigh(4)(l.Middleware(ok)))
+	rt.Handle(http.MethodPost, "/cheap", Weigh(4)(l.Middleware(ok)))
+	rt.Handle(http.MethodPost, "/huge", Weigh(50)(l.Middleware(ok)))
+	serve := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		rt.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, nil))
+		return rec
+	}
+
+	// 10 tokens: two heavy requests leave 2, too few for a third.
+	if rec := serve("/heavy"); rec.Code != http.StatusOK || rec.Header().Get("X-RateLimit-Remaining") != "6" {
+		t.Fatalf("first heavy request: %d, remaining %s", rec.Code, rec.Header().Get("X-RateLimit-Remaining"))
+	}
+	serve("/heavy")
+	if rec := serve("/heavy"); rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "2" {
+		t.Errorf("third heavy request: %d, Retry-After %s", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	// The route override's weight beats Weigh's.
+	if serve("/cheap").Code != http.StatusOK || serve("/cheap").Code != http.StatusOK || serve("/cheap").Code != http.StatusTooManyRequests {
+		t.Error("route override weight not applied")
+	}
+
+	// A request weighing more than the burst takes the whole burst.
+	l.Reset("ip:192.0.2.1")
+	if serve("/huge").Code != http.StatusOK || serve("/cheap").Code != http.StatusTooManyRequests {
+		t.Error("request heavier than the burst not clamped to it")
+	}
+}
+
+func TestEvictIdle(t *testing.T) {
+	l, now := newTestLimiter(Config{RPS: 1, Burst: 1, IdleTTL: time.Minute})
+	l.limiterFor("a", "a", 1, 1)
+	*now = now.Add(2 * time.Minute)
+	l.limiterFor("b", "b", 1, 1)
+	l.evictIdle()
+	if _, ok := l.clients["a"]; ok {
+		t.Error("idle client not evicted")
+	}
+	if _, ok := l.clients["b"]; !ok {
+		t.Error("active client evicted")
+	}
+}
+
+func TestSetConfig(t *testing.T) {
+	l, _ := newTestLimiter(Config{RPS: 1, Burst: 1})
+	h := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	serve := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rec
+	}
+	if serve().Code != http.StatusOK || serve().Code != http.StatusTooManyRequests {
+		t.Fatal("burst of 1 not enforced")
+	}
+
+	l.SetConfig(Config{RPS: 1, Burst: 5})
+	if got := serve().Header().Get("X-RateLimit-Limit"); got != "5" {
+		t.Errorf("X-RateLimit-Limit = %q after SetConfig, want 5", got)
+	}
+}
+
+func TestOverride(t *testing.T) {
+	l, now := newTestLimiter(Config{RPS: 1, Burst: 1, IdleTTL: time.Minute})
+	h := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	serve := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rec
+	}
+	const key = "ip:192.0.2.1"
+	if serve().Code != http.StatusOK || serve().Code != http.StatusTooManyRequests {
+		t.Fatal("burst of 1 not enforced")
+	}
+
+	// The override applies from the next request, with a full burst.
+	o := l.SetOverride(key, 1, 3, 5*time.Minute)
+	if !o.Expires.Equal(now.Add(5 * time.Minute)) {
+		t.Errorf("expires = %v", o.Expires)
+	}
+	for i := range 3 {
+		if rec := serve(); rec.Code != http.StatusOK || rec.Header().Get("X-RateLimit-Limit") != "3" {
+			t.Fatalf("request %d under override: %d, limit %s", i, rec.Code, rec.Header().Get("X-RateLimit-Limit"))
+		}
+	}
+	if serve().Code != http.StatusTooManyRequests {
+		t.Error("override burst of 3 not enforced")
+	}
+
+	// The sweeper keeps an override in force while the client is idle.
+	*now = now.Add(2 * time.Minute)
+	l.evictIdle()
+	if st, ok := l.State(key); !ok || st.Override == nil || len(st.Buckets) != 0 {
+		t.Errorf("state after sweep = %+v, %v", st, ok)
+	}
+
+	// Once it expires the usual limit resumes.
+	*now = now.Add(4 * time.Minute)
+	if rec := serve(); rec.Header().Get("X-RateLimit-Limit") != "1" {
+		t.Errorf("X-RateLimit-Limit = %q after expiry, want 1", rec.Header().Get("X-RateLimit-Limit"))
+	}
+	if l.ClearOverride(key) {
+		t.Error("ClearOverride found an expired override")
+	}
+
+	if capped := l.SetOverride(key, 1, 1, 48*time.Hour); !capped.Expires.Equal(now.Add(MaxOverrideTTL)) {
+		t.Errorf("expires = %v, want capped at MaxOverrideTTL", capped.Expires)
+	}
+	if !l.ClearOverride(key) {
+		t.Error("ClearOverride did not find the override")
+	}
+}
+
+func TestStateAndReset(t *testing.T) {
+	l, _ := newTestLimiter(Config{RPS: 1, Burst: 2, Routes: routeconf.Table{
+		"GET /api/v1/jobs/{id}": {RPS: 5, Burst: 10},
+	}})
+	rt := router.New()
+	noop := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	rt.Handle(http.MethodGet, "/api/v1/jobs", l.Middleware(noop))
+	rt.Handle(http.MethodGet, "/api/v1/jobs/{id}", l.Middleware(noop))
+	ctx := gateway.WithClaims(context.Background(), &gateway.Claims{Subject: "u1"})
+	for _, path := range []string{"/api/v1/jobs", "/api/v1/jobs", "/api/v1/jobs/j1"} {
+		rt.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil).WithContext(ctx))
+	}
+
+	if _, ok := l.State("user:u2"); ok {
+		t.Error("state found for an unseen client")
+	}
+	st, ok := l.State("user:u1")
+	if !ok || len(st.Buckets) != 2 {
+		t.Fatalf("state = %+v, %v", st, ok)
+	}
+	if b := st.Buckets[0]; b.Route != "" || b.Burst != 2 || b.Tokens != 0 {
+		t.Errorf("client bucket = %+v", b)
+	}
+	if b := st.Buckets[1]; b.Route != "GET /api/v1/jobs/{id}" || b.RPS != 5 || b.Tokens != 9 {
+		t.Errorf("route bucket = %+v", b)
+	}
+
+	if !l.Reset("user:u1") || l.Reset("user:u1") {
+		t.Error("Reset did not report the buckets it emptied")
+	}
+	rec := httptest.NewRecorder()
+	rt.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/jobs", nil).WithContext(ctx))
+	if rec.Code != http.StatusOK || rec.Header().Get("X-RateLimit-Remaining") != "1" {
+		t.Errorf("after reset: %d, remaining %s", rec.Code, rec.Header().Get("X-RateLimit-Remaining"))
+	}
+}
+
+func TestOverrideWhileSweeping(t *testing.T) {
+	l := New(Config{RPS: 1, Burst: 1, IdleTTL: time.Nanosecond})
+	h := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go l.Sweep(ctx, time.Microsecond)
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Go(func() {
+			for range 200 {
+				h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+				l.SetOverride("ip:192.0.2.1", 100, 100, time.Minute)
+				l.State("ip:192.0.2.1")
+				l.Reset("ip:192.0.2.1")
+			}
+		})
+	}
+	wg.Wait()
+	if st, ok := l.State("ip:192.0.2.1"); !ok || st.Override == nil || st.Override.Burst != 100 {
+		t.Errorf("state = %+v, %v", st, ok)
+	}
+}
@@ -0,0 +1,306 @@
+package realtime
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/jobs"
+	"github.com/content-factory/go-gateway/internal/metrics"
+)
+
+const (
+	// DefaultClientBuffer is how many events a subscriber may fall behind
+	// before the hub drops it.
+	DefaultClientBuffer = 16
+)
+
+// OverflowPolicy is what the hub does when a subscriber's queue is full.
+// It is the event bus's, so one setting serves both.
+type OverflowPolicy = jobs.OverflowPolicy
+
+const (
+	// Disconnect closes the subscriber's channel, ending its stream.
+	Disconnect = jobs.Disconnect
+	// DropOldest discards the subscriber's oldest queued event to make
+	// room.
+	DropOldest = jobs.DropOldest
+)
+
+var droppedEvents = metrics.NewCounterVec("gateway_realtime_dropped_events_total",
+	"Job events not delivered to a subscriber that fell behind, by overflow policy.", "policy")
+
+type dropCounterKey struct{}
+
+// withDropCounter returns a copy of ctx whose subscriptions count the
+// events they miss in n.
+func withDropCounter(ctx context.Context, n *atomic.Int64) context.Context {
+	return context.WithValue(ctx, dropCounterKey{}, n)
+}
+
+// Hub fans job events out to many subscribers, such as several browser
+// tabs watching one job. It is itself a jobs.Source, so handlers use it in
+// place of the upstream, and reads every job's events from the bus of a
+// jobs.Feed, which follows each job with a single backend subscription.
+//
+// A job has a topic here while anyone is subscribed to it. The topic holds
+// the job in the feed, and gives the hold up after its last subscriber
+// leaves, so the job's stream is closed unless the feed follows it for
+// someone else. A subscriber joining late, or resuming after some event,
+// first gets the events it missed from the feed's history of the job. A
+// subscriber that falls more than ClientBuffer events behind is handled
+// by Overflow rather than holding the others up, so memory stays bounded
+// however slow a client is. A topic is forgotten at its job's terminal
+// event; subscribers to a job that has finished are served the feed's
+// replay of it.
+type Hub struct {
+	feed *jobs.Feed
+	// ClientBuffer is the per-subscriber queue length; zero means
+	// DefaultClientBuffer.
+	ClientBuffer int
+	// Overflow applies to subscribers whose queue is full; empty means
+	// Disconnect.
+	Overflow OverflowPolicy
+
+	conns *Registry
+
+	mu     sync.Mutex
+	topics map[string]*topic // by tenant and job
+}
+
+// topic is the shared state of one job's events.
+type topic struct {
+	key   string
+	ready chan struct{} // closed once the feed follows the job, or failed to
+	err   error         // set before ready is closed
+
+	// Guarded by Hub.mu.
+	release func() // gives up the feed's hold of the job, once it has one
+	subs    map[*subscriber]bool
+	waiting int // subscribers waiting for ready
+	ended   bool
+}
+
+type subscriber struct {
+	ch      chan jobs.Event
+	after   int64         // the subscriber has seen the events up to after
+	stop    func() bool   // releases the context.AfterFunc watching the client
+	dropped *atomic.Int64 // events the subscriber missed
+}
+
+// NewHub returns a Hub relaying the events of feed's bus.
+func NewHub(feed *jobs.Feed) *Hub {
+	h := &Hub{feed: feed, conns: NewRegistry(), topics: make(map[string]*topic)}
+	go h.relay(feed.Bus().Subscribe("realtime", 0, jobs.DropOldest).Events())
+	return h
+}
+
+// Connections returns the registry of the client streams reading from h.
+// Handlers built on h with NewWebSocketHandler or NewSSEHandler record
+// their streams in it.
+func (h *Hub) Connections() *Registry {
+	return h.conns
+}
+
+// Subscribe implements jobs.Source. A job nobody has subscribed to yet is
+// followed by the feed first; an error doing so is returned to each of
+// its subscribers. Subscribers acting for different tenants never share a
+// topic. Callers check that the subscriber may see the job first.
+func (h *Hub) Subscribe(ctx context.Context, jobID string, after int64) (<-chan jobs.Event, error) {
+	key := gateway.TenantID(ctx) + "\x00" + jobID
+	h.mu.Lock()
+	t, ok := h.topics[key]
+	if !ok {
+		t = &topic{key: key, ready: make(chan struct{}), subs: make(map[*subscriber]bool)}
+		h.topics[key] = t
+		// Following is detached from any one client, which may leave
+		// before the rest.
+		go h.follow(context.WithoutCancel(ctx), jobID, t)
+	}
+	t.waiting++
+	h.mu.Unlock()
+
+	select {
+	case <-t.ready:
+	case <-ctx.Done():
+		h.mu.Lock()
+		t.waiting--
+		h.leave(t)
+		h.mu.Unlock()
+		return nil, ctx.Err()
+	}
+
+	h.mu.Lock()
+	t.waiting--
+	if errors.Is(t.err, jobs.ErrFinished) {
+		h.mu.Unlock()
+		return h.feed.Replay(ctx, jobID, after)
+	}
+	if t.err != nil {
+		h.mu.Unlock()
+		return nil, t.err
+	}
+	// The feed publishes an event and adds it to the history together, and
+	// the relay delivers it only once the subscriber is attached, so
+	// between the backlog and the relay the subscriber misses nothing.
+	backlog, ok := h.feed.History(ctx, jobID, after)
+	if t.ended || !ok {
+		// The job finished while we waited.
+		h.leave(t)
+		h.mu.Unlock()
+		return h.feed.Replay(ctx, jobID, after)
+	}
+	defer h.mu.Unlock()
+	sub := &subscriber{ch: make(chan jobs.Event, h.clientBuffer()+len(backlog)), after: after}
+	if sub.dropped, _ = ctx.Value(dropCounterKey{}).(*atomic.Int64); sub.dropped == nil {
+		sub.dropped = new(atomic.Int64)
+	}
+	for _, ev := range backlog {
+		sub.ch <- ev
+		sub.after = ev.Seq
+	}
+	if n := len(backlog); n > 0 && backlog[n-1].Terminal() {
+		// The backlog is all there is.
+		close(sub.ch)
+		h.leave(t)
+		return sub.ch, nil
+	}
+	t.subs[sub] = true
+	sub.stop = context.AfterFunc(ctx, func() { h.unsubscribe(t, sub) })
+	return sub.ch, nil
+}
+
+// follow has the feed follow jobID with ctx for t.
+func (h *Hub) follow(ctx context.Context, jobID string, t *topic) {
+	release, err := h.feed.Follow(ctx, jobID)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	t.err, t.release = err, release
+	close(t.ready)
+	if err != nil {
+		h.removeTopic(t)
+		return
+	}
+	// The subscribers may all have left while the feed opened the stream.
+	h.leave(t)
+}
+
+// relay hands the events of the feed's bus to the subscribers of their
+// topics, until the channel is closed. Events of jobs without a topic are
+// no one's here.
+func (h *Hub) relay(events <-chan jobs.Event) {
+	for ev := range events {
+		h.mu.Lock()
+		t, ok := h.topics[ev.Tenant+"\x00"+ev.JobID]
+		if !ok {
+			h.mu.Unlock()
+			continue
+		}
+		for sub := range t.subs {
+			// Subscribers have seen the events up to their cursor already.
+			if ev.Seq > sub.after {
+				h.deliver(t, sub, ev)
+			}
+		}
+		if ev.Terminal() {
+			t.ended = true
+			for sub := range t.subs {
+				h.drop(t, sub)
+			}
+			h.close(t)
+		}
+		h.mu.Unlock()
+	}
+}
+
+// deliver queues ev for sub without blocking, applying the overflow
+// policy if sub is too slow to keep up. h.mu must be held.
+func (h *Hub) deliver(t *topic, sub *subscriber, ev jobs.Event) {
+	select {
+	case sub.ch <- ev:
+		return
+	default:
+	}
+	policy := h.overflow()
+	sub.dropped.Add(1)
+	droppedEvents.With(string(policy)).Inc()
+	if policy == Disconnect {
+		// Let it go so it cannot stall the rest.
+		h.drop(t, sub)
+		h.leave(t)
+		return
+	}
+	// Only the hub sends on sub.ch, and it holds h.mu, so once the oldest
+	// event is gone there is room for ev.
+	select {
+	case <-sub.ch:
+	default:
+	}
+	sub.ch <- ev
+}
+
+// unsubscribe removes a subscriber whose client went away.
+func (h *Hub) unsubscribe(t *topic, sub *subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !t.subs[sub] {
+		return
+	}
+	delete(t.subs, sub)
+	close(sub.ch)
+	h.leave(t)
+}
+
+// drop closes sub's channel and forgets it. h.mu must be held.
+func (h *Hub) drop(t *topic, sub *subscriber) {
+	delete(t.subs, sub)
+	sub.stop()
+	close(sub.ch)
+}
+
+// leave closes t if nobody is subscribed to it or waiting to be, once the
+// feed follows its job. h.mu must be held.
+func (h *Hub) leave(t *topic) {
+	if len(t.subs) == 0 && t.waiting == 0 && t.release != nil {
+		h.close(t)
+	}
+}
+
+// close forgets t and gives up its hold of the job. h.mu must be held.
+func (h *Hub) close(t *topic) {
+	h.removeTopic(t)
+	if t.release != nil {
+		t.release()
+	}
+}
+
+// removeTopic forgets t, unless a newer topic has replaced it. h.mu must
+// be held.
+func (h *Hub) removeTopic(t *topic) {
+	if h.topics[t.key] == t {
+		delete(h.topics, t.key)
+	}
+}
+
+// Subscriptions reports how many jobs the hub has subscribers to.
+func (h *Hub) Subscriptions() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.topics)
+}
+
+func (h *Hub) overflow() OverflowPolicy {
+	if h.Overflow == "" {
+		return Disconnect
+	}
+	return h.Overflow
+}
+
+func (h *Hub) clientBuffer() int {
+	if h.ClientBuffer <= 0 {
+		return DefaultClientBuffer
+	}
+	return h.ClientBuffer
+}
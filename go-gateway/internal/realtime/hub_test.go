@@ -0,0 +1,468 @@
+package realtime
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/jobs"
+)
+
+// feedSource is an upstream whose events the test pushes per job. It
+// counts subscriptions opened and still running, and records the tenants
+// they were opened for.
+type feedSource struct {
+	opens  atomic.Int32
+	active atomic.Int32
+	err    error
+
+	mu      sync.Mutex
+	feeds   map[string]chan jobs.Event
+	tenants []string
+}
+
+func newFeedSource() *feedSource {
+	return &feedSource{feeds: make(map[string]chan jobs.Event)}
+}
+
+func (s *feedSource) Subscribe(ctx context.Context, jobID string, after int64) (<-chan jobs.Event, error) {
+	s.opens.Add(1)
+	if s.err != nil {
+		return nil, s.err
+	}
+	feed := make(chan jobs.Event)
+	s.mu.Lock()
+	s.feeds[jobID] = feed
+	s.tenants = append(s.tenants, gateway.TenantID(ctx))
+	s.mu.Unlock()
+
+	s.active.Add(1)
+	out := make(chan jobs.Event)
+	go func() {
+		defer s.active.Add(-1)
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev := <-feed:
+				select {
+				case out <- ev:
+				case <-ctx.Done():
+					return
+				}
+				if ev.Terminal() {
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+// send delivers ev to the job's current upstream subscription.
+func (s *feedSource) send(t *testing.T, jobID string, ev jobs.Event) {
+	t.Helper()
+	s.mu.Lock()
+	feed := s.feeds[jobID]
+	s.mu.Unlock()
+	select {
+	case feed <- ev:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("upstream for %s not reading", jobID)
+	}
+}
+
+// newHub returns a Hub on a feed of src.
+func newHub(src jobs.Source) *Hub {
+	return NewHub(jobs.NewFeed(context.Background(), src, jobs.NewBus()))
+}
+
+// subscribers counts the subscribers of h's topics.
+func subscribers(h *Hub) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n := 0
+	for _, t := range h.topics {
+		n += len(t.subs)
+	}
+	return n
+}
+
+func recv(t *testing.T, ch <-chan jobs.Event) (jobs.Event, bool) {
+	t.Helper()
+	select {
+	case ev, ok := <-ch:
+		return ev, ok
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for event")
+		return jobs.Event{}, false
+	}
+}
+
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestHubSharesUpstream(t *testing.T) {
+	src := newFeedSource()
+	hub := newHub(src)
+	ctx := context.Background()
+
+	a, err := hub.Subscribe(ctx, "j1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := hub.Subscribe(ctx, "j1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := src.opens.Load(); n != 1 {
+		t.Fatalf("upstream opened %d times, want 1", n)
+	}
+
+	src.send(t, "j1", jobs.Event{Seq: 1, Stage: "drafting"})
+	src.send(t, "j1", jobs.Event{Seq: 2, Stage: jobs.StageDone})
+	for name, ch := range map[string]<-chan jobs.Event{"a": a, "b": b} {
+		for want := int64(1); want <= 2; want++ {
+			if ev, ok := recv(t, ch); !ok || ev.Seq != want {
+				t.Fatalf("%s: got %+v, %v; want seq %d", name, ev, ok, want)
+			}
+		}
+		if _, ok := recv(t, ch); ok {
+			t.Errorf("%s: channel open after terminal event", name)
+		}
+	}
+	eventually(t, "topic removal", func() bool { return hub.Subscriptions() == 0 })
+}
+
+func TestHubSkipsReplayedEvents(t *testing.T) {
+	src := newFeedSource()
+	hub := newHub(src)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Resuming after event 2 opens the upstream, which replays from 1.
+	resumed, err := hub.Subscribe(ctx, "j1", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fresh, err := hub.Subscribe(ctx, "j1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for seq := int64(1); seq <= 3; seq++ {
+		src.send(t, "j1", jobs.Event{Seq: seq, Stage: "drafting"})
+	}
+	if ev, _ := recv(t, resumed); ev.Seq != 3 {
+		t.Errorf("resumed subscriber got seq %d first, want 3", ev.Seq)
+	}
+	for want := int64(1); want <= 3; want++ {
+		if ev, _ := recv(t, fresh); ev.Seq != want {
+			t.Errorf("fresh subscriber got seq %d, want %d", ev.Seq, want)
+		}
+	}
+}
+
+func TestHubKeepsTenantsApart(t *testing.T) {
+	src := newFeedSource()
+	hub := newHub(src)
+	for _, id := range []string{"acme", "globex", "acme"} {
+		ctx, cancel := context.WithCancel(gateway.WithTenant(context.Background(), &gateway.Tenant{ID: id}))
+		defer cancel()
+		if _, err := hub.Subscribe(ctx, "j1", 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := src.opens.Load(); n != 2 {
+		t.Fatalf("upstream opened %d times, want once per tenant", n)
+	}
+	src.mu.Lock()
+	defer src.mu.Unlock()
+	if len(src.tenants) != 2 || src.tenants[0] == src.tenants[1] {
+		t.Errorf("upstream opened for tenants %q, want acme and globex", src.tenants)
+	}
+}
+
+func TestHubReplaysToLateSubscriber(t *testing.T) {
+	src := newFeedSource()
+	hub := newHub(src)
+	ctx := context.Background()
+
+	first, _ := hub.Subscribe(ctx, "j1", 0)
+	src.send(t, "j1", jobs.Event{Seq: 1, Stage: "drafting"})
+	src.send(t, "j1", jobs.Event{Seq: 2, Stage: "rendering"})
+	recv(t, first)
+	recv(t, first)
+
+	late, err := hub.Subscribe(ctx, "j1", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev, _ := recv(t, late); ev.Seq != 2 {
+		t.Fatalf("late subscriber got seq %d first, want 2", ev.Seq)
+	}
+	src.send(t, "j1", jobs.Event{Seq: 3, Stage: "rendering"})
+	if ev, _ := recv(t, late); ev.Seq != 3 {
+		t.Errorf("late subscriber got seq %d, want 3", ev.Seq)
+	}
+	if n := src.opens.Load(); n != 1 {
+		t.Errorf("upstream opened %d times", n)
+	}
+}
+
+func TestHubDropsSlowSubscriber(t *testing.T) {
+	src := newFeedSource()
+	hub := newHub(src)
+	hub.ClientBuffer = 1
+	ctx := context.Background()
+
+	slow, _ := hub.Subscribe(ctx, "j1", 0)
+	fast, _ := hub.Subscribe(ctx, "j1", 0)
+	for seq := int64(1); seq <= 3; seq++ {
+		src.send(t, "j1", jobs.Event{Seq: seq, Stage: "rendering"})
+		if ev, _ := recv(t, fast); ev.Seq != seq {
+			t.Fatalf("fast subscriber got seq %d, want %d", ev.Seq, seq)
+		}
+	}
+
+	if ev, ok := recv(t, slow); !ok || ev.Seq != 1 {
+		t.Fatalf("slow subscriber: %+v, %v; want its buffered event", ev, ok)
+	}
+	if _, ok := recv(t, slow); ok {
+		t.Error("slow subscriber not dropped")
+	}
+	if hub.Subscriptions() != 1 {
+		t.Error("dropping one subscriber tore down the topic")
+	}
+}
+
+func TestHubDropOldest(t *testing.T) {
+	src := newFeedSource()
+	hub := newHub(src)
+	hub.ClientBuffer = 2
+	hub.Overflow = DropOldest
+	var dropped atomic.Int64
+	ctx := withDropCounter(context.Background(), &dropped)
+
+	slow, _ := hub.Subscribe(ctx, "j1", 0)
+	fast, _ := hub.Subscribe(context.Background(), "j1", 0)
+	for seq := int64(1); seq <= 5; seq++ {
+		stage := "rendering"
+		if seq == 5 {
+			stage = jobs.StageDone
+		}
+		src.send(t, "j1", jobs.Event{Seq: seq, Stage: stage})
+		if ev, _ := recv(t, fast); ev.Seq != seq {
+			t.Fatalf("fast subscriber got seq %d, want %d", ev.Seq, seq)
+		}
+	}
+
+	var got []int64
+	for {
+		ev, ok := recv(t, slow)
+		if !ok {
+			break
+		}
+		got = append(got, ev.Seq)
+	}
+	if len(got) != 2 || got[0] != 4 || got[1] != 5 {
+		t.Errorf("slow subscriber got seqs %v, want [4 5] ending with the terminal event", got)
+	}
+	if n := dropped.Load(); n != 3 {
+		t.Errorf("dropped = %d, want 3", n)
+	}
+}
+
+func TestHubTearsDownAfterLastSubscriber(t *testing.T) {
+	src := newFeedSource()
+	hub := newHub(src)
+	ctxA, cancelA := context.WithCancel(context.Background())
+	ctxB, cancelB := context.WithCancel(context.Background())
+
+	a, _ := hub.Subscribe(ctxA, "j1", 0)
+	hub.Subscribe(ctxB, "j1", 0)
+
+	cancelA()
+	if _, ok := recv(t, a); ok {
+		t.Fatal("cancelled subscriber still open")
+	}
+	if src.active.Load() != 1 {
+		t.Fatal("upstream closed while a subscriber remains")
+	}
+
+	cancelB()
+	eventually(t, "upstream cancellation", func() bool { return src.active.Load() == 0 })
+	if hub.Subscriptions() != 0 {
+		t.Errorf("%d topics left", hub.Subscriptions())
+	}
+
+	// A new subscriber starts afresh.
+	hub.Subscribe(context.Background(), "j1", 0)
+	if n := src.opens.Load(); n != 2 {
+		t.Errorf("upstream opened %d times, want 2", n)
+	}
+}
+
+func TestHubSubscribeError(t *testing.T) {
+	src := newFeedSource()
+	src.err = errors.New("job not found")
+	hub := newHub(src)
+	if _, err := hub.Subscribe(context.Background(), "j1", 0); err != src.err {
+		t.Fatalf("err = %v", err)
+	}
+	if hub.Subscriptions() != 0 {
+		t.Error("failed topic kept")
+	}
+}
+
+func TestHubJoinsFollowedJob(t *testing.T) {
+	src := newFeedSource()
+	feed := jobs.NewFeed(context.Background(), src, jobs.NewBus())
+	hub := NewHub(feed)
+	ctx := gateway.WithTenant(context.Background(), &gateway.Tenant{ID: "acme"})
+
+	// The job is followed, as on its submission, before anyone subscribes.
+	if _, err := feed.Follow(ctx, "j1"); err != nil {
+		t.Fatal(err)
+	}
+	src.send(t, "j1", jobs.Event{Seq: 1, Stage: "drafting"})
+	eventually(t, "the event to be published", func() bool {
+		evs, _ := feed.History(ctx, "j1", 0)
+		return len(evs) == 1
+	})
+	if hub.Subscriptions() != 0 {
+		t.Fatal("topic made for a job without subscribers")
+	}
+
+	sub, cancel := context.WithCancel(ctx)
+	ch, err := hub.Subscribe(sub, "j1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev, _ := recv(t, ch); ev.Seq != 1 || ev.JobID != "j1" {
+		t.Fatalf("subscriber got %+v first, want seq 1", ev)
+	}
+	src.send(t, "j1", jobs.Event{Seq: 2, Stage: "drafting"})
+	if ev, _ := recv(t, ch); ev.Seq != 2 {
+		t.Fatalf("subscriber got %+v, want seq 2", ev)
+	}
+
+	// Leaving does not end the stream the job was followed with before.
+	cancel()
+	eventually(t, "topic removal", func() bool { return hub.Subscriptions() == 0 })
+	if n := src.opens.Load(); n != 1 || src.active.Load() != 1 {
+		t.Errorf("upstream opened %d times, %d active; want one still open", n, src.active.Load())
+	}
+}
+
+func TestHubPublishesToBus(t *testing.T) {
+	src := newFeedSource()
+	feed := jobs.NewFeed(context.Background(), src, jobs.NewBus())
+	hub := NewHub(feed)
+	all := feed.Bus().Subscribe("test", 8, jobs.Disconnect)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The bus gets a topic's events whether or not its subscribers read them.
+	if _, err := hub.Subscribe(ctx, "j1", 0); err != nil {
+		t.Fatal(err)
+	}
+	src.send(t, "j1", jobs.Event{Seq: 1, Stage: "drafting"})
+	src.send(t, "j1", jobs.Event{Seq: 2, Stage: jobs.StageDone})
+	for want := int64(1); want <= 2; want++ {
+		if ev, _ := recv(t, all.Events()); ev.JobID != "j1" || ev.Seq != want {
+			t.Fatalf("bus got %+v, want seq %d", ev, want)
+		}
+	}
+}
+
+func TestHubReplaysFinishedJob(t *testing.T) {
+	src := newLogSource()
+	src.append(jobs.Event{Stage: "drafting"})
+	src.append(jobs.Event{Stage: jobs.StageDone})
+	hub := newHub(src)
+
+	first, err := hub.Subscribe(context.Background(), "j1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range first {
+	}
+	eventually(t, "topic removal", func() bool { return hub.Subscriptions() == 0 })
+
+	late, err := hub.Subscribe(context.Background(), "j1", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev, ok := recv(t, late); !ok || ev.Seq != 2 || ev.Stage != jobs.StageDone {
+		t.Errorf("late subscriber got %+v, %v; want the terminal event replayed", ev, ok)
+	}
+}
+
+func TestHubConcurrentSubscribers(t *testing.T) {
+	src := newFeedSource()
+	hub := newHub(src)
+	hub.ClientBuffer = 4
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			jobID := "j" + strconv.Itoa(i%5)
+			for range 20 {
+				ctx, cancel := context.WithCancel(context.Background())
+				ch, err := hub.Subscribe(ctx, jobID, 0)
+				if err != nil {
+					t.Error(err)
+					cancel()
+					return
+				}
+				select {
+				case <-ch:
+				default:
+				}
+				cancel()
+				for range ch {
+				}
+			}
+		}()
+	}
+
+	stop := make(chan struct{})
+	feederDone := make(chan struct{})
+	go func() {
+		defer close(feederDone)
+		for seq := int64(1); ; seq++ {
+			for i := range 5 {
+				src.mu.Lock()
+				feed := src.feeds["j"+strconv.Itoa(i)]
+				src.mu.Unlock()
+				select {
+				case feed <- jobs.Event{Seq: seq, Stage: "rendering"}:
+				case <-stop:
+					return
+				default:
+				}
+			}
+		}
+	}()
+
+	wg.Wait()
+	close(stop)
+	<-feederDone
+	eventually(t, "all subscribers released", func() bool { return subscribers(hub) == 0 })
+}
@@ -0,0 +1,43 @@
+package realtime
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/audit"
+	"github.com/content-factory/go-gateway/internal/gateway"
+)
+
+// OwnerFunc returns the principal that owns a job, or the error looking
+// it up, such as rpc.NotFound for a job the backend does not know.
+type OwnerFunc func(ctx context.Context, jobID string) (string, error)
+
+// authorize checks that the caller of ctx owns jobID, before anything of
+// the job is streamed to it. A job owned by someone else gets the same
+// 404 as one that does not exist, as in a bulk status request, so
+// streams cannot tell which IDs are taken. Without owner every caller is
+// let through.
+func authorize(ctx context.Context, owner OwnerFunc, jobID string) *apierror.Error {
+	if owner == nil {
+		return nil
+	}
+	id, err := owner(ctx, jobID)
+	if err != nil {
+		e := apierror.FromRPC(err)
+		if e.Code == apierror.CodeNotFound {
+			e = jobNotFound()
+		}
+		return e
+	}
+	if claims := gateway.ClaimsFromContext(ctx); claims == nil || claims.Subject != id {
+		audit.Record(ctx, audit.ActionAuthorize, audit.OutcomeDenied,
+			slog.String("reason", "the job belongs to another user"), slog.String("job_id", jobID))
+		return jobNotFound()
+	}
+	return nil
+}
+
+func jobNotFound() *apierror.Error {
+	return apierror.NotFound("no job with this ID")
+}
@@ -0,0 +1,127 @@
+package realtime
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/jobs"
+	"github.com/content-factory/go-gateway/internal/respond"
+	"github.com/content-factory/go-gateway/internal/router"
+)
+
+const (
+	// DefaultPollTimeout is how long a long-poll waits for an event, well
+	// inside the 60s or so after which proxies give up on a quiet request.
+	DefaultPollTimeout = 25 * time.Second
+	// CursorParam is the query parameter a long-poll resumes after.
+	CursorParam = "since"
+	// CursorHeader carries the cursor to poll with next, on every answer.
+	CursorHeader = "X-Poll-Cursor"
+)
+
+// PollResponse is the answer to a long-poll that got an event. Cursor is
+// the event's sequence number, to pass as since on the next poll.
+type PollResponse struct {
+	Event  jobs.Event `json:"event"`
+	Cursor int64      `json:"cursor"`
+}
+
+// PollHandler serves GET /api/v1/jobs/{id}/poll?since={cursor}, the
+// long-polling fallback for clients whose proxies break both WebSocket and
+// SSE. Each request waits up to Timeout for the first event after since
+// and answers 200 with it, or 204 with the same cursor when none came, so
+// the client polls again at once. A client stops after a terminal event.
+//
+// A waiting poll holds a subscription, sharing the job's upstream with the
+// streams when Source is a Hub, which is released when it answers or its
+// client goes away. Between polls the feed's history, or the upstream's
+// replay from since, means no event is missed.
+type PollHandler struct {
+	Source  jobs.Source
+	Timeout time.Duration
+	// Owner, if set, looks up the owner of the job of each poll, and only
+	// its owner may poll it; other callers get 404.
+	Owner OwnerFunc
+	// Registry, if set, records each waiting poll.
+	Registry *Registry
+}
+
+// NewPollHandler returns a handler long-polling events from src. If src
+// is a Hub, waiting polls are recorded in its registry.
+func NewPollHandler(src jobs.Source) *PollHandler {
+	return &PollHandler{Source: src, Timeout: DefaultPollTimeout, Registry: registryOf(src)}
+}
+
+func (h *PollHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var since int64
+	if v := strings.TrimSpace(r.URL.Query().Get(CursorParam)); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.CodeInvalidCursor, CursorParam+" must be a non-negative integer"))
+			return
+		}
+		since = n
+	}
+
+	jobID := router.Param(r, "id")
+	ctx, closing, untrack, err := h.Registry.trackRequest(r.Context(), r, jobID, TransportLongPoll)
+	if err != nil {
+		refuse(w, r, err)
+		return
+	}
+	defer untrack()
+	if e := authorize(ctx, h.Owner, jobID); e != nil {
+		apierror.Write(w, r, e)
+		return
+	}
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = DefaultPollTimeout
+	}
+	// Ending the wait, however it ends, releases the subscription.
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	w.Header().Set("Cache-Control", "no-store")
+	events, err := h.Source.Subscribe(ctx, jobID, since)
+	if errors.Is(err, context.DeadlineExceeded) {
+		// The upstream took the whole timeout to open.
+		noEvent(w, since)
+		return
+	}
+	if err != nil {
+		if r.Context().Err() == nil {
+			apierror.Write(w, r, apierror.FromRPC(err))
+		}
+		return
+	}
+	select {
+	case ev, ok := <-events:
+		if !ok {
+			// The job's events ended before one after since.
+			noEvent(w, since)
+			return
+		}
+		w.Header().Set(CursorHeader, strconv.FormatInt(ev.Seq, 10))
+		respond.Write(w, r, http.StatusOK, PollResponse{Event: ev, Cursor: ev.Seq})
+	case <-closing:
+		// Closed by an operator or Drain: the client polls again, and is
+		// refused while this instance drains.
+		noEvent(w, since)
+	case <-ctx.Done():
+		if r.Context().Err() == nil {
+			noEvent(w, since)
+		}
+	}
+}
+
+// noEvent answers a poll that got no event after since.
+func noEvent(w http.ResponseWriter, since int64) {
+	w.Header().Set(CursorHeader, strconv.FormatInt(since, 10))
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,288 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/jobs"
+	"github.com/content-factory/go-gateway/internal/router"
+	"github.com/content-factory/go-gateway/internal/rpc"
+)
+
+// logSource is an upstream that, like the content service, replays a
+// job's events after the requested one before following new ones.
+type logSource struct {
+	active atomic.Int32
+	opens  atomic.Int32
+	err    error
+
+	mu      sync.Mutex
+	log     []jobs.Event
+	changed chan struct{} // closed and replaced on every append
+}
+
+func newLogSource() *logSource {
+	return &logSource{changed: make(chan struct{})}
+}
+
+func (s *logSource) append(ev jobs.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ev.Seq = int64(len(s.log) + 1)
+	s.log = append(s.log, ev)
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *logSource) Subscribe(ctx context.Context, jobID string, after int64) (<-chan jobs.Event, error) {
+	s.opens.Add(1)
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.active.Add(1)
+	out := make(chan jobs.Event)
+	go func() {
+		defer s.active.Add(-1)
+		defer close(out)
+		next := after
+		for {
+			s.mu.Lock()
+			pending := append([]jobs.Event(nil), s.log[min(next, int64(len(s.log))):]...)
+			changed := s.changed
+			s.mu.Unlock()
+			for _, ev := range pending {
+				ev.JobID = jobID
+				select {
+				case out <- ev:
+				case <-ctx.Done():
+					return
+				}
+				if ev.Terminal() {
+					return
+				}
+				next = ev.Seq
+			}
+			select {
+			case <-changed:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func startPollServer(t *testing.T, h *PollHandler) string {
+	t.Helper()
+	rt := router.New()
+	rt.Handle(http.MethodGet, "/api/v1/jobs/{id}/poll", h)
+	srv := httptest.NewServer(rt)
+	t.Cleanup(srv.Close)
+	return srv.URL + "/api/v1/jobs/j1/poll"
+}
+
+// poll polls url after cursor since, returning the status, the cursor
+// header and the response body.
+func poll(ctx context.Context, url string, since int64) (int, string, PollResponse, error) {
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url+"?since="+strconv.FormatInt(since, 10), nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, "", PollResponse{}, err
+	}
+	defer resp.Body.Close()
+	var body PollResponse
+	if resp.StatusCode == http.StatusOK {
+		err = json.NewDecoder(resp.Body).Decode(&body)
+	}
+	return resp.StatusCode, resp.Header.Get(CursorHeader), body, err
+}
+
+func TestPollReturnsNextEvent(t *testing.T) {
+	src := newLogSource()
+	src.append(jobs.Event{Stage: "drafting", Percent: 10})
+	src.append(jobs.Event{Stage: "drafting", Percent: 40})
+	url := startPollServer(t, NewPollHandler(newHub(src)))
+
+	status, cursor, body, err := poll(context.Background(), url, 1)
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("status %d, %v", status, err)
+	}
+	if body.Event.Seq != 2 || body.Event.Percent != 40 || body.Cursor != 2 || cursor != "2" {
+		t.Errorf("got %+v, cursor header %q, want event 2", body, cursor)
+	}
+}
+
+func TestPollTimesOut(t *testing.T) {
+	src := newLogSource()
+	src.append(jobs.Event{Stage: "drafting"})
+	hub := newHub(src)
+	url := startPollServer(t, &PollHandler{Source: hub, Timeout: 50 * time.Millisecond, Registry: hub.Connections()})
+
+	start := time.Now()
+	status, cursor, _, err := poll(context.Background(), url, 1)
+	if err != nil || status != http.StatusNoContent || cursor != "1" {
+		t.Fatalf("status %d, cursor %q, %v; want 204 with cursor 1", status, cursor, err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("answered after %v, before the timeout", elapsed)
+	}
+	eventually(t, "the subscription to be released", func() bool {
+		return hub.Subscriptions() == 0 && hub.Connections().Len() == 0 && src.active.Load() == 0
+	})
+}
+
+// TestPollRace has several clients long-poll each event while it is
+// published, some subscribing before it, some after. Each must get the
+// event exactly once, and move on to the next with their new cursor.
+func TestPollRace(t *testing.T) {
+	src := newLogSource()
+	hub := newHub(src)
+	url := startPollServer(t, &PollHandler{Source: hub, Timeout: 5 * time.Second})
+
+	const clients, events = 8, 20
+	var wg sync.WaitGroup
+	errs := make(chan error, clients)
+	for range clients {
+		wg.Go(func() {
+			var since int64
+			for since < events {
+				status, _, body, err := poll(context.Background(), url, since)
+				if err != nil || status != http.StatusOK {
+					errs <- fmt.Errorf("poll since %d: status %d, %v", since, status, err)
+					return
+				}
+				if body.Cursor != since+1 || body.Event.Seq != since+1 {
+					errs <- fmt.Errorf("poll since %d got event %d, cursor %d", since, body.Event.Seq, body.Cursor)
+					return
+				}
+				since = body.Cursor
+			}
+		})
+	}
+	for i := 1; i <= events; i++ {
+		stage := "drafting"
+		if i == events {
+			stage = jobs.StageDone
+		}
+		src.append(jobs.Event{Stage: stage, Percent: float64(i * 100 / events)})
+		time.Sleep(time.Millisecond)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	eventually(t, "the subscriptions to be released", func() bool {
+		return hub.Subscriptions() == 0 && src.active.Load() == 0
+	})
+}
+
+func TestPollDisconnectReleasesSubscription(t *testing.T) {
+	src := newLogSource()
+	hub := newHub(src)
+	url := startPollServer(t, NewPollHandler(hub))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, _, _, err := poll(ctx, url, 0)
+		done <- err
+	}()
+	eventually(t, "the poll to subscribe", func() bool { return hub.Connections().Len() == 1 && src.active.Load() == 1 })
+	if c := hub.Connections().List()[0]; c.Transport != TransportLongPoll || c.JobID != "j1" {
+		t.Errorf("connection = %+v", c)
+	}
+	cancel()
+	<-done
+	eventually(t, "the subscription to be released", func() bool {
+		return hub.Subscriptions() == 0 && hub.Connections().Len() == 0 && src.active.Load() == 0
+	})
+}
+
+func TestPollOnlyForOwner(t *testing.T) {
+	src := newLogSource()
+	src.append(jobs.Event{Stage: "drafting"})
+	var lookups atomic.Int32
+	h := NewPollHandler(src)
+	h.Owner = ownedBy("user-1", &lookups)
+	rt := router.New()
+	rt.Handle(http.MethodGet, "/api/v1/jobs/{id}/poll", asQueryUser(h))
+	srv := httptest.NewServer(rt)
+	t.Cleanup(srv.Close)
+
+	for _, path := range []string{"j1/poll?user=user-2", "missing/poll?user=user-1"} {
+		resp, err := http.Get(srv.URL + "/api/v1/jobs/" + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("%s: status = %d, want 404", path, resp.StatusCode)
+		}
+	}
+	if n := src.opens.Load(); n != 0 || lookups.Load() != 2 {
+		t.Fatalf("subscribed %d times after %d lookups, want none after 2", n, lookups.Load())
+	}
+
+	resp, err := http.Get(srv.URL + "/api/v1/jobs/j1/poll?user=user-1&since=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body PollResponse
+	json.NewDecoder(resp.Body).Decode(&body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || body.Event.Seq != 1 {
+		t.Errorf("owner: status %d, %+v", resp.StatusCode, body)
+	}
+}
+
+func TestPollClosedByRegistry(t *testing.T) {
+	hub := newHub(newLogSource())
+	url := startPollServer(t, NewPollHandler(hub))
+
+	type result struct {
+		status int
+		cursor string
+	}
+	done := make(chan result, 1)
+	go func() {
+		status, cursor, _, _ := poll(context.Background(), url, 7)
+		done <- result{status, cursor}
+	}()
+	eventually(t, "the poll to wait", func() bool { return hub.Connections().Len() == 1 })
+	hub.Connections().CloseAll("maintenance")
+	if got := <-done; got.status != http.StatusNoContent || got.cursor != "7" {
+		t.Errorf("closed poll: %+v, want 204 with cursor 7", got)
+	}
+}
+
+func TestPollErrors(t *testing.T) {
+	src := newLogSource()
+	url := startPollServer(t, NewPollHandler(src))
+
+	for _, since := range []string{"abc", "-1"} {
+		resp, err := http.Get(url + "?since=" + since)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body struct{ Error struct{ Code string } }
+		json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest || body.Error.Code != "invalid_cursor" {
+			t.Errorf("since=%s: status %d, code %q, want 400 invalid_cursor", since, resp.StatusCode, body.Error.Code)
+		}
+	}
+
+	src.err = rpc.Errorf(rpc.NotFound, "no such job")
+	if status, _, _, err := poll(context.Background(), url, 0); err != nil || status != http.StatusNotFound {
+		t.Errorf("unknown job: status %d, %v, want 404", status, err)
+	}
+}
@@ -0,0 +1,101 @@
+package realtime
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/jobs"
+	"github.com/content-factory/go-gateway/internal/websocket"
+)
+
+// Event schema versions a WebSocket client can ask for in
+// Sec-WebSocket-Protocol.
+const (
+	ProtocolEventsV1 = "events.v1"
+	ProtocolEventsV2 = "events.v2"
+)
+
+// protocol is one version of the event schema.
+type protocol struct {
+	name   string
+	encode func(jobs.Event) ([]byte, error)
+}
+
+// protocols lists the supported schema versions, most preferred first. A
+// new version is one entry here and its encoder.
+var protocols = []protocol{
+	{ProtocolEventsV2, encodeEventV2},
+	{ProtocolEventsV1, encodeEventV1},
+}
+
+// legacyProtocol serves clients that offer no subprotocol at all, which
+// predate versioning and expect the events.v1 schema.
+var legacyProtocol = protocol{encode: encodeEventV1}
+
+// negotiate picks the schema to send r's events in: the most preferred of
+// those the client offers, or legacyProtocol if it offers none. It returns
+// the error to refuse the upgrade with if none of its offers is supported.
+func negotiate(r *http.Request) (protocol, *apierror.Error) {
+	offered := websocket.Subprotocols(r)
+	if len(offered) == 0 {
+		return legacyProtocol, nil
+	}
+	for _, p := range protocols {
+		for _, o := range offered {
+			if o == p.name {
+				return p, nil
+			}
+		}
+	}
+	names := make([]string, len(protocols))
+	for i, p := range protocols {
+		names[i] = p.name
+	}
+	return protocol{}, apierror.New(http.StatusBadRequest, apierror.CodeUnsupportedSubprotocol,
+		"none of the offered subprotocols is supported; offer one of "+strings.Join(names, ", "))
+}
+
+// encodeEventV1 sends the event as it is, with the stage saying whether
+// the job has finished.
+func encodeEventV1(ev jobs.Event) ([]byte, error) {
+	return json.Marshal(ev)
+}
+
+// eventV2 is the events.v2 schema. Its type says whether the job is still
+// running, so clients need not know which stages are terminal, and its
+// fields are named as in the job resource.
+type eventV2 struct {
+	// Type is "progress" until the last event, which is "completed" or
+	// "failed".
+	Type      string    `json:"type"`
+	JobID     string    `json:"job_id"`
+	Seq       int64     `json:"seq"`
+	Stage     string    `json:"stage"`
+	Progress  float64   `json:"progress"`
+	Message   string    `json:"message,omitempty"`
+	ResultURL string    `json:"result_url,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+func encodeEventV2(ev jobs.Event) ([]byte, error) {
+	typ := "progress"
+	switch ev.Stage {
+	case jobs.StageDone:
+		typ = "completed"
+	case jobs.StageFailed, jobs.StageError:
+		typ = "failed"
+	}
+	return json.Marshal(eventV2{
+		Type:      typ,
+		JobID:     ev.JobID,
+		Seq:       ev.Seq,
+		Stage:     ev.Stage,
+		Progress:  ev.Percent,
+		Message:   ev.Message,
+		ResultURL: ev.URL,
+		Time:      ev.Time,
+	})
+}
@@ -0,0 +1,308 @@
+package realtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/clientip"
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/jobs"
+	"github.com/content-factory/go-gateway/internal/metrics"
+	"github.com/content-factory/go-gateway/internal/uuid"
+)
+
+// Transports a Connection can use.
+const (
+	TransportWebSocket = "websocket"
+	TransportSSE       = "sse"
+	TransportLongPoll  = "long_poll"
+)
+
+// Connection describes one open WebSocket or SSE stream, or waiting
+// long-poll.
+type Connection struct {
+	ID          string    `json:"id"`
+	JobID       string    `json:"job_id"`
+	Principal   string    `json:"principal"`
+	ClientIP    string    `json:"client_ip"`
+	Transport   string    `json:"transport"`
+	ConnectedAt time.Time `json:"connected_since"`
+	// DroppedEvents counts the events the stream missed by falling
+	// behind, see Hub.Overflow.
+	DroppedEvents int64 `json:"dropped_events"`
+}
+
+// Defaults for Registry.MaxPerIP and MaxPerPrincipal.
+const (
+	DefaultMaxConnectionsPerIP        = 50
+	DefaultMaxConnectionsPerPrincipal = 20
+)
+
+// ErrDraining is returned for streams opened after Drain started.
+var ErrDraining = errors.New("realtime: server is shutting down")
+
+// LimitError is returned for a stream that would take its client IP or
+// principal over its connection limit.
+type LimitError struct {
+	// Key is "client_ip" or "principal".
+	Key   string
+	Limit int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("realtime: %s already has %d open streams", e.Key, e.Limit)
+}
+
+var rejectedConns = metrics.NewCounterVec("gateway_realtime_rejected_connections_total",
+	"Streams refused because their client IP or principal was at its connection limit.",
+	"limit")
+
+// Registry tracks the open streams so operators can see and close them,
+// and caps how many each client IP and principal may hold. The handlers
+// add to it for as long as a stream is open.
+type Registry struct {
+	// MaxPerIP and MaxPerPrincipal cap the open streams of one client IP
+	// and of one authenticated principal; zero means no limit. Set them
+	// before serving.
+	MaxPerIP        int
+	MaxPerPrincipal int
+
+	mu       sync.Mutex
+	conns    map[string]*entry
+	draining bool
+	// byIP and byPrincipal count the streams holding a slot, until their
+	// handler returns; streams Close removed still count.
+	byIP        map[string]int
+	byPrincipal map[string]int
+	// open counts streams that have not returned yet, including those
+	// Close already removed from conns.
+	open sync.WaitGroup
+}
+
+// closeNotice tells a stream to end. Reconnect is set when the client
+// should reconnect, to another instance, rather than give up.
+type closeNotice struct {
+	reason    string
+	reconnect bool
+}
+
+type entry struct {
+	info Connection
+	// closing receives the notice an operator or Drain asked the stream to
+	// close with. It holds one value, so neither ever blocks.
+	closing chan closeNotice
+	dropped atomic.Int64
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		conns:       make(map[string]*entry),
+		byIP:        make(map[string]int),
+		byPrincipal: make(map[string]int),
+	}
+}
+
+// registryOf returns the registry of src if it is a Hub.
+func registryOf(src jobs.Source) *Registry {
+	if h, ok := src.(*Hub); ok {
+		return h.conns
+	}
+	return nil
+}
+
+// trackRequest records the stream r opens for jobID. The returned
+// context, derived from ctx, counts the events its subscription drops.
+func (reg *Registry) trackRequest(ctx context.Context, r *http.Request, jobID, transport string) (context.Context, <-chan closeNotice, func(), error) {
+	info := Connection{JobID: jobID, Transport: transport, ClientIP: clientip.Of(r), ConnectedAt: time.Now().UTC()}
+	if c := gateway.ClaimsFromContext(r.Context()); c != nil {
+		info.Principal = c.Subject
+	}
+	e, untrack, err := reg.track(info)
+	if err != nil {
+		return ctx, nil, nil, err
+	}
+	return withDropCounter(ctx, &e.dropped), e.closing, untrack, nil
+}
+
+// track records a stream and returns its entry with a func that removes
+// it again, which the handler must call however the stream ends. info.ID
+// is assigned here. A nil Registry hands out entries without recording
+// them. Once Drain has started, track fails with ErrDraining, and for a
+// stream over its client IP's or principal's limit with a *LimitError.
+func (reg *Registry) track(info Connection) (*entry, func(), error) {
+	e := &entry{info: info, closing: make(chan closeNotice, 1)}
+	if reg == nil {
+		return e, func() {}, nil
+	}
+	e.info.ID = uuid.New()
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if reg.draining {
+		return nil, nil, ErrDraining
+	}
+	if err := reg.checkLimits(info); err != nil {
+		rejectedConns.With(err.Key).Inc()
+		return nil, nil, err
+	}
+	reg.conns[e.info.ID] = e
+	reg.byIP[info.ClientIP]++
+	if info.Principal != "" {
+		reg.byPrincipal[info.Principal]++
+	}
+	reg.open.Add(1)
+	var once sync.Once
+	return e, func() {
+		once.Do(func() {
+			reg.mu.Lock()
+			delete(reg.conns, e.info.ID)
+			release(reg.byIP, info.ClientIP)
+			if info.Principal != "" {
+				release(reg.byPrincipal, info.Principal)
+			}
+			reg.mu.Unlock()
+			reg.open.Done()
+		})
+	}, nil
+}
+
+// checkLimits returns the limit a new stream described by info would
+// exceed, or nil. Anonymous streams are limited by IP only.
+func (reg *Registry) checkLimits(info Connection) *LimitError {
+	if reg.MaxPerIP > 0 && reg.byIP[info.ClientIP] >= reg.MaxPerIP {
+		return &LimitError{Key: "client_ip", Limit: reg.MaxPerIP}
+	}
+	if reg.MaxPerPrincipal > 0 && info.Principal != "" && reg.byPrincipal[info.Principal] >= reg.MaxPerPrincipal {
+		return &LimitError{Key: "principal", Limit: reg.MaxPerPrincipal}
+	}
+	return nil
+}
+
+// release gives back one of key's slots, forgetting keys with none left
+// so the maps do not grow with every client ever seen.
+func release(counts map[string]int, key string) {
+	if counts[key] <= 1 {
+		delete(counts, key)
+		return
+	}
+	counts[key]--
+}
+
+// Counts returns how many streams each principal and each client IP holds
+// open. Anonymous streams count towards their IP only.
+func (reg *Registry) Counts() (byPrincipal, byIP map[string]int) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	return maps.Clone(reg.byPrincipal), maps.Clone(reg.byIP)
+}
+
+// List returns the open streams, oldest first.
+func (reg *Registry) List() []Connection {
+	reg.mu.Lock()
+	out := make([]Connection, 0, len(reg.conns))
+	for _, e := range reg.conns {
+		c := e.info
+		c.DroppedEvents = e.dropped.Load()
+		out = append(out, c)
+	}
+	reg.mu.Unlock()
+	slices.SortFunc(out, func(a, b Connection) int { return a.ConnectedAt.Compare(b.ConnectedAt) })
+	return out
+}
+
+// Len reports how many streams are open.
+func (reg *Registry) Len() int {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	return len(reg.conns)
+}
+
+// Close asks the stream with the given ID to end, telling the client
+// reason: WebSocket clients get it in a close frame, SSE clients in a
+// final "close" event. It reports whether the stream was open.
+func (reg *Registry) Close(id, reason string) bool {
+	reg.mu.Lock()
+	e, ok := reg.conns[id]
+	if ok {
+		delete(reg.conns, id)
+	}
+	reg.mu.Unlock()
+	if ok {
+		select {
+		case e.closing <- closeNotice{reason: reason}:
+		default:
+			// Drain got there first.
+		}
+	}
+	return ok
+}
+
+// refuse answers a stream request track turned down.
+func refuse(w http.ResponseWriter, r *http.Request, err error) {
+	var limit *LimitError
+	if errors.As(err, &limit) {
+		who := "this client IP"
+		if limit.Key == "principal" {
+			who = "this user"
+		}
+		apierror.Write(w, r, apierror.TooManyConnections(
+			fmt.Sprintf("%s already has %d open streams, the most allowed; close one before opening another", who, limit.Limit)))
+		return
+	}
+	apierror.Write(w, r, apierror.ShuttingDown("the gateway is shutting down, reconnect to retry"))
+}
+
+// CloseAll asks every open stream to end as Close does, and returns how
+// many were open. Unlike Drain it does not refuse new streams.
+func (reg *Registry) CloseAll(reason string) int {
+	reg.mu.Lock()
+	open := reg.conns
+	reg.conns = make(map[string]*entry, len(open))
+	reg.mu.Unlock()
+	for _, e := range open {
+		select {
+		case e.closing <- closeNotice{reason: reason}:
+		default:
+			// Drain got there first.
+		}
+	}
+	return len(open)
+}
+
+// Drain refuses new streams with ErrDraining, asks every open stream to
+// close with reason and a hint to reconnect elsewhere, then waits until
+// they have all ended or ctx is done. It returns how many streams were
+// open and the context's error if some were still open when it gave up.
+func (reg *Registry) Drain(ctx context.Context, reason string) (int, error) {
+	reg.mu.Lock()
+	reg.draining = true
+	n := len(reg.conns)
+	for _, e := range reg.conns {
+		select {
+		case e.closing <- closeNotice{reason: reason, reconnect: true}:
+		default:
+			// Already closing.
+		}
+	}
+	reg.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		reg.open.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return n, nil
+	case <-ctx.Done():
+		return n, ctx.Err()
+	}
+}
@@ -0,0 +1,135 @@
+package realtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/jobs"
+	"github.com/content-factory/go-gateway/internal/router"
+)
+
+const (
+	// heartbeatInterval keeps idle streams alive through proxies that close
+	// quiet connections, typically after 30-60s.
+	heartbeatInterval = 15 * time.Second
+	// retryAfter is the reconnect delay suggested to EventSource clients.
+	retryAfter = 3 * time.Second
+)
+
+// SSEHandler serves GET /api/v1/jobs/{id}/events, the Server-Sent Events
+// alternative to WebSocketHandler for clients behind proxies that break
+// upgrades. Each event carries its sequence number as the SSE id, so a
+// reconnecting EventSource resumes via Last-Event-ID.
+type SSEHandler struct {
+	Source    jobs.Source
+	Heartbeat time.Duration
+	// Owner, if set, looks up the owner of each job, and only its owner
+	// may stream it; other callers get 404.
+	Owner OwnerFunc
+	// Registry, if set, records each open stream.
+	Registry *Registry
+}
+
+// NewSSEHandler returns a handler streaming events from src. If src is a
+// Hub, streams are recorded in its registry.
+func NewSSEHandler(src jobs.Source) *SSEHandler {
+	return &SSEHandler{Source: src, Heartbeat: heartbeatInterval, Registry: registryOf(src)}
+}
+
+func (h *SSEHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		apierror.Write(w, r, apierror.New(http.StatusInternalServerError, apierror.CodeStreamingUnsupported, "response streaming is not supported"))
+		return
+	}
+	var after int64
+	if v := strings.TrimSpace(r.Header.Get("Last-Event-ID")); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.CodeInvalidLastEventID, "Last-Event-ID must be a non-negative integer"))
+			return
+		}
+		after = n
+	}
+
+	ctx := r.Context()
+	jobID := router.Param(r, "id")
+	ctx, closing, untrack, err := h.Registry.trackRequest(ctx, r, jobID, TransportSSE)
+	if err != nil {
+		refuse(w, r, err)
+		return
+	}
+	defer untrack()
+	if e := authorize(ctx, h.Owner, jobID); e != nil {
+		apierror.Write(w, r, e)
+		return
+	}
+	events, err := h.Source.Subscribe(ctx, jobID, after)
+	if err != nil {
+		apierror.Write(w, r, apierror.FromRPC(err))
+		return
+	}
+
+	hdr := w.Header()
+	hdr.Set("Content-Type", "text/event-stream")
+	hdr.Set("Cache-Control", "no-cache")
+	// Ask nginx-style proxies not to buffer the stream.
+	hdr.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", retryAfter.Milliseconds())
+	flusher.Flush()
+
+	interval := h.Heartbeat
+	if interval <= 0 {
+		interval = heartbeatInterval
+	}
+	heartbeat := time.NewTicker(interval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case notice := <-closing:
+			// EventSource reconnects when a stream ends, so the client is
+			// told why first: "close" when an operator ended the stream,
+			// "reconnect" when this instance is going away and the client
+			// should resume, with Last-Event-ID, on another.
+			event := "close"
+			if notice.reconnect {
+				event = "reconnect"
+			}
+			payload, _ := json.Marshal(map[string]string{"reason": notice.reason})
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+			flusher.Flush()
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			payload, err := json.Marshal(ev)
+			if err != nil {
+				gateway.Logger(ctx).Error("marshal job event", "job_id", ev.JobID, "error", err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\ndata: %s\n\n", ev.Seq, payload); err != nil {
+				return
+			}
+			flusher.Flush()
+			if ev.Terminal() {
+				return
+			}
+		}
+	}
+}
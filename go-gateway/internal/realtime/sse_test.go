@@ -0,0 +1,242 @@
+package realtime
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/jobs"
+	"github.com/content-factory/go-gateway/internal/router"
+	"github.com/content-factory/go-gateway/internal/rpc"
+)
+
+func startSSEServer(t *testing.T, h *SSEHandler) string {
+	t.Helper()
+	rt := router.New()
+	rt.Handle(http.MethodGet, "/api/v1/jobs/{id}/events", h)
+	srv := httptest.NewServer(rt)
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+// readFrame returns the next SSE frame (the lines up to a blank line).
+func readFrame(t *testing.T, br *bufio.Reader) []string {
+	t.Helper()
+	var lines []string
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read: %v (so far %q)", err, lines)
+		}
+		line = strings.TrimRight(line, "\n")
+		if line == "" {
+			return lines
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestSSEStreamsEvents(t *testing.T) {
+	src := newFakeSource()
+	url := startSSEServer(t, NewSSEHandler(src))
+
+	req, _ := http.NewRequest(http.MethodGet, url+"/api/v1/jobs/j1/events", nil)
+	req.Header.Set("Last-Event-ID", "3")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	if got := src.after.Load(); got != 3 {
+		t.Errorf("subscribed after %d, want 3", got)
+	}
+
+	go func() {
+		src.events <- jobs.Event{Seq: 4, Stage: "drafting", Percent: 50}
+		src.events <- jobs.Event{Seq: 5, Stage: jobs.StageDone, Percent: 100}
+	}()
+
+	br := bufio.NewReader(resp.Body)
+	if f := readFrame(t, br); len(f) != 1 || !strings.HasPrefix(f[0], "retry: ") {
+		t.Errorf("first frame = %q, want retry hint", f)
+	}
+	f := readFrame(t, br)
+	if len(f) != 2 || f[0] != "id: 4" || !strings.Contains(f[1], `"stage":"drafting"`) {
+		t.Errorf("progress frame = %q", f)
+	}
+	if f := readFrame(t, br); len(f) != 2 || f[0] != "id: 5" {
+		t.Errorf("terminal frame = %q", f)
+	}
+	if _, err := br.ReadString('\n'); err == nil {
+		t.Error("stream still open after terminal event")
+	}
+}
+
+func TestSSEOverH2C(t *testing.T) {
+	src := newFakeSource()
+	url := startH2CServer(t, "/api/v1/jobs/{id}/events", NewSSEHandler(src))
+
+	resp, err := h2cClient().Get(url + "/api/v1/jobs/j1/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.ProtoMajor != 2 || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("%s, Content-Type %q", resp.Proto, resp.Header.Get("Content-Type"))
+	}
+	br := bufio.NewReader(resp.Body)
+	readFrame(t, br) // the retry hint
+	// Each event arrives as it is sent, not when the stream ends.
+	src.events <- jobs.Event{Seq: 1, Stage: "drafting", Percent: 50}
+	if f := readFrame(t, br); len(f) != 2 || f[0] != "id: 1" {
+		t.Errorf("progress frame = %q", f)
+	}
+	src.events <- jobs.Event{Seq: 2, Stage: jobs.StageDone, Percent: 100}
+	if f := readFrame(t, br); len(f) != 2 || f[0] != "id: 2" {
+		t.Errorf("terminal frame = %q", f)
+	}
+}
+
+func TestSSEHeartbeatAndDisconnect(t *testing.T) {
+	src := newFakeSource()
+	url := startSSEServer(t, &SSEHandler{Source: src, Heartbeat: 20 * time.Millisecond})
+
+	resp, err := http.Get(url + "/api/v1/jobs/j1/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(resp.Body)
+	readFrame(t, br) // retry hint
+	if f := readFrame(t, br); len(f) != 1 || f[0] != ": keepalive" {
+		t.Errorf("heartbeat frame = %q", f)
+	}
+	resp.Body.Close()
+
+	select {
+	case <-src.cancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("upstream subscription not cancelled after client disconnect")
+	}
+}
+
+func TestSSEErrors(t *testing.T) {
+	src := newFakeSource()
+	url := startSSEServer(t, NewSSEHandler(src))
+
+	req, _ := http.NewRequest(http.MethodGet, url+"/api/v1/jobs/j1/events", nil)
+	req.Header.Set("Last-Event-ID", "abc")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("bad Last-Event-ID: status = %d, want 400", resp.StatusCode)
+	}
+
+	src.err = rpc.Errorf(rpc.NotFound, "no such job")
+	resp, err = http.Get(url + "/api/v1/jobs/nope/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown job: status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestSSEOnlyForOwner(t *testing.T) {
+	src := newFakeSource()
+	var lookups atomic.Int32
+	h := NewSSEHandler(src)
+	h.Owner = ownedBy("user-1", &lookups)
+	rt := router.New()
+	rt.Handle(http.MethodGet, "/api/v1/jobs/{id}/events", asQueryUser(h))
+	srv := httptest.NewServer(rt)
+	t.Cleanup(srv.Close)
+
+	for _, path := range []string{"j1/events?user=user-2", "missing/events?user=user-1"} {
+		resp, err := http.Get(srv.URL + "/api/v1/jobs/" + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("%s: status = %d, want 404", path, resp.StatusCode)
+		}
+	}
+	if n := src.opens.Load(); n != 0 || lookups.Load() != 2 {
+		t.Fatalf("subscribed %d times after %d lookups, want none after 2", n, lookups.Load())
+	}
+
+	resp, err := http.Get(srv.URL + "/api/v1/jobs/j1/events?user=user-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || src.opens.Load() != 1 {
+		t.Errorf("owner: status = %d after %d subscriptions", resp.StatusCode, src.opens.Load())
+	}
+}
+
+func TestSSEClosedByRegistry(t *testing.T) {
+	hub := newHub(newFakeSource())
+	url := startSSEServer(t, NewSSEHandler(hub))
+
+	resp, err := http.Get(url + "/api/v1/jobs/j1/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	br := bufio.NewReader(resp.Body)
+	readFrame(t, br) // retry hint
+
+	conns := hub.Connections().List()
+	if len(conns) != 1 || conns[0].JobID != "j1" || conns[0].Transport != TransportSSE {
+		t.Fatalf("connections = %+v", conns)
+	}
+	if !hub.Connections().Close(conns[0].ID, "maintenance") {
+		t.Fatal("Close reported the stream missing")
+	}
+	if f := readFrame(t, br); len(f) != 2 || f[0] != "event: close" || f[1] != `data: {"reason":"maintenance"}` {
+		t.Errorf("close frame = %q", f)
+	}
+	eventually(t, "the stream to be forgotten", func() bool { return hub.Connections().Len() == 0 })
+}
+
+func TestSSEDrainAsksToReconnect(t *testing.T) {
+	hub := newHub(newFakeSource())
+	url := startSSEServer(t, NewSSEHandler(hub))
+
+	resp, err := http.Get(url + "/api/v1/jobs/j1/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	br := bufio.NewReader(resp.Body)
+	readFrame(t, br) // retry hint
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	go hub.Connections().Drain(ctx, "server shutting down")
+	if f := readFrame(t, br); len(f) != 2 || f[0] != "event: reconnect" || f[1] != `data: {"reason":"server shutting down"}` {
+		t.Errorf("final frame = %q", f)
+	}
+
+	resp, err = http.Get(url + "/api/v1/jobs/j1/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("stream while draining: status = %d, want 503", resp.StatusCode)
+	}
+}
@@ -0,0 +1,256 @@
+// Package realtime streams job progress events to clients.
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/jobs"
+	"github.com/content-factory/go-gateway/internal/router"
+	"github.com/content-factory/go-gateway/internal/websocket"
+)
+
+const (
+	// writeWait bounds every write, so a peer that stops reading cannot
+	// wedge the connection.
+	writeWait = 10 * time.Second
+	// pongWait is how long we wait for any frame before declaring the peer
+	// dead; pings go out often enough to keep healthy peers inside it.
+	pongWait   = 60 * time.Second
+	pingPeriod = pongWait * 9 / 10
+	// closeAckWait is how long a client gets to answer our close frame
+	// before the connection is dropped.
+	closeAckWait = 2 * time.Second
+)
+
+// DefaultMaxMessageBytes caps client messages when
+// WebSocketHandler.MaxMessageBytes is zero. The job stream is
+// server-to-client, so clients send little more than auth messages.
+const DefaultMaxMessageBytes = 4 << 10
+
+// WebSocketHandler serves GET /ws/jobs/{id}, streaming a job's progress
+// events as JSON text messages until a terminal event, in the schema
+// version negotiated through Sec-WebSocket-Protocol.
+//
+// Clients may only send the JSON text messages of the protocol. A message
+// over MaxMessageBytes closes the connection with CloseMessageTooBig, a
+// binary one with CloseUnsupportedData, and one that is not a well-formed
+// message of a known type with ClosePolicyViolation.
+type WebSocketHandler struct {
+	Source   jobs.Source
+	Upgrader websocket.Upgrader
+	// Owner, if set, looks up the owner of each job, and only its owner
+	// may stream it; other callers get 404 before anything is subscribed.
+	Owner OwnerFunc
+	// Registry, if set, records each open connection.
+	Registry *Registry
+	// Verify, if set, checks the tokens clients send in auth messages to
+	// extend a connection past its token's expiry. A connection whose
+	// token expired is closed with CloseTokenExpired once AuthGrace (zero
+	// means DefaultAuthGrace) has passed without a valid refresh. Without
+	// Verify, connections outlive their tokens.
+	Verify    func(token string) (*gateway.Claims, error)
+	AuthGrace time.Duration
+	// MaxMessageBytes caps a client message, however it is fragmented or
+	// compressed; zero means DefaultMaxMessageBytes.
+	MaxMessageBytes int64
+}
+
+// NewWebSocketHandler returns a handler streaming events from src. If src
+// is a Hub, connections are recorded in its registry.
+func NewWebSocketHandler(src jobs.Source) *WebSocketHandler {
+	return &WebSocketHandler{
+		Source:   src,
+		Upgrader: websocket.Upgrader{HandshakeTimeout: writeWait},
+		Registry: registryOf(src),
+	}
+}
+
+func (h *WebSocketHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	jobID := router.Param(r, "id")
+	if r.ProtoMajor != 1 {
+		// HTTP/2 carries no Upgrade header, so its clients get told what to
+		// do instead of being asked for one.
+		apierror.Write(w, r, apierror.New(http.StatusHTTPVersionNotSupported, apierror.CodeHTTP11Required,
+			"WebSocket connections need HTTP/1.1; connect over HTTP/1.1, or follow the job at /api/v1/jobs/{id}/events"))
+		return
+	}
+	if !websocket.IsWebSocketUpgrade(r) {
+		apierror.Write(w, r, apierror.New(http.StatusBadRequest, apierror.CodeWebSocketRequired, "this endpoint requires a WebSocket upgrade"))
+		return
+	}
+	proto, e := negotiate(r)
+	if e != nil {
+		apierror.Write(w, r, e)
+		return
+	}
+
+	// The upstream subscription outlives the handshake, so it gets its own
+	// context, cancelled when either side goes away.
+	ctx, cancel := context.WithCancel(context.WithoutCancel(r.Context()))
+	defer cancel()
+	ctx, closing, untrack, err := h.Registry.trackRequest(ctx, r, jobID, TransportWebSocket)
+	if err != nil {
+		refuse(w, r, err)
+		return
+	}
+	defer untrack()
+	if e := authorize(ctx, h.Owner, jobID); e != nil {
+		apierror.Write(w, r, e)
+		return
+	}
+
+	events, err := h.Source.Subscribe(ctx, jobID, 0)
+	if err != nil {
+		apierror.Write(w, r, apierror.FromRPC(err))
+		return
+	}
+
+	var header http.Header
+	if proto.name != "" {
+		header = http.Header{"Sec-WebSocket-Protocol": {proto.name}}
+	}
+	conn, err := h.Upgrader.Upgrade(w, r, header)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	clock := newTokenClock(gateway.ClaimsFromContext(r.Context()), h.Verify, h.AuthGrace)
+	defer clock.stop()
+	limit := h.MaxMessageBytes
+	if limit <= 0 {
+		limit = DefaultMaxMessageBytes
+	}
+	go readPump(ctx, conn, cancel, clock, limit)
+	writePump(ctx, conn, events, proto.encode, closing, clock)
+}
+
+// readPump consumes inbound frames so pings, pongs and close frames are
+// processed, hands auth messages to clock, and cancels the subscription
+// once the peer is gone or broke the protocol. A panic is logged and
+// closes the connection, since no middleware recovers this goroutine.
+func readPump(ctx context.Context, conn *websocket.Conn, cancel context.CancelFunc, clock *tokenClock, limit int64) {
+	defer cancel()
+	defer func() {
+		if p := recover(); p != nil {
+			gateway.Logger(ctx).ErrorContext(ctx, "WebSocket read loop panicked", "panic", p, "stack", string(debug.Stack()))
+			conn.WriteClose(websocket.CloseInternalError, "internal error", time.Now().Add(writeWait))
+		}
+	}()
+	conn.SetReadLimit(limit)
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+	for {
+		typ, data, err := conn.ReadMessage()
+		if errors.Is(err, websocket.ErrReadLimit) {
+			gateway.Logger(ctx).InfoContext(ctx, "closed a WebSocket sending an oversized message", "max_bytes", limit)
+			return
+		}
+		if err != nil {
+			return
+		}
+		conn.SetReadDeadline(time.Now().Add(pongWait))
+		msg, code, err := parseClientMessage(typ, data)
+		if err != nil {
+			gateway.Logger(ctx).InfoContext(ctx, "closed a WebSocket breaking the protocol", "close_code", code, "error", err)
+			conn.WriteClose(code, err.Error(), time.Now().Add(writeWait))
+			return
+		}
+		clock.handle(ctx, msg)
+	}
+}
+
+// parseClientMessage checks that a message of typ is one the protocol
+// defines, returning it or the error, short enough for a close reason,
+// and the close code to send with it.
+func parseClientMessage(typ int, data []byte) (authMessage, int, error) {
+	if typ != websocket.TextMessage {
+		return authMessage{}, websocket.CloseUnsupportedData, errors.New("binary messages are not supported")
+	}
+	var msg authMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return authMessage{}, websocket.ClosePolicyViolation, errors.New("message is not a JSON object of the protocol")
+	}
+	switch msg.Type {
+	case "auth":
+		if msg.Token == "" {
+			return authMessage{}, websocket.ClosePolicyViolation, errors.New("auth message without a token")
+		}
+		return msg, 0, nil
+	case "":
+		return authMessage{}, websocket.ClosePolicyViolation, errors.New("message without a type")
+	default:
+		return authMessage{}, websocket.ClosePolicyViolation, fmt.Errorf("unknown message type %.32q", msg.Type)
+	}
+}
+
+// writePump sends events, encoded with encode, until the stream ends, the peer goes away, a
+// notice arrives on closing or clock runs out.
+func writePump(ctx context.Context, conn *websocket.Conn, events <-chan jobs.Event, encode func(jobs.Event) ([]byte, error), closing <-chan closeNotice, clock *tokenClock) {
+	ping := time.NewTicker(pingPeriod)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case notice := <-closing:
+			conn.WriteClose(websocket.CloseGoingAway, notice.reason, time.Now().Add(writeWait))
+			awaitCloseAck(ctx)
+			return
+		case <-clock.expired():
+			conn.WriteClose(CloseTokenExpired, "token expired", time.Now().Add(writeWait))
+			awaitCloseAck(ctx)
+			return
+		case u := <-clock.refreshed():
+			payload, _ := json.Marshal(clock.apply(u))
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				return
+			}
+		case ev, ok := <-events:
+			if !ok {
+				conn.WriteClose(websocket.CloseNormalClosure, "stream ended", time.Now().Add(writeWait))
+				return
+			}
+			payload, err := encode(ev)
+			if err != nil {
+				gateway.Logger(ctx).Error("marshal job event", "job_id", ev.JobID, "error", err)
+				continue
+			}
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+				return
+			}
+			if ev.Terminal() {
+				conn.WriteClose(websocket.CloseNormalClosure, ev.Stage, time.Now().Add(writeWait))
+				return
+			}
+		}
+	}
+}
+
+// awaitCloseAck gives the client closeAckWait to answer a close frame;
+// readPump cancels ctx once it does.
+func awaitCloseAck(ctx context.Context) {
+	ack := time.NewTimer(closeAckWait)
+	defer ack.Stop()
+	select {
+	case <-ctx.Done():
+	case <-ack.C:
+	}
+}
@@ -0,0 +1,559 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/jobs"
+	"github.com/content-factory/go-gateway/internal/router"
+	"github.com/content-factory/go-gateway/internal/rpc"
+	"github.com/content-factory/go-gateway/internal/websocket"
+)
+
+// fakeSource hands out a channel per subscription and records when the
+// subscriber's context is cancelled.
+type fakeSource struct {
+	events    chan jobs.Event
+	cancelled chan struct{}
+	err       error
+	after     atomic.Int64
+	opens     atomic.Int32
+}
+
+func newFakeSource() *fakeSource {
+	return &fakeSource{events: make(chan jobs.Event), cancelled: make(chan struct{})}
+}
+
+func (s *fakeSource) Subscribe(ctx context.Context, jobID string, after int64) (<-chan jobs.Event, error) {
+	s.opens.Add(1)
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.after.Store(after)
+	out := make(chan jobs.Event)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				close(s.cancelled)
+				return
+			case ev := <-s.events:
+				ev.JobID = jobID
+				select {
+				case out <- ev:
+				case <-ctx.Done():
+					close(s.cancelled)
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+func startServer(t *testing.T, src jobs.Source) string {
+	t.Helper()
+	rt := router.New()
+	rt.Handle(http.MethodGet, "/ws/jobs/{id}", NewWebSocketHandler(src))
+	srv := httptest.NewServer(rt)
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+// ownedBy is an OwnerFunc for jobs of owner, counting its lookups.
+func ownedBy(owner string, lookups *atomic.Int32) OwnerFunc {
+	return func(ctx context.Context, jobID string) (string, error) {
+		lookups.Add(1)
+		if jobID == "missing" {
+			return "", rpc.Errorf(rpc.NotFound, "no such job")
+		}
+		return owner, nil
+	}
+}
+
+// asQueryUser serves h as the user named in the request's user
+// parameter.
+func asQueryUser(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := gateway.WithClaims(r.Context(), &gateway.Claims{Subject: r.URL.Query().Get("user")})
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func readEvent(t *testing.T, c *websocket.Conn) jobs.Event {
+	t.Helper()
+	c.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, data, err := c.ReadMessage()
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	var ev jobs.Event
+	if err := json.Unmarshal(data, &ev); err != nil {
+		t.Fatalf("decode %s: %v", data, err)
+	}
+	return ev
+}
+
+func TestStreamsUntilTerminalEvent(t *testing.T) {
+	src := newFakeSource()
+	c, _, err := websocket.Dial(startServer(t, src)+"/ws/jobs/j1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	go func() {
+		src.events <- jobs.Event{Stage: "drafting", Percent: 42}
+		src.events <- jobs.Event{Stage: jobs.StageDone, Percent: 100, URL: "https://cdn/x.mp4"}
+	}()
+
+	if ev := readEvent(t, c); ev.Stage != "drafting" || ev.Percent != 42 || ev.JobID != "j1" {
+		t.Errorf("first event = %+v", ev)
+	}
+	if ev := readEvent(t, c); ev.Stage != jobs.StageDone || ev.URL != "https://cdn/x.mp4" {
+		t.Errorf("terminal event = %+v", ev)
+	}
+	_, _, err = c.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Errorf("after terminal event: %v, want normal close", err)
+	}
+}
+
+func TestSubprotocolNegotiation(t *testing.T) {
+	tests := []struct {
+		name    string
+		offered string
+		want    string // the subprotocol accepted
+		first   string // a key only the accepted schema's events have
+	}{
+		{"none", "", "", "percent"},
+		{"v1", "events.v1", ProtocolEventsV1, "percent"},
+		{"v2", "events.v2", ProtocolEventsV2, "progress"},
+		{"best of both", "events.v1, events.v2", ProtocolEventsV2, "progress"},
+		{"skips unknown", "events.v9, events.v1", ProtocolEventsV1, "percent"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := newFakeSource()
+			var header http.Header
+			if tt.offered != "" {
+				header = http.Header{"Sec-WebSocket-Protocol": {tt.offered}}
+			}
+			c, _, err := websocket.Dial(startServer(t, src)+"/ws/jobs/j1", header)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			if c.Subprotocol() != tt.want {
+				t.Errorf("subprotocol = %q, want %q", c.Subprotocol(), tt.want)
+			}
+
+			go func() { src.events <- jobs.Event{Stage: jobs.StageDone, Percent: 100, URL: "https://cdn/x.mp4"} }()
+			c.SetReadDeadline(time.Now().Add(2 * time.Second))
+			_, data, err := c.ReadMessage()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var msg map[string]any
+			json.Unmarshal(data, &msg)
+			if _, ok := msg[tt.first]; !ok || msg["job_id"] != "j1" {
+				t.Errorf("event = %s, want %s", data, tt.first)
+			}
+			if tt.want == ProtocolEventsV2 && (msg["type"] != "completed" || msg["result_url"] != "https://cdn/x.mp4") {
+				t.Errorf("events.v2 event = %s", data)
+			}
+		})
+	}
+}
+
+func TestUnsupportedSubprotocolRefused(t *testing.T) {
+	src := newFakeSource()
+	req := httptest.NewRequest(http.MethodGet, "/ws/jobs/j1", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Protocol", "events.v0, chat")
+	rec := httptest.NewRecorder()
+	NewWebSocketHandler(src).ServeHTTP(rec, req)
+
+	var body struct{ Error apierror.Error }
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	if rec.Code != http.StatusBadRequest || body.Error.Code != apierror.CodeUnsupportedSubprotocol {
+		t.Fatalf("got %d %s, want 400 unsupported_subprotocol", rec.Code, rec.Body)
+	}
+	if !strings.Contains(body.Error.Message, "events.v2, events.v1") {
+		t.Errorf("message %q does not list the supported subprotocols", body.Error.Message)
+	}
+}
+
+func TestClientDisconnectCancelsSubscription(t *testing.T) {
+	src := newFakeSource()
+	c, _, err := websocket.Dial(startServer(t, src)+"/ws/jobs/j1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.WriteClose(websocket.CloseNormalClosure, "", time.Now().Add(time.Second))
+	c.Close()
+
+	select {
+	case <-src.cancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("upstream subscription not cancelled after client disconnect")
+	}
+}
+
+func TestUnknownJobIs404(t *testing.T) {
+	src := newFakeSource()
+	src.err = rpc.Errorf(rpc.NotFound, "no such job")
+	_, resp, err := websocket.Dial(startServer(t, src)+"/ws/jobs/nope", nil)
+	if err == nil {
+		t.Fatal("expected handshake failure")
+	}
+	if resp == nil || resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("resp = %v, want 404", resp)
+	}
+}
+
+// startH2CServer serves h at path over HTTP/1.1 and HTTP/2 without TLS,
+// as the gateway does with h2c on, and returns its URL.
+func startH2CServer(t *testing.T, path string, h http.Handler) string {
+	t.Helper()
+	rt := router.New()
+	rt.Handle(http.MethodGet, path, h)
+	srv := httptest.NewUnstartedServer(rt)
+	srv.Config.Protocols = new(http.Protocols)
+	srv.Config.Protocols.SetHTTP1(true)
+	srv.Config.Protocols.SetUnencryptedHTTP2(true)
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+// h2cClient speaks HTTP/2 without TLS only, like a mesh sidecar.
+func h2cClient() *http.Client {
+	p := new(http.Protocols)
+	p.SetUnencryptedHTTP2(true)
+	return &http.Client{Transport: &http.Transport{Protocols: p}}
+}
+
+func TestWebSocketOverH2C(t *testing.T) {
+	url := startH2CServer(t, "/ws/jobs/{id}", NewWebSocketHandler(newFakeSource()))
+
+	resp, err := h2cClient().Get(url + "/ws/jobs/j1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body apierror.Envelope
+	json.NewDecoder(resp.Body).Decode(&body)
+	resp.Body.Close()
+	if resp.ProtoMajor != 2 || resp.StatusCode != http.StatusHTTPVersionNotSupported ||
+		body.Error == nil || body.Error.Code != apierror.CodeHTTP11Required {
+		t.Errorf("over HTTP/2: %s %d, error %+v; want 505 http_1_1_required", resp.Proto, resp.StatusCode, body.Error)
+	}
+
+	// The same server still upgrades HTTP/1.1 clients.
+	c, _, err := websocket.Dial("ws"+strings.TrimPrefix(url, "http")+"/ws/jobs/j1", nil)
+	if err != nil {
+		t.Fatalf("over HTTP/1.1: %v", err)
+	}
+	c.Close()
+}
+
+func TestDrainClosesAndRefuses(t *testing.T) {
+	hub := newHub(newFakeSource())
+	url := startServer(t, hub)
+	c, _, err := websocket.Dial(url+"/ws/jobs/j1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	eventually(t, "the connection to be tracked", func() bool { return hub.Connections().Len() == 1 })
+
+	drained := make(chan error, 1)
+	go func() {
+		n, err := hub.Connections().Drain(context.Background(), "server shutting down")
+		if n != 1 {
+			t.Errorf("Drain closed %d streams, want 1", n)
+		}
+		drained <- err
+	}()
+
+	c.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err = c.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Fatalf("read: %v, want going away close", err)
+	}
+	// Reading the close frame echoed it, so Drain need not wait for
+	// closeAckWait.
+	select {
+	case err := <-drained:
+		if err != nil {
+			t.Errorf("Drain: %v", err)
+		}
+	case <-time.After(closeAckWait / 2):
+		t.Fatal("Drain did not return after the client acknowledged")
+	}
+
+	_, resp, err := websocket.Dial(url+"/ws/jobs/j1", nil)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("upgrade while draining: resp = %v, err = %v, want 503", resp, err)
+	}
+}
+
+func TestInvalidClientMessagesClose(t *testing.T) {
+	for name, tt := range map[string]struct {
+		typ  int
+		data string
+		code int
+	}{
+		"too big":          {websocket.TextMessage, `{"type":"auth","token":"` + strings.Repeat("x", 100) + `"}`, websocket.CloseMessageTooBig},
+		"binary":           {websocket.BinaryMessage, "\x00\xff\xfe", websocket.CloseUnsupportedData},
+		"not JSON":         {websocket.TextMessage, "hello", websocket.ClosePolicyViolation},
+		"two objects":      {websocket.TextMessage, `{"type":"auth","token":"fresh"}{}`, websocket.ClosePolicyViolation},
+		"array":            {websocket.TextMessage, `["auth"]`, websocket.ClosePolicyViolation},
+		"null":             {websocket.TextMessage, `null`, websocket.ClosePolicyViolation},
+		"type not string":  {websocket.TextMessage, `{"type":1}`, websocket.ClosePolicyViolation},
+		"token not string": {websocket.TextMessage, `{"type":"auth","token":{}}`, websocket.ClosePolicyViolation},
+		"no token":         {websocket.TextMessage, `{"type":"auth"}`, websocket.ClosePolicyViolation},
+		"unknown type":     {websocket.TextMessage, `{"type":"subscribe","job_id":"j2"}`, websocket.ClosePolicyViolation},
+	} {
+		t.Run(name, func(t *testing.T) {
+			src := newFakeSource()
+			h := NewWebSocketHandler(src)
+			h.MaxMessageBytes = 64
+			rt := router.New()
+			rt.Handle(http.MethodGet, "/ws/jobs/{id}", h)
+			srv := httptest.NewServer(rt)
+			defer srv.Close()
+			c, _, err := websocket.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws/jobs/j1", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+
+			if err := c.WriteMessage(tt.typ, []byte(tt.data)); err != nil {
+				t.Fatal(err)
+			}
+			c.SetReadDeadline(time.Now().Add(2 * time.Second))
+			_, _, err = c.ReadMessage()
+			if !websocket.IsCloseError(err, tt.code) {
+				t.Fatalf("read: %v, want close %d", err, tt.code)
+			}
+			// The connection's subscription goes with it.
+			select {
+			case <-src.cancelled:
+			case <-time.After(2 * time.Second):
+				t.Fatal("subscription still open after the close")
+			}
+		})
+	}
+}
+
+func TestValidClientMessagesKeepConnection(t *testing.T) {
+	src := newFakeSource()
+	c, _, err := websocket.Dial(startServer(t, src)+"/ws/jobs/j1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	// Without a token to refresh, an auth message is accepted and ignored;
+	// fields the protocol does not define are too.
+	c.WriteMessage(websocket.TextMessage, []byte(`{"type":"auth","token":"fresh","client":"web"}`))
+	go func() { src.events <- jobs.Event{Stage: "drafting"} }()
+	if ev := readEvent(t, c); ev.Stage != "drafting" {
+		t.Errorf("event = %+v", ev)
+	}
+}
+
+// startAuthServer serves h as a user whose token expires after ttl.
+func startAuthServer(t *testing.T, h *WebSocketHandler, ttl time.Duration) string {
+	t.Helper()
+	rt := router.New()
+	rt.Handle(http.MethodGet, "/ws/jobs/{id}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims := &gateway.Claims{Subject: "u1", ExpiresAt: gateway.NumericDate(time.Now().Add(ttl).Unix())}
+		h.ServeHTTP(w, r.WithContext(gateway.WithClaims(r.Context(), claims)))
+	}))
+	srv := httptest.NewServer(rt)
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+// fakeVerify accepts "fresh" for u1, "other" for u2 and "moved" for u1 in
+// another tenant, each valid for an hour.
+func fakeVerify(token string) (*gateway.Claims, error) {
+	exp := gateway.NumericDate(time.Now().Add(time.Hour).Unix())
+	switch token {
+	case "fresh":
+		return &gateway.Claims{Subject: "u1", ExpiresAt: exp}, nil
+	case "other":
+		return &gateway.Claims{Subject: "u2", ExpiresAt: exp}, nil
+	case "moved":
+		return &gateway.Claims{Subject: "u1", Tenant: "t2", ExpiresAt: exp}, nil
+	}
+	return nil, errors.New("bad token")
+}
+
+func readAuthReply(t *testing.T, c *websocket.Conn) authReply {
+	t.Helper()
+	c.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, data, err := c.ReadMessage()
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	var reply authReply
+	if err := json.Unmarshal(data, &reply); err != nil {
+		t.Fatalf("decode %s: %v", data, err)
+	}
+	return reply
+}
+
+func TestTokenExpiryClosesConnection(t *testing.T) {
+	h := NewWebSocketHandler(newFakeSource())
+	h.Verify, h.AuthGrace = fakeVerify, 100*time.Millisecond
+	// Expiry has one-second resolution, so the token runs out between one
+	// and two seconds from now.
+	c, _, err := websocket.Dial(startAuthServer(t, h, 2*time.Second)+"/ws/jobs/j1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// A rejected refresh leaves the old expiry standing.
+	for _, token := range []string{"other", "moved"} {
+		c.WriteMessage(websocket.TextMessage, []byte(`{"type":"auth","token":"`+token+`"}`))
+		if reply := readAuthReply(t, c); reply.Type != "auth_error" {
+			t.Errorf("%s: reply = %+v, want auth_error", token, reply)
+		}
+	}
+
+	start := time.Now()
+	c.SetReadDeadline(time.Now().Add(4 * time.Second))
+	_, _, err = c.ReadMessage()
+	if !websocket.IsCloseError(err, CloseTokenExpired) {
+		t.Fatalf("read: %v, want token expired close", err)
+	}
+	if waited := time.Since(start); waited < time.Second {
+		t.Errorf("closed after %v, before the token expired", waited)
+	}
+}
+
+func TestTokenRefreshKeepsConnection(t *testing.T) {
+	src := newFakeSource()
+	h := NewWebSocketHandler(src)
+	h.Verify, h.AuthGrace = fakeVerify, 200*time.Millisecond
+	c, _, err := websocket.Dial(startAuthServer(t, h, time.Second)+"/ws/jobs/j1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.WriteMessage(websocket.TextMessage, []byte(`{"type":"auth","token":"fresh"}`))
+	reply := readAuthReply(t, c)
+	if reply.Type != "auth_ok" || reply.ExpiresAt == nil || time.Until(*reply.ExpiresAt) < 59*time.Minute {
+		t.Fatalf("reply = %+v, want auth_ok an hour out", reply)
+	}
+
+	time.Sleep(1500 * time.Millisecond) // past the original expiry and grace
+	go func() { src.events <- jobs.Event{Stage: "drafting"} }()
+	if ev := readEvent(t, c); ev.Stage != "drafting" {
+		t.Errorf("event after refresh = %+v", ev)
+	}
+}
+
+func TestWebSocketOnlyForOwner(t *testing.T) {
+	src := newFakeSource()
+	var lookups atomic.Int32
+	h := NewWebSocketHandler(src)
+	h.Owner = ownedBy("user-1", &lookups)
+	rt := router.New()
+	rt.Handle(http.MethodGet, "/ws/jobs/{id}", asQueryUser(h))
+	srv := httptest.NewServer(rt)
+	t.Cleanup(srv.Close)
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws/jobs/"
+
+	for _, path := range []string{"j1?user=user-2", "missing?user=user-1"} {
+		c, resp, err := websocket.Dial(url+path, nil)
+		if err == nil {
+			c.Close()
+		}
+		if resp == nil || resp.StatusCode != http.StatusNotFound {
+			t.Errorf("%s: resp = %v, err = %v, want 404", path, resp, err)
+		}
+	}
+	if n := src.opens.Load(); n != 0 || lookups.Load() != 2 {
+		t.Fatalf("subscribed %d times after %d lookups, want none after 2", n, lookups.Load())
+	}
+
+	c, _, err := websocket.Dial(url+"j1?user=user-1", nil)
+	if err != nil {
+		t.Fatalf("owner: %v", err)
+	}
+	defer c.Close()
+	go func() { src.events <- jobs.Event{Seq: 1, Stage: "drafting"} }()
+	if ev := readEvent(t, c); ev.Seq != 1 {
+		t.Errorf("owner got %+v", ev)
+	}
+}
+
+func TestConnectionLimits(t *testing.T) {
+	hub := newHub(newFakeSource())
+	hub.Connections().MaxPerIP = 3
+	hub.Connections().MaxPerPrincipal = 2
+	rt := router.New()
+	// Clients name their user in the query; "anon" stays unauthenticated.
+	rt.Handle(http.MethodGet, "/ws/jobs/{id}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user := r.URL.Query().Get("user"); user != "anon" {
+			r = r.WithContext(gateway.WithClaims(r.Context(), &gateway.Claims{Subject: user}))
+		}
+		NewWebSocketHandler(hub).ServeHTTP(w, r)
+	}))
+	srv := httptest.NewServer(rt)
+	t.Cleanup(srv.Close)
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws/jobs/j1?user="
+
+	dial := func(user string, want int) *websocket.Conn {
+		t.Helper()
+		c, resp, err := websocket.Dial(url+user, nil)
+		if want == http.StatusSwitchingProtocols {
+			if err != nil {
+				t.Fatalf("dial as %s: %v", user, err)
+			}
+			t.Cleanup(func() { c.Close() })
+			return c
+		}
+		if err == nil || resp == nil || resp.StatusCode != want {
+			t.Fatalf("dial as %s: resp = %v, err = %v, want %d", user, resp, err, want)
+		}
+		return nil
+	}
+
+	first := dial("user-1", http.StatusSwitchingProtocols)
+	dial("user-1", http.StatusSwitchingProtocols)
+	dial("user-1", http.StatusTooManyRequests)
+	dial("anon", http.StatusSwitchingProtocols)
+	// All four share 127.0.0.1, which is now at its limit.
+	dial("user-2", http.StatusTooManyRequests)
+
+	byPrincipal, byIP := hub.Connections().Counts()
+	if byPrincipal["user-1"] != 2 || byPrincipal["anon"] != 0 || byIP["127.0.0.1"] != 3 {
+		t.Errorf("counts = %v %v", byPrincipal, byIP)
+	}
+
+	// Dropping the TCP connection without a close frame gives the slot
+	// back all the same.
+	first.Close()
+	eventually(t, "the slot to be released", func() bool {
+		byPrincipal, _ := hub.Connections().Counts()
+		return byPrincipal["user-1"] == 1
+	})
+	dial("user-2", http.StatusSwitchingProtocols)
+}
@@ -0,0 +1,134 @@
+package realtime
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/gateway"
+)
+
+// CloseTokenExpired is the close code sent when a connection's token
+// expired and no fresh one arrived within the grace window. Clients
+// should authenticate again before reconnecting.
+const CloseTokenExpired = 4001
+
+// DefaultAuthGrace is how long past its token's expiry a connection stays
+// open when WebSocketHandler.AuthGrace is zero.
+const DefaultAuthGrace = 30 * time.Second
+
+var (
+	errSubjectChanged = errors.New("realtime: token is for another subject")
+	errTenantChanged  = errors.New("realtime: token is for another tenant")
+)
+
+// authMessage is what clients send to refresh their token:
+// {"type":"auth","token":"<JWT>"}. It is the only message they may send.
+type authMessage struct {
+	Type  string `json:"type"`
+	Token string `json:"token"`
+}
+
+// authReply answers an auth message: "auth_ok" with the new expiry, or
+// "auth_error" when the token was rejected and the old expiry stands.
+type authReply struct {
+	Type      string     `json:"type"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Message   string     `json:"message,omitempty"`
+}
+
+// tokenUpdate is the outcome of one auth message.
+type tokenUpdate struct {
+	expires time.Time
+	err     error
+}
+
+// tokenClock tracks when a connection's credentials run out. readPump
+// verifies the tokens clients send and writePump, the connection's only
+// writer, answers them and enforces the deadline. A nil tokenClock never
+// expires.
+type tokenClock struct {
+	subject string
+	tenant  string
+	grace   time.Duration
+	verify  func(string) (*gateway.Claims, error)
+	updates chan tokenUpdate
+	// timer fires when the grace window after the token's expiry ends.
+	timer *time.Timer
+}
+
+// newTokenClock returns the clock for a connection opened with claims, or
+// nil if they do not expire or tokens cannot be verified.
+func newTokenClock(claims *gateway.Claims, verify func(string) (*gateway.Claims, error), grace time.Duration) *tokenClock {
+	if claims == nil || claims.ExpiresAt == 0 || verify == nil {
+		return nil
+	}
+	if grace <= 0 {
+		grace = DefaultAuthGrace
+	}
+	return &tokenClock{
+		subject: claims.Subject,
+		tenant:  claims.Tenant,
+		grace:   grace,
+		verify:  verify,
+		updates: make(chan tokenUpdate),
+		timer:   time.NewTimer(time.Until(claims.ExpiresAt.Time()) + grace),
+	}
+}
+
+// handle verifies the token in an auth message and passes the outcome to
+// writePump.
+func (tc *tokenClock) handle(ctx context.Context, msg authMessage) {
+	if tc == nil {
+		return
+	}
+	var u tokenUpdate
+	claims, err := tc.verify(msg.Token)
+	switch {
+	case err != nil:
+		u.err = err
+	case claims.Subject != tc.subject:
+		u.err = errSubjectChanged
+	case claims.Tenant != tc.tenant:
+		u.err = errTenantChanged
+	default:
+		u.expires = claims.ExpiresAt.Time()
+	}
+	select {
+	case tc.updates <- u:
+	case <-ctx.Done():
+	}
+}
+
+// expired fires once the token has expired and the grace window passed.
+func (tc *tokenClock) expired() <-chan time.Time {
+	if tc == nil {
+		return nil
+	}
+	return tc.timer.C
+}
+
+// refreshed delivers the outcome of each auth message.
+func (tc *tokenClock) refreshed() <-chan tokenUpdate {
+	if tc == nil {
+		return nil
+	}
+	return tc.updates
+}
+
+func (tc *tokenClock) stop() {
+	if tc != nil {
+		tc.timer.Stop()
+	}
+}
+
+// apply moves the deadline to the new token's expiry, unless it was
+// rejected, and returns the reply to send.
+func (tc *tokenClock) apply(u tokenUpdate) authReply {
+	if u.err != nil {
+		return authReply{Type: "auth_error", Message: "the token is invalid, expired or for another subject"}
+	}
+	tc.timer.Reset(time.Until(u.expires) + tc.grace)
+	exp := u.expires.UTC()
+	return authReply{Type: "auth_ok", ExpiresAt: &exp}
+}
@@ -0,0 +1,152 @@
+// Package respond contains the helpers every gateway handler uses to write
+// response bodies: JSON, or MessagePack for clients that ask for it with
+// Accept. Error responses are written with package apierror.
+package respond
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/content-factory/go-gateway/internal/msgpack"
+)
+
+// Media types a body can be written in.
+const (
+	MediaJSON    = "application/json"
+	MediaMsgPack = msgpack.ContentType
+)
+
+// msgpackAliases are the other names clients use for MessagePack.
+var msgpackAliases = []string{"application/x-msgpack", "application/vnd.msgpack"}
+
+// Write writes v with the given status code in the media type Negotiate
+// picks for r, JSON unless the client prefers MessagePack.
+func Write(w http.ResponseWriter, r *http.Request, status int, v any) {
+	media, _ := Negotiate(r)
+	body, err := Encode(media, v)
+	if err != nil {
+		panic(err)
+	}
+	h := w.Header()
+	h.Set("Content-Type", media)
+	Vary(w, "Accept")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// Encode returns v encoded as media, MediaJSON or MediaMsgPack. JSON ends
+// with a newline, as JSON writes it.
+func Encode(media string, v any) ([]byte, error) {
+	if media == MediaMsgPack {
+		return msgpack.Marshal(v)
+	}
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Negotiate returns the media type r's Accept header prefers, MediaJSON or
+// MediaMsgPack, and whether it accepts either at all. Quality values are
+// honoured; a tie, a missing header and a wildcard go to JSON. When
+// neither is acceptable it still returns MediaJSON, for the 406 that
+// should follow.
+func Negotiate(r *http.Request) (string, bool) {
+	accept := r.Header.Values("Accept")
+	if len(accept) == 0 {
+		return MediaJSON, true
+	}
+	// The quality of each type is that of its most specific match.
+	qJSON, qMsgPack := -1.0, -1.0
+	specJSON, specMsgPack := -1, -1
+	for _, value := range accept {
+		for part := range strings.SplitSeq(value, ",") {
+			media, params, err := mime.ParseMediaType(part)
+			if err != nil {
+				continue
+			}
+			q := 1.0
+			if s, ok := params["q"]; ok {
+				if q, err = strconv.ParseFloat(s, 64); err != nil || q < 0 || q > 1 {
+					continue
+				}
+			}
+			if spec := specificity(media, MediaJSON); spec > specJSON {
+				qJSON, specJSON = q, spec
+			}
+			if spec := msgpackSpecificity(media); spec > specMsgPack {
+				qMsgPack, specMsgPack = q, spec
+			}
+		}
+	}
+	switch {
+	case qMsgPack > 0 && qMsgPack > qJSON:
+		return MediaMsgPack, true
+	case qJSON > 0:
+		return MediaJSON, true
+	}
+	return MediaJSON, false
+}
+
+// Accepts reports whether r's Accept header takes media: whether the
+// quality of its most specific match is above zero. A missing header takes
+// anything.
+func Accepts(r *http.Request, media string) bool {
+	accept := r.Header.Values("Accept")
+	if len(accept) == 0 {
+		return true
+	}
+	best, spec := -1.0, -1
+	for _, value := range accept {
+		for part := range strings.SplitSeq(value, ",") {
+			accepted, params, err := mime.ParseMediaType(part)
+			if err != nil {
+				continue
+			}
+			q := 1.0
+			if s, ok := params["q"]; ok {
+				if q, err = strconv.ParseFloat(s, 64); err != nil || q < 0 || q > 1 {
+					continue
+				}
+			}
+			if sp := specificity(accepted, media); sp > spec {
+				best, spec = q, sp
+			}
+		}
+	}
+	return best > 0
+}
+
+// IsMsgPack reports whether a Content-Type header names MessagePack.
+func IsMsgPack(contentType string) bool {
+	media, _, err := mime.ParseMediaType(contentType)
+	return err == nil && msgpackSpecificity(media) == 2
+}
+
+// specificity is how closely an Accept range matches media: 2 for the
+// type itself, 1 for type/*, 0 for */* and -1 for no match.
+func specificity(accepted, media string) int {
+	switch {
+	case accepted == media:
+		return 2
+	case accepted == "*/*":
+		return 0
+	case strings.HasSuffix(accepted, "/*") && strings.HasPrefix(media, strings.TrimSuffix(accepted, "*")):
+		return 1
+	}
+	return -1
+}
+
+func msgpackSpecificity(accepted string) int {
+	for _, alias := range msgpackAliases {
+		if accepted == alias {
+			return 2
+		}
+	}
+	return specificity(accepted, MediaMsgPack)
+}
@@ -0,0 +1,108 @@
+package respond
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	for accept, want := range map[string]struct {
+		media string
+		ok    bool
+	}{
+		"":                                      {MediaJSON, true},
+		"*/*":                                   {MediaJSON, true},
+		"application/json":                      {MediaJSON, true},
+		"application/msgpack":                   {MediaMsgPack, true},
+		"application/x-msgpack":                 {MediaMsgPack, true},
+		"application/*":                         {MediaJSON, true},
+		"application/msgpack, application/json": {MediaJSON, true},
+		"application/json;q=0.5, application/msgpack": {MediaMsgPack, true},
+		"application/msgpack;q=0.9, */*;q=0.1":        {MediaMsgPack, true},
+		"application/json;q=0, */*":                   {MediaMsgPack, true},
+		"text/html":                                   {MediaJSON, false},
+		"application/json;q=0":                        {MediaJSON, false},
+		"application/xml, text/*":                     {MediaJSON, false},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if accept != "" {
+			r.Header.Set("Accept", accept)
+		}
+		media, ok := Negotiate(r)
+		if media != want.media || ok != want.ok {
+			t.Errorf("Negotiate(%q) = %s, %v; want %s, %v", accept, media, ok, want.media, want.ok)
+		}
+	}
+}
+
+func TestAccepts(t *testing.T) {
+	const ndjson = "application/x-ndjson"
+	for accept, want := range map[string]bool{
+		"":                                   true,
+		"*/*":                                true,
+		ndjson:                               true,
+		"application/*":                      true,
+		"application/json":                   false,
+		"application/*;q=0, " + ndjson:       true,
+		ndjson + ";q=0, */*":                 false,
+		"text/event-stream, application/xml": false,
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if accept != "" {
+			r.Header.Set("Accept", accept)
+		}
+		if got := Accepts(r, ndjson); got != want {
+			t.Errorf("Accepts(%q) = %v, want %v", accept, got, want)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", MediaMsgPack)
+	rec := httptest.NewRecorder()
+	Write(rec, r, http.StatusCreated, map[string]int{"n": 1})
+	if rec.Code != http.StatusCreated || rec.Header().Get("Content-Type") != MediaMsgPack || rec.Header().Get("Vary") != "Accept" {
+		t.Fatalf("status %d, headers %v", rec.Code, rec.Header())
+	}
+	if got := rec.Body.String(); got != "\x81\xa1n\x01" {
+		t.Errorf("body = %q", got)
+	}
+
+	rec = httptest.NewRecorder()
+	Write(rec, httptest.NewRequest(http.MethodGet, "/", nil), http.StatusOK, map[string]int{"n": 1})
+	if rec.Header().Get("Content-Type") != MediaJSON || rec.Body.String() != "{\"n\":1}\n" {
+		t.Errorf("JSON: %v %q", rec.Header(), rec.Body)
+	}
+}
+
+func TestIsMsgPack(t *testing.T) {
+	for ct, want := range map[string]bool{
+		"application/msgpack":              true,
+		"application/x-msgpack; charset=x": true,
+		"application/json":                 false,
+		"":                                 false,
+	} {
+		if got := IsMsgPack(ct); got != want {
+			t.Errorf("IsMsgPack(%q) = %v", ct, got)
+		}
+	}
+}
+
+func TestVary(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rec.Header().Set("Vary", "accept, Origin")
+	Vary(rec, "Accept", "authorization", "X-API-Key", "Authorization", "")
+	if got := Varies(rec.Header()); strings.Join(got, ",") != "Accept,Origin,Authorization,X-Api-Key" {
+		t.Errorf("Varies = %q", got)
+	}
+
+	rec = httptest.NewRecorder()
+	rec.Header().Set("Vary", "*")
+	Vary(rec, "Accept")
+	if got := rec.Header().Values("Vary"); len(got) != 1 {
+		t.Errorf("Vary = %q, want only *", got)
+	}
+}
@@ -0,0 +1,48 @@
+package respond
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Vary declares that the response w is writing depends on the request
+// headers named, adding those Vary does not list yet. Handlers and
+// middleware call it for every header they read to shape a response, so
+// shared caches keep one copy per value; the cache package keys its
+// entries by the same headers. Vary: * already covers everything.
+func Vary(w http.ResponseWriter, headers ...string) {
+	h := w.Header()
+	listed := Varies(h)
+	for _, name := range headers {
+		name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+		if name == "" || hasVary(listed, name) {
+			continue
+		}
+		h.Add("Vary", name)
+		listed = append(listed, name)
+	}
+}
+
+// Varies returns the header names the Vary headers of h list, in
+// canonical form, without duplicates.
+func Varies(h http.Header) []string {
+	var names []string
+	for _, value := range h.Values("Vary") {
+		for part := range strings.SplitSeq(value, ",") {
+			name := http.CanonicalHeaderKey(strings.TrimSpace(part))
+			if name != "" && !hasVary(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+func hasVary(names []string, name string) bool {
+	for _, n := range names {
+		if n == name || n == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package retry
+
+import (
+	"log/slog"
+	"sync"
+
+	"github.com/content-factory/go-gateway/internal/metrics"
+)
+
+// Defaults of the retry budget, used by the config package. They are
+// those of gRPC's retry throttling examples: after a run of failures the
+// budget allows about one retry per ten successful calls.
+const (
+	DefaultBudgetMaxTokens  = 10
+	DefaultBudgetTokenRatio = 0.1
+)
+
+var (
+	budgetUtilization = metrics.NewGaugeVec("gateway_backend_retry_budget_utilization",
+		"Share of the retry budget used up, from 0 (full) to 1 (empty); retries stop past 0.5.")
+	throttled = metrics.NewCounterVec("gateway_backend_retries_throttled_total",
+		"Backend call retries not made because the retry budget was spent.",
+		"method")
+)
+
+// Budget caps retries at a fraction of backend calls, so that during a
+// brownout the gateway does not multiply the traffic of a struggling
+// backend. It is gRPC's retry throttling: the budget holds up to
+// maxTokens tokens, starting full; each call failing with a retryable
+// error takes one, each other call puts back ratio. Retries are allowed
+// only while more than half the tokens are left.
+//
+// One Budget is shared by every retrying Conn of the process. A nil
+// Budget allows every retry.
+type Budget struct {
+	mu         sync.Mutex
+	max        float64
+	ratio      float64
+	tokens     float64
+	throttling bool
+}
+
+// NewBudget returns a full Budget of maxTokens tokens, refilled by ratio
+// per successful call. With maxTokens 0 it returns nil, no budget.
+func NewBudget(maxTokens int, ratio float64) *Budget {
+	if maxTokens <= 0 {
+		return nil
+	}
+	if ratio <= 0 {
+		ratio = DefaultBudgetTokenRatio
+	}
+	budgetUtilization.With().Set(0)
+	return &Budget{max: float64(maxTokens), ratio: ratio, tokens: float64(maxTokens)}
+}
+
+// record accounts for the outcome of one call attempt.
+func (b *Budget) record(err error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if IsRetryable(err) {
+		b.tokens = max(b.tokens-1, 0)
+	} else {
+		b.tokens = min(b.tokens+b.ratio, b.max)
+	}
+	budgetUtilization.With().Set(1 - b.tokens/b.max)
+	if b.throttling && b.allowLocked() {
+		b.throttling = false
+		slog.Info("backend retries resumed", "retry_budget_tokens", b.tokens)
+	}
+}
+
+// allow reports whether a retry of method may be made now.
+func (b *Budget) allow(method string) bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.allowLocked() {
+		return true
+	}
+	throttled.With(method).Inc()
+	if !b.throttling {
+		// Logged once per spell rather than per call, which would flood
+		// the log just when the backend is failing.
+		b.throttling = true
+		slog.Warn("retry budget spent, throttling backend retries", "retry_budget_tokens", b.tokens, "method", method)
+	}
+	return false
+}
+
+func (b *Budget) allowLocked() bool {
+	return b.tokens > b.max/2
+}
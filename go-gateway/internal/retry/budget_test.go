@@ -0,0 +1,83 @@
+package retry
+
+import (
+	"context"
+	"testing"
+)
+
+// alwaysFailing fails every call with unavailable.
+type alwaysFailing struct{ fakeConn }
+
+func (c *alwaysFailing) Invoke(ctx context.Context, method string, req, resp any) error {
+	c.calls++
+	return unavailable
+}
+
+func TestBudgetThrottlesRetries(t *testing.T) {
+	budget := NewBudget(10, 0.1)
+	conn := &alwaysFailing{}
+	r, _ := newTestConn(conn, Config{MaxAttempts: 3, Budget: budget})
+	before := throttled.With("read").Value()
+
+	// Ten tokens, retries while more than five are left: each failed
+	// attempt takes one.
+	for i, want := range []int{3, 2, 1, 1} {
+		conn.calls = 0
+		r.Invoke(context.Background(), "read", nil, nil)
+		if conn.calls != want {
+			t.Errorf("call %d: %d attempts, want %d", i+1, conn.calls, want)
+		}
+	}
+	if got := throttled.With("read").Value() - before; got != 3 {
+		t.Errorf("throttled metric grew by %v, want 3", got)
+	}
+	if got := budgetUtilization.With().Value(); got != 0.7 {
+		t.Errorf("utilization = %v, want 0.7", got)
+	}
+
+	// Successes refill the budget a tenth of a token each, until retries
+	// are allowed again.
+	ok := &fakeConn{}
+	succeeding, _ := newTestConn(ok, Config{Budget: budget})
+	for range 31 {
+		succeeding.Invoke(context.Background(), "write", nil, nil)
+	}
+	conn.calls = 0
+	r.Invoke(context.Background(), "read", nil, nil)
+	if conn.calls != 2 {
+		t.Errorf("after refilling: %d attempts, want 2", conn.calls)
+	}
+}
+
+func TestBudgetIsShared(t *testing.T) {
+	budget := NewBudget(4, 0.1)
+	a, b := &alwaysFailing{}, &alwaysFailing{}
+	ra, _ := newTestConn(a, Config{MaxAttempts: 5, Budget: budget})
+	rb, _ := newTestConn(b, Config{MaxAttempts: 5, Budget: budget})
+
+	ra.Invoke(context.Background(), "read", nil, nil)
+	if a.calls != 2 {
+		t.Errorf("first conn: %d attempts, want 2", a.calls)
+	}
+	// The first conn spent the budget of the second, which is not allowed
+	// a retry.
+	rb.Invoke(context.Background(), "read", nil, nil)
+	if b.calls != 1 {
+		t.Errorf("second conn: %d attempts, want 1", b.calls)
+	}
+}
+
+func TestNoBudget(t *testing.T) {
+	if b := NewBudget(0, 0.5); b != nil {
+		t.Fatalf("NewBudget(0) = %+v, want nil", b)
+	}
+	conn := &alwaysFailing{}
+	r, _ := newTestConn(conn, Config{MaxAttempts: 3})
+	for range 10 {
+		conn.calls = 0
+		r.Invoke(context.Background(), "read", nil, nil)
+		if conn.calls != 3 {
+			t.Fatalf("%d attempts without a budget, want 3", conn.calls)
+		}
+	}
+}
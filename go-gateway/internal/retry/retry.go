@@ -0,0 +1,162 @@
+// Package retry retries idempotent backend calls that fail transiently,
+// with capped exponential backoff and jitter.
+package retry
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/metrics"
+	"github.com/content-factory/go-gateway/internal/rpc"
+)
+
+// Defaults used by the config package.
+const (
+	DefaultMaxAttempts    = 3
+	DefaultInitialBackoff = 100 * time.Millisecond
+	DefaultMaxBackoff     = 2 * time.Second
+	DefaultMaxElapsed     = 10 * time.Second
+)
+
+// Config tunes retries.
+type Config struct {
+	// MaxAttempts bounds the number of calls, including the first. One
+	// disables retrying.
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry; each later wait
+	// doubles, up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// MaxElapsed bounds the time spent on one call across all attempts.
+	MaxElapsed time.Duration
+	// Budget, if set, throttles retries once too many calls are failing.
+	// Every call is accounted to it, then, idempotent or not.
+	Budget *Budget
+}
+
+var retries = metrics.NewCounterVec("gateway_backend_retries_total",
+	"Backend calls retried after a transient failure.",
+	"method")
+
+// IsRetryable reports whether err is worth another attempt: the service
+// was unreachable or did not answer in time.
+func IsRetryable(err error) bool {
+	switch rpc.CodeOf(err) {
+	case rpc.Unavailable, rpc.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+// Conn is the backend transport; it matches backend.Conn.
+type Conn interface {
+	Invoke(ctx context.Context, method string, req, resp any) error
+	NewStream(ctx context.Context, method string, req any) (rpc.ClientStream, error)
+}
+
+// Wrap retries calls on conn whose method idempotent reports as safe to
+// repeat; other methods are called once. For streams only opening the
+// stream is retried.
+//
+// A retry is never started once the caller's context is done, and the
+// backoff before it must end before the context's deadline, so a retried
+// call never outlives the client's timeout. Nor is one started while
+// cfg.Budget is spent.
+func Wrap(conn Conn, cfg Config, idempotent func(method string) bool) Conn {
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = DefaultMaxAttempts
+	}
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = DefaultInitialBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = DefaultMaxBackoff
+	}
+	cfg.MaxBackoff = max(cfg.MaxBackoff, cfg.InitialBackoff)
+	if cfg.MaxElapsed <= 0 {
+		cfg.MaxElapsed = DefaultMaxElapsed
+	}
+	return &retrying{conn: conn, cfg: cfg, idempotent: idempotent, now: time.Now, sleep: sleep}
+}
+
+type retrying struct {
+	conn       Conn
+	cfg        Config
+	idempotent func(string) bool
+	now        func() time.Time
+	sleep      func(context.Context, time.Duration) error
+}
+
+func (r *retrying) Invoke(ctx context.Context, method string, req, resp any) error {
+	return r.do(ctx, method, func() error {
+		return r.conn.Invoke(ctx, method, req, resp)
+	})
+}
+
+func (r *retrying) NewStream(ctx context.Context, method string, req any) (rpc.ClientStream, error) {
+	var s rpc.ClientStream
+	err := r.do(ctx, method, func() error {
+		var err error
+		s, err = r.conn.NewStream(ctx, method, req)
+		return err
+	})
+	return s, err
+}
+
+func (r *retrying) do(ctx context.Context, method string, call func() error) error {
+	start := r.now()
+	err := call()
+	r.cfg.Budget.record(err)
+	if !r.idempotent(method) {
+		return err
+	}
+	backoff := r.cfg.InitialBackoff
+	for attempt := 1; attempt < r.cfg.MaxAttempts && IsRetryable(err); attempt++ {
+		if ctx.Err() != nil {
+			break
+		}
+		wait := jitter(backoff)
+		if r.now().Add(wait).Sub(start) > r.cfg.MaxElapsed {
+			break
+		}
+		if deadline, ok := ctx.Deadline(); ok && r.now().Add(wait).After(deadline) {
+			break
+		}
+		// A throttled call fails at once rather than after the backoff.
+		if !r.cfg.Budget.allow(method) {
+			break
+		}
+		if r.sleep(ctx, wait) != nil {
+			break
+		}
+		retries.With(method).Inc()
+		gateway.Logger(ctx).DebugContext(ctx, "retrying backend call", "rpc_method", method, "attempt", attempt+1, "error", err)
+		err = call()
+		r.cfg.Budget.record(err)
+		backoff = min(backoff*2, r.cfg.MaxBackoff)
+	}
+	return err
+}
+
+// jitter picks a wait in [d/2, d), so that clients failing together do
+// not retry in lockstep.
+func jitter(d time.Duration) time.Duration {
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + rand.N(half)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,132 @@
+package retry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/rpc"
+)
+
+// fakeConn fails Invoke with the queued errors, then succeeds.
+type fakeConn struct {
+	errs  []error
+	calls int
+}
+
+func (c *fakeConn) Invoke(ctx context.Context, method string, req, resp any) error {
+	c.calls++
+	if len(c.errs) == 0 {
+		return nil
+	}
+	err := c.errs[0]
+	c.errs = c.errs[1:]
+	return err
+}
+
+func (c *fakeConn) NewStream(ctx context.Context, method string, req any) (rpc.ClientStream, error) {
+	return nil, c.Invoke(ctx, method, req, nil)
+}
+
+// newTestConn returns a retrying conn on a fake clock whose sleeps only
+// advance the clock; the waits it was asked for are recorded.
+func newTestConn(conn Conn, cfg Config) (*retrying, *[]time.Duration) {
+	r := Wrap(conn, cfg, func(method string) bool { return method == "read" }).(*retrying)
+	clock := time.Unix(1_700_000_000, 0)
+	var waits []time.Duration
+	r.now = func() time.Time { return clock }
+	r.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		clock = clock.Add(d)
+		return nil
+	}
+	return r, &waits
+}
+
+var unavailable = rpc.Errorf(rpc.Unavailable, "down")
+
+func TestRetriesTransientFailures(t *testing.T) {
+	conn := &fakeConn{errs: []error{unavailable, rpc.Errorf(rpc.DeadlineExceeded, "slow")}}
+	r, waits := newTestConn(conn, Config{MaxAttempts: 5, InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second})
+	before := retries.With("read").Value()
+
+	if err := r.Invoke(context.Background(), "read", nil, nil); err != nil {
+		t.Fatalf("err = %v, want success on third attempt", err)
+	}
+	if conn.calls != 3 {
+		t.Errorf("calls = %d, want 3", conn.calls)
+	}
+	if w := *waits; len(w) != 2 || w[0] < 50*time.Millisecond || w[0] >= 100*time.Millisecond || w[1] < 100*time.Millisecond || w[1] >= 200*time.Millisecond {
+		t.Errorf("waits = %v, want jittered 100ms then 200ms", w)
+	}
+	if got := retries.With("read").Value() - before; got != 2 {
+		t.Errorf("retries metric grew by %v, want 2", got)
+	}
+}
+
+func TestDoesNotRetry(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		err    error
+	}{
+		{"non-idempotent method", "write", unavailable},
+		{"permanent error", "read", rpc.Errorf(rpc.InvalidArgument, "bad")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &fakeConn{errs: []error{tt.err, tt.err}}
+			r, _ := newTestConn(conn, Config{MaxAttempts: 3})
+			if err := r.Invoke(context.Background(), tt.method, nil, nil); err != tt.err {
+				t.Errorf("err = %v, want %v", err, tt.err)
+			}
+			if conn.calls != 1 {
+				t.Errorf("calls = %d, want 1", conn.calls)
+			}
+		})
+	}
+}
+
+func TestStopsAtLimits(t *testing.T) {
+	failing := func() *fakeConn {
+		return &fakeConn{errs: []error{unavailable, unavailable, unavailable, unavailable, unavailable, unavailable}}
+	}
+
+	conn := failing()
+	r, _ := newTestConn(conn, Config{MaxAttempts: 3})
+	if err := r.Invoke(context.Background(), "read", nil, nil); err != unavailable {
+		t.Errorf("err = %v", err)
+	}
+	if conn.calls != 3 {
+		t.Errorf("max attempts: calls = %d, want 3", conn.calls)
+	}
+
+	conn = failing()
+	r, _ = newTestConn(conn, Config{MaxAttempts: 10, InitialBackoff: time.Second, MaxBackoff: time.Second, MaxElapsed: 2500 * time.Millisecond})
+	r.Invoke(context.Background(), "read", nil, nil)
+	if conn.calls < 3 || conn.calls > 5 {
+		t.Errorf("max elapsed: calls = %d, want 3 to 5", conn.calls)
+	}
+
+	// The caller's deadline is nearer than the first backoff: no retry.
+	conn = failing()
+	r, _ = newTestConn(conn, Config{MaxAttempts: 10, InitialBackoff: time.Second})
+	r.now = time.Now
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	r.Invoke(ctx, "read", nil, nil)
+	if conn.calls != 1 {
+		t.Errorf("deadline: calls = %d, want 1", conn.calls)
+	}
+}
+
+func TestStreamOpenIsRetried(t *testing.T) {
+	conn := &fakeConn{errs: []error{unavailable}}
+	r, _ := newTestConn(conn, Config{})
+	if _, err := r.NewStream(context.Background(), "read", nil); err != nil {
+		t.Fatal(err)
+	}
+	if conn.calls != 2 {
+		t.Errorf("calls = %d, want 2", conn.calls)
+	}
+}
@@ -0,0 +1,107 @@
+// Package routeconf holds per-route overrides of the gateway-wide request
+// timeout, rate limit, rate-limit weight, body size limit and slow-request
+// threshold, the per-route coalescing window of duplicate submissions and
+// how long cached reads may be served stale when the backend fails.
+//
+// The middleware enforcing each setting looks up the route the router
+// matched, so it must run inside the router, or capture the template with
+// router.CapturePattern and use LookupPattern: the override for the route's
+// method and template, such as "POST /api/v1/content", comes first, then
+// the one for its template alone, such as "/api/v1/jobs/{id}", and
+// otherwise the global setting applies. Within an override, zero fields
+// fall back the same way.
+package routeconf
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/router"
+)
+
+// Override replaces global settings for one route. Zero fields keep the
+// global value.
+type Override struct {
+	Timeout time.Duration
+	RPS     float64
+	Burst   int
+	// Weight is how many tokens of the client's rate limit each request
+	// takes, for routes costing more than one backend call.
+	Weight       int
+	MaxBodyBytes int64
+	// SlowThreshold is how long a request may take before it is logged as
+	// slow; negative means never.
+	SlowThreshold time.Duration
+	// CoalesceWindow is how long a submission's response is given again
+	// to identical submissions by the same principal, see
+	// idempotency.Coalescer. Zero turns coalescing off.
+	CoalesceWindow time.Duration
+	// StaleIfError is how long past its TTL a cached GET response is kept
+	// to answer requests the backend fails, see cache.Middleware. Zero
+	// turns stale responses off.
+	StaleIfError time.Duration
+}
+
+// Table maps route keys, "METHOD /template" or "/template", to their
+// overrides. A nil Table has none.
+type Table map[string]Override
+
+// Key returns the Table key for method requests to pattern; an empty
+// method matches every method.
+func Key(method, pattern string) string {
+	if method == "" {
+		return pattern
+	}
+	return strings.ToUpper(method) + " " + pattern
+}
+
+// Lookup returns the override for the matched route of r, merged from its
+// method entry and its template entry. It reports false when neither
+// exists, including for requests no router has matched yet.
+func (t Table) Lookup(r *http.Request) (Override, bool) {
+	return t.LookupPattern(r.Method, router.Pattern(r))
+}
+
+// LookupPattern is Lookup for method requests to the route template
+// pattern; an empty pattern has no override.
+func (t Table) LookupPattern(method, pattern string) (Override, bool) {
+	if len(t) == 0 || pattern == "" {
+		return Override{}, false
+	}
+	byMethod, hasMethod := t[Key(method, pattern)]
+	byPattern, hasPattern := t[pattern]
+	switch {
+	case hasMethod && hasPattern:
+		return byMethod.or(byPattern), true
+	case hasMethod:
+		return byMethod, true
+	}
+	return byPattern, hasPattern
+}
+
+// or fills in the zero fields of o from fallback.
+func (o Override) or(fallback Override) Override {
+	if o.Timeout == 0 {
+		o.Timeout = fallback.Timeout
+	}
+	if o.RPS == 0 {
+		o.RPS, o.Burst = fallback.RPS, fallback.Burst
+	}
+	if o.Weight == 0 {
+		o.Weight = fallback.Weight
+	}
+	if o.MaxBodyBytes == 0 {
+		o.MaxBodyBytes = fallback.MaxBodyBytes
+	}
+	if o.SlowThreshold == 0 {
+		o.SlowThreshold = fallback.SlowThreshold
+	}
+	if o.CoalesceWindow == 0 {
+		o.CoalesceWindow = fallback.CoalesceWindow
+	}
+	if o.StaleIfError == 0 {
+		o.StaleIfError = fallback.StaleIfError
+	}
+	return o
+}
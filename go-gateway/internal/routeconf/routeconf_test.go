@@ -0,0 +1,118 @@
+package routeconf_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/middleware"
+	"github.com/content-factory/go-gateway/internal/ratelimit"
+	"github.com/content-factory/go-gateway/internal/routeconf"
+	"github.com/content-factory/go-gateway/internal/router"
+)
+
+func TestLookupOrder(t *testing.T) {
+	table := routeconf.Table{
+		"POST /api/v1/content": {Timeout: time.Minute},
+		"/api/v1/content":      {Timeout: time.Second, MaxBodyBytes: 10},
+		"/api/v1/jobs":         {RPS: 5, Burst: 10},
+		"POST /api/v1/batch":   {Weight: 3},
+		"/api/v1/batch":        {Weight: 1, Timeout: time.Second, CoalesceWindow: 2 * time.Second, StaleIfError: time.Minute},
+	}
+	var got routeconf.Override
+	var found bool
+	rt := router.New()
+	capture := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { got, found = table.Lookup(r) })
+	rt.Handle(http.MethodPost, "/api/v1/content", capture)
+	rt.Handle(http.MethodGet, "/api/v1/content", capture)
+	rt.Handle(http.MethodGet, "/api/v1/jobs/{id}", capture)
+	rt.Handle(http.MethodPost, "/api/v1/batch", capture)
+
+	tests := []struct {
+		method, path string
+		want         routeconf.Override
+		found        bool
+	}{
+		// The method entry wins; its zero fields come from the template entry.
+		{http.MethodPost, "/api/v1/content", routeconf.Override{Timeout: time.Minute, MaxBodyBytes: 10}, true},
+		{http.MethodGet, "/api/v1/content", routeconf.Override{Timeout: time.Second, MaxBodyBytes: 10}, true},
+		// Overrides name templates, not paths.
+		{http.MethodGet, "/api/v1/jobs/j1", routeconf.Override{}, false},
+		{http.MethodPost, "/api/v1/batch", routeconf.Override{Weight: 3, Timeout: time.Second, CoalesceWindow: 2 * time.Second, StaleIfError: time.Minute}, true},
+	}
+	for _, tt := range tests {
+		rt.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.path, nil))
+		if got != tt.want || found != tt.found {
+			t.Errorf("%s %s: Lookup = %+v, %v; want %+v, %v", tt.method, tt.path, got, found, tt.want, tt.found)
+		}
+	}
+
+	if _, ok := table.Lookup(httptest.NewRequest(http.MethodGet, "/api/v1/jobs", nil)); ok {
+		t.Error("Lookup outside the router found an override")
+	}
+	if o, ok := table.LookupPattern(http.MethodPost, "/api/v1/content"); !ok || o.Timeout != time.Minute || o.MaxBodyBytes != 10 {
+		t.Errorf("LookupPattern = %+v, %v", o, ok)
+	}
+}
+
+// TestSlowAndFastRoutes serves a slow submission route and a fast polling
+// route behind the same middleware, each with its own overrides.
+func TestSlowAndFastRoutes(t *testing.T) {
+	table := routeconf.Table{
+		"POST /submit": {Timeout: time.Second, MaxBodyBytes: 64},
+		"GET /status":  {RPS: 1, Burst: 5},
+	}
+	var timeout middleware.TimeoutVar
+	timeout.Set(20 * time.Millisecond)
+	timeout.SetRoutes(table)
+	limiter := ratelimit.New(ratelimit.Config{RPS: 1, Burst: 3, Routes: table})
+	chain := middleware.NewChain(limiter.Middleware, middleware.RouteBodyLimit(table, 8), timeout.Middleware)
+
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(50 * time.Millisecond):
+			w.WriteHeader(http.StatusAccepted)
+		case <-r.Context().Done():
+		}
+	})
+	rt := router.New()
+	rt.Handle(http.MethodPost, "/submit", chain.Then(slow))
+	rt.Handle(http.MethodPost, "/other", chain.Then(slow))
+	rt.Handle(http.MethodGet, "/status", chain.ThenFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	serve := func(method, path, body string) int {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.RemoteAddr = "192.0.2.1:1234"
+		rt.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// The slow route outlives the global 20ms timeout and takes a body
+	// over the global 8 bytes; a route without overrides does neither.
+	if code := serve(http.MethodPost, "/submit", strings.Repeat("a", 32)); code != http.StatusAccepted {
+		t.Errorf("slow route: status = %d, want 202", code)
+	}
+	if code := serve(http.MethodPost, "/submit", strings.Repeat("a", 65)); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("slow route over its body limit: status = %d, want 413", code)
+	}
+	if code := serve(http.MethodPost, "/other", ""); code != http.StatusGatewayTimeout {
+		t.Errorf("route without overrides: status = %d, want 504 from the global timeout", code)
+	}
+	if code := serve(http.MethodPost, "/other", ""); code != http.StatusTooManyRequests {
+		t.Errorf("route without overrides: status = %d, want 429 past the global burst", code)
+	}
+
+	// The fast route allows a burst of 5 in its own bucket, after the
+	// global bucket of 3 was used up above.
+	for i := range 5 {
+		if code := serve(http.MethodGet, "/status", ""); code != http.StatusOK {
+			t.Fatalf("fast route request %d: status = %d, want 200", i+1, code)
+		}
+	}
+	if code := serve(http.MethodGet, "/status", ""); code != http.StatusTooManyRequests {
+		t.Errorf("fast route past its burst: status = %d, want 429", code)
+	}
+}
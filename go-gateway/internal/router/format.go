@@ -0,0 +1,66 @@
+package router
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/content-factory/go-gateway/internal/uuid"
+)
+
+// paramFormat is a format a parameter can be declared to have, as in
+// "{id:uuid}". canonical reports whether a segment has the format and
+// returns it in canonical form, which is what handlers see.
+type paramFormat struct {
+	// description completes "must be ..." in the 400 for a segment
+	// without the format.
+	description string
+	canonical   func(string) (string, bool)
+}
+
+// formats are the parameter formats patterns can declare:
+//
+//   - uuid: a UUID in either case, with or without hyphens, in braces or
+//     as a urn:uuid: URN; handlers get it lowercase and hyphenated.
+//   - int: a non-negative decimal integer; handlers get it without
+//     leading zeros.
+//   - slug: letters, digits and single hyphens between them; handlers
+//     get it lowercase.
+var formats = map[string]paramFormat{
+	"uuid": {description: "a UUID", canonical: uuid.Parse},
+	"int":  {description: "a non-negative integer", canonical: canonicalInt},
+	"slug": {description: "a slug of letters, digits and hyphens", canonical: canonicalSlug},
+}
+
+func canonicalInt(s string) (string, bool) {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return "", false
+		}
+	}
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return "", false
+	}
+	return strconv.FormatUint(n, 10), true
+}
+
+func canonicalSlug(s string) (string, bool) {
+	s = strings.ToLower(s)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+		case c == '-' && i > 0 && i < len(s)-1 && s[i-1] != '-':
+		default:
+			return "", false
+		}
+	}
+	return s, s != ""
+}
+
+// invalidParam is a path segment without the format its parameter
+// declares.
+type invalidParam struct {
+	name   string
+	format paramFormat
+}
@@ -0,0 +1,87 @@
+package router
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// headHandler answers HEAD with get, the route's GET handler, sending its
+// status and headers but not its body. The body is counted rather than
+// kept, so when get sets no Content-Length and does not flush, the
+// response carries the length a GET would have had.
+func headHandler(get http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hw := &headWriter{ResponseWriter: w}
+		get.ServeHTTP(hw, r)
+		hw.finish()
+	})
+}
+
+// headWriter discards the body of a response to HEAD, holding the header
+// back until the handler returns so the body length is known.
+type headWriter struct {
+	http.ResponseWriter
+	status  int
+	written int64
+	sent    bool
+}
+
+func (w *headWriter) WriteHeader(status int) {
+	if w.status != 0 || w.sent {
+		return
+	}
+	// Informational responses are not the final header.
+	if status >= 100 && status < 200 {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.status = status
+}
+
+func (w *headWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.written += int64(len(b))
+	return len(b), nil
+}
+
+// Flush sends the header now, without a computed length, since the
+// handler is streaming and its length cannot be known.
+func (w *headWriter) Flush() {
+	w.send(false)
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *headWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *headWriter) finish() {
+	w.send(true)
+}
+
+func (w *headWriter) send(withLength bool) {
+	if w.sent {
+		return
+	}
+	w.sent = true
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	h := w.Header()
+	if withLength && h.Get("Content-Length") == "" && h.Get("Transfer-Encoding") == "" && bodyAllowed(status) {
+		h.Set("Content-Length", strconv.FormatInt(w.written, 10))
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// bodyAllowed reports whether a response with status may carry a body,
+// and so a Content-Length.
+func bodyAllowed(status int) bool {
+	return status != http.StatusNoContent && status != http.StatusNotModified && status >= 200
+}
@@ -0,0 +1,64 @@
+package router
+
+import (
+	"context"
+	"net/http"
+)
+
+type matchKey struct{}
+
+type param struct {
+	name  string
+	value string
+}
+
+type match struct {
+	pattern string
+	params  []param
+}
+
+// Param returns the path segment captured by the named parameter of the
+// route that matched r, or "" when there is no such parameter.
+func Param(r *http.Request, name string) string {
+	m, ok := r.Context().Value(matchKey{}).(*match)
+	if !ok {
+		return ""
+	}
+	for _, p := range m.params {
+		if p.name == name {
+			return p.value
+		}
+	}
+	return ""
+}
+
+// Pattern returns the template of the route that matched r, such as
+// "/api/v1/content/{id}", or "" when r was not dispatched by a Router.
+func Pattern(r *http.Request) string {
+	if m, ok := r.Context().Value(matchKey{}).(*match); ok {
+		return m.pattern
+	}
+	return ""
+}
+
+type captureKey struct{}
+
+// CapturePattern returns a request whose context lets an outer middleware
+// learn which route template the Router eventually matched. Call the
+// returned function after the handler has run; it reports "" when no route
+// matched. Nested captures share one slot, so every middleware that asks
+// sees the pattern.
+func CapturePattern(r *http.Request) (*http.Request, func() string) {
+	if slot, ok := r.Context().Value(captureKey{}).(*string); ok {
+		return r, func() string { return *slot }
+	}
+	slot := new(string)
+	ctx := context.WithValue(r.Context(), captureKey{}, slot)
+	return r.WithContext(ctx), func() string { return *slot }
+}
+
+func recordPattern(r *http.Request, pattern string) {
+	if slot, ok := r.Context().Value(captureKey{}).(*string); ok {
+		*slot = pattern
+	}
+}
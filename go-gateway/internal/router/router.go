@@ -0,0 +1,349 @@
+// Package router implements the gateway's HTTP router.
+//
+// Routes are registered per method against a pattern made of static
+// segments, named parameters ("{id}") and an optional trailing catch-all
+// ("{path...}"). When several patterns match a path the most specific one
+// wins: static segments beat parameters, which beat catch-alls.
+//
+// A parameter can declare a format, as in "{id:uuid}"; see formats for the
+// list. A path whose segment lacks the format gets a JSON 400 naming the
+// parameter, unless another route matches it, and handlers see the
+// segment in canonical form. Formats are part of a route, not its
+// template: Pattern reports "/jobs/{id}" for "/jobs/{id:uuid}".
+//
+// A route with a GET handler also answers HEAD, by running the GET handler
+// without sending the body, and every route answers OPTIONS with its
+// allowed methods. Handlers registered for HEAD or OPTIONS themselves take
+// precedence.
+//
+// Matched requests log with their route: it is added to their
+// gateway.Logger.
+package router
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/gateway"
+)
+
+type segmentKind int
+
+const (
+	segmentWildcard segmentKind = iota
+	segmentParam
+	segmentStatic
+)
+
+type segment struct {
+	kind  segmentKind
+	value string // literal text for static segments, name otherwise
+	// format is the declared format of a parameter, if any.
+	format string
+}
+
+type route struct {
+	// pattern is the route's template, without parameter formats.
+	pattern  string
+	segments []segment
+	handlers map[string]http.Handler
+	// visible holds the conditions of methods registered with HandleWhen.
+	visible map[string]func(*http.Request) bool
+}
+
+// Router dispatches requests to handlers by method and path pattern.
+// Unmatched paths get a JSON 404; paths that match with the wrong method
+// get a JSON 405 carrying an Allow header.
+type Router struct {
+	routes []*route
+}
+
+// New returns an empty Router.
+func New() *Router {
+	return &Router{}
+}
+
+// Handle registers h for method requests matching pattern. Registering the
+// same method and pattern twice panics, as it is always a programming error.
+func (rt *Router) Handle(method, pattern string, h http.Handler) {
+	rt.HandleWhen(method, pattern, h, nil)
+}
+
+// HandleWhen registers h like Handle, but only for requests visible
+// reports true for. To the others the method does not exist: it is left
+// out of Allow and OPTIONS, and a path with no visible method gets 404.
+// A nil visible is always true.
+func (rt *Router) HandleWhen(method, pattern string, h http.Handler, visible func(*http.Request) bool) {
+	method = strings.ToUpper(method)
+	segments := parsePattern(pattern)
+	template := templateOf(segments)
+	var rte *route
+	for _, existing := range rt.routes {
+		if existing.pattern == template {
+			if !slices.Equal(existing.segments, segments) {
+				panic("router: " + pattern + " declares other parameter formats than " + method + "'s siblings")
+			}
+			rte = existing
+			break
+		}
+	}
+	if rte == nil {
+		rte = &route{
+			pattern:  template,
+			segments: segments,
+			handlers: make(map[string]http.Handler),
+			visible:  make(map[string]func(*http.Request) bool),
+		}
+		rt.routes = append(rt.routes, rte)
+		sort.SliceStable(rt.routes, func(i, j int) bool {
+			return moreSpecific(rt.routes[i].segments, rt.routes[j].segments)
+		})
+	}
+	if _, dup := rte.handlers[method]; dup {
+		panic("router: duplicate route " + method + " " + pattern)
+	}
+	rte.handlers[method] = h
+	if visible != nil {
+		rte.visible[method] = visible
+	}
+}
+
+// HandleFunc registers a handler function for method requests matching
+// pattern.
+func (rt *Router) HandleFunc(method, pattern string, h http.HandlerFunc) {
+	rt.Handle(method, pattern, h)
+}
+
+// ServeHTTP implements http.Handler.
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := splitPath(r.URL.Path)
+	var allowed []string
+	var invalid *invalidParam
+
+	for _, rte := range rt.routes {
+		params, bad, ok := rte.match(path)
+		if !ok {
+			continue
+		}
+		h, ok := rte.handler(r)
+		if !ok {
+			allowed = appendMethods(allowed, rte.methods(r))
+			continue
+		}
+		if bad != nil {
+			// A less specific route may still take the path as it is.
+			if invalid == nil {
+				invalid = bad
+			}
+			continue
+		}
+		recordPattern(r, rte.pattern)
+		ctx := context.WithValue(r.Context(), matchKey{}, &match{pattern: rte.pattern, params: params})
+		ctx = gateway.WithLogAttrs(ctx, "route", rte.pattern)
+		h.ServeHTTP(w, r.WithContext(ctx))
+		return
+	}
+
+	if invalid != nil {
+		apierror.Write(w, r, apierror.InvalidRequest(
+			"path parameter "+invalid.name+" must be "+invalid.format.description,
+			apierror.FieldError{Field: invalid.name, Message: "must be " + invalid.format.description}))
+		return
+	}
+	if len(allowed) > 0 {
+		sort.Strings(allowed)
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		apierror.Write(w, r, apierror.MethodNotAllowed(
+			r.Method+" is not supported for "+r.URL.Path))
+		return
+	}
+	apierror.Write(w, r, apierror.NotFound("no route matches "+r.URL.Path))
+}
+
+// handler returns the handler serving r's method on rte: the one
+// registered for it or, for HEAD and OPTIONS, the automatic one.
+func (rte *route) handler(r *http.Request) (http.Handler, bool) {
+	if h, ok := rte.lookup(r, r.Method); ok {
+		return h, true
+	}
+	switch r.Method {
+	case http.MethodHead:
+		if get, ok := rte.lookup(r, http.MethodGet); ok {
+			return headHandler(get), true
+		}
+	case http.MethodOptions:
+		allow := rte.methods(r)
+		if allow == nil {
+			return nil, false
+		}
+		sort.Strings(allow)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Allow", strings.Join(allow, ", "))
+			w.WriteHeader(http.StatusNoContent)
+		}), true
+	}
+	return nil, false
+}
+
+// lookup returns the handler registered for method on rte, if r may see
+// it.
+func (rte *route) lookup(r *http.Request, method string) (http.Handler, bool) {
+	h, ok := rte.handlers[method]
+	if !ok {
+		return nil, false
+	}
+	if visible, gated := rte.visible[method]; gated && !visible(r) {
+		return nil, false
+	}
+	return h, true
+}
+
+// methods lists the methods rte answers r, automatic ones included.
+func (rte *route) methods(r *http.Request) []string {
+	methods := make([]string, 0, len(rte.handlers)+2)
+	for m := range rte.handlers {
+		if _, ok := rte.lookup(r, m); ok {
+			methods = append(methods, m)
+		}
+	}
+	if len(methods) == 0 {
+		return nil
+	}
+	if _, ok := rte.lookup(r, http.MethodGet); ok {
+		methods = appendMethods(methods, []string{http.MethodHead})
+	}
+	return appendMethods(methods, []string{http.MethodOptions})
+}
+
+// match reports whether path matches rte's template, returning the
+// parameters and, if one lacks its declared format, the first such.
+func (rte *route) match(path []string) ([]param, *invalidParam, bool) {
+	var params []param
+	var invalid *invalidParam
+	for i, seg := range rte.segments {
+		if seg.kind == segmentWildcard {
+			params = append(params, param{name: seg.value, value: strings.Join(path[i:], "/")})
+			return params, invalid, true
+		}
+		if i >= len(path) {
+			return nil, nil, false
+		}
+		switch seg.kind {
+		case segmentStatic:
+			if path[i] != seg.value {
+				return nil, nil, false
+			}
+		case segmentParam:
+			if path[i] == "" {
+				return nil, nil, false
+			}
+			value := path[i]
+			if seg.format != "" {
+				f := formats[seg.format]
+				canonical, ok := f.canonical(value)
+				if !ok && invalid == nil {
+					invalid = &invalidParam{name: seg.value, format: f}
+				}
+				value = canonical
+			}
+			params = append(params, param{name: seg.value, value: value})
+		}
+	}
+	if len(path) != len(rte.segments) {
+		return nil, nil, false
+	}
+	return params, invalid, true
+}
+
+func parsePattern(pattern string) []segment {
+	if !strings.HasPrefix(pattern, "/") {
+		panic("router: pattern must begin with '/': " + pattern)
+	}
+	parts := splitPath(pattern)
+	segments := make([]segment, 0, len(parts))
+	for i, part := range parts {
+		if !strings.HasPrefix(part, "{") || !strings.HasSuffix(part, "}") {
+			segments = append(segments, segment{kind: segmentStatic, value: part})
+			continue
+		}
+		name := part[1 : len(part)-1]
+		if rest, ok := strings.CutSuffix(name, "..."); ok {
+			if i != len(parts)-1 {
+				panic("router: catch-all must be the final segment: " + pattern)
+			}
+			segments = append(segments, segment{kind: segmentWildcard, value: rest})
+			continue
+		}
+		name, format, _ := strings.Cut(name, ":")
+		if _, ok := formats[format]; format != "" && !ok {
+			panic("router: unknown parameter format " + format + ": " + pattern)
+		}
+		segments = append(segments, segment{kind: segmentParam, value: name, format: format})
+	}
+	return segments
+}
+
+// Template returns pattern without its parameter formats: the template
+// Pattern reports for the requests pattern matches.
+func Template(pattern string) string { return templateOf(parsePattern(pattern)) }
+
+// templateOf returns the pattern of segments without parameter formats.
+func templateOf(segments []segment) string {
+	var b strings.Builder
+	for _, seg := range segments {
+		b.WriteByte('/')
+		switch seg.kind {
+		case segmentStatic:
+			b.WriteString(seg.value)
+		case segmentParam:
+			b.WriteString("{" + seg.value + "}")
+		case segmentWildcard:
+			b.WriteString("{" + seg.value + "...}")
+		}
+	}
+	if b.Len() == 0 {
+		return "/"
+	}
+	return b.String()
+}
+
+// splitPath breaks a URL path into segments, ignoring a trailing slash so
+// "/health" and "/health/" are the same route. The root path has no
+// segments.
+func splitPath(p string) []string {
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
+
+func moreSpecific(a, b []segment) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i].kind != b[i].kind {
+			return a[i].kind > b[i].kind
+		}
+	}
+	// A catch-all also matches the empty remainder, so it ranks below the
+	// shorter pattern it extends.
+	switch {
+	case len(a) > len(b):
+		return a[len(b)].kind != segmentWildcard
+	case len(b) > len(a):
+		return b[len(a)].kind == segmentWildcard
+	}
+	return false
+}
+
+func appendMethods(dst, methods []string) []string {
+	for _, m := range methods {
+		if !slices.Contains(dst, m) {
+			dst = append(dst, m)
+		}
+	}
+	return dst
+}
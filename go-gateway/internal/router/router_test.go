@@ -0,0 +1,306 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func serve(rt *Router, method, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	rt.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	return rec
+}
+
+func echo(label string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(label + ":" + Param(r, "id") + ":" + Pattern(r)))
+	}
+}
+
+func TestMethodDispatchAndParams(t *testing.T) {
+	rt := New()
+	rt.HandleFunc(http.MethodGet, "/content/{id}", echo("get"))
+	rt.HandleFunc(http.MethodDelete, "/content/{id}", echo("delete"))
+
+	tests := []struct {
+		method, path, want string
+	}{
+		{http.MethodGet, "/content/abc", "get:abc:/content/{id}"},
+		{http.MethodDelete, "/content/xyz/", "delete:xyz:/content/{id}"},
+	}
+	for _, tt := range tests {
+		rec := serve(rt, tt.method, tt.path)
+		if rec.Code != http.StatusOK || rec.Body.String() != tt.want {
+			t.Errorf("%s %s = %d %q, want 200 %q", tt.method, tt.path, rec.Code, rec.Body.String(), tt.want)
+		}
+	}
+}
+
+func TestStaticBeatsParam(t *testing.T) {
+	rt := New()
+	rt.HandleFunc(http.MethodGet, "/jobs/{id}", echo("param"))
+	rt.HandleFunc(http.MethodGet, "/jobs/latest", echo("static"))
+	rt.HandleFunc(http.MethodGet, "/legacy/{id...}", echo("wild"))
+	rt.HandleFunc(http.MethodGet, "/legacy", echo("root"))
+
+	for path, want := range map[string]string{
+		"/jobs/latest": "static::/jobs/latest",
+		"/jobs/42":     "param:42:/jobs/{id}",
+		"/legacy":      "root::/legacy",
+		"/legacy/a/b":  "wild:a/b:/legacy/{id...}",
+	} {
+		if got := serve(rt, http.MethodGet, path).Body.String(); got != want {
+			t.Errorf("GET %s = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	rt := New()
+	rt.HandleFunc(http.MethodGet, "/content/{id}", echo("get"))
+	rt.HandleFunc(http.MethodDelete, "/content/{id}", echo("delete"))
+
+	rec := serve(rt, http.MethodPost, "/content/abc")
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("status = %d, want 405", rec.Code)
+	}
+	if got := rec.Header().Get("Allow"); got != "DELETE, GET, HEAD, OPTIONS" {
+		t.Errorf("Allow = %q, want %q", got, "DELETE, GET, HEAD, OPTIONS")
+	}
+}
+
+func TestNotFoundIsJSON(t *testing.T) {
+	rec := serve(New(), http.MethodGet, "/missing")
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404", rec.Code)
+	}
+	var body map[string]map[string]string
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if body["error"]["code"] != "not_found" {
+		t.Errorf("error = %q, want not_found", body["error"])
+	}
+}
+
+func TestNestedCapturePattern(t *testing.T) {
+	rt := New()
+	rt.HandleFunc(http.MethodGet, "/jobs/{id}", echo("job"))
+
+	r, outer := CapturePattern(httptest.NewRequest(http.MethodGet, "/jobs/7", nil))
+	r, inner := CapturePattern(r)
+	rt.ServeHTTP(httptest.NewRecorder(), r)
+	if outer() != "/jobs/{id}" || inner() != "/jobs/{id}" {
+		t.Errorf("outer = %q, inner = %q", outer(), inner())
+	}
+}
+
+func TestHeadRunsGet(t *testing.T) {
+	rt := New()
+	rt.HandleFunc(http.MethodGet, "/jobs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("hello, "))
+		w.Write([]byte(Param(r, "id")))
+	})
+	rt.HandleFunc(http.MethodGet, "/sized", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1000")
+		w.Write([]byte("truncated"))
+	})
+	rt.HandleFunc(http.MethodGet, "/empty", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	rec := serve(rt, http.MethodHead, "/jobs/42")
+	if rec.Code != http.StatusOK || rec.Body.Len() != 0 || rec.Header().Get("ETag") != `"v1"` {
+		t.Fatalf("HEAD = %d %q, headers %v", rec.Code, rec.Body, rec.Header())
+	}
+	if got := rec.Header().Get("Content-Length"); got != "9" {
+		t.Errorf("Content-Length = %q, want 9", got)
+	}
+	if got := serve(rt, http.MethodHead, "/sized").Header().Get("Content-Length"); got != "1000" {
+		t.Errorf("handler's Content-Length replaced by %q", got)
+	}
+	if rec := serve(rt, http.MethodHead, "/empty"); rec.Code != http.StatusNoContent || rec.Header().Get("Content-Length") != "" {
+		t.Errorf("204: %d, headers %v", rec.Code, rec.Header())
+	}
+}
+
+func TestHeadOfStreamHasNoLength(t *testing.T) {
+	rt := New()
+	rt.HandleFunc(http.MethodGet, "/events", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: 1\n\n"))
+		http.NewResponseController(w).Flush()
+		w.Write([]byte("data: 2\n\n"))
+	})
+	rec := serve(rt, http.MethodHead, "/events")
+	if rec.Code != http.StatusOK || !rec.Flushed || rec.Body.Len() != 0 || rec.Header().Get("Content-Length") != "" {
+		t.Errorf("HEAD = %d %q, flushed %v, headers %v", rec.Code, rec.Body, rec.Flushed, rec.Header())
+	}
+}
+
+func TestAutomaticOptions(t *testing.T) {
+	rt := New()
+	called := false
+	rt.HandleFunc(http.MethodPost, "/content", func(w http.ResponseWriter, r *http.Request) { called = true })
+	rt.HandleFunc(http.MethodGet, "/content", echo("get"))
+
+	rec := serve(rt, http.MethodOptions, "/content")
+	if rec.Code != http.StatusNoContent || called {
+		t.Fatalf("OPTIONS = %d, handler called %v", rec.Code, called)
+	}
+	if got := rec.Header().Get("Allow"); got != "GET, HEAD, OPTIONS, POST" {
+		t.Errorf("Allow = %q", got)
+	}
+	if rec := serve(rt, http.MethodOptions, "/missing"); rec.Code != http.StatusNotFound {
+		t.Errorf("OPTIONS of an unknown path = %d", rec.Code)
+	}
+}
+
+func TestExplicitHeadAndOptionsWin(t *testing.T) {
+	rt := New()
+	rt.HandleFunc(http.MethodGet, "/files/{id}", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("GET handler ran for", r.Method)
+	})
+	rt.HandleFunc(http.MethodHead, "/files/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "123")
+		w.Header().Set("X-Handler", "head")
+	})
+	rt.HandleFunc(http.MethodOptions, "/files/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Handler", "options")
+	})
+
+	if rec := serve(rt, http.MethodHead, "/files/1"); rec.Header().Get("X-Handler") != "head" || rec.Header().Get("Content-Length") != "123" {
+		t.Errorf("HEAD headers = %v", rec.Header())
+	}
+	if rec := serve(rt, http.MethodOptions, "/files/1"); rec.Code != http.StatusOK || rec.Header().Get("X-Handler") != "options" {
+		t.Errorf("OPTIONS = %d, headers %v", rec.Code, rec.Header())
+	}
+}
+
+func TestHandleWhen(t *testing.T) {
+	rt := New()
+	rt.HandleFunc(http.MethodGet, "/content/{id}", echo("get"))
+	rt.HandleWhen(http.MethodPost, "/content/{id}", echo("post"), func(r *http.Request) bool { return r.Header.Get("X-Beta") != "" })
+	rt.HandleWhen(http.MethodGet, "/beta", echo("beta"), func(r *http.Request) bool { return r.Header.Get("X-Beta") != "" })
+
+	rec := serve(rt, http.MethodOptions, "/content/1")
+	if got := rec.Header().Get("Allow"); got != "GET, HEAD, OPTIONS" {
+		t.Errorf("hidden Allow = %q", got)
+	}
+	if rec := serve(rt, http.MethodPost, "/content/1"); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("hidden POST = %d", rec.Code)
+	}
+	for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut} {
+		if rec := serve(rt, method, "/beta"); rec.Code != http.StatusNotFound {
+			t.Errorf("hidden %s /beta = %d, want 404", method, rec.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodOptions, "/content/1", nil)
+	req.Header.Set("X-Beta", "1")
+	rec = httptest.NewRecorder()
+	rt.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Allow"); got != "GET, HEAD, OPTIONS, POST" {
+		t.Errorf("visible Allow = %q", got)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/beta", nil)
+	req.Header.Set("X-Beta", "1")
+	rec = httptest.NewRecorder()
+	rt.ServeHTTP(rec, req)
+	if rec.Body.String() != "beta::/beta" {
+		t.Errorf("visible GET /beta = %d %q", rec.Code, rec.Body)
+	}
+}
+
+func TestParamFormats(t *testing.T) {
+	rt := New()
+	rt.HandleFunc(http.MethodGet, "/jobs/{id:uuid}", echo("job"))
+	rt.HandleFunc(http.MethodGet, "/pages/{id:int}", echo("page"))
+	rt.HandleFunc(http.MethodGet, "/tags/{id:slug}", echo("tag"))
+
+	const id = "0f8fad5b-d9cb-469f-a165-70867728950e"
+	for path, want := range map[string]string{
+		"/jobs/" + id: "job:" + id + ":/jobs/{id}",
+		"/jobs/0F8FAD5B-D9CB-469F-A165-70867728950E":          "job:" + id + ":/jobs/{id}",
+		"/jobs/0f8fad5bd9cb469fa16570867728950e":              "job:" + id + ":/jobs/{id}",
+		"/jobs/%7B0f8fad5b-d9cb-469f-a165-70867728950e%7D":    "job:" + id + ":/jobs/{id}",
+		"/jobs/urn:uuid:0f8fad5b-d9cb-469f-a165-70867728950e": "job:" + id + ":/jobs/{id}",
+		"/pages/007":       "page:7:/pages/{id}",
+		"/tags/Go-Gateway": "tag:go-gateway:/tags/{id}",
+	} {
+		rec := serve(rt, http.MethodGet, path)
+		if rec.Code != http.StatusOK || rec.Body.String() != want {
+			t.Errorf("GET %s = %d %q, want 200 %q", path, rec.Code, rec.Body.String(), want)
+		}
+	}
+
+	for _, path := range []string{
+		"/jobs/job-1",
+		"/jobs/0f8fad5b-d9cb-469f-a165-70867728950",
+		"/jobs/0f8fad5b-d9cb-469f-a165_70867728950e",
+		"/pages/-1",
+		"/pages/1e3",
+		"/tags/go--gateway",
+		"/tags/-go",
+	} {
+		rec := serve(rt, http.MethodGet, path)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("GET %s = %d, want 400", path, rec.Code)
+			continue
+		}
+		var body struct {
+			Error struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+				Fields  []struct {
+					Field string `json:"field"`
+				} `json:"fields"`
+			} `json:"error"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("decode body: %v", err)
+		}
+		if body.Error.Code != "invalid_request" || len(body.Error.Fields) != 1 || body.Error.Fields[0].Field != "id" {
+			t.Errorf("GET %s error = %+v, want invalid_request naming id", path, body.Error)
+		}
+	}
+}
+
+func TestParamFormatFallsThrough(t *testing.T) {
+	rt := New()
+	rt.HandleFunc(http.MethodGet, "/jobs/{id:uuid}", echo("job"))
+	rt.HandleFunc(http.MethodGet, "/jobs/{id...}", echo("legacy"))
+	rt.HandleFunc(http.MethodPost, "/tasks/{id:uuid}", echo("task"))
+
+	if got := serve(rt, http.MethodGet, "/jobs/job-1").Body.String(); got != "legacy:job-1:/jobs/{id...}" {
+		t.Errorf("GET /jobs/job-1 = %q, want the catch-all", got)
+	}
+	// The method is checked first: a malformed id does not hide a 405.
+	if rec := serve(rt, http.MethodGet, "/tasks/task-1"); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET /tasks/task-1 = %d, want 405", rec.Code)
+	}
+}
+
+func TestUnknownParamFormatPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("registering {id:date} did not panic")
+		}
+	}()
+	New().HandleFunc(http.MethodGet, "/days/{id:date}", echo("day"))
+}
+
+func TestTemplate(t *testing.T) {
+	for pattern, want := range map[string]string{
+		"/":                             "/",
+		"/jobs/{id:uuid}/pages/{n:int}": "/jobs/{id}/pages/{n}",
+		"/legacy/{path...}":             "/legacy/{path...}",
+	} {
+		if got := Template(pattern); got != want {
+			t.Errorf("Template(%q) = %q, want %q", pattern, got, want)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// Package rpc is the gateway's client for the Python content services.
+//
+// It calls their gRPC APIs (see protos/content_factory.proto) with
+// google.golang.org/grpc, and turns the statuses they fail with into
+// *Error values carrying the code, message and details, which the rest of
+// the gateway maps onto HTTP.
+package rpc
+
+import "net/http"
+
+// Code is a gRPC status code. The values match google.golang.org/grpc/codes.
+type Code uint32
+
+// The canonical gRPC status codes.
+const (
+	OK                 Code = 0
+	Canceled           Code = 1
+	Unknown            Code = 2
+	InvalidArgument    Code = 3
+	DeadlineExceeded   Code = 4
+	NotFound           Code = 5
+	AlreadyExists      Code = 6
+	PermissionDenied   Code = 7
+	ResourceExhausted  Code = 8
+	FailedPrecondition Code = 9
+	Aborted            Code = 10
+	OutOfRange         Code = 11
+	Unimplemented      Code = 12
+	Internal           Code = 13
+	Unavailable        Code = 14
+	DataLoss           Code = 15
+	Unauthenticated    Code = 16
+)
+
+var codeNames = [...]string{
+	OK:                 "ok",
+	Canceled:           "canceled",
+	Unknown:            "unknown",
+	InvalidArgument:    "invalid_argument",
+	DeadlineExceeded:   "deadline_exceeded",
+	NotFound:           "not_found",
+	AlreadyExists:      "already_exists",
+	PermissionDenied:   "permission_denied",
+	ResourceExhausted:  "resource_exhausted",
+	FailedPrecondition: "failed_precondition",
+	Aborted:            "aborted",
+	OutOfRange:         "out_of_range",
+	Unimplemented:      "unimplemented",
+	Internal:           "internal",
+	Unavailable:        "unavailable",
+	DataLoss:           "data_loss",
+	Unauthenticated:    "unauthenticated",
+}
+
+// String returns the lower-case name of c, e.g. "not_found".
+func (c Code) String() string {
+	if int(c) < len(codeNames) {
+		return codeNames[c]
+	}
+	return "unknown"
+}
+
+// HTTPStatus returns the HTTP status a gateway should answer with when a
+// call fails with c, following the mapping of grpc-gateway. Codes outside
+// the canonical set map to 500.
+func HTTPStatus(c Code) int {
+	switch c {
+	case OK:
+		return http.StatusOK
+	case Canceled:
+		return StatusClientClosedRequest
+	case InvalidArgument, OutOfRange:
+		return http.StatusBadRequest
+	case DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case NotFound:
+		return http.StatusNotFound
+	case AlreadyExists, Aborted, FailedPrecondition:
+		return http.StatusConflict
+	case PermissionDenied:
+		return http.StatusForbidden
+	case Unauthenticated:
+		return http.StatusUnauthorized
+	case ResourceExhausted:
+		return http.StatusTooManyRequests
+	case Unimplemented:
+		return http.StatusNotImplemented
+	case Unavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// StatusClientClosedRequest is the non-standard status nginx logs for a
+// request the client abandoned, the HTTP counterpart of Canceled.
+const StatusClientClosedRequest = 499
@@ -0,0 +1,212 @@
+package rpc
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	// Registers the standard error details, such as BadRequest, so that
+	// they can be rendered as JSON.
+	_ "google.golang.org/genproto/googleapis/rpc/errdetails"
+
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/metrics"
+)
+
+// Conn is a client connection to a backend service. Method names use the
+// gRPC form "/package.Service/Method", and messages are the generated
+// protobuf types of protos/content_factory.proto.
+type Conn interface {
+	Invoke(ctx context.Context, method string, req, resp any) error
+	NewStream(ctx context.Context, method string, req any) (ClientStream, error)
+	Close() error
+}
+
+// DialOptions tunes the underlying transport of a ClientConn.
+type DialOptions struct {
+	// DialTimeout bounds establishing a new connection.
+	DialTimeout time.Duration
+	// KeepaliveTime is how long a connection may sit idle before a
+	// keepalive ping is sent.
+	KeepaliveTime time.Duration
+	// KeepaliveTimeout is how long to wait for a ping to be acknowledged
+	// before the connection is considered dead.
+	KeepaliveTimeout time.Duration
+	// DeadlineMargin is taken off the time left on a call's context before
+	// it is sent, so the service stops working on the call, and the caller
+	// gives up on it, while there is still time to answer the client with
+	// an error.
+	DeadlineMargin time.Duration
+	// MaxResponseBytes caps what a call may read from the service: a
+	// unary response, or all a stream sends. Uncapped, if set, exempts the
+	// streams of the methods it reports, such as downloads, from the total,
+	// though each of their messages still may not exceed the cap. Zero
+	// means no cap.
+	MaxResponseBytes int64
+	Uncapped         func(method string) bool
+	// StatsHandler, if set, is told of every call on the connection, as
+	// the tracing package's client spans are.
+	StatsHandler stats.Handler
+}
+
+// ErrResponseTooLarge is returned by calls whose response exceeds
+// DialOptions.MaxResponseBytes. The response is abandoned unread.
+var ErrResponseTooLarge = Errorf(ResourceExhausted, "the response exceeds the gateway's size limit")
+
+var oversized = metrics.NewCounterVec("gateway_backend_oversized_responses_total",
+	"Backend responses abandoned for exceeding the response size cap, by method.",
+	"method")
+
+// ClientConn is a gRPC client connection to a single backend address. Like
+// the grpc.ClientConn it wraps, it connects lazily on the first call.
+type ClientConn struct {
+	target   string
+	cc       *grpc.ClientConn
+	margin   time.Duration
+	maxBytes int64
+	uncapped func(string) bool
+}
+
+// Dial returns a ClientConn for addr, a host:port pair. An https:// prefix
+// asks for TLS; without one, or with http://, the connection is plaintext.
+func Dial(addr string, opts DialOptions) (*ClientConn, error) {
+	target := strings.TrimSuffix(addr, "/")
+	creds := insecure.NewCredentials()
+	switch {
+	case strings.HasPrefix(target, "https://"):
+		target = strings.TrimPrefix(target, "https://")
+		creds = credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	case strings.HasPrefix(target, "http://"):
+		target = strings.TrimPrefix(target, "http://")
+	}
+	maxMessage := math.MaxInt32
+	if opts.MaxResponseBytes > 0 && opts.MaxResponseBytes < math.MaxInt32 {
+		maxMessage = int(opts.MaxResponseBytes)
+	}
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(maxMessage)),
+	}
+	if opts.DialTimeout > 0 {
+		dialOpts = append(dialOpts, grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff:           backoff.DefaultConfig,
+			MinConnectTimeout: opts.DialTimeout,
+		}))
+	}
+	if opts.KeepaliveTime > 0 {
+		dialOpts = append(dialOpts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:    opts.KeepaliveTime,
+			Timeout: opts.KeepaliveTimeout,
+		}))
+	}
+	if opts.StatsHandler != nil {
+		dialOpts = append(dialOpts, grpc.WithStatsHandler(opts.StatsHandler))
+	}
+	cc, err := grpc.NewClient(target, dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return &ClientConn{
+		target:   target,
+		cc:       cc,
+		margin:   max(opts.DeadlineMargin, 0),
+		maxBytes: max(opts.MaxResponseBytes, 0),
+		uncapped: opts.Uncapped,
+	}, nil
+}
+
+// Target returns the address calls are sent to.
+func (c *ClientConn) Target() string { return c.target }
+
+// Invoke sends req to method and decodes the reply into resp. Failures are
+// always returned as *Error so callers can switch on CodeOf.
+func (c *ClientConn) Invoke(ctx context.Context, method string, req, resp any) error {
+	ctx, cancel, err := c.deadline(ctx, method)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	if err := c.cc.Invoke(ctx, method, req, resp); err != nil {
+		return c.fromStatus(ctx, method, err)
+	}
+	return nil
+}
+
+// tooLarge counts and logs a response abandoned for exceeding limit.
+func tooLarge(ctx context.Context, method string, limit int64) error {
+	oversized.With(method).Inc()
+	gateway.Logger(ctx).ErrorContext(ctx, "backend response exceeds size limit", "rpc_method", method, "limit_bytes", limit)
+	return ErrResponseTooLarge
+}
+
+// deadline returns ctx with its deadline, if it has one, brought forward
+// by the margin; gRPC sends the service the time then left. It fails with
+// DeadlineExceeded when no time would be left.
+func (c *ClientConn) deadline(ctx context.Context, method string) (context.Context, context.CancelFunc, error) {
+	if err := ctx.Err(); errors.Is(err, context.Canceled) {
+		// Not DeadlineExceeded below: the caller gave up, and the backend
+		// is not to blame.
+		return nil, nil, Errorf(Canceled, "%s not sent: %v", method, err)
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return ctx, func() {}, nil
+	}
+	deadline = deadline.Add(-c.margin)
+	if time.Until(deadline) < time.Millisecond {
+		return nil, nil, Errorf(DeadlineExceeded, "deadline exceeded before %s was sent", method)
+	}
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	return ctx, cancel, nil
+}
+
+// Close closes the underlying gRPC connection.
+func (c *ClientConn) Close() error {
+	return c.cc.Close()
+}
+
+// fromStatus turns the error of a gRPC call on method into an *Error,
+// keeping the status's code, message and details. A response over the
+// cap, which gRPC refuses to receive, is ErrResponseTooLarge.
+func (c *ClientConn) fromStatus(ctx context.Context, method string, err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return transportError(ctx, err)
+	}
+	if st.Code() == codes.ResourceExhausted && c.maxBytes > 0 && strings.Contains(st.Message(), "larger than max") {
+		return tooLarge(ctx, method, c.maxBytes)
+	}
+	out := &Error{Code: Code(st.Code()), Message: st.Message()}
+	for _, d := range st.Proto().GetDetails() {
+		if b, err := protojson.Marshal(d); err == nil {
+			out.Details = append(out.Details, json.RawMessage(b))
+		}
+	}
+	return out
+}
+
+func transportError(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(ctx.Err(), context.Canceled):
+		return Errorf(Canceled, "%v", err)
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return Errorf(DeadlineExceeded, "%v", err)
+	}
+	return Errorf(Unavailable, "%v", err)
+}
+
+var _ Conn = (*ClientConn)(nil)
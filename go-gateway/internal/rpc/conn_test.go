@@ -0,0 +1,294 @@
+package rpc
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/content-factory/go-gateway/internal/rpctest"
+)
+
+func dial(t *testing.T, addr string, opts DialOptions) *ClientConn {
+	t.Helper()
+	conn, err := Dial(addr, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// sendAll answers a streaming call with msgs, then ends it with err.
+func sendAll(stream grpc.ServerStream, err error, msgs ...proto.Message) error {
+	var req structpb.Struct
+	if err := stream.RecvMsg(&req); err != nil {
+		return err
+	}
+	for _, m := range msgs {
+		if err := stream.SendMsg(m); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+func TestInvokeRoundTrip(t *testing.T) {
+	var gotMethod, gotTrace string
+	var gotDeadline bool
+	addr := rpctest.Serve(t, rpctest.Unary(func(ctx context.Context, method string, req *structpb.Struct) (proto.Message, error) {
+		gotMethod = method
+		_, gotDeadline = ctx.Deadline()
+		md, _ := metadata.FromIncomingContext(ctx)
+		gotTrace = strings.Join(md.Get("traceparent"), ",")
+		return rpctest.Struct(map[string]any{"job_id": req.GetFields()["job_id"].GetStringValue(), "status": "queued"}), nil
+	}))
+	conn := dial(t, addr, DialOptions{})
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	ctx = AppendOutgoingHeader(ctx, "Traceparent", "00-trace-span-01")
+
+	var resp structpb.Struct
+	err := conn.Invoke(ctx, "/content_factory.ContentOrchestrator/GetJob", rpctest.Struct(map[string]any{"job_id": "j1"}), &resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := resp.GetFields(); f["job_id"].GetStringValue() != "j1" || f["status"].GetStringValue() != "queued" {
+		t.Errorf("resp = %v", &resp)
+	}
+	if gotMethod != "/content_factory.ContentOrchestrator/GetJob" {
+		t.Errorf("method = %s", gotMethod)
+	}
+	if !gotDeadline {
+		t.Error("no deadline sent for a context with one")
+	}
+	if gotTrace != "00-trace-span-01" {
+		t.Errorf("outgoing metadata = %q", gotTrace)
+	}
+}
+
+func TestInvokeErrors(t *testing.T) {
+	addr := rpctest.Serve(t, rpctest.Unary(func(ctx context.Context, method string, req *structpb.Struct) (proto.Message, error) {
+		st, _ := status.New(codes.NotFound, "job j1 not found").WithDetails(&errdetails.ResourceInfo{ResourceType: "job", ResourceName: "j1"})
+		return nil, st.Err()
+	}))
+	conn := dial(t, addr, DialOptions{})
+
+	err := conn.Invoke(context.Background(), "/svc/Get", &emptypb.Empty{}, &emptypb.Empty{})
+	rerr, ok := err.(*Error)
+	if !ok || rerr.Code != NotFound || rerr.Message != "job j1 not found" {
+		t.Fatalf("error = %#v", err)
+	}
+	if len(rerr.Details) != 1 || !strings.Contains(string(rerr.Details[0]), `"resourceName":"j1"`) {
+		t.Errorf("details = %s", rerr.Details)
+	}
+
+	refused := dial(t, "127.0.0.1:1", DialOptions{})
+	if code := CodeOf(refused.Invoke(context.Background(), "/svc/Get", &emptypb.Empty{}, &emptypb.Empty{})); code != Unavailable {
+		t.Errorf("refused connection code = %v, want unavailable", code)
+	}
+}
+
+func TestDeadlineMargin(t *testing.T) {
+	const margin = 100 * time.Millisecond
+	var calls atomic.Int32
+	left := make(chan time.Duration, 2)
+	addr := rpctest.Serve(t, func(_ any, stream grpc.ServerStream) error {
+		calls.Add(1)
+		ctx := stream.Context()
+		method, _ := grpc.MethodFromServerStream(stream)
+		if method == "/svc/Stall" {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		if deadline, ok := ctx.Deadline(); ok {
+			left <- time.Until(deadline)
+		}
+		if method == "/svc/Stream" {
+			return sendAll(stream, nil)
+		}
+		return sendAll(stream, nil, &emptypb.Empty{})
+	})
+	conn := dial(t, addr, DialOptions{DeadlineMargin: margin})
+
+	// The service sees the client's deadline less the margin.
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := conn.Invoke(ctx, "/svc/Unary", &emptypb.Empty{}, &emptypb.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+	s, err := conn.NewStream(ctx, "/svc/Stream", &emptypb.Empty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Recv(&emptypb.Empty{}); err != io.EOF {
+		t.Fatalf("stream ended with %v", err)
+	}
+	for _, call := range []string{"unary", "stream"} {
+		if got, want := <-left, 2*time.Second-margin; got > want || got < want-time.Second/2 {
+			t.Errorf("%s deadline in %v, want just under %v", call, got, want)
+		}
+	}
+
+	// A service that does not answer is given up on a margin before the
+	// client's deadline.
+	ctx, cancel = context.WithTimeout(context.Background(), margin+200*time.Millisecond)
+	defer cancel()
+	err = conn.Invoke(ctx, "/svc/Stall", &emptypb.Empty{}, &emptypb.Empty{})
+	if CodeOf(err) != DeadlineExceeded {
+		t.Errorf("stalled call error = %v, want deadline exceeded", err)
+	}
+	if ctx.Err() != nil {
+		t.Error("stalled call returned after the client's deadline")
+	}
+
+	// A call with no more time left than the margin is not sent.
+	calls.Store(0)
+	ctx, cancel = context.WithTimeout(context.Background(), margin/2)
+	defer cancel()
+	if code := CodeOf(conn.Invoke(ctx, "/svc/Unary", &emptypb.Empty{}, &emptypb.Empty{})); code != DeadlineExceeded {
+		t.Errorf("unary code = %v, want deadline exceeded", code)
+	}
+	if _, err := conn.NewStream(ctx, "/svc/Stream", &emptypb.Empty{}); CodeOf(err) != DeadlineExceeded {
+		t.Errorf("stream error = %v, want deadline exceeded", err)
+	}
+	if n := calls.Load(); n != 0 {
+		t.Errorf("service called %d times with too little time left", n)
+	}
+
+	// Unless the caller has already given up: then it is not the
+	// backend's failure.
+	ctx, cancel = context.WithTimeout(context.Background(), margin/2)
+	cancel()
+	if code := CodeOf(conn.Invoke(ctx, "/svc/Unary", &emptypb.Empty{}, &emptypb.Empty{})); code != Canceled {
+		t.Errorf("cancelled call code = %v, want canceled", code)
+	}
+}
+
+func TestResponseSizeCap(t *testing.T) {
+	const limit = 64
+	text := func(n int) proto.Message { return rpctest.Struct(map[string]any{"a": strings.Repeat("b", n)}) }
+	addr := rpctest.Serve(t, func(_ any, stream grpc.ServerStream) error {
+		switch method, _ := grpc.MethodFromServerStream(stream); method {
+		case "/svc/Small":
+			return sendAll(stream, nil, text(1))
+		case "/svc/Large", "/svc/Huge":
+			return sendAll(stream, nil, text(limit))
+		default:
+			return sendAll(stream, nil, text(limit/2), text(limit/2), text(limit/2), text(limit/2))
+		}
+	})
+	conn := dial(t, addr, DialOptions{
+		MaxResponseBytes: limit,
+		Uncapped:         func(method string) bool { return method != "/svc/Stream" },
+	})
+	ctx := context.Background()
+
+	if err := conn.Invoke(ctx, "/svc/Small", &emptypb.Empty{}, &structpb.Struct{}); err != nil {
+		t.Errorf("small response: %v", err)
+	}
+	before := oversized.With("/svc/Large").Value()
+	if err := conn.Invoke(ctx, "/svc/Large", &emptypb.Empty{}, &structpb.Struct{}); err != ErrResponseTooLarge {
+		t.Errorf("large response error = %v, want ErrResponseTooLarge", err)
+	}
+	if got := oversized.With("/svc/Large").Value() - before; got != 1 {
+		t.Errorf("oversized counter rose by %v, want 1", got)
+	}
+
+	recvAll := func(method string) (int, error) {
+		s, err := conn.NewStream(ctx, method, &emptypb.Empty{})
+		if err != nil {
+			return 0, err
+		}
+		defer s.Close()
+		for n := 0; ; n++ {
+			if err := s.Recv(&structpb.Struct{}); err != nil {
+				if err == io.EOF {
+					err = nil
+				}
+				return n, err
+			}
+		}
+	}
+	if n, err := recvAll("/svc/Stream"); err != ErrResponseTooLarge || n != 1 {
+		t.Errorf("capped stream = %d messages, %v; want 1, ErrResponseTooLarge", n, err)
+	}
+	if n, err := recvAll("/svc/Download"); err != nil || n != 4 {
+		t.Errorf("uncapped stream = %d messages, %v; want 4, nil", n, err)
+	}
+	if _, err := recvAll("/svc/Huge"); err != ErrResponseTooLarge {
+		t.Errorf("uncapped stream with a huge message: %v, want ErrResponseTooLarge", err)
+	}
+}
+
+func TestServerStream(t *testing.T) {
+	n := func(i float64) proto.Message { return rpctest.Struct(map[string]any{"n": i}) }
+	addr := rpctest.Serve(t, func(_ any, stream grpc.ServerStream) error {
+		if method, _ := grpc.MethodFromServerStream(stream); method == "/svc/Fail" {
+			return sendAll(stream, status.Error(codes.Unavailable, "worker died"), n(1))
+		}
+		return sendAll(stream, nil, n(1), n(2))
+	})
+	conn := dial(t, addr, DialOptions{})
+
+	s, err := conn.NewStream(context.Background(), "/svc/Ok", &emptypb.Empty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []float64
+	for {
+		var msg structpb.Struct
+		err := s.Recv(&msg)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, msg.GetFields()["n"].GetNumberValue())
+	}
+	if len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Errorf("messages = %v", got)
+	}
+
+	s, err = conn.NewStream(context.Background(), "/svc/Fail", &emptypb.Empty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Recv(&structpb.Struct{})
+	if err := s.Recv(&structpb.Struct{}); CodeOf(err) != Unavailable {
+		t.Errorf("stream error = %v, want unavailable", err)
+	}
+}
+
+func TestHTTPStatus(t *testing.T) {
+	tests := map[Code]int{
+		OK:                 http.StatusOK,
+		InvalidArgument:    http.StatusBadRequest,
+		NotFound:           http.StatusNotFound,
+		PermissionDenied:   http.StatusForbidden,
+		Unauthenticated:    http.StatusUnauthorized,
+		FailedPrecondition: http.StatusConflict,
+		Unavailable:        http.StatusServiceUnavailable,
+		DeadlineExceeded:   http.StatusGatewayTimeout,
+		Unknown:            http.StatusInternalServerError,
+		Code(42):           http.StatusInternalServerError,
+	}
+	for code, want := range tests {
+		if got := HTTPStatus(code); got != want {
+			t.Errorf("HTTPStatus(%v) = %d, want %d", code, got, want)
+		}
+	}
+}
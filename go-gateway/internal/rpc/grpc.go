@@ -0,0 +1,76 @@
+package rpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Caller is the part of Conn that makes calls.
+type Caller interface {
+	Invoke(ctx context.Context, method string, req, resp any) error
+	NewStream(ctx context.Context, method string, req any) (ClientStream, error)
+}
+
+// AsGRPC returns conn as a grpc.ClientConnInterface, so that the generated
+// clients of protos/content_factory.proto call through conn and whatever
+// wraps it, such as retries and circuit breakers. Call options are
+// ignored: DialOptions sets them for the whole connection. Only unary and
+// server-streaming methods are supported.
+func AsGRPC(conn Caller) grpc.ClientConnInterface {
+	return grpcConn{conn: conn}
+}
+
+type grpcConn struct {
+	conn Caller
+}
+
+func (c grpcConn) Invoke(ctx context.Context, method string, args, reply any, _ ...grpc.CallOption) error {
+	return c.conn.Invoke(ctx, method, args, reply)
+}
+
+func (c grpcConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, _ ...grpc.CallOption) (grpc.ClientStream, error) {
+	if desc.ClientStreams {
+		return nil, Errorf(Unimplemented, "%s: client streaming is not supported", method)
+	}
+	return &grpcStream{ctx: ctx, conn: c.conn, method: method}, nil
+}
+
+// grpcStream is a server-streaming call as the generated clients drive it:
+// the single request message opens the stream, which is closed once a
+// message fails to arrive.
+type grpcStream struct {
+	ctx    context.Context
+	conn   Caller
+	method string
+	stream ClientStream
+}
+
+func (s *grpcStream) SendMsg(m any) error {
+	if s.stream != nil {
+		return Errorf(Internal, "%s: a server-streaming call takes one request", s.method)
+	}
+	stream, err := s.conn.NewStream(s.ctx, s.method, m)
+	if err != nil {
+		return err
+	}
+	s.stream = stream
+	return nil
+}
+
+func (s *grpcStream) RecvMsg(m any) error {
+	if s.stream == nil {
+		return Errorf(Internal, "%s: no request was sent", s.method)
+	}
+	err := s.stream.Recv(m)
+	if err != nil {
+		s.stream.Close()
+	}
+	return err
+}
+
+func (s *grpcStream) CloseSend() error             { return nil }
+func (s *grpcStream) Context() context.Context     { return s.ctx }
+func (s *grpcStream) Header() (metadata.MD, error) { return nil, nil }
+func (s *grpcStream) Trailer() metadata.MD         { return nil }
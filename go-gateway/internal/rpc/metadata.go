@@ -0,0 +1,15 @@
+package rpc
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// AppendOutgoingHeader returns a copy of ctx that adds the metadata key:
+// value to every call made with it. It is how trace context and the caller's
+// tenant reach the backend.
+func AppendOutgoingHeader(ctx context.Context, key, value string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, strings.ToLower(key), value)
+}
@@ -0,0 +1,47 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Error is a failed RPC: a status code, the backend's human-readable
+// message and any structured error details it attached.
+type Error struct {
+	Code    Code              `json:"code"`
+	Message string            `json:"message,omitempty"`
+	Details []json.RawMessage `json:"details,omitempty"`
+}
+
+// Errorf returns an *Error with the given code and formatted message.
+func Errorf(code Code, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return "rpc error: code = " + e.Code.String()
+	}
+	return "rpc error: code = " + e.Code.String() + " desc = " + e.Message
+}
+
+// CodeOf returns the status code carried by err. Context errors map to
+// Canceled and DeadlineExceeded, nil to OK and anything else to Unknown.
+func CodeOf(err error) Code {
+	if err == nil {
+		return OK
+	}
+	var rerr *Error
+	if errors.As(err, &rerr) {
+		return rerr.Code
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		return Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return DeadlineExceeded
+	}
+	return Unknown
+}
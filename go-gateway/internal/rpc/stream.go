@@ -0,0 +1,96 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// ClientStream is the receiving side of a server-streaming call.
+type ClientStream interface {
+	// Recv decodes the next message into msg. It returns io.EOF once the
+	// server ends the stream cleanly and an *Error if it ends with one.
+	Recv(msg any) error
+	// Close abandons the stream and releases its connection.
+	Close() error
+}
+
+var serverStream = &grpc.StreamDesc{ServerStreams: true}
+
+// NewStream starts a server-streaming call, sending req as the single
+// request message.
+func (c *ClientConn) NewStream(ctx context.Context, method string, req any) (ClientStream, error) {
+	ctx, cancel, err := c.deadline(ctx, method)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancelStream := context.WithCancel(ctx)
+	release := func() { cancelStream(); cancel() }
+	cs, err := c.cc.NewStream(ctx, serverStream, method)
+	if err != nil {
+		defer release()
+		return nil, c.fromStatus(ctx, method, err)
+	}
+	if err := cs.SendMsg(req); err != nil && !errors.Is(err, io.EOF) {
+		// io.EOF means the stream already ended; Recv reports why.
+		defer release()
+		return nil, c.fromStatus(ctx, method, err)
+	}
+	if err := cs.CloseSend(); err != nil {
+		defer release()
+		return nil, c.fromStatus(ctx, method, err)
+	}
+	st := &stream{conn: c, ctx: ctx, cancel: release, method: method, cs: cs}
+	if c.uncapped == nil || !c.uncapped(method) {
+		st.maxTotal = c.maxBytes
+	}
+	return st, nil
+}
+
+type stream struct {
+	conn   *ClientConn
+	ctx    context.Context
+	cancel context.CancelFunc // releases ctx once the stream is over
+	method string
+	cs     grpc.ClientStream
+	err    error // sticky terminal error, io.EOF after a clean end
+
+	// maxTotal caps the size of the whole stream, zero meaning no cap; gRPC
+	// itself caps each message. read counts the bytes read so far.
+	maxTotal, read int64
+}
+
+func (s *stream) Recv(msg any) error {
+	if s.err != nil {
+		return s.err
+	}
+	if err := s.cs.RecvMsg(msg); err != nil {
+		if errors.Is(err, io.EOF) {
+			s.err = io.EOF
+		} else {
+			s.err = s.conn.fromStatus(s.ctx, s.method, err)
+		}
+		s.cancel()
+		return s.err
+	}
+	if m, ok := msg.(proto.Message); ok {
+		s.read += int64(proto.Size(m))
+	}
+	if s.maxTotal > 0 && s.read > s.maxTotal {
+		s.err = tooLarge(s.ctx, s.method, s.maxTotal)
+		s.cancel()
+		return s.err
+	}
+	return nil
+}
+
+func (s *stream) Close() error {
+	if s.err == nil {
+		s.err = Errorf(Canceled, "stream closed by client")
+	}
+	s.cancel()
+	return nil
+}
@@ -0,0 +1,58 @@
+// Package rpctest runs in-process gRPC servers for the tests of packages
+// that call backends, so that they exercise the real transport.
+package rpctest
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Serve starts a gRPC server on a loopback port that handles every call,
+// whatever its method, with handler, and returns its address. The server
+// stops when the test ends.
+func Serve(tb testing.TB, handler grpc.StreamHandler) string {
+	tb.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	srv := grpc.NewServer(grpc.UnknownServiceHandler(handler))
+	go srv.Serve(lis)
+	tb.Cleanup(srv.Stop)
+	return lis.Addr().String()
+}
+
+// UnaryFunc answers a unary call on method. Its request is decoded as a
+// Struct, so callers send Structs, or Empty; its context carries the
+// call's deadline and incoming metadata.
+type UnaryFunc func(ctx context.Context, method string, req *structpb.Struct) (proto.Message, error)
+
+// Unary returns a handler answering each call with reply.
+func Unary(reply UnaryFunc) grpc.StreamHandler {
+	return func(_ any, stream grpc.ServerStream) error {
+		method, _ := grpc.MethodFromServerStream(stream)
+		var req structpb.Struct
+		if err := stream.RecvMsg(&req); err != nil {
+			return err
+		}
+		resp, err := reply(stream.Context(), method, &req)
+		if err != nil {
+			return err
+		}
+		return stream.SendMsg(resp)
+	}
+}
+
+// Struct returns fields as a Struct, panicking on values it cannot hold.
+func Struct(fields map[string]any) *structpb.Struct {
+	s, err := structpb.NewStruct(fields)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
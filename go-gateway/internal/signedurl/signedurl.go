@@ -0,0 +1,163 @@
+// Package signedurl issues and checks time-limited download URLs. A signed
+// URL carries its expiry and an HMAC-SHA256 of its path, expiry and other
+// parameters under the gateway's key, so whoever holds it may fetch the
+// file until it expires without any other credentials, and changing any
+// part of it breaks the signature.
+package signedurl
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTTL is how long a signed URL is valid when Config.TTL is zero.
+const DefaultTTL = 15 * time.Minute
+
+// The query parameters a signed URL carries.
+const (
+	ExpiresParam   = "expires"
+	SignatureParam = "signature"
+)
+
+var (
+	// ErrExpired is returned by Verify for a correctly signed URL past its
+	// expiry.
+	ErrExpired = errors.New("signedurl: the URL has expired")
+	// ErrInvalid is returned by Verify for a URL whose signature is
+	// missing or does not match.
+	ErrInvalid = errors.New("signedurl: the URL's signature is missing or invalid")
+)
+
+// Config configures a Signer.
+type Config struct {
+	// Key signs the URLs. Anyone holding it can issue them.
+	Key []byte
+	// TTL is how long a URL is valid after it is issued.
+	TTL time.Duration
+	// BaseURL, if set, is prepended to the signed paths, for clients that
+	// fetch them from another origin than the one they asked. It must
+	// pass the paths on to the gateway unchanged.
+	BaseURL string
+}
+
+// Signer issues and verifies signed URLs.
+type Signer struct {
+	key  []byte
+	ttl  time.Duration
+	base string
+
+	// now is time.Now; tests replace it.
+	now func() time.Time
+}
+
+// New returns a Signer for cfg.
+func New(cfg Config) *Signer {
+	if cfg.TTL <= 0 {
+		cfg.TTL = DefaultTTL
+	}
+	return &Signer{
+		key:  cfg.Key,
+		ttl:  cfg.TTL,
+		base: strings.TrimSuffix(cfg.BaseURL, "/"),
+		now:  time.Now,
+	}
+}
+
+// TTL returns how long the URLs s issues are valid.
+func (s *Signer) TTL() time.Duration { return s.ttl }
+
+// Sign returns a URL for path, with params, valid until the returned
+// expiry.
+func (s *Signer) Sign(path string, params url.Values) (string, time.Time) {
+	expires := s.now().Add(s.ttl).Truncate(time.Second)
+	q := url.Values{}
+	for k, v := range params {
+		q[k] = v
+	}
+	q.Set(ExpiresParam, strconv.FormatInt(expires.Unix(), 10))
+	q.Set(SignatureParam, s.signature(path, q))
+	return s.base + path + "?" + q.Encode(), expires
+}
+
+// Link returns path with params and a signature that never expires, for
+// URLs meant to be shared for good, such as those of public content. It
+// is relative to the gateway whatever Config.BaseURL says.
+func (s *Signer) Link(path string, params url.Values) string {
+	q := url.Values{}
+	for k, v := range params {
+		q[k] = v
+	}
+	q.Set(SignatureParam, s.signature(path, q))
+	return path + "?" + q.Encode()
+}
+
+// VerifyLink checks the signature of a request for path with query q,
+// issued by Link. A URL with an expiry fails with ErrInvalid, so a
+// time-limited URL cannot pass for a link.
+func (s *Signer) VerifyLink(path string, q url.Values) error {
+	if q.Has(ExpiresParam) {
+		return ErrInvalid
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(q.Get(SignatureParam))
+	if err != nil || len(sig) == 0 {
+		return ErrInvalid
+	}
+	want, _ := base64.RawURLEncoding.DecodeString(s.signature(path, q))
+	if !hmac.Equal(sig, want) {
+		return ErrInvalid
+	}
+	return nil
+}
+
+// Verify checks the signature and expiry of a request for path with
+// query q. Tampered or unsigned URLs fail with ErrInvalid, and expired
+// ones with ErrExpired.
+func (s *Signer) Verify(path string, q url.Values) error {
+	sig, err := base64.RawURLEncoding.DecodeString(q.Get(SignatureParam))
+	if err != nil || len(sig) == 0 {
+		return ErrInvalid
+	}
+	want, _ := base64.RawURLEncoding.DecodeString(s.signature(path, q))
+	if !hmac.Equal(sig, want) {
+		return ErrInvalid
+	}
+	// The expiry is signed, so it parses unless the key is shared with a
+	// signer that writes it differently.
+	unix, err := strconv.ParseInt(q.Get(ExpiresParam), 10, 64)
+	if err != nil {
+		return ErrInvalid
+	}
+	if !s.now().Before(time.Unix(unix, 0)) {
+		return ErrExpired
+	}
+	return nil
+}
+
+// signature returns the encoded HMAC of path and every parameter of q but
+// the signature, in a canonical order so that reordering the query does
+// not change it.
+func (s *Signer) signature(path string, q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		if k != SignatureParam {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(path))
+	for _, k := range keys {
+		for _, v := range q[k] {
+			// Lengths keep "a=bc" and "ab=c" apart.
+			mac.Write([]byte("\n" + strconv.Itoa(len(k)) + ":" + k + "=" + strconv.Itoa(len(v)) + ":" + v))
+		}
+	}
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
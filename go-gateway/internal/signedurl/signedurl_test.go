@@ -0,0 +1,144 @@
+package signedurl
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func signer(cfg Config) (*Signer, *time.Time) {
+	if cfg.Key == nil {
+		cfg.Key = []byte("0123456789abcdef0123456789abcdef")
+	}
+	s := New(cfg)
+	now := start
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+// parse splits a signed URL into its path and query.
+func parse(t *testing.T, signed string) (string, url.Values) {
+	t.Helper()
+	u, err := url.Parse(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Path, u.Query()
+}
+
+func TestSignVerify(t *testing.T) {
+	s, now := signer(Config{TTL: time.Minute})
+	signed, expires := s.Sign("/files/f1", url.Values{"tenant": {"acme"}})
+	if !expires.Equal(start.Add(time.Minute)) {
+		t.Errorf("expires = %v, want a minute on", expires)
+	}
+	if !strings.HasPrefix(signed, "/files/f1?") {
+		t.Errorf("signed URL %q", signed)
+	}
+	path, q := parse(t, signed)
+	if err := s.Verify(path, q); err != nil {
+		t.Fatalf("fresh URL: %v", err)
+	}
+	*now = start.Add(59 * time.Second)
+	if err := s.Verify(path, q); err != nil {
+		t.Errorf("URL about to expire: %v", err)
+	}
+	*now = start.Add(time.Minute)
+	if err := s.Verify(path, q); !errors.Is(err, ErrExpired) {
+		t.Errorf("expired URL: %v, want ErrExpired", err)
+	}
+}
+
+func TestLink(t *testing.T) {
+	s, now := signer(Config{TTL: time.Minute, BaseURL: "https://cdn.example.com"})
+	link := s.Link("/public/j1", url.Values{"tenant": {"acme"}})
+	if !strings.HasPrefix(link, "/public/j1?") {
+		t.Errorf("link %q", link)
+	}
+	path, q := parse(t, link)
+	*now = start.Add(24 * 365 * time.Hour)
+	if err := s.VerifyLink(path, q); err != nil {
+		t.Fatalf("link a year on: %v", err)
+	}
+	if err := s.Verify(path, q); !errors.Is(err, ErrInvalid) {
+		t.Errorf("link passed for a time-limited URL: %v", err)
+	}
+	q.Set("tenant", "globex")
+	if err := s.VerifyLink(path, q); !errors.Is(err, ErrInvalid) {
+		t.Errorf("link with another tenant: %v, want ErrInvalid", err)
+	}
+	path, q = parse(t, must(s.Sign("/public/j1", url.Values{"tenant": {"acme"}})))
+	if err := s.VerifyLink(path, q); !errors.Is(err, ErrInvalid) {
+		t.Errorf("time-limited URL passed for a link: %v", err)
+	}
+}
+
+func must(signed string, _ time.Time) string { return signed }
+
+func TestVerifyRejectsTampering(t *testing.T) {
+	s, _ := signer(Config{})
+	signed, _ := s.Sign("/files/f1", url.Values{"tenant": {"acme"}})
+	path, q := parse(t, signed)
+
+	tamper := map[string]func() (string, url.Values){
+		"path": func() (string, url.Values) { return "/files/f2", q },
+		"expiry": func() (string, url.Values) {
+			q2 := clone(q)
+			q2.Set(ExpiresParam, "9999999999")
+			return path, q2
+		},
+		"param": func() (string, url.Values) {
+			q2 := clone(q)
+			q2.Set("tenant", "globex")
+			return path, q2
+		},
+		"added param": func() (string, url.Values) {
+			q2 := clone(q)
+			q2.Set("owner", "me")
+			return path, q2
+		},
+		"no signature": func() (string, url.Values) {
+			q2 := clone(q)
+			q2.Del(SignatureParam)
+			return path, q2
+		},
+		"garbled signature": func() (string, url.Values) {
+			q2 := clone(q)
+			q2.Set(SignatureParam, "!!!")
+			return path, q2
+		},
+	}
+	for name, f := range tamper {
+		if err := s.Verify(f()); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s changed: %v, want ErrInvalid", name, err)
+		}
+	}
+
+	other, _ := signer(Config{Key: []byte("another key of thirty-two bytes!")})
+	if err := other.Verify(path, q); !errors.Is(err, ErrInvalid) {
+		t.Errorf("signed with another key: %v, want ErrInvalid", err)
+	}
+}
+
+func TestBaseURL(t *testing.T) {
+	s, _ := signer(Config{BaseURL: "https://downloads.example.com/"})
+	signed, _ := s.Sign("/files/f1", nil)
+	if !strings.HasPrefix(signed, "https://downloads.example.com/files/f1?expires=") {
+		t.Fatalf("signed URL %q", signed)
+	}
+	if err := s.Verify(parse(t, signed)); err != nil {
+		t.Error(err)
+	}
+}
+
+func clone(q url.Values) url.Values {
+	out := url.Values{}
+	for k, v := range q {
+		out[k] = append([]string(nil), v...)
+	}
+	return out
+}
@@ -0,0 +1,49 @@
+package tenant
+
+import (
+	"context"
+
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/rpc"
+)
+
+// Conn is the backend transport; it matches backend.Conn.
+type Conn interface {
+	Invoke(ctx context.Context, method string, req, resp any) error
+	NewStream(ctx context.Context, method string, req any) (rpc.ClientStream, error)
+}
+
+// Backends routes backend calls by the tenant of their context: to the
+// tenant's own content service if it has one, otherwise to Default. Every
+// call made for a tenant carries its ID in Header, so a content service
+// shared by tenants can keep their jobs apart too.
+type Backends struct {
+	Default Conn
+	// Tenants holds the connections of tenants with a content service of
+	// their own, by tenant ID.
+	Tenants map[string]Conn
+}
+
+// Invoke implements Conn.
+func (b *Backends) Invoke(ctx context.Context, method string, req, resp any) error {
+	ctx, conn := b.route(ctx)
+	return conn.Invoke(ctx, method, req, resp)
+}
+
+// NewStream implements Conn.
+func (b *Backends) NewStream(ctx context.Context, method string, req any) (rpc.ClientStream, error) {
+	ctx, conn := b.route(ctx)
+	return conn.NewStream(ctx, method, req)
+}
+
+func (b *Backends) route(ctx context.Context) (context.Context, Conn) {
+	id := gateway.TenantID(ctx)
+	if id == "" {
+		return ctx, b.Default
+	}
+	ctx = rpc.AppendOutgoingHeader(ctx, Header, id)
+	if conn, ok := b.Tenants[id]; ok {
+		return ctx, conn
+	}
+	return ctx, b.Default
+}
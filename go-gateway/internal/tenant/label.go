@@ -0,0 +1,30 @@
+package tenant
+
+import (
+	"context"
+	"net/http"
+)
+
+type labelKey struct{}
+
+// Capture returns a request whose context lets an outer middleware learn
+// the metrics label of the tenant Middleware resolved, like
+// router.CapturePattern does the route. Call the returned function after
+// the handler has run; it reports "" for requests that never reached
+// Middleware. The label is a configured tenant's ID, LabelOther or
+// LabelUnknown, so it is bounded by the configuration.
+func Capture(r *http.Request) (*http.Request, func() string) {
+	if slot, ok := r.Context().Value(labelKey{}).(*string); ok {
+		return r, func() string { return *slot }
+	}
+	slot := new(string)
+	ctx := context.WithValue(r.Context(), labelKey{}, slot)
+	return r.WithContext(ctx), func() string { return *slot }
+}
+
+// record sets r's label for Capture, if an outer middleware asked.
+func record(r *http.Request, label string) {
+	if slot, ok := r.Context().Value(labelKey{}).(*string); ok {
+		*slot = label
+	}
+}
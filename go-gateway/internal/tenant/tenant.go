@@ -0,0 +1,160 @@
+// Package tenant resolves the tenant each request acts for and keeps the
+// tenants apart: each has its own rate limits, content formats and,
+// optionally, its own content service.
+//
+// A request's tenant comes from its credentials, the tenant claim of a
+// JWT or the tenant of an API key, or else from the X-Tenant-ID header,
+// or else is the default tenant. Requests naming a tenant that is not
+// configured, or is disabled, are refused with 403, as are requests whose
+// header disagrees with their credentials.
+package tenant
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/audit"
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/respond"
+)
+
+// Header names the tenant of a request whose credentials do not.
+const Header = "X-Tenant-ID"
+
+// Labels the metrics give requests whose tenant is not labelled by ID.
+const (
+	// LabelUnknown counts requests naming a tenant that is not configured,
+	// so made-up IDs cannot inflate label cardinality.
+	LabelUnknown = "unknown"
+	// LabelOther counts the requests of tenants with Metrics off.
+	LabelOther = "other"
+)
+
+// Tenant is one tenant's settings.
+type Tenant struct {
+	ID string
+	// Enabled is cleared to refuse the tenant's requests without deleting
+	// it.
+	Enabled bool
+	// RPS and Burst, when positive, replace the default rate limit of the
+	// tenant's clients.
+	RPS   float64
+	Burst int
+	// Formats, when not empty, are the only content formats the tenant
+	// may request.
+	Formats []string
+	// Metrics labels the tenant's requests with its ID; otherwise they are
+	// counted as LabelOther.
+	Metrics bool
+}
+
+func (t *Tenant) label() string {
+	if t.Metrics {
+		return t.ID
+	}
+	return LabelOther
+}
+
+// Registry holds the configured tenants.
+type Registry struct {
+	tenants map[string]*Tenant
+	def     string
+}
+
+// New returns a Registry of tenants. Requests that name no tenant act for
+// defaultID, or are refused if it is empty.
+func New(tenants []Tenant, defaultID string) *Registry {
+	m := make(map[string]*Tenant, len(tenants))
+	for _, t := range tenants {
+		t.Formats = slices.Clone(t.Formats)
+		m[t.ID] = &t
+	}
+	return &Registry{tenants: m, def: defaultID}
+}
+
+// Lookup returns the tenant with id, if it is configured.
+func (reg *Registry) Lookup(id string) (*Tenant, bool) {
+	t, ok := reg.tenants[id]
+	return t, ok
+}
+
+// Middleware resolves the tenant of each request, refusing it with 403
+// unless it is configured and enabled, and attaches it for
+// gateway.TenantFromContext. Install it after authentication, which
+// supplies the tenant of the credentials, and before rate limiting.
+func (reg *Registry) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		respond.Vary(w, Header)
+		id, e := reg.resolve(r)
+		if e != nil {
+			refuse(w, r, e)
+			return
+		}
+		t, ok := reg.tenants[id]
+		if !ok {
+			record(r, LabelUnknown)
+			refuse(w, r, apierror.New(http.StatusForbidden, apierror.CodeForbidden, "tenant "+quote(id)+" is not known"))
+			return
+		}
+		record(r, t.label())
+		if !t.Enabled {
+			refuse(w, r, apierror.New(http.StatusForbidden, apierror.CodeForbidden, "tenant "+quote(id)+" is disabled"))
+			return
+		}
+		ctx := gateway.WithTenant(r.Context(), &gateway.Tenant{
+			ID:      t.ID,
+			RPS:     t.RPS,
+			Burst:   t.Burst,
+			Formats: t.Formats,
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// refuse answers r with e, recording the denial in the audit log.
+func refuse(w http.ResponseWriter, r *http.Request, e *apierror.Error) {
+	audit.Record(r.Context(), audit.ActionAuthorize, audit.OutcomeDenied,
+		slog.String("reason", e.Message), slog.String("path", r.URL.Path))
+	apierror.Write(w, r, e)
+}
+
+// resolve returns the ID of the tenant r names, or the error to refuse it
+// with.
+func (reg *Registry) resolve(r *http.Request) (string, *apierror.Error) {
+	header := strings.TrimSpace(r.Header.Get(Header))
+	var claimed string
+	if claims := gateway.ClaimsFromContext(r.Context()); claims != nil {
+		claimed = claims.Tenant
+	}
+	switch {
+	case claimed != "" && header != "" && header != claimed:
+		return "", apierror.New(http.StatusForbidden, apierror.CodeForbidden,
+			Header+" names a different tenant from the request's credentials")
+	case claimed != "":
+		return claimed, nil
+	case header != "":
+		return header, nil
+	case reg.def != "":
+		return reg.def, nil
+	}
+	return "", apierror.New(http.StatusForbidden, apierror.CodeForbidden,
+		"the request names no tenant; send "+Header+" or use credentials issued for a tenant")
+}
+
+// quote bounds a client-supplied ID echoed in an error message.
+func quote(id string) string {
+	if len(id) > 64 {
+		id = id[:64] + "…"
+	}
+	return strconv.Quote(id)
+}
+
+// AllowsFormat reports whether t, the tenant of a request or nil, may
+// request content in format.
+func AllowsFormat(t *gateway.Tenant, format string) bool {
+	return t == nil || len(t.Formats) == 0 || slices.Contains(t.Formats, format)
+}
@@ -0,0 +1,157 @@
+package tenant
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/respond"
+	"github.com/content-factory/go-gateway/internal/rpc"
+	"github.com/content-factory/go-gateway/internal/rpctest"
+)
+
+func registry(defaultID string) *Registry {
+	return New([]Tenant{
+		{ID: "acme", Enabled: true, RPS: 5, Burst: 10, Formats: []string{"article"}, Metrics: true},
+		{ID: "globex", Enabled: true},
+		{ID: "initech", Enabled: false, Metrics: true},
+	}, defaultID)
+}
+
+// serve runs one request through reg with the tenant claim and header
+// given, returning the response and the tenant the handler saw.
+func serve(reg *Registry, claim, header string) (*httptest.ResponseRecorder, *gateway.Tenant, string) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if header != "" {
+		req.Header.Set(Header, header)
+	}
+	req = req.WithContext(gateway.WithClaims(req.Context(), &gateway.Claims{Subject: "u1", Tenant: claim}))
+	req, label := Capture(req)
+	var seen *gateway.Tenant
+	rec := httptest.NewRecorder()
+	reg.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = gateway.TenantFromContext(r.Context())
+	})).ServeHTTP(rec, req)
+	return rec, seen, label()
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name          string
+		defaultID     string
+		claim, header string
+		want          string // tenant served; empty for 403
+		label         string
+		message       string // in the 403's message
+	}{
+		{"claim", "", "acme", "", "acme", "acme", ""},
+		{"header", "", "", "globex", "globex", LabelOther, ""},
+		{"claim and matching header", "", "acme", "acme", "acme", "acme", ""},
+		{"header disagrees with claim", "", "acme", "globex", "", "", "different tenant"},
+		{"default", "acme", "", "", "acme", "acme", ""},
+		{"header beats default", "acme", "", "globex", "globex", LabelOther, ""},
+		{"none", "", "", "", "", "", "names no tenant"},
+		{"unknown", "", "", "umbrella", "", LabelUnknown, `tenant "umbrella" is not known`},
+		{"unknown claim", "", "umbrella", "", "", LabelUnknown, "is not known"},
+		{"disabled", "", "initech", "", "", "initech", `tenant "initech" is disabled`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, seen, label := serve(registry(tt.defaultID), tt.claim, tt.header)
+			if label != tt.label {
+				t.Errorf("label = %q, want %q", label, tt.label)
+			}
+			if !slices.Contains(respond.Varies(rec.Header()), http.CanonicalHeaderKey(Header)) {
+				t.Errorf("Vary = %q, want %s", rec.Header().Get("Vary"), Header)
+			}
+			if tt.want != "" {
+				if rec.Code != http.StatusOK || seen == nil || seen.ID != tt.want {
+					t.Fatalf("status %d, tenant %+v, want %s; body %s", rec.Code, seen, tt.want, rec.Body)
+				}
+				return
+			}
+			var body struct{ Error apierror.Error }
+			json.Unmarshal(rec.Body.Bytes(), &body)
+			if rec.Code != http.StatusForbidden || body.Error.Code != apierror.CodeForbidden || !strings.Contains(body.Error.Message, tt.message) {
+				t.Errorf("got %d %s, want 403 mentioning %q", rec.Code, rec.Body, tt.message)
+			}
+			if seen != nil {
+				t.Errorf("handler ran for tenant %+v", seen)
+			}
+		})
+	}
+}
+
+func TestMiddlewareAttachesSettings(t *testing.T) {
+	_, seen, _ := serve(registry(""), "acme", "")
+	if seen.RPS != 5 || seen.Burst != 10 || len(seen.Formats) != 1 {
+		t.Errorf("tenant = %+v", seen)
+	}
+	if !AllowsFormat(seen, "article") || AllowsFormat(seen, "video") {
+		t.Error("acme's formats not enforced")
+	}
+	if !AllowsFormat(nil, "video") || !AllowsFormat(&gateway.Tenant{ID: "globex"}, "video") {
+		t.Error("formats restricted without a format list")
+	}
+}
+
+func TestUnknownTenantMessageIsBounded(t *testing.T) {
+	rec, _, _ := serve(registry(""), "", strings.Repeat("x", 1000))
+	if rec.Body.Len() > 500 {
+		t.Errorf("body of %d bytes echoes the whole ID", rec.Body.Len())
+	}
+}
+
+// backendServer records the tenant header of the calls it serves.
+type backendServer struct {
+	mu      sync.Mutex
+	tenants []string
+}
+
+func (b *backendServer) start(t *testing.T) *rpc.ClientConn {
+	addr := rpctest.Serve(t, rpctest.Unary(func(ctx context.Context, method string, req *structpb.Struct) (proto.Message, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		b.mu.Lock()
+		b.tenants = append(b.tenants, strings.Join(md.Get(Header), ","))
+		b.mu.Unlock()
+		return &emptypb.Empty{}, nil
+	}))
+	conn, err := rpc.Dial(addr, rpc.DialOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestBackends(t *testing.T) {
+	var shared, own backendServer
+	b := &Backends{Default: shared.start(t), Tenants: map[string]Conn{"globex": own.start(t)}}
+	for _, id := range []string{"", "acme", "globex"} {
+		ctx := context.Background()
+		if id != "" {
+			ctx = gateway.WithTenant(ctx, &gateway.Tenant{ID: id})
+		}
+		if err := b.Invoke(ctx, "/svc/Get", &emptypb.Empty{}, &emptypb.Empty{}); err != nil {
+			t.Fatalf("%q: %v", id, err)
+		}
+	}
+	if got := strings.Join(shared.tenants, ","); got != ",acme" {
+		t.Errorf("shared backend served tenants %q, want none then acme", got)
+	}
+	if got := strings.Join(own.tenants, ","); got != "globex" {
+		t.Errorf("globex's backend served tenants %q", got)
+	}
+}
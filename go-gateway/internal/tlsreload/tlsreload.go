@@ -0,0 +1,134 @@
+// Package tlsreload serves a TLS certificate that is reloaded from disk
+// when it changes, so certificates can be rotated without a restart.
+package tlsreload
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultInterval is how often the files are checked for changes. Used by
+// the config package.
+const DefaultInterval = 30 * time.Second
+
+// CipherSuites are the TLS 1.2 suites offered: forward-secret AEAD suites
+// only. TLS 1.3 suites are not configurable and are all acceptable.
+var CipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+}
+
+// Reloader holds the certificate loaded from a cert/key file pair.
+type Reloader struct {
+	certFile, keyFile string
+
+	cert atomic.Pointer[tls.Certificate]
+
+	mu      sync.Mutex
+	certMod fileStamp
+	keyMod  fileStamp
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// New loads the key pair. It fails if the files are missing or do not
+// match, so a bad deployment is caught at startup.
+func New(certFile, keyFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// TLSConfig returns a server configuration that requires TLS 1.2 or later
+// and presents the current certificate on every handshake.
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		CipherSuites:   CipherSuites,
+		GetCertificate: r.GetCertificate,
+	}
+}
+
+// GetCertificate implements tls.Config.GetCertificate.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.cert.Load(), nil
+}
+
+// Reload loads the key pair again if either file changed since the last
+// successful load, and reports whether it did. On error the previous
+// certificate stays in use; a rotation that has replaced the certificate
+// but not yet the key is picked up on a later call.
+func (r *Reloader) Reload() (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	certMod, err := stat(r.certFile)
+	if err != nil {
+		return false, err
+	}
+	keyMod, err := stat(r.keyFile)
+	if err != nil {
+		return false, err
+	}
+	if r.cert.Load() != nil && certMod == r.certMod && keyMod == r.keyMod {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("tls: load key pair: %w", err)
+	}
+	if cert.Leaf == nil {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return false, fmt.Errorf("tls: parse certificate: %w", err)
+		}
+	}
+	r.cert.Store(&cert)
+	r.certMod, r.keyMod = certMod, keyMod
+	return true, nil
+}
+
+// Watch calls Reload every interval until ctx is done.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := r.Reload()
+			switch {
+			case err != nil:
+				slog.Warn("TLS certificate reload failed; keeping the current certificate", "error", err)
+			case reloaded:
+				leaf := r.cert.Load().Leaf
+				slog.Info("TLS certificate reloaded", "subject", leaf.Subject.String(), "not_after", leaf.NotAfter)
+			}
+		}
+	}
+}
+
+func stat(path string) (fileStamp, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, fmt.Errorf("tls: %w", err)
+	}
+	return fileStamp{modTime: fi.ModTime(), size: fi.Size()}, nil
+}
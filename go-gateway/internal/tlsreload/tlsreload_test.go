@@ -0,0 +1,127 @@
+package tlsreload
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writePair writes a self-signed certificate for cn and its key, and
+// bumps the files' modification time to mod.
+func writePair(t *testing.T, dir, cn string, mod time.Time) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile = filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	write := func(path, typ string, b []byte) {
+		if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: b}), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(certFile, "CERTIFICATE", der)
+	write(keyFile, "EC PRIVATE KEY", keyDER)
+	return certFile, keyFile
+}
+
+func commonName(t *testing.T, r *Reloader) string {
+	t.Helper()
+	cert, err := r.GetCertificate(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert.Leaf.Subject.CommonName
+}
+
+func TestReloadOnChange(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Now().Add(-time.Minute)
+	certFile, keyFile := writePair(t, dir, "first", base)
+
+	r, err := New(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := commonName(t, r); got != "first" {
+		t.Fatalf("CN = %q", got)
+	}
+	if reloaded, err := r.Reload(); reloaded || err != nil {
+		t.Errorf("unchanged files: Reload = %v, %v", reloaded, err)
+	}
+
+	writePair(t, dir, "second", base.Add(time.Second))
+	if reloaded, err := r.Reload(); !reloaded || err != nil {
+		t.Fatalf("rotated files: Reload = %v, %v", reloaded, err)
+	}
+	if got := commonName(t, r); got != "second" {
+		t.Errorf("CN after rotation = %q", got)
+	}
+}
+
+func TestBadRotationKeepsCertificate(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Now().Add(-time.Minute)
+	certFile, keyFile := writePair(t, dir, "good", base)
+	r, err := New(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Half-finished rotation: the key changed but the certificate has not.
+	if err := os.WriteFile(keyFile, []byte("garbage"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reload(); err == nil {
+		t.Error("expected error for mismatched pair")
+	}
+	if got := commonName(t, r); got != "good" {
+		t.Errorf("CN = %q, want previous certificate kept", got)
+	}
+}
+
+func TestNewFailsOnMissingFiles(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := New(filepath.Join(dir, "none.crt"), filepath.Join(dir, "none.key")); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writePair(t, dir, "cfg", time.Now())
+	r, err := New(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := r.TLSConfig()
+	if cfg.MinVersion != tls.VersionTLS12 || cfg.GetCertificate == nil {
+		t.Errorf("config = %+v", cfg)
+	}
+}
@@ -0,0 +1,126 @@
+// Package tracing instruments the gateway with OpenTelemetry. otelhttp
+// records a server span per request, otelgrpc's client stats handler a
+// client span per backend call, W3C Trace Context is continued from
+// callers and sent on to the content service, and spans are exported over
+// OTLP/HTTP.
+//
+// A nil *Tracer is valid and records nothing, which is how tracing is
+// disabled when no exporter endpoint is configured.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc/filters"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/stats"
+
+	"github.com/content-factory/go-gateway/internal/clientip"
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/router"
+)
+
+// Defaults used by the config package.
+const (
+	DefaultServiceName = "go-gateway"
+)
+
+// Tracer records the gateway's spans and hands them to an exporter.
+type Tracer struct {
+	provider   *sdktrace.TracerProvider
+	propagator propagation.TextMapPropagator
+}
+
+// New returns a Tracer exporting to endpoint's /v1/traces, with spans
+// attributed to serviceName. Spans are batched, and dropped rather than
+// slowing requests down when the collector cannot keep up. An empty
+// endpoint yields a nil, no-op Tracer.
+func New(endpoint, serviceName string) (*Tracer, error) {
+	if endpoint == "" {
+		return nil, nil
+	}
+	exporter, err := otlptracehttp.New(context.Background(),
+		otlptracehttp.WithEndpointURL(strings.TrimSuffix(endpoint, "/")+"/v1/traces"))
+	if err != nil {
+		return nil, fmt.Errorf("tracing: %w", err)
+	}
+	return newTracer(sdktrace.NewBatchSpanProcessor(exporter), serviceName), nil
+}
+
+// newTracer returns a Tracer handing its spans to sp. Traces are sampled
+// unless the caller's traceparent says otherwise.
+func newTracer(sp sdktrace.SpanProcessor, serviceName string) *Tracer {
+	return &Tracer{
+		provider: sdktrace.NewTracerProvider(
+			sdktrace.WithSpanProcessor(sp),
+			sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+		),
+		propagator: propagation.TraceContext{},
+	}
+}
+
+// Shutdown exports the spans still queued and stops the exporter, giving
+// up when ctx is done.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	return t.provider.Shutdown(ctx)
+}
+
+// Middleware starts a server span per request, continuing the caller's
+// trace when it sends a valid traceparent. The span is named after the
+// matched route template, e.g. "GET /api/v1/jobs/{id}", so span names stay
+// low-cardinality, and carries the request ID to correlate it with the
+// request's log line. Install it inside RequestID and the clientip
+// middleware.
+func (t *Tracer) Middleware(next http.Handler) http.Handler {
+	if t == nil {
+		return next
+	}
+	routed := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		span := trace.SpanFromContext(r.Context())
+		span.SetAttributes(
+			semconv.ClientAddress(clientip.Of(r)),
+			attribute.String("request_id", gateway.RequestIDFromContext(r.Context())),
+		)
+		r, pattern := router.CapturePattern(r)
+		next.ServeHTTP(w, r)
+		if route := pattern(); route != "" {
+			span.SetName(r.Method + " " + route)
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+	})
+	return otelhttp.NewHandler(routed, "",
+		otelhttp.WithTracerProvider(t.provider),
+		otelhttp.WithPropagators(t.propagator),
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string { return r.Method }),
+	)
+}
+
+// ClientHandler returns the gRPC stats handler that records a client span
+// for every call on a backend connection, retries each getting their own,
+// and sends the span's traceparent in the call's metadata, so the content
+// service's spans join the gateway's trace. Health checks are not traced.
+// A nil Tracer returns nil.
+func (t *Tracer) ClientHandler() stats.Handler {
+	if t == nil {
+		return nil
+	}
+	return otelgrpc.NewClientHandler(
+		otelgrpc.WithTracerProvider(t.provider),
+		otelgrpc.WithPropagators(t.propagator),
+		otelgrpc.WithFilter(filters.Not(filters.HealthCheck())),
+	)
+}
@@ -0,0 +1,249 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/router"
+	"github.com/content-factory/go-gateway/internal/rpc"
+	"github.com/content-factory/go-gateway/internal/rpctest"
+)
+
+// collector is a fake OTLP/HTTP endpoint.
+type collector struct {
+	mu    sync.Mutex
+	spans []*tracepb.Span
+	attrs []*commonpb.KeyValue
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/x-protobuf" {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var req coltracepb.ExportTraceServiceRequest
+	if err := proto.Unmarshal(body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, rs := range req.GetResourceSpans() {
+		c.attrs = append(c.attrs, rs.GetResource().GetAttributes()...)
+		for _, ss := range rs.GetScopeSpans() {
+			c.spans = append(c.spans, ss.GetSpans()...)
+		}
+	}
+}
+
+func (c *collector) byKind(kind tracepb.Span_SpanKind) []*tracepb.Span {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var out []*tracepb.Span
+	for _, s := range c.spans {
+		if s.GetKind() == kind {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func attr(attrs []*commonpb.KeyValue, key string) string {
+	for _, kv := range attrs {
+		if kv.GetKey() != key {
+			continue
+		}
+		if v, ok := kv.GetValue().GetValue().(*commonpb.AnyValue_IntValue); ok {
+			return strconv.FormatInt(v.IntValue, 10)
+		}
+		return kv.GetValue().GetStringValue()
+	}
+	return ""
+}
+
+// record returns a Tracer whose ended spans are kept in memory.
+func record() (*Tracer, *tracetest.SpanRecorder) {
+	rec := tracetest.NewSpanRecorder()
+	return newTracer(rec, "gw"), rec
+}
+
+func dialBackend(t *testing.T, addr string, tracer *Tracer) *rpc.ClientConn {
+	t.Helper()
+	conn, err := rpc.Dial(addr, rpc.DialOptions{StatsHandler: tracer.ClientHandler()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestRequestTraceReachesBackendAndCollector(t *testing.T) {
+	col := &collector{}
+	colSrv := httptest.NewServer(col)
+	defer colSrv.Close()
+
+	var sentTraceparent string
+	addr := rpctest.Serve(t, rpctest.Unary(func(ctx context.Context, method string, req *structpb.Struct) (proto.Message, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		sentTraceparent = strings.Join(md.Get("traceparent"), ",")
+		return &emptypb.Empty{}, nil
+	}))
+
+	tracer, err := New(colSrv.URL+"/", "test-gateway")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := dialBackend(t, addr, tracer)
+
+	rt := router.New()
+	rt.HandleFunc(http.MethodGet, "/jobs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if err := conn.Invoke(r.Context(), "/svc/GetJob", &emptypb.Empty{}, &emptypb.Empty{}); err != nil {
+			t.Errorf("Invoke: %v", err)
+		}
+	})
+	handler := tracer.Middleware(rt)
+
+	req := httptest.NewRequest(http.MethodGet, "/jobs/42", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req = req.WithContext(gateway.WithRequestID(req.Context(), "req-1"))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := tracer.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	servers, clients := col.byKind(tracepb.Span_SPAN_KIND_SERVER), col.byKind(tracepb.Span_SPAN_KIND_CLIENT)
+	if len(servers) != 1 || len(clients) != 1 {
+		t.Fatalf("got %d server and %d client spans", len(servers), len(clients))
+	}
+	server, client := servers[0], clients[0]
+	if server.GetName() != "GET /jobs/{id}" {
+		t.Errorf("server span name = %q", server.GetName())
+	}
+	if hex.EncodeToString(server.GetTraceId()) != "4bf92f3577b34da6a3ce929d0e0e4736" ||
+		hex.EncodeToString(server.GetParentSpanId()) != "00f067aa0ba902b7" {
+		t.Errorf("server span did not continue the incoming trace: %v", server)
+	}
+	if a := server.GetAttributes(); attr(a, "request_id") != "req-1" || attr(a, "http.route") != "/jobs/{id}" ||
+		attr(a, "http.response.status_code") != "200" {
+		t.Errorf("server span attributes = %v", a)
+	}
+	if string(client.GetTraceId()) != string(server.GetTraceId()) || string(client.GetParentSpanId()) != string(server.GetSpanId()) {
+		t.Errorf("client span not a child of the server span: %v", client)
+	}
+	if want := "00-" + hex.EncodeToString(client.GetTraceId()) + "-" + hex.EncodeToString(client.GetSpanId()) + "-01"; sentTraceparent != want {
+		t.Errorf("backend got traceparent %q, want %q", sentTraceparent, want)
+	}
+	if got := attr(col.attrs, "service.name"); got != "test-gateway" {
+		t.Errorf("service.name = %q", got)
+	}
+}
+
+func TestUnmatchedRouteIsNamedByMethod(t *testing.T) {
+	tracer, rec := record()
+	rt := router.New()
+	tracer.Middleware(rt).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nowhere", nil))
+
+	spans := rec.Ended()
+	if len(spans) != 1 || spans[0].Name() != "GET" {
+		t.Fatalf("spans = %v", spans)
+	}
+}
+
+func TestFailedCallMarksSpan(t *testing.T) {
+	tracer, rec := record()
+	addr := rpctest.Serve(t, rpctest.Unary(func(ctx context.Context, method string, req *structpb.Struct) (proto.Message, error) {
+		return nil, status.Error(codes.NotFound, "no such job")
+	}))
+	conn := dialBackend(t, addr, tracer)
+	if err := conn.Invoke(context.Background(), "/svc/GetJob", &emptypb.Empty{}, &emptypb.Empty{}); rpc.CodeOf(err) != rpc.NotFound {
+		t.Fatalf("err = %v", err)
+	}
+
+	spans := rec.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans", len(spans))
+	}
+	span := spans[0]
+	if span.SpanKind() != trace.SpanKindClient || span.Name() != "svc/GetJob" {
+		t.Errorf("span = %s %q", span.SpanKind(), span.Name())
+	}
+	if span.Parent().IsValid() {
+		t.Errorf("root span has parent %v", span.Parent().SpanID())
+	}
+	if span.Status().Code != otelcodes.Error {
+		t.Errorf("status = %+v", span.Status())
+	}
+}
+
+func TestHealthChecksAreNotTraced(t *testing.T) {
+	tracer, rec := record()
+	addr := rpctest.Serve(t, rpctest.Unary(func(ctx context.Context, method string, req *structpb.Struct) (proto.Message, error) {
+		return &emptypb.Empty{}, nil
+	}))
+	conn := dialBackend(t, addr, tracer)
+	if err := conn.Invoke(context.Background(), "/grpc.health.v1.Health/Check", &emptypb.Empty{}, &emptypb.Empty{}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(rec.Ended()); n != 0 {
+		t.Errorf("recorded %d health check spans", n)
+	}
+}
+
+func TestUnsampledTraceIsNotRecorded(t *testing.T) {
+	tracer, rec := record()
+	h := tracer.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if n := len(rec.Ended()); n != 0 {
+		t.Errorf("recorded %d unsampled spans", n)
+	}
+}
+
+func TestDisabledTracerIsNoop(t *testing.T) {
+	tracer, err := New("", "gw")
+	if err != nil || tracer != nil {
+		t.Fatalf("New without an endpoint = %v, %v; want nil, nil", tracer, err)
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	if got := tracer.Middleware(next); got == nil {
+		t.Error("Middleware returned nil")
+	}
+	if h := tracer.ClientHandler(); h != nil {
+		t.Errorf("ClientHandler = %v, want nil", h)
+	}
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
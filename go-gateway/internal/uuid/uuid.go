@@ -0,0 +1,65 @@
+// Package uuid generates random (version 4) UUIDs and parses UUIDs of
+// any version.
+package uuid
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+)
+
+// New returns a random UUID in canonical lowercase form.
+func New() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	return format(b)
+}
+
+// format returns b in canonical form.
+func format(b [16]byte) string {
+	var out [36]byte
+	hex.Encode(out[0:8], b[0:4])
+	out[8] = '-'
+	hex.Encode(out[9:13], b[4:6])
+	out[13] = '-'
+	hex.Encode(out[14:18], b[6:8])
+	out[18] = '-'
+	hex.Encode(out[19:23], b[8:10])
+	out[23] = '-'
+	hex.Encode(out[24:], b[10:])
+	return string(out[:])
+}
+
+// Parse returns s in canonical lowercase form if it is a UUID, in either
+// case, with or without its hyphens, and optionally in braces or as a
+// urn:uuid: URN.
+func Parse(s string) (string, bool) {
+	if len(s) > 9 && strings.EqualFold(s[:9], "urn:uuid:") {
+		s = s[9:]
+	} else if len(s) > 2 && s[0] == '{' && s[len(s)-1] == '}' {
+		s = s[1 : len(s)-1]
+	}
+	var digits [32]byte
+	switch len(s) {
+	case 32:
+		copy(digits[:], s)
+	case 36:
+		if s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+			return "", false
+		}
+		copy(digits[0:8], s[0:8])
+		copy(digits[8:12], s[9:13])
+		copy(digits[12:16], s[14:18])
+		copy(digits[16:20], s[19:23])
+		copy(digits[20:], s[24:])
+	default:
+		return "", false
+	}
+	var b [16]byte
+	if _, err := hex.Decode(b[:], digits[:]); err != nil {
+		return "", false
+	}
+	return format(b), true
+}
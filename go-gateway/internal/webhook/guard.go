@@ -0,0 +1,109 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// MaxURLLength caps webhook URLs.
+const MaxURLLength = 2048
+
+// ErrBlockedAddress is returned for a webhook whose host is, or resolves
+// to, an address the gateway will not call.
+var ErrBlockedAddress = errors.New("webhook: address is not publicly routable")
+
+// blockedPrefixes are the ranges outside the public internet that
+// netip.Addr's own predicates do not cover.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("2001:db8::/32"),
+}
+
+// Blocked reports whether the gateway refuses to deliver webhooks to ip:
+// loopback, private, link-local (including cloud metadata services),
+// multicast, unspecified and reserved addresses. IPv4-mapped IPv6
+// addresses are judged as the IPv4 address they carry.
+func Blocked(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsValid() || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return true
+	}
+	for _, p := range blockedPrefixes {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateURL checks that raw is a URL the gateway may deliver to: an
+// absolute http or https URL without credentials whose host, if it is an
+// IP address or localhost, is not blocked. Host names are checked again
+// once resolved, when each delivery connects.
+func ValidateURL(raw string, allowPrivate bool) error {
+	if len(raw) > MaxURLLength {
+		return fmt.Errorf("must be at most %d characters", MaxURLLength)
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Hostname() == "" {
+		return errors.New("must be an absolute http or https URL")
+	}
+	if u.User != nil {
+		return errors.New("must not carry credentials")
+	}
+	if allowPrivate {
+		return nil
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return errors.New("must not point at a private or loopback address")
+	}
+	if ip, err := netip.ParseAddr(host); err == nil && Blocked(ip) {
+		return errors.New("must not point at a private or loopback address")
+	}
+	return nil
+}
+
+// guardedClient returns an HTTP client that refuses to connect to blocked
+// addresses, unless allowPrivate is set. The check is made on the
+// resolved address of every connection, so a public name pointing at a
+// private address is caught too. Redirects are not followed, since a
+// redirect is a way to send the request somewhere else, and no proxy is
+// used.
+func guardedClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			ap, err := netip.ParseAddrPort(address)
+			if err != nil || Blocked(ap.Addr()) {
+				return fmt.Errorf("%w: %s", ErrBlockedAddress, address)
+			}
+			return nil
+		}
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+			MaxIdleConnsPerHost: 2,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
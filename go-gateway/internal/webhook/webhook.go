@@ -0,0 +1,462 @@
+// Package webhook tells integrators that their jobs have finished by
+// POSTing a signed JSON payload to the callback URL they submitted the
+// job with, so they need not poll.
+//
+// A Dispatcher has each watched job followed onto the job event bus until
+// it ends, and reads the bus for its terminal event. On that event it delivers,
+// retrying with capped exponential backoff until the receiver answers 2xx
+// or the attempts run out, and records every attempt for the job status
+// to report.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/jobs"
+	"github.com/content-factory/go-gateway/internal/metrics"
+	"github.com/content-factory/go-gateway/internal/uuid"
+)
+
+// Defaults for the Dispatcher fields left zero.
+const (
+	DefaultMaxAttempts    = 6
+	DefaultInitialBackoff = time.Second
+	DefaultMaxBackoff     = 5 * time.Minute
+	DefaultTimeout        = 10 * time.Second
+	// DefaultMaxTracked is how many jobs' delivery records are kept.
+	DefaultMaxTracked = 10000
+)
+
+// Headers set on each delivery.
+const (
+	SignatureHeader = "X-Signature"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+// EventJobFinished is the event of every delivery: the job reached a
+// terminal stage.
+const EventJobFinished = "job.finished"
+
+// Delivery states.
+const (
+	StatePending   = "pending"
+	StateDelivered = "delivered"
+	StateFailed    = "failed"
+)
+
+var deliveries = metrics.NewCounterVec("gateway_webhook_deliveries_total",
+	"Webhook delivery attempts, by result: delivered, rejected (non-2xx) or error.",
+	"result")
+
+// Payload is the body of a delivery.
+type Payload struct {
+	Event      string    `json:"event"`
+	DeliveryID string    `json:"delivery_id"`
+	JobID      string    `json:"job_id"`
+	Stage      string    `json:"stage"`
+	Message    string    `json:"message,omitempty"`
+	URL        string    `json:"url,omitempty"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// Status is the delivery record of one job's webhook.
+type Status struct {
+	URL string `json:"url"`
+	// State is pending until the job finishes and the receiver accepts a
+	// delivery, then delivered, or failed once the attempts run out.
+	State    string    `json:"state"`
+	Attempts []Attempt `json:"attempts"`
+}
+
+// Attempt is one try at delivering a job's webhook.
+type Attempt struct {
+	At         time.Time `json:"at"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Sign returns the X-Signature value of body under secret: "sha256="
+// followed by the hex HMAC-SHA256 of the body. Receivers recompute it
+// over the raw body and compare in constant time.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatcher delivers job webhooks. Set the fields before the first call
+// to Watch.
+type Dispatcher struct {
+	// Jobs follows each watched job, and its bus carries the job's
+	// terminal event; in the gateway it is the feed following every
+	// submitted job, so a watched job shares the stream of its WebSocket
+	// and SSE clients.
+	Jobs *jobs.Feed
+	// Secrets maps each principal allowed webhooks to the secret its
+	// deliveries are signed with.
+	Secrets map[string]string
+	// MaxAttempts caps the tries at a delivery, and at having the job
+	// followed; InitialBackoff and MaxBackoff bound the waits between
+	// them. Zero means the defaults.
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Timeout bounds each delivery request; zero means DefaultTimeout.
+	Timeout time.Duration
+	// AllowPrivate lets webhooks target private and loopback addresses,
+	// for development only.
+	AllowPrivate bool
+	// Client, if set, sends the deliveries in place of the default client,
+	// which refuses blocked addresses.
+	Client *http.Client
+
+	once   sync.Once
+	ctx    context.Context
+	cancel context.CancelFunc
+	events *jobs.Subscription
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	status  map[string]*Status
+	order   []string
+	watches map[string][]*watch // by tenant and job, until they finish
+}
+
+// watch is a webhook waiting for its job to finish.
+type watch struct {
+	target, secret string
+}
+
+func (d *Dispatcher) init() {
+	d.once.Do(func() {
+		d.ctx, d.cancel = context.WithCancel(context.Background())
+		d.status = make(map[string]*Status)
+		d.watches = make(map[string][]*watch)
+		if d.Client == nil {
+			d.Client = guardedClient(d.timeout(), d.AllowPrivate)
+		}
+		if d.Jobs != nil {
+			d.events = d.Jobs.Bus().Subscribe("webhooks", 0, jobs.DropOldest)
+			d.wg.Go(func() { d.relay(d.events.Events()) })
+		}
+	})
+}
+
+// Secret reports the signing secret of principal, and whether it may use
+// webhooks at all.
+func (d *Dispatcher) Secret(principal string) (string, bool) {
+	secret, ok := d.Secrets[principal]
+	return secret, ok && secret != ""
+}
+
+// ValidateURL checks a submitted webhook URL, see the package function.
+func (d *Dispatcher) ValidateURL(raw string) error {
+	return ValidateURL(raw, d.AllowPrivate)
+}
+
+// Watch starts following job, owned by principal, to deliver its webhook
+// to target when it finishes. The caller has checked target with
+// ValidateURL and that principal has a secret. ctx is the submitting
+// request's: the watch outlives it but follows the job for its tenant.
+func (d *Dispatcher) Watch(ctx context.Context, jobID, principal, target string) {
+	d.init()
+	secret, _ := d.Secret(principal)
+	d.record(jobID, &Status{URL: target, State: StatePending, Attempts: []Attempt{}})
+	key := gateway.TenantID(ctx) + "\x00" + jobID
+	w := &watch{target: target, secret: secret}
+	d.mu.Lock()
+	d.watches[key] = append(d.watches[key], w)
+	d.mu.Unlock()
+	follow := d.ctx
+	if t := gateway.TenantFromContext(ctx); t != nil {
+		follow = gateway.WithTenant(follow, t)
+	}
+	d.wg.Go(func() {
+		ev, err := d.follow(follow, jobID)
+		switch {
+		case err != nil:
+			if d.claim(key, w) && d.ctx.Err() == nil {
+				slog.Warn("gave up watching job for its webhook", "job_id", jobID, "error", err)
+				d.update(jobID, func(s *Status) { s.State = StateFailed })
+			}
+		case ev != nil && d.claim(key, w):
+			d.deliver(jobID, target, secret, *ev)
+		}
+	})
+}
+
+// Status returns a copy of job's delivery record, if it has a webhook.
+func (d *Dispatcher) Status(jobID string) (*Status, bool) {
+	d.init()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s, ok := d.status[jobID]
+	if !ok {
+		return nil, false
+	}
+	out := *s
+	out.Attempts = append([]Attempt{}, s.Attempts...)
+	return &out, true
+}
+
+// Shutdown stops the watches and deliveries in progress and waits for
+// them to return, or for ctx to be done. Deliveries not yet made are lost.
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	d.init()
+	d.cancel()
+	if d.events != nil {
+		d.events.Close()
+	}
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// follow has d.Jobs follow job, retrying like a delivery, since a queued
+// job is not known upstream until it has been submitted. Its terminal
+// event then comes from the bus, except for a job that had already
+// finished, whose terminal event is replayed and returned. ctx is d.ctx,
+// carrying the job's tenant.
+func (d *Dispatcher) follow(ctx context.Context, jobID string) (*jobs.Event, error) {
+	for failures := 1; ; failures++ {
+		// The hold is never given up: the feed follows the job to its end.
+		_, err := d.Jobs.Follow(ctx, jobID)
+		switch {
+		case err == nil:
+			return nil, nil
+		case errors.Is(err, jobs.ErrFinished):
+			return d.replay(ctx, jobID)
+		case failures >= d.maxAttempts():
+			return nil, err
+		}
+		if !d.sleep(d.backoff(failures)) {
+			return nil, d.ctx.Err()
+		}
+	}
+}
+
+// replay returns the terminal event of a job that has finished.
+func (d *Dispatcher) replay(ctx context.Context, jobID string) (*jobs.Event, error) {
+	events, err := d.Jobs.Replay(ctx, jobID, 0)
+	if err != nil {
+		return nil, err
+	}
+	for ev := range events {
+		if ev.Terminal() {
+			return &ev, nil
+		}
+	}
+	return nil, errors.New("the job's progress stream ended before it finished")
+}
+
+// relay delivers the webhooks of each job whose terminal event is among
+// events, until the channel is closed. A StageError event, the gateway
+// giving up on the job's stream, is delivered as such.
+func (d *Dispatcher) relay(events <-chan jobs.Event) {
+	for ev := range events {
+		if !ev.Terminal() {
+			continue
+		}
+		key := ev.Tenant + "\x00" + ev.JobID
+		d.mu.Lock()
+		watches := d.watches[key]
+		delete(d.watches, key)
+		d.mu.Unlock()
+		for _, w := range watches {
+			d.wg.Go(func() { d.deliver(ev.JobID, w.target, w.secret, ev) })
+		}
+	}
+}
+
+// claim takes w off the watches of the job under key, reporting whether
+// it was still there for the caller to settle.
+func (d *Dispatcher) claim(key string, w *watch) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i, other := range d.watches[key] {
+		if other == w {
+			d.watches[key] = append(d.watches[key][:i], d.watches[key][i+1:]...)
+			if len(d.watches[key]) == 0 {
+				delete(d.watches, key)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// deliver sends ev to target until the receiver accepts it or the
+// attempts run out. Every attempt carries the same body, delivery ID and
+// signature, so receivers can drop duplicates.
+func (d *Dispatcher) deliver(jobID, target, secret string, ev jobs.Event) {
+	finished := ev.Time
+	if finished.IsZero() {
+		finished = time.Now()
+	}
+	deliveryID := uuid.New()
+	body, _ := json.Marshal(Payload{
+		Event:      EventJobFinished,
+		DeliveryID: deliveryID,
+		JobID:      jobID,
+		Stage:      ev.Stage,
+		Message:    ev.Message,
+		URL:        ev.URL,
+		FinishedAt: finished.UTC(),
+	})
+	signature := Sign(secret, body)
+
+	for n := 1; ; n++ {
+		attempt := d.post(target, deliveryID, signature, body)
+		delivered := attempt.Error == "" && attempt.StatusCode/100 == 2
+		d.update(jobID, func(s *Status) {
+			s.Attempts = append(s.Attempts, attempt)
+			switch {
+			case delivered:
+				s.State = StateDelivered
+			case n >= d.maxAttempts():
+				s.State = StateFailed
+			}
+		})
+		if delivered {
+			return
+		}
+		if n >= d.maxAttempts() {
+			slog.Warn("webhook delivery failed", "job_id", jobID, "attempts", n, "status", attempt.StatusCode, "error", attempt.Error)
+			return
+		}
+		if !d.sleep(d.backoff(n)) {
+			return
+		}
+	}
+}
+
+// post makes one delivery attempt.
+func (d *Dispatcher) post(target, deliveryID, signature string, body []byte) Attempt {
+	attempt := Attempt{At: time.Now().UTC()}
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = err.Error()
+		deliveries.With("error").Inc()
+		return attempt
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "content-factory-gateway-webhooks")
+	req.Header.Set(SignatureHeader, signature)
+	req.Header.Set(EventHeader, EventJobFinished)
+	req.Header.Set(DeliveryHeader, deliveryID)
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		attempt.Error = describe(err)
+		deliveries.With("error").Inc()
+		return attempt
+	}
+	// Draining a little lets the connection be reused.
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	attempt.StatusCode = resp.StatusCode
+	if resp.StatusCode/100 == 2 {
+		deliveries.With("delivered").Inc()
+	} else {
+		deliveries.With("rejected").Inc()
+	}
+	return attempt
+}
+
+// describe is the error recorded for a failed request, without the
+// method and URL the client wraps it in.
+func describe(err error) string {
+	if errors.Is(err, ErrBlockedAddress) {
+		return ErrBlockedAddress.Error()
+	}
+	var uerr *url.Error
+	if errors.As(err, &uerr) {
+		return uerr.Err.Error()
+	}
+	return err.Error()
+}
+
+// backoff is the wait after the nth failed attempt.
+func (d *Dispatcher) backoff(n int) time.Duration {
+	wait := d.InitialBackoff
+	if wait <= 0 {
+		wait = DefaultInitialBackoff
+	}
+	limit := d.MaxBackoff
+	if limit <= 0 {
+		limit = DefaultMaxBackoff
+	}
+	for i := 1; i < n && wait < limit; i++ {
+		wait *= 2
+	}
+	return min(wait, limit)
+}
+
+func (d *Dispatcher) sleep(wait time.Duration) bool {
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-d.ctx.Done():
+		return false
+	}
+}
+
+func (d *Dispatcher) maxAttempts() int {
+	if d.MaxAttempts < 1 {
+		return DefaultMaxAttempts
+	}
+	return d.MaxAttempts
+}
+
+func (d *Dispatcher) timeout() time.Duration {
+	if d.Timeout <= 0 {
+		return DefaultTimeout
+	}
+	return d.Timeout
+}
+
+// record stores s as job's record, forgetting the oldest record once
+// DefaultMaxTracked are kept.
+func (d *Dispatcher) record(jobID string, s *Status) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.status[jobID]; !ok {
+		d.order = append(d.order, jobID)
+	}
+	d.status[jobID] = s
+	for len(d.order) > DefaultMaxTracked {
+		delete(d.status, d.order[0])
+		d.order = d.order[1:]
+	}
+}
+
+func (d *Dispatcher) update(jobID string, f func(*Status)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if s, ok := d.status[jobID]; ok {
+		f(s)
+	}
+}
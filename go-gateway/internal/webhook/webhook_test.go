@@ -0,0 +1,257 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/jobs"
+)
+
+// fakeSource streams a fixed list of events per job, failing jobs it does
+// not know.
+type fakeSource struct {
+	mu     sync.Mutex
+	events map[string][]jobs.Event
+	opens  atomic.Int32
+}
+
+func (s *fakeSource) Subscribe(ctx context.Context, jobID string, after int64) (<-chan jobs.Event, error) {
+	s.opens.Add(1)
+	s.mu.Lock()
+	evs, ok := s.events[jobID]
+	s.mu.Unlock()
+	if !ok {
+		return nil, errors.New("job not found")
+	}
+	ch := make(chan jobs.Event, len(evs))
+	for _, ev := range evs {
+		if ev.Seq > after {
+			ch <- ev
+		}
+	}
+	close(ch)
+	return ch, nil
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func state(d *Dispatcher, jobID string) func() bool {
+	return func() bool {
+		s, ok := d.Status(jobID)
+		return ok && s.State != StatePending
+	}
+}
+
+func TestBlocked(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"127.0.0.1":            true,
+		"10.1.2.3":             true,
+		"172.16.0.1":           true,
+		"192.168.1.1":          true,
+		"169.254.169.254":      true,
+		"100.64.0.1":           true,
+		"0.0.0.0":              true,
+		"::1":                  true,
+		"fd00::1":              true,
+		"fe80::1":              true,
+		"::ffff:127.0.0.1":     true,
+		"::ffff:10.0.0.1":      true,
+		"224.0.0.1":            true,
+		"8.8.8.8":              false,
+		"2606:4700::1111":      false,
+		"::ffff:93.184.216.34": false,
+	} {
+		if got := Blocked(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("Blocked(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestValidateURL(t *testing.T) {
+	for raw, ok := range map[string]bool{
+		"https://hooks.example.com/content":                      true,
+		"http://hooks.example.com:8080/x?y=1":                    true,
+		"ftp://hooks.example.com/":                               false,
+		"/relative":                                              false,
+		"https://user:pw@hooks.example.com/":                     false,
+		"https://localhost/hook":                                 false,
+		"https://api.localhost/hook":                             false,
+		"http://127.0.0.1:9000/":                                 false,
+		"http://[::1]/":                                          false,
+		"http://169.254.169.254/latest":                          false,
+		"https://" + strings.Repeat("a", MaxURLLength) + ".com/": false,
+	} {
+		if err := ValidateURL(raw, false); (err == nil) != ok {
+			t.Errorf("ValidateURL(%q) = %v, want ok %v", raw, err, ok)
+		}
+	}
+	if err := ValidateURL("http://127.0.0.1:9000/", true); err != nil {
+		t.Errorf("with private networks allowed: %v", err)
+	}
+}
+
+func TestDeliverRetriesUntilAccepted(t *testing.T) {
+	var calls atomic.Int32
+	var got struct {
+		sync.Mutex
+		bodies     [][]byte
+		signatures []string
+		deliveries []string
+	}
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got.Lock()
+		got.bodies = append(got.bodies, body)
+		got.signatures = append(got.signatures, r.Header.Get(SignatureHeader))
+		got.deliveries = append(got.deliveries, r.Header.Get(DeliveryHeader))
+		got.Unlock()
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	src := &fakeSource{events: map[string][]jobs.Event{"job-1": {
+		{JobID: "job-1", Seq: 1, Stage: "drafting", Percent: 40},
+		{JobID: "job-1", Seq: 2, Stage: jobs.StageDone, Percent: 100, URL: "https://cdn.example.com/job-1.mp4", Time: time.Unix(1700000000, 0)},
+	}}}
+	d := &Dispatcher{
+		Jobs: jobs.NewFeed(context.Background(), src, jobs.NewBus()), Secrets: map[string]string{"user-1": "0123456789abcdef"},
+		InitialBackoff: time.Millisecond, AllowPrivate: true,
+	}
+	defer d.Shutdown(context.Background())
+	d.Watch(context.Background(), "job-1", "user-1", receiver.URL+"/hook")
+	waitFor(t, "the delivery", state(d, "job-1"))
+
+	status, _ := d.Status("job-1")
+	if status.State != StateDelivered || len(status.Attempts) != 2 || status.Attempts[0].StatusCode != 503 || status.Attempts[1].StatusCode != 204 {
+		t.Fatalf("status = %+v", status)
+	}
+	got.Lock()
+	defer got.Unlock()
+	var p Payload
+	if err := json.Unmarshal(got.bodies[1], &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Event != EventJobFinished || p.JobID != "job-1" || p.Stage != jobs.StageDone || p.URL != "https://cdn.example.com/job-1.mp4" || !p.FinishedAt.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("payload = %+v", p)
+	}
+	if want := Sign("0123456789abcdef", got.bodies[1]); got.signatures[1] != want || !strings.HasPrefix(want, "sha256=") {
+		t.Errorf("signature = %q, want %q", got.signatures[1], want)
+	}
+	// A retry is the same delivery.
+	if string(got.bodies[0]) != string(got.bodies[1]) || got.deliveries[0] != p.DeliveryID || got.deliveries[1] != p.DeliveryID {
+		t.Errorf("retry differs: %s / %s, deliveries %v", got.bodies[0], got.bodies[1], got.deliveries)
+	}
+}
+
+func TestDeliverGivesUp(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer receiver.Close()
+	src := &fakeSource{events: map[string][]jobs.Event{"job-1": {{JobID: "job-1", Seq: 1, Stage: jobs.StageFailed}}}}
+	d := &Dispatcher{Jobs: jobs.NewFeed(context.Background(), src, jobs.NewBus()), Secrets: map[string]string{"u": "0123456789abcdef"}, MaxAttempts: 3, InitialBackoff: time.Millisecond, AllowPrivate: true}
+	defer d.Shutdown(context.Background())
+	d.Watch(context.Background(), "job-1", "u", receiver.URL)
+	waitFor(t, "the deliveries to run out", state(d, "job-1"))
+
+	if status, _ := d.Status("job-1"); status.State != StateFailed || len(status.Attempts) != 3 {
+		t.Errorf("status = %+v", status)
+	}
+}
+
+func TestGuardRefusesPrivateAddresses(t *testing.T) {
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer receiver.Close()
+	src := &fakeSource{events: map[string][]jobs.Event{"job-1": {{JobID: "job-1", Seq: 1, Stage: jobs.StageDone}}}}
+	// The URL skipped ValidateURL, as a public name resolving to a
+	// private address would.
+	d := &Dispatcher{Jobs: jobs.NewFeed(context.Background(), src, jobs.NewBus()), Secrets: map[string]string{"u": "0123456789abcdef"}, MaxAttempts: 2, InitialBackoff: time.Millisecond}
+	defer d.Shutdown(context.Background())
+	d.Watch(context.Background(), "job-1", "u", receiver.URL)
+	waitFor(t, "the deliveries to run out", state(d, "job-1"))
+
+	status, _ := d.Status("job-1")
+	if status.State != StateFailed || len(status.Attempts) != 2 || !strings.Contains(status.Attempts[0].Error, "not publicly routable") {
+		t.Errorf("status = %+v", status)
+	}
+	if calls.Load() != 0 {
+		t.Errorf("receiver called %d times", calls.Load())
+	}
+}
+
+func TestWatchFinishedJob(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+	src := &fakeSource{events: map[string][]jobs.Event{"job-1": {{JobID: "job-1", Seq: 1, Stage: jobs.StageDone}}}}
+	feed := jobs.NewFeed(context.Background(), src, jobs.NewBus())
+	done := feed.Bus().Subscribe("test", 1, jobs.Disconnect)
+	if _, err := feed.Follow(context.Background(), "job-1"); err != nil {
+		t.Fatal(err)
+	}
+	<-done.Events()
+	waitFor(t, "the job to finish", func() bool { return feed.Following() == 0 })
+
+	// Its terminal event has gone by on the bus, so it is replayed.
+	d := &Dispatcher{Jobs: feed, Secrets: map[string]string{"u": "0123456789abcdef"}, InitialBackoff: time.Millisecond, AllowPrivate: true}
+	defer d.Shutdown(context.Background())
+	d.Watch(context.Background(), "job-1", "u", receiver.URL)
+	waitFor(t, "the delivery", state(d, "job-1"))
+
+	if status, _ := d.Status("job-1"); status.State != StateDelivered || src.opens.Load() != 2 {
+		t.Errorf("status = %+v after %d opens", status, src.opens.Load())
+	}
+}
+
+func TestWatchGivesUpOnUnknownJob(t *testing.T) {
+	src := &fakeSource{events: map[string][]jobs.Event{}}
+	d := &Dispatcher{Jobs: jobs.NewFeed(context.Background(), src, jobs.NewBus()), Secrets: map[string]string{"u": "0123456789abcdef"}, MaxAttempts: 3, InitialBackoff: time.Millisecond}
+	defer d.Shutdown(context.Background())
+	d.Watch(context.Background(), "missing", "u", "https://hooks.example.com/")
+	waitFor(t, "the watch to give up", state(d, "missing"))
+
+	if status, _ := d.Status("missing"); status.State != StateFailed || len(status.Attempts) != 0 || src.opens.Load() != 3 {
+		t.Errorf("status = %+v after %d opens", status, src.opens.Load())
+	}
+}
+
+func TestShutdownStopsWatches(t *testing.T) {
+	src := &fakeSource{events: map[string][]jobs.Event{}}
+	d := &Dispatcher{Jobs: jobs.NewFeed(context.Background(), src, jobs.NewBus()), Secrets: map[string]string{"u": "0123456789abcdef"}, InitialBackoff: time.Hour}
+	d.Watch(context.Background(), "missing", "u", "https://hooks.example.com/")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := d.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := d.Status("missing"); status.State != StatePending {
+		t.Errorf("status after shutdown = %+v", status)
+	}
+}
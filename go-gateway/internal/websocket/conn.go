@@ -0,0 +1,383 @@
+// Package websocket is a compact RFC 6455 implementation covering what the
+// gateway needs: server upgrades, text/binary messages, ping/pong, the
+// closing handshake and permessage-deflate compression (RFC 7692).
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Message types, which are also the frame opcodes.
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+)
+
+const opContinuation = 0
+
+// Close codes defined by RFC 6455 section 7.4.1.
+const (
+	CloseNormalClosure    = 1000
+	CloseGoingAway        = 1001
+	CloseProtocolError    = 1002
+	CloseUnsupportedData  = 1003
+	CloseNoStatusReceived = 1005
+	CloseAbnormalClosure  = 1006
+	CloseInvalidPayload   = 1007
+	ClosePolicyViolation  = 1008
+	CloseMessageTooBig    = 1009
+	CloseInternalError    = 1011
+)
+
+const maxControlPayload = 125
+
+// CloseError is returned by ReadMessage once the peer has sent a close
+// frame or the connection was closed locally with Close.
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: close %d %s", e.Code, e.Text)
+}
+
+// ErrReadLimit is returned by ReadMessage when a message exceeds the limit
+// set with SetReadLimit. The connection has already been closed with
+// CloseMessageTooBig.
+var ErrReadLimit = errors.New("websocket: message exceeds read limit")
+
+// Conn is a WebSocket connection. One goroutine may read while others
+// write; writes are serialised internally.
+type Conn struct {
+	conn     net.Conn
+	br       *bufio.Reader
+	isServer bool
+	subproto string
+
+	writeMu   sync.Mutex
+	closeSent bool
+
+	readLimit   int64
+	pongHandler func(appData string) error
+
+	// deflate and inflate are set once permessage-deflate is negotiated;
+	// deflate is guarded by writeMu.
+	deflate *deflater
+	inflate *inflater
+}
+
+func newConn(c net.Conn, br *bufio.Reader, isServer bool) *Conn {
+	if br == nil {
+		br = bufio.NewReader(c)
+	}
+	return &Conn{conn: c, br: br, isServer: isServer}
+}
+
+// Subprotocol returns the negotiated Sec-WebSocket-Protocol, if any.
+func (c *Conn) Subprotocol() string { return c.subproto }
+
+// Compressed reports whether permessage-deflate was negotiated.
+func (c *Conn) Compressed() bool { return c.deflate != nil }
+
+// RemoteAddr returns the peer's network address.
+func (c *Conn) RemoteAddr() net.Addr { return c.conn.RemoteAddr() }
+
+// SetReadDeadline sets the deadline for the next frame read.
+func (c *Conn) SetReadDeadline(t time.Time) error { return c.conn.SetReadDeadline(t) }
+
+// SetWriteDeadline sets the deadline for future writes.
+func (c *Conn) SetWriteDeadline(t time.Time) error { return c.conn.SetWriteDeadline(t) }
+
+// SetReadLimit caps the size of an inbound message; zero means no limit.
+func (c *Conn) SetReadLimit(limit int64) { c.readLimit = limit }
+
+// SetPongHandler registers a callback for pong frames, typically used to
+// extend the read deadline.
+func (c *Conn) SetPongHandler(h func(appData string) error) { c.pongHandler = h }
+
+// WriteMessage sends a complete text or binary message in a single frame,
+// compressed if permessage-deflate was negotiated and it is long enough.
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return errors.New("websocket: WriteMessage requires a text or binary message type")
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return &CloseError{Code: CloseAbnormalClosure, Text: "close already sent"}
+	}
+	if c.deflate != nil && len(data) >= c.deflate.threshold {
+		compressed := c.deflate.compress(data)
+		compressionBytes.With("uncompressed").Add(float64(len(data)))
+		compressionBytes.With("compressed").Add(float64(len(compressed)))
+		return c.writeFrame(messageType, true, compressed)
+	}
+	return c.writeFrame(messageType, false, data)
+}
+
+// WriteControl sends a ping, pong or close frame with the given write
+// deadline.
+func (c *Conn) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	if messageType != PingMessage && messageType != PongMessage && messageType != CloseMessage {
+		return errors.New("websocket: WriteControl requires a control message type")
+	}
+	if len(data) > maxControlPayload {
+		return errors.New("websocket: control frame payload too large")
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return &CloseError{Code: CloseAbnormalClosure, Text: "close already sent"}
+	}
+	if messageType == CloseMessage {
+		c.closeSent = true
+	}
+	c.conn.SetWriteDeadline(deadline)
+	defer c.conn.SetWriteDeadline(time.Time{})
+	return c.writeFrame(messageType, false, data)
+}
+
+// WriteClose starts the closing handshake with the given code and reason.
+func (c *Conn) WriteClose(code int, reason string, deadline time.Time) error {
+	return c.WriteControl(CloseMessage, FormatCloseMessage(code, reason), deadline)
+}
+
+// FormatCloseMessage builds a close frame payload.
+func FormatCloseMessage(code int, text string) []byte {
+	if code == CloseNoStatusReceived {
+		return nil
+	}
+	if len(text) > maxControlPayload-2 {
+		text = text[:maxControlPayload-2]
+	}
+	buf := make([]byte, 2+len(text))
+	binary.BigEndian.PutUint16(buf, uint16(code))
+	copy(buf[2:], text)
+	return buf
+}
+
+// Close closes the underlying connection without a closing handshake.
+func (c *Conn) Close() error { return c.conn.Close() }
+
+// writeFrame sends payload as a final frame; compressed sets RSV1, which
+// marks a permessage-deflate message.
+func (c *Conn) writeFrame(opcode int, compressed bool, payload []byte) error {
+	var header [14]byte
+	header[0] = 0x80 | byte(opcode) // FIN
+	if compressed {
+		header[0] |= 0x40
+	}
+	n := 2
+	switch l := len(payload); {
+	case l <= 125:
+		header[1] = byte(l)
+	case l <= 0xFFFF:
+		header[1] = 126
+		binary.BigEndian.PutUint16(header[2:], uint16(l))
+		n += 2
+	default:
+		header[1] = 127
+		binary.BigEndian.PutUint64(header[2:], uint64(l))
+		n += 8
+	}
+
+	if !c.isServer {
+		// Clients must mask every frame (RFC 6455 section 5.3).
+		header[1] |= 0x80
+		var key [4]byte
+		if _, err := io.ReadFull(randReader, key[:]); err != nil {
+			return err
+		}
+		copy(header[n:], key[:])
+		n += 4
+		masked := make([]byte, len(payload))
+		copy(masked, payload)
+		maskBytes(key, masked)
+		payload = masked
+	}
+
+	if _, err := c.conn.Write(header[:n]); err != nil {
+		return err
+	}
+	_, err := c.conn.Write(payload)
+	return err
+}
+
+type frame struct {
+	fin        bool
+	compressed bool
+	opcode     int
+	payload    []byte
+}
+
+func (c *Conn) readFrame(remaining int64) (frame, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		return frame{}, err
+	}
+	f := frame{fin: head[0]&0x80 != 0, compressed: head[0]&0x40 != 0, opcode: int(head[0] & 0x0F)}
+	// RSV1 marks the first frame of a compressed data message.
+	dataStart := f.opcode == TextMessage || f.opcode == BinaryMessage
+	if head[0]&0x30 != 0 || (f.compressed && (c.inflate == nil || !dataStart)) {
+		return frame{}, c.protocolError(CloseProtocolError, "reserved bits set")
+	}
+	masked := head[1]&0x80 != 0
+	if masked != c.isServer {
+		return frame{}, c.protocolError(CloseProtocolError, "bad frame masking")
+	}
+
+	length := int64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var b [2]byte
+		if _, err := io.ReadFull(c.br, b[:]); err != nil {
+			return frame{}, err
+		}
+		length = int64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		if _, err := io.ReadFull(c.br, b[:]); err != nil {
+			return frame{}, err
+		}
+		length = int64(binary.BigEndian.Uint64(b[:]))
+		if length < 0 {
+			return frame{}, c.protocolError(CloseProtocolError, "invalid frame length")
+		}
+	}
+
+	isControl := f.opcode >= CloseMessage
+	if isControl && (length > maxControlPayload || !f.fin) {
+		return frame{}, c.protocolError(CloseProtocolError, "invalid control frame")
+	}
+	if !isControl && remaining >= 0 && length > remaining {
+		c.WriteClose(CloseMessageTooBig, "message too big", time.Now().Add(time.Second))
+		c.Close()
+		return frame{}, ErrReadLimit
+	}
+
+	var key [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.br, key[:]); err != nil {
+			return frame{}, err
+		}
+	}
+	f.payload = make([]byte, length)
+	if _, err := io.ReadFull(c.br, f.payload); err != nil {
+		return frame{}, err
+	}
+	if masked {
+		maskBytes(key, f.payload)
+	}
+	return f, nil
+}
+
+// ReadMessage returns the next text or binary message, answering pings and
+// dispatching pongs along the way. After the peer closes, it replies with
+// a close frame and returns a *CloseError.
+func (c *Conn) ReadMessage() (messageType int, data []byte, err error) {
+	var buf []byte
+	msgType, compressed := 0, false
+	for {
+		remaining := int64(-1)
+		if c.readLimit > 0 {
+			remaining = c.readLimit - int64(len(buf))
+		}
+		f, err := c.readFrame(remaining)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch f.opcode {
+		case PingMessage:
+			c.WriteControl(PongMessage, f.payload, time.Now().Add(time.Second))
+			continue
+		case PongMessage:
+			if c.pongHandler != nil {
+				if err := c.pongHandler(string(f.payload)); err != nil {
+					return 0, nil, err
+				}
+			}
+			continue
+		case CloseMessage:
+			return 0, nil, c.handleClose(f.payload)
+		case TextMessage, BinaryMessage:
+			if msgType != 0 {
+				return 0, nil, c.protocolError(CloseProtocolError, "expected continuation frame")
+			}
+			msgType, compressed = f.opcode, f.compressed
+		case opContinuation:
+			if msgType == 0 {
+				return 0, nil, c.protocolError(CloseProtocolError, "unexpected continuation frame")
+			}
+		default:
+			return 0, nil, c.protocolError(CloseProtocolError, "unknown opcode")
+		}
+
+		buf = append(buf, f.payload...)
+		if f.fin {
+			if compressed {
+				if buf, err = c.inflate.decompress(buf, c.readLimit); errors.Is(err, ErrReadLimit) {
+					c.WriteClose(CloseMessageTooBig, "message too big", time.Now().Add(time.Second))
+					c.Close()
+					return 0, nil, err
+				} else if err != nil {
+					return 0, nil, c.protocolError(CloseInvalidPayload, "invalid compressed message")
+				}
+			}
+			if msgType == TextMessage && !utf8.Valid(buf) {
+				return 0, nil, c.protocolError(CloseInvalidPayload, "invalid UTF-8 in text message")
+			}
+			return msgType, buf, nil
+		}
+	}
+}
+
+func (c *Conn) handleClose(payload []byte) error {
+	ce := &CloseError{Code: CloseNoStatusReceived}
+	if len(payload) >= 2 {
+		ce.Code = int(binary.BigEndian.Uint16(payload))
+		ce.Text = string(payload[2:])
+	}
+	// Echo the close to complete the handshake; ignore the error if we
+	// already started it ourselves.
+	c.WriteControl(CloseMessage, FormatCloseMessage(ce.Code, ""), time.Now().Add(time.Second))
+	return ce
+}
+
+func (c *Conn) protocolError(code int, reason string) error {
+	c.WriteClose(code, reason, time.Now().Add(time.Second))
+	c.Close()
+	return &CloseError{Code: code, Text: reason}
+}
+
+func maskBytes(key [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= key[i&3]
+	}
+}
+
+// IsCloseError reports whether err is a *CloseError with one of codes.
+func IsCloseError(err error, codes ...int) bool {
+	var ce *CloseError
+	if !errors.As(err, &ce) {
+		return false
+	}
+	for _, code := range codes {
+		if ce.Code == code {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,257 @@
+package websocket
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/content-factory/go-gateway/internal/metrics"
+)
+
+// Defaults for Compression's zero fields.
+const (
+	// DefaultCompressionLevel favours speed: event messages are small and
+	// repetitive, so higher levels gain little.
+	DefaultCompressionLevel = flate.BestSpeed
+	// DefaultCompressionThreshold is the smallest message compressed;
+	// smaller ones cost more to compress than they save.
+	DefaultCompressionThreshold = 256
+)
+
+const deflateExtension = "permessage-deflate"
+
+// maxWindow is the LZ77 window of compress/flate, 2^15 bytes. It cannot be
+// made smaller, so offers limiting the server's window are declined.
+const maxWindow = 1 << 15
+
+// deflateTail is the empty stored block ending every flushed deflate
+// stream, which RFC 7692 strips from messages. inflateTail puts it back,
+// followed by a final empty block so a reader reaches io.EOF.
+var (
+	deflateTail = []byte{0x00, 0x00, 0xff, 0xff}
+	inflateTail = []byte{0x00, 0x00, 0xff, 0xff, 0x01, 0x00, 0x00, 0xff, 0xff}
+)
+
+var compressionBytes = metrics.NewCounterVec("gateway_websocket_compression_bytes_total",
+	"Bytes of WebSocket messages sent compressed, before (uncompressed) and after (compressed) compression.",
+	"stage")
+
+// Compression configures the permessage-deflate extension (RFC 7692). It
+// is negotiated with clients that offer it; others get uncompressed
+// frames, as do messages shorter than Threshold.
+type Compression struct {
+	// Level is the compress/flate level, 1 to 9; zero means
+	// DefaultCompressionLevel.
+	Level int
+	// Threshold is the smallest message compressed, in bytes; zero means
+	// DefaultCompressionThreshold.
+	Threshold int
+	// NoContextTakeover compresses each message on its own instead of
+	// with the window of those before it, compressing worse but holding
+	// no compressor per idle connection.
+	NoContextTakeover bool
+}
+
+// flateWriters pools compressors, by level, between messages of
+// connections without context takeover.
+var flateWriters [flate.BestCompression + 1]sync.Pool
+
+// deflater compresses the messages one side of a connection sends.
+type deflater struct {
+	level     int
+	threshold int
+	takeover  bool
+	buf       bytes.Buffer
+	// fw is held between messages only with context takeover.
+	fw *flate.Writer
+}
+
+func newDeflater(cfg Compression, takeover bool) *deflater {
+	d := &deflater{level: cfg.Level, threshold: cfg.Threshold, takeover: takeover}
+	if d.level < flate.BestSpeed || d.level > flate.BestCompression {
+		d.level = DefaultCompressionLevel
+	}
+	if d.threshold <= 0 {
+		d.threshold = DefaultCompressionThreshold
+	}
+	return d
+}
+
+// compress returns p deflated, without the trailing empty block. The
+// result is only valid until the next call.
+func (d *deflater) compress(p []byte) []byte {
+	d.buf.Reset()
+	fw := d.fw
+	if fw == nil {
+		if pooled, ok := flateWriters[d.level].Get().(*flate.Writer); ok {
+			fw = pooled
+			fw.Reset(&d.buf)
+		} else {
+			// The level is valid, so NewWriter cannot fail.
+			fw, _ = flate.NewWriter(&d.buf, d.level)
+		}
+		if d.takeover {
+			d.fw = fw
+		} else {
+			defer flateWriters[d.level].Put(fw)
+		}
+	}
+	// Writes to a bytes.Buffer do not fail.
+	fw.Write(p)
+	fw.Flush()
+	return bytes.TrimSuffix(d.buf.Bytes(), deflateTail)
+}
+
+// inflater decompresses the messages one side of a connection receives.
+type inflater struct {
+	// takeover is whether the peer compresses with the window of earlier
+	// messages, which dict then holds.
+	takeover bool
+	dict     []byte
+	fr       io.ReadCloser
+}
+
+// decompress returns the message p inflated, or ErrReadLimit if it
+// inflates to more than limit bytes; zero means no limit.
+func (f *inflater) decompress(p []byte, limit int64) ([]byte, error) {
+	src := io.MultiReader(bytes.NewReader(p), bytes.NewReader(inflateTail))
+	if f.fr == nil {
+		f.fr = flate.NewReaderDict(src, f.dict)
+	} else if err := f.fr.(flate.Resetter).Reset(src, f.dict); err != nil {
+		return nil, err
+	}
+	r := io.Reader(f.fr)
+	if limit > 0 {
+		r = io.LimitReader(r, limit+1)
+	}
+	out, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && int64(len(out)) > limit {
+		return nil, ErrReadLimit
+	}
+	if f.takeover {
+		f.dict = append(f.dict, out...)
+		if len(f.dict) > maxWindow {
+			f.dict = append([]byte(nil), f.dict[len(f.dict)-maxWindow:]...)
+		}
+	}
+	return out, nil
+}
+
+// extension is one entry of a Sec-WebSocket-Extensions header.
+type extension struct {
+	name   string
+	params []extensionParam
+}
+
+type extensionParam struct{ name, value string }
+
+// parseExtensions returns the extensions named in h's
+// Sec-WebSocket-Extensions values, in order.
+func parseExtensions(h http.Header) []extension {
+	var exts []extension
+	for _, v := range h.Values("Sec-WebSocket-Extensions") {
+		for _, e := range strings.Split(v, ",") {
+			parts := strings.Split(e, ";")
+			ext := extension{name: strings.ToLower(strings.TrimSpace(parts[0]))}
+			if ext.name == "" {
+				continue
+			}
+			for _, p := range parts[1:] {
+				name, value, _ := strings.Cut(p, "=")
+				ext.params = append(ext.params, extensionParam{
+					name:  strings.ToLower(strings.TrimSpace(name)),
+					value: strings.Trim(strings.TrimSpace(value), `"`),
+				})
+			}
+			exts = append(exts, ext)
+		}
+	}
+	return exts
+}
+
+// negotiateDeflate picks the first permessage-deflate offer in h the
+// server can accept, returning its response and whether the server may
+// keep its context between messages. The client is always asked not to,
+// so the server holds no decompressor window. ok is false if no offer
+// was acceptable.
+func negotiateDeflate(h http.Header, noContextTakeover bool) (response string, takeover, ok bool) {
+offers:
+	for _, ext := range parseExtensions(h) {
+		if ext.name != deflateExtension {
+			continue
+		}
+		noTakeover, windowBits := noContextTakeover, ""
+		seen := make(map[string]bool, len(ext.params))
+		for _, p := range ext.params {
+			if seen[p.name] {
+				continue offers
+			}
+			seen[p.name] = true
+			switch p.name {
+			case "server_no_context_takeover":
+				noTakeover = true
+			case "client_no_context_takeover":
+			case "server_max_window_bits":
+				if p.value != "15" {
+					continue offers
+				}
+				windowBits = p.value
+			case "client_max_window_bits":
+				if p.value != "" {
+					if bits, err := strconv.Atoi(p.value); err != nil || bits < 8 || bits > 15 {
+						continue offers
+					}
+				}
+			default:
+				continue offers
+			}
+		}
+		response = deflateExtension + "; client_no_context_takeover"
+		if noTakeover {
+			response += "; server_no_context_takeover"
+		}
+		if windowBits != "" {
+			response += "; server_max_window_bits=" + windowBits
+		}
+		return response, !noTakeover, true
+	}
+	return "", false, false
+}
+
+// acceptDeflate applies the server's response to a client's
+// permessage-deflate offer, returning nil coders if the server declined
+// it.
+func acceptDeflate(h http.Header) (*deflater, *inflater, error) {
+	exts := parseExtensions(h)
+	if len(exts) == 0 {
+		return nil, nil, nil
+	}
+	if len(exts) > 1 || exts[0].name != deflateExtension {
+		return nil, nil, errors.New("websocket: server accepted an extension that was not offered")
+	}
+	serverTakeover, clientTakeover := true, true
+	for _, p := range exts[0].params {
+		switch p.name {
+		case "server_no_context_takeover":
+			serverTakeover = false
+		case "client_no_context_takeover":
+			clientTakeover = false
+		case "server_max_window_bits":
+		case "client_max_window_bits":
+			if p.value != "15" {
+				return nil, nil, errors.New("websocket: server asked for a smaller compression window")
+			}
+		default:
+			return nil, nil, errors.New("websocket: unknown permessage-deflate parameter " + p.name)
+		}
+	}
+	return newDeflater(Compression{}, clientTakeover), &inflater{takeover: serverTakeover}, nil
+}
@@ -0,0 +1,134 @@
+package websocket
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var offerDeflate = http.Header{"Sec-WebSocket-Extensions": {"permessage-deflate; client_max_window_bits"}}
+
+func TestCompressedEchoRoundTrip(t *testing.T) {
+	for _, noTakeover := range []bool{false, true} {
+		srv := upgraderEchoServer(t, &Upgrader{Compression: &Compression{NoContextTakeover: noTakeover}}, 0)
+		c, resp, err := Dial(wsURL(srv), offerDeflate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ext := resp.Header.Get("Sec-WebSocket-Extensions")
+		if !c.Compressed() || strings.Contains(ext, "server_no_context_takeover") != noTakeover {
+			t.Errorf("no context takeover %v: compressed %v, extensions %q", noTakeover, c.Compressed(), ext)
+		}
+
+		before := compressionBytes.With("uncompressed").Value()
+		// Repeated messages exercise the window kept between them; the
+		// short one goes uncompressed.
+		big := bytes.Repeat([]byte(`{"type":"progress","percent":50}`), 2000)
+		for _, msg := range [][]byte{big, []byte("hello"), big, big[:300]} {
+			if err := c.WriteMessage(TextMessage, msg); err != nil {
+				t.Fatal(err)
+			}
+			mt, got, err := c.ReadMessage()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if mt != TextMessage || !bytes.Equal(got, msg) {
+				t.Fatalf("echo mismatch: type %d len %d, want len %d", mt, len(got), len(msg))
+			}
+		}
+		// Each compressed message is counted by both ends.
+		if got, want := compressionBytes.With("uncompressed").Value()-before, float64(2*(2*len(big)+300)); got != want {
+			t.Errorf("uncompressed bytes = %v, want %v", got, want)
+		}
+		c.Close()
+		srv.Close()
+	}
+}
+
+func TestCompressedReadLimit(t *testing.T) {
+	srv := upgraderEchoServer(t, &Upgrader{Compression: &Compression{}}, 1024)
+	defer srv.Close()
+	c, _, err := Dial(wsURL(srv), offerDeflate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// The message compresses to far less than the limit, but inflates to
+	// more.
+	c.WriteMessage(TextMessage, bytes.Repeat([]byte("x"), 4096))
+	_, _, err = c.ReadMessage()
+	if !IsCloseError(err, CloseMessageTooBig) {
+		t.Fatalf("err = %v, want close 1009", err)
+	}
+}
+
+func TestCompressionNotNegotiated(t *testing.T) {
+	// Neither a server without Compression nor a client that does not
+	// offer it ends up compressing.
+	for name, tc := range map[string]struct {
+		up     *Upgrader
+		header http.Header
+	}{
+		"disabled":    {&Upgrader{}, offerDeflate},
+		"not offered": {&Upgrader{Compression: &Compression{}}, nil},
+	} {
+		srv := upgraderEchoServer(t, tc.up, 0)
+		c, resp, err := Dial(wsURL(srv), tc.header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Compressed() || resp.Header.Get("Sec-WebSocket-Extensions") != "" {
+			t.Errorf("%s: negotiated %q", name, resp.Header.Get("Sec-WebSocket-Extensions"))
+		}
+		msg := bytes.Repeat([]byte("x"), 1024)
+		c.WriteMessage(BinaryMessage, msg)
+		if _, got, err := c.ReadMessage(); err != nil || !bytes.Equal(got, msg) {
+			t.Errorf("%s: echo: %v", name, err)
+		}
+		c.Close()
+		srv.Close()
+	}
+}
+
+func TestNegotiateDeflate(t *testing.T) {
+	tests := []struct {
+		offer, want string
+	}{
+		{"permessage-deflate", "permessage-deflate; client_no_context_takeover"},
+		{"permessage-deflate; server_no_context_takeover", "permessage-deflate; client_no_context_takeover; server_no_context_takeover"},
+		{`permessage-deflate; server_max_window_bits="15"`, "permessage-deflate; client_no_context_takeover; server_max_window_bits=15"},
+		// An offer the server cannot meet is skipped for the next.
+		{"permessage-deflate; server_max_window_bits=10, permessage-deflate; client_max_window_bits=9", "permessage-deflate; client_no_context_takeover"},
+		{"permessage-deflate; server_max_window_bits=10", ""},
+		{"permessage-deflate; client_no_context_takeover; client_no_context_takeover", ""},
+		{"permessage-deflate; unknown_param", ""},
+		{"x-webkit-deflate-frame", ""},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Sec-WebSocket-Extensions", tt.offer)
+		if got, _, _ := negotiateDeflate(r.Header, false); got != tt.want {
+			t.Errorf("offer %q: response %q, want %q", tt.offer, got, tt.want)
+		}
+	}
+}
+
+func TestRejectsUnnegotiatedCompressedFrame(t *testing.T) {
+	srv := echoServer(t, 0)
+	defer srv.Close()
+	c, _, err := Dial(wsURL(srv), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.writeMu.Lock()
+	c.writeFrame(TextMessage, true, []byte("hello"))
+	c.writeMu.Unlock()
+	if _, _, err := c.ReadMessage(); !IsCloseError(err, CloseProtocolError) {
+		t.Fatalf("err = %v, want close 1002", err)
+	}
+}
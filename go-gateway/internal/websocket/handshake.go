@@ -0,0 +1,249 @@
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/apierror"
+)
+
+var randReader = rand.Reader
+
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// HandshakeError describes a rejected upgrade request. Upgrade has already
+// written an HTTP error response when it returns one.
+type HandshakeError struct {
+	Status int
+	Reason string
+}
+
+func (e *HandshakeError) Error() string { return "websocket: " + e.Reason }
+
+// Upgrader upgrades HTTP requests to WebSocket connections.
+type Upgrader struct {
+	// HandshakeTimeout bounds writing the 101 response.
+	HandshakeTimeout time.Duration
+	// CheckOrigin decides whether the request's Origin is acceptable. The
+	// default accepts requests without an Origin header and same-host
+	// origins.
+	CheckOrigin func(r *http.Request) bool
+	// Compression, if set, negotiates permessage-deflate with clients
+	// that offer it.
+	Compression *Compression
+}
+
+// IsWebSocketUpgrade reports whether r asks to switch to WebSocket.
+func IsWebSocketUpgrade(r *http.Request) bool {
+	return headerContainsToken(r.Header, "Connection", "upgrade") &&
+		headerContainsToken(r.Header, "Upgrade", "websocket")
+}
+
+// Upgrade completes the WebSocket handshake and hijacks the connection.
+// responseHeader may carry extra headers for the 101 response, such as
+// Sec-WebSocket-Protocol.
+func (u *Upgrader) Upgrade(w http.ResponseWriter, r *http.Request, responseHeader http.Header) (*Conn, error) {
+	if r.ProtoMajor != 1 {
+		// RFC 6455 upgrades HTTP/1.1 connections only; an HTTP/2 stream
+		// cannot be hijacked.
+		return nil, u.fail(w, r, http.StatusHTTPVersionNotSupported, "upgrade requires HTTP/1.1")
+	}
+	if r.Method != http.MethodGet {
+		return nil, u.fail(w, r, http.StatusMethodNotAllowed, "upgrade requires GET")
+	}
+	if !IsWebSocketUpgrade(r) {
+		return nil, u.fail(w, r, http.StatusBadRequest, "missing Connection: upgrade / Upgrade: websocket headers")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, u.fail(w, r, http.StatusUpgradeRequired, "unsupported Sec-WebSocket-Version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, u.fail(w, r, http.StatusBadRequest, "invalid Sec-WebSocket-Key")
+	}
+	checkOrigin := u.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+	if !checkOrigin(r) {
+		return nil, u.fail(w, r, http.StatusForbidden, "origin not allowed")
+	}
+
+	netConn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, u.fail(w, r, http.StatusInternalServerError, "connection cannot be hijacked")
+	}
+	// Anything the client sent after the handshake is already buffered.
+	br := brw.Reader
+
+	var b strings.Builder
+	b.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	b.WriteString("Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n")
+	var deflate *deflater
+	if u.Compression != nil {
+		if ext, takeover, ok := negotiateDeflate(r.Header, u.Compression.NoContextTakeover); ok {
+			b.WriteString("Sec-WebSocket-Extensions: " + ext + "\r\n")
+			deflate = newDeflater(*u.Compression, takeover)
+		}
+	}
+	for name, values := range responseHeader {
+		for _, v := range values {
+			b.WriteString(name + ": " + v + "\r\n")
+		}
+	}
+	b.WriteString("\r\n")
+
+	if u.HandshakeTimeout > 0 {
+		netConn.SetWriteDeadline(time.Now().Add(u.HandshakeTimeout))
+	}
+	if _, err := netConn.Write([]byte(b.String())); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	netConn.SetWriteDeadline(time.Time{})
+
+	c := newConn(netConn, br, true)
+	c.subproto = responseHeader.Get("Sec-WebSocket-Protocol")
+	if deflate != nil {
+		// Clients are always asked not to take over their context.
+		c.deflate, c.inflate = deflate, &inflater{}
+	}
+	return c, nil
+}
+
+func (u *Upgrader) fail(w http.ResponseWriter, r *http.Request, status int, reason string) error {
+	code := apierror.CodeInvalidHandshake
+	switch status {
+	case http.StatusForbidden:
+		code = apierror.CodeForbidden
+	case http.StatusInternalServerError:
+		code = apierror.CodeInternal
+	case http.StatusHTTPVersionNotSupported:
+		code = apierror.CodeHTTP11Required
+	}
+	apierror.Write(w, r, apierror.New(status, code, "websocket handshake failed: "+reason))
+	return &HandshakeError{Status: status, Reason: reason}
+}
+
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// Subprotocols returns the subprotocols r offers in Sec-WebSocket-Protocol,
+// in the client's order of preference.
+func Subprotocols(r *http.Request) []string {
+	var protos []string
+	for _, v := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				protos = append(protos, t)
+			}
+		}
+	}
+	return protos
+}
+
+func headerContainsToken(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Dial opens a client connection to a ws:// URL. It exists mainly for tests
+// and tooling; header is sent with the handshake request. If header offers
+// permessage-deflate in Sec-WebSocket-Extensions and the server accepts
+// it, messages are compressed both ways.
+func Dial(rawURL string, header http.Header) (*Conn, *http.Response, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	if u.Scheme != "ws" {
+		return nil, nil, fmt.Errorf("websocket: unsupported scheme %q", u.Scheme)
+	}
+	netConn, err := net.DialTimeout("tcp", u.Host, 10*time.Second)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var nonce [16]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		netConn.Close()
+		return nil, nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce[:])
+
+	req := &http.Request{
+		Method:     http.MethodGet,
+		URL:        u,
+		Host:       u.Host,
+		Header:     http.Header{},
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	if err := req.Write(netConn); err != nil {
+		netConn.Close()
+		return nil, nil, err
+	}
+
+	br := bufio.NewReader(netConn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		netConn.Close()
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		netConn.Close()
+		return nil, resp, errors.New("websocket: bad handshake: " + resp.Status)
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		netConn.Close()
+		return nil, resp, errors.New("websocket: bad Sec-WebSocket-Accept")
+	}
+	deflate, inflate, err := acceptDeflate(resp.Header)
+	if err != nil {
+		netConn.Close()
+		return nil, resp, err
+	}
+	c := newConn(netConn, br, false)
+	c.subproto = resp.Header.Get("Sec-WebSocket-Protocol")
+	c.deflate, c.inflate = deflate, inflate
+	return c, resp, nil
+}
@@ -0,0 +1,158 @@
+package websocket
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func echoServer(t *testing.T, limit int64) *httptest.Server {
+	t.Helper()
+	return upgraderEchoServer(t, &Upgrader{}, limit)
+}
+
+// upgraderEchoServer echoes messages on connections upgraded by up.
+func upgraderEchoServer(t *testing.T, up *Upgrader, limit int64) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := up.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		c.SetReadLimit(limit)
+		for {
+			mt, data, err := c.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := c.WriteMessage(mt, data); err != nil {
+				return
+			}
+		}
+	}))
+}
+
+func wsURL(srv *httptest.Server) string {
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func TestEchoRoundTrip(t *testing.T) {
+	srv := echoServer(t, 0)
+	defer srv.Close()
+	c, _, err := Dial(wsURL(srv), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	big := bytes.Repeat([]byte("x"), 70000) // exercises the 64-bit length form
+	for _, msg := range [][]byte{[]byte("hello"), big} {
+		if err := c.WriteMessage(TextMessage, msg); err != nil {
+			t.Fatal(err)
+		}
+		mt, got, err := c.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mt != TextMessage || !bytes.Equal(got, msg) {
+			t.Fatalf("echo mismatch: type %d len %d", mt, len(got))
+		}
+	}
+}
+
+func TestPingAnsweredWithPong(t *testing.T) {
+	srv := echoServer(t, 0)
+	defer srv.Close()
+	c, _, err := Dial(wsURL(srv), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	got := make(chan string, 1)
+	c.SetPongHandler(func(data string) error { got <- data; return nil })
+	c.WriteControl(PingMessage, []byte("p1"), time.Now().Add(time.Second))
+	c.WriteMessage(TextMessage, []byte("after"))
+	if _, _, err := c.ReadMessage(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case data := <-got:
+		if data != "p1" {
+			t.Errorf("pong payload = %q", data)
+		}
+	default:
+		t.Fatal("no pong received")
+	}
+}
+
+func TestCloseHandshake(t *testing.T) {
+	srv := echoServer(t, 0)
+	defer srv.Close()
+	c, _, err := Dial(wsURL(srv), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.WriteClose(CloseNormalClosure, "bye", time.Now().Add(time.Second))
+	_, _, err = c.ReadMessage()
+	if !IsCloseError(err, CloseNormalClosure) {
+		t.Fatalf("err = %v, want close 1000 echoed", err)
+	}
+}
+
+func TestReadLimit(t *testing.T) {
+	srv := echoServer(t, 16)
+	defer srv.Close()
+	c, _, err := Dial(wsURL(srv), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.WriteMessage(TextMessage, bytes.Repeat([]byte("x"), 17))
+	_, _, err = c.ReadMessage()
+	if !IsCloseError(err, CloseMessageTooBig) {
+		t.Fatalf("err = %v, want close 1009", err)
+	}
+}
+
+func TestRejectsPlainRequest(t *testing.T) {
+	srv := echoServer(t, 0)
+	defer srv.Close()
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestRejectsHTTP2(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Proto, r.ProtoMajor, r.ProtoMinor = "HTTP/2.0", 2, 0
+	rec := httptest.NewRecorder()
+	_, err := (&Upgrader{}).Upgrade(rec, r, nil)
+	if he, ok := err.(*HandshakeError); !ok || he.Status != http.StatusHTTPVersionNotSupported {
+		t.Fatalf("err = %v, want a 505 handshake error", err)
+	}
+	if !strings.Contains(rec.Body.String(), `"http_1_1_required"`) {
+		t.Errorf("body = %s", rec.Body)
+	}
+}
+
+func TestSubprotocols(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Add("Sec-WebSocket-Protocol", "events.v2, events.v1")
+	r.Header.Add("Sec-WebSocket-Protocol", " chat ,")
+	if got := strings.Join(Subprotocols(r), "|"); got != "events.v2|events.v1|chat" {
+		t.Errorf("Subprotocols = %q", got)
+	}
+}
@@ -1,54 +1,682 @@
-// Package main provides a placeholder implementation of the Go API Gateway.
+// Command go-gateway is the Content Factory API gateway. It serves the
+// REST API under /api/v1, streams job progress over WebSocket, SSE and
+// long polling, and forwards requests to the Python content service over
+// gRPC. Requests are authenticated with JWTs, API keys or signatures and
+// rate limited per principal; the gateway also serves its health probes,
+// Prometheus metrics, admin endpoints and its OpenAPI description.
 //
-// This is a minimal implementation to allow the Docker build to succeed.
-// The full implementation will include:
-// - REST API endpoints
-// - WebSocket support for real-time updates
-// - gRPC client for Python services
-// - JWT authentication
-// - Rate limiting
-//
-// See README.md for architecture details and planned features.
+// Configuration comes from the environment and an optional config file,
+// see README.md.
 package main
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/content-factory/go-gateway/internal/admin"
+	"github.com/content-factory/go-gateway/internal/api"
+	"github.com/content-factory/go-gateway/internal/audit"
+	"github.com/content-factory/go-gateway/internal/auth"
+	"github.com/content-factory/go-gateway/internal/backend"
+	"github.com/content-factory/go-gateway/internal/breaker"
+	"github.com/content-factory/go-gateway/internal/buildinfo"
+	"github.com/content-factory/go-gateway/internal/cache"
+	"github.com/content-factory/go-gateway/internal/clientip"
+	"github.com/content-factory/go-gateway/internal/config"
+	"github.com/content-factory/go-gateway/internal/cors"
+	"github.com/content-factory/go-gateway/internal/fairqueue"
+	"github.com/content-factory/go-gateway/internal/flags"
+	"github.com/content-factory/go-gateway/internal/gateway"
+	"github.com/content-factory/go-gateway/internal/grpcpool"
+	"github.com/content-factory/go-gateway/internal/headers"
+	"github.com/content-factory/go-gateway/internal/health"
+	"github.com/content-factory/go-gateway/internal/idempotency"
+	"github.com/content-factory/go-gateway/internal/jobs"
+	"github.com/content-factory/go-gateway/internal/loadshed"
+	"github.com/content-factory/go-gateway/internal/maintenance"
+	"github.com/content-factory/go-gateway/internal/methodlimit"
+	"github.com/content-factory/go-gateway/internal/metrics"
+	"github.com/content-factory/go-gateway/internal/middleware"
+	"github.com/content-factory/go-gateway/internal/openapi"
+	"github.com/content-factory/go-gateway/internal/proxy"
+	"github.com/content-factory/go-gateway/internal/queue"
+	"github.com/content-factory/go-gateway/internal/ratelimit"
+	"github.com/content-factory/go-gateway/internal/realtime"
+	"github.com/content-factory/go-gateway/internal/retry"
+	"github.com/content-factory/go-gateway/internal/router"
+	"github.com/content-factory/go-gateway/internal/signedurl"
+	"github.com/content-factory/go-gateway/internal/tenant"
+	"github.com/content-factory/go-gateway/internal/tlsreload"
+	"github.com/content-factory/go-gateway/internal/tracing"
+	"github.com/content-factory/go-gateway/internal/webhook"
 )
 
+// shuttingDown flips to true once a termination signal arrives so /health
+// starts failing and load balancers stop routing new traffic to us.
+var shuttingDown atomic.Bool
+
+// starting is true while the gateway waits for the backend at startup, so
+// /readyz reports "starting".
+var starting atomic.Bool
+
 func main() {
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	var level slog.LevelVar
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: &level}))
+	slog.SetDefault(logger)
+
+	cfg, err := config.Load()
+	if err != nil {
+		fatal("failed to load configuration", "error", err)
+	}
+	level.Set(cfg.SlogLevel())
+	build := buildinfo.Get()
+	slog.Info("Go API Gateway build", "version", build.Version, "commit", build.Commit,
+		"build_time", build.BuildTime, "go_version", build.GoVersion)
+
+	auditOut, closeAuditLog, err := openLog(cfg.AuditLog.File)
+	if err != nil {
+		fatal("failed to open the audit log", "file", cfg.AuditLog.File, "error", err)
+	}
+	defer closeAuditLog()
+	audit.SetDefault(audit.New(auditOut))
+
+	bearer, err := auth.NewAuthenticator(cfg.AuthConfig())
+	if err != nil {
+		fatal("invalid bearer token configuration", "error", err)
+	}
+	authn := &auth.Middleware{JWT: bearer}
+	if keys := cfg.APIKeys(); len(keys) > 0 {
+		store, err := auth.NewMemoryKeyStore(keys)
+		if err != nil {
+			fatal("invalid API key configuration", "error", err)
+		}
+		authn.Keys = store
+		slog.Info("API key authentication enabled", "keys", len(keys))
+	}
+	if keys := cfg.SigningKeys(); len(keys) > 0 {
+		v, err := auth.NewSignatureVerifier(keys, cfg.Auth.SignatureMaxClockSkew)
+		if err != nil {
+			fatal("invalid signing key configuration", "error", err)
+		}
+		authn.Signatures = v
+		slog.Info("request signature authentication enabled", "keys", len(keys), "max_clock_skew", cfg.Auth.SignatureMaxClockSkew)
+	}
+	switch v := bearer.(type) {
+	case *auth.JWTVerifier:
+		slog.Info("JWT authentication enabled", "algorithm", v.Algorithm)
+	case *auth.OIDCVerifier:
+		// A provider that is down now is tried again on the first token.
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := v.Refresh(ctx); err != nil {
+			slog.Warn("failed to fetch the OIDC key set; retrying on demand", "issuer", v.Issuer, "error", err)
+		}
+		cancel()
+		slog.Info("OIDC authentication enabled", "issuer", v.Issuer, "audience", v.Audience)
+	case nil:
+		if authn.Keys == nil && authn.Signatures == nil {
+			slog.Warn("authentication not configured; protected routes will reject every request",
+				"hint", "set JWT_SECRET, JWT_PUBLIC_KEY_FILE or OIDC_ISSUER, or configure auth.api_keys or auth.signing_keys")
+		}
 	}
 
-	http.HandleFunc("/health", healthHandler)
-	http.HandleFunc("/", rootHandler)
+	tracer, err := tracing.New(cfg.Tracing.Endpoint, cfg.Tracing.ServiceName)
+	if err != nil {
+		fatal("failed to set up tracing", "error", err)
+	}
+	if tracer != nil {
+		slog.Info("tracing enabled", "endpoint", cfg.Tracing.Endpoint, "service", cfg.Tracing.ServiceName)
+	}
+
+	poolCfg := cfg.PoolConfig()
+	poolCfg.Uncapped = backend.IsUncapped
+	poolCfg.StatsHandler = tracer.ClientHandler()
+	pool, err := grpcpool.New(poolCfg)
+	if err != nil {
+		fatal("failed to create backend pool", "error", err)
+	}
+	defer pool.Close()
+	slog.Info("content service backend configured", "addr", poolCfg.Addr, "pool_size", poolCfg.Size, "preferred_zone", poolCfg.PreferredZone)
+
+	limiter := ratelimit.New(cfg.RateLimitConfig())
+	var requestTimeout middleware.TimeoutVar
+	requestTimeout.Set(cfg.RequestTimeout)
+	requestTimeout.SetRoutes(cfg.RouteTable())
+	requestTimeout.SetClientBounds(cfg.ClientTimeout.Min, cfg.ClientTimeout.Max)
+	// Retries sit inside the breaker, so a call that exhausts them counts
+	// as one failure and an open breaker is never retried. Each attempt
+	// gets its own client span. All backends share one retry budget.
+	retryConfig := cfg.RetryConfig()
+	retryConfig.Budget = cfg.RetryBudget()
+	guard := func(name string, pool *grpcpool.Pool) backend.Conn {
+		return breaker.Wrap(
+			retry.Wrap(pool, retryConfig, backend.IsIdempotent),
+			breaker.New(name, cfg.BreakerConfig()))
+	}
+	contentConn := guard("content_service", pool)
+	// Requests acting for no tenant pass through untouched while the
+	// gateway has none.
+	tenancy := func(next http.Handler) http.Handler { return next }
+	if len(cfg.Tenants) > 0 {
+		tenants := tenant.New(cfg.TenantList(), cfg.DefaultTenant)
+		tenancy = tenants.Middleware
+		// Tenants with a content service of their own get their own pool
+		// and breaker, so one tenant's outage cannot open the others'.
+		routed := &tenant.Backends{Default: contentConn, Tenants: make(map[string]tenant.Conn)}
+		for _, t := range cfg.Tenants {
+			if t.BackendAddr == "" {
+				continue
+			}
+			tenantCfg := cfg.TenantPoolConfig(t)
+			tenantCfg.Uncapped = backend.IsUncapped
+			tenantCfg.StatsHandler = tracer.ClientHandler()
+			tenantPool, err := grpcpool.New(tenantCfg)
+			if err != nil {
+				fatal("failed to create tenant backend pool", "tenant", t.ID, "error", err)
+			}
+			defer tenantPool.Close()
+			routed.Tenants[t.ID] = guard("content_service:"+t.ID, tenantPool)
+			slog.Info("tenant content service configured", "tenant", t.ID, "addr", tenantCfg.Addr)
+		}
+		contentConn = routed
+		slog.Info("multi-tenancy enabled", "tenants", len(cfg.Tenants), "default_tenant", cfg.DefaultTenant)
+	}
+	// The fair scheduler sits outside the breakers, so calls it queues or
+	// refuses never count against the content service.
+	if fq := cfg.FairQueueConfig(); fq.MaxInFlight > 0 {
+		contentConn = fairqueue.Wrap(contentConn, fairqueue.New(fq))
+		slog.Info("fair scheduling of backend calls enabled", "max_in_flight", fq.MaxInFlight, "max_queued_per_principal", fq.MaxQueued)
+	}
+	// Method limits come first, so a call waiting for a slot of its method
+	// holds none of the fair scheduler's meanwhile.
+	if limits := cfg.MethodLimits(); limits != nil {
+		contentConn = methodlimit.Wrap(contentConn, methodlimit.New(limits))
+		slog.Info("backend method concurrency limits enabled", "methods", len(limits))
+	}
+	content := backend.New(contentConn)
+
+	backendProbe := health.GRPCProbe(pool, "")
+	starting.Store(cfg.Startup.WaitForBackend)
+	readiness := &health.Checker{Draining: shuttingDown.Load, Starting: starting.Load}
+	readiness.AddCached("content_service", backendProbe, health.DefaultCacheTTL)
+	if cfg.RedisURL != "" {
+		readiness.Add("redis", health.DialProbe(cfg.RedisURL))
+	}
 
-	log.Printf("Go API Gateway starting on port %s (placeholder implementation)", port)
-	log.Printf("This is a placeholder. Full implementation pending.")
+	// Route chains, outermost first. Probes, /version, the API docs and
+	// metrics use none, so monitoring always gets through; the streaming
+	// routes take the token from the query string and get no timeout,
+	// from the middleware or the server.
+	protected := middleware.NewChain(authn.Require, tenancy, limiter.Middleware)
+	streaming := middleware.NewChain(authn.RequireQuery, tenancy, limiter.Middleware, middleware.LongLived)
+	operator := middleware.NewChain(authn.Require, auth.RequireScope(admin.Scope),
+		limiter.Middleware, requestTimeout.Middleware)
 
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	rt := router.New()
+	rt.HandleFunc(http.MethodGet, "/health", healthHandler(pool))
+	rt.HandleFunc(http.MethodGet, "/livez", livezHandler)
+	rt.Handle(http.MethodGet, "/version", buildinfo.Handler())
+	rt.Handle(http.MethodGet, "/readyz", readiness)
+	rt.Handle(http.MethodGet, middleware.MetricsPath, metrics.Handler())
+	// Every submitted job is followed with one backend progress stream onto
+	// the event bus, whoever watches it. The audit log, job metrics,
+	// webhooks and the clients of the realtime hub read it, each from a
+	// queue of its own. The streams are closed on shutdown.
+	feedCtx, stopFeed := context.WithCancel(context.Background())
+	defer stopFeed()
+	jobFeed := jobs.NewFeed(feedCtx, &jobs.BackendSource{Client: content}, jobs.NewBus())
+	go audit.RecordJobEvents(jobFeed.Bus().Subscribe("audit", cfg.Realtime.EventBusBuffer, jobs.DropOldest).Events())
+	go jobs.CountEvents(jobFeed.Bus().Subscribe("metrics", cfg.Realtime.EventBusBuffer, jobs.DropOldest).Events())
+	progress := realtime.NewHub(jobFeed)
+	progress.ClientBuffer = cfg.Realtime.ClientBuffer
+	progress.Overflow = realtime.OverflowPolicy(cfg.Realtime.Overflow)
+	progress.Connections().MaxPerIP = cfg.Realtime.MaxPerIP
+	progress.Connections().MaxPerPrincipal = cfg.Realtime.MaxPerPrincipal
+	// Only a job's owner may follow it, over any transport.
+	owner := jobOwner(content)
+	wsHandler := realtime.NewWebSocketHandler(progress)
+	wsHandler.Owner = owner
+	wsHandler.Upgrader.Compression = cfg.WebSocketCompression()
+	wsHandler.MaxMessageBytes = cfg.Realtime.MaxMessageBytes
+	if bearer != nil {
+		// Clients refresh expiring tokens in-band; API keys do not expire.
+		wsHandler.Verify = func(token string) (*gateway.Claims, error) {
+			return bearer.Authenticate(context.Background(), token)
+		}
+		wsHandler.AuthGrace = cfg.Realtime.AuthGrace
 	}
+	rt.Handle(http.MethodGet, "/ws/jobs/{id:uuid}", streaming.Then(wsHandler))
+	sseHandler := realtime.NewSSEHandler(progress)
+	sseHandler.Owner = owner
+	rt.Handle(http.MethodGet, "/api/v1/jobs/{id:uuid}/events", streaming.Then(sseHandler))
+	poll := realtime.NewPollHandler(progress)
+	poll.Owner = owner
+	poll.Timeout = cfg.Realtime.PollTimeout
+	rt.Handle(http.MethodGet, "/api/v1/jobs/{id:uuid}/poll", streaming.Then(poll))
+	// Operators list and close those streams through the hub's registry,
+	// and read runtime figures including them.
+	// Maintenance mode is switched through them too, and is not touched by
+	// SIGHUP reloads.
+	maintenanceMode := maintenance.New(cfg.Maintenance.Enabled, cfg.Maintenance.Message, cfg.Maintenance.RetryAfter)
+	adminServer := &admin.Server{
+		Connections:        progress.Connections(),
+		Pool:               pool,
+		Profiling:          cfg.EnablePprof,
+		Maintenance:        maintenanceMode,
+		DrainOnMaintenance: cfg.Maintenance.DrainStreams,
+		RateLimits:         limiter,
+	}
+	adminServer.Register(rt, operator.Then)
+	// Profiles run for as long as they are asked to, so they get neither
+	// the request timeout nor the rate limit.
+	adminServer.RegisterProfiling(rt, middleware.NewChain(authn.Require, auth.RequireScope(admin.Scope), middleware.LongLived).Then)
+	if cfg.EnablePprof {
+		slog.Info("pprof enabled", "path", "/debug/pprof/")
+	}
+	if cfg.Maintenance.Enabled {
+		slog.Warn("starting in maintenance mode", "message", maintenanceMode.Status().Message)
+	}
+	rt.Handle(http.MethodGet, "/", middleware.NewChain(limiter.Middleware, requestTimeout.Middleware).
+		ThenFunc(rootHandler))
+
+	idempotencyKeys := idempotency.NewMemoryStore()
+	featureFlags := flags.New(cfg.Flags())
+	apiServer := &api.Server{
+		Backend:           content,
+		RequestTimeoutVar: &requestTimeout,
+		MaxBodyBytes:      cfg.API.MaxBodyBytes,
+		MaxUploadBytes:    cfg.API.MaxUploadBytes,
+		Routes:            cfg.RouteTable(),
+		MaxAssetBytes:     cfg.API.MaxAssetBytes,
+		AssetTypes:        cfg.API.AssetTypes,
+		StrictJSON:        cfg.API.StrictJSON,
+		Idempotency:       idempotencyKeys,
+		IdempotencyTTL:    cfg.API.IdempotencyTTL,
+		DefaultPageSize:   cfg.API.DefaultPageSize,
+		MaxPageSize:       cfg.API.MaxPageSize,
+		MaxBatchItems:     cfg.API.MaxBatchItems,
+		BatchConcurrency:  cfg.API.BatchConcurrency,
+		MaxStatusIDs:      cfg.API.MaxStatusIDs,
+		CacheTTL:          cfg.Cache.TTL,
+		Flags:             featureFlags,
+		Jobs:              jobFeed,
+	}
+	if cfg.Cache.MaxEntries > 0 {
+		apiServer.Cache = cache.NewLRU(cfg.Cache.MaxEntries)
+	}
+	if signCfg, ok := cfg.SignedURLConfig(); ok {
+		apiServer.SignedURLs = signedurl.New(signCfg)
+		slog.Info("signed download URLs enabled", "ttl", signCfg.TTL, "base_url", signCfg.BaseURL)
+	}
+	// Public content is read by anyone, so it has a limiter of its own,
+	// keyed by client IP, and is not held to the limits of signed-in users.
+	var publicLimiter *ratelimit.Limiter
+	if cfg.PublicContent.Enabled {
+		publicLimiter = ratelimit.New(cfg.PublicContentRateLimitConfig())
+		apiServer.PublicContent = publicLimiter.Middleware
+		slog.Info("public content enabled", "rps", cfg.PublicContent.RPS, "burst", cfg.PublicContent.Burst,
+			"cors_allowed_origins", cfg.PublicContent.AllowedOrigins)
+	}
+	var webhooks *webhook.Dispatcher
+	if secrets := cfg.WebhookSecrets(); secrets != nil {
+		// Watched jobs share the progress streams of every job.
+		webhooks = &webhook.Dispatcher{
+			Jobs:           jobFeed,
+			Secrets:        secrets,
+			MaxAttempts:    cfg.Webhooks.MaxAttempts,
+			InitialBackoff: cfg.Webhooks.InitialBackoff,
+			MaxBackoff:     cfg.Webhooks.MaxBackoff,
+			Timeout:        cfg.Webhooks.Timeout,
+			AllowPrivate:   cfg.Webhooks.AllowPrivate,
+		}
+		apiServer.Webhooks = webhooks
+		slog.Info("webhooks enabled", "clients", len(secrets), "allow_private_networks", cfg.Webhooks.AllowPrivate)
+	}
+	var submissions *queue.Workers
+	switch cfg.Queue.Backend {
+	case queue.BackendMemory:
+		apiServer.Queue = queue.NewMemory(cfg.Queue.Capacity)
+	case queue.BackendRedis:
+		q, err := queue.NewRedis(cfg.RedisURL, cfg.Queue.RedisKey, cfg.Queue.Capacity)
+		if err != nil {
+			fatal("failed to configure the submission queue", "error", err)
+		}
+		defer q.Close()
+		apiServer.Queue = q
+	}
+	if apiServer.Queue != nil {
+		apiServer.QueueThroughput = &queue.Throughput{}
+		submissions = &queue.Workers{
+			Queue:       apiServer.Queue,
+			Submit:      followSubmitted(content.CreateContent, jobFeed),
+			Concurrency: cfg.Queue.Workers,
+			Throughput:  apiServer.QueueThroughput,
+		}
+		submissions.Start()
+		slog.Info("content submissions queued", "backend", cfg.Queue.Backend, "capacity", cfg.Queue.Capacity, "workers", cfg.Queue.Workers)
+	}
+	// A saturated queue only stops submissions: the gateway stays ready,
+	// degraded, and keeps serving reads.
+	if apiServer.Queue != nil {
+		readiness.AddSource("queue", queue.SaturationProbe(apiServer.Queue, cfg.Queue.Capacity, cfg.Queue.HighWater), time.Second)
+		readiness.AddCapability(api.CapabilitySubmission, "content_service", "queue")
+	} else {
+		readiness.AddCapability(api.CapabilitySubmission, "content_service")
+	}
+	readiness.AddCapability(api.CapabilityReads, "content_service")
+	apiServer.Capabilities = readiness
+	apiServer.Register(rt, protected.Then)
+	// Signed URLs are their own credentials; their downloads are rate
+	// limited by client IP, as public content is by its own limiter.
+	apiServer.RegisterPublic(rt, limiter.Middleware)
+
+	// The contract is generated from the routes registered above.
+	spec := openapi.New("Content Factory API Gateway", build.Version)
+	spec.Add(http.MethodGet, "/version", openapi.Operation{
+		Summary:   "Build metadata of the running gateway",
+		Public:    true,
+		Responses: []openapi.Response{{Status: http.StatusOK, Description: "Build metadata.", Body: openapi.JSON(buildinfo.Info{})}},
+	})
+	apiServer.Describe(spec)
+	adminServer.Describe(spec)
+	rt.Handle(http.MethodGet, "/openapi.json", spec.Handler())
+	rt.Handle(http.MethodGet, "/docs", openapi.DocsHandler("Content Factory API Gateway", "/openapi.json"))
+	if cfg.Legacy.URL != "" {
+		// Validated by config.Load.
+		target, _ := url.Parse(cfg.Legacy.URL)
+		// Its headers are rewritten after authentication, which may need
+		// the Authorization the backend must not see.
+		legacyHeaders, _ := headers.New(cfg.LegacyHeaderRules())
+		proxy.Register(rt, cfg.Legacy.Prefix, proxy.New(target, cfg.Legacy.Prefix, cfg.Legacy.MaxResponseBytes),
+			protected.Append(middleware.LongLived, legacyHeaders.Middleware).Then)
+		slog.Info("legacy HTTP backend configured", "prefix", cfg.Legacy.Prefix, "url", cfg.Legacy.URL)
+	}
+
+	shedder := loadshed.New(cfg.LoadShed.MaxInFlight)
+	corsPolicy := cors.New(cfg.CORSConfig())
+	corsMiddleware := corsPolicy.Middleware
+	if cfg.PublicContent.Enabled {
+		// Public content embeds in other sites, under a policy of its own.
+		corsMiddleware = cors.Prefixed(corsPolicy, api.PublicContentPrefix, cors.New(cfg.PublicContentCORSConfig()))
+	}
+	// Validated by config.Load.
+	clientIPs, _ := clientip.New(cfg.TrustedProxyRanges())
+	accessLog, closeAccessLog, err := accessLogger(cfg, &level)
+	if err != nil {
+		fatal("failed to open the access log", "file", cfg.AccessLog.File, "error", err)
+	}
+	defer closeAccessLog()
+	// Validated by config.Load.
+	headerRules, _ := headers.New(cfg.HeaderRules())
+	global := middleware.NewChain(
+		middleware.RequestID,
+		clientIPs.Middleware,
+		// Request rules apply once the request ID and client IP are known;
+		// response rules see the headers of everything inside, but cannot
+		// touch those of CORS and compression.
+		headerRules.Middleware,
+		featureFlags.Middleware,
+		tracer.Middleware,
+		accessLog,
+		middleware.SlowRequests(logger, cfg.SlowRequestThreshold, cfg.RouteTable()),
+		middleware.Metrics,
+		shedder.Middleware,
+		middleware.MaxBytes(cfg.MaxRequestBytes),
+		middleware.Compress(cfg.Compress.MinSize),
+		corsMiddleware,
+		maintenanceMode.Middleware,
+		middleware.Recover(logger),
+	)
+	conns := &connTracker{}
+	srv := &http.Server{
+		Addr:      ":" + strconv.Itoa(cfg.Port),
+		Handler:   global.Then(rt),
+		ConnState: conns.track,
+		Protocols: cfg.ServerProtocols(),
+	}
+	cfg.ServerTimeouts().Apply(srv)
+	grace := cfg.ShutdownTimeout
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go limiter.Sweep(ctx, time.Minute)
+	if publicLimiter != nil {
+		go publicLimiter.Sweep(ctx, time.Minute)
+	}
+	go idempotencyKeys.Sweep(ctx, time.Minute)
+	go reloadOnHangup(ctx, cfg, liveSettings{
+		level:   &level,
+		timeout: &requestTimeout,
+		limiter: limiter,
+		cors:    corsPolicy,
+		shedder: shedder,
+		proxies: clientIPs,
+		flags:   featureFlags,
+	})
+
+	if cfg.TLS.Enabled() {
+		certs, err := tlsreload.New(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		if err != nil {
+			fatal("failed to load TLS certificate", "error", err)
+		}
+		srv.TLSConfig = certs.TLSConfig()
+		go certs.Watch(ctx, cfg.TLS.ReloadInterval)
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		protocols := protocolNames(srv.Protocols)
+		if srv.TLSConfig != nil {
+			slog.Info("Go API Gateway starting", "port", cfg.Port, "tls", true, "protocols", protocols)
+			// The certificate comes from TLSConfig.GetCertificate.
+			serveErr <- srv.ListenAndServeTLS("", "")
+			return
+		}
+		slog.Info("Go API Gateway starting", "port", cfg.Port, "tls", false, "protocols", protocols)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	if cfg.Startup.WaitForBackend {
+		go waitForBackend(ctx, cfg, backendProbe)
+	}
+
+	select {
+	case err := <-serveErr:
+		fatal("failed to start server", "error", err)
+	case <-ctx.Done():
+	}
+	stop()
+
+	shuttingDown.Store(true)
+	active := conns.active()
+	slog.Info("shutdown signal received, draining connections", "connections", active, "grace_period", grace)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	// srv.Shutdown does not see hijacked WebSocket connections and would
+	// wait out the grace period on SSE streams, so the realtime streams
+	// are told to go away alongside it. New streams are refused from here.
+	streamsDrained := make(chan struct{})
+	go func() {
+		defer close(streamsDrained)
+		streams, err := progress.Connections().Drain(shutdownCtx, "server shutting down")
+		if err != nil {
+			slog.Warn("realtime streams still open after the grace period", "streams", streams, "open", progress.Connections().Len())
+			return
+		}
+		slog.Info("realtime streams drained", "streams", streams)
+	}()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		remaining := conns.active()
+		fatal("grace period exceeded", "drained", active-remaining, "connections", active, "error", err)
+	}
+	if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fatal("server error during shutdown", "error", err)
+	}
+	<-streamsDrained
+	if submissions != nil {
+		// A memory queue dies with the process, so it is emptied first; a
+		// Redis queue keeps its jobs for the next gateway to take.
+		drain := cfg.Queue.Backend == queue.BackendMemory
+		if err := submissions.Shutdown(shutdownCtx, drain); err != nil {
+			n, _ := apiServer.Queue.Len(context.Background())
+			slog.Warn("submission queue not drained within the grace period", "queued", n)
+		}
+	}
+	stopFeed()
+	if webhooks != nil {
+		if err := webhooks.Shutdown(shutdownCtx); err != nil {
+			slog.Warn("webhook deliveries still running after the grace period", "error", err)
+		}
+	}
+	if err := tracer.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("failed to flush spans", "error", err)
+	}
+	slog.Info("shutdown complete", "drained", active)
+}
+
+// accessLogger returns the middleware writing the request log cfg asks
+// for, and a function closing its file. Without a level of its own it
+// follows level, the application log's.
+func accessLogger(cfg *config.Config, level *slog.LevelVar) (func(http.Handler) http.Handler, func() error, error) {
+	w, closeFile, err := openLog(cfg.AccessLog.File)
+	if err != nil {
+		return nil, nil, err
+	}
+	var leveler slog.Leveler = level
+	if own, ok := cfg.AccessLogLevel(); ok {
+		leveler = own
+	}
+	if cfg.AccessLog.Format == middleware.AccessLogCombined {
+		return middleware.CombinedLogger(w, leveler), closeFile, nil
+	}
+	return middleware.Logger(slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: leveler}))), closeFile, nil
+}
+
+// openLog opens the log file at path for appending, or returns standard
+// output if path is empty.
+func openLog(path string) (io.Writer, func() error, error) {
+	if path == "" {
+		return os.Stdout, func() error { return nil }, nil
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, f.Close, nil
+}
+
+// protocolNames lists p by ALPN name, h2c for HTTP/2 without TLS.
+func protocolNames(p *http.Protocols) []string {
+	var names []string
+	if p.HTTP1() {
+		names = append(names, "http/1.1")
+	}
+	if p.HTTP2() {
+		names = append(names, "h2")
+	}
+	if p.UnencryptedHTTP2() {
+		names = append(names, "h2c")
+	}
+	return names
+}
+
+// jobOwner looks up the owner of a job for the realtime handlers.
+func jobOwner(content *backend.Client) realtime.OwnerFunc {
+	return func(ctx context.Context, jobID string) (string, error) {
+		status, err := content.GetJobStatus(ctx, jobID)
+		if err != nil {
+			return "", err
+		}
+		return status.OwnerID, nil
+	}
+}
+
+// followSubmitted wraps the queue workers' submit, having feed follow
+// each job the content service accepts.
+func followSubmitted(submit func(context.Context, *backend.CreateContentRequest) (*backend.CreateContentResponse, error), feed *jobs.Feed) func(context.Context, *backend.CreateContentRequest) (*backend.CreateContentResponse, error) {
+	return func(ctx context.Context, req *backend.CreateContentRequest) (*backend.CreateContentResponse, error) {
+		resp, err := submit(ctx, req)
+		if err == nil {
+			feed.Start(ctx, req.JobID)
+		}
+		return resp, err
+	}
+}
+
+// fatal logs msg at error level and exits non-zero.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// waitForBackend checks the backend until it is up, then lets readiness
+// follow the checks. If it stays down past the startup wait, the gateway
+// exits or carries on degraded, as configured.
+func waitForBackend(ctx context.Context, cfg *config.Config, probe health.Probe) {
+	wait := cfg.StartupWaitConfig()
+	slog.Info("waiting for the content service", "max_wait", wait.MaxWait)
+	err := health.Wait(ctx, "content_service", probe, wait)
+	switch {
+	case ctx.Err() != nil:
+		return
+	case err != nil && cfg.Startup.OnTimeout == config.StartupExit:
+		fatal("content service unavailable at startup", "error", err)
+	case err != nil:
+		slog.Warn("content service unavailable at startup, serving degraded", "error", err)
+	}
+	starting.Store(false)
 }
 
-func healthHandler(w http.ResponseWriter, r *http.Request) {
+// healthHandler reports process health along with the state of each
+// backend connection. Backend trouble is reported but does not fail the
+// check.
+func healthHandler(pool *grpcpool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if shuttingDown.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(map[string]string{
+				"status":  "shutting_down",
+				"service": "go-gateway",
+			})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"status":  "healthy",
+			"service": "go-gateway",
+			"backend": map[string]any{
+				"endpoints": pool.Endpoints(),
+			},
+		})
+	}
+}
+
+// livezHandler is the liveness probe: if the process can answer, it is
+// alive.
+func livezHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"status":  "healthy",
-		"service": "go-gateway",
-		"note":    "placeholder implementation",
-	})
+	json.NewEncoder(w).Encode(map[string]string{"status": "alive"})
 }
 
+// rootHandler names the service and points at its API description.
 func rootHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
 		"service": "Content Factory Go API Gateway",
-		"status":  "placeholder",
-		"message": "Full implementation pending. See go-gateway/README.md for details.",
+		"status":  "ok",
+		"version": buildinfo.Get().Version,
+		"docs":    "/docs",
+		"openapi": "/openapi.json",
 	})
 }
@@ -0,0 +1,2904 @@
+// Content Factory Service Definitions
+//
+// gRPC Protocol Buffer definitions for communication between
+// Go API Gateway and Python AI Services.
+//
+// Architecture: Go Gateway -> gRPC -> Python Services -> External AI APIs
+//
+// Requires protobuf >= 3.15 for proto3 optional field support.
+// Generate Python code with:
+//   python -m grpc_tools.protoc -I. --python_out=. --grpc_python_out=. protos/content_factory.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: content_factory.proto
+
+package content_factory
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Empty struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Empty) Reset() {
+	*x = Empty{}
+	mi := &file_content_factory_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Empty) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Empty) ProtoMessage() {}
+
+func (x *Empty) ProtoReflect() protoreflect.Message {
+	mi := &file_content_factory_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Empty.ProtoReflect.Descriptor instead.
+func (*Empty) Descriptor() ([]byte, []int) {
+	return file_content_factory_proto_rawDescGZIP(), []int{0}
+}
+
+type VideoRenderRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	BriefId string                 `protobuf:"bytes,1,opt,name=brief_id,json=briefId,proto3" json:"brief_id,omitempty"`
+	Prompt  string                 `protobuf:"bytes,2,opt,name=prompt,proto3" json:"prompt,omitempty"`
+	Style   string                 `protobuf:"bytes,3,opt,name=style,proto3" json:"style,omitempty"`
+	// Video parameters
+	DurationSeconds int32 `protobuf:"varint,4,opt,name=duration_seconds,json=durationSeconds,proto3" json:"duration_seconds,omitempty"`
+	Width           int32 `protobuf:"varint,5,opt,name=width,proto3" json:"width,omitempty"`
+	Height          int32 `protobuf:"varint,6,opt,name=height,proto3" json:"height,omitempty"`
+	// Brand alignment
+	BrandAesthetic  string   `protobuf:"bytes,7,opt,name=brand_aesthetic,json=brandAesthetic,proto3" json:"brand_aesthetic,omitempty"`
+	TargetPlatforms []string `protobuf:"bytes,8,rep,name=target_platforms,json=targetPlatforms,proto3" json:"target_platforms,omitempty"`
+	// Music parameters for the video
+	MusicParams   *MusicParameters `protobuf:"bytes,9,opt,name=music_params,json=musicParams,proto3" json:"music_params,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VideoRenderRequest) Reset() {
+	*x = VideoRenderRequest{}
+	mi := &file_content_factory_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VideoRenderRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VideoRenderRequest) ProtoMessage() {}
+
+func (x *VideoRenderRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_content_factory_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VideoRenderRequest.ProtoReflect.Descriptor instead.
+func (*VideoRenderRequest) Descriptor() ([]byte, []int) {
+	return file_content_factory_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *VideoRenderRequest) GetBriefId() string {
+	if x != nil {
+		return x.BriefId
+	}
+	return ""
+}
+
+func (x *VideoRenderRequest) GetPrompt() string {
+	if x != nil {
+		return x.Prompt
+	}
+	return ""
+}
+
+func (x *VideoRenderRequest) GetStyle() string {
+	if x != nil {
+		return x.Style
+	}
+	return ""
+}
+
+func (x *VideoRenderRequest) GetDurationSeconds() int32 {
+	if x != nil {
+		return x.DurationSeconds
+	}
+	return 0
+}
+
+func (x *VideoRenderRequest) GetWidth() int32 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+func (x *VideoRenderRequest) GetHeight() int32 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *VideoRenderRequest) GetBrandAesthetic() string {
+	if x != nil {
+		return x.BrandAesthetic
+	}
+	return ""
+}
+
+func (x *VideoRenderRequest) GetTargetPlatforms() []string {
+	if x != nil {
+		return x.TargetPlatforms
+	}
+	return nil
+}
+
+func (x *VideoRenderRequest) GetMusicParams() *MusicParameters {
+	if x != nil {
+		return x.MusicParams
+	}
+	return nil
+}
+
+type VideoRenderResponse struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	JobId            string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	Status           string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"` // queued, processing, completed, failed
+	VideoUrl         string                 `protobuf:"bytes,3,opt,name=video_url,json=videoUrl,proto3" json:"video_url,omitempty"`
+	ThumbnailUrl     string                 `protobuf:"bytes,4,opt,name=thumbnail_url,json=thumbnailUrl,proto3" json:"thumbnail_url,omitempty"`
+	QualityScore     float32                `protobuf:"fixed32,5,opt,name=quality_score,json=qualityScore,proto3" json:"quality_score,omitempty"`
+	ProcessingTimeMs int32                  `protobuf:"varint,6,opt,name=processing_time_ms,json=processingTimeMs,proto3" json:"processing_time_ms,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *VideoRenderResponse) Reset() {
+	*x = VideoRenderResponse{}
+	mi := &file_content_factory_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VideoRenderResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VideoRenderResponse) ProtoMessage() {}
+
+func (x *VideoRenderResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_content_factory_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VideoRenderResponse.ProtoReflect.Descriptor instead.
+func (*VideoRenderResponse) Descriptor() ([]byte, []int) {
+	return file_content_factory_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *VideoRenderResponse) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *VideoRenderResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *VideoRenderResponse) GetVideoUrl() string {
+	if x != nil {
+		return x.VideoUrl
+	}
+	return ""
+}
+
+func (x *VideoRenderResponse) GetThumbnailUrl() string {
+	if x != nil {
+		return x.ThumbnailUrl
+	}
+	return ""
+}
+
+func (x *VideoRenderResponse) GetQualityScore() float32 {
+	if x != nil {
+		return x.QualityScore
+	}
+	return 0
+}
+
+func (x *VideoRenderResponse) GetProcessingTimeMs() int32 {
+	if x != nil {
+		return x.ProcessingTimeMs
+	}
+	return 0
+}
+
+type MusicParameters struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tempo         float32                `protobuf:"fixed32,1,opt,name=tempo,proto3" json:"tempo,omitempty"`
+	Key           string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	Mood          string                 `protobuf:"bytes,3,opt,name=mood,proto3" json:"mood,omitempty"`
+	Energy        float32                `protobuf:"fixed32,4,opt,name=energy,proto3" json:"energy,omitempty"`
+	Danceability  float32                `protobuf:"fixed32,5,opt,name=danceability,proto3" json:"danceability,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MusicParameters) Reset() {
+	*x = MusicParameters{}
+	mi := &file_content_factory_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MusicParameters) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MusicParameters) ProtoMessage() {}
+
+func (x *MusicParameters) ProtoReflect() protoreflect.Message {
+	mi := &file_content_factory_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MusicParameters.ProtoReflect.Descriptor instead.
+func (*MusicParameters) Descriptor() ([]byte, []int) {
+	return file_content_factory_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *MusicParameters) GetTempo() float32 {
+	if x != nil {
+		return x.Tempo
+	}
+	return 0
+}
+
+func (x *MusicParameters) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *MusicParameters) GetMood() string {
+	if x != nil {
+		return x.Mood
+	}
+	return ""
+}
+
+func (x *MusicParameters) GetEnergy() float32 {
+	if x != nil {
+		return x.Energy
+	}
+	return 0
+}
+
+func (x *MusicParameters) GetDanceability() float32 {
+	if x != nil {
+		return x.Danceability
+	}
+	return 0
+}
+
+type MusicGenerationRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	BriefId         string                 `protobuf:"bytes,1,opt,name=brief_id,json=briefId,proto3" json:"brief_id,omitempty"`
+	Params          *MusicParameters       `protobuf:"bytes,2,opt,name=params,proto3" json:"params,omitempty"`
+	DurationSeconds int32                  `protobuf:"varint,3,opt,name=duration_seconds,json=durationSeconds,proto3" json:"duration_seconds,omitempty"`
+	OutputFormat    string                 `protobuf:"bytes,4,opt,name=output_format,json=outputFormat,proto3" json:"output_format,omitempty"` // wav, mp3, etc.
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *MusicGenerationRequest) Reset() {
+	*x = MusicGenerationRequest{}
+	mi := &file_content_factory_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MusicGenerationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MusicGenerationRequest) ProtoMessage() {}
+
+func (x *MusicGenerationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_content_factory_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MusicGenerationRequest.ProtoReflect.Descriptor instead.
+func (*MusicGenerationRequest) Descriptor() ([]byte, []int) {
+	return file_content_factory_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *MusicGenerationRequest) GetBriefId() string {
+	if x != nil {
+		return x.BriefId
+	}
+	return ""
+}
+
+func (x *MusicGenerationRequest) GetParams() *MusicParameters {
+	if x != nil {
+		return x.Params
+	}
+	return nil
+}
+
+func (x *MusicGenerationRequest) GetDurationSeconds() int32 {
+	if x != nil {
+		return x.DurationSeconds
+	}
+	return 0
+}
+
+func (x *MusicGenerationRequest) GetOutputFormat() string {
+	if x != nil {
+		return x.OutputFormat
+	}
+	return ""
+}
+
+type MusicGenerationResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	Status        string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	AudioUrl      string                 `protobuf:"bytes,3,opt,name=audio_url,json=audioUrl,proto3" json:"audio_url,omitempty"`
+	QualityScore  float32                `protobuf:"fixed32,4,opt,name=quality_score,json=qualityScore,proto3" json:"quality_score,omitempty"`
+	DurationMs    int32                  `protobuf:"varint,5,opt,name=duration_ms,json=durationMs,proto3" json:"duration_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MusicGenerationResponse) Reset() {
+	*x = MusicGenerationResponse{}
+	mi := &file_content_factory_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MusicGenerationResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MusicGenerationResponse) ProtoMessage() {}
+
+func (x *MusicGenerationResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_content_factory_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MusicGenerationResponse.ProtoReflect.Descriptor instead.
+func (*MusicGenerationResponse) Descriptor() ([]byte, []int) {
+	return file_content_factory_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *MusicGenerationResponse) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *MusicGenerationResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *MusicGenerationResponse) GetAudioUrl() string {
+	if x != nil {
+		return x.AudioUrl
+	}
+	return ""
+}
+
+func (x *MusicGenerationResponse) GetQualityScore() float32 {
+	if x != nil {
+		return x.QualityScore
+	}
+	return 0
+}
+
+func (x *MusicGenerationResponse) GetDurationMs() int32 {
+	if x != nil {
+		return x.DurationMs
+	}
+	return 0
+}
+
+type VoiceoverRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Text          string                 `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`
+	Voice         string                 `protobuf:"bytes,2,opt,name=voice,proto3" json:"voice,omitempty"`
+	Model         string                 `protobuf:"bytes,3,opt,name=model,proto3" json:"model,omitempty"`
+	Speed         float32                `protobuf:"fixed32,4,opt,name=speed,proto3" json:"speed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VoiceoverRequest) Reset() {
+	*x = VoiceoverRequest{}
+	mi := &file_content_factory_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VoiceoverRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VoiceoverRequest) ProtoMessage() {}
+
+func (x *VoiceoverRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_content_factory_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VoiceoverRequest.ProtoReflect.Descriptor instead.
+func (*VoiceoverRequest) Descriptor() ([]byte, []int) {
+	return file_content_factory_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *VoiceoverRequest) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *VoiceoverRequest) GetVoice() string {
+	if x != nil {
+		return x.Voice
+	}
+	return ""
+}
+
+func (x *VoiceoverRequest) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *VoiceoverRequest) GetSpeed() float32 {
+	if x != nil {
+		return x.Speed
+	}
+	return 0
+}
+
+type VoiceoverResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	Status        string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	AudioUrl      string                 `protobuf:"bytes,3,opt,name=audio_url,json=audioUrl,proto3" json:"audio_url,omitempty"`
+	DurationMs    int32                  `protobuf:"varint,4,opt,name=duration_ms,json=durationMs,proto3" json:"duration_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VoiceoverResponse) Reset() {
+	*x = VoiceoverResponse{}
+	mi := &file_content_factory_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VoiceoverResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VoiceoverResponse) ProtoMessage() {}
+
+func (x *VoiceoverResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_content_factory_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VoiceoverResponse.ProtoReflect.Descriptor instead.
+func (*VoiceoverResponse) Descriptor() ([]byte, []int) {
+	return file_content_factory_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *VoiceoverResponse) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *VoiceoverResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *VoiceoverResponse) GetAudioUrl() string {
+	if x != nil {
+		return x.AudioUrl
+	}
+	return ""
+}
+
+func (x *VoiceoverResponse) GetDurationMs() int32 {
+	if x != nil {
+		return x.DurationMs
+	}
+	return 0
+}
+
+type AvatarGenerationRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	AudioUrl        string                 `protobuf:"bytes,1,opt,name=audio_url,json=audioUrl,proto3" json:"audio_url,omitempty"`
+	ImageUrl        string                 `protobuf:"bytes,2,opt,name=image_url,json=imageUrl,proto3" json:"image_url,omitempty"`
+	AvatarStyle     string                 `protobuf:"bytes,3,opt,name=avatar_style,json=avatarStyle,proto3" json:"avatar_style,omitempty"`
+	IncludeGestures bool                   `protobuf:"varint,4,opt,name=include_gestures,json=includeGestures,proto3" json:"include_gestures,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *AvatarGenerationRequest) Reset() {
+	*x = AvatarGenerationRequest{}
+	mi := &file_content_factory_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AvatarGenerationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AvatarGenerationRequest) ProtoMessage() {}
+
+func (x *AvatarGenerationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_content_factory_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AvatarGenerationRequest.ProtoReflect.Descriptor instead.
+func (*AvatarGenerationRequest) Descriptor() ([]byte, []int) {
+	return file_content_factory_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *AvatarGenerationRequest) GetAudioUrl() string {
+	if x != nil {
+		return x.AudioUrl
+	}
+	return ""
+}
+
+func (x *AvatarGenerationRequest) GetImageUrl() string {
+	if x != nil {
+		return x.ImageUrl
+	}
+	return ""
+}
+
+func (x *AvatarGenerationRequest) GetAvatarStyle() string {
+	if x != nil {
+		return x.AvatarStyle
+	}
+	return ""
+}
+
+func (x *AvatarGenerationRequest) GetIncludeGestures() bool {
+	if x != nil {
+		return x.IncludeGestures
+	}
+	return false
+}
+
+type AvatarGenerationResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	Status        string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	VideoUrl      string                 `protobuf:"bytes,3,opt,name=video_url,json=videoUrl,proto3" json:"video_url,omitempty"`
+	LipSyncScore  float32                `protobuf:"fixed32,4,opt,name=lip_sync_score,json=lipSyncScore,proto3" json:"lip_sync_score,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AvatarGenerationResponse) Reset() {
+	*x = AvatarGenerationResponse{}
+	mi := &file_content_factory_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AvatarGenerationResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AvatarGenerationResponse) ProtoMessage() {}
+
+func (x *AvatarGenerationResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_content_factory_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AvatarGenerationResponse.ProtoReflect.Descriptor instead.
+func (*AvatarGenerationResponse) Descriptor() ([]byte, []int) {
+	return file_content_factory_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *AvatarGenerationResponse) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *AvatarGenerationResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *AvatarGenerationResponse) GetVideoUrl() string {
+	if x != nil {
+		return x.VideoUrl
+	}
+	return ""
+}
+
+func (x *AvatarGenerationResponse) GetLipSyncScore() float32 {
+	if x != nil {
+		return x.LipSyncScore
+	}
+	return 0
+}
+
+type ContentCreationRequest struct {
+	state           protoimpl.MessageState   `protogen:"open.v1"`
+	Topic           string                   `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"` // the gateway's "prompt"
+	ForceGeneration bool                     `protobuf:"varint,2,opt,name=force_generation,json=forceGeneration,proto3" json:"force_generation,omitempty"`
+	TargetPlatforms []string                 `protobuf:"bytes,3,rep,name=target_platforms,json=targetPlatforms,proto3" json:"target_platforms,omitempty"`
+	BrandOverride   *BrandParametersOverride `protobuf:"bytes,4,opt,name=brand_override,json=brandOverride,proto3" json:"brand_override,omitempty"`
+	// Set by the Go gateway
+	JobId         string           `protobuf:"bytes,5,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"` // gateway-assigned ID; the service must reuse it as content_id
+	Format        string           `protobuf:"bytes,6,opt,name=format,proto3" json:"format,omitempty"`            // video, audio, avatar, article, social_post
+	Options       *structpb.Struct `protobuf:"bytes,7,opt,name=options,proto3" json:"options,omitempty"`
+	OwnerId       string           `protobuf:"bytes,8,opt,name=owner_id,json=ownerId,proto3" json:"owner_id,omitempty"` // authenticated principal that submitted the job
+	RetryOf       string           `protobuf:"bytes,9,opt,name=retry_of,json=retryOf,proto3" json:"retry_of,omitempty"` // the failed job this one retries, if any
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ContentCreationRequest) Reset() {
+	*x = ContentCreationRequest{}
+	mi := &file_content_factory_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ContentCreationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ContentCreationRequest) ProtoMessage() {}
+
+func (x *ContentCreationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_content_factory_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ContentCreationRequest.ProtoReflect.Descriptor instead.
+func (*ContentCreationRequest) Descriptor() ([]byte, []int) {
+	return file_content_factory_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ContentCreationRequest) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+func (x *ContentCreationRequest) GetForceGeneration() bool {
+	if x != nil {
+		return x.ForceGeneration
+	}
+	return false
+}
+
+func (x *ContentCreationRequest) GetTargetPlatforms() []string {
+	if x != nil {
+		return x.TargetPlatforms
+	}
+	return nil
+}
+
+func (x *ContentCreationRequest) GetBrandOverride() *BrandParametersOverride {
+	if x != nil {
+		return x.BrandOverride
+	}
+	return nil
+}
+
+func (x *ContentCreationRequest) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *ContentCreationRequest) GetFormat() string {
+	if x != nil {
+		return x.Format
+	}
+	return ""
+}
+
+func (x *ContentCreationRequest) GetOptions() *structpb.Struct {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+func (x *ContentCreationRequest) GetOwnerId() string {
+	if x != nil {
+		return x.OwnerId
+	}
+	return ""
+}
+
+func (x *ContentCreationRequest) GetRetryOf() string {
+	if x != nil {
+		return x.RetryOf
+	}
+	return ""
+}
+
+type BrandParametersOverride struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MusicTempo    *float32               `protobuf:"fixed32,1,opt,name=music_tempo,json=musicTempo,proto3,oneof" json:"music_tempo,omitempty"`
+	MusicEnergy   *float32               `protobuf:"fixed32,2,opt,name=music_energy,json=musicEnergy,proto3,oneof" json:"music_energy,omitempty"`
+	JargonLevel   *float32               `protobuf:"fixed32,3,opt,name=jargon_level,json=jargonLevel,proto3,oneof" json:"jargon_level,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BrandParametersOverride) Reset() {
+	*x = BrandParametersOverride{}
+	mi := &file_content_factory_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BrandParametersOverride) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BrandParametersOverride) ProtoMessage() {}
+
+func (x *BrandParametersOverride) ProtoReflect() protoreflect.Message {
+	mi := &file_content_factory_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BrandParametersOverride.ProtoReflect.Descriptor instead.
+func (*BrandParametersOverride) Descriptor() ([]byte, []int) {
+	return file_content_factory_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *BrandParametersOverride) GetMusicTempo() float32 {
+	if x != nil && x.MusicTempo != nil {
+		return *x.MusicTempo
+	}
+	return 0
+}
+
+func (x *BrandParametersOverride) GetMusicEnergy() float32 {
+	if x != nil && x.MusicEnergy != nil {
+		return *x.MusicEnergy
+	}
+	return 0
+}
+
+func (x *BrandParametersOverride) GetJargonLevel() float32 {
+	if x != nil && x.JargonLevel != nil {
+		return *x.JargonLevel
+	}
+	return 0
+}
+
+type ContentCreationResponse struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	ContentId    string                 `protobuf:"bytes,1,opt,name=content_id,json=contentId,proto3" json:"content_id,omitempty"`
+	Status       string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	Topic        string                 `protobuf:"bytes,3,opt,name=topic,proto3" json:"topic,omitempty"`
+	VideoUrl     string                 `protobuf:"bytes,4,opt,name=video_url,json=videoUrl,proto3" json:"video_url,omitempty"`
+	ThumbnailUrl string                 `protobuf:"bytes,5,opt,name=thumbnail_url,json=thumbnailUrl,proto3" json:"thumbnail_url,omitempty"`
+	// Quality metrics
+	OverallQualityScore   float32 `protobuf:"fixed32,6,opt,name=overall_quality_score,json=overallQualityScore,proto3" json:"overall_quality_score,omitempty"`
+	BrandAlignmentScore   float32 `protobuf:"fixed32,7,opt,name=brand_alignment_score,json=brandAlignmentScore,proto3" json:"brand_alignment_score,omitempty"`
+	TotalProcessingTimeMs int32   `protobuf:"varint,8,opt,name=total_processing_time_ms,json=totalProcessingTimeMs,proto3" json:"total_processing_time_ms,omitempty"`
+	unknownFields         protoimpl.UnknownFields
+	sizeCache             protoimpl.SizeCache
+}
+
+func (x *ContentCreationResponse) Reset() {
+	*x = ContentCreationResponse{}
+	mi := &file_content_factory_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ContentCreationResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ContentCreationResponse) ProtoMessage() {}
+
+func (x *ContentCreationResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_content_factory_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ContentCreationResponse.ProtoReflect.Descriptor instead.
+func (*ContentCreationResponse) Descriptor() ([]byte, []int) {
+	return file_content_factory_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ContentCreationResponse) GetContentId() string {
+	if x != nil {
+		return x.ContentId
+	}
+	return ""
+}
+
+func (x *ContentCreationResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *ContentCreationResponse) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+func (x *ContentCreationResponse) GetVideoUrl() string {
+	if x != nil {
+		return x.VideoUrl
+	}
+	return ""
+}
+
+func (x *ContentCreationResponse) GetThumbnailUrl() string {
+	if x != nil {
+		return x.ThumbnailUrl
+	}
+	return ""
+}
+
+func (x *ContentCreationResponse) GetOverallQualityScore() float32 {
+	if x != nil {
+		return x.OverallQualityScore
+	}
+	return 0
+}
+
+func (x *ContentCreationResponse) GetBrandAlignmentScore() float32 {
+	if x != nil {
+		return x.BrandAlignmentScore
+	}
+	return 0
+}
+
+func (x *ContentCreationResponse) GetTotalProcessingTimeMs() int32 {
+	if x != nil {
+		return x.TotalProcessingTimeMs
+	}
+	return 0
+}
+
+type ContentEstimate struct {
+	state                    protoimpl.MessageState `protogen:"open.v1"`
+	EstimatedCostUsd         float64                `protobuf:"fixed64,1,opt,name=estimated_cost_usd,json=estimatedCostUsd,proto3" json:"estimated_cost_usd,omitempty"`
+	EstimatedTokens          int64                  `protobuf:"varint,2,opt,name=estimated_tokens,json=estimatedTokens,proto3" json:"estimated_tokens,omitempty"`
+	EstimatedDurationSeconds int32                  `protobuf:"varint,3,opt,name=estimated_duration_seconds,json=estimatedDurationSeconds,proto3" json:"estimated_duration_seconds,omitempty"` // expected processing time
+	unknownFields            protoimpl.UnknownFields
+	sizeCache                protoimpl.SizeCache
+}
+
+func (x *ContentEstimate) Reset() {
+	*x = ContentEstimate{}
+	mi := &file_content_factory_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ContentEstimate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ContentEstimate) ProtoMessage() {}
+
+func (x *ContentEstimate) ProtoReflect() protoreflect.Message {
+	mi := &file_content_factory_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ContentEstimate.ProtoReflect.Descriptor instead.
+func (*ContentEstimate) Descriptor() ([]byte, []int) {
+	return file_content_factory_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ContentEstimate) GetEstimatedCostUsd() float64 {
+	if x != nil {
+		return x.EstimatedCostUsd
+	}
+	return 0
+}
+
+func (x *ContentEstimate) GetEstimatedTokens() int64 {
+	if x != nil {
+		return x.EstimatedTokens
+	}
+	return 0
+}
+
+func (x *ContentEstimate) GetEstimatedDurationSeconds() int32 {
+	if x != nil {
+		return x.EstimatedDurationSeconds
+	}
+	return 0
+}
+
+type ProgressUpdate struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	ContentId       string                 `protobuf:"bytes,1,opt,name=content_id,json=contentId,proto3" json:"content_id,omitempty"`
+	Stage           string                 `protobuf:"bytes,2,opt,name=stage,proto3" json:"stage,omitempty"` // trend_detection, brief_generation, image_generation, audio_generation, video_assembly
+	ProgressPercent float32                `protobuf:"fixed32,3,opt,name=progress_percent,json=progressPercent,proto3" json:"progress_percent,omitempty"`
+	Message         string                 `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`
+	Timestamp       *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	ResultUrl       string                 `protobuf:"bytes,6,opt,name=result_url,json=resultUrl,proto3" json:"result_url,omitempty"` // set on the final "completed" update
+	Sequence        int64                  `protobuf:"varint,7,opt,name=sequence,proto3" json:"sequence,omitempty"`                   // increases by one per update of a job, starting at 1
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ProgressUpdate) Reset() {
+	*x = ProgressUpdate{}
+	mi := &file_content_factory_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProgressUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProgressUpdate) ProtoMessage() {}
+
+func (x *ProgressUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_content_factory_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProgressUpdate.ProtoReflect.Descriptor instead.
+func (*ProgressUpdate) Descriptor() ([]byte, []int) {
+	return file_content_factory_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ProgressUpdate) GetContentId() string {
+	if x != nil {
+		return x.ContentId
+	}
+	return ""
+}
+
+func (x *ProgressUpdate) GetStage() string {
+	if x != nil {
+		return x.Stage
+	}
+	return ""
+}
+
+func (x *ProgressUpdate) GetProgressPercent() float32 {
+	if x != nil {
+		return x.ProgressPercent
+	}
+	return 0
+}
+
+func (x *ProgressUpdate) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *ProgressUpdate) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *ProgressUpdate) GetResultUrl() string {
+	if x != nil {
+		return x.ResultUrl
+	}
+	return ""
+}
+
+func (x *ProgressUpdate) GetSequence() int64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+type ContentInfoRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ContentId     string                 `protobuf:"bytes,1,opt,name=content_id,json=contentId,proto3" json:"content_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ContentInfoRequest) Reset() {
+	*x = ContentInfoRequest{}
+	mi := &file_content_factory_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ContentInfoRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ContentInfoRequest) ProtoMessage() {}
+
+func (x *ContentInfoRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_content_factory_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ContentInfoRequest.ProtoReflect.Descriptor instead.
+func (*ContentInfoRequest) Descriptor() ([]byte, []int) {
+	return file_content_factory_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ContentInfoRequest) GetContentId() string {
+	if x != nil {
+		return x.ContentId
+	}
+	return ""
+}
+
+type ContentInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ContentId     string                 `protobuf:"bytes,1,opt,name=content_id,json=contentId,proto3" json:"content_id,omitempty"`
+	ContentType   string                 `protobuf:"bytes,2,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"` // MIME type, e.g. video/mp4
+	Filename      string                 `protobuf:"bytes,3,opt,name=filename,proto3" json:"filename,omitempty"`                          // suggested download name
+	Size          int64                  `protobuf:"varint,4,opt,name=size,proto3" json:"size,omitempty"`                                 // bytes; 0 if unknown
+	OwnerId       string                 `protobuf:"bytes,5,opt,name=owner_id,json=ownerId,proto3" json:"owner_id,omitempty"`
+	Etag          string                 `protobuf:"bytes,6,opt,name=etag,proto3" json:"etag,omitempty"` // changes whenever the file's bytes do
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ContentInfo) Reset() {
+	*x = ContentInfo{}
+	mi := &file_content_factory_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ContentInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ContentInfo) ProtoMessage() {}
+
+func (x *ContentInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_content_factory_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ContentInfo.ProtoReflect.Descriptor instead.
+func (*ContentInfo) Descriptor() ([]byte, []int) {
+	return file_content_factory_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ContentInfo) GetContentId() string {
+	if x != nil {
+		return x.ContentId
+	}
+	return ""
+}
+
+func (x *ContentInfo) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *ContentInfo) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *ContentInfo) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *ContentInfo) GetOwnerId() string {
+	if x != nil {
+		return x.OwnerId
+	}
+	return ""
+}
+
+func (x *ContentInfo) GetEtag() string {
+	if x != nil {
+		return x.Etag
+	}
+	return ""
+}
+
+type DownloadRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ContentId     string                 `protobuf:"bytes,1,opt,name=content_id,json=contentId,proto3" json:"content_id,omitempty"`
+	Offset        int64                  `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"` // first byte to send
+	Length        int64                  `protobuf:"varint,3,opt,name=length,proto3" json:"length,omitempty"` // bytes to send; 0 means to the end
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DownloadRequest) Reset() {
+	*x = DownloadRequest{}
+	mi := &file_content_factory_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DownloadRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DownloadRequest) ProtoMessage() {}
+
+func (x *DownloadRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_content_factory_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DownloadRequest.ProtoReflect.Descriptor instead.
+func (*DownloadRequest) Descriptor() ([]byte, []int) {
+	return file_content_factory_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *DownloadRequest) GetContentId() string {
+	if x != nil {
+		return x.ContentId
+	}
+	return ""
+}
+
+func (x *DownloadRequest) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *DownloadRequest) GetLength() int64 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+type ContentChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Data          []byte                 `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"` // at most 64 KiB per message
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ContentChunk) Reset() {
+	*x = ContentChunk{}
+	mi := &file_content_factory_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ContentChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ContentChunk) ProtoMessage() {}
+
+func (x *ContentChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_content_factory_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ContentChunk.ProtoReflect.Descriptor instead.
+func (*ContentChunk) Descriptor() ([]byte, []int) {
+	return file_content_factory_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ContentChunk) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type GenerationChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Text          string                 `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`                                     // the next piece of generated text
+	Usage         *GenerationUsage       `protobuf:"bytes,2,opt,name=usage,proto3" json:"usage,omitempty"`                                   // set on the final message only
+	FinishReason  string                 `protobuf:"bytes,3,opt,name=finish_reason,json=finishReason,proto3" json:"finish_reason,omitempty"` // set on the final message: stop, length, ...
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GenerationChunk) Reset() {
+	*x = GenerationChunk{}
+	mi := &file_content_factory_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GenerationChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerationChunk) ProtoMessage() {}
+
+func (x *GenerationChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_content_factory_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerationChunk.ProtoReflect.Descriptor instead.
+func (*GenerationChunk) Descriptor() ([]byte, []int) {
+	return file_content_factory_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *GenerationChunk) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *GenerationChunk) GetUsage() *GenerationUsage {
+	if x != nil {
+		return x.Usage
+	}
+	return nil
+}
+
+func (x *GenerationChunk) GetFinishReason() string {
+	if x != nil {
+		return x.FinishReason
+	}
+	return ""
+}
+
+type GenerationUsage struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	PromptTokens     int64                  `protobuf:"varint,1,opt,name=prompt_tokens,json=promptTokens,proto3" json:"prompt_tokens,omitempty"`
+	CompletionTokens int64                  `protobuf:"varint,2,opt,name=completion_tokens,json=completionTokens,proto3" json:"completion_tokens,omitempty"`
+	TotalTokens      int64                  `protobuf:"varint,3,opt,name=total_tokens,json=totalTokens,proto3" json:"total_tokens,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *GenerationUsage) Reset() {
+	*x = GenerationUsage{}
+	mi := &file_content_factory_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GenerationUsage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerationUsage) ProtoMessage() {}
+
+func (x *GenerationUsage) ProtoReflect() protoreflect.Message {
+	mi := &file_content_factory_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerationUsage.ProtoReflect.Descriptor instead.
+func (*GenerationUsage) Descriptor() ([]byte, []int) {
+	return file_content_factory_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *GenerationUsage) GetPromptTokens() int64 {
+	if x != nil {
+		return x.PromptTokens
+	}
+	return 0
+}
+
+func (x *GenerationUsage) GetCompletionTokens() int64 {
+	if x != nil {
+		return x.CompletionTokens
+	}
+	return 0
+}
+
+func (x *GenerationUsage) GetTotalTokens() int64 {
+	if x != nil {
+		return x.TotalTokens
+	}
+	return 0
+}
+
+type StartAssetUploadRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	OwnerId       string                 `protobuf:"bytes,2,opt,name=owner_id,json=ownerId,proto3" json:"owner_id,omitempty"`
+	Filename      string                 `protobuf:"bytes,3,opt,name=filename,proto3" json:"filename,omitempty"`
+	ContentType   string                 `protobuf:"bytes,4,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StartAssetUploadRequest) Reset() {
+	*x = StartAssetUploadRequest{}
+	mi := &file_content_factory_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StartAssetUploadRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StartAssetUploadRequest) ProtoMessage() {}
+
+func (x *StartAssetUploadRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_content_factory_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StartAssetUploadRequest.ProtoReflect.Descriptor instead.
+func (*StartAssetUploadRequest) Descriptor() ([]byte, []int) {
+	return file_content_factory_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *StartAssetUploadRequest) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *StartAssetUploadRequest) GetOwnerId() string {
+	if x != nil {
+		return x.OwnerId
+	}
+	return ""
+}
+
+func (x *StartAssetUploadRequest) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *StartAssetUploadRequest) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+type StartAssetUploadResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UploadId      string                 `protobuf:"bytes,1,opt,name=upload_id,json=uploadId,proto3" json:"upload_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StartAssetUploadResponse) Reset() {
+	*x = StartAssetUploadResponse{}
+	mi := &file_content_factory_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StartAssetUploadResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StartAssetUploadResponse) ProtoMessage() {}
+
+func (x *StartAssetUploadResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_content_factory_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StartAssetUploadResponse.ProtoReflect.Descriptor instead.
+func (*StartAssetUploadResponse) Descriptor() ([]byte, []int) {
+	return file_content_factory_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *StartAssetUploadResponse) GetUploadId() string {
+	if x != nil {
+		return x.UploadId
+	}
+	return ""
+}
+
+type AssetChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UploadId      string                 `protobuf:"bytes,1,opt,name=upload_id,json=uploadId,proto3" json:"upload_id,omitempty"`
+	Offset        int64                  `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"` // bytes of the upload sent before this chunk
+	Data          []byte                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`      // at most 64 KiB per message
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AssetChunk) Reset() {
+	*x = AssetChunk{}
+	mi := &file_content_factory_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AssetChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AssetChunk) ProtoMessage() {}
+
+func (x *AssetChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_content_factory_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AssetChunk.ProtoReflect.Descriptor instead.
+func (*AssetChunk) Descriptor() ([]byte, []int) {
+	return file_content_factory_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *AssetChunk) GetUploadId() string {
+	if x != nil {
+		return x.UploadId
+	}
+	return ""
+}
+
+func (x *AssetChunk) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *AssetChunk) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type FinishAssetUploadRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UploadId      string                 `protobuf:"bytes,1,opt,name=upload_id,json=uploadId,proto3" json:"upload_id,omitempty"`
+	Size          int64                  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"` // total bytes sent, checked by the service
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FinishAssetUploadRequest) Reset() {
+	*x = FinishAssetUploadRequest{}
+	mi := &file_content_factory_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FinishAssetUploadRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FinishAssetUploadRequest) ProtoMessage() {}
+
+func (x *FinishAssetUploadRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_content_factory_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FinishAssetUploadRequest.ProtoReflect.Descriptor instead.
+func (*FinishAssetUploadRequest) Descriptor() ([]byte, []int) {
+	return file_content_factory_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *FinishAssetUploadRequest) GetUploadId() string {
+	if x != nil {
+		return x.UploadId
+	}
+	return ""
+}
+
+func (x *FinishAssetUploadRequest) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+type Asset struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AssetId       string                 `protobuf:"bytes,1,opt,name=asset_id,json=assetId,proto3" json:"asset_id,omitempty"`
+	JobId         string                 `protobuf:"bytes,2,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	Filename      string                 `protobuf:"bytes,3,opt,name=filename,proto3" json:"filename,omitempty"`
+	ContentType   string                 `protobuf:"bytes,4,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	Size          int64                  `protobuf:"varint,5,opt,name=size,proto3" json:"size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Asset) Reset() {
+	*x = Asset{}
+	mi := &file_content_factory_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Asset) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Asset) ProtoMessage() {}
+
+func (x *Asset) ProtoReflect() protoreflect.Message {
+	mi := &file_content_factory_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Asset.ProtoReflect.Descriptor instead.
+func (*Asset) Descriptor() ([]byte, []int) {
+	return file_content_factory_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *Asset) GetAssetId() string {
+	if x != nil {
+		return x.AssetId
+	}
+	return ""
+}
+
+func (x *Asset) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *Asset) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *Asset) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *Asset) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+type BrandParametersResponse struct {
+	state                protoimpl.MessageState `protogen:"open.v1"`
+	Generation           int32                  `protobuf:"varint,1,opt,name=generation,proto3" json:"generation,omitempty"`
+	MusicTempo           float32                `protobuf:"fixed32,2,opt,name=music_tempo,json=musicTempo,proto3" json:"music_tempo,omitempty"`
+	MusicEnergy          float32                `protobuf:"fixed32,3,opt,name=music_energy,json=musicEnergy,proto3" json:"music_energy,omitempty"`
+	MusicDanceability    float32                `protobuf:"fixed32,4,opt,name=music_danceability,json=musicDanceability,proto3" json:"music_danceability,omitempty"`
+	TextJargonLevel      float32                `protobuf:"fixed32,5,opt,name=text_jargon_level,json=textJargonLevel,proto3" json:"text_jargon_level,omitempty"`
+	TextLengthPreference float32                `protobuf:"fixed32,6,opt,name=text_length_preference,json=textLengthPreference,proto3" json:"text_length_preference,omitempty"`
+	VisualContrast       float32                `protobuf:"fixed32,7,opt,name=visual_contrast,json=visualContrast,proto3" json:"visual_contrast,omitempty"`
+	VisualSaturation     float32                `protobuf:"fixed32,8,opt,name=visual_saturation,json=visualSaturation,proto3" json:"visual_saturation,omitempty"`
+	FitnessAverage       float32                `protobuf:"fixed32,9,opt,name=fitness_average,json=fitnessAverage,proto3" json:"fitness_average,omitempty"`
+	unknownFields        protoimpl.UnknownFields
+	sizeCache            protoimpl.SizeCache
+}
+
+func (x *BrandParametersResponse) Reset() {
+	*x = BrandParametersResponse{}
+	mi := &file_content_factory_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BrandParametersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BrandParametersResponse) ProtoMessage() {}
+
+func (x *BrandParametersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_content_factory_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BrandParametersResponse.ProtoReflect.Descriptor instead.
+func (*BrandParametersResponse) Descriptor() ([]byte, []int) {
+	return file_content_factory_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *BrandParametersResponse) GetGeneration() int32 {
+	if x != nil {
+		return x.Generation
+	}
+	return 0
+}
+
+func (x *BrandParametersResponse) GetMusicTempo() float32 {
+	if x != nil {
+		return x.MusicTempo
+	}
+	return 0
+}
+
+func (x *BrandParametersResponse) GetMusicEnergy() float32 {
+	if x != nil {
+		return x.MusicEnergy
+	}
+	return 0
+}
+
+func (x *BrandParametersResponse) GetMusicDanceability() float32 {
+	if x != nil {
+		return x.MusicDanceability
+	}
+	return 0
+}
+
+func (x *BrandParametersResponse) GetTextJargonLevel() float32 {
+	if x != nil {
+		return x.TextJargonLevel
+	}
+	return 0
+}
+
+func (x *BrandParametersResponse) GetTextLengthPreference() float32 {
+	if x != nil {
+		return x.TextLengthPreference
+	}
+	return 0
+}
+
+func (x *BrandParametersResponse) GetVisualContrast() float32 {
+	if x != nil {
+		return x.VisualContrast
+	}
+	return 0
+}
+
+func (x *BrandParametersResponse) GetVisualSaturation() float32 {
+	if x != nil {
+		return x.VisualSaturation
+	}
+	return 0
+}
+
+func (x *BrandParametersResponse) GetFitnessAverage() float32 {
+	if x != nil {
+		return x.FitnessAverage
+	}
+	return 0
+}
+
+type EvolutionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ContentIds    []string               `protobuf:"bytes,1,rep,name=content_ids,json=contentIds,proto3" json:"content_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EvolutionRequest) Reset() {
+	*x = EvolutionRequest{}
+	mi := &file_content_factory_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EvolutionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EvolutionRequest) ProtoMessage() {}
+
+func (x *EvolutionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_content_factory_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EvolutionRequest.ProtoReflect.Descriptor instead.
+func (*EvolutionRequest) Descriptor() ([]byte, []int) {
+	return file_content_factory_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *EvolutionRequest) GetContentIds() []string {
+	if x != nil {
+		return x.ContentIds
+	}
+	return nil
+}
+
+type EvolutionResponse struct {
+	state         protoimpl.MessageState   `protogen:"open.v1"`
+	Status        string                   `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	NewGeneration int32                    `protobuf:"varint,2,opt,name=new_generation,json=newGeneration,proto3" json:"new_generation,omitempty"`
+	NewParameters *BrandParametersResponse `protobuf:"bytes,3,opt,name=new_parameters,json=newParameters,proto3" json:"new_parameters,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EvolutionResponse) Reset() {
+	*x = EvolutionResponse{}
+	mi := &file_content_factory_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EvolutionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EvolutionResponse) ProtoMessage() {}
+
+func (x *EvolutionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_content_factory_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EvolutionResponse.ProtoReflect.Descriptor instead.
+func (*EvolutionResponse) Descriptor() ([]byte, []int) {
+	return file_content_factory_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *EvolutionResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *EvolutionResponse) GetNewGeneration() int32 {
+	if x != nil {
+		return x.NewGeneration
+	}
+	return 0
+}
+
+func (x *EvolutionResponse) GetNewParameters() *BrandParametersResponse {
+	if x != nil {
+		return x.NewParameters
+	}
+	return nil
+}
+
+type JobStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	AfterSequence int64                  `protobuf:"varint,2,opt,name=after_sequence,json=afterSequence,proto3" json:"after_sequence,omitempty"` // StreamProgress only: resume after this update
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JobStatusRequest) Reset() {
+	*x = JobStatusRequest{}
+	mi := &file_content_factory_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobStatusRequest) ProtoMessage() {}
+
+func (x *JobStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_content_factory_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobStatusRequest.ProtoReflect.Descriptor instead.
+func (*JobStatusRequest) Descriptor() ([]byte, []int) {
+	return file_content_factory_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *JobStatusRequest) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *JobStatusRequest) GetAfterSequence() int64 {
+	if x != nil {
+		return x.AfterSequence
+	}
+	return 0
+}
+
+type JobStatusResponse struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	JobId           string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	Status          string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"` // queued, processing, completed, failed, cancelled
+	ProgressPercent float32                `protobuf:"fixed32,3,opt,name=progress_percent,json=progressPercent,proto3" json:"progress_percent,omitempty"`
+	ErrorMessage    string                 `protobuf:"bytes,4,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
+	CreatedAt       *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt       *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	OwnerId         string                 `protobuf:"bytes,7,opt,name=owner_id,json=ownerId,proto3" json:"owner_id,omitempty"`       // ContentOrchestrator only: the submitting principal
+	ResultUrl       string                 `protobuf:"bytes,8,opt,name=result_url,json=resultUrl,proto3" json:"result_url,omitempty"` // set once status is completed
+	ThumbnailUrl    string                 `protobuf:"bytes,9,opt,name=thumbnail_url,json=thumbnailUrl,proto3" json:"thumbnail_url,omitempty"`
+	RetryOf         string                 `protobuf:"bytes,10,opt,name=retry_of,json=retryOf,proto3" json:"retry_of,omitempty"` // ContentOrchestrator only: the failed job this one retries
+	Public          bool                   `protobuf:"varint,11,opt,name=public,proto3" json:"public,omitempty"`                 // ContentOrchestrator only: its owner shared it
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *JobStatusResponse) Reset() {
+	*x = JobStatusResponse{}
+	mi := &file_content_factory_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobStatusResponse) ProtoMessage() {}
+
+func (x *JobStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_content_factory_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobStatusResponse.ProtoReflect.Descriptor instead.
+func (*JobStatusResponse) Descriptor() ([]byte, []int) {
+	return file_content_factory_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *JobStatusResponse) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *JobStatusResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *JobStatusResponse) GetProgressPercent() float32 {
+	if x != nil {
+		return x.ProgressPercent
+	}
+	return 0
+}
+
+func (x *JobStatusResponse) GetErrorMessage() string {
+	if x != nil {
+		return x.ErrorMessage
+	}
+	return ""
+}
+
+func (x *JobStatusResponse) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *JobStatusResponse) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+func (x *JobStatusResponse) GetOwnerId() string {
+	if x != nil {
+		return x.OwnerId
+	}
+	return ""
+}
+
+func (x *JobStatusResponse) GetResultUrl() string {
+	if x != nil {
+		return x.ResultUrl
+	}
+	return ""
+}
+
+func (x *JobStatusResponse) GetThumbnailUrl() string {
+	if x != nil {
+		return x.ThumbnailUrl
+	}
+	return ""
+}
+
+func (x *JobStatusResponse) GetRetryOf() string {
+	if x != nil {
+		return x.RetryOf
+	}
+	return ""
+}
+
+func (x *JobStatusResponse) GetPublic() bool {
+	if x != nil {
+		return x.Public
+	}
+	return false
+}
+
+type ListJobsRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	OwnerId  string                 `protobuf:"bytes,1,opt,name=owner_id,json=ownerId,proto3" json:"owner_id,omitempty"`
+	PageSize int32                  `protobuf:"varint,2,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// next_page_token of the previous page; empty for the first. A token
+	// marks the position after the last job of its page, not an offset, so
+	// jobs submitted or deleted in between neither repeat nor hide others.
+	PageToken string `protobuf:"bytes,3,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	// Count the owner's jobs as well: "exact", "approximate" for a cheaper
+	// estimate, or empty not to count them
+	Total         string `protobuf:"bytes,4,opt,name=total,proto3" json:"total,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListJobsRequest) Reset() {
+	*x = ListJobsRequest{}
+	mi := &file_content_factory_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListJobsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListJobsRequest) ProtoMessage() {}
+
+func (x *ListJobsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_content_factory_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListJobsRequest.ProtoReflect.Descriptor instead.
+func (*ListJobsRequest) Descriptor() ([]byte, []int) {
+	return file_content_factory_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *ListJobsRequest) GetOwnerId() string {
+	if x != nil {
+		return x.OwnerId
+	}
+	return ""
+}
+
+func (x *ListJobsRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListJobsRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+func (x *ListJobsRequest) GetTotal() string {
+	if x != nil {
+		return x.Total
+	}
+	return ""
+}
+
+type ListJobsResponse struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Jobs             []*JobStatusResponse   `protobuf:"bytes,1,rep,name=jobs,proto3" json:"jobs,omitempty"`
+	NextPageToken    string                 `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`         // empty on the last page
+	TotalCount       int64                  `protobuf:"varint,3,opt,name=total_count,json=totalCount,proto3" json:"total_count,omitempty"`                   // with total set: all of the owner's jobs
+	TotalApproximate bool                   `protobuf:"varint,4,opt,name=total_approximate,json=totalApproximate,proto3" json:"total_approximate,omitempty"` // total_count is an estimate
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *ListJobsResponse) Reset() {
+	*x = ListJobsResponse{}
+	mi := &file_content_factory_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListJobsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListJobsResponse) ProtoMessage() {}
+
+func (x *ListJobsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_content_factory_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListJobsResponse.ProtoReflect.Descriptor instead.
+func (*ListJobsResponse) Descriptor() ([]byte, []int) {
+	return file_content_factory_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *ListJobsResponse) GetJobs() []*JobStatusResponse {
+	if x != nil {
+		return x.Jobs
+	}
+	return nil
+}
+
+func (x *ListJobsResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+func (x *ListJobsResponse) GetTotalCount() int64 {
+	if x != nil {
+		return x.TotalCount
+	}
+	return 0
+}
+
+func (x *ListJobsResponse) GetTotalApproximate() bool {
+	if x != nil {
+		return x.TotalApproximate
+	}
+	return false
+}
+
+type CancelJobRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	JobId string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	// ContentOrchestrator only: the updated_at the caller last saw; the job
+	// is left alone if it has changed since
+	IfUpdatedAt   *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=if_updated_at,json=ifUpdatedAt,proto3" json:"if_updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelJobRequest) Reset() {
+	*x = CancelJobRequest{}
+	mi := &file_content_factory_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelJobRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelJobRequest) ProtoMessage() {}
+
+func (x *CancelJobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_content_factory_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelJobRequest.ProtoReflect.Descriptor instead.
+func (*CancelJobRequest) Descriptor() ([]byte, []int) {
+	return file_content_factory_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *CancelJobRequest) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *CancelJobRequest) GetIfUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.IfUpdatedAt
+	}
+	return nil
+}
+
+type SetJobVisibilityRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	Public        bool                   `protobuf:"varint,2,opt,name=public,proto3" json:"public,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetJobVisibilityRequest) Reset() {
+	*x = SetJobVisibilityRequest{}
+	mi := &file_content_factory_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetJobVisibilityRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetJobVisibilityRequest) ProtoMessage() {}
+
+func (x *SetJobVisibilityRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_content_factory_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetJobVisibilityRequest.ProtoReflect.Descriptor instead.
+func (*SetJobVisibilityRequest) Descriptor() ([]byte, []int) {
+	return file_content_factory_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *SetJobVisibilityRequest) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *SetJobVisibilityRequest) GetPublic() bool {
+	if x != nil {
+		return x.Public
+	}
+	return false
+}
+
+type CancelJobResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelJobResponse) Reset() {
+	*x = CancelJobResponse{}
+	mi := &file_content_factory_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelJobResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelJobResponse) ProtoMessage() {}
+
+func (x *CancelJobResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_content_factory_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelJobResponse.ProtoReflect.Descriptor instead.
+func (*CancelJobResponse) Descriptor() ([]byte, []int) {
+	return file_content_factory_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *CancelJobResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *CancelJobResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+var File_content_factory_proto protoreflect.FileDescriptor
+
+const file_content_factory_proto_rawDesc = "" +
+	"\n" +
+	"\x15content_factory.proto\x12\x0fcontent_factory\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\a\n" +
+	"\x05Empty\"\xcf\x02\n" +
+	"\x12VideoRenderRequest\x12\x19\n" +
+	"\bbrief_id\x18\x01 \x01(\tR\abriefId\x12\x16\n" +
+	"\x06prompt\x18\x02 \x01(\tR\x06prompt\x12\x14\n" +
+	"\x05style\x18\x03 \x01(\tR\x05style\x12)\n" +
+	"\x10duration_seconds\x18\x04 \x01(\x05R\x0fdurationSeconds\x12\x14\n" +
+	"\x05width\x18\x05 \x01(\x05R\x05width\x12\x16\n" +
+	"\x06height\x18\x06 \x01(\x05R\x06height\x12'\n" +
+	"\x0fbrand_aesthetic\x18\a \x01(\tR\x0ebrandAesthetic\x12)\n" +
+	"\x10target_platforms\x18\b \x03(\tR\x0ftargetPlatforms\x12C\n" +
+	"\fmusic_params\x18\t \x01(\v2 .content_factory.MusicParametersR\vmusicParams\"\xd9\x01\n" +
+	"\x13VideoRenderResponse\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x1b\n" +
+	"\tvideo_url\x18\x03 \x01(\tR\bvideoUrl\x12#\n" +
+	"\rthumbnail_url\x18\x04 \x01(\tR\fthumbnailUrl\x12#\n" +
+	"\rquality_score\x18\x05 \x01(\x02R\fqualityScore\x12,\n" +
+	"\x12processing_time_ms\x18\x06 \x01(\x05R\x10processingTimeMs\"\x89\x01\n" +
+	"\x0fMusicParameters\x12\x14\n" +
+	"\x05tempo\x18\x01 \x01(\x02R\x05tempo\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\x12\x12\n" +
+	"\x04mood\x18\x03 \x01(\tR\x04mood\x12\x16\n" +
+	"\x06energy\x18\x04 \x01(\x02R\x06energy\x12\"\n" +
+	"\fdanceability\x18\x05 \x01(\x02R\fdanceability\"\xbd\x01\n" +
+	"\x16MusicGenerationRequest\x12\x19\n" +
+	"\bbrief_id\x18\x01 \x01(\tR\abriefId\x128\n" +
+	"\x06params\x18\x02 \x01(\v2 .content_factory.MusicParametersR\x06params\x12)\n" +
+	"\x10duration_seconds\x18\x03 \x01(\x05R\x0fdurationSeconds\x12#\n" +
+	"\routput_format\x18\x04 \x01(\tR\foutputFormat\"\xab\x01\n" +
+	"\x17MusicGenerationResponse\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x1b\n" +
+	"\taudio_url\x18\x03 \x01(\tR\baudioUrl\x12#\n" +
+	"\rquality_score\x18\x04 \x01(\x02R\fqualityScore\x12\x1f\n" +
+	"\vduration_ms\x18\x05 \x01(\x05R\n" +
+	"durationMs\"h\n" +
+	"\x10VoiceoverRequest\x12\x12\n" +
+	"\x04text\x18\x01 \x01(\tR\x04text\x12\x14\n" +
+	"\x05voice\x18\x02 \x01(\tR\x05voice\x12\x14\n" +
+	"\x05model\x18\x03 \x01(\tR\x05model\x12\x14\n" +
+	"\x05speed\x18\x04 \x01(\x02R\x05speed\"\x80\x01\n" +
+	"\x11VoiceoverResponse\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x1b\n" +
+	"\taudio_url\x18\x03 \x01(\tR\baudioUrl\x12\x1f\n" +
+	"\vduration_ms\x18\x04 \x01(\x05R\n" +
+	"durationMs\"\xa1\x01\n" +
+	"\x17AvatarGenerationRequest\x12\x1b\n" +
+	"\taudio_url\x18\x01 \x01(\tR\baudioUrl\x12\x1b\n" +
+	"\timage_url\x18\x02 \x01(\tR\bimageUrl\x12!\n" +
+	"\favatar_style\x18\x03 \x01(\tR\vavatarStyle\x12)\n" +
+	"\x10include_gestures\x18\x04 \x01(\bR\x0fincludeGestures\"\x8c\x01\n" +
+	"\x18AvatarGenerationResponse\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x1b\n" +
+	"\tvideo_url\x18\x03 \x01(\tR\bvideoUrl\x12$\n" +
+	"\x0elip_sync_score\x18\x04 \x01(\x02R\flipSyncScore\"\xed\x02\n" +
+	"\x16ContentCreationRequest\x12\x14\n" +
+	"\x05topic\x18\x01 \x01(\tR\x05topic\x12)\n" +
+	"\x10force_generation\x18\x02 \x01(\bR\x0fforceGeneration\x12)\n" +
+	"\x10target_platforms\x18\x03 \x03(\tR\x0ftargetPlatforms\x12O\n" +
+	"\x0ebrand_override\x18\x04 \x01(\v2(.content_factory.BrandParametersOverrideR\rbrandOverride\x12\x15\n" +
+	"\x06job_id\x18\x05 \x01(\tR\x05jobId\x12\x16\n" +
+	"\x06format\x18\x06 \x01(\tR\x06format\x121\n" +
+	"\aoptions\x18\a \x01(\v2\x17.google.protobuf.StructR\aoptions\x12\x19\n" +
+	"\bowner_id\x18\b \x01(\tR\aownerId\x12\x19\n" +
+	"\bretry_of\x18\t \x01(\tR\aretryOf\"\xc1\x01\n" +
+	"\x17BrandParametersOverride\x12$\n" +
+	"\vmusic_tempo\x18\x01 \x01(\x02H\x00R\n" +
+	"musicTempo\x88\x01\x01\x12&\n" +
+	"\fmusic_energy\x18\x02 \x01(\x02H\x01R\vmusicEnergy\x88\x01\x01\x12&\n" +
+	"\fjargon_level\x18\x03 \x01(\x02H\x02R\vjargonLevel\x88\x01\x01B\x0e\n" +
+	"\f_music_tempoB\x0f\n" +
+	"\r_music_energyB\x0f\n" +
+	"\r_jargon_level\"\xc9\x02\n" +
+	"\x17ContentCreationResponse\x12\x1d\n" +
+	"\n" +
+	"content_id\x18\x01 \x01(\tR\tcontentId\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x14\n" +
+	"\x05topic\x18\x03 \x01(\tR\x05topic\x12\x1b\n" +
+	"\tvideo_url\x18\x04 \x01(\tR\bvideoUrl\x12#\n" +
+	"\rthumbnail_url\x18\x05 \x01(\tR\fthumbnailUrl\x122\n" +
+	"\x15overall_quality_score\x18\x06 \x01(\x02R\x13overallQualityScore\x122\n" +
+	"\x15brand_alignment_score\x18\a \x01(\x02R\x13brandAlignmentScore\x127\n" +
+	"\x18total_processing_time_ms\x18\b \x01(\x05R\x15totalProcessingTimeMs\"\xa8\x01\n" +
+	"\x0fContentEstimate\x12,\n" +
+	"\x12estimated_cost_usd\x18\x01 \x01(\x01R\x10estimatedCostUsd\x12)\n" +
+	"\x10estimated_tokens\x18\x02 \x01(\x03R\x0festimatedTokens\x12<\n" +
+	"\x1aestimated_duration_seconds\x18\x03 \x01(\x05R\x18estimatedDurationSeconds\"\xff\x01\n" +
+	"\x0eProgressUpdate\x12\x1d\n" +
+	"\n" +
+	"content_id\x18\x01 \x01(\tR\tcontentId\x12\x14\n" +
+	"\x05stage\x18\x02 \x01(\tR\x05stage\x12)\n" +
+	"\x10progress_percent\x18\x03 \x01(\x02R\x0fprogressPercent\x12\x18\n" +
+	"\amessage\x18\x04 \x01(\tR\amessage\x128\n" +
+	"\ttimestamp\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12\x1d\n" +
+	"\n" +
+	"result_url\x18\x06 \x01(\tR\tresultUrl\x12\x1a\n" +
+	"\bsequence\x18\a \x01(\x03R\bsequence\"3\n" +
+	"\x12ContentInfoRequest\x12\x1d\n" +
+	"\n" +
+	"content_id\x18\x01 \x01(\tR\tcontentId\"\xae\x01\n" +
+	"\vContentInfo\x12\x1d\n" +
+	"\n" +
+	"content_id\x18\x01 \x01(\tR\tcontentId\x12!\n" +
+	"\fcontent_type\x18\x02 \x01(\tR\vcontentType\x12\x1a\n" +
+	"\bfilename\x18\x03 \x01(\tR\bfilename\x12\x12\n" +
+	"\x04size\x18\x04 \x01(\x03R\x04size\x12\x19\n" +
+	"\bowner_id\x18\x05 \x01(\tR\aownerId\x12\x12\n" +
+	"\x04etag\x18\x06 \x01(\tR\x04etag\"`\n" +
+	"\x0fDownloadRequest\x12\x1d\n" +
+	"\n" +
+	"content_id\x18\x01 \x01(\tR\tcontentId\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x03R\x06offset\x12\x16\n" +
+	"\x06length\x18\x03 \x01(\x03R\x06length\"\"\n" +
+	"\fContentChunk\x12\x12\n" +
+	"\x04data\x18\x01 \x01(\fR\x04data\"\x82\x01\n" +
+	"\x0fGenerationChunk\x12\x12\n" +
+	"\x04text\x18\x01 \x01(\tR\x04text\x126\n" +
+	"\x05usage\x18\x02 \x01(\v2 .content_factory.GenerationUsageR\x05usage\x12#\n" +
+	"\rfinish_reason\x18\x03 \x01(\tR\ffinishReason\"\x86\x01\n" +
+	"\x0fGenerationUsage\x12#\n" +
+	"\rprompt_tokens\x18\x01 \x01(\x03R\fpromptTokens\x12+\n" +
+	"\x11completion_tokens\x18\x02 \x01(\x03R\x10completionTokens\x12!\n" +
+	"\ftotal_tokens\x18\x03 \x01(\x03R\vtotalTokens\"\x8a\x01\n" +
+	"\x17StartAssetUploadRequest\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\x12\x19\n" +
+	"\bowner_id\x18\x02 \x01(\tR\aownerId\x12\x1a\n" +
+	"\bfilename\x18\x03 \x01(\tR\bfilename\x12!\n" +
+	"\fcontent_type\x18\x04 \x01(\tR\vcontentType\"7\n" +
+	"\x18StartAssetUploadResponse\x12\x1b\n" +
+	"\tupload_id\x18\x01 \x01(\tR\buploadId\"U\n" +
+	"\n" +
+	"AssetChunk\x12\x1b\n" +
+	"\tupload_id\x18\x01 \x01(\tR\buploadId\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x03R\x06offset\x12\x12\n" +
+	"\x04data\x18\x03 \x01(\fR\x04data\"K\n" +
+	"\x18FinishAssetUploadRequest\x12\x1b\n" +
+	"\tupload_id\x18\x01 \x01(\tR\buploadId\x12\x12\n" +
+	"\x04size\x18\x02 \x01(\x03R\x04size\"\x8c\x01\n" +
+	"\x05Asset\x12\x19\n" +
+	"\basset_id\x18\x01 \x01(\tR\aassetId\x12\x15\n" +
+	"\x06job_id\x18\x02 \x01(\tR\x05jobId\x12\x1a\n" +
+	"\bfilename\x18\x03 \x01(\tR\bfilename\x12!\n" +
+	"\fcontent_type\x18\x04 \x01(\tR\vcontentType\x12\x12\n" +
+	"\x04size\x18\x05 \x01(\x03R\x04size\"\x8d\x03\n" +
+	"\x17BrandParametersResponse\x12\x1e\n" +
+	"\n" +
+	"generation\x18\x01 \x01(\x05R\n" +
+	"generation\x12\x1f\n" +
+	"\vmusic_tempo\x18\x02 \x01(\x02R\n" +
+	"musicTempo\x12!\n" +
+	"\fmusic_energy\x18\x03 \x01(\x02R\vmusicEnergy\x12-\n" +
+	"\x12music_danceability\x18\x04 \x01(\x02R\x11musicDanceability\x12*\n" +
+	"\x11text_jargon_level\x18\x05 \x01(\x02R\x0ftextJargonLevel\x124\n" +
+	"\x16text_length_preference\x18\x06 \x01(\x02R\x14textLengthPreference\x12'\n" +
+	"\x0fvisual_contrast\x18\a \x01(\x02R\x0evisualContrast\x12+\n" +
+	"\x11visual_saturation\x18\b \x01(\x02R\x10visualSaturation\x12'\n" +
+	"\x0ffitness_average\x18\t \x01(\x02R\x0efitnessAverage\"3\n" +
+	"\x10EvolutionRequest\x12\x1f\n" +
+	"\vcontent_ids\x18\x01 \x03(\tR\n" +
+	"contentIds\"\xa3\x01\n" +
+	"\x11EvolutionResponse\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status\x12%\n" +
+	"\x0enew_generation\x18\x02 \x01(\x05R\rnewGeneration\x12O\n" +
+	"\x0enew_parameters\x18\x03 \x01(\v2(.content_factory.BrandParametersResponseR\rnewParameters\"P\n" +
+	"\x10JobStatusRequest\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\x12%\n" +
+	"\x0eafter_sequence\x18\x02 \x01(\x03R\rafterSequence\"\x9a\x03\n" +
+	"\x11JobStatusResponse\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12)\n" +
+	"\x10progress_percent\x18\x03 \x01(\x02R\x0fprogressPercent\x12#\n" +
+	"\rerror_message\x18\x04 \x01(\tR\ferrorMessage\x129\n" +
+	"\n" +
+	"created_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x12\x19\n" +
+	"\bowner_id\x18\a \x01(\tR\aownerId\x12\x1d\n" +
+	"\n" +
+	"result_url\x18\b \x01(\tR\tresultUrl\x12#\n" +
+	"\rthumbnail_url\x18\t \x01(\tR\fthumbnailUrl\x12\x19\n" +
+	"\bretry_of\x18\n" +
+	" \x01(\tR\aretryOf\x12\x16\n" +
+	"\x06public\x18\v \x01(\bR\x06public\"~\n" +
+	"\x0fListJobsRequest\x12\x19\n" +
+	"\bowner_id\x18\x01 \x01(\tR\aownerId\x12\x1b\n" +
+	"\tpage_size\x18\x02 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x03 \x01(\tR\tpageToken\x12\x14\n" +
+	"\x05total\x18\x04 \x01(\tR\x05total\"\xc0\x01\n" +
+	"\x10ListJobsResponse\x126\n" +
+	"\x04jobs\x18\x01 \x03(\v2\".content_factory.JobStatusResponseR\x04jobs\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\x12\x1f\n" +
+	"\vtotal_count\x18\x03 \x01(\x03R\n" +
+	"totalCount\x12+\n" +
+	"\x11total_approximate\x18\x04 \x01(\bR\x10totalApproximate\"i\n" +
+	"\x10CancelJobRequest\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\x12>\n" +
+	"\rif_updated_at\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\vifUpdatedAt\"H\n" +
+	"\x17SetJobVisibilityRequest\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\x12\x16\n" +
+	"\x06public\x18\x02 \x01(\bR\x06public\"G\n" +
+	"\x11CancelJobResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage2\x8e\x02\n" +
+	"\fVideoService\x12S\n" +
+	"\x06Render\x12#.content_factory.VideoRenderRequest\x1a$.content_factory.VideoRenderResponse\x12U\n" +
+	"\fGetJobStatus\x12!.content_factory.JobStatusRequest\x1a\".content_factory.JobStatusResponse\x12R\n" +
+	"\tCancelJob\x12!.content_factory.CancelJobRequest\x1a\".content_factory.CancelJobResponse2\xa5\x02\n" +
+	"\fAudioService\x12b\n" +
+	"\rGenerateMusic\x12'.content_factory.MusicGenerationRequest\x1a(.content_factory.MusicGenerationResponse\x12Z\n" +
+	"\x11GenerateVoiceover\x12!.content_factory.VoiceoverRequest\x1a\".content_factory.VoiceoverResponse\x12U\n" +
+	"\fGetJobStatus\x12!.content_factory.JobStatusRequest\x1a\".content_factory.JobStatusResponse2\xcd\x01\n" +
+	"\rAvatarService\x12e\n" +
+	"\x0eGenerateAvatar\x12(.content_factory.AvatarGenerationRequest\x1a).content_factory.AvatarGenerationResponse\x12U\n" +
+	"\fGetJobStatus\x12!.content_factory.JobStatusRequest\x1a\".content_factory.JobStatusResponse2\xb1\v\n" +
+	"\x13ContentOrchestrator\x12b\n" +
+	"\rCreateContent\x12'.content_factory.ContentCreationRequest\x1a(.content_factory.ContentCreationResponse\x12\\\n" +
+	"\x0fEstimateContent\x12'.content_factory.ContentCreationRequest\x1a .content_factory.ContentEstimate\x12V\n" +
+	"\x0eStreamProgress\x12!.content_factory.JobStatusRequest\x1a\x1f.content_factory.ProgressUpdate0\x01\x12U\n" +
+	"\fGetJobStatus\x12!.content_factory.JobStatusRequest\x1a\".content_factory.JobStatusResponse\x12[\n" +
+	"\rGetJobRequest\x12!.content_factory.JobStatusRequest\x1a'.content_factory.ContentCreationRequest\x12O\n" +
+	"\bListJobs\x12 .content_factory.ListJobsRequest\x1a!.content_factory.ListJobsResponse\x12R\n" +
+	"\tCancelJob\x12!.content_factory.CancelJobRequest\x1a\".content_factory.JobStatusResponse\x12`\n" +
+	"\x10SetJobVisibility\x12(.content_factory.SetJobVisibilityRequest\x1a\".content_factory.JobStatusResponse\x12S\n" +
+	"\x0eGetContentInfo\x12#.content_factory.ContentInfoRequest\x1a\x1c.content_factory.ContentInfo\x12T\n" +
+	"\x0fDownloadContent\x12 .content_factory.DownloadRequest\x1a\x1d.content_factory.ContentChunk0\x01\x12]\n" +
+	"\x0eGenerateStream\x12'.content_factory.ContentCreationRequest\x1a .content_factory.GenerationChunk0\x01\x12g\n" +
+	"\x10StartAssetUpload\x12(.content_factory.StartAssetUploadRequest\x1a).content_factory.StartAssetUploadResponse\x12G\n" +
+	"\x10UploadAssetChunk\x12\x1b.content_factory.AssetChunk\x1a\x16.content_factory.Empty\x12V\n" +
+	"\x11FinishAssetUpload\x12).content_factory.FinishAssetUploadRequest\x1a\x16.content_factory.Asset\x12V\n" +
+	"\x12GetBrandParameters\x12\x16.content_factory.Empty\x1a(.content_factory.BrandParametersResponse\x12Y\n" +
+	"\x10TriggerEvolution\x12!.content_factory.EvolutionRequest\x1a\".content_factory.EvolutionResponseB>Z<github.com/content-factory/go-gateway/protos/content_factoryb\x06proto3"
+
+var (
+	file_content_factory_proto_rawDescOnce sync.Once
+	file_content_factory_proto_rawDescData []byte
+)
+
+func file_content_factory_proto_rawDescGZIP() []byte {
+	file_content_factory_proto_rawDescOnce.Do(func() {
+		file_content_factory_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_content_factory_proto_rawDesc), len(file_content_factory_proto_rawDesc)))
+	})
+	return file_content_factory_proto_rawDescData
+}
+
+var file_content_factory_proto_msgTypes = make([]protoimpl.MessageInfo, 36)
+var file_content_factory_proto_goTypes = []any{
+	(*Empty)(nil),                    // 0: content_factory.Empty
+	(*VideoRenderRequest)(nil),       // 1: content_factory.VideoRenderRequest
+	(*VideoRenderResponse)(nil),      // 2: content_factory.VideoRenderResponse
+	(*MusicParameters)(nil),          // 3: content_factory.MusicParameters
+	(*MusicGenerationRequest)(nil),   // 4: content_factory.MusicGenerationRequest
+	(*MusicGenerationResponse)(nil),  // 5: content_factory.MusicGenerationResponse
+	(*VoiceoverRequest)(nil),         // 6: content_factory.VoiceoverRequest
+	(*VoiceoverResponse)(nil),        // 7: content_factory.VoiceoverResponse
+	(*AvatarGenerationRequest)(nil),  // 8: content_factory.AvatarGenerationRequest
+	(*AvatarGenerationResponse)(nil), // 9: content_factory.AvatarGenerationResponse
+	(*ContentCreationRequest)(nil),   // 10: content_factory.ContentCreationRequest
+	(*BrandParametersOverride)(nil),  // 11: content_factory.BrandParametersOverride
+	(*ContentCreationResponse)(nil),  // 12: content_factory.ContentCreationResponse
+	(*ContentEstimate)(nil),          // 13: content_factory.ContentEstimate
+	(*ProgressUpdate)(nil),           // 14: content_factory.ProgressUpdate
+	(*ContentInfoRequest)(nil),       // 15: content_factory.ContentInfoRequest
+	(*ContentInfo)(nil),              // 16: content_factory.ContentInfo
+	(*DownloadRequest)(nil),          // 17: content_factory.DownloadRequest
+	(*ContentChunk)(nil),             // 18: content_factory.ContentChunk
+	(*GenerationChunk)(nil),          // 19: content_factory.GenerationChunk
+	(*GenerationUsage)(nil),          // 20: content_factory.GenerationUsage
+	(*StartAssetUploadRequest)(nil),  // 21: content_factory.StartAssetUploadRequest
+	(*StartAssetUploadResponse)(nil), // 22: content_factory.StartAssetUploadResponse
+	(*AssetChunk)(nil),               // 23: content_factory.AssetChunk
+	(*FinishAssetUploadRequest)(nil), // 24: content_factory.FinishAssetUploadRequest
+	(*Asset)(nil),                    // 25: content_factory.Asset
+	(*BrandParametersResponse)(nil),  // 26: content_factory.BrandParametersResponse
+	(*EvolutionRequest)(nil),         // 27: content_factory.EvolutionRequest
+	(*EvolutionResponse)(nil),        // 28: content_factory.EvolutionResponse
+	(*JobStatusRequest)(nil),         // 29: content_factory.JobStatusRequest
+	(*JobStatusResponse)(nil),        // 30: content_factory.JobStatusResponse
+	(*ListJobsRequest)(nil),          // 31: content_factory.ListJobsRequest
+	(*ListJobsResponse)(nil),         // 32: content_factory.ListJobsResponse
+	(*CancelJobRequest)(nil),         // 33: content_factory.CancelJobRequest
+	(*SetJobVisibilityRequest)(nil),  // 34: content_factory.SetJobVisibilityRequest
+	(*CancelJobResponse)(nil),        // 35: content_factory.CancelJobResponse
+	(*structpb.Struct)(nil),          // 36: google.protobuf.Struct
+	(*timestamppb.Timestamp)(nil),    // 37: google.protobuf.Timestamp
+}
+var file_content_factory_proto_depIdxs = []int32{
+	3,  // 0: content_factory.VideoRenderRequest.music_params:type_name -> content_factory.MusicParameters
+	3,  // 1: content_factory.MusicGenerationRequest.params:type_name -> content_factory.MusicParameters
+	11, // 2: content_factory.ContentCreationRequest.brand_override:type_name -> content_factory.BrandParametersOverride
+	36, // 3: content_factory.ContentCreationRequest.options:type_name -> google.protobuf.Struct
+	37, // 4: content_factory.ProgressUpdate.timestamp:type_name -> google.protobuf.Timestamp
+	20, // 5: content_factory.GenerationChunk.usage:type_name -> content_factory.GenerationUsage
+	26, // 6: content_factory.EvolutionResponse.new_parameters:type_name -> content_factory.BrandParametersResponse
+	37, // 7: content_factory.JobStatusResponse.created_at:type_name -> google.protobuf.Timestamp
+	37, // 8: content_factory.JobStatusResponse.updated_at:type_name -> google.protobuf.Timestamp
+	30, // 9: content_factory.ListJobsResponse.jobs:type_name -> content_factory.JobStatusResponse
+	37, // 10: content_factory.CancelJobRequest.if_updated_at:type_name -> google.protobuf.Timestamp
+	1,  // 11: content_factory.VideoService.Render:input_type -> content_factory.VideoRenderRequest
+	29, // 12: content_factory.VideoService.GetJobStatus:input_type -> content_factory.JobStatusRequest
+	33, // 13: content_factory.VideoService.CancelJob:input_type -> content_factory.CancelJobRequest
+	4,  // 14: content_factory.AudioService.GenerateMusic:input_type -> content_factory.MusicGenerationRequest
+	6,  // 15: content_factory.AudioService.GenerateVoiceover:input_type -> content_factory.VoiceoverRequest
+	29, // 16: content_factory.AudioService.GetJobStatus:input_type -> content_factory.JobStatusRequest
+	8,  // 17: content_factory.AvatarService.GenerateAvatar:input_type -> content_factory.AvatarGenerationRequest
+	29, // 18: content_factory.AvatarService.GetJobStatus:input_type -> content_factory.JobStatusRequest
+	10, // 19: content_factory.ContentOrchestrator.CreateContent:input_type -> content_factory.ContentCreationRequest
+	10, // 20: content_factory.ContentOrchestrator.EstimateContent:input_type -> content_factory.ContentCreationRequest
+	29, // 21: content_factory.ContentOrchestrator.StreamProgress:input_type -> content_factory.JobStatusRequest
+	29, // 22: content_factory.ContentOrchestrator.GetJobStatus:input_type -> content_factory.JobStatusRequest
+	29, // 23: content_factory.ContentOrchestrator.GetJobRequest:input_type -> content_factory.JobStatusRequest
+	31, // 24: content_factory.ContentOrchestrator.ListJobs:input_type -> content_factory.ListJobsRequest
+	33, // 25: content_factory.ContentOrchestrator.CancelJob:input_type -> content_factory.CancelJobRequest
+	34, // 26: content_factory.ContentOrchestrator.SetJobVisibility:input_type -> content_factory.SetJobVisibilityRequest
+	15, // 27: content_factory.ContentOrchestrator.GetContentInfo:input_type -> content_factory.ContentInfoRequest
+	17, // 28: content_factory.ContentOrchestrator.DownloadContent:input_type -> content_factory.DownloadRequest
+	10, // 29: content_factory.ContentOrchestrator.GenerateStream:input_type -> content_factory.ContentCreationRequest
+	21, // 30: content_factory.ContentOrchestrator.StartAssetUpload:input_type -> content_factory.StartAssetUploadRequest
+	23, // 31: content_factory.ContentOrchestrator.UploadAssetChunk:input_type -> content_factory.AssetChunk
+	24, // 32: content_factory.ContentOrchestrator.FinishAssetUpload:input_type -> content_factory.FinishAssetUploadRequest
+	0,  // 33: content_factory.ContentOrchestrator.GetBrandParameters:input_type -> content_factory.Empty
+	27, // 34: content_factory.ContentOrchestrator.TriggerEvolution:input_type -> content_factory.EvolutionRequest
+	2,  // 35: content_factory.VideoService.Render:output_type -> content_factory.VideoRenderResponse
+	30, // 36: content_factory.VideoService.GetJobStatus:output_type -> content_factory.JobStatusResponse
+	35, // 37: content_factory.VideoService.CancelJob:output_type -> content_factory.CancelJobResponse
+	5,  // 38: content_factory.AudioService.GenerateMusic:output_type -> content_factory.MusicGenerationResponse
+	7,  // 39: content_factory.AudioService.GenerateVoiceover:output_type -> content_factory.VoiceoverResponse
+	30, // 40: content_factory.AudioService.GetJobStatus:output_type -> content_factory.JobStatusResponse
+	9,  // 41: content_factory.AvatarService.GenerateAvatar:output_type -> content_factory.AvatarGenerationResponse
+	30, // 42: content_factory.AvatarService.GetJobStatus:output_type -> content_factory.JobStatusResponse
+	12, // 43: content_factory.ContentOrchestrator.CreateContent:output_type -> content_factory.ContentCreationResponse
+	13, // 44: content_factory.ContentOrchestrator.EstimateContent:output_type -> content_factory.ContentEstimate
+	14, // 45: content_factory.ContentOrchestrator.StreamProgress:output_type -> content_factory.ProgressUpdate
+	30, // 46: content_factory.ContentOrchestrator.GetJobStatus:output_type -> content_factory.JobStatusResponse
+	10, // 47: content_factory.ContentOrchestrator.GetJobRequest:output_type -> content_factory.ContentCreationRequest
+	32, // 48: content_factory.ContentOrchestrator.ListJobs:output_type -> content_factory.ListJobsResponse
+	30, // 49: content_factory.ContentOrchestrator.CancelJob:output_type -> content_factory.JobStatusResponse
+	30, // 50: content_factory.ContentOrchestrator.SetJobVisibility:output_type -> content_factory.JobStatusResponse
+	16, // 51: content_factory.ContentOrchestrator.GetContentInfo:output_type -> content_factory.ContentInfo
+	18, // 52: content_factory.ContentOrchestrator.DownloadContent:output_type -> content_factory.ContentChunk
+	19, // 53: content_factory.ContentOrchestrator.GenerateStream:output_type -> content_factory.GenerationChunk
+	22, // 54: content_factory.ContentOrchestrator.StartAssetUpload:output_type -> content_factory.StartAssetUploadResponse
+	0,  // 55: content_factory.ContentOrchestrator.UploadAssetChunk:output_type -> content_factory.Empty
+	25, // 56: content_factory.ContentOrchestrator.FinishAssetUpload:output_type -> content_factory.Asset
+	26, // 57: content_factory.ContentOrchestrator.GetBrandParameters:output_type -> content_factory.BrandParametersResponse
+	28, // 58: content_factory.ContentOrchestrator.TriggerEvolution:output_type -> content_factory.EvolutionResponse
+	35, // [35:59] is the sub-list for method output_type
+	11, // [11:35] is the sub-list for method input_type
+	11, // [11:11] is the sub-list for extension type_name
+	11, // [11:11] is the sub-list for extension extendee
+	0,  // [0:11] is the sub-list for field type_name
+}
+
+func init() { file_content_factory_proto_init() }
+func file_content_factory_proto_init() {
+	if File_content_factory_proto != nil {
+		return
+	}
+	file_content_factory_proto_msgTypes[11].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_content_factory_proto_rawDesc), len(file_content_factory_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   36,
+			NumExtensions: 0,
+			NumServices:   4,
+		},
+		GoTypes:           file_content_factory_proto_goTypes,
+		DependencyIndexes: file_content_factory_proto_depIdxs,
+		MessageInfos:      file_content_factory_proto_msgTypes,
+	}.Build()
+	File_content_factory_proto = out.File
+	file_content_factory_proto_goTypes = nil
+	file_content_factory_proto_depIdxs = nil
+}
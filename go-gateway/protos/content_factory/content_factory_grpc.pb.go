@@ -0,0 +1,1270 @@
+// Content Factory Service Definitions
+//
+// gRPC Protocol Buffer definitions for communication between
+// Go API Gateway and Python AI Services.
+//
+// Architecture: Go Gateway -> gRPC -> Python Services -> External AI APIs
+//
+// Requires protobuf >= 3.15 for proto3 optional field support.
+// Generate Python code with:
+//   python -m grpc_tools.protoc -I. --python_out=. --grpc_python_out=. protos/content_factory.proto
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.1
+// - protoc             (unknown)
+// source: content_factory.proto
+
+package content_factory
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	VideoService_Render_FullMethodName       = "/content_factory.VideoService/Render"
+	VideoService_GetJobStatus_FullMethodName = "/content_factory.VideoService/GetJobStatus"
+	VideoService_CancelJob_FullMethodName    = "/content_factory.VideoService/CancelJob"
+)
+
+// VideoServiceClient is the client API for VideoService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type VideoServiceClient interface {
+	// Generate a video from creative brief
+	Render(ctx context.Context, in *VideoRenderRequest, opts ...grpc.CallOption) (*VideoRenderResponse, error)
+	// Check the status of a video render job
+	GetJobStatus(ctx context.Context, in *JobStatusRequest, opts ...grpc.CallOption) (*JobStatusResponse, error)
+	// Cancel a pending video render job
+	CancelJob(ctx context.Context, in *CancelJobRequest, opts ...grpc.CallOption) (*CancelJobResponse, error)
+}
+
+type videoServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewVideoServiceClient(cc grpc.ClientConnInterface) VideoServiceClient {
+	return &videoServiceClient{cc}
+}
+
+func (c *videoServiceClient) Render(ctx context.Context, in *VideoRenderRequest, opts ...grpc.CallOption) (*VideoRenderResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(VideoRenderResponse)
+	err := c.cc.Invoke(ctx, VideoService_Render_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *videoServiceClient) GetJobStatus(ctx context.Context, in *JobStatusRequest, opts ...grpc.CallOption) (*JobStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(JobStatusResponse)
+	err := c.cc.Invoke(ctx, VideoService_GetJobStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *videoServiceClient) CancelJob(ctx context.Context, in *CancelJobRequest, opts ...grpc.CallOption) (*CancelJobResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CancelJobResponse)
+	err := c.cc.Invoke(ctx, VideoService_CancelJob_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// VideoServiceServer is the server API for VideoService service.
+// All implementations must embed UnimplementedVideoServiceServer
+// for forward compatibility.
+type VideoServiceServer interface {
+	// Generate a video from creative brief
+	Render(context.Context, *VideoRenderRequest) (*VideoRenderResponse, error)
+	// Check the status of a video render job
+	GetJobStatus(context.Context, *JobStatusRequest) (*JobStatusResponse, error)
+	// Cancel a pending video render job
+	CancelJob(context.Context, *CancelJobRequest) (*CancelJobResponse, error)
+	mustEmbedUnimplementedVideoServiceServer()
+}
+
+// UnimplementedVideoServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedVideoServiceServer struct{}
+
+func (UnimplementedVideoServiceServer) Render(context.Context, *VideoRenderRequest) (*VideoRenderResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Render not implemented")
+}
+func (UnimplementedVideoServiceServer) GetJobStatus(context.Context, *JobStatusRequest) (*JobStatusResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetJobStatus not implemented")
+}
+func (UnimplementedVideoServiceServer) CancelJob(context.Context, *CancelJobRequest) (*CancelJobResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method CancelJob not implemented")
+}
+func (UnimplementedVideoServiceServer) mustEmbedUnimplementedVideoServiceServer() {}
+func (UnimplementedVideoServiceServer) testEmbeddedByValue()                      {}
+
+// UnsafeVideoServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to VideoServiceServer will
+// result in compilation errors.
+type UnsafeVideoServiceServer interface {
+	mustEmbedUnimplementedVideoServiceServer()
+}
+
+func RegisterVideoServiceServer(s grpc.ServiceRegistrar, srv VideoServiceServer) {
+	// If the following call panics, it indicates UnimplementedVideoServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&VideoService_ServiceDesc, srv)
+}
+
+func _VideoService_Render_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VideoRenderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VideoServiceServer).Render(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VideoService_Render_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VideoServiceServer).Render(ctx, req.(*VideoRenderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VideoService_GetJobStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(JobStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VideoServiceServer).GetJobStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VideoService_GetJobStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VideoServiceServer).GetJobStatus(ctx, req.(*JobStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VideoService_CancelJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VideoServiceServer).CancelJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VideoService_CancelJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VideoServiceServer).CancelJob(ctx, req.(*CancelJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// VideoService_ServiceDesc is the grpc.ServiceDesc for VideoService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var VideoService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "content_factory.VideoService",
+	HandlerType: (*VideoServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Render",
+			Handler:    _VideoService_Render_Handler,
+		},
+		{
+			MethodName: "GetJobStatus",
+			Handler:    _VideoService_GetJobStatus_Handler,
+		},
+		{
+			MethodName: "CancelJob",
+			Handler:    _VideoService_CancelJob_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "content_factory.proto",
+}
+
+const (
+	AudioService_GenerateMusic_FullMethodName     = "/content_factory.AudioService/GenerateMusic"
+	AudioService_GenerateVoiceover_FullMethodName = "/content_factory.AudioService/GenerateVoiceover"
+	AudioService_GetJobStatus_FullMethodName      = "/content_factory.AudioService/GetJobStatus"
+)
+
+// AudioServiceClient is the client API for AudioService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AudioServiceClient interface {
+	// Generate background music track
+	GenerateMusic(ctx context.Context, in *MusicGenerationRequest, opts ...grpc.CallOption) (*MusicGenerationResponse, error)
+	// Generate text-to-speech voiceover
+	GenerateVoiceover(ctx context.Context, in *VoiceoverRequest, opts ...grpc.CallOption) (*VoiceoverResponse, error)
+	// Get job status
+	GetJobStatus(ctx context.Context, in *JobStatusRequest, opts ...grpc.CallOption) (*JobStatusResponse, error)
+}
+
+type audioServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAudioServiceClient(cc grpc.ClientConnInterface) AudioServiceClient {
+	return &audioServiceClient{cc}
+}
+
+func (c *audioServiceClient) GenerateMusic(ctx context.Context, in *MusicGenerationRequest, opts ...grpc.CallOption) (*MusicGenerationResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MusicGenerationResponse)
+	err := c.cc.Invoke(ctx, AudioService_GenerateMusic_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *audioServiceClient) GenerateVoiceover(ctx context.Context, in *VoiceoverRequest, opts ...grpc.CallOption) (*VoiceoverResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(VoiceoverResponse)
+	err := c.cc.Invoke(ctx, AudioService_GenerateVoiceover_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *audioServiceClient) GetJobStatus(ctx context.Context, in *JobStatusRequest, opts ...grpc.CallOption) (*JobStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(JobStatusResponse)
+	err := c.cc.Invoke(ctx, AudioService_GetJobStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AudioServiceServer is the server API for AudioService service.
+// All implementations must embed UnimplementedAudioServiceServer
+// for forward compatibility.
+type AudioServiceServer interface {
+	// Generate background music track
+	GenerateMusic(context.Context, *MusicGenerationRequest) (*MusicGenerationResponse, error)
+	// Generate text-to-speech voiceover
+	GenerateVoiceover(context.Context, *VoiceoverRequest) (*VoiceoverResponse, error)
+	// Get job status
+	GetJobStatus(context.Context, *JobStatusRequest) (*JobStatusResponse, error)
+	mustEmbedUnimplementedAudioServiceServer()
+}
+
+// UnimplementedAudioServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedAudioServiceServer struct{}
+
+func (UnimplementedAudioServiceServer) GenerateMusic(context.Context, *MusicGenerationRequest) (*MusicGenerationResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GenerateMusic not implemented")
+}
+func (UnimplementedAudioServiceServer) GenerateVoiceover(context.Context, *VoiceoverRequest) (*VoiceoverResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GenerateVoiceover not implemented")
+}
+func (UnimplementedAudioServiceServer) GetJobStatus(context.Context, *JobStatusRequest) (*JobStatusResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetJobStatus not implemented")
+}
+func (UnimplementedAudioServiceServer) mustEmbedUnimplementedAudioServiceServer() {}
+func (UnimplementedAudioServiceServer) testEmbeddedByValue()                      {}
+
+// UnsafeAudioServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AudioServiceServer will
+// result in compilation errors.
+type UnsafeAudioServiceServer interface {
+	mustEmbedUnimplementedAudioServiceServer()
+}
+
+func RegisterAudioServiceServer(s grpc.ServiceRegistrar, srv AudioServiceServer) {
+	// If the following call panics, it indicates UnimplementedAudioServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&AudioService_ServiceDesc, srv)
+}
+
+func _AudioService_GenerateMusic_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MusicGenerationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AudioServiceServer).GenerateMusic(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AudioService_GenerateMusic_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AudioServiceServer).GenerateMusic(ctx, req.(*MusicGenerationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AudioService_GenerateVoiceover_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VoiceoverRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AudioServiceServer).GenerateVoiceover(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AudioService_GenerateVoiceover_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AudioServiceServer).GenerateVoiceover(ctx, req.(*VoiceoverRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AudioService_GetJobStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(JobStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AudioServiceServer).GetJobStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AudioService_GetJobStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AudioServiceServer).GetJobStatus(ctx, req.(*JobStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// AudioService_ServiceDesc is the grpc.ServiceDesc for AudioService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AudioService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "content_factory.AudioService",
+	HandlerType: (*AudioServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GenerateMusic",
+			Handler:    _AudioService_GenerateMusic_Handler,
+		},
+		{
+			MethodName: "GenerateVoiceover",
+			Handler:    _AudioService_GenerateVoiceover_Handler,
+		},
+		{
+			MethodName: "GetJobStatus",
+			Handler:    _AudioService_GetJobStatus_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "content_factory.proto",
+}
+
+const (
+	AvatarService_GenerateAvatar_FullMethodName = "/content_factory.AvatarService/GenerateAvatar"
+	AvatarService_GetJobStatus_FullMethodName   = "/content_factory.AvatarService/GetJobStatus"
+)
+
+// AvatarServiceClient is the client API for AvatarService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AvatarServiceClient interface {
+	// Generate avatar video from audio and image
+	GenerateAvatar(ctx context.Context, in *AvatarGenerationRequest, opts ...grpc.CallOption) (*AvatarGenerationResponse, error)
+	// Get job status
+	GetJobStatus(ctx context.Context, in *JobStatusRequest, opts ...grpc.CallOption) (*JobStatusResponse, error)
+}
+
+type avatarServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAvatarServiceClient(cc grpc.ClientConnInterface) AvatarServiceClient {
+	return &avatarServiceClient{cc}
+}
+
+func (c *avatarServiceClient) GenerateAvatar(ctx context.Context, in *AvatarGenerationRequest, opts ...grpc.CallOption) (*AvatarGenerationResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AvatarGenerationResponse)
+	err := c.cc.Invoke(ctx, AvatarService_GenerateAvatar_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *avatarServiceClient) GetJobStatus(ctx context.Context, in *JobStatusRequest, opts ...grpc.CallOption) (*JobStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(JobStatusResponse)
+	err := c.cc.Invoke(ctx, AvatarService_GetJobStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AvatarServiceServer is the server API for AvatarService service.
+// All implementations must embed UnimplementedAvatarServiceServer
+// for forward compatibility.
+type AvatarServiceServer interface {
+	// Generate avatar video from audio and image
+	GenerateAvatar(context.Context, *AvatarGenerationRequest) (*AvatarGenerationResponse, error)
+	// Get job status
+	GetJobStatus(context.Context, *JobStatusRequest) (*JobStatusResponse, error)
+	mustEmbedUnimplementedAvatarServiceServer()
+}
+
+// UnimplementedAvatarServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedAvatarServiceServer struct{}
+
+func (UnimplementedAvatarServiceServer) GenerateAvatar(context.Context, *AvatarGenerationRequest) (*AvatarGenerationResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GenerateAvatar not implemented")
+}
+func (UnimplementedAvatarServiceServer) GetJobStatus(context.Context, *JobStatusRequest) (*JobStatusResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetJobStatus not implemented")
+}
+func (UnimplementedAvatarServiceServer) mustEmbedUnimplementedAvatarServiceServer() {}
+func (UnimplementedAvatarServiceServer) testEmbeddedByValue()                       {}
+
+// UnsafeAvatarServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AvatarServiceServer will
+// result in compilation errors.
+type UnsafeAvatarServiceServer interface {
+	mustEmbedUnimplementedAvatarServiceServer()
+}
+
+func RegisterAvatarServiceServer(s grpc.ServiceRegistrar, srv AvatarServiceServer) {
+	// If the following call panics, it indicates UnimplementedAvatarServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&AvatarService_ServiceDesc, srv)
+}
+
+func _AvatarService_GenerateAvatar_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AvatarGenerationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AvatarServiceServer).GenerateAvatar(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AvatarService_GenerateAvatar_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AvatarServiceServer).GenerateAvatar(ctx, req.(*AvatarGenerationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AvatarService_GetJobStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(JobStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AvatarServiceServer).GetJobStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AvatarService_GetJobStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AvatarServiceServer).GetJobStatus(ctx, req.(*JobStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// AvatarService_ServiceDesc is the grpc.ServiceDesc for AvatarService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AvatarService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "content_factory.AvatarService",
+	HandlerType: (*AvatarServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GenerateAvatar",
+			Handler:    _AvatarService_GenerateAvatar_Handler,
+		},
+		{
+			MethodName: "GetJobStatus",
+			Handler:    _AvatarService_GetJobStatus_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "content_factory.proto",
+}
+
+const (
+	ContentOrchestrator_CreateContent_FullMethodName      = "/content_factory.ContentOrchestrator/CreateContent"
+	ContentOrchestrator_EstimateContent_FullMethodName    = "/content_factory.ContentOrchestrator/EstimateContent"
+	ContentOrchestrator_StreamProgress_FullMethodName     = "/content_factory.ContentOrchestrator/StreamProgress"
+	ContentOrchestrator_GetJobStatus_FullMethodName       = "/content_factory.ContentOrchestrator/GetJobStatus"
+	ContentOrchestrator_GetJobRequest_FullMethodName      = "/content_factory.ContentOrchestrator/GetJobRequest"
+	ContentOrchestrator_ListJobs_FullMethodName           = "/content_factory.ContentOrchestrator/ListJobs"
+	ContentOrchestrator_CancelJob_FullMethodName          = "/content_factory.ContentOrchestrator/CancelJob"
+	ContentOrchestrator_SetJobVisibility_FullMethodName   = "/content_factory.ContentOrchestrator/SetJobVisibility"
+	ContentOrchestrator_GetContentInfo_FullMethodName     = "/content_factory.ContentOrchestrator/GetContentInfo"
+	ContentOrchestrator_DownloadContent_FullMethodName    = "/content_factory.ContentOrchestrator/DownloadContent"
+	ContentOrchestrator_GenerateStream_FullMethodName     = "/content_factory.ContentOrchestrator/GenerateStream"
+	ContentOrchestrator_StartAssetUpload_FullMethodName   = "/content_factory.ContentOrchestrator/StartAssetUpload"
+	ContentOrchestrator_UploadAssetChunk_FullMethodName   = "/content_factory.ContentOrchestrator/UploadAssetChunk"
+	ContentOrchestrator_FinishAssetUpload_FullMethodName  = "/content_factory.ContentOrchestrator/FinishAssetUpload"
+	ContentOrchestrator_GetBrandParameters_FullMethodName = "/content_factory.ContentOrchestrator/GetBrandParameters"
+	ContentOrchestrator_TriggerEvolution_FullMethodName   = "/content_factory.ContentOrchestrator/TriggerEvolution"
+)
+
+// ContentOrchestratorClient is the client API for ContentOrchestrator service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ContentOrchestratorClient interface {
+	// Execute full content creation pipeline
+	CreateContent(ctx context.Context, in *ContentCreationRequest, opts ...grpc.CallOption) (*ContentCreationResponse, error)
+	// Estimate what CreateContent would cost for the same request without
+	// creating a job; job_id is unset
+	EstimateContent(ctx context.Context, in *ContentCreationRequest, opts ...grpc.CallOption) (*ContentEstimate, error)
+	// Stream progress updates
+	StreamProgress(ctx context.Context, in *JobStatusRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ProgressUpdate], error)
+	// Get the current state of a content job; NOT_FOUND for unknown jobs
+	GetJobStatus(ctx context.Context, in *JobStatusRequest, opts ...grpc.CallOption) (*JobStatusResponse, error)
+	// Get the request a content job was submitted with, so it can be
+	// retried; NOT_FOUND for unknown jobs
+	GetJobRequest(ctx context.Context, in *JobStatusRequest, opts ...grpc.CallOption) (*ContentCreationRequest, error)
+	// List a principal's jobs, newest first, one page at a time
+	ListJobs(ctx context.Context, in *ListJobsRequest, opts ...grpc.CallOption) (*ListJobsResponse, error)
+	// Cancel a queued or processing job and return its new state;
+	// FAILED_PRECONDITION once it has finished, ABORTED if it changed after
+	// if_updated_at
+	CancelJob(ctx context.Context, in *CancelJobRequest, opts ...grpc.CallOption) (*JobStatusResponse, error)
+	// Make a job public, so anyone may read it once finished, or private
+	// again, and return its new state
+	SetJobVisibility(ctx context.Context, in *SetJobVisibilityRequest, opts ...grpc.CallOption) (*JobStatusResponse, error)
+	// Describe a finished content file; FAILED_PRECONDITION while unfinished
+	GetContentInfo(ctx context.Context, in *ContentInfoRequest, opts ...grpc.CallOption) (*ContentInfo, error)
+	// Stream a content file, or a byte range of it, in chunks
+	DownloadContent(ctx context.Context, in *DownloadRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ContentChunk], error)
+	// Generate text for a request without creating a job, streaming the
+	// tokens as they are produced; the last message carries the usage.
+	// Cancelling the call stops the generation.
+	GenerateStream(ctx context.Context, in *ContentCreationRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[GenerationChunk], error)
+	// Attach an uploaded file, such as a reference image, to a job. The
+	// gateway opens an upload, appends its bytes in order, then finishes it;
+	// uploads never finished are discarded by the service.
+	StartAssetUpload(ctx context.Context, in *StartAssetUploadRequest, opts ...grpc.CallOption) (*StartAssetUploadResponse, error)
+	UploadAssetChunk(ctx context.Context, in *AssetChunk, opts ...grpc.CallOption) (*Empty, error)
+	FinishAssetUpload(ctx context.Context, in *FinishAssetUploadRequest, opts ...grpc.CallOption) (*Asset, error)
+	// Get current brand parameters
+	GetBrandParameters(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*BrandParametersResponse, error)
+	// Trigger evolution cycle
+	TriggerEvolution(ctx context.Context, in *EvolutionRequest, opts ...grpc.CallOption) (*EvolutionResponse, error)
+}
+
+type contentOrchestratorClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewContentOrchestratorClient(cc grpc.ClientConnInterface) ContentOrchestratorClient {
+	return &contentOrchestratorClient{cc}
+}
+
+func (c *contentOrchestratorClient) CreateContent(ctx context.Context, in *ContentCreationRequest, opts ...grpc.CallOption) (*ContentCreationResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ContentCreationResponse)
+	err := c.cc.Invoke(ctx, ContentOrchestrator_CreateContent_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *contentOrchestratorClient) EstimateContent(ctx context.Context, in *ContentCreationRequest, opts ...grpc.CallOption) (*ContentEstimate, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ContentEstimate)
+	err := c.cc.Invoke(ctx, ContentOrchestrator_EstimateContent_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *contentOrchestratorClient) StreamProgress(ctx context.Context, in *JobStatusRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ProgressUpdate], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ContentOrchestrator_ServiceDesc.Streams[0], ContentOrchestrator_StreamProgress_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[JobStatusRequest, ProgressUpdate]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ContentOrchestrator_StreamProgressClient = grpc.ServerStreamingClient[ProgressUpdate]
+
+func (c *contentOrchestratorClient) GetJobStatus(ctx context.Context, in *JobStatusRequest, opts ...grpc.CallOption) (*JobStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(JobStatusResponse)
+	err := c.cc.Invoke(ctx, ContentOrchestrator_GetJobStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *contentOrchestratorClient) GetJobRequest(ctx context.Context, in *JobStatusRequest, opts ...grpc.CallOption) (*ContentCreationRequest, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ContentCreationRequest)
+	err := c.cc.Invoke(ctx, ContentOrchestrator_GetJobRequest_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *contentOrchestratorClient) ListJobs(ctx context.Context, in *ListJobsRequest, opts ...grpc.CallOption) (*ListJobsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListJobsResponse)
+	err := c.cc.Invoke(ctx, ContentOrchestrator_ListJobs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *contentOrchestratorClient) CancelJob(ctx context.Context, in *CancelJobRequest, opts ...grpc.CallOption) (*JobStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(JobStatusResponse)
+	err := c.cc.Invoke(ctx, ContentOrchestrator_CancelJob_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *contentOrchestratorClient) SetJobVisibility(ctx context.Context, in *SetJobVisibilityRequest, opts ...grpc.CallOption) (*JobStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(JobStatusResponse)
+	err := c.cc.Invoke(ctx, ContentOrchestrator_SetJobVisibility_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *contentOrchestratorClient) GetContentInfo(ctx context.Context, in *ContentInfoRequest, opts ...grpc.CallOption) (*ContentInfo, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ContentInfo)
+	err := c.cc.Invoke(ctx, ContentOrchestrator_GetContentInfo_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *contentOrchestratorClient) DownloadContent(ctx context.Context, in *DownloadRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ContentChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ContentOrchestrator_ServiceDesc.Streams[1], ContentOrchestrator_DownloadContent_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[DownloadRequest, ContentChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ContentOrchestrator_DownloadContentClient = grpc.ServerStreamingClient[ContentChunk]
+
+func (c *contentOrchestratorClient) GenerateStream(ctx context.Context, in *ContentCreationRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[GenerationChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ContentOrchestrator_ServiceDesc.Streams[2], ContentOrchestrator_GenerateStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ContentCreationRequest, GenerationChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ContentOrchestrator_GenerateStreamClient = grpc.ServerStreamingClient[GenerationChunk]
+
+func (c *contentOrchestratorClient) StartAssetUpload(ctx context.Context, in *StartAssetUploadRequest, opts ...grpc.CallOption) (*StartAssetUploadResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StartAssetUploadResponse)
+	err := c.cc.Invoke(ctx, ContentOrchestrator_StartAssetUpload_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *contentOrchestratorClient) UploadAssetChunk(ctx context.Context, in *AssetChunk, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, ContentOrchestrator_UploadAssetChunk_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *contentOrchestratorClient) FinishAssetUpload(ctx context.Context, in *FinishAssetUploadRequest, opts ...grpc.CallOption) (*Asset, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Asset)
+	err := c.cc.Invoke(ctx, ContentOrchestrator_FinishAssetUpload_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *contentOrchestratorClient) GetBrandParameters(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*BrandParametersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BrandParametersResponse)
+	err := c.cc.Invoke(ctx, ContentOrchestrator_GetBrandParameters_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *contentOrchestratorClient) TriggerEvolution(ctx context.Context, in *EvolutionRequest, opts ...grpc.CallOption) (*EvolutionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(EvolutionResponse)
+	err := c.cc.Invoke(ctx, ContentOrchestrator_TriggerEvolution_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ContentOrchestratorServer is the server API for ContentOrchestrator service.
+// All implementations must embed UnimplementedContentOrchestratorServer
+// for forward compatibility.
+type ContentOrchestratorServer interface {
+	// Execute full content creation pipeline
+	CreateContent(context.Context, *ContentCreationRequest) (*ContentCreationResponse, error)
+	// Estimate what CreateContent would cost for the same request without
+	// creating a job; job_id is unset
+	EstimateContent(context.Context, *ContentCreationRequest) (*ContentEstimate, error)
+	// Stream progress updates
+	StreamProgress(*JobStatusRequest, grpc.ServerStreamingServer[ProgressUpdate]) error
+	// Get the current state of a content job; NOT_FOUND for unknown jobs
+	GetJobStatus(context.Context, *JobStatusRequest) (*JobStatusResponse, error)
+	// Get the request a content job was submitted with, so it can be
+	// retried; NOT_FOUND for unknown jobs
+	GetJobRequest(context.Context, *JobStatusRequest) (*ContentCreationRequest, error)
+	// List a principal's jobs, newest first, one page at a time
+	ListJobs(context.Context, *ListJobsRequest) (*ListJobsResponse, error)
+	// Cancel a queued or processing job and return its new state;
+	// FAILED_PRECONDITION once it has finished, ABORTED if it changed after
+	// if_updated_at
+	CancelJob(context.Context, *CancelJobRequest) (*JobStatusResponse, error)
+	// Make a job public, so anyone may read it once finished, or private
+	// again, and return its new state
+	SetJobVisibility(context.Context, *SetJobVisibilityRequest) (*JobStatusResponse, error)
+	// Describe a finished content file; FAILED_PRECONDITION while unfinished
+	GetContentInfo(context.Context, *ContentInfoRequest) (*ContentInfo, error)
+	// Stream a content file, or a byte range of it, in chunks
+	DownloadContent(*DownloadRequest, grpc.ServerStreamingServer[ContentChunk]) error
+	// Generate text for a request without creating a job, streaming the
+	// tokens as they are produced; the last message carries the usage.
+	// Cancelling the call stops the generation.
+	GenerateStream(*ContentCreationRequest, grpc.ServerStreamingServer[GenerationChunk]) error
+	// Attach an uploaded file, such as a reference image, to a job. The
+	// gateway opens an upload, appends its bytes in order, then finishes it;
+	// uploads never finished are discarded by the service.
+	StartAssetUpload(context.Context, *StartAssetUploadRequest) (*StartAssetUploadResponse, error)
+	UploadAssetChunk(context.Context, *AssetChunk) (*Empty, error)
+	FinishAssetUpload(context.Context, *FinishAssetUploadRequest) (*Asset, error)
+	// Get current brand parameters
+	GetBrandParameters(context.Context, *Empty) (*BrandParametersResponse, error)
+	// Trigger evolution cycle
+	TriggerEvolution(context.Context, *EvolutionRequest) (*EvolutionResponse, error)
+	mustEmbedUnimplementedContentOrchestratorServer()
+}
+
+// UnimplementedContentOrchestratorServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedContentOrchestratorServer struct{}
+
+func (UnimplementedContentOrchestratorServer) CreateContent(context.Context, *ContentCreationRequest) (*ContentCreationResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method CreateContent not implemented")
+}
+func (UnimplementedContentOrchestratorServer) EstimateContent(context.Context, *ContentCreationRequest) (*ContentEstimate, error) {
+	return nil, status.Error(codes.Unimplemented, "method EstimateContent not implemented")
+}
+func (UnimplementedContentOrchestratorServer) StreamProgress(*JobStatusRequest, grpc.ServerStreamingServer[ProgressUpdate]) error {
+	return status.Error(codes.Unimplemented, "method StreamProgress not implemented")
+}
+func (UnimplementedContentOrchestratorServer) GetJobStatus(context.Context, *JobStatusRequest) (*JobStatusResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetJobStatus not implemented")
+}
+func (UnimplementedContentOrchestratorServer) GetJobRequest(context.Context, *JobStatusRequest) (*ContentCreationRequest, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetJobRequest not implemented")
+}
+func (UnimplementedContentOrchestratorServer) ListJobs(context.Context, *ListJobsRequest) (*ListJobsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListJobs not implemented")
+}
+func (UnimplementedContentOrchestratorServer) CancelJob(context.Context, *CancelJobRequest) (*JobStatusResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method CancelJob not implemented")
+}
+func (UnimplementedContentOrchestratorServer) SetJobVisibility(context.Context, *SetJobVisibilityRequest) (*JobStatusResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method SetJobVisibility not implemented")
+}
+func (UnimplementedContentOrchestratorServer) GetContentInfo(context.Context, *ContentInfoRequest) (*ContentInfo, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetContentInfo not implemented")
+}
+func (UnimplementedContentOrchestratorServer) DownloadContent(*DownloadRequest, grpc.ServerStreamingServer[ContentChunk]) error {
+	return status.Error(codes.Unimplemented, "method DownloadContent not implemented")
+}
+func (UnimplementedContentOrchestratorServer) GenerateStream(*ContentCreationRequest, grpc.ServerStreamingServer[GenerationChunk]) error {
+	return status.Error(codes.Unimplemented, "method GenerateStream not implemented")
+}
+func (UnimplementedContentOrchestratorServer) StartAssetUpload(context.Context, *StartAssetUploadRequest) (*StartAssetUploadResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method StartAssetUpload not implemented")
+}
+func (UnimplementedContentOrchestratorServer) UploadAssetChunk(context.Context, *AssetChunk) (*Empty, error) {
+	return nil, status.Error(codes.Unimplemented, "method UploadAssetChunk not implemented")
+}
+func (UnimplementedContentOrchestratorServer) FinishAssetUpload(context.Context, *FinishAssetUploadRequest) (*Asset, error) {
+	return nil, status.Error(codes.Unimplemented, "method FinishAssetUpload not implemented")
+}
+func (UnimplementedContentOrchestratorServer) GetBrandParameters(context.Context, *Empty) (*BrandParametersResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetBrandParameters not implemented")
+}
+func (UnimplementedContentOrchestratorServer) TriggerEvolution(context.Context, *EvolutionRequest) (*EvolutionResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method TriggerEvolution not implemented")
+}
+func (UnimplementedContentOrchestratorServer) mustEmbedUnimplementedContentOrchestratorServer() {}
+func (UnimplementedContentOrchestratorServer) testEmbeddedByValue()                             {}
+
+// UnsafeContentOrchestratorServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ContentOrchestratorServer will
+// result in compilation errors.
+type UnsafeContentOrchestratorServer interface {
+	mustEmbedUnimplementedContentOrchestratorServer()
+}
+
+func RegisterContentOrchestratorServer(s grpc.ServiceRegistrar, srv ContentOrchestratorServer) {
+	// If the following call panics, it indicates UnimplementedContentOrchestratorServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&ContentOrchestrator_ServiceDesc, srv)
+}
+
+func _ContentOrchestrator_CreateContent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ContentCreationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ContentOrchestratorServer).CreateContent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ContentOrchestrator_CreateContent_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ContentOrchestratorServer).CreateContent(ctx, req.(*ContentCreationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ContentOrchestrator_EstimateContent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ContentCreationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ContentOrchestratorServer).EstimateContent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ContentOrchestrator_EstimateContent_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ContentOrchestratorServer).EstimateContent(ctx, req.(*ContentCreationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ContentOrchestrator_StreamProgress_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(JobStatusRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ContentOrchestratorServer).StreamProgress(m, &grpc.GenericServerStream[JobStatusRequest, ProgressUpdate]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ContentOrchestrator_StreamProgressServer = grpc.ServerStreamingServer[ProgressUpdate]
+
+func _ContentOrchestrator_GetJobStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(JobStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ContentOrchestratorServer).GetJobStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ContentOrchestrator_GetJobStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ContentOrchestratorServer).GetJobStatus(ctx, req.(*JobStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ContentOrchestrator_GetJobRequest_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(JobStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ContentOrchestratorServer).GetJobRequest(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ContentOrchestrator_GetJobRequest_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ContentOrchestratorServer).GetJobRequest(ctx, req.(*JobStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ContentOrchestrator_ListJobs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListJobsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ContentOrchestratorServer).ListJobs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ContentOrchestrator_ListJobs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ContentOrchestratorServer).ListJobs(ctx, req.(*ListJobsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ContentOrchestrator_CancelJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ContentOrchestratorServer).CancelJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ContentOrchestrator_CancelJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ContentOrchestratorServer).CancelJob(ctx, req.(*CancelJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ContentOrchestrator_SetJobVisibility_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetJobVisibilityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ContentOrchestratorServer).SetJobVisibility(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ContentOrchestrator_SetJobVisibility_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ContentOrchestratorServer).SetJobVisibility(ctx, req.(*SetJobVisibilityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ContentOrchestrator_GetContentInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ContentInfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ContentOrchestratorServer).GetContentInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ContentOrchestrator_GetContentInfo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ContentOrchestratorServer).GetContentInfo(ctx, req.(*ContentInfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ContentOrchestrator_DownloadContent_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DownloadRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ContentOrchestratorServer).DownloadContent(m, &grpc.GenericServerStream[DownloadRequest, ContentChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ContentOrchestrator_DownloadContentServer = grpc.ServerStreamingServer[ContentChunk]
+
+func _ContentOrchestrator_GenerateStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ContentCreationRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ContentOrchestratorServer).GenerateStream(m, &grpc.GenericServerStream[ContentCreationRequest, GenerationChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ContentOrchestrator_GenerateStreamServer = grpc.ServerStreamingServer[GenerationChunk]
+
+func _ContentOrchestrator_StartAssetUpload_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StartAssetUploadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ContentOrchestratorServer).StartAssetUpload(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ContentOrchestrator_StartAssetUpload_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ContentOrchestratorServer).StartAssetUpload(ctx, req.(*StartAssetUploadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ContentOrchestrator_UploadAssetChunk_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AssetChunk)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ContentOrchestratorServer).UploadAssetChunk(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ContentOrchestrator_UploadAssetChunk_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ContentOrchestratorServer).UploadAssetChunk(ctx, req.(*AssetChunk))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ContentOrchestrator_FinishAssetUpload_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FinishAssetUploadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ContentOrchestratorServer).FinishAssetUpload(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ContentOrchestrator_FinishAssetUpload_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ContentOrchestratorServer).FinishAssetUpload(ctx, req.(*FinishAssetUploadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ContentOrchestrator_GetBrandParameters_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ContentOrchestratorServer).GetBrandParameters(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ContentOrchestrator_GetBrandParameters_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ContentOrchestratorServer).GetBrandParameters(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ContentOrchestrator_TriggerEvolution_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EvolutionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ContentOrchestratorServer).TriggerEvolution(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ContentOrchestrator_TriggerEvolution_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ContentOrchestratorServer).TriggerEvolution(ctx, req.(*EvolutionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ContentOrchestrator_ServiceDesc is the grpc.ServiceDesc for ContentOrchestrator service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ContentOrchestrator_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "content_factory.ContentOrchestrator",
+	HandlerType: (*ContentOrchestratorServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateContent",
+			Handler:    _ContentOrchestrator_CreateContent_Handler,
+		},
+		{
+			MethodName: "EstimateContent",
+			Handler:    _ContentOrchestrator_EstimateContent_Handler,
+		},
+		{
+			MethodName: "GetJobStatus",
+			Handler:    _ContentOrchestrator_GetJobStatus_Handler,
+		},
+		{
+			MethodName: "GetJobRequest",
+			Handler:    _ContentOrchestrator_GetJobRequest_Handler,
+		},
+		{
+			MethodName: "ListJobs",
+			Handler:    _ContentOrchestrator_ListJobs_Handler,
+		},
+		{
+			MethodName: "CancelJob",
+			Handler:    _ContentOrchestrator_CancelJob_Handler,
+		},
+		{
+			MethodName: "SetJobVisibility",
+			Handler:    _ContentOrchestrator_SetJobVisibility_Handler,
+		},
+		{
+			MethodName: "GetContentInfo",
+			Handler:    _ContentOrchestrator_GetContentInfo_Handler,
+		},
+		{
+			MethodName: "StartAssetUpload",
+			Handler:    _ContentOrchestrator_StartAssetUpload_Handler,
+		},
+		{
+			MethodName: "UploadAssetChunk",
+			Handler:    _ContentOrchestrator_UploadAssetChunk_Handler,
+		},
+		{
+			MethodName: "FinishAssetUpload",
+			Handler:    _ContentOrchestrator_FinishAssetUpload_Handler,
+		},
+		{
+			MethodName: "GetBrandParameters",
+			Handler:    _ContentOrchestrator_GetBrandParameters_Handler,
+		},
+		{
+			MethodName: "TriggerEvolution",
+			Handler:    _ContentOrchestrator_TriggerEvolution_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamProgress",
+			Handler:       _ContentOrchestrator_StreamProgress_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "DownloadContent",
+			Handler:       _ContentOrchestrator_DownloadContent_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "GenerateStream",
+			Handler:       _ContentOrchestrator_GenerateStream_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "content_factory.proto",
+}
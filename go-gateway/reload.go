@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/content-factory/go-gateway/internal/clientip"
+	"github.com/content-factory/go-gateway/internal/config"
+	"github.com/content-factory/go-gateway/internal/cors"
+	"github.com/content-factory/go-gateway/internal/flags"
+	"github.com/content-factory/go-gateway/internal/loadshed"
+	"github.com/content-factory/go-gateway/internal/middleware"
+	"github.com/content-factory/go-gateway/internal/ratelimit"
+)
+
+// liveSettings are the components whose configuration SIGHUP replaces.
+// Each takes its new settings as a whole with one atomic swap, so a
+// request sees a component's old settings or its new ones, never a mix;
+// a request's deadline is fixed when it starts.
+type liveSettings struct {
+	level   *slog.LevelVar
+	timeout *middleware.TimeoutVar
+	limiter *ratelimit.Limiter
+	cors    *cors.Policy
+	shedder *loadshed.Shedder
+	proxies *clientip.Resolver
+	flags   *flags.Set
+}
+
+// reloadOnHangup re-reads the configuration on each SIGHUP until ctx is
+// done. A configuration that fails to load or validate is logged and
+// nothing changes; otherwise the reloadable settings are applied, and
+// changes to the rest are logged as ignored until the next restart.
+func reloadOnHangup(ctx context.Context, running *config.Config, live liveSettings) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+		}
+		next, err := config.Load()
+		if err != nil {
+			slog.Error("configuration reload failed, keeping the running configuration", "error", err)
+			continue
+		}
+		if keys := running.RestartRequired(next); len(keys) > 0 {
+			slog.Warn("configuration changes need a restart and were ignored", "keys", keys)
+		}
+		// Applying the merged configuration keeps settings that need a
+		// restart, like the route overrides the limiter carries, as they
+		// were.
+		running = mergeReloadable(running, next)
+		live.apply(running)
+		slog.Info("configuration reloaded", "log_level", next.LogLevel, "request_timeout", next.RequestTimeout,
+			"rate_limit_rps", next.RateLimit.RPS, "rate_limit_burst", next.RateLimit.Burst,
+			"cors_allowed_origins", next.CORS.AllowedOrigins, "load_shed_max_in_flight", next.LoadShed.MaxInFlight,
+			"feature_flags", len(next.FeatureFlags))
+	}
+}
+
+func (l liveSettings) apply(cfg *config.Config) {
+	l.level.Set(cfg.SlogLevel())
+	l.timeout.Set(cfg.RequestTimeout)
+	l.timeout.SetClientBounds(cfg.ClientTimeout.Min, cfg.ClientTimeout.Max)
+	l.limiter.SetConfig(cfg.RateLimitConfig())
+	l.cors.Set(cfg.CORSConfig())
+	l.shedder.SetLimit(cfg.LoadShed.MaxInFlight)
+	// Validated by config.Load.
+	l.proxies.SetTrusted(cfg.TrustedProxyRanges())
+	l.flags.Set(cfg.Flags())
+}
+
+// mergeReloadable returns running with the reloadable settings of next,
+// the configuration now in force.
+func mergeReloadable(running, next *config.Config) *config.Config {
+	merged := *running
+	merged.LogLevel = next.LogLevel
+	merged.RequestTimeout = next.RequestTimeout
+	merged.ClientTimeout = next.ClientTimeout
+	merged.RateLimit = next.RateLimit
+	merged.CORS = next.CORS
+	merged.LoadShed = next.LoadShed
+	merged.TrustedProxies = next.TrustedProxies
+	merged.FeatureFlags = next.FeatureFlags
+	return &merged
+}